@@ -92,6 +92,7 @@ type State struct {
 	Paused     bool      `json:"Paused,omitempty" yaml:"Paused,omitempty"`
 	Restarting bool      `json:"Restarting,omitempty" yaml:"Restarting,omitempty"`
 	OOMKilled  bool      `json:"OOMKilled,omitempty" yaml:"OOMKilled,omitempty"`
+	Dead       bool      `json:"Dead,omitempty" yaml:"Dead,omitempty"`
 	Pid        int       `json:"Pid,omitempty" yaml:"Pid,omitempty"`
 	ExitCode   int       `json:"ExitCode,omitempty" yaml:"ExitCode,omitempty"`
 	Error      string    `json:"Error,omitempty" yaml:"Error,omitempty"`
@@ -425,6 +426,7 @@ type HostConfig struct {
 	ReadonlyRootfs  bool                   `json:"ReadonlyRootfs,omitempty" yaml:"ReadonlyRootfs,omitempty"`
 	SecurityOpt     []string               `json:"SecurityOpt,omitempty" yaml:"SecurityOpt,omitempty"`
 	CgroupParent    string                 `json:"CgroupParent,omitempty" yaml:"CgroupParent,omitempty"`
+	ShmSize         int64                  `json:"ShmSize,omitempty" yaml:"ShmSize,omitempty"`
 }
 
 // StartContainer starts a container, returning an error in case of failure.
@@ -464,6 +466,28 @@ func (c *Client) StopContainer(id string, timeout uint) error {
 	return nil
 }
 
+// UpdateContainerOptions specify the resource limits to apply to a running
+// container, used by UpdateContainer.
+type UpdateContainerOptions struct {
+	CPUShares  int64 `json:"CpuShares,omitempty"`
+	Memory     int64 `json:"Memory,omitempty"`
+	MemorySwap int64 `json:"MemorySwap,omitempty"`
+}
+
+// UpdateContainer updates the resource limits of a running container without
+// restarting it.
+func (c *Client) UpdateContainer(id string, opts UpdateContainerOptions) error {
+	path := "/containers/" + id + "/update"
+	_, status, err := c.do("POST", path, doOptions{data: opts, forceJSON: true})
+	if status == http.StatusNotFound {
+		return &NoSuchContainer{ID: id}
+	}
+	if err != nil {
+		return err
+	}
+	return nil
+}
+
 // RestartContainer stops a container, killing it after the given timeout (in
 // seconds), during the stop process.
 //
@@ -748,6 +772,7 @@ type LogsOptions struct {
 	Follow       bool
 	Stdout       bool
 	Stderr       bool
+	Since        int64
 	Timestamps   bool
 	Tail         string
 
@@ -0,0 +1,242 @@
+/*
+Copyright 2015 Google Inc. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package app
+
+import (
+	"bytes"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path"
+	"time"
+
+	"github.com/GoogleCloudPlatform/kubernetes/pkg/clientauth"
+	"github.com/GoogleCloudPlatform/kubernetes/pkg/kubelet"
+	"github.com/GoogleCloudPlatform/kubernetes/pkg/util"
+
+	"github.com/golang/glog"
+)
+
+const (
+	// Client certificate and key obtained through the bootstrap flow,
+	// relative to the kubelet's root directory.
+	clientCertificatePath = "pki/kubelet-client.crt"
+	clientKeyPath         = "pki/kubelet-client.key"
+
+	// A certificate is rotated once less than this fraction of its lifetime
+	// remains, expressed in percent.
+	certificateRotationThresholdPercent = 20
+
+	// How often the rotation goroutine inspects the certificate on disk.
+	certificateRotationCheckPeriod = 6 * time.Hour
+
+	// Organization placed in requested certificates, identifying the
+	// subject as a node.
+	nodeCertificateOrganization = "system:nodes"
+)
+
+// bootstrapClientCertificate makes sure a usable client certificate exists
+// under the kubelet's root directory, requesting one from the certificate
+// signing endpoint with the bootstrap credentials if it is missing or close
+// to expiry. It returns the certificate and key paths to use for the API
+// server client.
+func (s *KubeletServer) bootstrapClientCertificate(hostname string) (certFile, keyFile string, err error) {
+	certFile = path.Join(s.RootDirectory, clientCertificatePath)
+	keyFile = path.Join(s.RootDirectory, clientKeyPath)
+	if !certificateNeedsRotation(certFile) {
+		glog.V(2).Infof("Using existing client certificate %q", certFile)
+		return certFile, keyFile, nil
+	}
+	if err := s.requestClientCertificate(hostname, certFile, keyFile); err != nil {
+		return "", "", err
+	}
+	return certFile, keyFile, nil
+}
+
+// rotateClientCertificate requests a fresh client certificate once the
+// current one approaches expiry. The new certificate is picked up on the
+// next connection to the API server.
+func (s *KubeletServer) rotateClientCertificate(hostname string) {
+	certFile := path.Join(s.RootDirectory, clientCertificatePath)
+	keyFile := path.Join(s.RootDirectory, clientKeyPath)
+	if !certificateNeedsRotation(certFile) {
+		return
+	}
+	glog.Infof("Client certificate %q is approaching expiry, requesting a new one", certFile)
+	if err := s.requestClientCertificate(hostname, certFile, keyFile); err != nil {
+		glog.Errorf("Unable to rotate client certificate: %v", err)
+		return
+	}
+	glog.Infof("Client certificate rotated; it will be used for new connections to the API server")
+}
+
+// rotateServingCertificate renews the kubelet's serving certificate before
+// it expires, through the certificate signing endpoint when one is
+// configured and by re-self-signing otherwise, and swaps it into the
+// running TLS listener.
+func (s *KubeletServer) rotateServingCertificate(servingCert *kubelet.ReloadableCertificate) {
+	if !certificateNeedsRotation(s.TLSCertFile) {
+		return
+	}
+	glog.Infof("Serving certificate %q is approaching expiry, renewing", s.TLSCertFile)
+	hostname := util.GetHostname(s.HostnameOverride)
+	if s.CertificateSigningURL != "" {
+		if err := s.requestClientCertificate(hostname, s.TLSCertFile, s.TLSPrivateKeyFile); err != nil {
+			glog.Errorf("Unable to renew serving certificate: %v", err)
+			return
+		}
+	} else if err := util.GenerateSelfSignedCert(hostname, s.TLSCertFile, s.TLSPrivateKeyFile); err != nil {
+		glog.Errorf("Unable to re-self-sign serving certificate: %v", err)
+		return
+	}
+	if err := servingCert.Reload(); err != nil {
+		glog.Errorf("Unable to load renewed serving certificate: %v", err)
+		return
+	}
+	glog.Infof("Serving certificate renewed")
+}
+
+// certificateNeedsRotation returns true if the certificate at the given path
+// is missing, unparseable, expired, or has used up all but a small fraction
+// of its lifetime.
+func certificateNeedsRotation(certFile string) bool {
+	data, err := ioutil.ReadFile(certFile)
+	if err != nil {
+		return true
+	}
+	block, _ := pem.Decode(data)
+	if block == nil {
+		glog.Warningf("Client certificate %q is not PEM encoded, requesting a new one", certFile)
+		return true
+	}
+	cert, err := x509.ParseCertificate(block.Bytes)
+	if err != nil {
+		glog.Warningf("Unable to parse client certificate %q: %v, requesting a new one", certFile, err)
+		return true
+	}
+	now := time.Now()
+	total := cert.NotAfter.Sub(cert.NotBefore)
+	remaining := cert.NotAfter.Sub(now)
+	return remaining*100 < total*certificateRotationThresholdPercent
+}
+
+// requestClientCertificate generates a new private key and certificate
+// signing request for this node, submits it to the certificate signing
+// endpoint using the bootstrap credentials, and persists the returned
+// certificate and the key under the kubelet's root directory.
+func (s *KubeletServer) requestClientCertificate(hostname, certFile, keyFile string) error {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		return fmt.Errorf("unable to generate private key: %v", err)
+	}
+	csrDER, err := x509.CreateCertificateRequest(rand.Reader, &x509.CertificateRequest{
+		Subject: pkix.Name{
+			CommonName:   hostname,
+			Organization: []string{nodeCertificateOrganization},
+		},
+	}, key)
+	if err != nil {
+		return fmt.Errorf("unable to create certificate request: %v", err)
+	}
+	csrPEM := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE REQUEST", Bytes: csrDER})
+
+	certPEM, err := s.submitCertificateRequest(csrPEM)
+	if err != nil {
+		return err
+	}
+	block, _ := pem.Decode(certPEM)
+	if block == nil {
+		return fmt.Errorf("certificate signing endpoint returned a response that is not PEM encoded")
+	}
+	if _, err := x509.ParseCertificate(block.Bytes); err != nil {
+		return fmt.Errorf("certificate signing endpoint returned an invalid certificate: %v", err)
+	}
+
+	keyPEM := pem.EncodeToMemory(&pem.Block{Type: "RSA PRIVATE KEY", Bytes: x509.MarshalPKCS1PrivateKey(key)})
+	if err := os.MkdirAll(path.Dir(certFile), 0700); err != nil {
+		return fmt.Errorf("unable to create certificate directory: %v", err)
+	}
+	if err := ioutil.WriteFile(keyFile, keyPEM, 0600); err != nil {
+		return fmt.Errorf("unable to write private key: %v", err)
+	}
+	if err := ioutil.WriteFile(certFile, certPEM, 0644); err != nil {
+		return fmt.Errorf("unable to write certificate: %v", err)
+	}
+	return nil
+}
+
+// submitCertificateRequest posts the PEM encoded certificate signing request
+// to the signing endpoint, authenticated with the bootstrap credentials, and
+// returns the PEM encoded signed certificate.
+func (s *KubeletServer) submitCertificateRequest(csrPEM []byte) ([]byte, error) {
+	authInfo, err := clientauth.LoadFromFile(s.BootstrapAuthPath)
+	if err != nil {
+		return nil, fmt.Errorf("unable to load bootstrap auth file %q: %v", s.BootstrapAuthPath, err)
+	}
+
+	tlsConfig := &tls.Config{}
+	if authInfo.CAFile != "" {
+		caData, err := ioutil.ReadFile(authInfo.CAFile)
+		if err != nil {
+			return nil, fmt.Errorf("unable to read CA file %q: %v", authInfo.CAFile, err)
+		}
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(caData) {
+			return nil, fmt.Errorf("no certificates found in CA file %q", authInfo.CAFile)
+		}
+		tlsConfig.RootCAs = pool
+	}
+	if authInfo.Insecure != nil && *authInfo.Insecure {
+		tlsConfig.InsecureSkipVerify = true
+	}
+	httpClient := &http.Client{
+		Transport: &http.Transport{TLSClientConfig: tlsConfig},
+		Timeout:   time.Minute,
+	}
+
+	req, err := http.NewRequest("POST", s.CertificateSigningURL, bytes.NewReader(csrPEM))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/x-pem-file")
+	if authInfo.BearerToken != "" {
+		req.Header.Set("Authorization", "Bearer "+authInfo.BearerToken)
+	} else if authInfo.User != "" {
+		req.SetBasicAuth(authInfo.User, authInfo.Password)
+	}
+
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("certificate signing request failed: %v", err)
+	}
+	defer resp.Body.Close()
+	body, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("unable to read certificate signing response: %v", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("certificate signing endpoint returned status %q: %s", resp.Status, string(body))
+	}
+	return body, nil
+}
@@ -0,0 +1,298 @@
+/*
+Copyright 2015 Google Inc. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package app
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"os/signal"
+	"strconv"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/GoogleCloudPlatform/kubernetes/pkg/configz"
+	"github.com/GoogleCloudPlatform/kubernetes/pkg/util"
+
+	"github.com/ghodss/yaml"
+	"github.com/golang/glog"
+)
+
+const (
+	kubeletConfigurationKind = "KubeletConfiguration"
+	// The configuration file format is versioned independently of the main
+	// API so it can evolve with the kubelet.
+	kubeletConfigurationVersion = "kubelet/v1alpha1"
+)
+
+// configDuration makes duration fields in the configuration file readable as
+// strings accepted by time.ParseDuration, e.g. "10s" or "2m30s".
+type configDuration struct {
+	time.Duration
+}
+
+func (d *configDuration) UnmarshalJSON(data []byte) error {
+	var str string
+	if err := json.Unmarshal(data, &str); err != nil {
+		return err
+	}
+	parsed, err := time.ParseDuration(str)
+	if err != nil {
+		return err
+	}
+	d.Duration = parsed
+	return nil
+}
+
+// KubeletConfiguration is the versioned configuration object read from the
+// file given to --kubelet_config. Fields left unset keep the kubelet's
+// defaults, and any flag set explicitly on the command line overrides the
+// corresponding file value.
+type KubeletConfiguration struct {
+	Kind       string `json:"kind"`
+	APIVersion string `json:"apiVersion"`
+
+	LogLevel                    *int            `json:"logLevel,omitempty"`
+	SyncFrequency               *configDuration `json:"syncFrequency,omitempty"`
+	FileCheckFrequency          *configDuration `json:"fileCheckFrequency,omitempty"`
+	HTTPCheckFrequency          *configDuration `json:"httpCheckFrequency,omitempty"`
+	NodeStatusUpdateFrequency   *configDuration `json:"nodeStatusUpdateFrequency,omitempty"`
+	MinimumGCAge                *configDuration `json:"minimumGCAge,omitempty"`
+	MaxPerPodContainerCount     *int            `json:"maxPerPodContainerCount,omitempty"`
+	MaxContainerCount           *int            `json:"maxContainerCount,omitempty"`
+	ImageGCHighThresholdPercent *int            `json:"imageGCHighThresholdPercent,omitempty"`
+	ImageGCLowThresholdPercent  *int            `json:"imageGCLowThresholdPercent,omitempty"`
+	MaxConcurrentPodSyncs       *int            `json:"maxConcurrentPodSyncs,omitempty"`
+	ContainerLogMaxSize         *string         `json:"containerLogMaxSize,omitempty"`
+	ContainerLogMaxFiles        *int            `json:"containerLogMaxFiles,omitempty"`
+	ClusterDomain               *string         `json:"clusterDomain,omitempty"`
+	EnableServiceLinks          *bool           `json:"enableServiceLinks,omitempty"`
+	AllowedUnsafeSysctls        []string        `json:"allowedUnsafeSysctls,omitempty"`
+	PodInfraShmSize             *string         `json:"podInfraShmSize,omitempty"`
+	MaxPods                     *int            `json:"maxPods,omitempty"`
+}
+
+// loadKubeletConfiguration reads, decodes and validates the configuration
+// file at the given path. Both YAML and JSON are accepted.
+func loadKubeletConfiguration(path string) (*KubeletConfiguration, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	conf := &KubeletConfiguration{}
+	if err := yaml.Unmarshal(data, conf); err != nil {
+		return nil, err
+	}
+	if conf.Kind != kubeletConfigurationKind {
+		return nil, fmt.Errorf("expected kind %q, got %q", kubeletConfigurationKind, conf.Kind)
+	}
+	if conf.APIVersion != kubeletConfigurationVersion {
+		return nil, fmt.Errorf("unsupported apiVersion %q, only %q is supported", conf.APIVersion, kubeletConfigurationVersion)
+	}
+	if err := conf.validate(); err != nil {
+		return nil, err
+	}
+	return conf, nil
+}
+
+// validate rejects values the kubelet could not run with before any of them
+// are applied.
+func (conf *KubeletConfiguration) validate() error {
+	if conf.LogLevel != nil && *conf.LogLevel < 0 {
+		return fmt.Errorf("logLevel must not be negative, got %d", *conf.LogLevel)
+	}
+	durations := map[string]*configDuration{
+		"syncFrequency":             conf.SyncFrequency,
+		"fileCheckFrequency":        conf.FileCheckFrequency,
+		"httpCheckFrequency":        conf.HTTPCheckFrequency,
+		"nodeStatusUpdateFrequency": conf.NodeStatusUpdateFrequency,
+	}
+	for field, d := range durations {
+		if d != nil && d.Duration <= 0 {
+			return fmt.Errorf("%s must be positive, got %v", field, d.Duration)
+		}
+	}
+	if conf.MinimumGCAge != nil && conf.MinimumGCAge.Duration < 0 {
+		return fmt.Errorf("minimumGCAge must not be negative, got %v", conf.MinimumGCAge.Duration)
+	}
+	percents := map[string]*int{
+		"imageGCHighThresholdPercent": conf.ImageGCHighThresholdPercent,
+		"imageGCLowThresholdPercent":  conf.ImageGCLowThresholdPercent,
+	}
+	for field, p := range percents {
+		if p != nil && (*p < 0 || *p > 100) {
+			return fmt.Errorf("%s must be between 0 and 100, got %d", field, *p)
+		}
+	}
+	if conf.ImageGCHighThresholdPercent != nil && conf.ImageGCLowThresholdPercent != nil &&
+		*conf.ImageGCLowThresholdPercent > *conf.ImageGCHighThresholdPercent {
+		return fmt.Errorf("imageGCLowThresholdPercent %d must not exceed imageGCHighThresholdPercent %d",
+			*conf.ImageGCLowThresholdPercent, *conf.ImageGCHighThresholdPercent)
+	}
+	if conf.ContainerLogMaxFiles != nil && *conf.ContainerLogMaxFiles < 2 {
+		return fmt.Errorf("containerLogMaxFiles must be at least 2, got %d", *conf.ContainerLogMaxFiles)
+	}
+	if conf.MaxConcurrentPodSyncs != nil && *conf.MaxConcurrentPodSyncs < 0 {
+		return fmt.Errorf("maxConcurrentPodSyncs must not be negative, got %d", *conf.MaxConcurrentPodSyncs)
+	}
+	if conf.MaxPods != nil && *conf.MaxPods < 0 {
+		return fmt.Errorf("maxPods must not be negative, got %d", *conf.MaxPods)
+	}
+	return nil
+}
+
+// applyConfiguration copies every value set in the configuration file onto
+// the server, except for fields whose flag was given explicitly on the
+// command line; flags always win over the file.
+func (s *KubeletServer) applyConfiguration(conf *KubeletConfiguration, setFlags util.StringSet) {
+	if conf.LogLevel != nil && !setFlags.Has("v") {
+		applyLogLevel(*conf.LogLevel)
+	}
+	if conf.SyncFrequency != nil && !setFlags.Has("sync_frequency") {
+		s.SyncFrequency = conf.SyncFrequency.Duration
+	}
+	if conf.FileCheckFrequency != nil && !setFlags.Has("file_check_frequency") {
+		s.FileCheckFrequency = conf.FileCheckFrequency.Duration
+	}
+	if conf.HTTPCheckFrequency != nil && !setFlags.Has("http_check_frequency") {
+		s.HTTPCheckFrequency = conf.HTTPCheckFrequency.Duration
+	}
+	if conf.NodeStatusUpdateFrequency != nil && !setFlags.Has("node_status_update_frequency") {
+		s.NodeStatusUpdateFrequency = conf.NodeStatusUpdateFrequency.Duration
+	}
+	if conf.MinimumGCAge != nil && !setFlags.Has("minimum_container_ttl_duration") {
+		s.MinimumGCAge = conf.MinimumGCAge.Duration
+	}
+	if conf.MaxPerPodContainerCount != nil && !setFlags.Has("maximum_dead_containers_per_container") {
+		s.MaxPerPodContainerCount = *conf.MaxPerPodContainerCount
+	}
+	if conf.MaxContainerCount != nil && !setFlags.Has("maximum_dead_containers") {
+		s.MaxContainerCount = *conf.MaxContainerCount
+	}
+	if conf.ImageGCHighThresholdPercent != nil && !setFlags.Has("image_gc_high_threshold") {
+		s.ImageGCHighThresholdPercent = *conf.ImageGCHighThresholdPercent
+	}
+	if conf.ImageGCLowThresholdPercent != nil && !setFlags.Has("image_gc_low_threshold") {
+		s.ImageGCLowThresholdPercent = *conf.ImageGCLowThresholdPercent
+	}
+	if conf.MaxConcurrentPodSyncs != nil && !setFlags.Has("max_concurrent_pod_syncs") {
+		s.MaxConcurrentPodSyncs = *conf.MaxConcurrentPodSyncs
+	}
+	if conf.ContainerLogMaxSize != nil && !setFlags.Has("container_log_max_size") {
+		s.ContainerLogMaxSize = *conf.ContainerLogMaxSize
+	}
+	if conf.ContainerLogMaxFiles != nil && !setFlags.Has("container_log_max_files") {
+		s.ContainerLogMaxFiles = *conf.ContainerLogMaxFiles
+	}
+	if conf.ClusterDomain != nil && !setFlags.Has("cluster_domain") {
+		s.ClusterDomain = *conf.ClusterDomain
+	}
+	if conf.EnableServiceLinks != nil && !setFlags.Has("enable_service_links") {
+		s.EnableServiceLinks = *conf.EnableServiceLinks
+	}
+	if conf.AllowedUnsafeSysctls != nil && !setFlags.Has("allowed_unsafe_sysctls") {
+		s.AllowedUnsafeSysctls = util.StringList(conf.AllowedUnsafeSysctls)
+	}
+	if conf.PodInfraShmSize != nil && !setFlags.Has("pod_infra_shm_size") {
+		s.PodInfraShmSize = *conf.PodInfraShmSize
+	}
+	if conf.MaxPods != nil && !setFlags.Has("max_pods") {
+		s.MaxPods = *conf.MaxPods
+	}
+}
+
+// publishedConfig mirrors what /configz serves for the kubelet, so the
+// SIGHUP reloader can fold runtime overrides into it.
+var (
+	publishedConfigLock sync.Mutex
+	publishedConfig     *KubeletServer
+	kubeletConfigz      *configz.Config
+)
+
+// publishConfigz exposes the kubelet's effective configuration, with the
+// configuration file and any explicit flags already applied, on the /configz
+// endpoint.
+func publishConfigz(s KubeletServer) {
+	publishedConfigLock.Lock()
+	defer publishedConfigLock.Unlock()
+	if kubeletConfigz == nil {
+		cz, err := configz.New("kubeletconfig")
+		if err != nil {
+			glog.Errorf("Unable to register kubelet configuration with /configz: %v", err)
+			return
+		}
+		kubeletConfigz = cz
+	}
+	publishedConfig = &s
+	kubeletConfigz.Set(s)
+}
+
+// republishConfigz updates the published configuration with the overrides a
+// SIGHUP reload applied at runtime.
+func republishConfigz(conf *KubeletConfiguration) {
+	publishedConfigLock.Lock()
+	defer publishedConfigLock.Unlock()
+	if publishedConfig == nil {
+		return
+	}
+	if conf.ImageGCHighThresholdPercent != nil {
+		publishedConfig.ImageGCHighThresholdPercent = *conf.ImageGCHighThresholdPercent
+	}
+	if conf.ImageGCLowThresholdPercent != nil {
+		publishedConfig.ImageGCLowThresholdPercent = *conf.ImageGCLowThresholdPercent
+	}
+	kubeletConfigz.Set(*publishedConfig)
+}
+
+// applyLogLevel sets glog's verbosity, which may be changed while the
+// kubelet is running.
+func applyLogLevel(level int) {
+	if err := flag.Set("v", strconv.Itoa(level)); err != nil {
+		glog.Errorf("Failed to set log verbosity to %d: %v", level, err)
+	}
+}
+
+// startConfigurationReloader rereads the configuration file whenever the
+// kubelet receives SIGHUP and applies the subset of settings that can change
+// at runtime: log verbosity and the image garbage collection thresholds.
+// All other settings still require a restart to take effect.
+func startConfigurationReloader(path string, k KubeletBootstrap) {
+	sigCh := make(chan os.Signal, 1)
+	signal.Notify(sigCh, syscall.SIGHUP)
+	go func() {
+		for range sigCh {
+			glog.Infof("SIGHUP received, reloading kubelet configuration from %q", path)
+			conf, err := loadKubeletConfiguration(path)
+			if err != nil {
+				glog.Errorf("Not applying invalid kubelet configuration file %q: %v", path, err)
+				continue
+			}
+			if conf.LogLevel != nil {
+				applyLogLevel(*conf.LogLevel)
+			}
+			if conf.ImageGCHighThresholdPercent != nil || conf.ImageGCLowThresholdPercent != nil {
+				if err := k.UpdateImageGCPolicy(conf.ImageGCHighThresholdPercent, conf.ImageGCLowThresholdPercent); err != nil {
+					glog.Errorf("Failed to update image garbage collection policy: %v", err)
+				}
+			}
+			republishConfigz(conf)
+		}
+	}()
+}
@@ -19,17 +19,24 @@ package app
 
 import (
 	"crypto/tls"
+	"encoding/json"
 	"fmt"
+	"io"
 	"math/rand"
 	"net"
 	"net/http"
 	_ "net/http/pprof"
+	"os"
 	"path"
 	"strconv"
 	"strings"
 	"time"
 
 	"github.com/GoogleCloudPlatform/kubernetes/pkg/api"
+	"github.com/GoogleCloudPlatform/kubernetes/pkg/api/resource"
+	"github.com/GoogleCloudPlatform/kubernetes/pkg/apiserver"
+	"github.com/GoogleCloudPlatform/kubernetes/pkg/auth/authenticator/bearertoken"
+	"github.com/GoogleCloudPlatform/kubernetes/pkg/auth/authorizer"
 	"github.com/GoogleCloudPlatform/kubernetes/pkg/capabilities"
 	"github.com/GoogleCloudPlatform/kubernetes/pkg/client"
 	"github.com/GoogleCloudPlatform/kubernetes/pkg/client/chaosclient"
@@ -47,6 +54,8 @@ import (
 	"github.com/GoogleCloudPlatform/kubernetes/pkg/volume"
 
 	"github.com/GoogleCloudPlatform/kubernetes/pkg/cloudprovider"
+	webhooktoken "github.com/GoogleCloudPlatform/kubernetes/plugin/pkg/auth/authenticator/token/webhook"
+	webhookauthz "github.com/GoogleCloudPlatform/kubernetes/plugin/pkg/auth/authorizer/webhook"
 	"github.com/golang/glog"
 	"github.com/spf13/pflag"
 )
@@ -57,6 +66,7 @@ const defaultRootDir = "/var/lib/kubelet"
 // a kubelet. These can either be set via command line or directly.
 type KubeletServer struct {
 	Config                         string
+	KubeletConfigFile              string
 	SyncFrequency                  time.Duration
 	FileCheckFrequency             time.Duration
 	HTTPCheckFrequency             time.Duration
@@ -73,6 +83,7 @@ type KubeletServer struct {
 	HostNetworkSources             string
 	RegistryPullQPS                float64
 	RegistryBurst                  int
+	ImagePullTimeout               time.Duration
 	RunOnce                        bool
 	EnableDebuggingHandlers        bool
 	MinimumGCAge                   time.Duration
@@ -87,6 +98,13 @@ type KubeletServer struct {
 	ClusterDomain                  string
 	MasterServiceNamespace         string
 	ClusterDNS                     util.IP
+	NodeIP                         util.IP
+	RegisterNode                   bool
+	NodeLabels                     util.StringList
+	RegistryRewrites               util.StringList
+	AlwaysPullImages               bool
+	NodeTaints                     util.StringList
+	RegisterSchedulable            bool
 	StreamingConnectionIdleTimeout time.Duration
 	ImageGCHighThresholdPercent    int
 	ImageGCLowThresholdPercent     int
@@ -97,7 +115,24 @@ type KubeletServer struct {
 	TLSPrivateKeyFile              string
 	CertDirectory                  string
 	NodeStatusUpdateFrequency      time.Duration
+	NodeStatusFullUpdateFrequency  time.Duration
 	ResourceContainer              string
+	MaxConcurrentPodSyncs          int
+	SharedPIDNamespace             bool
+	DockerExecHandlerName          string
+	PostStartFailurePolicy         string
+	ContainerLogMaxSize            string
+	ContainerLogMaxFiles           int
+	EnableServiceLinks             bool
+	AllowedUnsafeSysctls           util.StringList
+	PodInfraShmSize                string
+	MaxPods                        int
+	MigrateDataDirs                bool
+	NodeProblemConfig              string
+	BootstrapAuthPath              string
+	CertificateSigningURL          string
+	AuthnTokenWebhookURL           string
+	AuthzWebhookURL                string
 
 	// Flags intended for testing
 
@@ -105,16 +140,26 @@ type KubeletServer struct {
 	ReallyCrashForTesting bool
 	// Insert a probability of random errors during calls to the master.
 	ChaosChance float64
+
+	// The flag set the server was registered on, used to tell which flags
+	// were set explicitly when applying the configuration file.
+	flagSet *pflag.FlagSet
+
+	// Client certificate and key obtained through the bootstrap flow, used
+	// instead of any credentials from --auth_path.
+	clientCertFile string
+	clientKeyFile  string
 }
 
 // bootstrapping interface for kubelet, targets the initialization protocol
 type KubeletBootstrap interface {
 	BirthCry()
 	StartGarbageCollection()
-	ListenAndServe(net.IP, uint, *kubelet.TLSOptions, bool)
+	ListenAndServe(net.IP, uint, *kubelet.TLSOptions, kubelet.AuthInterface, bool)
 	ListenAndServeReadOnly(net.IP, uint)
 	Run(<-chan kubelet.PodUpdate)
 	RunOnce(<-chan kubelet.PodUpdate) ([]kubelet.RunPodResult, error)
+	UpdateImageGCPolicy(highThresholdPercent, lowThresholdPercent *int) error
 }
 
 // create and initialize a Kubelet instance
@@ -123,39 +168,55 @@ type KubeletBuilder func(kc *KubeletConfig) (KubeletBootstrap, *config.PodConfig
 // NewKubeletServer will create a new KubeletServer with default values.
 func NewKubeletServer() *KubeletServer {
 	return &KubeletServer{
-		SyncFrequency:               10 * time.Second,
-		FileCheckFrequency:          20 * time.Second,
-		HTTPCheckFrequency:          20 * time.Second,
-		EnableServer:                true,
-		Address:                     util.IP(net.ParseIP("0.0.0.0")),
-		Port:                        ports.KubeletPort,
-		ReadOnlyPort:                ports.KubeletReadOnlyPort,
-		PodInfraContainerImage:      dockertools.PodInfraContainerImage,
-		RootDirectory:               defaultRootDir,
-		RegistryBurst:               10,
-		EnableDebuggingHandlers:     true,
-		MinimumGCAge:                1 * time.Minute,
-		MaxPerPodContainerCount:     5,
-		MaxContainerCount:           100,
-		AuthPath:                    "/var/lib/kubelet/kubernetes_auth",
-		CadvisorPort:                4194,
-		HealthzPort:                 10248,
-		HealthzBindAddress:          util.IP(net.ParseIP("127.0.0.1")),
-		OOMScoreAdj:                 -900,
-		MasterServiceNamespace:      api.NamespaceDefault,
-		ImageGCHighThresholdPercent: 90,
-		ImageGCLowThresholdPercent:  80,
-		NetworkPluginName:           "",
-		HostNetworkSources:          kubelet.FileSource,
-		CertDirectory:               "/var/run/kubernetes",
-		NodeStatusUpdateFrequency:   10 * time.Second,
-		ResourceContainer:           "/kubelet",
+		SyncFrequency:                 10 * time.Second,
+		FileCheckFrequency:            20 * time.Second,
+		HTTPCheckFrequency:            20 * time.Second,
+		EnableServer:                  true,
+		Address:                       util.IP(net.ParseIP("0.0.0.0")),
+		Port:                          ports.KubeletPort,
+		ReadOnlyPort:                  ports.KubeletReadOnlyPort,
+		PodInfraContainerImage:        dockertools.DefaultPodInfraContainerImage(),
+		RootDirectory:                 defaultRootDir,
+		RegistryBurst:                 10,
+		EnableDebuggingHandlers:       true,
+		MinimumGCAge:                  1 * time.Minute,
+		MaxPerPodContainerCount:       5,
+		MaxContainerCount:             100,
+		AuthPath:                      "/var/lib/kubelet/kubernetes_auth",
+		CadvisorPort:                  4194,
+		HealthzPort:                   10248,
+		HealthzBindAddress:            util.IP(net.ParseIP("127.0.0.1")),
+		OOMScoreAdj:                   -900,
+		MasterServiceNamespace:        api.NamespaceDefault,
+		ImageGCHighThresholdPercent:   90,
+		ImageGCLowThresholdPercent:    80,
+		NetworkPluginName:             "",
+		HostNetworkSources:            kubelet.FileSource,
+		CertDirectory:                 "/var/run/kubernetes",
+		NodeStatusUpdateFrequency:     10 * time.Second,
+		NodeStatusFullUpdateFrequency: 1 * time.Minute,
+		RegisterNode:                  true,
+		RegisterSchedulable:           true,
+		ResourceContainer:             "/kubelet",
+		MaxConcurrentPodSyncs:         10,
+		SharedPIDNamespace:            false,
+		DockerExecHandlerName:         "native",
+		PostStartFailurePolicy:        "fail-container",
+		ContainerLogMaxSize:           "10Mi",
+		ContainerLogMaxFiles:          5,
+		EnableServiceLinks:            true,
+		PodInfraShmSize:               "64Mi",
+		MaxPods:                       40,
+		MigrateDataDirs:               true,
+		NodeProblemConfig:             "",
 	}
 }
 
 // AddFlags adds flags for a specific KubeletServer to the specified FlagSet
 func (s *KubeletServer) AddFlags(fs *pflag.FlagSet) {
+	s.flagSet = fs
 	fs.StringVar(&s.Config, "config", s.Config, "Path to the config file or directory of files")
+	fs.StringVar(&s.KubeletConfigFile, "kubelet_config", s.KubeletConfigFile, "Path to a versioned KubeletConfiguration file (YAML or JSON). Values from the file are overridden by any corresponding flags set explicitly on the command line.")
 	fs.DurationVar(&s.SyncFrequency, "sync_frequency", s.SyncFrequency, "Max period between synchronizing running containers and config")
 	fs.DurationVar(&s.FileCheckFrequency, "file_check_frequency", s.FileCheckFrequency, "Duration between checking config files for new data")
 	fs.DurationVar(&s.HTTPCheckFrequency, "http_check_frequency", s.HTTPCheckFrequency, "Duration between checking http for new data")
@@ -172,20 +233,25 @@ func (s *KubeletServer) AddFlags(fs *pflag.FlagSet) {
 	fs.StringVar(&s.CertDirectory, "cert_dir", s.CertDirectory, "The directory where the TLS certs are located (by default /var/run/kubernetes). "+
 		"If --tls_cert_file and --tls_private_key_file are provided, this flag will be ignored.")
 	fs.StringVar(&s.HostnameOverride, "hostname_override", s.HostnameOverride, "If non-empty, will use this string as identification instead of the actual hostname.")
-	fs.StringVar(&s.PodInfraContainerImage, "pod_infra_container_image", s.PodInfraContainerImage, "The image whose network/ipc namespaces containers in each pod will use.")
+	fs.StringVar(&s.PodInfraContainerImage, "pod_infra_container_image", s.PodInfraContainerImage, "The image whose network/ipc namespaces containers in each pod will use. The default depends on the build architecture.")
 	fs.StringVar(&s.DockerEndpoint, "docker_endpoint", s.DockerEndpoint, "If non-empty, use this for the docker endpoint to communicate with")
 	fs.StringVar(&s.RootDirectory, "root_dir", s.RootDirectory, "Directory path for managing kubelet files (volume mounts,etc).")
 	fs.BoolVar(&s.AllowPrivileged, "allow_privileged", s.AllowPrivileged, "If true, allow containers to request privileged mode. [default=false]")
 	fs.StringVar(&s.HostNetworkSources, "host_network_sources", s.HostNetworkSources, "Comma-separated list of sources from which the Kubelet allows pods to use of host network. For all sources use \"*\" [default=\"file\"]")
 	fs.Float64Var(&s.RegistryPullQPS, "registry_qps", s.RegistryPullQPS, "If > 0, limit registry pull QPS to this value.  If 0, unlimited. [default=0.0]")
 	fs.IntVar(&s.RegistryBurst, "registry_burst", s.RegistryBurst, "Maximum size of a bursty pulls, temporarily allows pulls to burst to this number, while still not exceeding registry_qps.  Only used if --registry_qps > 0")
+	fs.DurationVar(&s.ImagePullTimeout, "image_pull_timeout", s.ImagePullTimeout, "If non-zero, image pulls that have not finished within this duration fail and are retried on the next sync.  0 means pulls never time out. [default=0]")
+	fs.Var(&s.RegistryRewrites, "registry_rewrites", "Registry rewrite rules applied before pulling images, as original=mirror pairs (e.g. docker.io=mirror.example.com). May be specified multiple times or comma separated")
+	fs.BoolVar(&s.AlwaysPullImages, "always_pull_images", s.AlwaysPullImages, "If true, pull images before every container start regardless of the pod's image pull policy, so a cached image can never be run by a pod that could not have pulled it")
 	fs.BoolVar(&s.RunOnce, "runonce", s.RunOnce, "If true, exit after spawning pods from local manifests or remote urls. Exclusive with --api_servers, and --enable-server")
 	fs.BoolVar(&s.EnableDebuggingHandlers, "enable_debugging_handlers", s.EnableDebuggingHandlers, "Enables server endpoints for log collection and local running of containers and commands")
 	fs.DurationVar(&s.MinimumGCAge, "minimum_container_ttl_duration", s.MinimumGCAge, "Minimum age for a finished container before it is garbage collected.  Examples: '300ms', '10s' or '2h45m'")
 	fs.IntVar(&s.MaxPerPodContainerCount, "maximum_dead_containers_per_container", s.MaxPerPodContainerCount, "Maximum number of old instances of a container to retain per container.  Each container takes up some disk space.  Default: 5.")
 	fs.IntVar(&s.MaxContainerCount, "maximum_dead_containers", s.MaxContainerCount, "Maximum number of old instances of a containers to retain globally.  Each container takes up some disk space.  Default: 100.")
 	fs.StringVar(&s.AuthPath, "auth_path", s.AuthPath, "Path to .kubernetes_auth file, specifying how to authenticate to API server.")
-	fs.UintVar(&s.CadvisorPort, "cadvisor_port", s.CadvisorPort, "The port of the localhost cAdvisor endpoint")
+	fs.StringVar(&s.BootstrapAuthPath, "bootstrap_auth_path", s.BootstrapAuthPath, "Path to a .kubernetes_auth file with bootstrap credentials (e.g. a bootstrap bearer token), used only to request the kubelet's client certificate. Requires --certificate_signing_url.")
+	fs.StringVar(&s.CertificateSigningURL, "certificate_signing_url", s.CertificateSigningURL, "If set, request the kubelet's API server client certificate by posting a PEM certificate signing request to this endpoint, persist it under --root_dir and rotate it before expiry, instead of requiring a pre-distributed certificate.")
+	fs.UintVar(&s.CadvisorPort, "cadvisor_port", s.CadvisorPort, "The port of the localhost cAdvisor endpoint. 0 disables the embedded cAdvisor entirely.")
 	fs.IntVar(&s.HealthzPort, "healthz_port", s.HealthzPort, "The port of the localhost healthz endpoint")
 	fs.Var(&s.HealthzBindAddress, "healthz_bind_address", "The IP address for the healthz server to serve on, defaulting to 127.0.0.1 (set to 0.0.0.0 for all interfaces)")
 	fs.IntVar(&s.OOMScoreAdj, "oom_score_adj", s.OOMScoreAdj, "The oom_score_adj value for kubelet process. Values must be within the range [-1000, 1000]")
@@ -193,14 +259,34 @@ func (s *KubeletServer) AddFlags(fs *pflag.FlagSet) {
 	fs.StringVar(&s.ClusterDomain, "cluster_domain", s.ClusterDomain, "Domain for this cluster.  If set, kubelet will configure all containers to search this domain in addition to the host's search domains")
 	fs.StringVar(&s.MasterServiceNamespace, "master_service_namespace", s.MasterServiceNamespace, "The namespace from which the kubernetes master services should be injected into pods")
 	fs.Var(&s.ClusterDNS, "cluster_dns", "IP address for a cluster DNS server.  If set, kubelet will configure all containers to use this for DNS resolution in addition to the host's DNS servers")
+	fs.Var(&s.NodeIP, "node_ip", "IP address of the node. If set, kubelet will use this IP address for the node instead of resolving the hostname or asking the cloud provider")
+	fs.BoolVar(&s.RegisterNode, "register_node", s.RegisterNode, "Register the node with the apiserver (requires --api_servers)")
+	fs.Var(&s.NodeLabels, "node_labels", "Labels to add when registering the node in the cluster, as key=value pairs. May be specified multiple times or comma separated")
+	fs.Var(&s.NodeTaints, "node_taints", "Taints to add when registering the node in the cluster, as key=value:Effect triples. May be specified multiple times or comma separated")
+	fs.BoolVar(&s.RegisterSchedulable, "register_schedulable", s.RegisterSchedulable, "Register the node as schedulable. No-op if register_node is false")
 	fs.DurationVar(&s.StreamingConnectionIdleTimeout, "streaming_connection_idle_timeout", 0, "Maximum time a streaming connection can be idle before the connection is automatically closed.  Example: '5m'")
 	fs.DurationVar(&s.NodeStatusUpdateFrequency, "node_status_update_frequency", s.NodeStatusUpdateFrequency, "Specifies how often kubelet posts node status to master. Note: be cautious when changing the constant, it must work with nodeMonitorGracePeriod in nodecontroller. Default: 10s")
+	fs.DurationVar(&s.NodeStatusFullUpdateFrequency, "node_status_full_update_frequency", s.NodeStatusFullUpdateFrequency, "Specifies how often kubelet posts the full node status to master; between full updates only the readiness heartbeat is refreshed. Default: 1m")
 	fs.IntVar(&s.ImageGCHighThresholdPercent, "image_gc_high_threshold", s.ImageGCHighThresholdPercent, "The percent of disk usage after which image garbage collection is always run. Default: 90%%")
 	fs.IntVar(&s.ImageGCLowThresholdPercent, "image_gc_low_threshold", s.ImageGCLowThresholdPercent, "The percent of disk usage before which image garbage collection is never run. Lowest disk usage to garbage collect to. Default: 80%%")
 	fs.StringVar(&s.NetworkPluginName, "network_plugin", s.NetworkPluginName, "<Warning: Alpha feature> The name of the network plugin to be invoked for various events in kubelet/pod lifecycle")
 	fs.StringVar(&s.CloudProvider, "cloud_provider", s.CloudProvider, "The provider for cloud services.  Empty string for no provider.")
 	fs.StringVar(&s.CloudConfigFile, "cloud_config", s.CloudConfigFile, "The path to the cloud provider configuration file.  Empty string for no configuration file.")
 	fs.StringVar(&s.ResourceContainer, "resource_container", s.ResourceContainer, "Absolute name of the resource-only container to create and run the Kubelet in (Default: /kubelet).")
+	fs.IntVar(&s.MaxConcurrentPodSyncs, "max_concurrent_pod_syncs", s.MaxConcurrentPodSyncs, "Maximum number of pod syncs to run concurrently. 0 for no limit. Default: 10")
+	fs.BoolVar(&s.SharedPIDNamespace, "shared_pid_namespace", s.SharedPIDNamespace, "If true, run all containers of a pod in the pod infra container's PID namespace, letting the pause process reap orphaned zombies. Default: false")
+	fs.StringVar(&s.DockerExecHandlerName, "docker_exec_handler", s.DockerExecHandlerName, "Handler to use when executing a command in a container. Valid values are 'native' and 'nsenter'. Default: 'native'")
+	fs.StringVar(&s.PostStartFailurePolicy, "post_start_failure_policy", s.PostStartFailurePolicy, "Policy applied to a container whose PostStart hook still fails after retries. Valid values are 'fail-container' (kill the container) and 'warn-only' (record the failure and leave the container running). Default: 'fail-container'")
+	fs.StringVar(&s.ContainerLogMaxSize, "container_log_max_size", s.ContainerLogMaxSize, "Size at which a container log file is rotated, e.g. 10Mi. Empty string to disable rotation. Default: 10Mi")
+	fs.IntVar(&s.ContainerLogMaxFiles, "container_log_max_files", s.ContainerLogMaxFiles, "Maximum number of log files kept per container, including the live file. Must be at least 2. Default: 5")
+	fs.BoolVar(&s.EnableServiceLinks, "enable_service_links", s.EnableServiceLinks, "If true, inject env vars describing the services in a pod's namespace, for pods that do not set spec.enableServiceLinks. The master service env vars are always injected. Default: true")
+	fs.Var(&s.AllowedUnsafeSysctls, "allowed_unsafe_sysctls", "Comma separated whitelist of unsafe sysctls or sysctl patterns (ending in *) that pods may request in addition to the safe sysctls. Use with caution.")
+	fs.StringVar(&s.PodInfraShmSize, "pod_infra_shm_size", s.PodInfraShmSize, "Size of a pod's /dev/shm when not every container in the pod has a memory limit, e.g. 64Mi. Empty string to keep the docker default. Default: 64Mi")
+	fs.IntVar(&s.MaxPods, "max_pods", s.MaxPods, "Number of pods that can run on this node. Published as the node's pod capacity. 0 for no limit. Default: 40")
+	fs.BoolVar(&s.MigrateDataDirs, "migrate_data_dirs", s.MigrateDataDirs, "If true, move pod data directories written by pre-\"pods/\" kubelets into the current layout at startup. Default: true")
+	fs.StringVar(&s.NodeProblemConfig, "node_problem_config", s.NodeProblemConfig, "Path to a JSON file describing log patterns to report as node conditions and events. Empty disables node problem detection.")
+	fs.StringVar(&s.AuthnTokenWebhookURL, "authentication_token_webhook_url", s.AuthnTokenWebhookURL, "If set, authenticate bearer tokens on the Kubelet's API by posting a token review to this API server endpoint.")
+	fs.StringVar(&s.AuthzWebhookURL, "authorization_webhook_url", s.AuthzWebhookURL, "If set, authorize requests to the Kubelet's API by posting a subject access review to this API server endpoint. Requires --authentication_token_webhook_url.")
 
 	// Flags intended for testing, not recommended used in production environments.
 	fs.BoolVar(&s.ReallyCrashForTesting, "really_crash_for_testing", s.ReallyCrashForTesting, "If true, when panics occur crash. Intended for testing.")
@@ -209,6 +295,19 @@ func (s *KubeletServer) AddFlags(fs *pflag.FlagSet) {
 
 // Run runs the specified KubeletServer.  This should never exit.
 func (s *KubeletServer) Run(_ []string) error {
+	if s.KubeletConfigFile != "" {
+		conf, err := loadKubeletConfiguration(s.KubeletConfigFile)
+		if err != nil {
+			return fmt.Errorf("invalid kubelet configuration file %q: %v", s.KubeletConfigFile, err)
+		}
+		setFlags := util.NewStringSet()
+		if s.flagSet != nil {
+			s.flagSet.Visit(func(f *pflag.Flag) { setFlags.Insert(f.Name) })
+		}
+		s.applyConfiguration(conf, setFlags)
+	}
+	publishConfigz(*s)
+
 	util.ReallyCrash = s.ReallyCrashForTesting
 	rand.Seed(time.Now().UTC().UnixNano())
 
@@ -217,6 +316,16 @@ func (s *KubeletServer) Run(_ []string) error {
 		glog.Info(err)
 	}
 
+	if s.CertificateSigningURL != "" {
+		hostname := util.GetHostname(s.HostnameOverride)
+		certFile, keyFile, err := s.bootstrapClientCertificate(hostname)
+		if err != nil {
+			return fmt.Errorf("unable to bootstrap client certificate: %v", err)
+		}
+		s.clientCertFile, s.clientKeyFile = certFile, keyFile
+		go util.Forever(func() { s.rotateClientCertificate(hostname) }, certificateRotationCheckPeriod)
+	}
+
 	client, err := s.createAPIServerClient()
 	if err != nil && len(s.APIServerList) > 0 {
 		glog.Warningf("No API client: %v", err)
@@ -226,9 +335,15 @@ func (s *KubeletServer) Run(_ []string) error {
 
 	credentialprovider.SetPreferredDockercfgPath(s.RootDirectory)
 
-	cadvisorInterface, err := cadvisor.New(s.CadvisorPort)
-	if err != nil {
-		return err
+	var cadvisorInterface cadvisor.Interface
+	if s.CadvisorPort > 0 {
+		cadvisorInterface, err = cadvisor.New(s.CadvisorPort)
+		if err != nil {
+			return err
+		}
+	} else {
+		glog.Infof("cAdvisor is disabled; container stats will not be available")
+		cadvisorInterface = cadvisor.NewDisabled()
 	}
 
 	imageGCPolicy := kubelet.ImageGCPolicy{
@@ -244,6 +359,12 @@ func (s *KubeletServer) Run(_ []string) error {
 		return err
 	}
 
+	auth, err := s.newKubeletAuth()
+	if err != nil {
+		return err
+	}
+
+	selfSignedCert := false
 	if s.TLSCertFile == "" && s.TLSPrivateKeyFile == "" {
 		s.TLSCertFile = path.Join(s.CertDirectory, "kubelet.crt")
 		s.TLSPrivateKeyFile = path.Join(s.CertDirectory, "kubelet.key")
@@ -251,6 +372,11 @@ func (s *KubeletServer) Run(_ []string) error {
 			glog.Fatalf("Unable to generate self signed cert: %v", err)
 		}
 		glog.Infof("Using self-signed cert (%s, %s)", s.TLSCertFile, s.TLSPrivateKeyFile)
+		selfSignedCert = true
+	}
+	servingCert, err := kubelet.NewReloadableCertificate(s.TLSCertFile, s.TLSPrivateKeyFile)
+	if err != nil {
+		return fmt.Errorf("unable to load TLS certificate: %v", err)
 	}
 	tlsOptions := &kubelet.TLSOptions{
 		Config: &tls.Config{
@@ -258,9 +384,69 @@ func (s *KubeletServer) Run(_ []string) error {
 			MinVersion: tls.VersionTLS10,
 			// Populate PeerCertificates in requests, but don't yet reject connections without certificates.
 			ClientAuth: tls.RequestClientCert,
+			// Served dynamically so a renewed certificate is picked up
+			// without restarting the listener.
+			GetCertificate: servingCert.GetCertificate,
 		},
-		CertFile: s.TLSCertFile,
-		KeyFile:  s.TLSPrivateKeyFile,
+	}
+	// Renew the serving certificate before it expires when the kubelet owns
+	// it: either it is self-signed, or a signing endpoint is configured.
+	// Operator-distributed certificates are left alone.
+	if selfSignedCert || s.CertificateSigningURL != "" {
+		go util.Forever(func() { s.rotateServingCertificate(servingCert) }, certificateRotationCheckPeriod)
+	}
+
+	nodeLabels, err := parseNodeLabels(s.NodeLabels)
+	if err != nil {
+		return err
+	}
+	nodeTaints, err := parseNodeTaints(s.NodeTaints)
+	if err != nil {
+		return err
+	}
+	registryRewrites, err := parseRegistryRewrites(s.RegistryRewrites)
+	if err != nil {
+		return err
+	}
+
+	containerLogMaxSize := int64(0)
+	if s.ContainerLogMaxSize != "" {
+		quantity, err := resource.ParseQuantity(s.ContainerLogMaxSize)
+		if err != nil {
+			return fmt.Errorf("invalid container log max size %q: %v", s.ContainerLogMaxSize, err)
+		}
+		containerLogMaxSize = quantity.Value()
+	}
+
+	podInfraShmSize := int64(0)
+	if s.PodInfraShmSize != "" {
+		quantity, err := resource.ParseQuantity(s.PodInfraShmSize)
+		if err != nil {
+			return fmt.Errorf("invalid pod infra shm size %q: %v", s.PodInfraShmSize, err)
+		}
+		podInfraShmSize = quantity.Value()
+	}
+
+	var dockerExecHandler dockertools.ExecHandler
+	switch s.DockerExecHandlerName {
+	case "native":
+		dockerExecHandler = &dockertools.NativeExecHandler{}
+	case "nsenter":
+		dockerExecHandler = &dockertools.NsenterExecHandler{}
+	default:
+		glog.Warningf("Unknown Docker exec handler %q; defaulting to native", s.DockerExecHandlerName)
+		dockerExecHandler = &dockertools.NativeExecHandler{}
+	}
+
+	var postStartFailurePolicy dockertools.HookFailurePolicy
+	switch s.PostStartFailurePolicy {
+	case "fail-container":
+		postStartFailurePolicy = dockertools.HookFailureFailContainer
+	case "warn-only":
+		postStartFailurePolicy = dockertools.HookFailureWarnOnly
+	default:
+		glog.Warningf("Unknown PostStart failure policy %q; defaulting to fail-container", s.PostStartFailurePolicy)
+		postStartFailurePolicy = dockertools.HookFailureFailContainer
 	}
 
 	kcfg := KubeletConfig{
@@ -270,6 +456,7 @@ func (s *KubeletServer) Run(_ []string) error {
 		HostnameOverride:               s.HostnameOverride,
 		RootDirectory:                  s.RootDirectory,
 		ConfigFile:                     s.Config,
+		KubeletConfigFile:              s.KubeletConfigFile,
 		ManifestURL:                    s.ManifestURL,
 		FileCheckFrequency:             s.FileCheckFrequency,
 		HTTPCheckFrequency:             s.HTTPCheckFrequency,
@@ -277,11 +464,19 @@ func (s *KubeletServer) Run(_ []string) error {
 		SyncFrequency:                  s.SyncFrequency,
 		RegistryPullQPS:                s.RegistryPullQPS,
 		RegistryBurst:                  s.RegistryBurst,
+		ImagePullTimeout:               s.ImagePullTimeout,
+		RegistryRewrites:               registryRewrites,
+		AlwaysPullImages:               s.AlwaysPullImages,
 		MinimumGCAge:                   s.MinimumGCAge,
 		MaxPerPodContainerCount:        s.MaxPerPodContainerCount,
 		MaxContainerCount:              s.MaxContainerCount,
 		ClusterDomain:                  s.ClusterDomain,
 		ClusterDNS:                     s.ClusterDNS,
+		NodeIP:                         s.NodeIP,
+		RegisterNode:                   s.RegisterNode,
+		NodeLabels:                     nodeLabels,
+		NodeTaints:                     nodeTaints,
+		RegisterSchedulable:            s.RegisterSchedulable,
 		Runonce:                        s.RunOnce,
 		Port:                           s.Port,
 		ReadOnlyPort:                   s.ReadOnlyPort,
@@ -296,10 +491,24 @@ func (s *KubeletServer) Run(_ []string) error {
 		NetworkPluginName:              s.NetworkPluginName,
 		StreamingConnectionIdleTimeout: s.StreamingConnectionIdleTimeout,
 		TLSOptions:                     tlsOptions,
+		Auth:                           auth,
 		ImageGCPolicy:                  imageGCPolicy,
 		Cloud:                          cloud,
-		NodeStatusUpdateFrequency: s.NodeStatusUpdateFrequency,
-		ResourceContainer:         s.ResourceContainer,
+		NodeStatusUpdateFrequency:      s.NodeStatusUpdateFrequency,
+		NodeStatusFullUpdateFrequency:  s.NodeStatusFullUpdateFrequency,
+		ResourceContainer:              s.ResourceContainer,
+		MaxConcurrentPodSyncs:          s.MaxConcurrentPodSyncs,
+		SharedPIDNamespace:             s.SharedPIDNamespace,
+		DockerExecHandler:              dockerExecHandler,
+		PostStartFailurePolicy:         postStartFailurePolicy,
+		ContainerLogMaxSize:            containerLogMaxSize,
+		ContainerLogMaxFiles:           s.ContainerLogMaxFiles,
+		EnableServiceLinks:             s.EnableServiceLinks,
+		AllowedUnsafeSysctls:           s.AllowedUnsafeSysctls,
+		PodInfraShmSize:                podInfraShmSize,
+		MaxPods:                        s.MaxPods,
+		MigrateDataDirs:                s.MigrateDataDirs,
+		NodeProblemConfig:              s.NodeProblemConfig,
 	}
 
 	RunKubelet(&kcfg, nil)
@@ -319,6 +528,25 @@ func (s *KubeletServer) Run(_ []string) error {
 
 }
 
+// newKubeletAuth creates the authenticator/authorizer for the Kubelet's API
+// from the webhook flags, or returns nil if delegated auth is not configured.
+func (s *KubeletServer) newKubeletAuth() (kubelet.AuthInterface, error) {
+	if s.AuthnTokenWebhookURL == "" {
+		if s.AuthzWebhookURL != "" {
+			return nil, fmt.Errorf("--authorization_webhook_url requires --authentication_token_webhook_url")
+		}
+		return nil, nil
+	}
+	authn := bearertoken.New(webhooktoken.New(s.AuthnTokenWebhookURL))
+	var authz authorizer.Authorizer
+	if s.AuthzWebhookURL != "" {
+		authz = webhookauthz.New(s.AuthzWebhookURL)
+	} else {
+		authz = apiserver.NewAlwaysAllowAuthorizer()
+	}
+	return kubelet.NewKubeletAuth(authn, authz), nil
+}
+
 // TODO: replace this with clientcmd
 func (s *KubeletServer) createAPIServerClient() (*client.Client, error) {
 	authInfo, err := clientauth.LoadFromFile(s.AuthPath)
@@ -329,6 +557,11 @@ func (s *KubeletServer) createAPIServerClient() (*client.Client, error) {
 		// authInfo didn't load correctly - continue with defaults.
 		authInfo = &clientauth.Info{}
 	}
+	if s.clientCertFile != "" {
+		// Prefer the certificate obtained through the bootstrap flow.
+		authInfo.CertFile = s.clientCertFile
+		authInfo.KeyFile = s.clientKeyFile
+	}
 	clientConfig, err := authInfo.MergeWithConfig(client.Config{})
 	if err != nil {
 		return nil, err
@@ -382,32 +615,42 @@ func SimpleKubelet(client *client.Client,
 		LowThresholdPercent:  80,
 	}
 	kcfg := KubeletConfig{
-		KubeClient:             client,
-		DockerClient:           dockerClient,
-		HostnameOverride:       hostname,
-		RootDirectory:          rootDir,
-		ManifestURL:            manifestURL,
-		PodInfraContainerImage: dockertools.PodInfraContainerImage,
-		Port:                    port,
-		Address:                 util.IP(net.ParseIP(address)),
-		EnableServer:            true,
-		EnableDebuggingHandlers: true,
-		HTTPCheckFrequency:      1 * time.Second,
-		FileCheckFrequency:      1 * time.Second,
-		SyncFrequency:           3 * time.Second,
-		MinimumGCAge:            10 * time.Second,
-		MaxPerPodContainerCount: 5,
-		MaxContainerCount:       100,
-		MasterServiceNamespace:  masterServiceNamespace,
-		VolumePlugins:           volumePlugins,
-		TLSOptions:              tlsOptions,
-		CadvisorInterface:       cadvisorInterface,
-		ConfigFile:              configFilePath,
-		ImageGCPolicy:           imageGCPolicy,
-		Cloud:                   cloud,
-		NodeStatusUpdateFrequency: 10 * time.Second,
-		ResourceContainer:         "/kubelet",
-		OSInterface:               osInterface,
+		KubeClient:                    client,
+		DockerClient:                  dockerClient,
+		HostnameOverride:              hostname,
+		RootDirectory:                 rootDir,
+		ManifestURL:                   manifestURL,
+		PodInfraContainerImage:        dockertools.DefaultPodInfraContainerImage(),
+		Port:                          port,
+		Address:                       util.IP(net.ParseIP(address)),
+		EnableServer:                  true,
+		EnableDebuggingHandlers:       true,
+		HTTPCheckFrequency:            1 * time.Second,
+		FileCheckFrequency:            1 * time.Second,
+		SyncFrequency:                 3 * time.Second,
+		MinimumGCAge:                  10 * time.Second,
+		MaxPerPodContainerCount:       5,
+		MaxContainerCount:             100,
+		MasterServiceNamespace:        masterServiceNamespace,
+		VolumePlugins:                 volumePlugins,
+		TLSOptions:                    tlsOptions,
+		CadvisorInterface:             cadvisorInterface,
+		ConfigFile:                    configFilePath,
+		ImageGCPolicy:                 imageGCPolicy,
+		Cloud:                         cloud,
+		NodeStatusUpdateFrequency:     10 * time.Second,
+		NodeStatusFullUpdateFrequency: 1 * time.Minute,
+		ResourceContainer:             "/kubelet",
+		MaxConcurrentPodSyncs:         10,
+		SharedPIDNamespace:            false,
+		DockerExecHandler:             &dockertools.NativeExecHandler{},
+		ContainerLogMaxSize:           10 * 1024 * 1024,
+		ContainerLogMaxFiles:          5,
+		EnableServiceLinks:            true,
+		PodInfraShmSize:               64 * 1024 * 1024,
+		MaxPods:                       40,
+		MigrateDataDirs:               true,
+		OSInterface:                   osInterface,
 	}
 	return &kcfg
 }
@@ -445,15 +688,56 @@ func RunKubelet(kcfg *KubeletConfig, builder KubeletBuilder) {
 	}
 	// process pods and exit.
 	if kcfg.Runonce {
-		if _, err := k.RunOnce(podCfg.Updates()); err != nil {
+		results, err := k.RunOnce(podCfg.Updates())
+		if err != nil {
 			glog.Errorf("--runonce failed: %v", err)
 		}
+		os.Exit(writeRunOnceSummary(os.Stdout, results, err))
 	} else {
 		startKubelet(k, podCfg, kcfg)
+		if kcfg.KubeletConfigFile != "" {
+			startConfigurationReloader(kcfg.KubeletConfigFile, k)
+		}
 	}
 	glog.Infof("Started kubelet")
 }
 
+// runOnceSummary is the machine readable report printed on stdout after a
+// --runonce pass, one entry per pod.
+type runOnceSummary struct {
+	Name      string `json:"name"`
+	Namespace string `json:"namespace"`
+	Succeeded bool   `json:"succeeded"`
+	Error     string `json:"error,omitempty"`
+}
+
+// writeRunOnceSummary prints the per-pod results of a --runonce pass as JSON
+// and returns the exit code for the process: 0 if every pod started or
+// completed successfully, 1 if any pod failed, and 2 if no pods could be
+// processed at all.
+func writeRunOnceSummary(w io.Writer, results []kubelet.RunPodResult, runErr error) int {
+	summary := []runOnceSummary{}
+	code := 0
+	for _, res := range results {
+		entry := runOnceSummary{Name: res.Pod.Name, Namespace: res.Pod.Namespace, Succeeded: res.Err == nil}
+		if res.Err != nil {
+			entry.Error = res.Err.Error()
+			code = 1
+		}
+		summary = append(summary, entry)
+	}
+	if len(results) == 0 && runErr != nil {
+		code = 2
+	}
+	data, err := json.Marshal(summary)
+	if err != nil {
+		glog.Errorf("Unable to marshal --runonce summary: %v", err)
+		return 2
+	}
+	fmt.Fprintln(w, string(data))
+	return code
+}
+
 func startKubelet(k KubeletBootstrap, podCfg *config.PodConfig, kc *KubeletConfig) {
 	// start the kubelet
 	go util.Forever(func() { k.Run(podCfg.Updates()) }, 0)
@@ -461,13 +745,15 @@ func startKubelet(k KubeletBootstrap, podCfg *config.PodConfig, kc *KubeletConfi
 	// start the kubelet server
 	if kc.EnableServer {
 		go util.Forever(func() {
-			k.ListenAndServe(net.IP(kc.Address), kc.Port, kc.TLSOptions, kc.EnableDebuggingHandlers)
+			k.ListenAndServe(net.IP(kc.Address), kc.Port, kc.TLSOptions, kc.Auth, kc.EnableDebuggingHandlers)
 		}, 0)
 	}
 	if kc.ReadOnlyPort > 0 {
 		go util.Forever(func() {
 			k.ListenAndServeReadOnly(net.IP(kc.Address), kc.ReadOnlyPort)
 		}, 0)
+	} else {
+		glog.Infof("Read-only port disabled")
 	}
 }
 
@@ -505,6 +791,7 @@ type KubeletConfig struct {
 	HostnameOverride               string
 	RootDirectory                  string
 	ConfigFile                     string
+	KubeletConfigFile              string
 	ManifestURL                    string
 	FileCheckFrequency             time.Duration
 	HTTPCheckFrequency             time.Duration
@@ -513,11 +800,19 @@ type KubeletConfig struct {
 	SyncFrequency                  time.Duration
 	RegistryPullQPS                float64
 	RegistryBurst                  int
+	ImagePullTimeout               time.Duration
 	MinimumGCAge                   time.Duration
 	MaxPerPodContainerCount        int
 	MaxContainerCount              int
 	ClusterDomain                  string
 	ClusterDNS                     util.IP
+	NodeIP                         util.IP
+	RegisterNode                   bool
+	NodeLabels                     map[string]string
+	RegistryRewrites               map[string]string
+	AlwaysPullImages               bool
+	NodeTaints                     []api.Taint
+	RegisterSchedulable            bool
 	EnableServer                   bool
 	EnableDebuggingHandlers        bool
 	Port                           uint
@@ -530,13 +825,82 @@ type KubeletConfig struct {
 	StreamingConnectionIdleTimeout time.Duration
 	Recorder                       record.EventRecorder
 	TLSOptions                     *kubelet.TLSOptions
+	Auth                           kubelet.AuthInterface
 	ImageGCPolicy                  kubelet.ImageGCPolicy
 	Cloud                          cloudprovider.Interface
 	NodeStatusUpdateFrequency      time.Duration
+	NodeStatusFullUpdateFrequency  time.Duration
 	ResourceContainer              string
+	MaxConcurrentPodSyncs          int
+	SharedPIDNamespace             bool
+	DockerExecHandler              dockertools.ExecHandler
+	PostStartFailurePolicy         dockertools.HookFailurePolicy
+	ContainerLogMaxSize            int64
+	ContainerLogMaxFiles           int
+	EnableServiceLinks             bool
+	AllowedUnsafeSysctls           []string
+	PodInfraShmSize                int64
+	MaxPods                        int
+	MigrateDataDirs                bool
+	NodeProblemConfig              string
 	OSInterface                    kubelet.OSInterface
 }
 
+// parseNodeLabels converts a list of key=value pairs into a label map.
+func parseNodeLabels(labels []string) (map[string]string, error) {
+	if len(labels) == 0 {
+		return nil, nil
+	}
+	result := make(map[string]string)
+	for _, label := range labels {
+		parts := strings.SplitN(label, "=", 2)
+		if len(parts) != 2 {
+			return nil, fmt.Errorf("malformed node label %q, expected key=value", label)
+		}
+		result[parts[0]] = parts[1]
+	}
+	return result, nil
+}
+
+// parseRegistryRewrites converts a list of original=mirror pairs into a
+// registry rewrite map.
+func parseRegistryRewrites(rewrites []string) (map[string]string, error) {
+	if len(rewrites) == 0 {
+		return nil, nil
+	}
+	result := make(map[string]string)
+	for _, rewrite := range rewrites {
+		parts := strings.SplitN(rewrite, "=", 2)
+		if len(parts) != 2 {
+			return nil, fmt.Errorf("malformed registry rewrite %q, expected original=mirror", rewrite)
+		}
+		result[parts[0]] = parts[1]
+	}
+	return result, nil
+}
+
+// parseNodeTaints converts a list of key=value:Effect triples into taints.
+func parseNodeTaints(taints []string) ([]api.Taint, error) {
+	var result []api.Taint
+	for _, taint := range taints {
+		parts := strings.SplitN(taint, "=", 2)
+		if len(parts) != 2 {
+			return nil, fmt.Errorf("malformed node taint %q, expected key=value:Effect", taint)
+		}
+		valueEffect := strings.SplitN(parts[1], ":", 2)
+		if len(valueEffect) != 2 {
+			return nil, fmt.Errorf("malformed node taint %q, expected key=value:Effect", taint)
+		}
+		switch effect := api.TaintEffect(valueEffect[1]); effect {
+		case api.TaintEffectNoSchedule, api.TaintEffectPreferNoSchedule, api.TaintEffectNoExecute:
+			result = append(result, api.Taint{Key: parts[0], Value: valueEffect[0], Effect: effect})
+		default:
+			return nil, fmt.Errorf("invalid taint effect %q in %q", effect, taint)
+		}
+	}
+	return result, nil
+}
+
 func createAndInitKubelet(kc *KubeletConfig) (k KubeletBootstrap, pc *config.PodConfig, err error) {
 	// TODO: block until all sources have delivered at least one update to the channel, or break the sync loop
 	// up into "per source" synchronizations
@@ -564,6 +928,9 @@ func createAndInitKubelet(kc *KubeletConfig) (k KubeletBootstrap, pc *config.Pod
 		kc.SyncFrequency,
 		float32(kc.RegistryPullQPS),
 		kc.RegistryBurst,
+		kc.ImagePullTimeout,
+		kc.RegistryRewrites,
+		kc.AlwaysPullImages,
 		gcPolicy,
 		pc.SeenAllSources,
 		kc.ClusterDomain,
@@ -577,9 +944,27 @@ func createAndInitKubelet(kc *KubeletConfig) (k KubeletBootstrap, pc *config.Pod
 		kc.CadvisorInterface,
 		kc.ImageGCPolicy,
 		kc.Cloud,
+		net.IP(kc.NodeIP),
+		kc.RegisterNode,
+		kc.NodeLabels,
+		kc.NodeTaints,
+		kc.RegisterSchedulable,
 		kc.NodeStatusUpdateFrequency,
+		kc.NodeStatusFullUpdateFrequency,
 		kc.ResourceContainer,
-		kc.OSInterface)
+		kc.OSInterface,
+		kc.MaxConcurrentPodSyncs,
+		kc.SharedPIDNamespace,
+		kc.DockerExecHandler,
+		kc.PostStartFailurePolicy,
+		kc.ContainerLogMaxSize,
+		kc.ContainerLogMaxFiles,
+		kc.EnableServiceLinks,
+		kc.AllowedUnsafeSysctls,
+		kc.PodInfraShmSize,
+		kc.MaxPods,
+		kc.MigrateDataDirs,
+		kc.NodeProblemConfig)
 
 	if err != nil {
 		return nil, nil, err
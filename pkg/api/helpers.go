@@ -17,6 +17,7 @@ limitations under the License.
 package api
 
 import (
+	"encoding/json"
 	"reflect"
 
 	"github.com/GoogleCloudPlatform/kubernetes/pkg/api/resource"
@@ -82,7 +83,8 @@ var standardResources = util.NewStringSet(
 	string(ResourceReplicationControllers),
 	string(ResourceSecrets),
 	string(ResourcePersistentVolumeClaims),
-	string(ResourceStorage))
+	string(ResourceStorage),
+	string(ResourceEphemeralStorage))
 
 func IsStandardResourceName(str string) bool {
 	return standardResources.Has(str)
@@ -116,6 +118,81 @@ func IsStandardFinalizerName(str string) bool {
 
 // AddToNodeAddresses appends the NodeAddresses to the passed-by-pointer slice,
 // only if they do not already exist
+const (
+	// TaintsAnnotationKey is the annotation under which a node's taints are
+	// kept while taints are alpha.
+	TaintsAnnotationKey = "scheduler.alpha.kubernetes.io/taints"
+
+	// TolerationsAnnotationKey is the annotation under which a pod's
+	// tolerations are kept while tolerations are alpha.
+	TolerationsAnnotationKey = "scheduler.alpha.kubernetes.io/tolerations"
+
+	// CriticalPodAnnotationKey marks a pod that is critical to the operation
+	// of the node (e.g. kube-proxy). The kubelet admits critical pods ahead
+	// of all others, evicting non-critical pods if necessary to make room.
+	CriticalPodAnnotationKey = "scheduler.alpha.kubernetes.io/critical-pod"
+)
+
+// GetTaintsFromNodeAnnotations returns the taints declared in the given node
+// annotations, or nil if there are none.
+func GetTaintsFromNodeAnnotations(annotations map[string]string) ([]Taint, error) {
+	var taints []Taint
+	if value, found := annotations[TaintsAnnotationKey]; found {
+		if err := json.Unmarshal([]byte(value), &taints); err != nil {
+			return nil, err
+		}
+	}
+	return taints, nil
+}
+
+// GetTolerationsFromPodAnnotations returns the tolerations declared in the
+// given pod annotations, or nil if there are none.
+func GetTolerationsFromPodAnnotations(annotations map[string]string) ([]Toleration, error) {
+	var tolerations []Toleration
+	if value, found := annotations[TolerationsAnnotationKey]; found {
+		if err := json.Unmarshal([]byte(value), &tolerations); err != nil {
+			return nil, err
+		}
+	}
+	return tolerations, nil
+}
+
+// IsCriticalPod returns whether the pod is marked critical to the operation
+// of the node.
+func IsCriticalPod(pod *Pod) bool {
+	_, found := pod.Annotations[CriticalPodAnnotationKey]
+	return found
+}
+
+// TolerationToleratesTaint checks whether the toleration tolerates the taint.
+func TolerationToleratesTaint(toleration Toleration, taint Taint) bool {
+	if len(toleration.Effect) != 0 && toleration.Effect != taint.Effect {
+		return false
+	}
+	if toleration.Key != taint.Key {
+		return false
+	}
+	switch toleration.Operator {
+	case TolerationOpExists:
+		return true
+	// Empty operator means Equal.
+	case TolerationOpEqual, "":
+		return toleration.Value == taint.Value
+	default:
+		return false
+	}
+}
+
+// TaintToleratedByTolerations checks whether any toleration tolerates the taint.
+func TaintToleratedByTolerations(taint Taint, tolerations []Toleration) bool {
+	for _, toleration := range tolerations {
+		if TolerationToleratesTaint(toleration, taint) {
+			return true
+		}
+	}
+	return false
+}
+
 func AddToNodeAddresses(addresses *[]NodeAddress, addAddresses ...NodeAddress) {
 	for _, add := range addAddresses {
 		exists := false
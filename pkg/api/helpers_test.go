@@ -17,6 +17,7 @@ limitations under the License.
 package api
 
 import (
+	"reflect"
 	"strings"
 	"testing"
 
@@ -75,6 +76,7 @@ func TestIsStandardResource(t *testing.T) {
 	}{
 		{"cpu", true},
 		{"memory", true},
+		{"ephemeral-storage", true},
 		{"disk", false},
 		{"blah", false},
 		{"x.y.z", false},
@@ -142,3 +144,70 @@ func TestAddToNodeAddresses(t *testing.T) {
 		}
 	}
 }
+
+func TestTaintToleratedByTolerations(t *testing.T) {
+	taint := Taint{Key: "dedicated", Value: "gpu", Effect: TaintEffectNoExecute}
+	testCases := []struct {
+		tolerations []Toleration
+		tolerated   bool
+	}{
+		{tolerations: nil, tolerated: false},
+		{
+			tolerations: []Toleration{{Key: "dedicated", Operator: TolerationOpEqual, Value: "gpu", Effect: TaintEffectNoExecute}},
+			tolerated:   true,
+		},
+		{
+			// Empty operator defaults to Equal.
+			tolerations: []Toleration{{Key: "dedicated", Value: "gpu", Effect: TaintEffectNoExecute}},
+			tolerated:   true,
+		},
+		{
+			tolerations: []Toleration{{Key: "dedicated", Operator: TolerationOpExists, Effect: TaintEffectNoExecute}},
+			tolerated:   true,
+		},
+		{
+			// Empty effect matches all effects.
+			tolerations: []Toleration{{Key: "dedicated", Operator: TolerationOpExists}},
+			tolerated:   true,
+		},
+		{
+			tolerations: []Toleration{{Key: "dedicated", Operator: TolerationOpEqual, Value: "build", Effect: TaintEffectNoExecute}},
+			tolerated:   false,
+		},
+		{
+			tolerations: []Toleration{{Key: "other", Operator: TolerationOpExists}},
+			tolerated:   false,
+		},
+		{
+			tolerations: []Toleration{{Key: "dedicated", Operator: TolerationOpExists, Effect: TaintEffectNoSchedule}},
+			tolerated:   false,
+		},
+	}
+	for i, tc := range testCases {
+		if tolerated := TaintToleratedByTolerations(taint, tc.tolerations); tolerated != tc.tolerated {
+			t.Errorf("case %d: expected tolerated=%v, got %v", i, tc.tolerated, tolerated)
+		}
+	}
+}
+
+func TestGetTaintsFromNodeAnnotations(t *testing.T) {
+	annotations := map[string]string{
+		TaintsAnnotationKey: `[{"key": "dedicated", "value": "gpu", "effect": "NoSchedule"}]`,
+	}
+	taints, err := GetTaintsFromNodeAnnotations(annotations)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	expected := []Taint{{Key: "dedicated", Value: "gpu", Effect: TaintEffectNoSchedule}}
+	if !reflect.DeepEqual(taints, expected) {
+		t.Errorf("expected %v, got %v", expected, taints)
+	}
+
+	if taints, err := GetTaintsFromNodeAnnotations(nil); err != nil || taints != nil {
+		t.Errorf("expected no taints without the annotation, got %v, %v", taints, err)
+	}
+
+	if _, err := GetTaintsFromNodeAnnotations(map[string]string{TaintsAnnotationKey: "junk"}); err == nil {
+		t.Errorf("expected an error for a malformed annotation")
+	}
+}
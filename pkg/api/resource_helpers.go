@@ -17,6 +17,10 @@ limitations under the License.
 package api
 
 import (
+	"fmt"
+	"math"
+	"strconv"
+
 	"github.com/GoogleCloudPlatform/kubernetes/pkg/api/resource"
 )
 
@@ -41,6 +45,50 @@ func (self *ResourceList) Memory() *resource.Quantity {
 	return &resource.Quantity{}
 }
 
+// Returns the ephemeral storage limit if specified.
+func (self *ResourceList) EphemeralStorage() *resource.Quantity {
+	if val, ok := (*self)[ResourceEphemeralStorage]; ok {
+		return &val
+	}
+	return &resource.Quantity{}
+}
+
+// ExtractContainerResourceValue extracts a resource limit or request of the
+// given container, formatted in terms of the selector's divisor; fractional
+// results are rounded up.
+func ExtractContainerResourceValue(fs *ResourceFieldSelector, container *Container) (string, error) {
+	divisor := fs.Divisor
+	if divisor.Amount == nil {
+		divisor = resource.MustParse("1")
+	}
+
+	switch fs.Resource {
+	case "limits.cpu":
+		return convertResourceCPUToString(container.Resources.Limits.Cpu(), divisor)
+	case "limits.memory":
+		return convertResourceMemoryToString(container.Resources.Limits.Memory(), divisor)
+	case "requests.cpu":
+		return convertResourceCPUToString(container.Resources.Requests.Cpu(), divisor)
+	case "requests.memory":
+		return convertResourceMemoryToString(container.Resources.Requests.Memory(), divisor)
+	}
+	return "", fmt.Errorf("unsupported container resource %q", fs.Resource)
+}
+
+// convertResourceCPUToString converts a cpu quantity to a string in terms of
+// the divisor, rounding up.
+func convertResourceCPUToString(cpu *resource.Quantity, divisor resource.Quantity) (string, error) {
+	c := int64(math.Ceil(float64(cpu.MilliValue()) / float64(divisor.MilliValue())))
+	return strconv.FormatInt(c, 10), nil
+}
+
+// convertResourceMemoryToString converts a memory quantity to a string in
+// terms of the divisor, rounding up.
+func convertResourceMemoryToString(memory *resource.Quantity, divisor resource.Quantity) (string, error) {
+	m := int64(math.Ceil(float64(memory.Value()) / float64(divisor.Value())))
+	return strconv.FormatInt(m, 10), nil
+}
+
 func GetContainerStatus(statuses []ContainerStatus, name string) (ContainerStatus, bool) {
 	for i := range statuses {
 		if statuses[i].Name == name {
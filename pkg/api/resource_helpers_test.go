@@ -51,3 +51,52 @@ func TestResourceHelpers(t *testing.T) {
 		t.Errorf("expected memorylimit %d, got %d", memoryLimit, res)
 	}
 }
+
+func TestExtractContainerResourceValue(t *testing.T) {
+	container := &Container{
+		Resources: ResourceRequirements{
+			Limits: ResourceList{
+				"cpu":    resource.MustParse("2500m"),
+				"memory": resource.MustParse("1Gi"),
+			},
+			Requests: ResourceList{
+				"cpu":    resource.MustParse("250m"),
+				"memory": resource.MustParse("64Mi"),
+			},
+		},
+	}
+
+	cases := []struct {
+		resource string
+		divisor  string
+		expected string
+	}{
+		{"limits.cpu", "", "3"},
+		{"limits.cpu", "1m", "2500"},
+		{"limits.memory", "", "1073741824"},
+		{"limits.memory", "1Mi", "1024"},
+		{"requests.cpu", "", "1"},
+		{"requests.cpu", "1m", "250"},
+		{"requests.memory", "", "67108864"},
+		{"requests.memory", "1Mi", "64"},
+	}
+
+	for _, tc := range cases {
+		fs := &ResourceFieldSelector{Resource: tc.resource}
+		if tc.divisor != "" {
+			fs.Divisor = resource.MustParse(tc.divisor)
+		}
+		value, err := ExtractContainerResourceValue(fs, container)
+		if err != nil {
+			t.Errorf("%s (divisor %q): unexpected error: %v", tc.resource, tc.divisor, err)
+			continue
+		}
+		if value != tc.expected {
+			t.Errorf("%s (divisor %q): expected %q, got %q", tc.resource, tc.divisor, tc.expected, value)
+		}
+	}
+
+	if _, err := ExtractContainerResourceValue(&ResourceFieldSelector{Resource: "limits.disk"}, container); err == nil {
+		t.Errorf("expected an error for an unsupported resource")
+	}
+}
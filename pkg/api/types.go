@@ -508,6 +508,40 @@ type EnvVar struct {
 	Name string `json:"name"`
 	// Optional: defaults to "".
 	Value string `json:"value,omitempty"`
+	// Optional: Specifies a source the value of this var should come from.
+	ValueFrom *EnvVarSource `json:"valueFrom,omitempty"`
+}
+
+// EnvVarSource represents a source for the value of an EnvVar.
+// Only one of its fields may be set.
+type EnvVarSource struct {
+	// Selects a resource of the container: only resources limits and
+	// requests (limits.cpu, limits.memory, requests.cpu and requests.memory)
+	// are currently supported.
+	ResourceFieldRef *ResourceFieldSelector `json:"resourceFieldRef,omitempty"`
+	// Selects a key of a secret in the pod's namespace.
+	SecretKeyRef *SecretKeySelector `json:"secretKeyRef,omitempty"`
+}
+
+// ResourceFieldSelector represents container resources (cpu, memory) and
+// their output format.
+type ResourceFieldSelector struct {
+	// Optional: the name of the container whose resources to select;
+	// defaults to the container declaring the env var.
+	ContainerName string `json:"containerName,omitempty"`
+	// Required: resource to select.
+	Resource string `json:"resource"`
+	// Specifies the output format of the exposed resources, defaults to "1".
+	Divisor resource.Quantity `json:"divisor,omitempty"`
+}
+
+// SecretKeySelector selects a key of a Secret.
+type SecretKeySelector struct {
+	// Required: The name of the secret in the pod's namespace to select from.
+	Name string `json:"name"`
+	// Required: The key of the secret to select from.  Must be a valid
+	// DNS_SUBDOMAIN.
+	Key string `json:"key"`
 }
 
 // HTTPGetAction describes an action based on HTTP Get requests.
@@ -533,6 +567,12 @@ type ExecAction struct {
 	// not run inside a shell, so traditional shell instructions ('|', etc) won't work.  To use
 	// a shell, you need to explicitly call out to that shell.
 	Command []string `json:"command,omitempty"`
+	// Optional: Additional environment variables set for the command, on top
+	// of the container's own (expanded) environment.
+	Env []EnvVar `json:"env,omitempty"`
+	// Optional: Working directory the command is run in. Defaults to the
+	// container's working directory.
+	WorkingDir string `json:"workingDir,omitempty"`
 }
 
 // Probe describes a liveness probe to be examined to the container.
@@ -576,6 +616,18 @@ type ResourceRequirements struct {
 	Requests ResourceList `json:"requests,omitempty"`
 }
 
+// Device maps a host device node into a container, with cgroup device
+// permissions, as an alternative to running the container privileged.
+type Device struct {
+	// Required: path of the device node on the host, e.g. /dev/fuse.
+	HostPath string `json:"hostPath"`
+	// Path of the device inside the container; defaults to HostPath.
+	ContainerPath string `json:"containerPath,omitempty"`
+	// Cgroup permissions for the device, any combination of "r" (read),
+	// "w" (write) and "m" (mknod); defaults to "mrw".
+	Permissions string `json:"permissions,omitempty"`
+}
+
 // Container represents a single container that is expected to be run on the host.
 type Container struct {
 	// Required: This must be a DNS_LABEL.  Each container in a pod must
@@ -594,6 +646,7 @@ type Container struct {
 	// Compute resource requirements.
 	Resources      ResourceRequirements `json:"resources,omitempty"`
 	VolumeMounts   []VolumeMount        `json:"volumeMounts,omitempty"`
+	Devices        []Device             `json:"devices,omitempty"`
 	LivenessProbe  *Probe               `json:"livenessProbe,omitempty"`
 	ReadinessProbe *Probe               `json:"readinessProbe,omitempty"`
 	Lifecycle      *Lifecycle           `json:"lifecycle,omitempty"`
@@ -605,6 +658,31 @@ type Container struct {
 	ImagePullPolicy PullPolicy `json:"imagePullPolicy"`
 	// Optional: Capabilities for container.
 	Capabilities Capabilities `json:"capabilities,omitempty"`
+	// Optional: SecurityContext defines the security options the pod should be run with.
+	SecurityContext *SecurityContext `json:"securityContext,omitempty"`
+	// Optional: Sidecar marks the container as auxiliary to the pod's
+	// primary containers. A sidecar is restarted regardless of the restart
+	// policy while any primary container still has work to do, and is torn
+	// down once every primary container is done.
+	Sidecar bool `json:"sidecar,omitempty"`
+}
+
+// SecurityContext holds security configuration that will be applied to a container.
+type SecurityContext struct {
+	// SELinuxOptions are the labels to be applied to the container.
+	SELinuxOptions *SELinuxOptions `json:"seLinuxOptions,omitempty"`
+}
+
+// SELinuxOptions contains the fields that make up the SELinux context of a container.
+type SELinuxOptions struct {
+	// SELinux user label
+	User string `json:"user,omitempty"`
+	// SELinux role label
+	Role string `json:"role,omitempty"`
+	// SELinux type label
+	Type string `json:"type,omitempty"`
+	// SELinux level label.
+	Level string `json:"level,omitempty"`
 }
 
 // Handler defines a specific action that should be taken
@@ -770,11 +848,56 @@ const (
 	// DNSDefault indicates that the pod should use the default (as
 	// determined by kubelet) DNS settings.
 	DNSDefault DNSPolicy = "Default"
+
+	// DNSNone indicates that the pod should use only the DNS settings
+	// provided in DNSConfig.
+	DNSNone DNSPolicy = "None"
 )
 
+// PodDNSConfig defines the DNS parameters of a pod in addition to
+// those generated from DNSPolicy.
+type PodDNSConfig struct {
+	// A list of DNS name server IP addresses. This will be appended to the
+	// base nameservers generated from DNSPolicy.
+	Nameservers []string `json:"nameservers,omitempty"`
+	// A list of DNS search domains for host-name lookup. This will be
+	// appended to the base search paths generated from DNSPolicy.
+	Searches []string `json:"searches,omitempty"`
+	// A list of DNS resolver options. This will be merged with the base
+	// options generated from DNSPolicy.
+	Options []PodDNSConfigOption `json:"options,omitempty"`
+}
+
+// PodDNSConfigOption defines DNS resolver options of a pod.
+type PodDNSConfigOption struct {
+	// Required.
+	Name  string  `json:"name"`
+	Value *string `json:"value,omitempty"`
+}
+
+// HostAlias holds the mapping between IP and hostnames that will be injected
+// as an entry in the pod's hosts file.
+type HostAlias struct {
+	// IP address of the host file entry.
+	IP string `json:"ip"`
+	// Hostnames for the above IP address.
+	Hostnames []string `json:"hostnames"`
+}
+
+// Sysctl defines a kernel parameter to be set for a pod.
+type Sysctl struct {
+	// Name of a property to set, e.g. net.core.somaxconn.
+	Name string `json:"name"`
+	// Value of a property to set.
+	Value string `json:"value"`
+}
+
 // PodSpec is a description of a pod
 type PodSpec struct {
 	Volumes []Volume `json:"volumes"`
+	// InitContainers are run in order, one at a time, to completion before
+	// any of the regular containers are started.
+	InitContainers []Container `json:"initContainers,omitempty"`
 	// Required: there must be at least one container in a pod.
 	Containers    []Container   `json:"containers"`
 	RestartPolicy RestartPolicy `json:"restartPolicy,omitempty"`
@@ -791,6 +914,25 @@ type PodSpec struct {
 	// used must be specified.
 	// Optional: Default to false.
 	HostNetwork bool `json:"hostNetwork,omitempty"`
+	// EnableServiceLinks indicates whether env vars describing the services
+	// in the pod's namespace should be injected. The master service env vars
+	// are injected regardless. If nil, the kubelet's default applies.
+	EnableServiceLinks *bool `json:"enableServiceLinks,omitempty"`
+	// Specifies the DNS parameters of the pod. Parameters specified here
+	// will be merged to the generated DNS configuration based on DNSPolicy.
+	DNSConfig *PodDNSConfig `json:"dnsConfig,omitempty"`
+	// HostAliases is an optional list of hosts and IPs that will be injected
+	// into the pod's hosts file.
+	HostAliases []HostAlias `json:"hostAliases,omitempty"`
+	// Specifies the hostname of the pod.
+	// If not specified, the pod's hostname will be set to its name.
+	Hostname string `json:"hostname,omitempty"`
+	// If specified, the fully qualified pod hostname will be
+	// "<hostname>.<subdomain>.<pod namespace>.svc.<cluster domain>".
+	Subdomain string `json:"subdomain,omitempty"`
+	// Sysctls is a list of namespaced kernel parameters to set in the pod.
+	// Unsafe sysctls must be explicitly allowed by the kubelet.
+	Sysctls []Sysctl `json:"sysctls,omitempty"`
 }
 
 // PodStatus represents information about the status of a pod. Status may trail the actual
@@ -804,6 +946,13 @@ type PodStatus struct {
 	HostIP string `json:"hostIP,omitempty"`
 	PodIP  string `json:"podIP,omitempty"`
 
+	// Date and time at which the kubelet first observed the pod. This is
+	// before the kubelet pulled the container image(s) for the pod.
+	StartTime *util.Time `json:"startTime,omitempty"`
+
+	// The list has one entry per init container in the manifest, in order.
+	InitContainerStatuses []ContainerStatus `json:"initContainerStatuses,omitempty"`
+
 	// The list has one entry per container in the manifest. Each entry is
 	// currently the output of `docker inspect`. This output format is *not*
 	// final and should not be relied upon.
@@ -1115,6 +1264,16 @@ type NodeStatus struct {
 	Addresses []NodeAddress `json:"addresses,omitempty"`
 	// NodeSystemInfo is a set of ids/uuids to uniquely identify the node
 	NodeInfo NodeSystemInfo `json:"nodeInfo,omitempty"`
+	// List of container images on this node, largest first.
+	Images []ContainerImage `json:"images,omitempty"`
+}
+
+// Describe a container image
+type ContainerImage struct {
+	// Names by which this image is known.
+	Names []string `json:"names"`
+	// The size of the image in bytes.
+	SizeBytes int64 `json:"sizeBytes,omitempty"`
 }
 
 type NodePhase string
@@ -1137,6 +1296,12 @@ type NodeConditionType string
 const (
 	// NodeReady means kubelet is healthy and ready to accept pods.
 	NodeReady NodeConditionType = "Ready"
+	// NodeMemoryPressure means the kubelet is under pressure due to insufficient available memory.
+	NodeMemoryPressure NodeConditionType = "MemoryPressure"
+	// NodeDiskPressure means the kubelet is under pressure due to insufficient available disk.
+	NodeDiskPressure NodeConditionType = "DiskPressure"
+	// NodePIDPressure means the kubelet is under pressure due to insufficient available process IDs.
+	NodePIDPressure NodeConditionType = "PIDPressure"
 )
 
 type NodeCondition struct {
@@ -1148,6 +1313,58 @@ type NodeCondition struct {
 	Message            string            `json:"message,omitempty"`
 }
 
+// The node this Taint is attached to has the effect "effect" on
+// any pod that does not tolerate the Taint.
+// Taints are alpha and carried in the node annotations rather than NodeSpec;
+// see TaintsAnnotationKey.
+type Taint struct {
+	// Required. The taint key to be applied to a node.
+	Key string `json:"key"`
+	// Required. The taint value corresponding to the taint key.
+	Value string `json:"value,omitempty"`
+	// Required. The effect of the taint on pods that do not tolerate the taint.
+	Effect TaintEffect `json:"effect"`
+}
+
+type TaintEffect string
+
+const (
+	// Do not allow new pods to schedule onto the node unless they tolerate the taint.
+	TaintEffectNoSchedule TaintEffect = "NoSchedule"
+	// Like TaintEffectNoSchedule, but the scheduler tries not to schedule
+	// new pods onto the node, rather than prohibiting them entirely.
+	TaintEffectPreferNoSchedule TaintEffect = "PreferNoSchedule"
+	// Do not allow new pods to schedule onto the node unless they tolerate
+	// the taint, and evict (or on the kubelet, reject) pods already running
+	// on the node that do not tolerate the taint.
+	TaintEffectNoExecute TaintEffect = "NoExecute"
+)
+
+// The pod this Toleration is attached to tolerates any taint that matches
+// the triple <key,value,effect> using the matching operator <operator>.
+// Tolerations are alpha and carried in the pod annotations rather than
+// PodSpec; see TolerationsAnnotationKey.
+type Toleration struct {
+	// Required. Key is the taint key that the toleration applies to.
+	Key string `json:"key,omitempty"`
+	// Operator represents a key's relationship to the value.
+	// Valid operators are Exists and Equal. Defaults to Equal.
+	Operator TolerationOperator `json:"operator,omitempty"`
+	// Value is the taint value the toleration matches to.
+	// If the operator is Exists, the value should be empty.
+	Value string `json:"value,omitempty"`
+	// Effect indicates the taint effect to match. Empty means match all taint effects.
+	Effect TaintEffect `json:"effect,omitempty"`
+}
+
+// A toleration operator is the set of operators that can be used in a toleration.
+type TolerationOperator string
+
+const (
+	TolerationOpExists TolerationOperator = "Exists"
+	TolerationOpEqual  TolerationOperator = "Equal"
+)
+
 type NodeAddressType string
 
 // These are valid address types of node. NodeLegacyHostIP is used to transit
@@ -1181,6 +1398,8 @@ const (
 	ResourceMemory ResourceName = "memory"
 	// Volume size, in bytes (e,g. 5Gi = 5GiB = 5 * 1024 * 1024 * 1024)
 	ResourceStorage ResourceName = "storage"
+	// Local ephemeral storage, in bytes. (500Gi = 500GiB = 500 * 1024 * 1024 * 1024)
+	ResourceEphemeralStorage ResourceName = "ephemeral-storage"
 )
 
 // ResourceList is a set of (resource name, quantity) pairs.
@@ -1301,6 +1520,20 @@ type PodLogOptions struct {
 
 	// If true, follow the logs for the pod
 	Follow bool
+
+	// If true, return logs of the previous terminated instance of the container
+	Previous bool
+
+	// If set, show logs newer than a relative duration like 10s in seconds;
+	// if unset, show logs since the creation of the container
+	SinceSeconds *int64
+
+	// If true, add an RFC3339 timestamp at the beginning of every line of log output
+	Timestamps bool
+
+	// If set, the number of lines from the end of the logs to show;
+	// if unset, show logs from the creation of the container
+	TailLines *int64
 }
 
 // PodExecOptions is the query options to a Pod's remote exec call
@@ -1626,9 +1859,30 @@ type ContainerManifest struct {
 	Volumes       []Volume      `json:"volumes"`
 	Containers    []Container   `json:"containers"`
 	RestartPolicy RestartPolicy `json:"restartPolicy,omitempty"`
+	// InitContainers are run in order, one at a time, to completion before
+	// any of the regular containers are started.
+	InitContainers []Container `json:"initContainers,omitempty"`
 	// Required: Set DNS policy.
 	DNSPolicy   DNSPolicy `json:"dnsPolicy"`
 	HostNetwork bool      `json:"hostNetwork,omitempty"`
+	// EnableServiceLinks indicates whether env vars describing the services in the
+	// pod's namespace should be injected. If nil, the kubelet's default applies.
+	EnableServiceLinks *bool `json:"enableServiceLinks,omitempty"`
+	// Specifies the DNS parameters of the pod. Parameters specified here
+	// will be merged to the generated DNS configuration based on DNSPolicy.
+	DNSConfig *PodDNSConfig `json:"dnsConfig,omitempty"`
+	// HostAliases is an optional list of hosts and IPs that will be injected
+	// into the pod's hosts file.
+	HostAliases []HostAlias `json:"hostAliases,omitempty"`
+	// Specifies the hostname of the pod.
+	// If not specified, the pod's hostname will be set to its name.
+	Hostname string `json:"hostname,omitempty"`
+	// If specified, the fully qualified pod hostname will be
+	// "<hostname>.<subdomain>.<pod namespace>.svc.<cluster domain>".
+	Subdomain string `json:"subdomain,omitempty"`
+	// Sysctls is a list of namespaced kernel parameters to set in the pod.
+	// Unsafe sysctls must be explicitly allowed by the kubelet.
+	Sysctls []Sysctl `json:"sysctls,omitempty"`
 }
 
 // ContainerManifestList is used to communicate container manifests to kubelet.
@@ -1801,7 +2055,12 @@ const (
 	StreamTypeData   = "data"
 	StreamTypeError  = "error"
 
-	PortHeader = "port"
+	PortHeader     = "port"
+	ProtocolHeader = "protocol"
+
+	// Protocols supported for port forwarding
+	PortForwardProtocolTCP = "tcp"
+	PortForwardProtocolUDP = "udp"
 )
 
 // Similarly to above, these are constants to support HTTP PATCH utilized by
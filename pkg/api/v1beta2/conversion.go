@@ -366,6 +366,15 @@ func init() {
 			if err := s.Convert(&in.Capabilities, &out.Capabilities, 0); err != nil {
 				return err
 			}
+			if err := s.Convert(&in.SecurityContext, &out.SecurityContext, 0); err != nil {
+				return err
+			}
+			if err := s.Convert(&in.Devices, &out.Devices, 0); err != nil {
+				return err
+			}
+			if err := s.Convert(&in.Sidecar, &out.Sidecar, 0); err != nil {
+				return err
+			}
 			return nil
 		},
 		// Internal API does not support CPU to be specified via an explicit field.
@@ -454,6 +463,15 @@ func init() {
 			if err := s.Convert(&in.Capabilities, &out.Capabilities, 0); err != nil {
 				return err
 			}
+			if err := s.Convert(&in.SecurityContext, &out.SecurityContext, 0); err != nil {
+				return err
+			}
+			if err := s.Convert(&in.Devices, &out.Devices, 0); err != nil {
+				return err
+			}
+			if err := s.Convert(&in.Sidecar, &out.Sidecar, 0); err != nil {
+				return err
+			}
 			return nil
 		},
 		func(in *newer.PodSpec, out *ContainerManifest, s conversion.Scope) error {
@@ -463,12 +481,27 @@ func init() {
 			if err := s.Convert(&in.Containers, &out.Containers, 0); err != nil {
 				return err
 			}
+			if err := s.Convert(&in.InitContainers, &out.InitContainers, 0); err != nil {
+				return err
+			}
 			if err := s.Convert(&in.RestartPolicy, &out.RestartPolicy, 0); err != nil {
 				return err
 			}
 			out.DNSPolicy = DNSPolicy(in.DNSPolicy)
 			out.Version = "v1beta2"
 			out.HostNetwork = in.HostNetwork
+			out.EnableServiceLinks = in.EnableServiceLinks
+			if err := s.Convert(&in.DNSConfig, &out.DNSConfig, 0); err != nil {
+				return err
+			}
+			if err := s.Convert(&in.HostAliases, &out.HostAliases, 0); err != nil {
+				return err
+			}
+			out.Hostname = in.Hostname
+			out.Subdomain = in.Subdomain
+			if err := s.Convert(&in.Sysctls, &out.Sysctls, 0); err != nil {
+				return err
+			}
 			return nil
 		},
 		func(in *ContainerManifest, out *newer.PodSpec, s conversion.Scope) error {
@@ -478,11 +511,26 @@ func init() {
 			if err := s.Convert(&in.Containers, &out.Containers, 0); err != nil {
 				return err
 			}
+			if err := s.Convert(&in.InitContainers, &out.InitContainers, 0); err != nil {
+				return err
+			}
 			if err := s.Convert(&in.RestartPolicy, &out.RestartPolicy, 0); err != nil {
 				return err
 			}
 			out.DNSPolicy = newer.DNSPolicy(in.DNSPolicy)
 			out.HostNetwork = in.HostNetwork
+			out.EnableServiceLinks = in.EnableServiceLinks
+			if err := s.Convert(&in.DNSConfig, &out.DNSConfig, 0); err != nil {
+				return err
+			}
+			if err := s.Convert(&in.HostAliases, &out.HostAliases, 0); err != nil {
+				return err
+			}
+			out.Hostname = in.Hostname
+			out.Subdomain = in.Subdomain
+			if err := s.Convert(&in.Sysctls, &out.Sysctls, 0); err != nil {
+				return err
+			}
 			return nil
 		},
 
@@ -493,12 +541,16 @@ func init() {
 			if err := s.Convert(&in.ContainerStatuses, &out.Info, 0); err != nil {
 				return err
 			}
+			if err := s.Convert(&in.InitContainerStatuses, &out.InitContainerInfo, 0); err != nil {
+				return err
+			}
 			if err := s.Convert(&in.Conditions, &out.Conditions, 0); err != nil {
 				return err
 			}
 			out.Message = in.Message
 			out.HostIP = in.HostIP
 			out.PodIP = in.PodIP
+			out.StartTime = in.StartTime
 			return nil
 		},
 		func(in *PodState, out *newer.PodStatus, s conversion.Scope) error {
@@ -508,12 +560,16 @@ func init() {
 			if err := s.Convert(&in.Info, &out.ContainerStatuses, 0); err != nil {
 				return err
 			}
+			if err := s.Convert(&in.InitContainerInfo, &out.InitContainerStatuses, 0); err != nil {
+				return err
+			}
 			if err := s.Convert(&in.Conditions, &out.Conditions, 0); err != nil {
 				return err
 			}
 			out.Message = in.Message
 			out.HostIP = in.HostIP
 			out.PodIP = in.PodIP
+			out.StartTime = in.StartTime
 			return nil
 		},
 
@@ -735,6 +791,9 @@ func init() {
 			if err := s.Convert(&in.Status.NodeInfo, &out.Status.NodeInfo, 0); err != nil {
 				return err
 			}
+			if err := s.Convert(&in.Status.Images, &out.Status.Images, 0); err != nil {
+				return err
+			}
 
 			for _, address := range in.Status.Addresses {
 				if address.Type == newer.NodeLegacyHostIP {
@@ -768,6 +827,9 @@ func init() {
 			if err := s.Convert(&in.Status.NodeInfo, &out.Status.NodeInfo, 0); err != nil {
 				return err
 			}
+			if err := s.Convert(&in.Status.Images, &out.Status.Images, 0); err != nil {
+				return err
+			}
 
 			if in.HostIP != "" {
 				newer.AddToNodeAddresses(&out.Status.Addresses,
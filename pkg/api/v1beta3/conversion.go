@@ -261,6 +261,14 @@ func init() {
 			out.Message = in.Message
 			out.HostIP = in.HostIP
 			out.PodIP = in.PodIP
+			if in.InitContainerStatuses != nil {
+				out.InitContainerStatuses = make([]newer.ContainerStatus, len(in.InitContainerStatuses))
+				for i := range in.InitContainerStatuses {
+					if err := s.Convert(&in.InitContainerStatuses[i], &out.InitContainerStatuses[i], 0); err != nil {
+						return err
+					}
+				}
+			}
 			if in.ContainerStatuses != nil {
 				out.ContainerStatuses = make([]newer.ContainerStatus, len(in.ContainerStatuses))
 				for i := range in.ContainerStatuses {
@@ -284,6 +292,14 @@ func init() {
 			out.Message = in.Message
 			out.HostIP = in.HostIP
 			out.PodIP = in.PodIP
+			if in.InitContainerStatuses != nil {
+				out.InitContainerStatuses = make([]ContainerStatus, len(in.InitContainerStatuses))
+				for i := range in.InitContainerStatuses {
+					if err := s.Convert(&in.InitContainerStatuses[i], &out.InitContainerStatuses[i], 0); err != nil {
+						return err
+					}
+				}
+			}
 			if in.ContainerStatuses != nil {
 				out.ContainerStatuses = make([]ContainerStatus, len(in.ContainerStatuses))
 				for i := range in.ContainerStatuses {
@@ -822,6 +838,13 @@ func init() {
 			if err := s.Convert(&in.Capabilities, &out.Capabilities, 0); err != nil {
 				return err
 			}
+			if err := s.Convert(&in.SecurityContext, &out.SecurityContext, 0); err != nil {
+				return err
+			}
+			if err := s.Convert(&in.Devices, &out.Devices, 0); err != nil {
+				return err
+			}
+			out.Sidecar = in.Sidecar
 			return nil
 		},
 		func(in *newer.Container, out *Container, s conversion.Scope) error {
@@ -882,6 +905,13 @@ func init() {
 			if err := s.Convert(&in.Capabilities, &out.Capabilities, 0); err != nil {
 				return err
 			}
+			if err := s.Convert(&in.SecurityContext, &out.SecurityContext, 0); err != nil {
+				return err
+			}
+			if err := s.Convert(&in.Devices, &out.Devices, 0); err != nil {
+				return err
+			}
+			out.Sidecar = in.Sidecar
 			return nil
 		},
 		func(in *ContainerStateWaiting, out *newer.ContainerStateWaiting, s conversion.Scope) error {
@@ -1323,6 +1353,15 @@ func init() {
 					out.Command[i] = in.Command[i]
 				}
 			}
+			if in.Env != nil {
+				out.Env = make([]newer.EnvVar, len(in.Env))
+				for i := range in.Env {
+					if err := s.Convert(&in.Env[i], &out.Env[i], 0); err != nil {
+						return err
+					}
+				}
+			}
+			out.WorkingDir = in.WorkingDir
 			return nil
 		},
 		func(in *newer.ExecAction, out *ExecAction, s conversion.Scope) error {
@@ -1332,6 +1371,15 @@ func init() {
 					out.Command[i] = in.Command[i]
 				}
 			}
+			if in.Env != nil {
+				out.Env = make([]EnvVar, len(in.Env))
+				for i := range in.Env {
+					if err := s.Convert(&in.Env[i], &out.Env[i], 0); err != nil {
+						return err
+					}
+				}
+			}
+			out.WorkingDir = in.WorkingDir
 			return nil
 		},
 		func(in *Probe, out *newer.Probe, s conversion.Scope) error {
@@ -1453,12 +1501,12 @@ func init() {
 		func(in *EnvVar, out *newer.EnvVar, s conversion.Scope) error {
 			out.Name = in.Name
 			out.Value = in.Value
-			return nil
+			return s.Convert(&in.ValueFrom, &out.ValueFrom, 0)
 		},
 		func(in *newer.EnvVar, out *EnvVar, s conversion.Scope) error {
 			out.Name = in.Name
 			out.Value = in.Value
-			return nil
+			return s.Convert(&in.ValueFrom, &out.ValueFrom, 0)
 		},
 		func(in *PodSpec, out *newer.PodSpec, s conversion.Scope) error {
 			if in.Volumes != nil {
@@ -1477,6 +1525,14 @@ func init() {
 					}
 				}
 			}
+			if in.InitContainers != nil {
+				out.InitContainers = make([]newer.Container, len(in.InitContainers))
+				for i := range in.InitContainers {
+					if err := s.Convert(&in.InitContainers[i], &out.InitContainers[i], 0); err != nil {
+						return err
+					}
+				}
+			}
 			out.RestartPolicy = newer.RestartPolicy(in.RestartPolicy)
 			out.DNSPolicy = newer.DNSPolicy(in.DNSPolicy)
 			if in.NodeSelector != nil {
@@ -1487,6 +1543,18 @@ func init() {
 			}
 			out.Host = in.Host
 			out.HostNetwork = in.HostNetwork
+			out.EnableServiceLinks = in.EnableServiceLinks
+			if err := s.Convert(&in.DNSConfig, &out.DNSConfig, 0); err != nil {
+				return err
+			}
+			if err := s.Convert(&in.HostAliases, &out.HostAliases, 0); err != nil {
+				return err
+			}
+			out.Hostname = in.Hostname
+			out.Subdomain = in.Subdomain
+			if err := s.Convert(&in.Sysctls, &out.Sysctls, 0); err != nil {
+				return err
+			}
 			return nil
 		},
 		func(in *newer.PodSpec, out *PodSpec, s conversion.Scope) error {
@@ -1506,6 +1574,14 @@ func init() {
 					}
 				}
 			}
+			if in.InitContainers != nil {
+				out.InitContainers = make([]Container, len(in.InitContainers))
+				for i := range in.InitContainers {
+					if err := s.Convert(&in.InitContainers[i], &out.InitContainers[i], 0); err != nil {
+						return err
+					}
+				}
+			}
 			out.RestartPolicy = RestartPolicy(in.RestartPolicy)
 			out.DNSPolicy = DNSPolicy(in.DNSPolicy)
 			if in.NodeSelector != nil {
@@ -1516,6 +1592,18 @@ func init() {
 			}
 			out.Host = in.Host
 			out.HostNetwork = in.HostNetwork
+			out.EnableServiceLinks = in.EnableServiceLinks
+			if err := s.Convert(&in.DNSConfig, &out.DNSConfig, 0); err != nil {
+				return err
+			}
+			if err := s.Convert(&in.HostAliases, &out.HostAliases, 0); err != nil {
+				return err
+			}
+			out.Hostname = in.Hostname
+			out.Subdomain = in.Subdomain
+			if err := s.Convert(&in.Sysctls, &out.Sysctls, 0); err != nil {
+				return err
+			}
 			return nil
 		},
 		func(in *ComponentCondition, out *newer.ComponentCondition, s conversion.Scope) error {
@@ -1960,6 +2048,10 @@ func init() {
 			}
 			out.Container = in.Container
 			out.Follow = in.Follow
+			out.Previous = in.Previous
+			out.SinceSeconds = in.SinceSeconds
+			out.Timestamps = in.Timestamps
+			out.TailLines = in.TailLines
 			return nil
 		},
 		func(in *newer.PodLogOptions, out *PodLogOptions, s conversion.Scope) error {
@@ -1968,6 +2060,10 @@ func init() {
 			}
 			out.Container = in.Container
 			out.Follow = in.Follow
+			out.Previous = in.Previous
+			out.SinceSeconds = in.SinceSeconds
+			out.Timestamps = in.Timestamps
+			out.TailLines = in.TailLines
 			return nil
 		},
 		func(in *GlusterfsVolumeSource, out *newer.GlusterfsVolumeSource, s conversion.Scope) error {
@@ -2125,6 +2221,14 @@ func init() {
 			if err := s.Convert(&in.NodeInfo, &out.NodeInfo, 0); err != nil {
 				return err
 			}
+			if in.Images != nil {
+				out.Images = make([]newer.ContainerImage, len(in.Images))
+				for i := range in.Images {
+					if err := s.Convert(&in.Images[i], &out.Images[i], 0); err != nil {
+						return err
+					}
+				}
+			}
 			return nil
 		},
 		func(in *newer.NodeStatus, out *NodeStatus, s conversion.Scope) error {
@@ -2158,6 +2262,14 @@ func init() {
 			if err := s.Convert(&in.NodeInfo, &out.NodeInfo, 0); err != nil {
 				return err
 			}
+			if in.Images != nil {
+				out.Images = make([]ContainerImage, len(in.Images))
+				for i := range in.Images {
+					if err := s.Convert(&in.Images[i], &out.Images[i], 0); err != nil {
+						return err
+					}
+				}
+			}
 			return nil
 		},
 		func(in *PersistentVolumeSpec, out *newer.PersistentVolumeSpec, s conversion.Scope) error {
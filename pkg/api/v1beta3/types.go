@@ -519,6 +519,40 @@ type EnvVar struct {
 	Name string `json:"name" description:"name of the environment variable; must be a C_IDENTIFIER"`
 	// Optional: defaults to "".
 	Value string `json:"value,omitempty" description:"value of the environment variable; defaults to empty string"`
+	// Optional: Specifies a source the value of this var should come from.
+	ValueFrom *EnvVarSource `json:"valueFrom,omitempty" description:"source for the environment variable's value; cannot be used if value is not empty"`
+}
+
+// EnvVarSource represents a source for the value of an EnvVar.
+// Only one of its fields may be set.
+type EnvVarSource struct {
+	// Selects a resource of the container: only resources limits and
+	// requests (limits.cpu, limits.memory, requests.cpu and requests.memory)
+	// are currently supported.
+	ResourceFieldRef *ResourceFieldSelector `json:"resourceFieldRef,omitempty" description:"selects a resource limit or request of the container; only limits.cpu, limits.memory, requests.cpu and requests.memory are supported"`
+	// Selects a key of a secret in the pod's namespace.
+	SecretKeyRef *SecretKeySelector `json:"secretKeyRef,omitempty" description:"selects a key of a secret in the pod's namespace"`
+}
+
+// ResourceFieldSelector represents container resources (cpu, memory) and
+// their output format.
+type ResourceFieldSelector struct {
+	// Optional: the name of the container whose resources to select;
+	// defaults to the container declaring the env var.
+	ContainerName string `json:"containerName,omitempty" description:"name of the container whose resources to select; defaults to the container declaring the env var"`
+	// Required: resource to select.
+	Resource string `json:"resource" description:"resource to select; one of limits.cpu, limits.memory, requests.cpu, requests.memory"`
+	// Specifies the output format of the exposed resources, defaults to "1".
+	Divisor resource.Quantity `json:"divisor,omitempty" description:"output format of the exposed resource; defaults to 1"`
+}
+
+// SecretKeySelector selects a key of a Secret.
+type SecretKeySelector struct {
+	// Required: The name of the secret in the pod's namespace to select from.
+	Name string `json:"name" description:"name of the secret in the pod's namespace to select from"`
+	// Required: The key of the secret to select from.  Must be a valid
+	// DNS_SUBDOMAIN.
+	Key string `json:"key" description:"the key of the secret to select from; must be a valid DNS_SUBDOMAIN"`
 }
 
 // HTTPGetAction describes an action based on HTTP Get requests.
@@ -544,6 +578,12 @@ type ExecAction struct {
 	// not run inside a shell, so traditional shell instructions ('|', etc) won't work.  To use
 	// a shell, you need to explicitly call out to that shell.
 	Command []string `json:"command,omitempty" description:"command line to execute inside the container; working directory for the command is root ('/') in the container's file system; the command is exec'd, not run inside a shell; exit status of 0 is treated as live/healthy and non-zero is unhealthy"`
+	// Optional: Additional environment variables set for the command, on top
+	// of the container's own (expanded) environment.
+	Env []EnvVar `json:"env,omitempty" description:"additional environment variables set for the command, on top of the container's own environment"`
+	// Optional: Working directory the command is run in. Defaults to the
+	// container's working directory.
+	WorkingDir string `json:"workingDir,omitempty" description:"working directory the command is run in; defaults to the container's working directory"`
 }
 
 // Probe describes a liveness probe to be examined to the container.
@@ -572,6 +612,18 @@ const (
 type CapabilityType string
 
 // Capabilities represent POSIX capabilities that can be added or removed to a running container.
+// Device maps a host device node into a container, with cgroup device
+// permissions, as an alternative to running the container privileged.
+type Device struct {
+	// Required: path of the device node on the host, e.g. /dev/fuse.
+	HostPath string `json:"hostPath" description:"path of the device node on the host"`
+	// Path of the device inside the container; defaults to HostPath.
+	ContainerPath string `json:"containerPath,omitempty" description:"path of the device inside the container; defaults to hostPath"`
+	// Cgroup permissions for the device, any combination of "r" (read),
+	// "w" (write) and "m" (mknod); defaults to "mrw".
+	Permissions string `json:"permissions,omitempty" description:"cgroup permissions for the device, any combination of r, w and m; defaults to mrw"`
+}
+
 type Capabilities struct {
 	// Added capabilities
 	Add []CapabilityType `json:"add,omitempty" description:"added capabilities"`
@@ -609,6 +661,7 @@ type Container struct {
 	Env            []EnvVar             `json:"env,omitempty" description:"list of environment variables to set in the container; cannot be updated" patchStrategy:"merge" patchMergeKey:"name"`
 	Resources      ResourceRequirements `json:"resources,omitempty" description:"Compute Resources required by this container; cannot be updated"`
 	VolumeMounts   []VolumeMount        `json:"volumeMounts,omitempty" description:"pod volumes to mount into the container's filesyste; cannot be updated" patchStrategy:"merge" patchMergeKey:"name"`
+	Devices        []Device             `json:"devices,omitempty" description:"host device nodes to map into the container; cannot be updated"`
 	LivenessProbe  *Probe               `json:"livenessProbe,omitempty" description:"periodic probe of container liveness; container will be restarted if the probe fails; cannot be updated"`
 	ReadinessProbe *Probe               `json:"readinessProbe,omitempty" description:"periodic probe of container service readiness; container will be removed from service endpoints if the probe fails; cannot be updated"`
 	Lifecycle      *Lifecycle           `json:"lifecycle,omitempty" description:"actions that the management system should take in response to container lifecycle events; cannot be updated"`
@@ -620,6 +673,31 @@ type Container struct {
 	ImagePullPolicy PullPolicy `json:"imagePullPolicy" description:"image pull policy; one of PullAlways, PullNever, PullIfNotPresent; defaults to PullAlways if :latest tag is specified, or PullIfNotPresent otherwise; cannot be updated"`
 	// Optional: Capabilities for container.
 	Capabilities Capabilities `json:"capabilities,omitempty" description:"capabilities for container; cannot be updated"`
+	// Optional: SecurityContext defines the security options the pod should be run with.
+	SecurityContext *SecurityContext `json:"securityContext,omitempty" description:"security options the pod should run with"`
+	// Optional: Sidecar marks the container as auxiliary to the pod's
+	// primary containers. A sidecar is restarted regardless of the restart
+	// policy while any primary container still has work to do, and is torn
+	// down once every primary container is done.
+	Sidecar bool `json:"sidecar,omitempty" description:"whether the container is a sidecar; sidecars are restarted regardless of the restart policy while any primary container still has work to do, and are stopped once every primary container is done"`
+}
+
+// SecurityContext holds security configuration that will be applied to a container.
+type SecurityContext struct {
+	// SELinuxOptions are the labels to be applied to the container.
+	SELinuxOptions *SELinuxOptions `json:"seLinuxOptions,omitempty" description:"options that control the SELinux labels applied"`
+}
+
+// SELinuxOptions contains the fields that make up the SELinux context of a container.
+type SELinuxOptions struct {
+	// SELinux user label
+	User string `json:"user,omitempty" description:"the user label to apply to the container"`
+	// SELinux role label
+	Role string `json:"role,omitempty" description:"the role label to apply to the container"`
+	// SELinux type label
+	Type string `json:"type,omitempty" description:"the type label to apply to the container"`
+	// SELinux level label.
+	Level string `json:"level,omitempty" description:"the level label to apply to the container"`
 }
 
 // Handler defines a specific action that should be taken
@@ -770,14 +848,59 @@ const (
 	// DNSDefault indicates that the pod should use the default (as
 	// determined by kubelet) DNS settings.
 	DNSDefault DNSPolicy = "Default"
+
+	// DNSNone indicates that the pod should use only the DNS settings
+	// provided in DNSConfig.
+	DNSNone DNSPolicy = "None"
 )
 
+// PodDNSConfig defines the DNS parameters of a pod in addition to
+// those generated from DNSPolicy.
+type PodDNSConfig struct {
+	// A list of DNS name server IP addresses. This will be appended to the
+	// base nameservers generated from DNSPolicy.
+	Nameservers []string `json:"nameservers,omitempty" description:"list of DNS name server IP addresses appended to the base nameservers generated from the DNS policy"`
+	// A list of DNS search domains for host-name lookup. This will be
+	// appended to the base search paths generated from DNSPolicy.
+	Searches []string `json:"searches,omitempty" description:"list of DNS search domains appended to the base search paths generated from the DNS policy"`
+	// A list of DNS resolver options. This will be merged with the base
+	// options generated from DNSPolicy.
+	Options []PodDNSConfigOption `json:"options,omitempty" description:"list of DNS resolver options merged with the base options generated from the DNS policy"`
+}
+
+// PodDNSConfigOption defines DNS resolver options of a pod.
+type PodDNSConfigOption struct {
+	// Required.
+	Name  string  `json:"name" description:"name of the resolver option"`
+	Value *string `json:"value,omitempty" description:"value of the resolver option"`
+}
+
+// HostAlias holds the mapping between IP and hostnames that will be injected
+// as an entry in the pod's hosts file.
+type HostAlias struct {
+	// IP address of the host file entry.
+	IP string `json:"ip" description:"IP address of the host file entry"`
+	// Hostnames for the above IP address.
+	Hostnames []string `json:"hostnames" description:"hostnames for the above IP address"`
+}
+
+// Sysctl defines a kernel parameter to be set for a pod.
+type Sysctl struct {
+	// Name of a property to set, e.g. net.core.somaxconn.
+	Name string `json:"name" description:"name of the sysctl"`
+	// Value of a property to set.
+	Value string `json:"value" description:"value of the sysctl"`
+}
+
 // PodSpec is a description of a pod
 type PodSpec struct {
 	Volumes []Volume `json:"volumes" description:"list of volumes that can be mounted by containers belonging to the pod" patchStrategy:"merge" patchMergeKey:"name"`
 	// Required: there must be at least one container in a pod.
 	Containers    []Container   `json:"containers" description:"list of containers belonging to the pod; cannot be updated; containers cannot currently be added or removed; there must be at least one container in a Pod" patchStrategy:"merge" patchMergeKey:"name"`
 	RestartPolicy RestartPolicy `json:"restartPolicy,omitempty" description:"restart policy for all containers within the pod; one of RestartPolicyAlways, RestartPolicyOnFailure, RestartPolicyNever"`
+	// InitContainers are run in order, one at a time, to completion before
+	// any of the regular containers are started.
+	InitContainers []Container `json:"initContainers,omitempty" description:"list of init containers run in order to completion before the regular containers are started"`
 	// Optional: Set DNS policy.  Defaults to "ClusterFirst"
 	DNSPolicy DNSPolicy `json:"dnsPolicy,omitempty" description:"DNS policy for containers within the pod; one of 'ClusterFirst' or 'Default'"`
 	// NodeSelector is a selector which must be true for the pod to fit on a node
@@ -791,6 +914,25 @@ type PodSpec struct {
 	// used must be specified.
 	// Optional: Default to false.
 	HostNetwork bool `json:"hostNetwork,omitempty" description:"host networking requested for this pod"`
+	// EnableServiceLinks indicates whether env vars describing the services
+	// in the pod's namespace should be injected. The master service env vars
+	// are injected regardless. If nil, the kubelet's default applies.
+	EnableServiceLinks *bool `json:"enableServiceLinks,omitempty" description:"whether service environment variables for services in the same namespace should be injected into the containers; the master service variables are always injected; if unset the kubelet default applies"`
+	// Specifies the DNS parameters of the pod. Parameters specified here
+	// will be merged to the generated DNS configuration based on DNSPolicy.
+	DNSConfig *PodDNSConfig `json:"dnsConfig,omitempty" description:"DNS parameters merged into the DNS configuration generated from the DNS policy"`
+	// HostAliases is an optional list of hosts and IPs that will be injected
+	// into the pod's hosts file.
+	HostAliases []HostAlias `json:"hostAliases,omitempty" description:"list of hosts and IPs injected into the pod's hosts file"`
+	// Specifies the hostname of the pod.
+	// If not specified, the pod's hostname will be set to its name.
+	Hostname string `json:"hostname,omitempty" description:"specifies the hostname of the pod; defaults to the pod's name"`
+	// If specified, the fully qualified pod hostname will be
+	// "<hostname>.<subdomain>.<pod namespace>.svc.<cluster domain>".
+	Subdomain string `json:"subdomain,omitempty" description:"subdomain of the fully qualified pod hostname"`
+	// Sysctls is a list of namespaced kernel parameters to set in the pod.
+	// Unsafe sysctls must be explicitly allowed by the kubelet.
+	Sysctls []Sysctl `json:"sysctls,omitempty" description:"list of namespaced kernel parameters to set in the pod; unsafe sysctls must be allowed by the kubelet"`
 }
 
 // PodStatus represents information about the status of a pod. Status may trail the actual
@@ -804,6 +946,11 @@ type PodStatus struct {
 	HostIP string `json:"hostIP,omitempty" description:"IP address of the host to which the pod is assigned; empty if not yet scheduled"`
 	PodIP  string `json:"podIP,omitempty" description:"IP address allocated to the pod; routable at least within the cluster; empty if not yet allocated"`
 
+	StartTime *util.Time `json:"startTime,omitempty" description:"RFC 3339 date and time at which the kubelet first observed the pod; before the container images have been pulled"`
+
+	// The list has one entry per init container in the manifest, in order.
+	InitContainerStatuses []ContainerStatus `json:"initContainerStatuses,omitempty" description:"list of init container statuses"`
+
 	// The list has one entry per container in the manifest. Each entry is currently the output
 	// of `docker inspect`.
 	ContainerStatuses []ContainerStatus `json:"containerStatuses,omitempty" description:"list of container statuses"`
@@ -1112,6 +1259,16 @@ type NodeStatus struct {
 	Addresses []NodeAddress `json:"addresses,omitempty" description:"list of addresses reachable to the node" patchStrategy:"merge" patchMergeKey:"type"`
 	// NodeSystemInfo is a set of ids/uuids to uniquely identify the node
 	NodeInfo NodeSystemInfo `json:"nodeInfo,omitempty"`
+	// List of container images on this node, largest first.
+	Images []ContainerImage `json:"images,omitempty" description:"list of container images on this node"`
+}
+
+// Describe a container image
+type ContainerImage struct {
+	// Names by which this image is known.
+	Names []string `json:"names" description:"names by which this image is known"`
+	// The size of the image in bytes.
+	SizeBytes int64 `json:"sizeBytes,omitempty" description:"size of the image in bytes"`
 }
 
 type NodePhase string
@@ -1134,6 +1291,12 @@ type NodeConditionType string
 const (
 	// NodeReady means kubelet is healthy and ready to accept pods.
 	NodeReady NodeConditionType = "Ready"
+	// NodeMemoryPressure means the kubelet is under pressure due to insufficient available memory.
+	NodeMemoryPressure NodeConditionType = "MemoryPressure"
+	// NodeDiskPressure means the kubelet is under pressure due to insufficient available disk.
+	NodeDiskPressure NodeConditionType = "DiskPressure"
+	// NodePIDPressure means the kubelet is under pressure due to insufficient available process IDs.
+	NodePIDPressure NodeConditionType = "PIDPressure"
 )
 
 type NodeCondition struct {
@@ -1169,6 +1332,8 @@ const (
 	ResourceMemory ResourceName = "memory"
 	// Volume size, in bytes (e,g. 5Gi = 5GiB = 5 * 1024 * 1024 * 1024)
 	ResourceStorage ResourceName = "storage"
+	// Local ephemeral storage, in bytes. (500Gi = 500GiB = 500 * 1024 * 1024 * 1024)
+	ResourceEphemeralStorage ResourceName = "ephemeral-storage"
 )
 
 // ResourceList is a set of (resource name, quantity) pairs.
@@ -1289,6 +1454,18 @@ type PodLogOptions struct {
 
 	// If true, follow the logs for the pod
 	Follow bool `json:"follow,omitempty" description:"follow the log stream of the pod; defaults to false"`
+
+	// If true, return previous terminated container logs
+	Previous bool `json:"previous,omitempty" description:"return logs of the previous terminated instance of the container; defaults to false"`
+
+	// A relative time in seconds before the current time from which to show logs
+	SinceSeconds *int64 `json:"sinceSeconds,omitempty" description:"relative time in seconds before the current time from which to show logs; defaults to showing logs since the creation of the container"`
+
+	// If true, add a timestamp at the beginning of every line of log output
+	Timestamps bool `json:"timestamps,omitempty" description:"add an RFC3339 timestamp at the beginning of every line of log output; defaults to false"`
+
+	// If set, the number of lines from the end of the logs to show
+	TailLines *int64 `json:"tailLines,omitempty" description:"number of lines from the end of the logs to show; defaults to showing logs from the creation of the container"`
 }
 
 // PodExecOptions is the query options to a Pod's remote exec call
@@ -559,11 +559,65 @@ func validateEnv(vars []api.EnvVar) errs.ValidationErrorList {
 		if !util.IsCIdentifier(ev.Name) {
 			vErrs = append(vErrs, errs.NewFieldInvalid("name", ev.Name, cIdentifierErrorMsg))
 		}
+		if ev.ValueFrom != nil {
+			if len(ev.Value) != 0 {
+				vErrs = append(vErrs, errs.NewFieldInvalid("valueFrom", ev.ValueFrom, "may not be specified when `value` is not empty"))
+			}
+			vErrs = append(vErrs, validateEnvVarSource(ev.ValueFrom).Prefix("valueFrom")...)
+		}
 		allErrs = append(allErrs, vErrs.PrefixIndex(i)...)
 	}
 	return allErrs
 }
 
+func validateEnvVarSource(source *api.EnvVarSource) errs.ValidationErrorList {
+	allErrs := errs.ValidationErrorList{}
+
+	numSources := 0
+	if source.ResourceFieldRef != nil {
+		numSources++
+		allErrs = append(allErrs, validateResourceFieldSelector(source.ResourceFieldRef).Prefix("resourceFieldRef")...)
+	}
+	if source.SecretKeyRef != nil {
+		numSources++
+		allErrs = append(allErrs, validateSecretKeySelector(source.SecretKeyRef).Prefix("secretKeyRef")...)
+	}
+	if numSources != 1 {
+		allErrs = append(allErrs, errs.NewFieldInvalid("", source, "exactly one source must be specified"))
+	}
+	return allErrs
+}
+
+var validContainerResourceFieldPathExpressions = util.NewStringSet("limits.cpu", "limits.memory", "requests.cpu", "requests.memory")
+
+func validateResourceFieldSelector(fs *api.ResourceFieldSelector) errs.ValidationErrorList {
+	allErrs := errs.ValidationErrorList{}
+
+	if len(fs.Resource) == 0 {
+		allErrs = append(allErrs, errs.NewFieldRequired("resource"))
+	} else if !validContainerResourceFieldPathExpressions.Has(fs.Resource) {
+		allErrs = append(allErrs, errs.NewFieldNotSupported("resource", fs.Resource))
+	}
+	if fs.Divisor.Amount != nil && fs.Divisor.MilliValue() <= 0 {
+		allErrs = append(allErrs, errs.NewFieldInvalid("divisor", fs.Divisor, "must be a positive quantity"))
+	}
+	return allErrs
+}
+
+func validateSecretKeySelector(selector *api.SecretKeySelector) errs.ValidationErrorList {
+	allErrs := errs.ValidationErrorList{}
+
+	if len(selector.Name) == 0 {
+		allErrs = append(allErrs, errs.NewFieldRequired("name"))
+	}
+	if len(selector.Key) == 0 {
+		allErrs = append(allErrs, errs.NewFieldRequired("key"))
+	} else if !util.IsDNS1123Subdomain(selector.Key) {
+		allErrs = append(allErrs, errs.NewFieldInvalid("key", selector.Key, dnsSubdomainErrorMsg))
+	}
+	return allErrs
+}
+
 func validateVolumeMounts(mounts []api.VolumeMount, volumes util.StringSet) errs.ValidationErrorList {
 	allErrs := errs.ValidationErrorList{}
 
@@ -582,6 +636,27 @@ func validateVolumeMounts(mounts []api.VolumeMount, volumes util.StringSet) errs
 	return allErrs
 }
 
+func validateDevices(devices []api.Device) errs.ValidationErrorList {
+	allErrs := errs.ValidationErrorList{}
+
+	for i, device := range devices {
+		dErrs := errs.ValidationErrorList{}
+		if len(device.HostPath) == 0 {
+			dErrs = append(dErrs, errs.NewFieldRequired("hostPath"))
+		} else if !path.IsAbs(device.HostPath) {
+			dErrs = append(dErrs, errs.NewFieldInvalid("hostPath", device.HostPath, "must be an absolute path"))
+		}
+		if len(device.ContainerPath) > 0 && !path.IsAbs(device.ContainerPath) {
+			dErrs = append(dErrs, errs.NewFieldInvalid("containerPath", device.ContainerPath, "must be an absolute path"))
+		}
+		if len(device.Permissions) > 0 && len(strings.Trim(device.Permissions, "rwm")) > 0 {
+			dErrs = append(dErrs, errs.NewFieldInvalid("permissions", device.Permissions, "may only contain 'r', 'w' and 'm'"))
+		}
+		allErrs = append(allErrs, dErrs.PrefixIndex(i)...)
+	}
+	return allErrs
+}
+
 func validateProbe(probe *api.Probe) errs.ValidationErrorList {
 	allErrs := errs.ValidationErrorList{}
 
@@ -621,11 +696,36 @@ func AccumulateUniquePorts(containers []api.Container, accumulator map[int]bool,
 	return allErrs
 }
 
-// checkHostPortConflicts checks for colliding Port.HostPort values across
-// a slice of containers.
+// AccumulateUniqueHostPorts records each container's (hostIP, hostPort,
+// protocol) bindings in accumulator, reporting a duplicate for any binding
+// already claimed. Bindings that differ in host IP or protocol do not
+// conflict.
+func AccumulateUniqueHostPorts(containers []api.Container, accumulator map[string]bool) errs.ValidationErrorList {
+	allErrs := errs.ValidationErrorList{}
+	for ci, ctr := range containers {
+		cErrs := errs.ValidationErrorList{}
+		for pi := range ctr.Ports {
+			port := &ctr.Ports[pi]
+			if port.HostPort == 0 {
+				continue
+			}
+			str := fmt.Sprintf("%s/%s/%d", port.HostIP, strings.ToUpper(string(port.Protocol)), port.HostPort)
+			if accumulator[str] {
+				cErrs = append(cErrs, errs.NewFieldDuplicate("hostPort", str))
+			} else {
+				accumulator[str] = true
+			}
+		}
+		allErrs = append(allErrs, cErrs.PrefixIndex(ci)...)
+	}
+	return allErrs
+}
+
+// checkHostPortConflicts checks for colliding (hostIP, hostPort, protocol)
+// bindings across a slice of containers.
 func checkHostPortConflicts(containers []api.Container) errs.ValidationErrorList {
-	allPorts := map[int]bool{}
-	return AccumulateUniquePorts(containers, allPorts, func(p *api.ContainerPort) int { return p.HostPort })
+	allPorts := map[string]bool{}
+	return AccumulateUniqueHostPorts(containers, allPorts)
 }
 
 func validateExecAction(exec *api.ExecAction) errs.ValidationErrorList {
@@ -633,6 +733,7 @@ func validateExecAction(exec *api.ExecAction) errs.ValidationErrorList {
 	if len(exec.Command) == 0 {
 		allErrors = append(allErrors, errs.NewFieldRequired("command"))
 	}
+	allErrors = append(allErrors, validateEnv(exec.Env).Prefix("env")...)
 	return allErrors
 }
 
@@ -739,6 +840,7 @@ func validateContainers(containers []api.Container, volumes util.StringSet) errs
 		cErrs = append(cErrs, validatePorts(ctr.Ports).Prefix("ports")...)
 		cErrs = append(cErrs, validateEnv(ctr.Env).Prefix("env")...)
 		cErrs = append(cErrs, validateVolumeMounts(ctr.VolumeMounts, volumes).Prefix("volumeMounts")...)
+		cErrs = append(cErrs, validateDevices(ctr.Devices).Prefix("devices")...)
 		cErrs = append(cErrs, validatePullPolicy(&ctr).Prefix("pullPolicy")...)
 		cErrs = append(cErrs, ValidateResourceRequirements(&ctr.Resources).Prefix("resources")...)
 		allErrs = append(allErrs, cErrs.PrefixIndex(i)...)
@@ -753,6 +855,27 @@ func validateContainers(containers []api.Container, volumes util.StringSet) errs
 	return allErrs
 }
 
+func validateInitContainers(initContainers, containers []api.Container, volumes util.StringSet) errs.ValidationErrorList {
+	allErrs := errs.ValidationErrorList{}
+	if len(initContainers) == 0 {
+		return allErrs
+	}
+	allErrs = append(allErrs, validateContainers(initContainers, volumes)...)
+
+	// Names must also be unique across the regular containers.
+	allNames := util.StringSet{}
+	for _, ctr := range containers {
+		allNames.Insert(ctr.Name)
+	}
+	for i, ctr := range initContainers {
+		if allNames.Has(ctr.Name) {
+			cErrs := errs.ValidationErrorList{errs.NewFieldDuplicate("name", ctr.Name)}
+			allErrs = append(allErrs, cErrs.PrefixIndex(i)...)
+		}
+	}
+	return allErrs
+}
+
 var supportedManifestVersions = util.NewStringSet("v1beta1", "v1beta2")
 
 // ValidateManifest tests that the specified ContainerManifest has valid data.
@@ -771,8 +894,18 @@ func ValidateManifest(manifest *api.ContainerManifest) errs.ValidationErrorList
 	allVolumes, vErrs := validateVolumes(manifest.Volumes)
 	allErrs = append(allErrs, vErrs.Prefix("volumes")...)
 	allErrs = append(allErrs, validateContainers(manifest.Containers, allVolumes).Prefix("containers")...)
+	allErrs = append(allErrs, validateInitContainers(manifest.InitContainers, manifest.Containers, allVolumes).Prefix("initContainers")...)
 	allErrs = append(allErrs, validateRestartPolicy(&manifest.RestartPolicy).Prefix("restartPolicy")...)
 	allErrs = append(allErrs, validateDNSPolicy(&manifest.DNSPolicy).Prefix("dnsPolicy")...)
+	allErrs = append(allErrs, validatePodDNSConfig(manifest.DNSConfig, &manifest.DNSPolicy).Prefix("dnsConfig")...)
+	allErrs = append(allErrs, validateHostAliases(manifest.HostAliases)...)
+	allErrs = append(allErrs, validateSysctls(manifest.Sysctls)...)
+	if len(manifest.Hostname) > 0 && !util.IsDNS1123Label(manifest.Hostname) {
+		allErrs = append(allErrs, errs.NewFieldInvalid("hostname", manifest.Hostname, dns1123LabelErrorMsg))
+	}
+	if len(manifest.Subdomain) > 0 && !util.IsDNS1123Label(manifest.Subdomain) {
+		allErrs = append(allErrs, errs.NewFieldInvalid("subdomain", manifest.Subdomain, dns1123LabelErrorMsg))
+	}
 	return allErrs
 }
 
@@ -793,7 +926,7 @@ func validateRestartPolicy(restartPolicy *api.RestartPolicy) errs.ValidationErro
 func validateDNSPolicy(dnsPolicy *api.DNSPolicy) errs.ValidationErrorList {
 	allErrors := errs.ValidationErrorList{}
 	switch *dnsPolicy {
-	case api.DNSClusterFirst, api.DNSDefault:
+	case api.DNSClusterFirst, api.DNSDefault, api.DNSNone:
 		break
 	case "":
 		allErrors = append(allErrors, errs.NewFieldRequired(""))
@@ -803,6 +936,71 @@ func validateDNSPolicy(dnsPolicy *api.DNSPolicy) errs.ValidationErrorList {
 	return allErrors
 }
 
+func validatePodDNSConfig(dnsConfig *api.PodDNSConfig, dnsPolicy *api.DNSPolicy) errs.ValidationErrorList {
+	allErrors := errs.ValidationErrorList{}
+	// With DNSNone the pod's dnsConfig is the entire resolver configuration,
+	// so it must provide at least one nameserver.
+	if dnsPolicy != nil && *dnsPolicy == api.DNSNone {
+		if dnsConfig == nil {
+			allErrors = append(allErrors, errs.NewFieldRequired(""))
+			return allErrors
+		}
+		if len(dnsConfig.Nameservers) == 0 {
+			allErrors = append(allErrors, errs.NewFieldRequired("nameservers"))
+		}
+	}
+	if dnsConfig == nil {
+		return allErrors
+	}
+	for i, nameserver := range dnsConfig.Nameservers {
+		if net.ParseIP(nameserver) == nil {
+			allErrors = append(allErrors, errs.NewFieldInvalid(fmt.Sprintf("nameservers[%d]", i), nameserver, "must be a valid IP address"))
+		}
+	}
+	for i, search := range dnsConfig.Searches {
+		if !util.IsDNS1123Subdomain(search) {
+			allErrors = append(allErrors, errs.NewFieldInvalid(fmt.Sprintf("searches[%d]", i), search, dnsSubdomainErrorMsg))
+		}
+	}
+	for i, option := range dnsConfig.Options {
+		if len(option.Name) == 0 {
+			allErrors = append(allErrors, errs.NewFieldRequired(fmt.Sprintf("options[%d].name", i)))
+		}
+	}
+	return allErrors
+}
+
+func validateHostAliases(hostAliases []api.HostAlias) errs.ValidationErrorList {
+	allErrors := errs.ValidationErrorList{}
+	for i, hostAlias := range hostAliases {
+		if net.ParseIP(hostAlias.IP) == nil {
+			allErrors = append(allErrors, errs.NewFieldInvalid(fmt.Sprintf("hostAliases[%d].ip", i), hostAlias.IP, "must be a valid IP address"))
+		}
+		for j, hostname := range hostAlias.Hostnames {
+			if !util.IsDNS1123Subdomain(hostname) {
+				allErrors = append(allErrors, errs.NewFieldInvalid(fmt.Sprintf("hostAliases[%d].hostnames[%d]", i, j), hostname, dnsSubdomainErrorMsg))
+			}
+		}
+	}
+	return allErrors
+}
+
+func validateSysctls(sysctls []api.Sysctl) errs.ValidationErrorList {
+	allErrors := errs.ValidationErrorList{}
+	names := map[string]bool{}
+	for i, sysctl := range sysctls {
+		if len(sysctl.Name) == 0 {
+			allErrors = append(allErrors, errs.NewFieldRequired(fmt.Sprintf("sysctls[%d].name", i)))
+		} else if !util.IsValidSysctlName(sysctl.Name) {
+			allErrors = append(allErrors, errs.NewFieldInvalid(fmt.Sprintf("sysctls[%d].name", i), sysctl.Name, "must consist of dot-separated alphanumeric tokens"))
+		} else if names[sysctl.Name] {
+			allErrors = append(allErrors, errs.NewFieldDuplicate(fmt.Sprintf("sysctls[%d].name", i), sysctl.Name))
+		}
+		names[sysctl.Name] = true
+	}
+	return allErrors
+}
+
 func validateHostNetwork(hostNetwork bool, containers []api.Container) errs.ValidationErrorList {
 	allErrors := errs.ValidationErrorList{}
 	if hostNetwork {
@@ -836,8 +1034,18 @@ func ValidatePodSpec(spec *api.PodSpec) errs.ValidationErrorList {
 	allVolumes, vErrs := validateVolumes(spec.Volumes)
 	allErrs = append(allErrs, vErrs.Prefix("volumes")...)
 	allErrs = append(allErrs, validateContainers(spec.Containers, allVolumes).Prefix("containers")...)
+	allErrs = append(allErrs, validateInitContainers(spec.InitContainers, spec.Containers, allVolumes).Prefix("initContainers")...)
 	allErrs = append(allErrs, validateRestartPolicy(&spec.RestartPolicy).Prefix("restartPolicy")...)
 	allErrs = append(allErrs, validateDNSPolicy(&spec.DNSPolicy).Prefix("dnsPolicy")...)
+	allErrs = append(allErrs, validatePodDNSConfig(spec.DNSConfig, &spec.DNSPolicy).Prefix("dnsConfig")...)
+	allErrs = append(allErrs, validateHostAliases(spec.HostAliases)...)
+	allErrs = append(allErrs, validateSysctls(spec.Sysctls)...)
+	if len(spec.Hostname) > 0 && !util.IsDNS1123Label(spec.Hostname) {
+		allErrs = append(allErrs, errs.NewFieldInvalid("hostname", spec.Hostname, dns1123LabelErrorMsg))
+	}
+	if len(spec.Subdomain) > 0 && !util.IsDNS1123Label(spec.Subdomain) {
+		allErrs = append(allErrs, errs.NewFieldInvalid("subdomain", spec.Subdomain, dns1123LabelErrorMsg))
+	}
 	allErrs = append(allErrs, ValidateLabels(spec.NodeSelector, "nodeSelector")...)
 	allErrs = append(allErrs, validateHostNetwork(spec.HostNetwork, spec.Containers).Prefix("hostNetwork")...)
 	return allErrs
@@ -817,6 +817,24 @@ func TestValidateContainers(t *testing.T) {
 			ImagePullPolicy: "IfNotPresent",
 		},
 		{Name: "abc-1234", Image: "image", Privileged: true, ImagePullPolicy: "IfNotPresent"},
+		{
+			Name:  "same-host-port-different-protocol",
+			Image: "image",
+			Ports: []api.ContainerPort{
+				{ContainerPort: 80, HostPort: 80, Protocol: "TCP"},
+				{ContainerPort: 80, HostPort: 80, Protocol: "UDP"},
+			},
+			ImagePullPolicy: "IfNotPresent",
+		},
+		{
+			Name:  "same-host-port-different-host-ip",
+			Image: "image",
+			Ports: []api.ContainerPort{
+				{ContainerPort: 81, HostPort: 81, HostIP: "127.0.0.1", Protocol: "TCP"},
+				{ContainerPort: 81, HostPort: 81, HostIP: "127.0.0.2", Protocol: "TCP"},
+			},
+			ImagePullPolicy: "IfNotPresent",
+		},
 	}
 	if errs := validateContainers(successCase, volumes); len(errs) != 0 {
 		t.Errorf("expected success: %v", errs)
@@ -840,6 +858,12 @@ func TestValidateContainers(t *testing.T) {
 			{Name: "def", Image: "image", Ports: []api.ContainerPort{{ContainerPort: 81, HostPort: 80, Protocol: "TCP"}},
 				ImagePullPolicy: "IfNotPresent"},
 		},
+		"host port binding not unique": {
+			{Name: "abc", Image: "image", Ports: []api.ContainerPort{{ContainerPort: 80, HostPort: 80, HostIP: "127.0.0.1", Protocol: "UDP"}},
+				ImagePullPolicy: "IfNotPresent"},
+			{Name: "def", Image: "image", Ports: []api.ContainerPort{{ContainerPort: 81, HostPort: 80, HostIP: "127.0.0.1", Protocol: "UDP"}},
+				ImagePullPolicy: "IfNotPresent"},
+		},
 		"invalid env var name": {
 			{Name: "abc", Image: "image", Env: []api.EnvVar{{Name: "ev.1"}}, ImagePullPolicy: "IfNotPresent"},
 		},
@@ -1004,7 +1028,7 @@ func TestValidateRestartPolicy(t *testing.T) {
 }
 
 func TestValidateDNSPolicy(t *testing.T) {
-	successCases := []api.DNSPolicy{api.DNSClusterFirst, api.DNSDefault, api.DNSPolicy(api.DNSClusterFirst)}
+	successCases := []api.DNSPolicy{api.DNSClusterFirst, api.DNSDefault, api.DNSNone, api.DNSPolicy(api.DNSClusterFirst)}
 	for _, policy := range successCases {
 		if errs := validateDNSPolicy(&policy); len(errs) != 0 {
 			t.Errorf("expected success: %v", errs)
@@ -1019,6 +1043,125 @@ func TestValidateDNSPolicy(t *testing.T) {
 	}
 }
 
+func TestValidatePodDNSConfig(t *testing.T) {
+	clusterFirst := api.DNSClusterFirst
+	none := api.DNSNone
+	optionValue := "2"
+
+	successCases := []struct {
+		dnsConfig *api.PodDNSConfig
+		dnsPolicy *api.DNSPolicy
+	}{
+		{nil, &clusterFirst},
+		{&api.PodDNSConfig{}, &clusterFirst},
+		{&api.PodDNSConfig{Nameservers: []string{"1.2.3.4"}}, &none},
+		{
+			&api.PodDNSConfig{
+				Nameservers: []string{"1.2.3.4", "fd00::1"},
+				Searches:    []string{"my.domain", "svc.cluster.local"},
+				Options: []api.PodDNSConfigOption{
+					{Name: "ndots", Value: &optionValue},
+					{Name: "debug"},
+				},
+			},
+			&clusterFirst,
+		},
+	}
+	for i, tc := range successCases {
+		if errs := validatePodDNSConfig(tc.dnsConfig, tc.dnsPolicy); len(errs) != 0 {
+			t.Errorf("[%d] expected success: %v", i, errs)
+		}
+	}
+
+	errorCases := []struct {
+		dnsConfig *api.PodDNSConfig
+		dnsPolicy *api.DNSPolicy
+	}{
+		{nil, &none},
+		{&api.PodDNSConfig{}, &none},
+		{&api.PodDNSConfig{Nameservers: []string{"not-an-ip"}}, &clusterFirst},
+		{&api.PodDNSConfig{Searches: []string{"-invalid.domain"}}, &clusterFirst},
+		{&api.PodDNSConfig{Options: []api.PodDNSConfigOption{{Value: &optionValue}}}, &clusterFirst},
+	}
+	for i, tc := range errorCases {
+		if errs := validatePodDNSConfig(tc.dnsConfig, tc.dnsPolicy); len(errs) == 0 {
+			t.Errorf("[%d] expected failure", i)
+		}
+	}
+}
+
+func TestValidateHostAliases(t *testing.T) {
+	successCases := [][]api.HostAlias{
+		{},
+		{{IP: "1.2.3.4", Hostnames: []string{"foo"}}},
+		{{IP: "fd00::1", Hostnames: []string{"foo", "foo.bar"}}},
+	}
+	for i, hostAliases := range successCases {
+		if errs := validateHostAliases(hostAliases); len(errs) != 0 {
+			t.Errorf("[%d] expected success: %v", i, errs)
+		}
+	}
+
+	errorCases := [][]api.HostAlias{
+		{{IP: "not-an-ip", Hostnames: []string{"foo"}}},
+		{{IP: "1.2.3.4", Hostnames: []string{"-invalid"}}},
+	}
+	for i, hostAliases := range errorCases {
+		if errs := validateHostAliases(hostAliases); len(errs) == 0 {
+			t.Errorf("[%d] expected failure", i)
+		}
+	}
+}
+
+func TestValidateDevices(t *testing.T) {
+	successCases := [][]api.Device{
+		{},
+		{{HostPath: "/dev/fuse"}},
+		{{HostPath: "/dev/nvidia0", ContainerPath: "/dev/nvidia0", Permissions: "rw"}},
+	}
+	for i, devices := range successCases {
+		if errs := validateDevices(devices); len(errs) != 0 {
+			t.Errorf("[%d] expected success: %v", i, errs)
+		}
+	}
+
+	errorCases := [][]api.Device{
+		{{HostPath: ""}},
+		{{HostPath: "dev/fuse"}},
+		{{HostPath: "/dev/fuse", ContainerPath: "dev/fuse"}},
+		{{HostPath: "/dev/fuse", Permissions: "rwx"}},
+	}
+	for i, devices := range errorCases {
+		if errs := validateDevices(devices); len(errs) == 0 {
+			t.Errorf("[%d] expected failure", i)
+		}
+	}
+}
+
+func TestValidateSysctls(t *testing.T) {
+	successCases := [][]api.Sysctl{
+		{},
+		{{Name: "net.core.somaxconn", Value: "1024"}},
+		{{Name: "kernel.shm_rmid_forced", Value: "1"}, {Name: "net.ipv4.tcp_syncookies", Value: "0"}},
+	}
+	for i, sysctls := range successCases {
+		if errs := validateSysctls(sysctls); len(errs) != 0 {
+			t.Errorf("[%d] expected success: %v", i, errs)
+		}
+	}
+
+	errorCases := [][]api.Sysctl{
+		{{Name: "", Value: "1"}},
+		{{Name: "not a sysctl", Value: "1"}},
+		{{Name: "net.core.somaxconn", Value: "1024"}, {Name: "net.core.somaxconn", Value: "512"}},
+	}
+	for i, sysctls := range errorCases {
+		if errs := validateSysctls(sysctls); len(errs) == 0 {
+			t.Errorf("[%d] expected failure", i)
+		}
+	}
+}
+
 func TestValidatePodSpec(t *testing.T) {
 	successCases := []api.PodSpec{
 		{ // Populate basic fields, leave defaults for most.
@@ -57,10 +57,12 @@ type PortForwarder struct {
 	Ready      chan struct{}
 }
 
-// ForwardedPort contains a Local:Remote port pairing.
+// ForwardedPort contains a Local:Remote port pairing and the protocol
+// ("tcp" or "udp") to forward. An empty protocol means "tcp".
 type ForwardedPort struct {
-	Local  uint16
-	Remote uint16
+	Local    uint16
+	Remote   uint16
+	Protocol string
 }
 
 /*
@@ -76,10 +78,23 @@ type ForwardedPort struct {
 	:5000
 	- selects a random available local port,
 	  forwards from localhost:<random port> to pod:5000
+
+	udp/5353:53
+	- forwards UDP packets from localhost:5353 to pod:53
 */
 func parsePorts(ports []string) ([]ForwardedPort, error) {
 	var forwards []ForwardedPort
 	for _, portString := range ports {
+		protocol := api.PortForwardProtocolTCP
+		if parts := strings.SplitN(portString, "/", 2); len(parts) == 2 {
+			switch parts[0] {
+			case api.PortForwardProtocolTCP, api.PortForwardProtocolUDP:
+				protocol = parts[0]
+			default:
+				return nil, fmt.Errorf("Invalid port forwarding protocol '%s'", parts[0])
+			}
+			portString = parts[1]
+		}
 		parts := strings.Split(portString, ":")
 		var localString, remoteString string
 		if len(parts) == 1 {
@@ -109,7 +124,7 @@ func parsePorts(ports []string) ([]ForwardedPort, error) {
 			return nil, fmt.Errorf("Remote port must be > 0")
 		}
 
-		forwards = append(forwards, ForwardedPort{uint16(localPort), uint16(remotePort)})
+		forwards = append(forwards, ForwardedPort{uint16(localPort), uint16(remotePort), protocol})
 	}
 
 	return forwards, nil
@@ -162,7 +177,7 @@ func (pf *PortForwarder) forward() error {
 	for _, port := range pf.ports {
 		err = pf.listenOnPort(&port)
 		if err != nil {
-			glog.Warningf("Unable to listen on port %d: %v", port, err)
+			glog.Warningf("Unable to listen on %s port %d: %v", port.Protocol, port.Local, err)
 		}
 		listenSuccess = true
 	}
@@ -186,6 +201,9 @@ func (pf *PortForwarder) forward() error {
 // listenOnPort delegates listener creation and waits for new connections
 // in the background.
 func (pf *PortForwarder) listenOnPort(port *ForwardedPort) error {
+	if port.Protocol == api.PortForwardProtocolUDP {
+		return pf.listenOnUDPPort(port)
+	}
 	listener, err := pf.getListener("tcp", "localhost", port)
 	if err != nil {
 		return err
@@ -197,6 +215,108 @@ func (pf *PortForwarder) listenOnPort(port *ForwardedPort) error {
 	return nil
 }
 
+// listenOnUDPPort creates a UDP listener and forwards packets in the
+// background. Each distinct peer address gets its own stream pair to the
+// remote port; replies read from the stream are sent back to that peer.
+func (pf *PortForwarder) listenOnUDPPort(port *ForwardedPort) error {
+	conn, err := net.ListenUDP("udp", &net.UDPAddr{IP: net.ParseIP("127.0.0.1"), Port: int(port.Local)})
+	if err != nil {
+		glog.Errorf("Unable to create UDP listener: Error %s", err)
+		return err
+	}
+	pf.listeners = append(pf.listeners, conn)
+
+	localPort := uint16(conn.LocalAddr().(*net.UDPAddr).Port)
+	port.Local = localPort
+	glog.Infof("Forwarding from %d -> %d (udp)", localPort, port.Remote)
+
+	go pf.waitForUDPPackets(conn, *port)
+
+	return nil
+}
+
+// waitForUDPPackets reads packets from the UDP listener and dispatches them
+// to a per-peer stream, creating the stream on first use.
+func (pf *PortForwarder) waitForUDPPackets(conn *net.UDPConn, port ForwardedPort) {
+	streams := make(map[string]httpstream.Stream)
+	buffer := make([]byte, 65536)
+	for {
+		n, peer, err := conn.ReadFromUDP(buffer)
+		if err != nil {
+			if !strings.Contains(strings.ToLower(err.Error()), "use of closed network connection") {
+				glog.Errorf("Error reading UDP packet on port %d: %v", port.Local, err)
+			}
+			return
+		}
+		dataStream, ok := streams[peer.String()]
+		if !ok {
+			dataStream, err = pf.createUDPStream(conn, peer, port)
+			if err != nil {
+				glog.Errorf("Error creating UDP stream for port %d -> %d: %v", port.Local, port.Remote, err)
+				continue
+			}
+			streams[peer.String()] = dataStream
+		}
+		if _, err := dataStream.Write(buffer[0:n]); err != nil {
+			glog.Errorf("Error forwarding UDP packet to remote stream: %v", err)
+			dataStream.Reset()
+			delete(streams, peer.String())
+		}
+	}
+}
+
+// createUDPStream creates the error/data stream pair for a UDP peer and
+// starts copying replies from the data stream back to the peer.
+func (pf *PortForwarder) createUDPStream(conn *net.UDPConn, peer *net.UDPAddr, port ForwardedPort) (httpstream.Stream, error) {
+	headers := http.Header{}
+	headers.Set(api.StreamType, api.StreamTypeError)
+	headers.Set(api.PortHeader, fmt.Sprintf("%d", port.Remote))
+	headers.Set(api.ProtocolHeader, api.PortForwardProtocolUDP)
+	errorStream, err := pf.streamConn.CreateStream(headers)
+	if err != nil {
+		return nil, fmt.Errorf("error creating error stream: %v", err)
+	}
+	go func() {
+		message, err := ioutil.ReadAll(errorStream)
+		if err != nil && err != io.EOF {
+			glog.Errorf("Error reading from error stream for port %d -> %d: %v", port.Local, port.Remote, err)
+		}
+		if len(message) > 0 {
+			glog.Errorf("An error occurred forwarding %d -> %d: %v", port.Local, port.Remote, string(message))
+		}
+	}()
+
+	headers.Set(api.StreamType, api.StreamTypeData)
+	dataStream, err := pf.streamConn.CreateStream(headers)
+	if err != nil {
+		errorStream.Reset()
+		return nil, fmt.Errorf("error creating data stream: %v", err)
+	}
+
+	go func() {
+		defer dataStream.Reset()
+		defer errorStream.Reset()
+		buffer := make([]byte, 65536)
+		for {
+			n, err := dataStream.Read(buffer)
+			if n > 0 {
+				if _, werr := conn.WriteToUDP(buffer[0:n], peer); werr != nil {
+					glog.Errorf("Error sending UDP reply to %s: %v", peer, werr)
+					return
+				}
+			}
+			if err != nil {
+				if err != io.EOF {
+					glog.Errorf("Error reading from remote UDP stream: %v", err)
+				}
+				return
+			}
+		}
+	}()
+
+	return dataStream, nil
+}
+
 // getListener creates a listener on the interface targeted by the given hostname on the given port with
 // the given protocol. protocol is in net.Listen style which basically admits values like tcp, tcp4, tcp6
 func (pf *PortForwarder) getListener(protocol string, hostname string, port *ForwardedPort) (net.Listener, error) {
@@ -52,12 +52,12 @@ func TestParsePortsAndNew(t *testing.T) {
 		{
 			input: []string{"5000", "5000:5000", "8888:5000", "5000:8888", ":5000", "0:5000"},
 			expected: []ForwardedPort{
-				{5000, 5000},
-				{5000, 5000},
-				{8888, 5000},
-				{5000, 8888},
-				{0, 5000},
-				{0, 5000},
+				{5000, 5000, "tcp"},
+				{5000, 5000, "tcp"},
+				{8888, 5000, "tcp"},
+				{5000, 8888, "tcp"},
+				{0, 5000, "tcp"},
+				{0, 5000, "tcp"},
 			},
 		},
 	}
@@ -265,7 +265,7 @@ func TestGetListener(t *testing.T) {
 
 	for i, testCase := range testCases {
 		expectedListenerPort := "12345"
-		listener, err := pf.getListener(testCase.Protocol, testCase.Hostname, &ForwardedPort{12345, 12345})
+		listener, err := pf.getListener(testCase.Protocol, testCase.Hostname, &ForwardedPort{12345, 12345, "tcp"})
 		errorRaised := err != nil
 
 		if testCase.ShouldRaiseError != errorRaised {
@@ -106,6 +106,11 @@ func (eventBroadcaster *eventBroadcasterImpl) StartRecordingToSink(sink EventSin
 			eventCopy := *event
 			event = &eventCopy
 
+			if !eventSpamFilter.allowEvent(event) {
+				glog.V(4).Infof("Event spam filter dropping event: '%v' '%v' for %v/%v", event.Reason, event.Message, event.InvolvedObject.Namespace, event.InvolvedObject.Name)
+				return
+			}
+
 			previousEvent := getEvent(event)
 			updateExistingEvent := previousEvent.Count > 0
 			if updateExistingEvent {
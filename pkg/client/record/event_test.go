@@ -449,14 +449,16 @@ func TestLotsOfEvents(t *testing.T) {
 		loggerCalled <- struct{}{}
 	})
 	recorder := eventBroadcaster.NewRecorder(api.EventSource{Component: "eventTest"})
-	ref := &api.ObjectReference{
-		Kind:       "Pod",
-		Name:       "foo",
-		Namespace:  "baz",
-		UID:        "bar",
-		APIVersion: "version",
-	}
 	for i := 0; i < maxQueuedEvents; i++ {
+		// Use a distinct involved object per event so the spam filter,
+		// which budgets per object, does not drop any of them.
+		ref := &api.ObjectReference{
+			Kind:       "Pod",
+			Name:       "foo-" + strconv.Itoa(i),
+			Namespace:  "baz",
+			UID:        "bar",
+			APIVersion: "version",
+		}
 		go recorder.Eventf(ref, "Reason", strconv.Itoa(i))
 	}
 	// Make sure no events were dropped by either of the listeners.
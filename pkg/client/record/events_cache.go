@@ -18,6 +18,7 @@ package record
 
 import (
 	"sync"
+	"time"
 
 	"github.com/GoogleCloudPlatform/kubernetes/pkg/api"
 	"github.com/GoogleCloudPlatform/kubernetes/pkg/util"
@@ -86,6 +87,78 @@ func getEventFromCache(key string) history {
 	return history{}
 }
 
+const (
+	// Number of events an object may generate in a burst before the spam
+	// filter starts dropping them.
+	spamBurst = 25
+	// Rate at which dropped budget is returned to an object, in events per
+	// second. One token every five minutes keeps a steady trickle from
+	// crash-looping containers without flooding the apiserver.
+	spamRefillRate = 1. / 300.
+)
+
+// spamRecord tracks the remaining event budget for a single object.
+type spamRecord struct {
+	// Number of events the object may still send.
+	tokens float64
+
+	// The time at which tokens was last refilled.
+	lastRefill time.Time
+}
+
+type spamFilter struct {
+	sync.Mutex
+	cache *lru.Cache
+
+	// Clock function, overridable for testing.
+	now func() time.Time
+}
+
+var eventSpamFilter = spamFilter{cache: lru.New(maxLruCacheEntries), now: time.Now}
+
+// allowEvent decides, based on the event budget of the involved object,
+// whether the event may be sent to the sink. Budget is tracked per source and
+// involved object rather than per event, so an object cannot evade the filter
+// by varying messages.
+func (f *spamFilter) allowEvent(event *api.Event) bool {
+	key := getSpamKey(event)
+	f.Lock()
+	defer f.Unlock()
+
+	record := spamRecord{tokens: spamBurst}
+	if value, found := f.cache.Get(key); found {
+		record = value.(spamRecord)
+	}
+
+	now := f.now()
+	if !record.lastRefill.IsZero() {
+		record.tokens += now.Sub(record.lastRefill).Seconds() * spamRefillRate
+		if record.tokens > spamBurst {
+			record.tokens = spamBurst
+		}
+	}
+	record.lastRefill = now
+
+	allowed := record.tokens >= 1
+	if allowed {
+		record.tokens--
+	}
+	f.cache.Add(key, record)
+	return allowed
+}
+
+// getSpamKey builds a key for the spam filter from the event source and the
+// involved object; reason and message are deliberately excluded.
+func getSpamKey(event *api.Event) string {
+	return event.Source.Component +
+		event.Source.Host +
+		event.InvolvedObject.Kind +
+		event.InvolvedObject.Namespace +
+		event.InvolvedObject.Name +
+		string(event.InvolvedObject.UID) +
+		event.InvolvedObject.APIVersion
+}
+
 func getEventKey(event *api.Event) string {
 	return event.Source.Component +
 		event.Source.Host +
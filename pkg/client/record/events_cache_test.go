@@ -17,10 +17,13 @@ limitations under the License.
 package record
 
 import (
+	"fmt"
 	"testing"
+	"time"
 
 	"github.com/GoogleCloudPlatform/kubernetes/pkg/api"
 	"github.com/GoogleCloudPlatform/kubernetes/pkg/util"
+	"github.com/golang/groupcache/lru"
 )
 
 func TestAddOrUpdateEventNoExisting(t *testing.T) {
@@ -186,3 +189,50 @@ func compareEventWithHistoryEntry(expected *api.Event, actual *history, t *testi
 	}
 
 }
+
+func TestSpamFilterDropsFloodingObject(t *testing.T) {
+	filter := spamFilter{cache: lru.New(maxLruCacheEntries), now: time.Now}
+	event := api.Event{
+		Reason: "BackOff",
+		InvolvedObject: api.ObjectReference{
+			Kind:       "Pod",
+			Name:       "crashloop",
+			Namespace:  "default",
+			UID:        "12345",
+			APIVersion: "v1beta1",
+		},
+		Source: api.EventSource{
+			Component: "kubelet",
+			Host:      "kublet.node1",
+		},
+	}
+
+	for i := 0; i < spamBurst; i++ {
+		// Vary the message; the filter must key on the object, not the event.
+		event.Message = fmt.Sprintf("Restarting failed container, attempt %d", i)
+		if !filter.allowEvent(&event) {
+			t.Fatalf("expected event %d within the burst to be allowed", i)
+		}
+	}
+	if filter.allowEvent(&event) {
+		t.Errorf("expected event beyond the burst to be dropped")
+	}
+
+	// Another object is not affected by the flooding one.
+	otherEvent := event
+	otherEvent.InvolvedObject.Name = "quiet"
+	if !filter.allowEvent(&otherEvent) {
+		t.Errorf("expected event for a different object to be allowed")
+	}
+
+	// After the refill interval the object may send one more event.
+	filter.now = func() time.Time {
+		return time.Now().Add(time.Duration(float64(time.Second) / spamRefillRate))
+	}
+	if !filter.allowEvent(&event) {
+		t.Errorf("expected event to be allowed after the refill interval")
+	}
+	if filter.allowEvent(&event) {
+		t.Errorf("expected followup event to be dropped again")
+	}
+}
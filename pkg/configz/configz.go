@@ -0,0 +1,91 @@
+/*
+Copyright 2015 Google Inc. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package configz
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sync"
+)
+
+var (
+	configsGuard sync.RWMutex
+	configs      = map[string]*Config{}
+)
+
+// Config is a named section of the /configz output. The component that
+// registered it keeps the published value up to date with Set as its
+// configuration changes.
+type Config struct {
+	val interface{}
+}
+
+// New registers a section under the given name and returns a handle used to
+// publish values into it. Registering the same name twice is an error.
+func New(name string) (*Config, error) {
+	configsGuard.Lock()
+	defer configsGuard.Unlock()
+	if _, found := configs[name]; found {
+		return nil, fmt.Errorf("configz section %q is already registered", name)
+	}
+	c := &Config{}
+	configs[name] = c
+	return c, nil
+}
+
+// Delete removes the named section from the /configz output.
+func Delete(name string) {
+	configsGuard.Lock()
+	defer configsGuard.Unlock()
+	delete(configs, name)
+}
+
+// Set replaces the value served for the section.
+func (c *Config) Set(val interface{}) {
+	configsGuard.Lock()
+	defer configsGuard.Unlock()
+	c.val = val
+}
+
+// MarshalJSON serializes the section as its current value.
+func (c *Config) MarshalJSON() ([]byte, error) {
+	return json.Marshal(c.val)
+}
+
+// InstallHandler registers a handler serving all registered sections as a
+// single JSON object on the path "/configz" to mux.
+func InstallHandler(mux mux) {
+	mux.Handle("/configz", http.HandlerFunc(handle))
+}
+
+// mux is an interface describing the methods InstallHandler requires.
+type mux interface {
+	Handle(pattern string, handler http.Handler)
+}
+
+func handle(w http.ResponseWriter, r *http.Request) {
+	configsGuard.RLock()
+	data, err := json.Marshal(configs)
+	configsGuard.RUnlock()
+	if err != nil {
+		http.Error(w, fmt.Sprintf("error marshaling configuration: %v", err), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(data)
+}
@@ -0,0 +1,73 @@
+/*
+Copyright 2015 Google Inc. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package configz
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func serveConfigz(t *testing.T) string {
+	mux := http.NewServeMux()
+	InstallHandler(mux)
+	req, err := http.NewRequest("GET", "http://example.com/configz", nil)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	w := httptest.NewRecorder()
+	mux.ServeHTTP(w, req)
+	if w.Code != http.StatusOK {
+		t.Errorf("Expected %v, got %v", http.StatusOK, w.Code)
+	}
+	return w.Body.String()
+}
+
+func TestConfigz(t *testing.T) {
+	v, err := New("testing")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	defer Delete("testing")
+
+	v.Set(map[string]interface{}{"key": "value"})
+	if body := serveConfigz(t); body != `{"testing":{"key":"value"}}` {
+		t.Errorf("Unexpected response: %v", body)
+	}
+
+	// A later Set must be visible on the next request.
+	v.Set(map[string]interface{}{"key": "replacement"})
+	if body := serveConfigz(t); body != `{"testing":{"key":"replacement"}}` {
+		t.Errorf("Unexpected response: %v", body)
+	}
+
+	if _, err := New("testing"); err == nil {
+		t.Errorf("Expected error registering the same section twice")
+	}
+}
+
+func TestDelete(t *testing.T) {
+	v, err := New("doomed")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	v.Set("value")
+	Delete("doomed")
+	if body := serveConfigz(t); body != `{}` {
+		t.Errorf("Unexpected response: %v", body)
+	}
+}
@@ -0,0 +1,246 @@
+/*
+Copyright 2015 Google Inc. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package kubelet
+
+import (
+	"fmt"
+
+	"github.com/GoogleCloudPlatform/kubernetes/pkg/api"
+	"github.com/GoogleCloudPlatform/kubernetes/pkg/api/resource"
+	"github.com/GoogleCloudPlatform/kubernetes/pkg/api/validation"
+	kubecontainer "github.com/GoogleCloudPlatform/kubernetes/pkg/kubelet/container"
+	"github.com/GoogleCloudPlatform/kubernetes/pkg/scheduler"
+	utilErrors "github.com/GoogleCloudPlatform/kubernetes/pkg/util/errors"
+	"github.com/golang/glog"
+)
+
+// podsByAdmissionOrder sorts critical pods before all others and otherwise
+// respects creation time, so critical pods get the first claim on shared
+// resources such as host ports and capacity. On a full node this lets a
+// critical pod displace a non-critical one: the non-critical pod loses the
+// conflict, is rejected, and its containers are killed as undesired.
+type podsByAdmissionOrder []*api.Pod
+
+func (s podsByAdmissionOrder) Len() int {
+	return len(s)
+}
+
+func (s podsByAdmissionOrder) Swap(i, j int) {
+	s[i], s[j] = s[j], s[i]
+}
+
+func (s podsByAdmissionOrder) Less(i, j int) bool {
+	if critical := api.IsCriticalPod(s[i]); critical != api.IsCriticalPod(s[j]) {
+		return critical
+	}
+	return s[i].CreationTimestamp.Before(s[j].CreationTimestamp)
+}
+
+// podAdmitHandler checks whether a single pod can be admitted to this node.
+// Handlers are consulted in order and may accumulate state across calls
+// within a single admission pass (e.g. host ports claimed by previously
+// admitted pods), so a fresh chain must be built for every pass.
+type podAdmitHandler interface {
+	// Admit returns true if the pod fits on the node. When it does not,
+	// reason is a camelCase event reason and message is a human readable
+	// explanation of the rejection.
+	Admit(pod *api.Pod) (ok bool, reason, message string)
+}
+
+// validationAdmitHandler rejects pods whose spec fails api validation, so a
+// bad volume reference or a duplicate container name surfaces as a failed
+// pod with an actionable message instead of cryptic docker errors mid-sync.
+// Pods from the apiserver were fully validated at admission there; this is
+// a backstop for the file and http sources, which bypass the apiserver.
+type validationAdmitHandler struct{}
+
+func (h *validationAdmitHandler) Admit(pod *api.Pod) (bool, string, string) {
+	source := pod.Annotations[ConfigSourceAnnotationKey]
+	if source != FileSource && source != HTTPSource {
+		return true, "", ""
+	}
+	if errs := validation.ValidatePodSpec(&pod.Spec); len(errs) != 0 {
+		glog.Errorf("Pod %q failed validation, rejecting: %v", kubecontainer.GetPodFullName(pod), errs)
+		return false, "failedValidation", fmt.Sprintf("Pod cannot be started because its spec is invalid: %v", utilErrors.NewAggregate(errs))
+	}
+	return true, "", ""
+}
+
+// hostPortAdmitHandler rejects pods whose host port bindings collide with
+// those of previously admitted pods. Bindings that differ in host IP or
+// protocol do not conflict.
+type hostPortAdmitHandler struct {
+	ports map[string]bool
+}
+
+func newHostPortAdmitHandler() *hostPortAdmitHandler {
+	return &hostPortAdmitHandler{ports: map[string]bool{}}
+}
+
+func (h *hostPortAdmitHandler) Admit(pod *api.Pod) (bool, string, string) {
+	if errs := validation.AccumulateUniqueHostPorts(pod.Spec.Containers, h.ports); len(errs) != 0 {
+		glog.Errorf("Pod %q: HostPort is already allocated, ignoring: %v", kubecontainer.GetPodFullName(pod), errs)
+		return false, "hostPortConflict", "Pod cannot be started due to host port conflict"
+	}
+	return true, "", ""
+}
+
+// nodeSelectorAdmitHandler rejects pods whose node selector does not match
+// the node's labels.
+type nodeSelectorAdmitHandler struct {
+	node *api.Node
+}
+
+func (h *nodeSelectorAdmitHandler) Admit(pod *api.Pod) (bool, string, string) {
+	if !scheduler.PodMatchesNodeLabels(pod, h.node) {
+		return false, "nodeSelectorMismatching", "Pod cannot be started due to node selector mismatch"
+	}
+	return true, "", ""
+}
+
+// taintAdmitHandler rejects pods that do not tolerate the node's NoExecute
+// taints. This is a node-side backstop; the scheduler is expected to respect
+// taints when placing pods.
+type taintAdmitHandler struct {
+	noExecuteTaints []api.Taint
+}
+
+func newTaintAdmitHandler(taints []api.Taint) *taintAdmitHandler {
+	h := &taintAdmitHandler{}
+	for _, taint := range taints {
+		if taint.Effect == api.TaintEffectNoExecute {
+			h.noExecuteTaints = append(h.noExecuteTaints, taint)
+		}
+	}
+	return h
+}
+
+func (h *taintAdmitHandler) Admit(pod *api.Pod) (bool, string, string) {
+	if len(h.noExecuteTaints) == 0 {
+		return true, "", ""
+	}
+	tolerations, err := api.GetTolerationsFromPodAnnotations(pod.Annotations)
+	if err != nil {
+		glog.Errorf("Invalid tolerations on pod %q: %v", kubecontainer.GetPodFullName(pod), err)
+	}
+	for _, taint := range h.noExecuteTaints {
+		if !api.TaintToleratedByTolerations(taint, tolerations) {
+			return false, "taintsNotTolerated", "Pod cannot be started because it does not tolerate the node's taints"
+		}
+	}
+	return true, "", ""
+}
+
+// sysctlAdmitHandler rejects pods that request sysctls this node does not
+// allow.
+type sysctlAdmitHandler struct {
+	kl *Kubelet
+}
+
+func (h *sysctlAdmitHandler) Admit(pod *api.Pod) (bool, string, string) {
+	for _, sysctl := range pod.Spec.Sysctls {
+		if !h.kl.sysctlAllowed(sysctl.Name) {
+			glog.Errorf("Pod %q: sysctl %q is not allowed on this node", kubecontainer.GetPodFullName(pod), sysctl.Name)
+			return false, "forbiddenSysctl", "Pod cannot be started because it requests a sysctl that is not allowed on this node"
+		}
+	}
+	return true, "", ""
+}
+
+// podCountAdmitHandler rejects pods beyond the node's maximum pod count.
+type podCountAdmitHandler struct {
+	maxPods  int
+	admitted int
+}
+
+func (h *podCountAdmitHandler) Admit(pod *api.Pod) (bool, string, string) {
+	if h.maxPods > 0 && h.admitted >= h.maxPods {
+		return false, "maxPodsExceeded", fmt.Sprintf("Pod cannot be started because the node limit of %d pods has been reached", h.maxPods)
+	}
+	h.admitted++
+	return true, "", ""
+}
+
+// capacityAdmitHandler rejects pods whose resource limits do not fit in the
+// capacity left over by previously admitted pods. Mirrors the accounting of
+// scheduler.CheckPodsExceedingCapacity, applied one pod at a time. Because
+// pods are admitted in priority order, a pod whose cpu, memory or ephemeral
+// storage no longer fits is also the one evicted when the node is full.
+type capacityAdmitHandler struct {
+	totalMilliCPU     int64
+	totalMemory       int64
+	totalStorage      int64
+	milliCPURequested int64
+	memoryRequested   int64
+	storageRequested  int64
+}
+
+func newCapacityAdmitHandler(capacity api.ResourceList) *capacityAdmitHandler {
+	return &capacityAdmitHandler{
+		totalMilliCPU: capacity.Cpu().MilliValue(),
+		totalMemory:   capacity.Memory().Value(),
+		totalStorage:  capacity.EphemeralStorage().Value(),
+	}
+}
+
+func (h *capacityAdmitHandler) Admit(pod *api.Pod) (bool, string, string) {
+	var milliCPU, memory, storage int64
+	for ix := range pod.Spec.Containers {
+		limits := pod.Spec.Containers[ix].Resources.Limits
+		milliCPU += limits.Cpu().MilliValue()
+		memory += limits.Memory().Value()
+		storage += limits.EphemeralStorage().Value()
+	}
+	fitsCPU := h.totalMilliCPU == 0 || h.totalMilliCPU-h.milliCPURequested >= milliCPU
+	fitsMemory := h.totalMemory == 0 || h.totalMemory-h.memoryRequested >= memory
+	fitsStorage := h.totalStorage == 0 || h.totalStorage-h.storageRequested >= storage
+	if !fitsCPU || !fitsMemory || !fitsStorage {
+		return false, "capacityExceeded", "Pod cannot be started due to exceeded capacity"
+	}
+	h.milliCPURequested += milliCPU
+	h.memoryRequested += memory
+	h.storageRequested += storage
+	return true, "", ""
+}
+
+// admissionHandlers builds the ordered chain of admission checks applied to
+// the desired pods on every sync. Handlers that depend on node state are
+// skipped, rather than rejecting pods, when that state is unavailable.
+func (kl *Kubelet) admissionHandlers() []podAdmitHandler {
+	handlers := []podAdmitHandler{&validationAdmitHandler{}, newHostPortAdmitHandler()}
+	if node, err := kl.GetNode(); err != nil {
+		glog.Errorf("error getting node: %v", err)
+	} else {
+		handlers = append(handlers, &nodeSelectorAdmitHandler{node: node})
+	}
+	handlers = append(handlers, newTaintAdmitHandler(kl.nodeTaints), &sysctlAdmitHandler{kl: kl})
+	if info, err := kl.GetCachedMachineInfo(); err != nil {
+		glog.Errorf("error getting machine info: %v", err)
+	} else {
+		capacity := CapacityFromMachineInfo(info)
+		// Ephemeral storage is backed by the filesystem holding container
+		// writable layers.
+		if fsInfo, err := kl.cadvisor.DockerImagesFsInfo(); err != nil {
+			glog.Errorf("error getting docker images fs info: %v", err)
+		} else {
+			capacity[api.ResourceEphemeralStorage] = *resource.NewQuantity(int64(fsInfo.Capacity), resource.BinarySI)
+		}
+		handlers = append(handlers, newCapacityAdmitHandler(capacity))
+	}
+	handlers = append(handlers, &podCountAdmitHandler{maxPods: kl.maxPods})
+	return handlers
+}
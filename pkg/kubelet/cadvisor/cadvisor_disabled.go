@@ -0,0 +1,75 @@
+/*
+Copyright 2015 Google Inc. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cadvisor
+
+import (
+	"errors"
+
+	"github.com/google/cadvisor/events"
+	cadvisorApi "github.com/google/cadvisor/info/v1"
+	cadvisorApiV2 "github.com/google/cadvisor/info/v2"
+)
+
+// ErrDisabled is returned by every method of the disabled implementation, so
+// that callers can tell an administratively disabled cAdvisor from a
+// transient failure and degrade gracefully.
+var ErrDisabled = errors.New("cAdvisor is disabled")
+
+// cadvisorDisabled satisfies the interface without collecting anything, for
+// kubelets configured to run without cAdvisor.
+type cadvisorDisabled struct {
+}
+
+var _ Interface = new(cadvisorDisabled)
+
+// NewDisabled returns a cAdvisor implementation for kubelets running without
+// stats collection.
+func NewDisabled() Interface {
+	return &cadvisorDisabled{}
+}
+
+func (cd *cadvisorDisabled) DockerContainer(name string, req *cadvisorApi.ContainerInfoRequest) (cadvisorApi.ContainerInfo, error) {
+	return cadvisorApi.ContainerInfo{}, ErrDisabled
+}
+
+func (cd *cadvisorDisabled) ContainerInfo(name string, req *cadvisorApi.ContainerInfoRequest) (*cadvisorApi.ContainerInfo, error) {
+	return nil, ErrDisabled
+}
+
+func (cd *cadvisorDisabled) SubcontainerInfo(name string, req *cadvisorApi.ContainerInfoRequest) (map[string]*cadvisorApi.ContainerInfo, error) {
+	return nil, ErrDisabled
+}
+
+func (cd *cadvisorDisabled) MachineInfo() (*cadvisorApi.MachineInfo, error) {
+	return nil, ErrDisabled
+}
+
+func (cd *cadvisorDisabled) VersionInfo() (*cadvisorApi.VersionInfo, error) {
+	return nil, ErrDisabled
+}
+
+func (cd *cadvisorDisabled) DockerImagesFsInfo() (cadvisorApiV2.FsInfo, error) {
+	return cadvisorApiV2.FsInfo{}, ErrDisabled
+}
+
+func (cd *cadvisorDisabled) GetPastEvents(request *events.Request) ([]*cadvisorApi.Event, error) {
+	return []*cadvisorApi.Event{}, ErrDisabled
+}
+
+func (cd *cadvisorDisabled) WatchForEvents(request *events.Request) (*events.EventChannel, error) {
+	return nil, ErrDisabled
+}
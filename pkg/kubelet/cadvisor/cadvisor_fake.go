@@ -55,3 +55,7 @@ func (c *Fake) DockerImagesFsInfo() (cadvisorApiV2.FsInfo, error) {
 func (c *Fake) GetPastEvents(request *events.Request) ([]*cadvisorApi.Event, error) {
 	return []*cadvisorApi.Event{}, nil
 }
+
+func (c *Fake) WatchForEvents(request *events.Request) (*events.EventChannel, error) {
+	return events.NewEventChannel(0), nil
+}
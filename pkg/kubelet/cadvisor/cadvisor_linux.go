@@ -45,6 +45,13 @@ var _ Interface = new(cadvisorClient)
 // The amount of time for which to keep stats in memory.
 const statsCacheDuration = 2 * time.Minute
 
+// How often the embedded cAdvisor is health checked, and how long a health
+// check may run before cAdvisor is declared wedged and restarted.
+const (
+	watchdogPeriod  = 1 * time.Minute
+	watchdogTimeout = 1 * time.Minute
+)
+
 // Creates a cAdvisor and exports its API on the specified port if port > 0.
 func New(port uint) (Interface, error) {
 	sysFs, err := sysfs.NewRealSysFs()
@@ -74,9 +81,53 @@ func New(port uint) (Interface, error) {
 		}
 	}
 
+	// Restart the manager when it wedges instead of serving errors for the
+	// rest of the kubelet's life.
+	go cadvisorClient.watchdog()
+
 	return cadvisorClient, nil
 }
 
+// watchdog periodically health checks the embedded cAdvisor and restarts it
+// when a check fails or times out.
+func (cc *cadvisorClient) watchdog() {
+	defer util.HandleCrash()
+
+	for {
+		time.Sleep(watchdogPeriod)
+		result := make(chan error, 1)
+		go func() {
+			defer util.HandleCrash()
+			_, err := cc.GetMachineInfo()
+			result <- err
+		}()
+		select {
+		case err := <-result:
+			if err == nil {
+				continue
+			}
+			glog.Errorf("cAdvisor health check failed, restarting it: %v", err)
+		case <-time.After(watchdogTimeout):
+			glog.Errorf("cAdvisor health check timed out after %v, restarting it", watchdogTimeout)
+		}
+		cc.restart()
+	}
+}
+
+// restart stops and starts the embedded cAdvisor manager. Failures are
+// logged; the watchdog tries again on its next round.
+func (cc *cadvisorClient) restart() {
+	if err := cc.Stop(); err != nil {
+		glog.Errorf("Failed to stop the embedded cAdvisor: %v", err)
+		return
+	}
+	if err := cc.Start(); err != nil {
+		glog.Errorf("Failed to restart the embedded cAdvisor: %v", err)
+		return
+	}
+	glog.Infof("Restarted the embedded cAdvisor")
+}
+
 func (cc *cadvisorClient) exportHTTP(port uint) error {
 	mux := http.NewServeMux()
 	err := cadvisorHttp.RegisterHandlers(mux, cc, "", "", "", "", "/metrics")
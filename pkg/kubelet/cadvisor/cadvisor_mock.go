@@ -66,3 +66,8 @@ func (c *Mock) GetPastEvents(request *events.Request) ([]*cadvisorApi.Event, err
 	args := c.Called()
 	return args.Get(0).([]*cadvisorApi.Event), args.Error(1)
 }
+
+func (c *Mock) WatchForEvents(request *events.Request) (*events.EventChannel, error) {
+	args := c.Called()
+	return args.Get(0).(*events.EventChannel), args.Error(1)
+}
@@ -64,3 +64,7 @@ func (cu *cadvisorUnsupported) DockerImagesFsInfo() (cadvisorApiV2.FsInfo, error
 func (cu *cadvisorUnsupported) GetPastEvents(request *events.Request) ([]*cadvisorApi.Event, error) {
 	return []*cadvisorApi.Event{}, unsupportedErr
 }
+
+func (cu *cadvisorUnsupported) WatchForEvents(request *events.Request) (*events.EventChannel, error) {
+	return nil, unsupportedErr
+}
@@ -36,4 +36,7 @@ type Interface interface {
 
 	// Get past events that have been detected and that fit the request.
 	GetPastEvents(request *events.Request) ([]*cadvisorApi.Event, error)
+
+	// Streams events matching the request as they occur.
+	WatchForEvents(request *events.Request) (*events.EventChannel, error)
 }
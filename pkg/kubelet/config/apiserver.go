@@ -18,6 +18,8 @@ limitations under the License.
 package config
 
 import (
+	"time"
+
 	"github.com/GoogleCloudPlatform/kubernetes/pkg/api"
 	"github.com/GoogleCloudPlatform/kubernetes/pkg/client"
 	"github.com/GoogleCloudPlatform/kubernetes/pkg/client/cache"
@@ -25,14 +27,20 @@ import (
 	"github.com/GoogleCloudPlatform/kubernetes/pkg/kubelet"
 )
 
-// NewSourceApiserver creates a config source that watches and pulls from the apiserver.
+// How often the full list of pods bound to this node is refetched from the
+// apiserver. The watch keeps the kubelet up to date between relists; the
+// periodic relist only guards against missed watch events.
+const apiserverResyncPeriod = 30 * time.Minute
+
+// NewSourceApiserver creates a config source that watches pods bound to this
+// node, relisting on watch failure and every apiserverResyncPeriod.
 func NewSourceApiserver(c *client.Client, hostname string, updates chan<- interface{}) {
 	lw := cache.NewListWatchFromClient(c, "pods", api.NamespaceAll, fields.OneTermEqualSelector(client.PodHost, hostname))
-	newSourceApiserverFromLW(lw, updates)
+	newSourceApiserverFromLW(lw, apiserverResyncPeriod, updates)
 }
 
 // newSourceApiserverFromLW holds creates a config source that watches and pulls from the apiserver.
-func newSourceApiserverFromLW(lw cache.ListerWatcher, updates chan<- interface{}) {
+func newSourceApiserverFromLW(lw cache.ListerWatcher, resyncPeriod time.Duration, updates chan<- interface{}) {
 	send := func(objs []interface{}) {
 		var pods []*api.Pod
 		for _, o := range objs {
@@ -40,7 +48,7 @@ func newSourceApiserverFromLW(lw cache.ListerWatcher, updates chan<- interface{}
 		}
 		updates <- kubelet.PodUpdate{pods, kubelet.SET, kubelet.ApiserverSource}
 	}
-	cache.NewReflector(lw, &api.Pod{}, cache.NewUndeltaStore(send, cache.MetaNamespaceKeyFunc), 0).Run()
+	cache.NewReflector(lw, &api.Pod{}, cache.NewUndeltaStore(send, cache.MetaNamespaceKeyFunc), resyncPeriod).Run()
 }
 
 func getHostFieldLabel(apiVersion string) string {
@@ -61,7 +61,7 @@ func TestNewSourceApiserver_UpdatesAndMultiplePods(t *testing.T) {
 
 	ch := make(chan interface{})
 
-	newSourceApiserverFromLW(lw, ch)
+	newSourceApiserverFromLW(lw, 0, ch)
 
 	got, ok := <-ch
 	if !ok {
@@ -144,7 +144,7 @@ func TestNewSourceApiserver_TwoNamespacesSameName(t *testing.T) {
 
 	ch := make(chan interface{})
 
-	newSourceApiserverFromLW(lw, ch)
+	newSourceApiserverFromLW(lw, 0, ch)
 
 	got, ok := <-ch
 	if !ok {
@@ -178,7 +178,7 @@ func TestNewSourceApiserverInitialEmptySendsEmptyPodUpdate(t *testing.T) {
 
 	ch := make(chan interface{})
 
-	newSourceApiserverFromLW(lw, ch)
+	newSourceApiserverFromLW(lw, 0, ch)
 
 	got, ok := <-ch
 	if !ok {
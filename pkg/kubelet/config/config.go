@@ -19,6 +19,7 @@ package config
 import (
 	"fmt"
 	"reflect"
+	"strings"
 	"sync"
 
 	"github.com/GoogleCloudPlatform/kubernetes/pkg/api"
@@ -26,6 +27,7 @@ import (
 	"github.com/GoogleCloudPlatform/kubernetes/pkg/client/record"
 	"github.com/GoogleCloudPlatform/kubernetes/pkg/kubelet"
 	kubecontainer "github.com/GoogleCloudPlatform/kubernetes/pkg/kubelet/container"
+	"github.com/GoogleCloudPlatform/kubernetes/pkg/kubelet/metrics"
 	"github.com/GoogleCloudPlatform/kubernetes/pkg/util"
 	"github.com/GoogleCloudPlatform/kubernetes/pkg/util/config"
 	utilerrors "github.com/GoogleCloudPlatform/kubernetes/pkg/util/errors"
@@ -120,6 +122,10 @@ type podStorage struct {
 	updateLock sync.Mutex
 	updates    chan<- kubelet.PodUpdate
 
+	// pods hidden because the same pod arrived from a higher precedence
+	// source, keyed by source and pod full name; guarded by podLock
+	shadowedPods util.StringSet
+
 	// contains the set of all sources that have sent at least one SET
 	sourcesSeenLock sync.Mutex
 	sourcesSeen     util.StringSet
@@ -133,11 +139,12 @@ type podStorage struct {
 // TODO: allow initialization of the current state of the store with snapshotted version.
 func newPodStorage(updates chan<- kubelet.PodUpdate, mode PodConfigNotificationMode, recorder record.EventRecorder) *podStorage {
 	return &podStorage{
-		pods:        make(map[string]map[string]*api.Pod),
-		mode:        mode,
-		updates:     updates,
-		sourcesSeen: util.StringSet{},
-		recorder:    recorder,
+		pods:         make(map[string]map[string]*api.Pod),
+		mode:         mode,
+		updates:      updates,
+		shadowedPods: util.StringSet{},
+		sourcesSeen:  util.StringSet{},
+		recorder:     recorder,
 	}
 }
 
@@ -282,9 +289,97 @@ func (s *podStorage) merge(source string, change interface{}) (adds, updates, de
 	}
 
 	s.pods[source] = pods
+	s.resolveConflicts(source, adds, updates, deletes)
 	return adds, updates, deletes
 }
 
+// Pod config sources in decreasing order of precedence. When the same pod
+// (namespace/name) arrives from more than one source, only the copy from the
+// source listed first here is run; the other copies are shadowed.
+var podSourcePrecedence = []string{kubelet.FileSource, kubelet.HTTPSource, kubelet.ApiserverSource}
+
+func sourcePrecedence(source string) int {
+	for i, s := range podSourcePrecedence {
+		if s == source {
+			return i
+		}
+	}
+	return len(podSourcePrecedence)
+}
+
+func shadowKey(source, podFullName string) string {
+	return source + "|" + podFullName
+}
+
+// resolveConflicts hides pods that arrived from more than one source behind
+// the copy from the highest precedence source, so listeners only ever see one
+// copy of each pod. Pods delivered before a higher precedence copy arrived
+// are turned into deletes, and pods whose shadowing copy went away are turned
+// back into adds. Called with podLock held, after s.pods has been updated
+// for the merging source.
+func (s *podStorage) resolveConflicts(source string, adds, updates, deletes *kubelet.PodUpdate) {
+	// Determine the winning source for every pod full name.
+	winners := make(map[string]string)
+	for src, pods := range s.pods {
+		for name := range pods {
+			if winner, found := winners[name]; !found || sourcePrecedence(src) < sourcePrecedence(winner) {
+				winners[name] = src
+			}
+		}
+	}
+
+	shadowed := util.StringSet{}
+	for src, pods := range s.pods {
+		for name, pod := range pods {
+			winner := winners[name]
+			if winner == src {
+				continue
+			}
+			key := shadowKey(src, name)
+			shadowed.Insert(key)
+			if s.shadowedPods.Has(key) {
+				continue
+			}
+			glog.Warningf("Pod %q from source %s is shadowed by the same pod from source %s", name, src, winner)
+			s.recorder.Eventf(pod, "podConflict", "Pod is also present in higher precedence source %s; this copy will not be run.", winner)
+			if src != source {
+				// The pod was delivered before the conflicting copy arrived.
+				deletes.Pods = append(deletes.Pods, pod)
+			}
+		}
+	}
+
+	// Resurface pods whose shadowing copy has been removed.
+	for _, key := range s.shadowedPods.List() {
+		if shadowed.Has(key) {
+			continue
+		}
+		parts := strings.SplitN(key, "|", 2)
+		if pod, found := s.pods[parts[0]][parts[1]]; found {
+			adds.Pods = append(adds.Pods, pod)
+		}
+	}
+
+	s.shadowedPods = shadowed
+	metrics.ShadowedPodCount.Set(float64(shadowed.Len()))
+
+	adds.Pods = filterShadowedPods(source, adds.Pods, shadowed)
+	updates.Pods = filterShadowedPods(source, updates.Pods, shadowed)
+}
+
+// filterShadowedPods drops pods of the merging source from a pending update
+// when a higher precedence source carries the same pod.
+func filterShadowedPods(source string, pods []*api.Pod, shadowed util.StringSet) []*api.Pod {
+	var filtered []*api.Pod
+	for _, pod := range pods {
+		if shadowed.Has(shadowKey(source, kubecontainer.GetPodFullName(pod))) {
+			continue
+		}
+		filtered = append(filtered, pod)
+	}
+	return filtered
+}
+
 func (s *podStorage) markSourceSet(source string) {
 	s.sourcesSeenLock.Lock()
 	defer s.sourcesSeenLock.Unlock()
@@ -301,7 +396,11 @@ func filterInvalidPods(pods []*api.Pod, source string, recorder record.EventReco
 	names := util.StringSet{}
 	for i, pod := range pods {
 		var errlist []error
-		if errs := validation.ValidatePod(pod); len(errs) != 0 {
+		// Only the pod's metadata is validated here: a pod whose name or
+		// namespace cannot be trusted cannot even be reported on. The spec is
+		// validated at kubelet admission, where a rejection leaves a failed
+		// status with an actionable message on the pod.
+		if errs := validation.ValidateObjectMeta(&pod.ObjectMeta, true, validation.ValidatePodName); len(errs) != 0 {
 			errlist = append(errlist, errs...)
 			// If validation fails, don't trust it any further -
 			// even Name could be bad.
@@ -337,8 +436,11 @@ func (s *podStorage) MergedState() interface{} {
 	s.podLock.RLock()
 	defer s.podLock.RUnlock()
 	pods := make([]*api.Pod, 0)
-	for _, sourcePods := range s.pods {
-		for _, podRef := range sourcePods {
+	for source, sourcePods := range s.pods {
+		for name, podRef := range sourcePods {
+			if s.shadowedPods.Has(shadowKey(source, name)) {
+				continue
+			}
 			pod, err := api.Scheme.Copy(podRef)
 			if err != nil {
 				glog.Errorf("unable to copy pod: %v", err)
@@ -243,3 +243,27 @@ func TestNewPodAddedUpdatedSet(t *testing.T) {
 		CreatePodUpdate(kubelet.ADD, NoneSource, CreateValidPod("foo4", "new", "test")),
 		CreatePodUpdate(kubelet.UPDATE, NoneSource, pod))
 }
+
+func TestPodConflictBetweenSources(t *testing.T) {
+	eventBroadcaster := record.NewBroadcaster()
+	config := NewPodConfig(PodConfigNotificationIncremental, eventBroadcaster.NewRecorder(api.EventSource{Component: "kubelet"}))
+	fileChannel := config.Channel(kubelet.FileSource)
+	apiChannel := config.Channel(kubelet.ApiserverSource)
+	ch := config.Updates()
+
+	// a pod from the apiserver is delivered normally
+	apiChannel <- CreatePodUpdate(kubelet.ADD, kubelet.ApiserverSource, CreateValidPod("foo", "new", ""))
+	expectPodUpdate(t, ch, CreatePodUpdate(kubelet.ADD, NoneSource, CreateValidPod("foo", "new", kubelet.ApiserverSource)))
+
+	// the same pod from a file shadows the apiserver copy
+	fileChannel <- CreatePodUpdate(kubelet.ADD, kubelet.FileSource, CreateValidPod("foo", "new", ""))
+	expectPodUpdate(t, ch,
+		CreatePodUpdate(kubelet.REMOVE, NoneSource, CreateValidPod("foo", "new", kubelet.ApiserverSource)),
+		CreatePodUpdate(kubelet.ADD, NoneSource, CreateValidPod("foo", "new", kubelet.FileSource)))
+
+	// removing the file copy resurfaces the apiserver copy
+	fileChannel <- CreatePodUpdate(kubelet.REMOVE, kubelet.FileSource, CreateValidPod("foo", "new", kubelet.FileSource))
+	expectPodUpdate(t, ch,
+		CreatePodUpdate(kubelet.REMOVE, NoneSource, CreateValidPod("foo", "new", kubelet.FileSource)),
+		CreatePodUpdate(kubelet.ADD, NoneSource, CreateValidPod("foo", "new", kubelet.ApiserverSource)))
+}
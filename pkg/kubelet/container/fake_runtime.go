@@ -23,6 +23,7 @@ import (
 	"time"
 
 	"github.com/GoogleCloudPlatform/kubernetes/pkg/api"
+	"github.com/GoogleCloudPlatform/kubernetes/pkg/types"
 	"github.com/GoogleCloudPlatform/kubernetes/pkg/volume"
 )
 
@@ -53,6 +54,16 @@ func (f *FakeRuntimeCache) GetPods() ([]*Pod, error) {
 	return f.getter.GetPods(false)
 }
 
+func (f *FakeRuntimeCache) GetPodByUID(uid types.UID) (Pod, error) {
+	pods, err := f.getter.GetPods(false)
+	if err != nil {
+		return Pod{}, err
+	}
+	return Pods(pods).FindPodByID(uid), nil
+}
+
+func (f *FakeRuntimeCache) InvalidatePod(types.UID) {}
+
 func (f *FakeRuntimeCache) ForceUpdateIfOlder(time.Time) error {
 	return nil
 }
@@ -35,5 +35,5 @@ type Prober interface {
 // container runtime to run a container.
 // TODO(yifan): Remove netMode, ipcMode.
 type RunContainerOptionsGenerator interface {
-	GenerateRunContainerOptions(pod *api.Pod, container *api.Container, netMode, ipcMode string) (*RunContainerOptions, error)
+	GenerateRunContainerOptions(pod *api.Pod, container *api.Container, podIP, netMode, ipcMode, pidMode string) (*RunContainerOptions, error)
 }
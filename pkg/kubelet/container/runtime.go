@@ -161,13 +161,22 @@ type RunContainerOptions struct {
 	DNS []string
 	// The list of DNS search domains.
 	DNSSearch []string
-	// Docker namespace identifiers(currently we have 'NetMode' and 'IpcMode'.
-	// These are for docker to attach a container in a pod to the pod infra
-	// container's namespace.
+	// If set, the path of a resolv.conf file that the runtime mounts over
+	// the container's /etc/resolv.conf, so that resolver options such as
+	// ndots take effect.
+	ResolvConfPath string
+	// If set, the path of a hosts file that the runtime mounts over the
+	// container's /etc/hosts, so that the pod's hostname and hostAliases
+	// resolve regardless of the base image.
+	HostsFilePath string
+	// Docker namespace identifiers (currently we have 'NetMode', 'IpcMode'
+	// and 'PidMode'). These are for docker to attach a container in a pod to
+	// the pod infra container's namespace.
 	// TODO(yifan): Remove these after we pushed the pod infra container logic
 	// into docker's container runtime.
 	NetMode string
 	IpcMode string
+	PidMode string
 }
 
 type Pods []*Pod
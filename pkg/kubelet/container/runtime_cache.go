@@ -19,16 +19,26 @@ package container
 import (
 	"sync"
 	"time"
+
+	"github.com/GoogleCloudPlatform/kubernetes/pkg/types"
 )
 
 var (
-	// TODO(yifan): Maybe set the them as parameters for NewCache().
+	// defaultCachePeriod is used as the max age when none is given to NewRuntimeCache.
 	defaultCachePeriod    = time.Second * 2
 	defaultUpdateInterval = time.Millisecond * 100
 )
 
 type RuntimeCache interface {
 	GetPods() ([]*Pod, error)
+	// GetPodByUID returns the cached pod with the given UID, refreshing the
+	// cache first if the entry has been invalidated or is older than the max
+	// age. A pod with no containers is returned if the pod is not running.
+	GetPodByUID(types.UID) (Pod, error)
+	// InvalidatePod marks the cached entry for the pod as stale, e.g. because
+	// one of its containers was created or killed; the next per-pod lookup
+	// will refresh the cache regardless of its age.
+	InvalidatePod(types.UID)
 	ForceUpdateIfOlder(time.Time) error
 }
 
@@ -38,11 +48,19 @@ type podsGetter interface {
 	GetPods(bool) ([]*Pod, error)
 }
 
-// NewRuntimeCache creates a container runtime cache.
-func NewRuntimeCache(getter podsGetter) (RuntimeCache, error) {
+// NewRuntimeCache creates a container runtime cache whose entries are
+// considered fresh for at most maxAge; a non-positive maxAge selects the
+// default cache period.
+func NewRuntimeCache(getter podsGetter, maxAge time.Duration) (RuntimeCache, error) {
+	if maxAge <= 0 {
+		maxAge = defaultCachePeriod
+	}
 	return &runtimeCache{
-		getter:   getter,
-		updating: false,
+		getter:    getter,
+		maxAge:    maxAge,
+		podsByUID: map[types.UID]*Pod{},
+		invalid:   map[types.UID]bool{},
+		updating:  false,
 	}, nil
 }
 
@@ -50,10 +68,17 @@ type runtimeCache struct {
 	sync.Mutex
 	// The underlying container runtime used to update the cache.
 	getter podsGetter
+	// How old the cache may become before a read forces a refresh.
+	maxAge time.Duration
 	// Last time when cache was updated.
 	cacheTime time.Time
 	// The content of the cache.
 	pods []*Pod
+	// The cached pods indexed by UID.
+	podsByUID map[types.UID]*Pod
+	// Pods whose cached entries have been invalidated by a container
+	// lifecycle event since the last update.
+	invalid map[types.UID]bool
 	// Whether the background thread updating the cache is running.
 	updating bool
 	// Time when the background thread should be stopped.
@@ -66,13 +91,13 @@ type runtimeCache struct {
 func (r *runtimeCache) GetPods() ([]*Pod, error) {
 	r.Lock()
 	defer r.Unlock()
-	if time.Since(r.cacheTime) > defaultCachePeriod {
+	if time.Since(r.cacheTime) > r.maxAge {
 		if err := r.updateCache(); err != nil {
 			return nil, err
 		}
 	}
-	// Stop refreshing thread if there were no requests within the default cache period
-	r.updatingThreadStopTime = time.Now().Add(defaultCachePeriod)
+	// Stop refreshing thread if there were no requests within the max age.
+	r.updatingThreadStopTime = time.Now().Add(r.maxAge)
 	if !r.updating {
 		r.updating = true
 		go r.startUpdatingCache()
@@ -80,6 +105,26 @@ func (r *runtimeCache) GetPods() ([]*Pod, error) {
 	return r.pods, nil
 }
 
+func (r *runtimeCache) GetPodByUID(uid types.UID) (Pod, error) {
+	r.Lock()
+	defer r.Unlock()
+	if r.invalid[uid] || time.Since(r.cacheTime) > r.maxAge {
+		if err := r.updateCache(); err != nil {
+			return Pod{}, err
+		}
+	}
+	if pod, found := r.podsByUID[uid]; found {
+		return *pod, nil
+	}
+	return Pod{}, nil
+}
+
+func (r *runtimeCache) InvalidatePod(uid types.UID) {
+	r.Lock()
+	defer r.Unlock()
+	r.invalid[uid] = true
+}
+
 func (r *runtimeCache) ForceUpdateIfOlder(minExpectedCacheTime time.Time) error {
 	r.Lock()
 	defer r.Unlock()
@@ -94,13 +139,24 @@ func (r *runtimeCache) updateCache() error {
 	if err != nil {
 		return err
 	}
-	r.pods = pods
-	r.cacheTime = time.Now()
+	r.setPods(pods, time.Now())
 	return nil
 }
 
+// setPods replaces the content of the cache and re-indexes it by pod UID.
+// The fresh listing satisfies any pending per-pod invalidations.
+func (r *runtimeCache) setPods(pods []*Pod, cacheTime time.Time) {
+	r.pods = pods
+	r.podsByUID = make(map[types.UID]*Pod, len(pods))
+	for _, pod := range pods {
+		r.podsByUID[pod.ID] = pod
+	}
+	r.invalid = map[types.UID]bool{}
+	r.cacheTime = cacheTime
+}
+
 // startUpdateingCache continues to invoke GetPods to get the newest result until
-// there is no requests within the default cache period.
+// there is no requests within the max age.
 func (r *runtimeCache) startUpdatingCache() {
 	run := true
 	for run {
@@ -116,8 +172,7 @@ func (r *runtimeCache) startUpdatingCache() {
 			r.updating = false
 			run = false
 		}
-		r.pods = pods
-		r.cacheTime = cacheTime
+		r.setPods(pods, cacheTime)
 		r.Unlock()
 	}
 }
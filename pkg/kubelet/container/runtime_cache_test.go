@@ -0,0 +1,140 @@
+/*
+Copyright 2015 Google Inc. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package container
+
+import (
+	"testing"
+	"time"
+
+	"github.com/GoogleCloudPlatform/kubernetes/pkg/types"
+)
+
+type countingPodsGetter struct {
+	pods  []*Pod
+	calls int
+}
+
+func (g *countingPodsGetter) GetPods(all bool) ([]*Pod, error) {
+	g.calls++
+	return g.pods, nil
+}
+
+func TestGetPodByUID(t *testing.T) {
+	getter := &countingPodsGetter{
+		pods: []*Pod{
+			{ID: types.UID("12345678"), Name: "foo", Namespace: "new"},
+			{ID: types.UID("87654321"), Name: "bar", Namespace: "new"},
+		},
+	}
+	cache, err := NewRuntimeCache(getter, time.Hour)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	pod, err := cache.GetPodByUID(types.UID("12345678"))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if pod.Name != "foo" {
+		t.Errorf("expected pod %q, got %q", "foo", pod.Name)
+	}
+	if getter.calls != 1 {
+		t.Errorf("expected 1 listing, got %d", getter.calls)
+	}
+
+	// A second lookup within the max age is served from the cache.
+	if pod, err = cache.GetPodByUID(types.UID("87654321")); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if pod.Name != "bar" {
+		t.Errorf("expected pod %q, got %q", "bar", pod.Name)
+	}
+	if getter.calls != 1 {
+		t.Errorf("expected the cached listing to be reused, got %d listings", getter.calls)
+	}
+
+	// A missing pod yields an empty pod, not an error.
+	if pod, err = cache.GetPodByUID(types.UID("unknown")); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(pod.Containers) != 0 || pod.Name != "" {
+		t.Errorf("expected an empty pod, got %+v", pod)
+	}
+}
+
+func TestGetPodByUIDInvalidation(t *testing.T) {
+	getter := &countingPodsGetter{
+		pods: []*Pod{{ID: types.UID("12345678"), Name: "foo", Namespace: "new"}},
+	}
+	cache, err := NewRuntimeCache(getter, time.Hour)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if _, err := cache.GetPodByUID(types.UID("12345678")); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if getter.calls != 1 {
+		t.Errorf("expected 1 listing, got %d", getter.calls)
+	}
+
+	// Invalidating the pod forces a refresh on the next lookup even though
+	// the cache is well within its max age.
+	cache.InvalidatePod(types.UID("12345678"))
+	getter.pods = []*Pod{}
+	pod, err := cache.GetPodByUID(types.UID("12345678"))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if getter.calls != 2 {
+		t.Errorf("expected the invalidated entry to force a listing, got %d listings", getter.calls)
+	}
+	if pod.Name != "" {
+		t.Errorf("expected the pod to be gone after the refresh, got %+v", pod)
+	}
+
+	// The refresh satisfied the invalidation; further lookups hit the cache.
+	if _, err := cache.GetPodByUID(types.UID("12345678")); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if getter.calls != 2 {
+		t.Errorf("expected the cached listing to be reused, got %d listings", getter.calls)
+	}
+}
+
+func TestGetPodByUIDMaxAge(t *testing.T) {
+	getter := &countingPodsGetter{
+		pods: []*Pod{{ID: types.UID("12345678"), Name: "foo", Namespace: "new"}},
+	}
+	cache, err := NewRuntimeCache(getter, time.Hour)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if _, err := cache.GetPodByUID(types.UID("12345678")); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	// Age the cache past the max age; the next lookup must refresh.
+	cache.(*runtimeCache).cacheTime = time.Now().Add(-2 * time.Hour)
+	if _, err := cache.GetPodByUID(types.UID("12345678")); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if getter.calls != 2 {
+		t.Errorf("expected the stale cache to force a listing, got %d listings", getter.calls)
+	}
+}
@@ -0,0 +1,102 @@
+/*
+Copyright 2015 Google Inc. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package container
+
+import (
+	"fmt"
+
+	utilErrors "github.com/GoogleCloudPlatform/kubernetes/pkg/util/errors"
+)
+
+// SyncAction is one step taken while syncing a pod, e.g. pulling an image
+// or setting up the pod network.
+type SyncAction string
+
+const (
+	KillPod                SyncAction = "KillPod"
+	KillContainer          SyncAction = "KillContainer"
+	MountVolumes           SyncAction = "MountVolumes"
+	StartPodInfraContainer SyncAction = "StartPodInfraContainer"
+	SetupNetwork           SyncAction = "SetupNetwork"
+	TeardownNetwork        SyncAction = "TeardownNetwork"
+	ApplySysctls           SyncAction = "ApplySysctls"
+	InitContainer          SyncAction = "InitContainer"
+	PullImage              SyncAction = "PullImage"
+	StartContainer         SyncAction = "StartContainer"
+)
+
+// SyncResult is the result of one sync action on one target.
+type SyncResult struct {
+	// The associated action of the result
+	Action SyncAction
+	// The target of the action, e.g. the container name or the pod full name
+	Target string
+	// Brief error reason
+	Error error
+	// Human readable error reason
+	Message string
+}
+
+// NewSyncResult generates new SyncResult with specific Action and Target
+func NewSyncResult(action SyncAction, target string) *SyncResult {
+	return &SyncResult{Action: action, Target: target}
+}
+
+// Fail fails the SyncResult with specific error and message
+func (r *SyncResult) Fail(err error, msg string) {
+	r.Error, r.Message = err, msg
+}
+
+// PodSyncResult aggregates the results of all the actions taken during one
+// sync of a pod, so callers can tell which step failed.
+type PodSyncResult struct {
+	// Result of different sync actions
+	SyncResults []*SyncResult
+	// Error encountered in SyncPod() and KillPod() that is not already in SyncResults
+	SyncError error
+}
+
+// AddSyncResult adds multiple SyncResult to current PodSyncResult
+func (p *PodSyncResult) AddSyncResult(result ...*SyncResult) {
+	p.SyncResults = append(p.SyncResults, result...)
+}
+
+// AddPodSyncResult merges a PodSyncResult to current one
+func (p *PodSyncResult) AddPodSyncResult(result PodSyncResult) {
+	p.AddSyncResult(result.SyncResults...)
+	p.SyncError = result.SyncError
+}
+
+// Fail fails the PodSyncResult with an error occurred in SyncPod() and KillPod() itself
+func (p *PodSyncResult) Fail(err error) {
+	p.SyncError = err
+}
+
+// Error returns an aggregate of the errors in the result, or nil if every
+// action succeeded.
+func (p *PodSyncResult) Error() error {
+	errlist := []error{}
+	if p.SyncError != nil {
+		errlist = append(errlist, fmt.Errorf("failed to sync pod: %v", p.SyncError))
+	}
+	for _, result := range p.SyncResults {
+		if result.Error != nil {
+			errlist = append(errlist, fmt.Errorf("failed to %q for %q: %v", result.Action, result.Target, result.Error))
+		}
+	}
+	return utilErrors.NewAggregate(errlist)
+}
@@ -0,0 +1,51 @@
+/*
+Copyright 2015 Google Inc. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package container
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestPodSyncResult(t *testing.T) {
+	okResult := NewSyncResult(StartContainer, "container_0")
+	result := PodSyncResult{}
+	result.AddSyncResult(okResult)
+	if err := result.Error(); err != nil {
+		t.Errorf("unexpected error for a successful sync: %v", err)
+	}
+
+	failedResult := NewSyncResult(PullImage, "image_1")
+	failedResult.Fail(errors.New("no such image"), "pull failed")
+	result.AddSyncResult(failedResult)
+	err := result.Error()
+	if err == nil {
+		t.Fatalf("expected an error after a failed action")
+	}
+	if !strings.Contains(err.Error(), "PullImage") || !strings.Contains(err.Error(), "image_1") {
+		t.Errorf("expected the failed action and target in the error, got %q", err.Error())
+	}
+
+	syncError := errors.New("error in SyncPod")
+	otherResult := PodSyncResult{}
+	otherResult.Fail(syncError)
+	result.AddPodSyncResult(otherResult)
+	if err := result.Error(); !strings.Contains(err.Error(), syncError.Error()) {
+		t.Errorf("expected the sync error to be reported, got %q", err.Error())
+	}
+}
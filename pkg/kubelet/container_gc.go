@@ -18,6 +18,9 @@ package kubelet
 
 import (
 	"fmt"
+	"os"
+	"path"
+	"path/filepath"
 	"sort"
 	"time"
 
@@ -79,6 +82,14 @@ type containerGCInfo struct {
 
 	// Creation time for the container.
 	createTime time.Time
+
+	// Full pod name, in the format <pod_name>_<pod_namespace>. Empty if the
+	// container could not be identified.
+	podNameWithNamespace string
+
+	// Name of the container in the pod. Empty if the container could not be
+	// identified.
+	containerName string
 }
 
 // Containers are considered for eviction as units of (UID, container name) pair.
@@ -157,6 +168,17 @@ func (cgc *realContainerGC) GarbageCollect() error {
 		}
 	}
 
+	// Remove dangling symlinks whose log file is gone, e.g. for containers
+	// removed behind the kubelet's back.
+	logSymlinks, _ := filepath.Glob(path.Join(containerLogsDir, "*.log"))
+	for _, logSymlink := range logSymlinks {
+		if _, err = os.Stat(logSymlink); os.IsNotExist(err) {
+			if err = os.Remove(logSymlink); err != nil {
+				glog.Warningf("Failed to remove container log symlink %q: %v", logSymlink, err)
+			}
+		}
+	}
+
 	return nil
 }
 
@@ -178,6 +200,14 @@ func (cgc *realContainerGC) removeOldestN(containers []containerGCInfo, toRemove
 		err := cgc.dockerClient.RemoveContainer(docker.RemoveContainerOptions{ID: containers[i].id})
 		if err != nil {
 			glog.Warningf("Failed to remove dead container %q: %v", containers[i].name, err)
+			continue
+		}
+
+		// Remove the symlink kept for cluster level logging along with the
+		// container whose log file it points at.
+		symlinkPath := dockertools.LogSymlink(containerLogsDir, containers[i].podNameWithNamespace, containers[i].containerName, containers[i].id)
+		if err = os.Remove(symlinkPath); err != nil && !os.IsNotExist(err) {
+			glog.Warningf("Failed to remove container log symlink %q: %v", symlinkPath, err)
 		}
 	}
 
@@ -214,11 +244,18 @@ func (cgc *realContainerGC) evictableContainers() (containersByEvictUnit, []cont
 			createTime: data.Created,
 		}
 
-		containerName, _, err := dockertools.ParseDockerName(container.Names[0])
+		// Prefer the metadata labels recorded on the container; fall back to
+		// the legacy encoded name for containers created before labels.
+		containerName, _, err := dockertools.GetContainerNameInfo(data)
+		if err != nil {
+			containerName, _, err = dockertools.ParseDockerName(container.Names[0])
+		}
 
 		if err != nil {
 			unidentifiedContainers = append(unidentifiedContainers, containerInfo)
 		} else {
+			containerInfo.podNameWithNamespace = containerName.PodFullName
+			containerInfo.containerName = containerName.ContainerName
 			key := evictUnit{
 				uid:  containerName.PodUID,
 				name: containerName.ContainerName,
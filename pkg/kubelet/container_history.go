@@ -0,0 +1,141 @@
+/*
+Copyright 2015 Google Inc. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package kubelet
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path"
+	"reflect"
+	"sync"
+
+	"github.com/GoogleCloudPlatform/kubernetes/pkg/api"
+	"github.com/GoogleCloudPlatform/kubernetes/pkg/types"
+	"github.com/golang/glog"
+)
+
+// containerHistoryFile is the name of the JSON file under the kubelet's root
+// directory that holds the persisted container history.
+const containerHistoryFile = "container_history.json"
+
+// containerHistoryEntry is what is persisted per container: the bits of
+// status that cannot be reconstructed from docker once the dead containers
+// have been garbage collected.
+type containerHistoryEntry struct {
+	RestartCount         int                `json:"restartCount"`
+	LastTerminationState api.ContainerState `json:"lastTerminationState,omitempty"`
+}
+
+// containerHistory persists per-container restart counts and last
+// termination details in a JSON file under the kubelet's root directory,
+// keyed by pod UID and container name, so that generated statuses survive
+// container garbage collection and kubelet restarts.
+type containerHistory struct {
+	lock    sync.Mutex
+	path    string
+	entries map[types.UID]map[string]containerHistoryEntry
+}
+
+// newContainerHistory loads any previously persisted history from
+// rootDirectory. A missing file is a normal first boot; a corrupt file is
+// discarded with a log message rather than wedging the kubelet.
+func newContainerHistory(rootDirectory string) *containerHistory {
+	h := &containerHistory{
+		path:    path.Join(rootDirectory, containerHistoryFile),
+		entries: map[types.UID]map[string]containerHistoryEntry{},
+	}
+	data, err := ioutil.ReadFile(h.path)
+	if err != nil {
+		if !os.IsNotExist(err) {
+			glog.Errorf("Failed to read container history file %q: %v", h.path, err)
+		}
+		return h
+	}
+	if err := json.Unmarshal(data, &h.entries); err != nil {
+		glog.Errorf("Discarding corrupt container history file %q: %v", h.path, err)
+		h.entries = map[types.UID]map[string]containerHistoryEntry{}
+	}
+	return h
+}
+
+// reconcile folds the persisted history of the given pod into statuses and
+// records anything newer than what was persisted. Restart counts only ever
+// move forward; a generated count lower than the persisted one means the
+// older containers have been garbage collected.
+func (h *containerHistory) reconcile(uid types.UID, statuses []api.ContainerStatus) {
+	h.lock.Lock()
+	defer h.lock.Unlock()
+	known := h.entries[uid]
+	dirty := false
+	for i := range statuses {
+		status := &statuses[i]
+		entry := known[status.Name]
+		if status.RestartCount < entry.RestartCount {
+			status.RestartCount = entry.RestartCount
+		}
+		if status.LastTerminationState.Termination == nil && entry.LastTerminationState.Termination != nil {
+			status.LastTerminationState = entry.LastTerminationState
+		}
+		newEntry := containerHistoryEntry{
+			RestartCount:         status.RestartCount,
+			LastTerminationState: status.LastTerminationState,
+		}
+		// Compare the termination state by value; the status generator
+		// allocates a fresh Termination struct on every sync.
+		if newEntry.RestartCount != entry.RestartCount || !reflect.DeepEqual(newEntry.LastTerminationState, entry.LastTerminationState) {
+			if known == nil {
+				known = map[string]containerHistoryEntry{}
+				h.entries[uid] = known
+			}
+			known[status.Name] = newEntry
+			dirty = true
+		}
+	}
+	if dirty {
+		h.save()
+	}
+}
+
+// forget drops the persisted history of a pod that no longer exists.
+func (h *containerHistory) forget(uid types.UID) {
+	h.lock.Lock()
+	defer h.lock.Unlock()
+	if _, found := h.entries[uid]; !found {
+		return
+	}
+	delete(h.entries, uid)
+	h.save()
+}
+
+// save writes the history to disk through a rename so a crash mid-write
+// cannot leave a truncated file. Callers must hold the lock.
+func (h *containerHistory) save() {
+	data, err := json.Marshal(h.entries)
+	if err != nil {
+		glog.Errorf("Failed to marshal container history: %v", err)
+		return
+	}
+	tmpFile := h.path + ".tmp"
+	if err := ioutil.WriteFile(tmpFile, data, 0600); err != nil {
+		glog.Errorf("Failed to write container history file %q: %v", tmpFile, err)
+		return
+	}
+	if err := os.Rename(tmpFile, h.path); err != nil {
+		glog.Errorf("Failed to rename container history file %q: %v", tmpFile, err)
+	}
+}
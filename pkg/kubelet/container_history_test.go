@@ -0,0 +1,118 @@
+/*
+Copyright 2015 Google Inc. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package kubelet
+
+import (
+	"io/ioutil"
+	"os"
+	"testing"
+
+	"github.com/GoogleCloudPlatform/kubernetes/pkg/api"
+	"github.com/GoogleCloudPlatform/kubernetes/pkg/types"
+)
+
+func TestContainerHistorySurvivesReload(t *testing.T) {
+	tempDir, err := ioutil.TempDir("/tmp", "container_history_test.")
+	if err != nil {
+		t.Fatalf("can't make a temp dir: %v", err)
+	}
+	defer os.RemoveAll(tempDir)
+	uid := types.UID("12345678")
+
+	history := newContainerHistory(tempDir)
+	statuses := []api.ContainerStatus{{
+		Name:         "bar",
+		RestartCount: 3,
+		LastTerminationState: api.ContainerState{
+			Termination: &api.ContainerStateTerminated{ExitCode: 1},
+		},
+	}}
+	history.reconcile(uid, statuses)
+
+	// A fresh instance must see the persisted history, as after a kubelet
+	// restart.
+	reloaded := newContainerHistory(tempDir)
+
+	// Docker has since forgotten the dead containers, as after GC.
+	statuses = []api.ContainerStatus{{Name: "bar"}}
+	reloaded.reconcile(uid, statuses)
+	if statuses[0].RestartCount != 3 {
+		t.Errorf("expected restart count 3, got %d", statuses[0].RestartCount)
+	}
+	if statuses[0].LastTerminationState.Termination == nil || statuses[0].LastTerminationState.Termination.ExitCode != 1 {
+		t.Errorf("expected the last termination state to be restored, got %+v", statuses[0].LastTerminationState)
+	}
+
+	// A higher generated count moves the history forward.
+	statuses[0].RestartCount = 5
+	reloaded.reconcile(uid, statuses)
+	freshCount := []api.ContainerStatus{{Name: "bar"}}
+	reloaded.reconcile(uid, freshCount)
+	if freshCount[0].RestartCount != 5 {
+		t.Errorf("expected restart count 5, got %d", freshCount[0].RestartCount)
+	}
+}
+
+func TestContainerHistoryUnchangedStateNotRewritten(t *testing.T) {
+	tempDir, err := ioutil.TempDir("/tmp", "container_history_test.")
+	if err != nil {
+		t.Fatalf("can't make a temp dir: %v", err)
+	}
+	defer os.RemoveAll(tempDir)
+	uid := types.UID("12345678")
+
+	history := newContainerHistory(tempDir)
+	makeStatuses := func() []api.ContainerStatus {
+		return []api.ContainerStatus{{
+			Name:         "bar",
+			RestartCount: 3,
+			LastTerminationState: api.ContainerState{
+				Termination: &api.ContainerStateTerminated{ExitCode: 1},
+			},
+		}}
+	}
+	history.reconcile(uid, makeStatuses())
+
+	// Every sync allocates a fresh Termination struct; an equal state must
+	// not be persisted again.
+	if err := os.Remove(history.path); err != nil {
+		t.Fatalf("can't remove the history file: %v", err)
+	}
+	history.reconcile(uid, makeStatuses())
+	if _, err := os.Stat(history.path); !os.IsNotExist(err) {
+		t.Errorf("expected an unchanged history not to be rewritten")
+	}
+}
+
+func TestContainerHistoryForget(t *testing.T) {
+	tempDir, err := ioutil.TempDir("/tmp", "container_history_test.")
+	if err != nil {
+		t.Fatalf("can't make a temp dir: %v", err)
+	}
+	defer os.RemoveAll(tempDir)
+	uid := types.UID("12345678")
+
+	history := newContainerHistory(tempDir)
+	history.reconcile(uid, []api.ContainerStatus{{Name: "bar", RestartCount: 2}})
+	history.forget(uid)
+
+	statuses := []api.ContainerStatus{{Name: "bar"}}
+	newContainerHistory(tempDir).reconcile(uid, statuses)
+	if statuses[0].RestartCount != 0 {
+		t.Errorf("expected the history of a forgotten pod to be dropped, got restart count %d", statuses[0].RestartCount)
+	}
+}
@@ -0,0 +1,154 @@
+/*
+Copyright 2015 Google Inc. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package kubelet
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"time"
+
+	"github.com/GoogleCloudPlatform/kubernetes/pkg/kubelet/dockertools"
+	"github.com/GoogleCloudPlatform/kubernetes/pkg/util"
+	"github.com/golang/glog"
+)
+
+// How often container log files are checked against the rotation policy.
+const containerLogRotatePeriod = time.Minute
+
+// Rotates the json log files docker keeps for the containers this kubelet
+// manages, so that a chatty container cannot fill the disk on daemons that
+// are not configured with log-opts.
+//
+// Implementation is thread-safe.
+type containerLogManager interface {
+	// Applies the rotation policy to the log files of all known containers.
+	// Errors include being unable to list the containers; per file failures
+	// are logged and skipped.
+	RotateLogs() error
+}
+
+type realContainerLogManager struct {
+	// Connection to the Docker daemon.
+	dockerClient dockertools.DockerInterface
+
+	// Root of the Docker runtime, used to locate container log files.
+	dockerRoot string
+
+	// Size in bytes at which a log file is rotated.
+	maxSize int64
+
+	// Max number of log files kept per container, including the live one.
+	maxFiles int
+}
+
+func newContainerLogManager(dockerClient dockertools.DockerInterface, dockerRoot string, maxSize int64, maxFiles int) (containerLogManager, error) {
+	// Validate policy.
+	if maxSize <= 0 {
+		return nil, fmt.Errorf("invalid maximum log file size %d, must be positive", maxSize)
+	}
+	if maxFiles < 2 {
+		return nil, fmt.Errorf("invalid maximum log file count %d, must keep at least 2 files", maxFiles)
+	}
+	clm := &realContainerLogManager{
+		dockerClient: dockerClient,
+		dockerRoot:   dockerRoot,
+		maxSize:      maxSize,
+		maxFiles:     maxFiles,
+	}
+
+	go util.Forever(func() {
+		err := clm.RotateLogs()
+		if err != nil {
+			glog.Warningf("[LogManager] Failed to rotate container logs: %v", err)
+		}
+	}, containerLogRotatePeriod)
+
+	return clm, nil
+}
+
+func (clm *realContainerLogManager) RotateLogs() error {
+	containers, err := dockertools.GetKubeletDockerContainers(clm.dockerClient, true)
+	if err != nil {
+		return err
+	}
+
+	for _, container := range containers {
+		logFile := path.Join(clm.dockerRoot, "containers", container.ID, fmt.Sprintf("%s-json.log", container.ID))
+		info, err := os.Stat(logFile)
+		if err != nil {
+			// Missing log files are expected for containers that never logged.
+			if !os.IsNotExist(err) {
+				glog.Warningf("[LogManager] Failed to stat log file %q: %v", logFile, err)
+			}
+			continue
+		}
+		if info.Size() < clm.maxSize {
+			continue
+		}
+
+		glog.V(2).Infof("[LogManager] Rotating log file %q of size %d", logFile, info.Size())
+		if err := rotateLogFile(logFile, clm.maxFiles); err != nil {
+			glog.Warningf("[LogManager] Failed to rotate log file %q: %v", logFile, err)
+		}
+	}
+
+	return nil
+}
+
+// Rotates logFile in place, keeping at most maxFiles files including the live
+// one. The live file is copied and truncated rather than renamed so that the
+// writer's open file descriptor stays valid.
+func rotateLogFile(logFile string, maxFiles int) error {
+	// Drop the oldest rotation and shift the remaining ones up.
+	oldest := fmt.Sprintf("%s.%d", logFile, maxFiles-1)
+	if _, err := os.Stat(oldest); err == nil {
+		if err := os.Remove(oldest); err != nil {
+			return err
+		}
+	}
+	for i := maxFiles - 2; i >= 1; i-- {
+		rotated := fmt.Sprintf("%s.%d", logFile, i)
+		if _, err := os.Stat(rotated); err != nil {
+			continue
+		}
+		if err := os.Rename(rotated, fmt.Sprintf("%s.%d", logFile, i+1)); err != nil {
+			return err
+		}
+	}
+
+	if err := copyLogFile(logFile, fmt.Sprintf("%s.1", logFile)); err != nil {
+		return err
+	}
+	return os.Truncate(logFile, 0)
+}
+
+func copyLogFile(src, dst string) error {
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+	out, err := os.Create(dst)
+	if err != nil {
+		return err
+	}
+	defer out.Close()
+	_, err = io.Copy(out, in)
+	return err
+}
@@ -0,0 +1,92 @@
+/*
+Copyright 2015 Google Inc. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package kubelet
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path"
+	"testing"
+)
+
+func writeLogFile(t *testing.T, name, content string) {
+	if err := ioutil.WriteFile(name, []byte(content), 0644); err != nil {
+		t.Fatalf("failed to write %q: %v", name, err)
+	}
+}
+
+func expectLogFile(t *testing.T, name, content string) {
+	data, err := ioutil.ReadFile(name)
+	if err != nil {
+		t.Errorf("failed to read %q: %v", name, err)
+		return
+	}
+	if string(data) != content {
+		t.Errorf("unexpected content of %q: got %q, want %q", name, string(data), content)
+	}
+}
+
+func TestRotateLogFile(t *testing.T) {
+	dir, err := ioutil.TempDir("", "container-log-manager")
+	if err != nil {
+		t.Fatalf("failed to create temp dir: %v", err)
+	}
+	defer os.RemoveAll(dir)
+
+	logFile := path.Join(dir, "abcd-json.log")
+	writeLogFile(t, logFile, "live")
+	writeLogFile(t, logFile+".1", "first")
+	writeLogFile(t, logFile+".2", "second")
+
+	if err := rotateLogFile(logFile, 3); err != nil {
+		t.Fatalf("rotateLogFile failed: %v", err)
+	}
+
+	// The live file is truncated, the first rotation holds its old content,
+	// and the oldest rotation was dropped to stay within three files.
+	expectLogFile(t, logFile, "")
+	expectLogFile(t, logFile+".1", "live")
+	expectLogFile(t, logFile+".2", "first")
+	if _, err := os.Stat(logFile + ".3"); !os.IsNotExist(err) {
+		t.Errorf("expected %q to not exist, got err %v", logFile+".3", err)
+	}
+}
+
+func TestRotateLogFileNoPriorRotations(t *testing.T) {
+	dir, err := ioutil.TempDir("", "container-log-manager")
+	if err != nil {
+		t.Fatalf("failed to create temp dir: %v", err)
+	}
+	defer os.RemoveAll(dir)
+
+	logFile := path.Join(dir, "abcd-json.log")
+	writeLogFile(t, logFile, "live")
+
+	if err := rotateLogFile(logFile, 5); err != nil {
+		t.Fatalf("rotateLogFile failed: %v", err)
+	}
+
+	expectLogFile(t, logFile, "")
+	expectLogFile(t, logFile+".1", "live")
+	for i := 2; i < 5; i++ {
+		rotated := fmt.Sprintf("%s.%d", logFile, i)
+		if _, err := os.Stat(rotated); !os.IsNotExist(err) {
+			t.Errorf("expected %q to not exist, got err %v", rotated, err)
+		}
+	}
+}
@@ -17,13 +17,17 @@ limitations under the License.
 package dockertools
 
 import (
+	"bytes"
+	"encoding/json"
 	"fmt"
 	"hash/adler32"
 	"io"
 	"math/rand"
 	"os"
+	goruntime "runtime"
 	"strconv"
 	"strings"
+	"time"
 
 	"github.com/GoogleCloudPlatform/kubernetes/pkg/api"
 	"github.com/GoogleCloudPlatform/kubernetes/pkg/credentialprovider"
@@ -37,11 +41,26 @@ import (
 )
 
 const (
-	PodInfraContainerName  = leaky.PodInfraContainerName
-	DockerPrefix           = "docker://"
-	PodInfraContainerImage = "gcr.io/google_containers/pause:0.8.0"
+	PodInfraContainerName = leaky.PodInfraContainerName
+	DockerPrefix          = "docker://"
+	// pause:1.0 reaps orphaned zombie processes when it runs as PID 1 of a
+	// shared pod PID namespace (see the shared_pid_namespace kubelet flag).
+	PodInfraContainerImage = "gcr.io/google_containers/pause:1.0"
+
+	podInfraContainerImageName    = "gcr.io/google_containers/pause"
+	podInfraContainerImageVersion = "1.0"
 )
 
+// DefaultPodInfraContainerImage returns the default pod infra container
+// image for the architecture the kubelet was built for. Only amd64 images
+// are published without an architecture suffix.
+func DefaultPodInfraContainerImage() string {
+	if goruntime.GOARCH == "amd64" {
+		return podInfraContainerImageName + ":" + podInfraContainerImageVersion
+	}
+	return podInfraContainerImageName + "-" + goruntime.GOARCH + ":" + podInfraContainerImageVersion
+}
+
 const (
 	// Taken from lmctfy https://github.com/google/lmctfy/blob/master/lmctfy/controllers/cpu_controller.cc
 	minShares     = 2
@@ -56,16 +75,20 @@ type DockerInterface interface {
 	CreateContainer(docker.CreateContainerOptions) (*docker.Container, error)
 	StartContainer(id string, hostConfig *docker.HostConfig) error
 	StopContainer(id string, timeout uint) error
+	UpdateContainer(id string, opts docker.UpdateContainerOptions) error
+	WaitContainer(id string) (int, error)
 	RemoveContainer(opts docker.RemoveContainerOptions) error
 	InspectImage(image string) (*docker.Image, error)
 	ListImages(opts docker.ListImagesOptions) ([]docker.APIImages, error)
 	PullImage(opts docker.PullImageOptions, auth docker.AuthConfiguration) error
+	TagImage(name string, opts docker.TagImageOptions) error
 	RemoveImage(image string) error
 	Logs(opts docker.LogsOptions) error
 	Version() (*docker.Env, error)
 	Info() (*docker.Env, error)
 	CreateExec(docker.CreateExecOptions) (*docker.Exec, error)
 	StartExec(string, docker.StartExecOptions) error
+	AttachToContainer(opts docker.AttachToContainerOptions) error
 }
 
 // DockerID is an ID of docker container. It is a type to make it clear when we're working with docker container Ids
@@ -80,14 +103,99 @@ type KubeletContainerName struct {
 
 // DockerPuller is an abstract interface for testability.  It abstracts image pull operations.
 type DockerPuller interface {
-	Pull(image string) error
+	Pull(image string, onProgress PullProgressFunc) error
 	IsImagePresent(image string) (bool, error)
+	// ForgetImages drops any cached image presence checks, for use after
+	// images have been removed.
+	ForgetImages()
+}
+
+// PullProgressFunc is called with periodic human-readable reports while an
+// image pull is in flight. It may be nil when no reporting is wanted.
+type PullProgressFunc func(progress string)
+
+// How often an in-flight image pull reports its progress.
+const pullProgressReportPeriod = 10 * time.Second
+
+// pullProgressMessage is the subset of docker's raw JSON pull stream that
+// progress reporting cares about.
+type pullProgressMessage struct {
+	ID             string `json:"id"`
+	Status         string `json:"status"`
+	ProgressDetail struct {
+		Current int64 `json:"current"`
+		Total   int64 `json:"total"`
+	} `json:"progressDetail"`
+}
+
+// pullProgressWriter consumes docker's raw JSON pull stream and calls
+// onProgress with the overall download percentage at most once per report
+// period. Only layers whose total size is already known are counted, so
+// early reports may cover a subset of the image.
+type pullProgressWriter struct {
+	onProgress PullProgressFunc
+	buf        []byte
+	current    map[string]int64
+	total      map[string]int64
+	lastReport time.Time
+}
+
+func newPullProgressWriter(onProgress PullProgressFunc) *pullProgressWriter {
+	return &pullProgressWriter{
+		onProgress: onProgress,
+		current:    map[string]int64{},
+		total:      map[string]int64{},
+		lastReport: time.Now(),
+	}
+}
+
+func (w *pullProgressWriter) Write(p []byte) (int, error) {
+	w.buf = append(w.buf, p...)
+	for {
+		newline := bytes.IndexByte(w.buf, '\n')
+		if newline < 0 {
+			break
+		}
+		line := w.buf[:newline]
+		w.buf = w.buf[newline+1:]
+		var msg pullProgressMessage
+		// Anything that doesn't parse is simply not progress.
+		if err := json.Unmarshal(line, &msg); err != nil || msg.ID == "" {
+			continue
+		}
+		if msg.Status != "Downloading" || msg.ProgressDetail.Total <= 0 {
+			continue
+		}
+		w.current[msg.ID] = msg.ProgressDetail.Current
+		w.total[msg.ID] = msg.ProgressDetail.Total
+	}
+	w.report()
+	return len(p), nil
+}
+
+// report calls onProgress when enough progress is known and a report period
+// has elapsed since the last report.
+func (w *pullProgressWriter) report() {
+	if time.Since(w.lastReport) < pullProgressReportPeriod {
+		return
+	}
+	var current, total int64
+	for id := range w.total {
+		current += w.current[id]
+		total += w.total[id]
+	}
+	if total == 0 {
+		return
+	}
+	w.lastReport = time.Now()
+	w.onProgress(fmt.Sprintf("%d%% (%d/%d bytes of known layers)", current*100/total, current, total))
 }
 
 // dockerPuller is the default implementation of DockerPuller.
 type dockerPuller struct {
-	client  DockerInterface
-	keyring credentialprovider.DockerKeyring
+	client   DockerInterface
+	keyring  credentialprovider.DockerKeyring
+	presence *imagePresenceCache
 }
 
 type throttledDockerPuller struct {
@@ -98,8 +206,9 @@ type throttledDockerPuller struct {
 // newDockerPuller creates a new instance of the default implementation of DockerPuller.
 func newDockerPuller(client DockerInterface, qps float32, burst int) DockerPuller {
 	dp := dockerPuller{
-		client:  client,
-		keyring: credentialprovider.NewDockerKeyring(),
+		client:   client,
+		keyring:  credentialprovider.NewDockerKeyring(),
+		presence: newImagePresenceCache(imagePresenceCacheTTL),
 	}
 
 	if qps == 0.0 {
@@ -115,7 +224,25 @@ func parseImageName(image string) (string, string) {
 	return parsers.ParseRepositoryTag(image)
 }
 
-func (p dockerPuller) Pull(image string) error {
+// applyRegistryRewrites rewrites the registry component of an image spec
+// according to rules mapping a registry to its mirror. Images without an
+// explicit registry are implicitly on docker.io.
+func applyRegistryRewrites(image string, rules map[string]string) string {
+	if len(rules) == 0 {
+		return image
+	}
+	registry, remainder := "docker.io", image
+	if parts := strings.SplitN(image, "/", 2); len(parts) == 2 && strings.ContainsAny(parts[0], ".:") {
+		registry, remainder = parts[0], parts[1]
+	}
+	mirror, found := rules[registry]
+	if !found {
+		return image
+	}
+	return mirror + "/" + remainder
+}
+
+func (p dockerPuller) Pull(image string, onProgress PullProgressFunc) error {
 	repoToPull, tag := parseImageName(image)
 
 	// If no tag was specified, use the default "latest".
@@ -127,6 +254,10 @@ func (p dockerPuller) Pull(image string) error {
 		Repository: repoToPull,
 		Tag:        tag,
 	}
+	if onProgress != nil {
+		opts.OutputStream = newPullProgressWriter(onProgress)
+		opts.RawJSONStream = true
+	}
 
 	creds, ok := p.keyring.Lookup(repoToPull)
 	if !ok {
@@ -134,8 +265,12 @@ func (p dockerPuller) Pull(image string) error {
 	}
 
 	err := p.client.PullImage(opts, creds)
-	// If there was no error, or we had credentials, just return the error.
-	if err == nil || ok {
+	if err == nil {
+		p.presence.Set(image, true)
+		return nil
+	}
+	// If we had credentials, just return the error.
+	if ok {
 		return err
 	}
 	// Image spec: [<registry>/]<repository>/<image>[:<version] so we count '/'
@@ -149,30 +284,43 @@ func (p dockerPuller) Pull(image string) error {
 	return err
 }
 
-func (p throttledDockerPuller) Pull(image string) error {
+func (p throttledDockerPuller) Pull(image string, onProgress PullProgressFunc) error {
 	if p.limiter.CanAccept() {
-		return p.puller.Pull(image)
+		return p.puller.Pull(image, onProgress)
 	}
 	return fmt.Errorf("pull QPS exceeded.")
 }
 
 func (p dockerPuller) IsImagePresent(image string) (bool, error) {
+	if present, found := p.presence.Get(image); found {
+		return present, nil
+	}
 	_, err := p.client.InspectImage(image)
 	if err == nil {
+		p.presence.Set(image, true)
 		return true, nil
 	}
 	// This is super brittle, but its the best we got.
 	// TODO: Land code in the docker client to use docker.Error here instead.
 	if err.Error() == "no such image" {
+		p.presence.Set(image, false)
 		return false, nil
 	}
 	return false, err
 }
 
+func (p dockerPuller) ForgetImages() {
+	p.presence.Flush()
+}
+
 func (p throttledDockerPuller) IsImagePresent(name string) (bool, error) {
 	return p.puller.IsImagePresent(name)
 }
 
+func (p throttledDockerPuller) ForgetImages() {
+	p.puller.ForgetImages()
+}
+
 // DockerContainers is a map of containers
 type DockerContainers map[DockerID]*docker.APIContainers
 
@@ -197,12 +345,90 @@ func (c DockerContainers) FindPodContainer(podFullName string, uid types.UID, co
 
 const containerNamePrefix = "k8s"
 
+// Version of the container hash scheme, recorded in the upper 32 bits of the
+// hash. Version 0 (produced by older kubelets) hashed the entire
+// api.Container struct.
+const containerHashVersion = 1
+
+// containerHashFields is the subset of api.Container that affects the running
+// container. Fields that the kubelet enforces by itself (e.g. probes) and
+// future cosmetic additions to api.Container are deliberately left out, so
+// that kubelet upgrades do not restart every running container.
+type containerHashFields struct {
+	Name                   string
+	Image                  string
+	Command                []string
+	Args                   []string
+	WorkingDir             string
+	Ports                  []api.ContainerPort
+	Env                    []api.EnvVar
+	Resources              api.ResourceRequirements
+	VolumeMounts           []api.VolumeMount
+	Devices                []api.Device
+	Lifecycle              *api.Lifecycle
+	TerminationMessagePath string
+	Privileged             bool
+	Capabilities           api.Capabilities
+	SecurityContext        *api.SecurityContext
+}
+
+// HashContainer computes a hash over the runtime-affecting fields of the
+// container, tagged with the hash scheme version.
 func HashContainer(container *api.Container) uint64 {
+	hash := adler32.New()
+	util.DeepHashObject(hash, containerHashFields{
+		Name:                   container.Name,
+		Image:                  container.Image,
+		Command:                container.Command,
+		Args:                   container.Args,
+		WorkingDir:             container.WorkingDir,
+		Ports:                  container.Ports,
+		Env:                    container.Env,
+		Resources:              container.Resources,
+		VolumeMounts:           container.VolumeMounts,
+		Devices:                container.Devices,
+		Lifecycle:              container.Lifecycle,
+		TerminationMessagePath: container.TerminationMessagePath,
+		Privileged:             container.Privileged,
+		Capabilities:           container.Capabilities,
+		SecurityContext:        container.SecurityContext,
+	})
+	return containerHashVersion<<32 | uint64(hash.Sum32())
+}
+
+// HashContainerExcludingResources hashes the runtime-affecting fields of the
+// container except its resource requirements, letting callers tell when only
+// the resources changed.
+func HashContainerExcludingResources(container *api.Container) uint64 {
+	clone := *container
+	clone.Resources = api.ResourceRequirements{}
+	return HashContainer(&clone)
+}
+
+// legacyHashContainer computes the version 0 hash, which covered every field
+// of api.Container.
+func legacyHashContainer(container *api.Container) uint64 {
 	hash := adler32.New()
 	util.DeepHashObject(hash, *container)
 	return uint64(hash.Sum32())
 }
 
+// ContainerHashChanged compares the hash recorded on a running container
+// against the current spec, using the scheme the recorded hash was produced
+// with so that a kubelet upgrade does not restart existing containers.
+func ContainerHashChanged(container *api.Container, recordedHash uint64) bool {
+	switch recordedHash >> 32 {
+	case containerHashVersion:
+		return recordedHash != HashContainer(container)
+	case 0:
+		return recordedHash != legacyHashContainer(container)
+	default:
+		// The hash was written by a newer, unknown scheme; assume the
+		// container changed.
+		return true
+	}
+}
+
 // Creates a name which can be reversed to identify both full pod name and container name.
 func BuildDockerName(dockerName KubeletContainerName, container *api.Container) string {
 	containerName := dockerName.ContainerName + "." + strconv.FormatUint(HashContainer(container), 16)
@@ -237,7 +463,7 @@ func ParseDockerName(name string) (dockerName *KubeletContainerName, hash uint64
 	nameParts := strings.Split(parts[1], ".")
 	containerName := nameParts[0]
 	if len(nameParts) > 1 {
-		hash, err = strconv.ParseUint(nameParts[1], 16, 32)
+		hash, err = strconv.ParseUint(nameParts[1], 16, 64)
 		if err != nil {
 			glog.Warningf("invalid container hash %q in container %q", nameParts[1], name)
 		}
@@ -281,7 +507,8 @@ func ConnectToDockerOrDie(dockerEndpoint string) DockerInterface {
 type ContainerCommandRunner interface {
 	RunInContainer(containerID string, cmd []string) ([]byte, error)
 	ExecInContainer(containerID string, cmd []string, in io.Reader, out, err io.WriteCloser, tty bool) error
-	PortForward(pod *kubecontainer.Pod, port uint16, stream io.ReadWriteCloser) error
+	AttachContainer(containerID string, stdin io.Reader, stdout, stderr io.WriteCloser, tty bool) error
+	PortForward(pod *kubecontainer.Pod, port uint16, protocol string, stream io.ReadWriteCloser) error
 }
 
 func milliCPUToShares(milliCPU int64) int64 {
@@ -0,0 +1,99 @@
+/*
+Copyright 2015 Google Inc. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package dockertools
+
+import (
+	"net"
+	"net/http"
+	"net/url"
+
+	"github.com/fsouza/go-dockerclient"
+)
+
+// Error classes reported by the docker error metrics. Callers can use the
+// predicates below to decide whether a failed operation should be retried or
+// surfaced in the pod status.
+const (
+	errorClassTimeout    = "timeout"
+	errorClassNotFound   = "not_found"
+	errorClassConflict   = "conflict"
+	errorClassDaemonDown = "daemon_down"
+	errorClassOther      = "other"
+)
+
+// IsTimeoutError returns true when the operation did not complete within its
+// request timeout; the state of the daemon is unknown and the operation
+// should be retried.
+func IsTimeoutError(err error) bool {
+	_, ok := err.(*operationTimeout)
+	return ok
+}
+
+// IsContainerNotFoundError returns true when the daemon does not know the
+// referenced container, e.g. because it has already been removed.
+func IsContainerNotFoundError(err error) bool {
+	if _, ok := err.(*docker.NoSuchContainer); ok {
+		return true
+	}
+	if derr, ok := err.(*docker.Error); ok {
+		return derr.Status == http.StatusNotFound
+	}
+	return false
+}
+
+// IsConflictError returns true when the daemon rejected the operation because
+// the container is already in the requested state, e.g. stopping a container
+// that is not running.
+func IsConflictError(err error) bool {
+	if _, ok := err.(*docker.ContainerNotRunning); ok {
+		return true
+	}
+	if derr, ok := err.(*docker.Error); ok {
+		return derr.Status == http.StatusConflict || derr.Status == http.StatusNotModified
+	}
+	return false
+}
+
+// IsDaemonDownError returns true when the request never reached the daemon.
+func IsDaemonDownError(err error) bool {
+	if err == nil {
+		return false
+	}
+	if _, ok := err.(*url.Error); ok {
+		return true
+	}
+	if _, ok := err.(*net.OpError); ok {
+		return true
+	}
+	return false
+}
+
+// classifyDockerError buckets an error into one of the error classes above
+// for metrics purposes.
+func classifyDockerError(err error) string {
+	switch {
+	case IsTimeoutError(err):
+		return errorClassTimeout
+	case IsContainerNotFoundError(err):
+		return errorClassNotFound
+	case IsConflictError(err):
+		return errorClassConflict
+	case IsDaemonDownError(err):
+		return errorClassDaemonDown
+	}
+	return errorClassOther
+}
@@ -17,18 +17,20 @@ limitations under the License.
 package dockertools
 
 import (
+	"errors"
 	"fmt"
-	"hash/adler32"
+	"net/url"
 	"reflect"
 	"sort"
+	"strconv"
 	"testing"
+	"time"
 
 	"github.com/GoogleCloudPlatform/kubernetes/pkg/api"
 	"github.com/GoogleCloudPlatform/kubernetes/pkg/client/record"
 	"github.com/GoogleCloudPlatform/kubernetes/pkg/credentialprovider"
 	kubecontainer "github.com/GoogleCloudPlatform/kubernetes/pkg/kubelet/container"
 	"github.com/GoogleCloudPlatform/kubernetes/pkg/types"
-	"github.com/GoogleCloudPlatform/kubernetes/pkg/util"
 	docker "github.com/fsouza/go-dockerclient"
 )
 
@@ -91,9 +93,7 @@ func TestGetContainerID(t *testing.T) {
 
 func verifyPackUnpack(t *testing.T, podNamespace, podUID, podName, containerName string) {
 	container := &api.Container{Name: containerName}
-	hasher := adler32.New()
-	util.DeepHashObject(hasher, *container)
-	computedHash := uint64(hasher.Sum32())
+	computedHash := HashContainer(container)
 	podFullName := fmt.Sprintf("%s_%s", podName, podNamespace)
 	name := BuildDockerName(KubeletContainerName{podFullName, types.UID(podUID), container.Name}, container)
 	returned, hash, err := ParseDockerName(name)
@@ -129,6 +129,34 @@ func TestContainerManifestNaming(t *testing.T) {
 	}
 }
 
+func TestContainerHashIgnoresCosmeticChanges(t *testing.T) {
+	container := &api.Container{Name: "foo", Image: "ubuntu"}
+	hash := HashContainer(container)
+
+	// Fields not affecting the running container don't change the hash.
+	withProbe := *container
+	withProbe.LivenessProbe = &api.Probe{InitialDelaySeconds: 30}
+	if ContainerHashChanged(&withProbe, hash) {
+		t.Errorf("adding a probe should not change the container hash")
+	}
+
+	// Runtime-affecting fields do.
+	withImage := *container
+	withImage.Image = "ubuntu:new"
+	if !ContainerHashChanged(&withImage, hash) {
+		t.Errorf("changing the image should change the container hash")
+	}
+
+	// Hashes recorded by an older kubelet are compared with the old scheme.
+	legacyHash := legacyHashContainer(container)
+	if ContainerHashChanged(container, legacyHash) {
+		t.Errorf("unchanged container should match its legacy hash")
+	}
+	if !ContainerHashChanged(&withProbe, legacyHash) {
+		t.Errorf("legacy hashes cover the whole spec; adding a probe should not match")
+	}
+}
+
 func TestVersion(t *testing.T) {
 	fakeDocker := &FakeDockerClient{VersionInfo: docker.Env{"Version=1.1.3", "ApiVersion=1.15"}}
 	manager := &DockerManager{client: fakeDocker}
@@ -215,11 +243,12 @@ func TestPull(t *testing.T) {
 		fakeClient := &FakeDockerClient{}
 
 		dp := dockerPuller{
-			client:  fakeClient,
-			keyring: fakeKeyring,
+			client:   fakeClient,
+			keyring:  fakeKeyring,
+			presence: newImagePresenceCache(imagePresenceCacheTTL),
 		}
 
-		err := dp.Pull(test.imageName)
+		err := dp.Pull(test.imageName, nil)
 		if err != nil {
 			t.Errorf("unexpected non-nil err: %s", err)
 			continue
@@ -236,6 +265,96 @@ func TestPull(t *testing.T) {
 	}
 }
 
+func TestPullProgressWriter(t *testing.T) {
+	var reports []string
+	w := newPullProgressWriter(func(progress string) {
+		reports = append(reports, progress)
+	})
+
+	// Nothing is reported before a report period has elapsed.
+	w.Write([]byte(`{"id":"layer1","status":"Downloading","progressDetail":{"current":50,"total":100}}` + "\n"))
+	if len(reports) != 0 {
+		t.Errorf("unexpected early reports: %v", reports)
+	}
+
+	w.lastReport = time.Now().Add(-pullProgressReportPeriod)
+	w.Write([]byte(`{"id":"layer2","status":"Downloading","progressDetail":{"current":25,"total":100}}` + "\n"))
+	if len(reports) != 1 {
+		t.Fatalf("expected 1 report, got %v", reports)
+	}
+	if expected := "37% (75/200 bytes of known layers)"; reports[0] != expected {
+		t.Errorf("expected %q, got %q", expected, reports[0])
+	}
+
+	// Statuses without progress, and partial lines, are ignored.
+	w.Write([]byte(`{"id":"layer1","status":"Pull complete"}` + "\n" + `{"id":"lay`))
+	w.lastReport = time.Now().Add(-pullProgressReportPeriod)
+	w.Write([]byte(`er1","status":"Downloading","progressDetail":{"current":100,"total":100}}` + "\n"))
+	if len(reports) != 2 {
+		t.Fatalf("expected 2 reports, got %v", reports)
+	}
+	if expected := "62% (125/200 bytes of known layers)"; reports[1] != expected {
+		t.Errorf("expected %q, got %q", expected, reports[1])
+	}
+}
+
+func TestApplyRegistryRewrites(t *testing.T) {
+	rules := map[string]string{
+		"docker.io":            "mirror.example.com",
+		"registry.example.com": "mirror.example.com/example",
+	}
+	tests := []struct {
+		image    string
+		expected string
+	}{
+		{"ubuntu", "mirror.example.com/ubuntu"},
+		{"ubuntu:2342", "mirror.example.com/ubuntu:2342"},
+		{"foo/bar:445566", "mirror.example.com/foo/bar:445566"},
+		{"registry.example.com/foobar:5342", "mirror.example.com/example/foobar:5342"},
+		{"other.example.com/foobar", "other.example.com/foobar"},
+	}
+	for _, test := range tests {
+		if rewritten := applyRegistryRewrites(test.image, rules); rewritten != test.expected {
+			t.Errorf("%s: expected %q, got %q", test.image, test.expected, rewritten)
+		}
+	}
+	if rewritten := applyRegistryRewrites("ubuntu", nil); rewritten != "ubuntu" {
+		t.Errorf("expected no rules to leave the image alone, got %q", rewritten)
+	}
+}
+
+func TestIsImagePresentCached(t *testing.T) {
+	fakeClient := &FakeDockerClient{Image: &docker.Image{ID: "1111"}}
+	dp := dockerPuller{
+		client:   fakeClient,
+		keyring:  &credentialprovider.FakeKeyring{},
+		presence: newImagePresenceCache(imagePresenceCacheTTL),
+	}
+
+	for i := 0; i < 3; i++ {
+		present, err := dp.IsImagePresent("ubuntu")
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if !present {
+			t.Errorf("expected the image to be present")
+		}
+	}
+	// All but the first check must be served from the cache.
+	if e, a := 1, len(fakeClient.called); e != a {
+		t.Errorf("expected %d inspects, got %d: %v", e, a, fakeClient.called)
+	}
+
+	// Removed images must be inspected again once the cache is flushed.
+	dp.ForgetImages()
+	if _, err := dp.IsImagePresent("ubuntu"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if e, a := 2, len(fakeClient.called); e != a {
+		t.Errorf("expected %d inspects, got %d: %v", e, a, fakeClient.called)
+	}
+}
+
 func TestDockerKeyringLookupFails(t *testing.T) {
 	fakeKeyring := &credentialprovider.FakeKeyring{}
 	fakeClient := &FakeDockerClient{
@@ -243,11 +362,12 @@ func TestDockerKeyringLookupFails(t *testing.T) {
 	}
 
 	dp := dockerPuller{
-		client:  fakeClient,
-		keyring: fakeKeyring,
+		client:   fakeClient,
+		keyring:  fakeKeyring,
+		presence: newImagePresenceCache(imagePresenceCacheTTL),
 	}
 
-	err := dp.Pull("host/repository/image:version")
+	err := dp.Pull("host/repository/image:version", nil)
 	if err == nil {
 		t.Errorf("unexpected non-error")
 	}
@@ -381,7 +501,8 @@ func (f *imageTrackingDockerClient) InspectImage(name string) (image *docker.Ima
 func TestIsImagePresent(t *testing.T) {
 	cl := &imageTrackingDockerClient{&FakeDockerClient{}, ""}
 	puller := &dockerPuller{
-		client: cl,
+		client:   cl,
+		presence: newImagePresenceCache(imagePresenceCacheTTL),
 	}
 	_, _ = puller.IsImagePresent("abc:123")
 	if cl.imageName != "abc:123" {
@@ -392,7 +513,7 @@ func TestIsImagePresent(t *testing.T) {
 func TestGetRunningContainers(t *testing.T) {
 	fakeDocker := &FakeDockerClient{Errors: make(map[string]error)}
 	fakeRecorder := &record.FakeRecorder{}
-	containerManager := NewDockerManager(fakeDocker, fakeRecorder, nil, nil, PodInfraContainerImage, 0, 0)
+	containerManager := NewDockerManager(fakeDocker, fakeRecorder, nil, nil, PodInfraContainerImage, 0, 0, "/var/lib/docker", "/var/log/containers", 0)
 	tests := []struct {
 		containers  map[string]*docker.Container
 		inputIDs    []string
@@ -657,7 +778,7 @@ func TestFindContainersByPod(t *testing.T) {
 		},
 	}
 	fakeClient := &FakeDockerClient{}
-	containerManager := NewDockerManager(fakeClient, &record.FakeRecorder{}, nil, nil, PodInfraContainerImage, 0, 0)
+	containerManager := NewDockerManager(fakeClient, &record.FakeRecorder{}, nil, nil, PodInfraContainerImage, 0, 0, "/var/lib/docker", "/var/log/containers", 0)
 	for i, test := range tests {
 		fakeClient.ContainerList = test.containerList
 		fakeClient.ExitedContainerList = test.exitedContainerList
@@ -740,3 +861,67 @@ func TestMakePortsAndBindings(t *testing.T) {
 		}
 	}
 }
+
+func TestClassifyDockerError(t *testing.T) {
+	testCases := []struct {
+		err      error
+		expected string
+	}{
+		{&operationTimeout{operation: "inspect_container", timeout: time.Minute}, errorClassTimeout},
+		{&docker.NoSuchContainer{ID: "foo"}, errorClassNotFound},
+		{&docker.Error{Status: 404, Message: "no such container"}, errorClassNotFound},
+		{&docker.ContainerNotRunning{ID: "foo"}, errorClassConflict},
+		{&docker.Error{Status: 409, Message: "conflict"}, errorClassConflict},
+		{&url.Error{Op: "Get", URL: "unix:///var/run/docker.sock", Err: errors.New("connection refused")}, errorClassDaemonDown},
+		{errors.New("something else"), errorClassOther},
+	}
+	for i, tc := range testCases {
+		if class := classifyDockerError(tc.err); class != tc.expected {
+			t.Errorf("case %d: expected class %q for %v, got %q", i, tc.expected, tc.err, class)
+		}
+	}
+}
+
+func TestGetContainerNameInfo(t *testing.T) {
+	pod := &api.Pod{
+		ObjectMeta: api.ObjectMeta{
+			UID:       "12345678",
+			Name:      "foo",
+			Namespace: "new",
+		},
+	}
+	container := &api.Container{Name: "bar"}
+
+	// A container with labels must not depend on the name encoding at all.
+	labeled := &docker.Container{
+		ID:     "1234",
+		Name:   "/unparseable",
+		Config: &docker.Config{Labels: newLabels(pod, container, 0)},
+	}
+	dockerName, hash, err := GetContainerNameInfo(labeled)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if dockerName.PodFullName != "foo_new" || dockerName.PodUID != "12345678" || dockerName.ContainerName != "bar" {
+		t.Errorf("unexpected name info from labels: %+v", dockerName)
+	}
+	if hash != HashContainer(container) {
+		t.Errorf("expected hash %x, got %x", HashContainer(container), hash)
+	}
+
+	// A container created before labels falls back to the legacy name.
+	legacy := &docker.Container{
+		ID:   "5678",
+		Name: "/k8s_bar." + strconv.FormatUint(HashContainer(container), 16) + "_foo_new_12345678_42",
+	}
+	dockerName, hash, err = GetContainerNameInfo(legacy)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if dockerName.PodFullName != "foo_new" || dockerName.PodUID != "12345678" || dockerName.ContainerName != "bar" {
+		t.Errorf("unexpected name info from legacy name: %+v", dockerName)
+	}
+	if hash != HashContainer(container) {
+		t.Errorf("expected hash %x, got %x", HashContainer(container), hash)
+	}
+}
@@ -40,11 +40,16 @@ type FakeDockerClient struct {
 	called              []string
 	Stopped             []string
 	pulled              []string
+	Tagged              []string
 	Created             []string
 	Removed             []string
 	RemovedImages       util.StringSet
 	VersionInfo         docker.Env
 	Information         docker.Env
+	// ExitCode is returned by WaitContainer.
+	ExitCode int
+	// Updated records the ids passed to UpdateContainer.
+	Updated []string
 }
 
 func (f *FakeDockerClient) ClearCalls() {
@@ -232,6 +237,22 @@ func (f *FakeDockerClient) StopContainer(id string, timeout uint) error {
 	return err
 }
 
+func (f *FakeDockerClient) UpdateContainer(id string, opts docker.UpdateContainerOptions) error {
+	f.Lock()
+	defer f.Unlock()
+	f.called = append(f.called, "update")
+	f.Updated = append(f.Updated, id)
+	return f.popError("update")
+}
+
+func (f *FakeDockerClient) WaitContainer(id string) (int, error) {
+	f.Lock()
+	defer f.Unlock()
+	f.called = append(f.called, "wait")
+	err := f.popError("wait")
+	return f.ExitCode, err
+}
+
 func (f *FakeDockerClient) RemoveContainer(opts docker.RemoveContainerOptions) error {
 	f.Lock()
 	defer f.Unlock()
@@ -254,6 +275,19 @@ func (f *FakeDockerClient) Logs(opts docker.LogsOptions) error {
 
 // PullImage is a test-spy implementation of DockerInterface.StopContainer.
 // It adds an entry "pull" to the internal method call record.
+// TagImage is a test-spy implementation of DockerInterface.TagImage.
+// It adds an entry "tag" to the internal method call record.
+func (f *FakeDockerClient) TagImage(name string, opts docker.TagImageOptions) error {
+	f.Lock()
+	defer f.Unlock()
+	f.called = append(f.called, "tag")
+	err := f.popError("tag")
+	if err == nil {
+		f.Tagged = append(f.Tagged, fmt.Sprintf("%s -> %s:%s", name, opts.Repo, opts.Tag))
+	}
+	return err
+}
+
 func (f *FakeDockerClient) PullImage(opts docker.PullImageOptions, auth docker.AuthConfiguration) error {
 	f.Lock()
 	defer f.Unlock()
@@ -285,6 +319,10 @@ func (f *FakeDockerClient) StartExec(_ string, _ docker.StartExecOptions) error
 	return nil
 }
 
+func (f *FakeDockerClient) AttachToContainer(opts docker.AttachToContainerOptions) error {
+	return nil
+}
+
 func (f *FakeDockerClient) ListImages(opts docker.ListImagesOptions) ([]docker.APIImages, error) {
 	err := f.popError("list_images")
 	return f.Images, err
@@ -311,7 +349,7 @@ type FakeDockerPuller struct {
 }
 
 // Pull records the image pull attempt, and optionally injects an error.
-func (f *FakeDockerPuller) Pull(image string) (err error) {
+func (f *FakeDockerPuller) Pull(image string, onProgress PullProgressFunc) (err error) {
 	f.Lock()
 	defer f.Unlock()
 	f.ImagesPulled = append(f.ImagesPulled, image)
@@ -323,6 +361,8 @@ func (f *FakeDockerPuller) Pull(image string) (err error) {
 	return err
 }
 
+func (f *FakeDockerPuller) ForgetImages() {}
+
 func (f *FakeDockerPuller) IsImagePresent(name string) (bool, error) {
 	f.Lock()
 	defer f.Unlock()
@@ -0,0 +1,76 @@
+/*
+Copyright 2015 Google Inc. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package dockertools
+
+import (
+	"sync"
+	"time"
+)
+
+// How long a cached image presence check stays valid before docker is
+// inspected again.
+const imagePresenceCacheTTL = 30 * time.Second
+
+// imagePresenceCache remembers recent image presence checks so that syncing
+// pods with an IfNotPresent pull policy does not cost a docker inspect per
+// container per sync. Entries are refreshed by successful pulls and flushed
+// when images are removed behind the cache's back.
+type imagePresenceCache struct {
+	ttl     time.Duration
+	lock    sync.Mutex
+	entries map[string]imagePresenceEntry
+}
+
+type imagePresenceEntry struct {
+	present bool
+	expires time.Time
+}
+
+func newImagePresenceCache(ttl time.Duration) *imagePresenceCache {
+	return &imagePresenceCache{
+		ttl:     ttl,
+		entries: map[string]imagePresenceEntry{},
+	}
+}
+
+// Get returns whether the image was present, and whether an unexpired entry
+// was found at all.
+func (c *imagePresenceCache) Get(image string) (bool, bool) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	entry, found := c.entries[image]
+	if !found || time.Now().After(entry.expires) {
+		return false, false
+	}
+	return entry.present, true
+}
+
+func (c *imagePresenceCache) Set(image string, present bool) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	c.entries[image] = imagePresenceEntry{
+		present: present,
+		expires: time.Now().Add(c.ttl),
+	}
+}
+
+// Flush drops every entry, for use after images have been removed.
+func (c *imagePresenceCache) Flush() {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	c.entries = map[string]imagePresenceEntry{}
+}
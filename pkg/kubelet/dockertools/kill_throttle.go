@@ -0,0 +1,95 @@
+/*
+Copyright 2015 Google Inc. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package dockertools
+
+import (
+	"sync"
+	"time"
+)
+
+// A container stop can block a docker daemon thread for the full stop
+// timeout when the process ignores SIGTERM, so an unbounded burst of stops
+// - e.g. draining a node - can exhaust the daemon. maxConcurrentKills
+// bounds how many stops are in flight at once; the rest queue in the kill
+// throttle.
+const maxConcurrentKills = 16
+
+// killThrottle is a bounded worker pool for container stop operations. At
+// most free+running stops proceed concurrently; when all slots are taken,
+// waiters are admitted by deadline, earliest first, so a pod whose grace
+// period is about to run out is stopped ahead of pods with time to spare.
+// Waiters without a deadline are admitted last, in arrival order.
+type killThrottle struct {
+	lock    sync.Mutex
+	free    int
+	waiters []*killWaiter
+}
+
+// killWaiter is one stop operation waiting for a slot. Its channel is
+// closed when the slot is granted.
+type killWaiter struct {
+	deadline time.Time
+	admitted chan struct{}
+}
+
+func newKillThrottle(slots int) *killThrottle {
+	return &killThrottle{free: slots}
+}
+
+// acquire blocks until a stop slot is available. A zero deadline means the
+// stop has no urgency. Every acquire must be paired with a release.
+func (t *killThrottle) acquire(deadline time.Time) {
+	t.lock.Lock()
+	if t.free > 0 {
+		t.free--
+		t.lock.Unlock()
+		return
+	}
+	w := &killWaiter{deadline: deadline, admitted: make(chan struct{})}
+	t.waiters = append(t.waiters, w)
+	t.lock.Unlock()
+	<-w.admitted
+}
+
+// release returns a stop slot, handing it directly to the most urgent
+// waiter if any are queued.
+func (t *killThrottle) release() {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+	if len(t.waiters) == 0 {
+		t.free++
+		return
+	}
+	next := 0
+	for i, w := range t.waiters[1:] {
+		if moreUrgent(w.deadline, t.waiters[next].deadline) {
+			next = i + 1
+		}
+	}
+	w := t.waiters[next]
+	t.waiters = append(t.waiters[:next], t.waiters[next+1:]...)
+	close(w.admitted)
+}
+
+// moreUrgent reports whether deadline a should be served strictly before
+// b. Any deadline beats no deadline; otherwise the earlier deadline wins.
+func moreUrgent(a, b time.Time) bool {
+	if a.IsZero() || b.IsZero() {
+		return !a.IsZero() && b.IsZero()
+	}
+	return a.Before(b)
+}
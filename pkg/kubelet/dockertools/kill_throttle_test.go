@@ -0,0 +1,104 @@
+/*
+Copyright 2015 Google Inc. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package dockertools
+
+import (
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestKillThrottleBoundsConcurrency(t *testing.T) {
+	const slots = 2
+	throttle := newKillThrottle(slots)
+	var lock sync.Mutex
+	inFlight, maxInFlight := 0, 0
+	var wg sync.WaitGroup
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			throttle.acquire(time.Time{})
+			lock.Lock()
+			inFlight++
+			if inFlight > maxInFlight {
+				maxInFlight = inFlight
+			}
+			lock.Unlock()
+			time.Sleep(time.Millisecond)
+			lock.Lock()
+			inFlight--
+			lock.Unlock()
+			throttle.release()
+		}()
+	}
+	wg.Wait()
+	if maxInFlight > slots {
+		t.Errorf("expected at most %d stops in flight, saw %d", slots, maxInFlight)
+	}
+	if throttle.free != slots {
+		t.Errorf("expected all %d slots returned, have %d free", slots, throttle.free)
+	}
+}
+
+func TestKillThrottleAdmitsByDeadline(t *testing.T) {
+	throttle := newKillThrottle(1)
+	// Take the only slot so that subsequent acquires queue.
+	throttle.acquire(time.Time{})
+
+	order := make(chan string, 3)
+	enqueue := func(name string, deadline time.Time, queued int) {
+		go func() {
+			throttle.acquire(deadline)
+			order <- name
+			throttle.release()
+		}()
+		// Wait until the waiter is queued, so the queue contents are
+		// deterministic before the next waiter arrives.
+		for limit := time.Now().Add(10 * time.Second); ; {
+			throttle.lock.Lock()
+			n := len(throttle.waiters)
+			throttle.lock.Unlock()
+			if n == queued {
+				break
+			}
+			if time.Now().After(limit) {
+				t.Fatalf("timed out waiting for %q to queue", name)
+			}
+			time.Sleep(time.Millisecond)
+		}
+	}
+
+	now := time.Now()
+	enqueue("none", time.Time{}, 1)
+	enqueue("far", now.Add(time.Hour), 2)
+	enqueue("near", now.Add(time.Second), 3)
+
+	// Releasing the slot must admit the waiters nearest deadline first,
+	// with the deadline-less waiter last despite arriving first.
+	throttle.release()
+	for _, expected := range []string{"near", "far", "none"} {
+		select {
+		case name := <-order:
+			if name != expected {
+				t.Errorf("expected waiter %q to be admitted, got %q", expected, name)
+			}
+		case <-time.After(10 * time.Second):
+			t.Fatalf("timed out waiting for waiter %q to be admitted", expected)
+		}
+	}
+}
@@ -0,0 +1,125 @@
+/*
+Copyright 2015 Google Inc. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package dockertools
+
+import (
+	"strconv"
+
+	"github.com/GoogleCloudPlatform/kubernetes/pkg/api"
+	kubecontainer "github.com/GoogleCloudPlatform/kubernetes/pkg/kubelet/container"
+	"github.com/GoogleCloudPlatform/kubernetes/pkg/types"
+	"github.com/fsouza/go-dockerclient"
+	"github.com/golang/glog"
+)
+
+// Docker labels recorded on every container the kubelet creates. The labels
+// carry enough information to rebuild the container status without parsing
+// the docker name, which breaks once older containers with the same name are
+// garbage collected.
+const (
+	kubernetesPodFullNameLabel                     = "io.kubernetes.pod.fullName"
+	kubernetesPodUIDLabel                          = "io.kubernetes.pod.uid"
+	kubernetesContainerNameLabel                   = "io.kubernetes.container.name"
+	kubernetesContainerHashLabel                   = "io.kubernetes.container.hash"
+	kubernetesContainerHashExcludingResourcesLabel = "io.kubernetes.container.hashExcludingResources"
+	kubernetesContainerRestartCountLabel           = "io.kubernetes.container.restartCount"
+	kubernetesTerminationMessagePathLabel          = "io.kubernetes.container.terminationMessagePath"
+)
+
+// newLabels creates the labels to attach to a container at creation time.
+func newLabels(pod *api.Pod, container *api.Container, restartCount int) map[string]string {
+	return map[string]string{
+		kubernetesPodFullNameLabel:                     kubecontainer.GetPodFullName(pod),
+		kubernetesPodUIDLabel:                          string(pod.UID),
+		kubernetesContainerNameLabel:                   container.Name,
+		kubernetesContainerHashLabel:                   strconv.FormatUint(HashContainer(container), 16),
+		kubernetesContainerHashExcludingResourcesLabel: strconv.FormatUint(HashContainerExcludingResources(container), 16),
+		kubernetesContainerRestartCountLabel:           strconv.Itoa(restartCount),
+		kubernetesTerminationMessagePathLabel:          container.TerminationMessagePath,
+	}
+}
+
+// containerNameFromLabels rebuilds the kubelet name information for a
+// container from its labels. It returns false if the container predates the
+// labels and the legacy encoded docker name must be parsed instead.
+func containerNameFromLabels(labels map[string]string) (*KubeletContainerName, uint64, bool) {
+	podFullName, found := labels[kubernetesPodFullNameLabel]
+	if !found {
+		return nil, 0, false
+	}
+	hash, err := strconv.ParseUint(labels[kubernetesContainerHashLabel], 16, 64)
+	if err != nil {
+		glog.Errorf("Invalid container hash label %q: %v", labels[kubernetesContainerHashLabel], err)
+	}
+	return &KubeletContainerName{
+		PodFullName:   podFullName,
+		PodUID:        types.UID(labels[kubernetesPodUIDLabel]),
+		ContainerName: labels[kubernetesContainerNameLabel],
+	}, hash, true
+}
+
+// GetContainerNameInfo returns the kubelet name information for an inspected
+// container, preferring the metadata recorded in its labels and falling back
+// to parsing the legacy encoded docker name for containers created before
+// labels were recorded.
+func GetContainerNameInfo(inspect *docker.Container) (*KubeletContainerName, uint64, error) {
+	if inspect.Config != nil {
+		if dockerName, hash, found := containerNameFromLabels(inspect.Config.Labels); found {
+			return dockerName, hash, nil
+		}
+	}
+	return ParseDockerName(inspect.Name)
+}
+
+// getHashExcludingResourcesFromLabels returns the hash of the container spec
+// without its resource requirements recorded at creation time, or false if
+// the container predates the label.
+func getHashExcludingResourcesFromLabels(labels map[string]string) (uint64, bool) {
+	value, found := labels[kubernetesContainerHashExcludingResourcesLabel]
+	if !found {
+		return 0, false
+	}
+	hash, err := strconv.ParseUint(value, 16, 64)
+	if err != nil {
+		glog.Errorf("Invalid container hash label %q: %v", value, err)
+		return 0, false
+	}
+	return hash, true
+}
+
+// getRestartCountFromLabels returns the restart count recorded on a container
+// at creation time, or false if the container predates the label.
+func getRestartCountFromLabels(labels map[string]string) (int, bool) {
+	value, found := labels[kubernetesContainerRestartCountLabel]
+	if !found {
+		return 0, false
+	}
+	count, err := strconv.Atoi(value)
+	if err != nil {
+		glog.Errorf("Invalid restart count label %q: %v", value, err)
+		return 0, false
+	}
+	return count, true
+}
+
+// getTerminationMessagePathFromLabels returns the termination message path
+// recorded on a container at creation time, or false if the container
+// predates the label.
+func getTerminationMessagePathFromLabels(labels map[string]string) (string, bool) {
+	path, found := labels[kubernetesTerminationMessagePathLabel]
+	return path, found && path != ""
+}
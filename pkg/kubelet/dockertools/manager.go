@@ -29,13 +29,16 @@ import (
 	"strconv"
 	"strings"
 	"sync"
+	"time"
 
 	"github.com/GoogleCloudPlatform/kubernetes/pkg/api"
 	"github.com/GoogleCloudPlatform/kubernetes/pkg/capabilities"
 	"github.com/GoogleCloudPlatform/kubernetes/pkg/client/record"
 	kubecontainer "github.com/GoogleCloudPlatform/kubernetes/pkg/kubelet/container"
+	"github.com/GoogleCloudPlatform/kubernetes/pkg/kubelet/metrics"
 	"github.com/GoogleCloudPlatform/kubernetes/pkg/types"
 	"github.com/GoogleCloudPlatform/kubernetes/pkg/util"
+	"github.com/GoogleCloudPlatform/kubernetes/pkg/util/expansion"
 	"github.com/fsouza/go-dockerclient"
 	"github.com/golang/glog"
 	"github.com/golang/groupcache/lru"
@@ -43,6 +46,43 @@ import (
 
 const (
 	maxReasonCacheEntries = 200
+
+	// Max amount of a termination message to keep; longer messages are
+	// truncated to their trailing bytes.
+	maxTerminationMessageLength = 4096
+	// Number of trailing log lines used as the termination message when the
+	// termination message file is empty.
+	terminationMessageFallbackLogLines = "10"
+
+	// A container that has not started this long after it was created is
+	// considered stuck and is removed. The grace period keeps a start that
+	// is still in flight from being misjudged.
+	stuckContainerGracePeriod = time.Minute
+
+	// How many times RunContainer attempts to create and start a container
+	// before giving up for this sync, and the initial delay between
+	// attempts; the delay doubles after every failure.
+	maxRunContainerAttempts  = 3
+	runContainerRetryBackoff = 500 * time.Millisecond
+
+	// How many times a container's PostStart hook is attempted before the
+	// PostStart failure policy is applied, and the initial delay between
+	// attempts; the delay doubles after every failure.
+	maxPostStartAttempts  = 3
+	postStartRetryBackoff = 500 * time.Millisecond
+)
+
+// HookFailurePolicy controls what happens to a container whose PostStart
+// hook still fails after the last retry.
+type HookFailurePolicy string
+
+const (
+	// HookFailureFailContainer kills the container and reports the start
+	// as failed. This is the default.
+	HookFailureFailContainer HookFailurePolicy = "fail-container"
+	// HookFailureWarnOnly records the failure and leaves the container
+	// running.
+	HookFailureWarnOnly HookFailurePolicy = "warn-only"
 )
 
 // TODO: Eventually DockerManager should implement kubecontainer.Runtime
@@ -70,6 +110,45 @@ type DockerManager struct {
 	// use the concrete type so that we can record the pull failure and eliminate
 	// the image checking in GetPodStatus().
 	Puller DockerPuller
+
+	// ExecHandler runs commands in containers; exported for the same
+	// testability reason as Puller. Defaults to the native docker exec API.
+	ExecHandler ExecHandler
+
+	// RegistryRewrites maps a registry to the mirror images should really
+	// be pulled from, e.g. docker.io to an in-house mirror. Applied to
+	// every pull; the pulled image is tagged back under its original name.
+	RegistryRewrites map[string]string
+
+	// PostStartFailurePolicy is applied to a container whose PostStart
+	// hook still fails after the last retry.
+	PostStartFailurePolicy HookFailurePolicy
+
+	// Root of the Docker runtime, used to locate container log files.
+	dockerRoot string
+
+	// Directory in which to keep symlinks to container log files.
+	containerLogsDir string
+
+	// Size of the infra container's /dev/shm for pods without a memory
+	// limit, in bytes. Zero leaves the docker default in place.
+	podInfraShmSize int64
+
+	// Cache of container inspect results shared by the pod workers within a
+	// sync pass; flushed at the start of each pass and invalidated whenever
+	// this manager mutates a container.
+	inspectLock  sync.Mutex
+	inspectCache map[string]*docker.Container
+
+	// Hash of the container spec whose resource limits were last applied in
+	// place, keyed by container ID. Avoids re-sending the same limits to
+	// docker on every sync pass.
+	updatedResourcesLock sync.Mutex
+	updatedResources     map[string]uint64
+
+	// Bounds the number of concurrent container stops so that a mass pod
+	// deletion does not overwhelm the docker daemon.
+	killThrottle *killThrottle
 }
 
 func NewDockerManager(
@@ -79,7 +158,10 @@ func NewDockerManager(
 	containerRefManager *kubecontainer.RefManager,
 	podInfraContainerImage string,
 	qps float32,
-	burst int) *DockerManager {
+	burst int,
+	dockerRoot string,
+	containerLogsDir string,
+	podInfraShmSize int64) *DockerManager {
 	reasonCache := stringCache{cache: lru.New(maxReasonCacheEntries)}
 	return &DockerManager{
 		client:                 client,
@@ -89,7 +171,58 @@ func NewDockerManager(
 		PodInfraContainerImage: podInfraContainerImage,
 		reasonCache:            reasonCache,
 		Puller:                 newDockerPuller(client, qps, burst),
+		ExecHandler:            &NativeExecHandler{},
+		PostStartFailurePolicy: HookFailureFailContainer,
+		dockerRoot:             dockerRoot,
+		containerLogsDir:       containerLogsDir,
+		podInfraShmSize:        podInfraShmSize,
+		inspectCache:           make(map[string]*docker.Container),
+		updatedResources:       make(map[string]uint64),
+		killThrottle:           newKillThrottle(maxConcurrentKills),
+	}
+}
+
+// LogSymlink composes the name of the symlink kept in containerLogsDir for a
+// container's log file. The name carries the pod full name and the container
+// name so that cluster level logging agents can attach pod metadata to the
+// log lines they collect.
+func LogSymlink(containerLogsDir, podFullName, containerName, dockerId string) string {
+	return path.Join(containerLogsDir, fmt.Sprintf("%s_%s-%s.log", podFullName, containerName, dockerId))
+}
+
+// inspectContainerCached inspects a container, serving repeated inspects of
+// the same container from the cache. It must only be used on paths that
+// tolerate results as old as the current sync pass.
+func (dm *DockerManager) inspectContainerCached(id string) (*docker.Container, error) {
+	dm.inspectLock.Lock()
+	cached, found := dm.inspectCache[id]
+	dm.inspectLock.Unlock()
+	if found {
+		return cached, nil
+	}
+	container, err := dm.client.InspectContainer(id)
+	if err != nil {
+		return nil, err
 	}
+	dm.inspectLock.Lock()
+	dm.inspectCache[id] = container
+	dm.inspectLock.Unlock()
+	return container, nil
+}
+
+// FlushInspectCache drops all cached inspect results. The kubelet calls this
+// at the start of every sync pass.
+func (dm *DockerManager) FlushInspectCache() {
+	dm.inspectLock.Lock()
+	defer dm.inspectLock.Unlock()
+	dm.inspectCache = make(map[string]*docker.Container)
+}
+
+// invalidateInspectCache drops the cached inspect result for one container.
+func (dm *DockerManager) invalidateInspectCache(id string) {
+	dm.inspectLock.Lock()
+	defer dm.inspectLock.Unlock()
+	delete(dm.inspectCache, id)
 }
 
 // A cache which stores strings keyed by <pod_UID>_<container_name>.
@@ -126,24 +259,27 @@ func (sc *stringCache) Get(uid types.UID, name string) (string, bool) {
 }
 
 // GetKubeletDockerContainerLogs returns logs of a specific container. By
-// default, it returns a snapshot of the container log. Set |follow| to true to
-// stream the log. Set |follow| to false and specify the number of lines (e.g.
-// "100" or "all") to tail the log.
+// default, it returns a snapshot of the container log. Set logOptions.Follow
+// to true to stream the log. Set logOptions.TailLines to tail the log.
 // TODO: Make 'RawTerminal' option  flagable.
-func (dm *DockerManager) GetKubeletDockerContainerLogs(containerID, tail string, follow bool, stdout, stderr io.Writer) (err error) {
+func (dm *DockerManager) GetKubeletDockerContainerLogs(containerID string, logOptions *api.PodLogOptions, stdout, stderr io.Writer) (err error) {
 	opts := docker.LogsOptions{
 		Container:    containerID,
 		Stdout:       true,
 		Stderr:       true,
 		OutputStream: stdout,
 		ErrorStream:  stderr,
-		Timestamps:   true,
+		Timestamps:   logOptions.Timestamps,
 		RawTerminal:  false,
-		Follow:       follow,
+		Follow:       logOptions.Follow,
+	}
+
+	if logOptions.SinceSeconds != nil {
+		opts.Since = time.Now().Unix() - *logOptions.SinceSeconds
 	}
 
-	if !follow {
-		opts.Tail = tail
+	if !logOptions.Follow && logOptions.TailLines != nil {
+		opts.Tail = strconv.FormatInt(*logOptions.TailLines, 10)
 	}
 
 	err = dm.client.Logs(opts)
@@ -165,13 +301,16 @@ var (
 type containerStatusResult struct {
 	status api.ContainerStatus
 	ip     string
-	err    error
+	// restartCountFromLabels is true if the restart count in the status was
+	// read from the container's docker labels.
+	restartCountFromLabels bool
+	err                    error
 }
 
 func (dm *DockerManager) inspectContainer(dockerID, containerName, tPath string) *containerStatusResult {
-	result := containerStatusResult{api.ContainerStatus{}, "", nil}
+	result := containerStatusResult{}
 
-	inspectResult, err := dm.client.InspectContainer(dockerID)
+	inspectResult, err := dm.inspectContainerCached(dockerID)
 
 	if err != nil {
 		result.err = err
@@ -190,6 +329,16 @@ func (dm *DockerManager) inspectContainer(dockerID, containerName, tPath string)
 		ContainerID: DockerPrefix + dockerID,
 	}
 
+	// Prefer the information recorded as labels at creation time; it survives
+	// garbage collection of older containers while name parsing does not.
+	if count, found := getRestartCountFromLabels(inspectResult.Config.Labels); found {
+		result.status.RestartCount = count
+		result.restartCountFromLabels = true
+	}
+	if path, found := getTerminationMessagePathFromLabels(inspectResult.Config.Labels); found {
+		tPath = path
+	}
+
 	if inspectResult.State.Running {
 		result.status.State.Running = &api.ContainerStateRunning{
 			StartedAt: util.NewTime(inspectResult.State.StartedAt),
@@ -203,7 +352,7 @@ func (dm *DockerManager) inspectContainer(dockerID, containerName, tPath string)
 		// In that case, the container is oom killed, but the exit
 		// code could be 0.
 		if inspectResult.State.OOMKilled {
-			reason = "OOM Killed"
+			reason = "OOMKilled"
 		} else {
 			reason = inspectResult.State.Error
 		}
@@ -220,10 +369,19 @@ func (dm *DockerManager) inspectContainer(dockerID, containerName, tPath string)
 				data, err := ioutil.ReadFile(path)
 				if err != nil {
 					glog.Errorf("Error on reading termination-log %s: %v", path, err)
+				} else if len(data) > maxTerminationMessageLength {
+					// Keep the tail; the most recent output is usually the
+					// most relevant for debugging.
+					result.status.State.Termination.Message = string(data[len(data)-maxTerminationMessageLength:])
 				} else {
 					result.status.State.Termination.Message = string(data)
 				}
 			}
+			if result.status.State.Termination.Message == "" {
+				// The container did not write a termination message; fall
+				// back to the tail of the container log.
+				result.status.State.Termination.Message = dm.getTerminationMessageFromLogs(dockerID)
+			}
 		}
 	} else {
 		// TODO(dchen1107): Separate issue docker/docker#8294 was filed
@@ -235,19 +393,95 @@ func (dm *DockerManager) inspectContainer(dockerID, containerName, tPath string)
 	return &result
 }
 
+// getTerminationMessageFromLogs returns the tail of a dead container's log
+// for use as its termination message. Failures are logged and an empty string
+// is returned, since the message is best-effort.
+func (dm *DockerManager) getTerminationMessageFromLogs(dockerID string) string {
+	var buf bytes.Buffer
+	err := dm.client.Logs(docker.LogsOptions{
+		Container:    dockerID,
+		Stdout:       true,
+		Stderr:       true,
+		OutputStream: &buf,
+		ErrorStream:  &buf,
+		Tail:         terminationMessageFallbackLogLines,
+		RawTerminal:  false,
+	})
+	if err != nil {
+		glog.Errorf("Error on reading log for termination message of container %q: %v", dockerID, err)
+		return ""
+	}
+	message := buf.String()
+	if len(message) > maxTerminationMessageLength {
+		message = message[len(message)-maxTerminationMessageLength:]
+	}
+	return message
+}
+
 // GetPodStatus returns docker related status for all containers in the pod as
 // well as the infrastructure container.
+// containerIsStuck returns true when the inspected container can never run
+// again: docker marked it dead after a failed removal, or it was created but
+// never started. Containers whose creation time is unknown or recent are
+// left alone so a start that is still in flight is not misjudged.
+func containerIsStuck(inspect *docker.Container) bool {
+	if inspect == nil {
+		return false
+	}
+	state := inspect.State
+	if state.Running || state.Restarting {
+		return false
+	}
+	if state.Dead {
+		return true
+	}
+	if inspect.Created.IsZero() || time.Since(inspect.Created) < stuckContainerGracePeriod {
+		return false
+	}
+	return state.StartedAt.IsZero() && state.FinishedAt.IsZero()
+}
+
+// stuckContainerState names the docker state a stuck container is in, for
+// logs and events.
+func stuckContainerState(inspect *docker.Container) string {
+	if inspect.State.Dead {
+		return "Dead"
+	}
+	return "Created"
+}
+
+// removeStuckContainer force-removes a container that can never run again,
+// so that the next sync can create a replacement with a clean slate.
+// Failures are only logged; the removal is retried on the next sync.
+func (dm *DockerManager) removeStuckContainer(id string, pod *api.Pod, state string) {
+	glog.Warningf("Removing container %q of pod %q stuck in the %s state", id, kubecontainer.GetPodFullName(pod), state)
+	if err := dm.client.RemoveContainer(docker.RemoveContainerOptions{ID: id, Force: true}); err != nil {
+		glog.Errorf("Failed to remove stuck container %q: %v", id, err)
+		return
+	}
+	dm.invalidateInspectCache(id)
+	dm.recorder.Eventf(pod, "StuckContainer", "Removed container %v stuck in the %s state; it will be created anew", id, state)
+}
+
 func (dm *DockerManager) GetPodStatus(pod *api.Pod) (*api.PodStatus, error) {
 	podFullName := kubecontainer.GetPodFullName(pod)
 	uid := pod.UID
 	manifest := pod.Spec
 
-	oldStatuses := make(map[string]api.ContainerStatus, len(pod.Spec.Containers))
-	lastObservedTime := make(map[string]util.Time, len(pod.Spec.Containers))
-	for _, status := range pod.Status.ContainerStatuses {
-		oldStatuses[status.Name] = status
-		if status.LastTerminationState.Termination != nil {
-			lastObservedTime[status.Name] = status.LastTerminationState.Termination.FinishedAt
+	// Init containers are handled exactly like the regular containers until
+	// the statuses are assembled at the end.
+	allContainers := make([]api.Container, 0, len(manifest.InitContainers)+len(manifest.Containers))
+	allContainers = append(allContainers, manifest.InitContainers...)
+	allContainers = append(allContainers, manifest.Containers...)
+
+	oldStatuses := make(map[string]api.ContainerStatus, len(allContainers))
+	lastObservedTime := make(map[string]util.Time, len(allContainers))
+	for _, statuses := range [][]api.ContainerStatus{pod.Status.InitContainerStatuses, pod.Status.ContainerStatuses} {
+		for _, status := range statuses {
+			oldStatuses[status.Name] = status
+			if status.LastTerminationState.Termination != nil {
+				lastObservedTime[status.Name] = status.LastTerminationState.Termination.FinishedAt
+			}
 		}
 	}
 
@@ -255,17 +489,25 @@ func (dm *DockerManager) GetPodStatus(pod *api.Pod) (*api.PodStatus, error) {
 	statuses := make(map[string]*api.ContainerStatus, len(pod.Spec.Containers))
 
 	expectedContainers := make(map[string]api.Container)
-	for _, container := range manifest.Containers {
+	for _, container := range allContainers {
 		expectedContainers[container.Name] = container
 	}
 	expectedContainers[PodInfraContainerName] = api.Container{}
 
+	initContainerNames := util.NewStringSet()
+	for _, container := range manifest.InitContainers {
+		initContainerNames.Insert(container.Name)
+	}
+
 	containers, err := dm.client.ListContainers(docker.ListContainersOptions{All: true})
 	if err != nil {
 		return nil, err
 	}
 
 	containerDone := util.NewStringSet()
+	// Containers whose restart count was read from docker labels; for these,
+	// older dead containers must not be counted.
+	countedFromLabels := util.NewStringSet()
 	// Loop through list of running and exited docker containers to construct
 	// the statuses. We assume docker returns a list of containers sorted in
 	// reverse by time.
@@ -298,7 +540,16 @@ func (dm *DockerManager) GetPodStatus(pod *api.Pod) (*api.PodStatus, error) {
 		result := dm.inspectContainer(value.ID, dockerContainerName, terminationMessagePath)
 		if result.err != nil {
 			return nil, result.err
-		} else if result.status.State.Termination != nil {
+		}
+		// A container that docker failed to remove or that never started
+		// will not run again; remove it so it does not wedge the pod, and
+		// let the status fall through to an older incarnation or to the
+		// default waiting state so that the container is created anew.
+		if inspect, err := dm.inspectContainerCached(value.ID); err == nil && containerIsStuck(inspect) {
+			dm.removeStuckContainer(value.ID, pod, stuckContainerState(inspect))
+			continue
+		}
+		if result.status.State.Termination != nil {
 			terminationState = &result.status.State
 		}
 
@@ -307,7 +558,9 @@ func (dm *DockerManager) GetPodStatus(pod *api.Pod) (*api.PodStatus, error) {
 				// Populate the last termination state.
 				containerStatus.LastTerminationState = *terminationState
 			}
-			count := true
+			// The restart count read from the newest container's labels is
+			// authoritative; never reconstruct it from older containers.
+			count := !countedFromLabels.Has(dockerContainerName)
 			// Only count dead containers terminated after last time we observed,
 			if lastObservedTime, ok := lastObservedTime[dockerContainerName]; ok {
 				if terminationState != nil && terminationState.Termination.FinishedAt.After(lastObservedTime.Time) {
@@ -332,7 +585,9 @@ func (dm *DockerManager) GetPodStatus(pod *api.Pod) (*api.PodStatus, error) {
 			}
 		} else {
 			// Add user container information.
-			if oldStatus, found := oldStatuses[dockerContainerName]; found {
+			if result.restartCountFromLabels {
+				countedFromLabels.Insert(dockerContainerName)
+			} else if oldStatus, found := oldStatuses[dockerContainerName]; found {
 				// Use the last observed restart count if it's available.
 				result.status.RestartCount = oldStatus.RestartCount
 			}
@@ -342,7 +597,7 @@ func (dm *DockerManager) GetPodStatus(pod *api.Pod) (*api.PodStatus, error) {
 
 	// Handle the containers for which we cannot find any associated active or
 	// dead docker containers.
-	for _, container := range manifest.Containers {
+	for _, container := range allContainers {
 		if _, found := statuses[container.Name]; found {
 			continue
 		}
@@ -360,8 +615,12 @@ func (dm *DockerManager) GetPodStatus(pod *api.Pod) (*api.PodStatus, error) {
 		// record the pull failure and eliminate the image checking below.
 		image := container.Image
 		// TODO(dchen1107): docker/docker/issues/8365 to figure out if the image exists
-		_, err := dm.client.InspectImage(image)
+		inspected, err := dm.client.InspectImage(image)
 		if err == nil {
+			if inspected != nil {
+				// Record exactly which image would run; tags are mutable.
+				containerStatus.ImageID = DockerPrefix + inspected.ID
+			}
 			containerStatus.State.Waiting = &api.ContainerStateWaiting{
 				Reason: fmt.Sprintf("Image: %s is ready, container is creating", image),
 			}
@@ -381,7 +640,11 @@ func (dm *DockerManager) GetPodStatus(pod *api.Pod) (*api.PodStatus, error) {
 				status.State.Waiting.Reason = reason
 			}
 		}
-		podStatus.ContainerStatuses = append(podStatus.ContainerStatuses, *status)
+		if initContainerNames.Has(containerName) {
+			podStatus.InitContainerStatuses = append(podStatus.InitContainerStatuses, *status)
+		} else {
+			podStatus.ContainerStatuses = append(podStatus.ContainerStatuses, *status)
+		}
 	}
 
 	return &podStatus, nil
@@ -404,6 +667,11 @@ func (dm *DockerManager) GetRunningContainers(ids []string) ([]*docker.Container
 	return result, nil
 }
 
+// startFailure marks an error from the start step of running a container.
+// The created container has already been removed when it is returned, so
+// the whole attempt can safely be retried.
+type startFailure struct{ error }
+
 func (dm *DockerManager) runContainerRecordErrorReason(pod *api.Pod, container *api.Container, opts *kubecontainer.RunContainerOptions, ref *api.ObjectReference) (string, error) {
 	dockerID, err := dm.runContainer(pod, container, opts, ref)
 	if err != nil {
@@ -429,9 +697,23 @@ func (dm *DockerManager) runContainer(pod *api.Pod, container *api.Container, op
 	// Cap hostname at 63 chars (specification is 64bytes which is 63 chars and the null terminating char).
 	const hostnameMaxLen = 63
 	containerHostname := pod.Name
+	if len(pod.Spec.Hostname) > 0 {
+		containerHostname = pod.Spec.Hostname
+	}
 	if len(containerHostname) > hostnameMaxLen {
 		containerHostname = containerHostname[:hostnameMaxLen]
 	}
+	// Derive the restart count for the new container from the last observed
+	// status; it is recorded as a label so that garbage collection of older
+	// dead containers cannot corrupt the count.
+	restartCount := 0
+	for _, status := range pod.Status.ContainerStatuses {
+		if status.Name == container.Name && status.State.Waiting == nil {
+			restartCount = status.RestartCount + 1
+			break
+		}
+	}
+
 	dockerOpts := docker.CreateContainerOptions{
 		Name: BuildDockerName(dockerName, container),
 		Config: &docker.Config{
@@ -442,23 +724,24 @@ func (dm *DockerManager) runContainer(pod *api.Pod, container *api.Container, op
 			Memory:       container.Resources.Limits.Memory().Value(),
 			CPUShares:    milliCPUToShares(container.Resources.Limits.Cpu().MilliValue()),
 			WorkingDir:   container.WorkingDir,
+			Labels:       newLabels(pod, container, restartCount),
 		},
 	}
 
-	setEntrypointAndCommand(container, &dockerOpts)
+	setEntrypointAndCommand(container, opts, &dockerOpts)
 
 	glog.V(3).Infof("Container %v/%v/%v: setting entrypoint \"%v\" and command \"%v\"", pod.Namespace, pod.Name, container.Name, dockerOpts.Config.Entrypoint, dockerOpts.Config.Cmd)
 
 	dockerContainer, err := dm.client.CreateContainer(dockerOpts)
 	if err != nil {
 		if ref != nil {
-			dm.recorder.Eventf(ref, "failed", "Failed to create docker container with error: %v", err)
+			dm.recorder.Eventf(ref, "Failed", "Failed to create docker container with error: %v", err)
 		}
 		return "", err
 	}
 
 	if ref != nil {
-		dm.recorder.Eventf(ref, "created", "Created with docker id %v", dockerContainer.ID)
+		dm.recorder.Eventf(ref, "Created", "Created with docker id %v", dockerContainer.ID)
 	}
 
 	// The reason we create and mount the log file in here (not in kubelet) is because
@@ -486,15 +769,26 @@ func (dm *DockerManager) runContainer(pod *api.Pod, container *api.Container, op
 		return "", fmt.Errorf("container requested privileged mode, but it is disallowed globally.")
 	}
 
+	securityOpts, err := dm.getSecurityOpts(pod, container)
+	if err != nil {
+		return "", err
+	}
+
 	capAdd, capDrop := makeCapabilites(container.Capabilities.Add, container.Capabilities.Drop)
 	hc := &docker.HostConfig{
 		PortBindings: portBindings,
-		Binds:        opts.Binds,
-		NetworkMode:  opts.NetMode,
-		IpcMode:      opts.IpcMode,
-		Privileged:   privileged,
-		CapAdd:       capAdd,
-		CapDrop:      capDrop,
+		// The kubelet owns restarts; a docker-level restart policy would
+		// revive containers behind its back and corrupt its accounting.
+		RestartPolicy: docker.NeverRestart(),
+		Binds:         opts.Binds,
+		NetworkMode:   opts.NetMode,
+		IpcMode:       opts.IpcMode,
+		PidMode:       opts.PidMode,
+		Privileged:    privileged,
+		CapAdd:        capAdd,
+		CapDrop:       capDrop,
+		SecurityOpt:   securityOpts,
+		Devices:       makeDevices(container.Devices),
 	}
 	if len(opts.DNS) > 0 {
 		hc.DNS = opts.DNS
@@ -502,26 +796,113 @@ func (dm *DockerManager) runContainer(pod *api.Pod, container *api.Container, op
 	if len(opts.DNSSearch) > 0 {
 		hc.DNSSearch = opts.DNSSearch
 	}
+	if len(opts.ResolvConfPath) > 0 {
+		hc.Binds = append(hc.Binds, fmt.Sprintf("%s:/etc/resolv.conf:ro", opts.ResolvConfPath))
+	}
+	if len(opts.HostsFilePath) > 0 {
+		hc.Binds = append(hc.Binds, fmt.Sprintf("%s:/etc/hosts", opts.HostsFilePath))
+	}
+	if container.Name == PodInfraContainerName {
+		hc.ShmSize = dm.podShmSize(pod)
+	}
 
 	if err = dm.client.StartContainer(dockerContainer.ID, hc); err != nil {
 		if ref != nil {
-			dm.recorder.Eventf(ref, "failed",
+			dm.recorder.Eventf(ref, "Failed",
 				"Failed to start with docker id %v with error: %v", dockerContainer.ID, err)
 		}
-		return "", err
+		// The container is left behind in the "Created" state and will
+		// never run; remove it right away so it cannot wedge the pod.
+		if rmErr := dm.client.RemoveContainer(docker.RemoveContainerOptions{ID: dockerContainer.ID}); rmErr != nil {
+			glog.Errorf("Failed to remove container %q that did not start: %v", dockerContainer.ID, rmErr)
+		}
+		return "", startFailure{err}
 	}
 	if ref != nil {
-		dm.recorder.Eventf(ref, "started", "Started with docker id %v", dockerContainer.ID)
+		dm.recorder.Eventf(ref, "Started", "Started with docker id %v", dockerContainer.ID)
 	}
+
+	// Create a symbolic link to the docker json log file using a name that
+	// captures the full pod name, the container name and the docker container
+	// ID. Cluster level logging agents pick the pod metadata out of the link
+	// name to enrich the log entries they collect.
+	containerLogFile := path.Join(dm.dockerRoot, "containers", dockerContainer.ID, fmt.Sprintf("%s-json.log", dockerContainer.ID))
+	symlinkFile := LogSymlink(dm.containerLogsDir, dockerName.PodFullName, container.Name, dockerContainer.ID)
+	if err := os.Symlink(containerLogFile, symlinkFile); err != nil {
+		glog.Errorf("Failed to create symbolic link to the log file of pod %q container %q: %v", dockerName.PodFullName, container.Name, err)
+	}
+
 	return dockerContainer.ID, nil
 }
 
-func setEntrypointAndCommand(container *api.Container, opts *docker.CreateContainerOptions) {
+// makeDevices converts the container's device mappings into the docker form,
+// filling in the defaults for the container path and cgroup permissions.
+func makeDevices(devices []api.Device) []docker.Device {
+	var result []docker.Device
+	for _, device := range devices {
+		containerPath := device.ContainerPath
+		if len(containerPath) == 0 {
+			containerPath = device.HostPath
+		}
+		permissions := device.Permissions
+		if len(permissions) == 0 {
+			permissions = "mrw"
+		}
+		result = append(result, docker.Device{
+			PathOnHost:        device.HostPath,
+			PathInContainer:   containerPath,
+			CgroupPermissions: permissions,
+		})
+	}
+	return result
+}
+
+// podShmSize returns the size of the pod's /dev/shm in bytes. The shm lives
+// in the infra container and is shared with the other containers through the
+// pod's IPC namespace, so it is sized to the pod's memory limit. For pods
+// where not every container has a memory limit, the configured default is
+// used instead; zero keeps the docker default.
+func (dm *DockerManager) podShmSize(pod *api.Pod) int64 {
+	var limit int64
+	for _, container := range pod.Spec.Containers {
+		l := container.Resources.Limits.Memory().Value()
+		if l == 0 {
+			return dm.podInfraShmSize
+		}
+		limit += l
+	}
+	if limit == 0 {
+		return dm.podInfraShmSize
+	}
+	return limit
+}
+
+func setEntrypointAndCommand(container *api.Container, opts *kubecontainer.RunContainerOptions, dockerOpts *docker.CreateContainerOptions) {
+	// Command and args can reference the container's env vars with the
+	// $(VAR) syntax; references to vars that are not defined are left
+	// unchanged.
+	envs := make(map[string]string, len(opts.Envs))
+	for _, env := range opts.Envs {
+		parts := strings.SplitN(env, "=", 2)
+		if len(parts) == 2 {
+			envs[parts[0]] = parts[1]
+		}
+	}
+	mappingFunc := expansion.MappingFuncFor(envs)
+
 	if len(container.Command) != 0 {
-		opts.Config.Entrypoint = container.Command
+		command := make([]string, len(container.Command))
+		for i, cmd := range container.Command {
+			command[i] = expansion.Expand(cmd, mappingFunc)
+		}
+		dockerOpts.Config.Entrypoint = command
 	}
 	if len(container.Args) != 0 {
-		opts.Config.Cmd = container.Args
+		args := make([]string, len(container.Args))
+		for i, arg := range container.Args {
+			args[i] = expansion.Expand(arg, mappingFunc)
+		}
+		dockerOpts.Config.Cmd = args
 	}
 }
 
@@ -574,6 +955,10 @@ func makeCapabilites(capAdd []api.CapabilityType, capDrop []api.CapabilityType)
 }
 
 func (dm *DockerManager) GetPods(all bool) ([]*kubecontainer.Pod, error) {
+	start := time.Now()
+	defer func() {
+		metrics.ContainerRelistLatency.Observe(metrics.SinceInMicroseconds(start))
+	}()
 	pods := make(map[types.UID]*kubecontainer.Pod)
 	var result []*kubecontainer.Pod
 
@@ -588,6 +973,8 @@ func (dm *DockerManager) GetPods(all bool) ([]*kubecontainer.Pod, error) {
 			glog.Warningf("Cannot parse empty docker container name: %#v", c.Names)
 			continue
 		}
+		// The list API does not return labels, so the legacy encoded name is
+		// still the only metadata available here.
 		dockerName, hash, err := ParseDockerName(c.Names[0])
 		if err != nil {
 			glog.Warningf("Parse docker container name %q error: %v", c.Names[0], err)
@@ -622,20 +1009,43 @@ func (dm *DockerManager) GetPods(all bool) ([]*kubecontainer.Pod, error) {
 	return result, nil
 }
 
-func (dm *DockerManager) Pull(image string) error {
-	return dm.Puller.Pull(image)
+func (dm *DockerManager) Pull(image string, onProgress PullProgressFunc) error {
+	rewritten := applyRegistryRewrites(image, dm.RegistryRewrites)
+	if rewritten == image {
+		return dm.Puller.Pull(image, onProgress)
+	}
+	glog.V(2).Infof("Pulling image %q via mirror as %q", image, rewritten)
+	if err := dm.Puller.Pull(rewritten, onProgress); err != nil {
+		return err
+	}
+	// Tag the mirrored image back under its original name so that the rest
+	// of the kubelet finds it there.
+	repo, tag := parseImageName(image)
+	if len(tag) == 0 {
+		tag = "latest"
+	}
+	rewrittenRepo, rewrittenTag := parseImageName(rewritten)
+	if len(rewrittenTag) == 0 {
+		rewrittenTag = "latest"
+	}
+	return dm.client.TagImage(fmt.Sprintf("%s:%s", rewrittenRepo, rewrittenTag), docker.TagImageOptions{Repo: repo, Tag: tag, Force: true})
 }
 
 func (dm *DockerManager) IsImagePresent(image string) (bool, error) {
 	return dm.Puller.IsImagePresent(image)
 }
 
+// ForgetImages drops any cached image presence checks.
+func (dm *DockerManager) ForgetImages() {
+	dm.Puller.ForgetImages()
+}
+
 // PodInfraContainer returns true if the pod infra container has changed.
 func (dm *DockerManager) PodInfraContainerChanged(pod *api.Pod, podInfraContainer *kubecontainer.Container) (bool, error) {
 	networkMode := ""
 	var ports []api.ContainerPort
 
-	dockerPodInfraContainer, err := dm.client.InspectContainer(string(podInfraContainer.ID))
+	dockerPodInfraContainer, err := dm.inspectContainerCached(string(podInfraContainer.ID))
 	if err != nil {
 		return false, err
 	}
@@ -661,7 +1071,7 @@ func (dm *DockerManager) PodInfraContainerChanged(pod *api.Pod, podInfraContaine
 		Image: dm.PodInfraContainerImage,
 		Ports: ports,
 	}
-	return podInfraContainer.Hash != HashContainer(expectedPodInfraContainer), nil
+	return ContainerHashChanged(expectedPodInfraContainer, podInfraContainer.Hash), nil
 }
 
 type dockerVersion docker.APIVersion
@@ -690,6 +1100,61 @@ func (dv dockerVersion) Compare(other string) (int, error) {
 	return 0, nil
 }
 
+// RestartPolicyMutated returns true if the container's docker-level restart
+// policy has been changed out-of-band to something other than "no". Such a
+// container would be revived by docker behind the kubelet's back, so the
+// caller is expected to kill and re-create it.
+func (dm *DockerManager) RestartPolicyMutated(containerID DockerID) bool {
+	inspect, err := dm.inspectContainerCached(string(containerID))
+	if err != nil || inspect.HostConfig == nil {
+		return false
+	}
+	name := inspect.HostConfig.RestartPolicy.Name
+	return name != "" && name != "no"
+}
+
+// TryUpdateContainerResources returns true if the only difference between
+// the running container and the desired spec is the resource requirements,
+// and the new limits were successfully applied in place through the docker
+// update endpoint. Containers created before the hash-excluding-resources
+// label was recorded cannot be diagnosed this way and are restarted as
+// before.
+func (dm *DockerManager) TryUpdateContainerResources(containerID DockerID, container *api.Container) bool {
+	ID := string(containerID)
+	specHash := HashContainer(container)
+	dm.updatedResourcesLock.Lock()
+	applied := dm.updatedResources[ID]
+	dm.updatedResourcesLock.Unlock()
+	if applied == specHash {
+		// These limits have already been applied.
+		return true
+	}
+
+	inspect, err := dm.inspectContainerCached(ID)
+	if err != nil || inspect.Config == nil {
+		return false
+	}
+	recordedHash, found := getHashExcludingResourcesFromLabels(inspect.Config.Labels)
+	if !found || recordedHash != HashContainerExcludingResources(container) {
+		// Something other than the resources changed too.
+		return false
+	}
+
+	limits := container.Resources.Limits
+	opts := docker.UpdateContainerOptions{
+		CPUShares: milliCPUToShares(limits.Cpu().MilliValue()),
+		Memory:    limits.Memory().Value(),
+	}
+	if err := dm.client.UpdateContainer(ID, opts); err != nil {
+		glog.Warningf("Failed to update resources of container %q in place: %v", ID, err)
+		return false
+	}
+	dm.updatedResourcesLock.Lock()
+	dm.updatedResources[ID] = specHash
+	dm.updatedResourcesLock.Unlock()
+	return true
+}
+
 func (dm *DockerManager) Version() (kubecontainer.Version, error) {
 	env, err := dm.client.Version()
 	if err != nil {
@@ -774,19 +1239,13 @@ func (dm *DockerManager) RunInContainer(containerID string, cmd []string) ([]byt
 	return buf.Bytes(), <-errChan
 }
 
-// ExecInContainer uses nsenter to run the command inside the container identified by containerID.
+// ExecInContainer runs the command inside the container identified by
+// containerID using the configured exec handler, degrading to nsenter when
+// the daemon has no native exec endpoint.
 //
 // TODO:
-//  - match cgroups of container
-//  - should we support `docker exec`?
-//  - should we support nsenter in a container, running with elevated privs and --pid=host?
 //  - use strong type for containerId
 func (dm *DockerManager) ExecInContainer(containerId string, cmd []string, stdin io.Reader, stdout, stderr io.WriteCloser, tty bool) error {
-	nsenter, err := exec.LookPath("nsenter")
-	if err != nil {
-		return fmt.Errorf("exec unavailable - unable to locate nsenter")
-	}
-
 	container, err := dm.client.InspectContainer(containerId)
 	if err != nil {
 		return err
@@ -796,67 +1255,46 @@ func (dm *DockerManager) ExecInContainer(containerId string, cmd []string, stdin
 		return fmt.Errorf("container not running (%s)", container)
 	}
 
-	containerPid := container.State.Pid
-
-	// TODO what if the container doesn't have `env`???
-	args := []string{"-t", fmt.Sprintf("%d", containerPid), "-m", "-i", "-u", "-n", "-p", "--", "env", "-i"}
-	args = append(args, fmt.Sprintf("HOSTNAME=%s", container.Config.Hostname))
-	args = append(args, container.Config.Env...)
-	args = append(args, cmd...)
-	command := exec.Command(nsenter, args...)
-	if tty {
-		p, err := StartPty(command)
-		if err != nil {
-			return err
-		}
-		defer p.Close()
-
-		// make sure to close the stdout stream
-		defer stdout.Close()
-
-		if stdin != nil {
-			go io.Copy(p, stdin)
-		}
-
-		if stdout != nil {
-			go io.Copy(stdout, p)
-		}
-
-		return command.Wait()
-	} else {
-		if stdin != nil {
-			// Use an os.Pipe here as it returns true *os.File objects.
-			// This way, if you run 'kubectl exec -p <pod> -i bash' (no tty) and type 'exit',
-			// the call below to command.Run() can unblock because its Stdin is the read half
-			// of the pipe.
-			r, w, err := os.Pipe()
-			if err != nil {
-				return err
-			}
-			go io.Copy(w, stdin)
-
-			command.Stdin = r
+	handler := dm.ExecHandler
+	if _, native := handler.(*NativeExecHandler); native {
+		if ok, err := dm.nativeExecSupportExists(); err == nil && !ok {
+			handler = &NsenterExecHandler{}
 		}
-		if stdout != nil {
-			command.Stdout = stdout
-		}
-		if stderr != nil {
-			command.Stderr = stderr
-		}
-
-		return command.Run()
 	}
+	return handler.ExecInContainer(dm.client, container, cmd, stdin, stdout, stderr, tty)
+}
+
+// AttachContainer attaches to the container identified by containerId,
+// copying data between the container's stdio and the supplied
+// stdin/stdout/stderr. When tty is true the container's output is a raw
+// terminal stream; otherwise it is multiplexed by docker.
+func (dm *DockerManager) AttachContainer(containerId string, stdin io.Reader, stdout, stderr io.WriteCloser, tty bool) error {
+	opts := docker.AttachToContainerOptions{
+		Container:    containerId,
+		InputStream:  stdin,
+		OutputStream: stdout,
+		ErrorStream:  stderr,
+		Stream:       true,
+		Logs:         true,
+		Stdin:        stdin != nil,
+		Stdout:       stdout != nil,
+		Stderr:       stderr != nil,
+		RawTerminal:  tty,
+	}
+	return dm.client.AttachToContainer(opts)
 }
 
 // PortForward executes socat in the pod's network namespace and copies
 // data between stream (representing the user's local connection on their
-// computer) and the specified port in the container.
+// computer) and the specified port in the container. The protocol is either
+// "tcp" or "udp"; UDP forwarding also runs through the infra container's
+// network namespace.
 //
 // TODO:
 //  - match cgroups of container
 //  - should we support nsenter + socat on the host? (current impl)
 //  - should we support nsenter + socat in a container, running with elevated privs and --pid=host?
-func (dm *DockerManager) PortForward(pod *kubecontainer.Pod, port uint16, stream io.ReadWriteCloser) error {
+func (dm *DockerManager) PortForward(pod *kubecontainer.Pod, port uint16, protocol string, stream io.ReadWriteCloser) error {
 	podInfraContainer := pod.FindContainerByName(PodInfraContainerName)
 	if podInfraContainer == nil {
 		return fmt.Errorf("cannot find pod infra container in pod %q", kubecontainer.BuildPodFullName(pod.Name, pod.Namespace))
@@ -870,13 +1308,23 @@ func (dm *DockerManager) PortForward(pod *kubecontainer.Pod, port uint16, stream
 		return fmt.Errorf("container not running (%s)", container)
 	}
 
+	var target string
+	switch protocol {
+	case "", api.PortForwardProtocolTCP:
+		target = fmt.Sprintf("TCP4:localhost:%d", port)
+	case api.PortForwardProtocolUDP:
+		target = fmt.Sprintf("UDP4:localhost:%d", port)
+	default:
+		return fmt.Errorf("unsupported port forwarding protocol %q", protocol)
+	}
+
 	containerPid := container.State.Pid
 	// TODO what if the host doesn't have it???
 	_, lookupErr := exec.LookPath("socat")
 	if lookupErr != nil {
 		return fmt.Errorf("Unable to do port forwarding: socat not found.")
 	}
-	args := []string{"-t", fmt.Sprintf("%d", containerPid), "-n", "socat", "-", fmt.Sprintf("TCP4:localhost:%d", port)}
+	args := []string{"-t", fmt.Sprintf("%d", containerPid), "-n", "socat", "-", target}
 	// TODO use exec.LookPath
 	command := exec.Command("nsenter", args...)
 	command.Stdin = stream
@@ -888,34 +1336,69 @@ func (dm *DockerManager) PortForward(pod *kubecontainer.Pod, port uint16, stream
 // Internally, it invokes docker's StopContainer API with a timeout of 10s.
 // TODO(yifan): Use new ContainerID type.
 func (dm *DockerManager) KillContainer(containerID types.UID) error {
+	return dm.KillContainerWithDeadline(containerID, time.Time{})
+}
+
+// KillContainerWithDeadline is KillContainer for a container that must be
+// stopped by the given deadline, typically because its pod's deletion grace
+// period ends then. Stops run through a bounded worker pool so that a mass
+// pod deletion does not issue hundreds of docker stops at once; when stops
+// queue, the nearest deadline is served first and a zero deadline queues
+// behind all others.
+func (dm *DockerManager) KillContainerWithDeadline(containerID types.UID, deadline time.Time) error {
 	ID := string(containerID)
 	glog.V(2).Infof("Killing container with id %q", ID)
 	dm.readinessManager.RemoveReadiness(ID)
+	dm.invalidateInspectCache(ID)
+	dm.updatedResourcesLock.Lock()
+	delete(dm.updatedResources, ID)
+	dm.updatedResourcesLock.Unlock()
+	dm.killThrottle.acquire(deadline)
 	err := dm.client.StopContainer(ID, 10)
+	dm.killThrottle.release()
+	if IsContainerNotFoundError(err) || IsConflictError(err) {
+		// The container is already gone or stopped; killing it is idempotent
+		// and callers such as syncPod should not see this as a failure.
+		glog.V(4).Infof("Container %q already stopped: %v", ID, err)
+		err = nil
+	}
 
 	ref, ok := dm.containerRefManager.GetRef(ID)
 	if !ok {
 		glog.Warningf("No ref for pod '%v'", ID)
 	} else {
 		// TODO: pass reason down here, and state, or move this call up the stack.
-		dm.recorder.Eventf(ref, "killing", "Killing %v", ID)
+		dm.recorder.Eventf(ref, "Killing", "Killing %v", ID)
 	}
 	return err
 }
 
 // Run a single container from a pod. Returns the docker container ID
-func (dm *DockerManager) RunContainer(pod *api.Pod, container *api.Container, generator kubecontainer.RunContainerOptionsGenerator, runner kubecontainer.HandlerRunner, netMode, ipcMode string) (DockerID, error) {
+func (dm *DockerManager) RunContainer(pod *api.Pod, container *api.Container, generator kubecontainer.RunContainerOptionsGenerator, runner kubecontainer.HandlerRunner, podIP, netMode, ipcMode, pidMode string) (DockerID, error) {
 	ref, err := kubecontainer.GenerateContainerRef(pod, container)
 	if err != nil {
 		glog.Errorf("Couldn't make a ref to pod %v, container %v: '%v'", pod.Name, container.Name, err)
 	}
 
-	opts, err := generator.GenerateRunContainerOptions(pod, container, netMode, ipcMode)
+	opts, err := generator.GenerateRunContainerOptions(pod, container, podIP, netMode, ipcMode, pidMode)
 	if err != nil {
 		return "", err
 	}
 
 	id, err := dm.runContainerRecordErrorReason(pod, container, opts, ref)
+	// A failed start leaves no container behind, so it is safe to retry the
+	// whole attempt; back off between attempts to give docker a chance to
+	// recover.
+	backoff := runContainerRetryBackoff
+	for attempt := 1; err != nil && attempt < maxRunContainerAttempts; attempt++ {
+		if _, retryable := err.(startFailure); !retryable {
+			break
+		}
+		glog.Warningf("Failed to run container %q of pod %q (attempt %d): %v; retrying in %v", container.Name, kubecontainer.GetPodFullName(pod), attempt, err, backoff)
+		time.Sleep(backoff)
+		backoff *= 2
+		id, err = dm.runContainerRecordErrorReason(pod, container, opts, ref)
+	}
 	if err != nil {
 		return "", err
 	}
@@ -927,9 +1410,26 @@ func (dm *DockerManager) RunContainer(pod *api.Pod, container *api.Container, ge
 
 	if container.Lifecycle != nil && container.Lifecycle.PostStart != nil {
 		handlerErr := runner.Run(id, pod, container, container.Lifecycle.PostStart)
+		hookBackoff := postStartRetryBackoff
+		for attempt := 1; handlerErr != nil && attempt < maxPostStartAttempts; attempt++ {
+			glog.Warningf("PostStart hook for container %q of pod %q failed (attempt %d): %v; retrying in %v", container.Name, kubecontainer.GetPodFullName(pod), attempt, handlerErr, hookBackoff)
+			time.Sleep(hookBackoff)
+			hookBackoff *= 2
+			handlerErr = runner.Run(id, pod, container, container.Lifecycle.PostStart)
+		}
 		if handlerErr != nil {
-			dm.KillContainer(types.UID(id))
-			return DockerID(""), fmt.Errorf("failed to call event handler: %v", handlerErr)
+			// Propagate the hook error to the container status, best-effort
+			// like the creation failure reasons above.
+			dm.reasonCache.Add(pod.UID, container.Name, fmt.Sprintf("PostStart hook failed: %v", handlerErr))
+			if ref != nil {
+				dm.recorder.Eventf(ref, "FailedPostStartHook", "PostStart hook failed: %v", handlerErr)
+			}
+			if dm.PostStartFailurePolicy == HookFailureWarnOnly {
+				glog.Warningf("PostStart hook for container %q of pod %q failed after %d attempts: %v; leaving the container running", container.Name, kubecontainer.GetPodFullName(pod), maxPostStartAttempts, handlerErr)
+			} else {
+				dm.KillContainer(types.UID(id))
+				return DockerID(""), fmt.Errorf("failed to call event handler: %v", handlerErr)
+			}
 		}
 	}
 	return DockerID(id), err
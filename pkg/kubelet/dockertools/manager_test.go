@@ -17,9 +17,16 @@ limitations under the License.
 package dockertools
 
 import (
+	"fmt"
+	"reflect"
+	"strconv"
 	"testing"
+	"time"
 
 	"github.com/GoogleCloudPlatform/kubernetes/pkg/api"
+	"github.com/GoogleCloudPlatform/kubernetes/pkg/api/resource"
+	"github.com/GoogleCloudPlatform/kubernetes/pkg/client/record"
+	kubecontainer "github.com/GoogleCloudPlatform/kubernetes/pkg/kubelet/container"
 	"github.com/fsouza/go-dockerclient"
 )
 
@@ -27,6 +34,7 @@ func TestSetEntrypointAndCommand(t *testing.T) {
 	cases := []struct {
 		name      string
 		container *api.Container
+		envs      []string
 		expected  *docker.CreateContainerOptions
 	}{
 		{
@@ -47,6 +55,18 @@ func TestSetEntrypointAndCommand(t *testing.T) {
 				},
 			},
 		},
+		{
+			name: "command expanded",
+			container: &api.Container{
+				Command: []string{"foo", "$(VAR_TEST)", "$(VAR_TEST2)"},
+			},
+			envs: []string{"VAR_TEST=zoo", "VAR_TEST2=boo"},
+			expected: &docker.CreateContainerOptions{
+				Config: &docker.Config{
+					Entrypoint: []string{"foo", "zoo", "boo"},
+				},
+			},
+		},
 		{
 			name: "args",
 			container: &api.Container{
@@ -58,6 +78,18 @@ func TestSetEntrypointAndCommand(t *testing.T) {
 				},
 			},
 		},
+		{
+			name: "args expanded",
+			container: &api.Container{
+				Args: []string{"zap", "$(VAR_TEST):8080", "$$(ESCAPED)"},
+			},
+			envs: []string{"VAR_TEST=hap"},
+			expected: &docker.CreateContainerOptions{
+				Config: &docker.Config{
+					Cmd: []string{"zap", "hap:8080", "$(ESCAPED)"},
+				},
+			},
+		},
 		{
 			name: "both",
 			container: &api.Container{
@@ -71,13 +103,28 @@ func TestSetEntrypointAndCommand(t *testing.T) {
 				},
 			},
 		},
+		{
+			name: "both expanded",
+			container: &api.Container{
+				Command: []string{"$(VAR_TEST2)--$(VAR_TEST)", "foo", "$(VAR_TEST3)"},
+				Args:    []string{"foo", "$(VAR_TEST)", "$(VAR_TEST2)"},
+			},
+			envs: []string{"VAR_TEST=zoo", "VAR_TEST2=boo", "VAR_TEST3=roo"},
+			expected: &docker.CreateContainerOptions{
+				Config: &docker.Config{
+					Entrypoint: []string{"boo--zoo", "foo", "roo"},
+					Cmd:        []string{"foo", "zoo", "boo"},
+				},
+			},
+		},
 	}
 
 	for _, tc := range cases {
+		opts := &kubecontainer.RunContainerOptions{Envs: tc.envs}
 		actualOpts := &docker.CreateContainerOptions{
 			Config: &docker.Config{},
 		}
-		setEntrypointAndCommand(tc.container, actualOpts)
+		setEntrypointAndCommand(tc.container, opts, actualOpts)
 
 		if e, a := tc.expected.Config.Entrypoint, actualOpts.Config.Entrypoint; !api.Semantic.DeepEqual(e, a) {
 			t.Errorf("%v: unexpected entrypoint: expected %v, got %v", tc.name, e, a)
@@ -87,3 +134,389 @@ func TestSetEntrypointAndCommand(t *testing.T) {
 		}
 	}
 }
+
+func TestMakeDevices(t *testing.T) {
+	devices := []api.Device{
+		{HostPath: "/dev/fuse"},
+		{HostPath: "/dev/nvidia0", ContainerPath: "/dev/nvidia0", Permissions: "rw"},
+	}
+	expected := []docker.Device{
+		{PathOnHost: "/dev/fuse", PathInContainer: "/dev/fuse", CgroupPermissions: "mrw"},
+		{PathOnHost: "/dev/nvidia0", PathInContainer: "/dev/nvidia0", CgroupPermissions: "rw"},
+	}
+	if actual := makeDevices(devices); !api.Semantic.DeepEqual(expected, actual) {
+		t.Errorf("expected %v, got %v", expected, actual)
+	}
+	if actual := makeDevices(nil); actual != nil {
+		t.Errorf("expected no devices, got %v", actual)
+	}
+}
+
+func TestPodShmSize(t *testing.T) {
+	dm := &DockerManager{podInfraShmSize: 64 * 1024 * 1024}
+	limits := func(mem string) api.ResourceRequirements {
+		return api.ResourceRequirements{
+			Limits: api.ResourceList{
+				api.ResourceMemory: resource.MustParse(mem),
+			},
+		}
+	}
+	cases := []struct {
+		name     string
+		pod      *api.Pod
+		expected int64
+	}{
+		{
+			name:     "no limits",
+			pod:      &api.Pod{Spec: api.PodSpec{Containers: []api.Container{{Name: "bar"}}}},
+			expected: 64 * 1024 * 1024,
+		},
+		{
+			name: "single container with limit",
+			pod: &api.Pod{Spec: api.PodSpec{Containers: []api.Container{
+				{Name: "bar", Resources: limits("128Mi")},
+			}}},
+			expected: 128 * 1024 * 1024,
+		},
+		{
+			name: "limits are summed",
+			pod: &api.Pod{Spec: api.PodSpec{Containers: []api.Container{
+				{Name: "bar", Resources: limits("128Mi")},
+				{Name: "baz", Resources: limits("256Mi")},
+			}}},
+			expected: 384 * 1024 * 1024,
+		},
+		{
+			name: "container without limit falls back to the default",
+			pod: &api.Pod{Spec: api.PodSpec{Containers: []api.Container{
+				{Name: "bar", Resources: limits("128Mi")},
+				{Name: "baz"},
+			}}},
+			expected: 64 * 1024 * 1024,
+		},
+	}
+	for _, tc := range cases {
+		if e, a := tc.expected, dm.podShmSize(tc.pod); e != a {
+			t.Errorf("%v: expected %d, got %d", tc.name, e, a)
+		}
+	}
+}
+
+func TestGetSecurityOpts(t *testing.T) {
+	dm := &DockerManager{}
+	cases := []struct {
+		name      string
+		pod       *api.Pod
+		container *api.Container
+		expected  []string
+		expectErr bool
+	}{
+		{
+			name:      "none",
+			pod:       &api.Pod{},
+			container: &api.Container{Name: "bar"},
+		},
+		{
+			name: "selinux options",
+			pod:  &api.Pod{},
+			container: &api.Container{
+				Name: "bar",
+				SecurityContext: &api.SecurityContext{
+					SELinuxOptions: &api.SELinuxOptions{
+						User:  "user_u",
+						Role:  "role_r",
+						Type:  "type_t",
+						Level: "s0:c1,c2",
+					},
+				},
+			},
+			expected: []string{"label:user:user_u", "label:role:role_r", "label:type:type_t", "label:level:s0:c1,c2"},
+		},
+		{
+			name: "apparmor unconfined",
+			pod: &api.Pod{
+				ObjectMeta: api.ObjectMeta{
+					Annotations: map[string]string{AppArmorAnnotationPrefix + "bar": AppArmorProfileUnconfined},
+				},
+			},
+			container: &api.Container{Name: "bar"},
+			expected:  []string{"apparmor:unconfined"},
+		},
+		{
+			name: "apparmor runtime default",
+			pod: &api.Pod{
+				ObjectMeta: api.ObjectMeta{
+					Annotations: map[string]string{AppArmorAnnotationPrefix + "bar": AppArmorProfileRuntimeDefault},
+				},
+			},
+			container: &api.Container{Name: "bar"},
+		},
+		{
+			name: "invalid apparmor profile",
+			pod: &api.Pod{
+				ObjectMeta: api.ObjectMeta{
+					Annotations: map[string]string{AppArmorAnnotationPrefix + "bar": "bogus"},
+				},
+			},
+			container: &api.Container{Name: "bar"},
+			expectErr: true,
+		},
+	}
+
+	for _, tc := range cases {
+		actual, err := dm.getSecurityOpts(tc.pod, tc.container)
+		if tc.expectErr {
+			if err == nil {
+				t.Errorf("%v: expected error", tc.name)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%v: unexpected error: %v", tc.name, err)
+			continue
+		}
+		if !api.Semantic.DeepEqual(tc.expected, actual) {
+			t.Errorf("%v: expected %v, got %v", tc.name, tc.expected, actual)
+		}
+	}
+}
+
+func TestTryUpdateContainerResources(t *testing.T) {
+	container := &api.Container{Name: "foo", Image: "ubuntu"}
+	fakeDocker := &FakeDockerClient{
+		Container: &docker.Container{
+			ID: "id",
+			Config: &docker.Config{
+				Labels: map[string]string{
+					kubernetesContainerHashExcludingResourcesLabel: strconv.FormatUint(HashContainerExcludingResources(container), 16),
+				},
+			},
+		},
+	}
+	manager := NewDockerManager(fakeDocker, &record.FakeRecorder{}, kubecontainer.NewReadinessManager(), kubecontainer.NewRefManager(), PodInfraContainerImage, 0, 0, "", "", 0)
+
+	// Only the resources differ: the limits are applied in place.
+	updated := *container
+	updated.Resources.Limits = api.ResourceList{
+		api.ResourceMemory: resource.MustParse("64Mi"),
+	}
+	if !manager.TryUpdateContainerResources("id", &updated) {
+		t.Errorf("expected in-place update when only resources changed")
+	}
+	if len(fakeDocker.Updated) != 1 || fakeDocker.Updated[0] != "id" {
+		t.Errorf("unexpected update calls: %v", fakeDocker.Updated)
+	}
+
+	// The same limits are not re-sent on the next sync.
+	if !manager.TryUpdateContainerResources("id", &updated) {
+		t.Errorf("expected already applied limits to be accepted")
+	}
+	if len(fakeDocker.Updated) != 1 {
+		t.Errorf("unexpected update calls: %v", fakeDocker.Updated)
+	}
+
+	// Any other change requires a restart.
+	changed := updated
+	changed.Image = "ubuntu:new"
+	if manager.TryUpdateContainerResources("id", &changed) {
+		t.Errorf("expected no in-place update when the image changed")
+	}
+}
+
+// optionsGeneratorStub returns empty run options for any container.
+type optionsGeneratorStub struct{}
+
+func (optionsGeneratorStub) GenerateRunContainerOptions(pod *api.Pod, container *api.Container, podIP, netMode, ipcMode, pidMode string) (*kubecontainer.RunContainerOptions, error) {
+	return &kubecontainer.RunContainerOptions{}, nil
+}
+
+func TestRunContainerRetriesAfterStartFailure(t *testing.T) {
+	fakeDocker := &FakeDockerClient{
+		Errors: map[string]error{
+			"start": fmt.Errorf("transient start failure"),
+		},
+	}
+	manager := NewDockerManager(fakeDocker, &record.FakeRecorder{}, kubecontainer.NewReadinessManager(), kubecontainer.NewRefManager(), PodInfraContainerImage, 0, 0, "", "", 0)
+	pod := &api.Pod{
+		ObjectMeta: api.ObjectMeta{
+			UID:       "12345678",
+			Name:      "foo",
+			Namespace: "new",
+		},
+		Spec: api.PodSpec{
+			Containers: []api.Container{
+				{Name: "bar"},
+			},
+		},
+	}
+
+	id, err := manager.RunContainer(pod, &pod.Spec.Containers[0], optionsGeneratorStub{}, nil, "", "", "", "")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if id == "" {
+		t.Errorf("expected a container id from the retried attempt")
+	}
+	// The container created by the failed attempt must have been removed
+	// before the retry.
+	if len(fakeDocker.Created) != 2 {
+		t.Errorf("unexpected containers created: %v", fakeDocker.Created)
+	}
+	if len(fakeDocker.Removed) != 1 {
+		t.Errorf("unexpected containers removed: %v", fakeDocker.Removed)
+	}
+}
+
+func TestGetPodStatusRemovesStuckContainers(t *testing.T) {
+	fakeDocker := &FakeDockerClient{}
+	manager := NewDockerManager(fakeDocker, &record.FakeRecorder{}, kubecontainer.NewReadinessManager(), kubecontainer.NewRefManager(), PodInfraContainerImage, 0, 0, "", "", 0)
+	pod := &api.Pod{
+		ObjectMeta: api.ObjectMeta{
+			UID:       "12345678",
+			Name:      "foo",
+			Namespace: "new",
+		},
+		Spec: api.PodSpec{
+			Containers: []api.Container{
+				{Name: "bar"},
+				{Name: "baz"},
+			},
+		},
+	}
+	fakeDocker.ExitedContainerList = []docker.APIContainers{
+		{
+			Names: []string{"/k8s_bar_foo_new_12345678_0"},
+			ID:    "created-id",
+		},
+		{
+			Names: []string{"/k8s_baz_foo_new_12345678_0"},
+			ID:    "dead-id",
+		},
+	}
+	fakeDocker.ContainerMap = map[string]*docker.Container{
+		// Created long ago but never started.
+		"created-id": {
+			ID:      "created-id",
+			Created: time.Now().Add(-2 * stuckContainerGracePeriod),
+			Config:  &docker.Config{},
+		},
+		// Docker failed to remove this one.
+		"dead-id": {
+			ID:      "dead-id",
+			Created: time.Now().Add(-2 * stuckContainerGracePeriod),
+			Config:  &docker.Config{},
+			State: docker.State{
+				Dead:       true,
+				ExitCode:   1,
+				FinishedAt: time.Now().Add(-stuckContainerGracePeriod),
+			},
+		},
+	}
+
+	status, err := manager.GetPodStatus(pod)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	expectedToRemove := map[string]bool{"created-id": true, "dead-id": true}
+	if len(fakeDocker.Removed) != 2 || !expectedToRemove[fakeDocker.Removed[0]] || !expectedToRemove[fakeDocker.Removed[1]] {
+		t.Errorf("wrong containers were removed: %v", fakeDocker.Removed)
+	}
+	// Both containers fall back to the default waiting state, so they will
+	// be created anew.
+	for _, containerStatus := range status.ContainerStatuses {
+		if containerStatus.State.Waiting == nil {
+			t.Errorf("expected container %q to be waiting, got %+v", containerStatus.Name, containerStatus.State)
+		}
+	}
+}
+
+func TestPullThroughMirror(t *testing.T) {
+	fakeDocker := &FakeDockerClient{}
+	manager := NewDockerManager(fakeDocker, &record.FakeRecorder{}, kubecontainer.NewReadinessManager(), kubecontainer.NewRefManager(), PodInfraContainerImage, 0, 0, "", "", 0)
+	manager.RegistryRewrites = map[string]string{"docker.io": "mirror.example.com"}
+	puller := &FakeDockerPuller{}
+	manager.Puller = puller
+
+	if err := manager.Pull("ubuntu:precise", nil); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !reflect.DeepEqual(puller.ImagesPulled, []string{"mirror.example.com/ubuntu:precise"}) {
+		t.Errorf("unexpected pulls: %v", puller.ImagesPulled)
+	}
+	expectedTag := []string{"mirror.example.com/ubuntu:precise -> ubuntu:precise"}
+	if !reflect.DeepEqual(fakeDocker.Tagged, expectedTag) {
+		t.Errorf("expected %v, got %v", expectedTag, fakeDocker.Tagged)
+	}
+
+	// Images on unlisted registries are pulled directly, without tagging.
+	if err := manager.Pull("other.example.com/foobar", nil); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !reflect.DeepEqual(puller.ImagesPulled, []string{"mirror.example.com/ubuntu:precise", "other.example.com/foobar"}) {
+		t.Errorf("unexpected pulls: %v", puller.ImagesPulled)
+	}
+	if !reflect.DeepEqual(fakeDocker.Tagged, expectedTag) {
+		t.Errorf("expected %v, got %v", expectedTag, fakeDocker.Tagged)
+	}
+}
+
+func TestGetPodStatusReportsImageID(t *testing.T) {
+	fakeDocker := &FakeDockerClient{Image: &docker.Image{ID: "5111"}}
+	manager := NewDockerManager(fakeDocker, &record.FakeRecorder{}, kubecontainer.NewReadinessManager(), kubecontainer.NewRefManager(), PodInfraContainerImage, 0, 0, "", "", 0)
+	pod := &api.Pod{
+		ObjectMeta: api.ObjectMeta{
+			UID:       "12345678",
+			Name:      "foo",
+			Namespace: "new",
+		},
+		Spec: api.PodSpec{
+			Containers: []api.Container{
+				{Name: "bar", Image: "ubuntu"},
+			},
+		},
+	}
+
+	// The container has not been created yet, but its image is present; the
+	// status must already report which image would run.
+	status, err := manager.GetPodStatus(pod)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(status.ContainerStatuses) != 1 {
+		t.Fatalf("unexpected statuses: %+v", status.ContainerStatuses)
+	}
+	if e, a := DockerPrefix+"5111", status.ContainerStatuses[0].ImageID; e != a {
+		t.Errorf("expected image ID %q, got %q", e, a)
+	}
+}
+
+func TestRestartPolicyMutated(t *testing.T) {
+	fakeDocker := &FakeDockerClient{}
+	manager := NewDockerManager(fakeDocker, &record.FakeRecorder{}, kubecontainer.NewReadinessManager(), kubecontainer.NewRefManager(), PodInfraContainerImage, 0, 0, "", "", 0)
+	fakeDocker.ContainerMap = map[string]*docker.Container{
+		"unmutated-id": {
+			ID:         "unmutated-id",
+			Config:     &docker.Config{},
+			HostConfig: &docker.HostConfig{RestartPolicy: docker.NeverRestart()},
+		},
+		"mutated-id": {
+			ID:         "mutated-id",
+			Config:     &docker.Config{},
+			HostConfig: &docker.HostConfig{RestartPolicy: docker.AlwaysRestart()},
+		},
+		"no-host-config-id": {
+			ID:     "no-host-config-id",
+			Config: &docker.Config{},
+		},
+	}
+
+	if manager.RestartPolicyMutated("unmutated-id") {
+		t.Errorf("expected a %q policy not to count as mutated", "no")
+	}
+	if !manager.RestartPolicyMutated("mutated-id") {
+		t.Errorf("expected an %q policy to count as mutated", "always")
+	}
+	if manager.RestartPolicyMutated("no-host-config-id") {
+		t.Errorf("expected a missing host config not to count as mutated")
+	}
+}
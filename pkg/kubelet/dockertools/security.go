@@ -0,0 +1,122 @@
+/*
+Copyright 2015 Google Inc. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package dockertools
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/GoogleCloudPlatform/kubernetes/pkg/api"
+	"github.com/GoogleCloudPlatform/kubernetes/pkg/util"
+)
+
+const (
+	// AppArmorAnnotationPrefix is the prefix of the pod annotation that
+	// requests an AppArmor profile for a container; the annotation key is the
+	// prefix followed by the container name.
+	AppArmorAnnotationPrefix = "container.apparmor.security.kubernetes.io/"
+
+	// AppArmorProfileRuntimeDefault requests the container runtime's default
+	// AppArmor profile.
+	AppArmorProfileRuntimeDefault = "runtime/default"
+
+	// AppArmorProfileUnconfined requests that no AppArmor profile be applied.
+	AppArmorProfileUnconfined = "unconfined"
+
+	// AppArmorProfileNamePrefix is the prefix for profiles that are loaded on
+	// the node.
+	AppArmorProfileNamePrefix = "localhost/"
+
+	// The securityfs file listing the AppArmor profiles loaded in the kernel.
+	apparmorProfilesPath = "/sys/kernel/security/apparmor/profiles"
+)
+
+// GetAppArmorProfileName returns the AppArmor profile requested for the given
+// container through pod annotations, or the empty string if none is set.
+func GetAppArmorProfileName(pod *api.Pod, containerName string) string {
+	return pod.Annotations[AppArmorAnnotationPrefix+containerName]
+}
+
+// getSecurityOpts builds the docker SecurityOpt entries for a container from
+// its SELinuxOptions and the pod's AppArmor annotations. Requested localhost
+// AppArmor profiles are checked against the profiles loaded in the kernel so
+// that the container fails to start instead of running unconfined.
+func (dm *DockerManager) getSecurityOpts(pod *api.Pod, container *api.Container) ([]string, error) {
+	var securityOpts []string
+
+	if container.SecurityContext != nil && container.SecurityContext.SELinuxOptions != nil {
+		selinuxOpts := container.SecurityContext.SELinuxOptions
+		if selinuxOpts.User != "" {
+			securityOpts = append(securityOpts, "label:user:"+selinuxOpts.User)
+		}
+		if selinuxOpts.Role != "" {
+			securityOpts = append(securityOpts, "label:role:"+selinuxOpts.Role)
+		}
+		if selinuxOpts.Type != "" {
+			securityOpts = append(securityOpts, "label:type:"+selinuxOpts.Type)
+		}
+		if selinuxOpts.Level != "" {
+			securityOpts = append(securityOpts, "label:level:"+selinuxOpts.Level)
+		}
+	}
+
+	switch profile := GetAppArmorProfileName(pod, container.Name); {
+	case profile == "":
+		// No profile requested; docker applies its default.
+	case profile == AppArmorProfileRuntimeDefault:
+		// Docker applies its default profile without an explicit option.
+	case profile == AppArmorProfileUnconfined:
+		securityOpts = append(securityOpts, "apparmor:unconfined")
+	case strings.HasPrefix(profile, AppArmorProfileNamePrefix):
+		name := strings.TrimPrefix(profile, AppArmorProfileNamePrefix)
+		loaded, err := loadedAppArmorProfiles()
+		if err != nil {
+			return nil, fmt.Errorf("cannot verify AppArmor profile %q: %v", name, err)
+		}
+		if !loaded.Has(name) {
+			return nil, fmt.Errorf("AppArmor profile %q is not loaded on this node", name)
+		}
+		securityOpts = append(securityOpts, "apparmor:"+name)
+	default:
+		return nil, fmt.Errorf("invalid AppArmor profile name %q", profile)
+	}
+
+	return securityOpts, nil
+}
+
+// loadedAppArmorProfiles returns the names of the AppArmor profiles loaded in
+// the kernel, read from securityfs.
+func loadedAppArmorProfiles() (util.StringSet, error) {
+	profiles := util.NewStringSet()
+	file, err := os.Open(apparmorProfilesPath)
+	if err != nil {
+		return profiles, err
+	}
+	defer file.Close()
+
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		// Lines are of the form "name (mode)".
+		fields := strings.Fields(scanner.Text())
+		if len(fields) > 0 {
+			profiles.Insert(fields[0])
+		}
+	}
+	return profiles, scanner.Err()
+}
@@ -0,0 +1,222 @@
+/*
+Copyright 2015 Google Inc. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package dockertools
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/fsouza/go-dockerclient"
+)
+
+// Per-operation request timeouts. A hung docker daemon should surface as an
+// error to the caller instead of wedging the calling goroutine forever.
+const (
+	// Applied to operations without a more specific limit below.
+	defaultDockerTimeout = 2 * time.Minute
+	// Listing and inspecting are cheap; a daemon that cannot answer them
+	// quickly is effectively down.
+	listContainersTimeout   = 1 * time.Minute
+	inspectContainerTimeout = 1 * time.Minute
+	// Image pulls legitimately take a long time for large images.
+	pullImageTimeout = 10 * time.Minute
+)
+
+// operationTimeout is the error returned when a docker operation does not
+// complete within its timeout.
+type operationTimeout struct {
+	operation string
+	timeout   time.Duration
+}
+
+func (e *operationTimeout) Error() string {
+	return fmt.Sprintf("docker operation %q did not complete within %v", e.operation, e.timeout)
+}
+
+type timeoutDockerInterface struct {
+	client DockerInterface
+}
+
+// NewTimeoutDockerInterface creates a DockerInterface that bounds the
+// duration of every non-streaming request to the underlying client. The
+// vendored docker client has no cancellation support, so a timed out
+// request leaves its goroutine running until the daemon answers; the
+// wrapper only unblocks the caller.
+func NewTimeoutDockerInterface(dockerClient DockerInterface) DockerInterface {
+	return timeoutDockerInterface{
+		client: dockerClient,
+	}
+}
+
+func withTimeout(operation string, timeout time.Duration, fn func() error) error {
+	done := make(chan error, 1)
+	go func() { done <- fn() }()
+	select {
+	case err := <-done:
+		return err
+	case <-time.After(timeout):
+		return &operationTimeout{operation: operation, timeout: timeout}
+	}
+}
+
+func (in timeoutDockerInterface) ListContainers(options docker.ListContainersOptions) ([]docker.APIContainers, error) {
+	var out []docker.APIContainers
+	err := withTimeout("list_containers", listContainersTimeout, func() error {
+		var err error
+		out, err = in.client.ListContainers(options)
+		return err
+	})
+	return out, err
+}
+
+func (in timeoutDockerInterface) InspectContainer(id string) (*docker.Container, error) {
+	var out *docker.Container
+	err := withTimeout("inspect_container", inspectContainerTimeout, func() error {
+		var err error
+		out, err = in.client.InspectContainer(id)
+		return err
+	})
+	return out, err
+}
+
+func (in timeoutDockerInterface) CreateContainer(opts docker.CreateContainerOptions) (*docker.Container, error) {
+	var out *docker.Container
+	err := withTimeout("create_container", defaultDockerTimeout, func() error {
+		var err error
+		out, err = in.client.CreateContainer(opts)
+		return err
+	})
+	return out, err
+}
+
+func (in timeoutDockerInterface) StartContainer(id string, hostConfig *docker.HostConfig) error {
+	return withTimeout("start_container", defaultDockerTimeout, func() error {
+		return in.client.StartContainer(id, hostConfig)
+	})
+}
+
+func (in timeoutDockerInterface) StopContainer(id string, timeout uint) error {
+	// Allow for the grace period the daemon itself waits before killing.
+	limit := time.Duration(timeout)*time.Second + defaultDockerTimeout
+	return withTimeout("stop_container", limit, func() error {
+		return in.client.StopContainer(id, timeout)
+	})
+}
+
+func (in timeoutDockerInterface) UpdateContainer(id string, opts docker.UpdateContainerOptions) error {
+	return withTimeout("update_container", defaultDockerTimeout, func() error {
+		return in.client.UpdateContainer(id, opts)
+	})
+}
+
+// WaitContainer blocks until the container exits, which can legitimately
+// take arbitrarily long; it cannot be bounded.
+func (in timeoutDockerInterface) WaitContainer(id string) (int, error) {
+	return in.client.WaitContainer(id)
+}
+
+func (in timeoutDockerInterface) RemoveContainer(opts docker.RemoveContainerOptions) error {
+	return withTimeout("remove_container", defaultDockerTimeout, func() error {
+		return in.client.RemoveContainer(opts)
+	})
+}
+
+func (in timeoutDockerInterface) InspectImage(image string) (*docker.Image, error) {
+	var out *docker.Image
+	err := withTimeout("inspect_image", inspectContainerTimeout, func() error {
+		var err error
+		out, err = in.client.InspectImage(image)
+		return err
+	})
+	return out, err
+}
+
+func (in timeoutDockerInterface) ListImages(opts docker.ListImagesOptions) ([]docker.APIImages, error) {
+	var out []docker.APIImages
+	err := withTimeout("list_images", listContainersTimeout, func() error {
+		var err error
+		out, err = in.client.ListImages(opts)
+		return err
+	})
+	return out, err
+}
+
+func (in timeoutDockerInterface) PullImage(opts docker.PullImageOptions, auth docker.AuthConfiguration) error {
+	return withTimeout("pull_image", pullImageTimeout, func() error {
+		return in.client.PullImage(opts, auth)
+	})
+}
+
+func (in timeoutDockerInterface) TagImage(name string, opts docker.TagImageOptions) error {
+	return withTimeout("tag_image", defaultDockerTimeout, func() error {
+		return in.client.TagImage(name, opts)
+	})
+}
+
+func (in timeoutDockerInterface) RemoveImage(image string) error {
+	return withTimeout("remove_image", defaultDockerTimeout, func() error {
+		return in.client.RemoveImage(image)
+	})
+}
+
+// Logs streams until the caller closes the output or the container exits;
+// it cannot be bounded by a request timeout.
+func (in timeoutDockerInterface) Logs(opts docker.LogsOptions) error {
+	return in.client.Logs(opts)
+}
+
+func (in timeoutDockerInterface) Version() (*docker.Env, error) {
+	var out *docker.Env
+	err := withTimeout("version", inspectContainerTimeout, func() error {
+		var err error
+		out, err = in.client.Version()
+		return err
+	})
+	return out, err
+}
+
+func (in timeoutDockerInterface) Info() (*docker.Env, error) {
+	var out *docker.Env
+	err := withTimeout("info", inspectContainerTimeout, func() error {
+		var err error
+		out, err = in.client.Info()
+		return err
+	})
+	return out, err
+}
+
+func (in timeoutDockerInterface) CreateExec(opts docker.CreateExecOptions) (*docker.Exec, error) {
+	var out *docker.Exec
+	err := withTimeout("create_exec", defaultDockerTimeout, func() error {
+		var err error
+		out, err = in.client.CreateExec(opts)
+		return err
+	})
+	return out, err
+}
+
+// StartExec runs until the exec'd process exits and may stream its output;
+// it cannot be bounded by a request timeout.
+func (in timeoutDockerInterface) StartExec(startExec string, opts docker.StartExecOptions) error {
+	return in.client.StartExec(startExec, opts)
+}
+
+// AttachToContainer streams until the container exits; it cannot be bounded
+// by a request timeout.
+func (in timeoutDockerInterface) AttachToContainer(opts docker.AttachToContainerOptions) error {
+	return in.client.AttachToContainer(opts)
+}
@@ -28,18 +28,24 @@ import (
 	"github.com/golang/glog"
 )
 
+// containerEnvFunc returns the expanded environment of a container as
+// docker-style KEY=VALUE strings.
+type containerEnvFunc func(pod *api.Pod, container *api.Container) ([]string, error)
+
 type handlerRunner struct {
 	httpGetter       httpGetter
 	commandRunner    dockertools.ContainerCommandRunner
 	containerManager *dockertools.DockerManager
+	containerEnv     containerEnvFunc
 }
 
 // TODO(yifan): Merge commandRunner and containerManager once containerManager implements the ContainerCommandRunner interface.
-func newHandlerRunner(httpGetter httpGetter, commandRunner dockertools.ContainerCommandRunner, containerManager *dockertools.DockerManager) kubecontainer.HandlerRunner {
+func newHandlerRunner(httpGetter httpGetter, commandRunner dockertools.ContainerCommandRunner, containerManager *dockertools.DockerManager, containerEnv containerEnvFunc) kubecontainer.HandlerRunner {
 	return &handlerRunner{
 		httpGetter:       httpGetter,
 		commandRunner:    commandRunner,
 		containerManager: containerManager,
+		containerEnv:     containerEnv,
 	}
 }
 
@@ -47,7 +53,11 @@ func newHandlerRunner(httpGetter httpGetter, commandRunner dockertools.Container
 func (hr *handlerRunner) Run(containerID string, pod *api.Pod, container *api.Container, handler *api.Handler) error {
 	switch {
 	case handler.Exec != nil:
-		_, err := hr.commandRunner.RunInContainer(containerID, handler.Exec.Command)
+		cmd, err := hr.execHandlerCommand(pod, container, handler.Exec)
+		if err != nil {
+			return err
+		}
+		_, err = hr.commandRunner.RunInContainer(containerID, cmd)
 		return err
 	case handler.HTTPGet != nil:
 		return hr.runHTTPHandler(pod, container, handler)
@@ -58,6 +68,33 @@ func (hr *handlerRunner) Run(containerID string, pod *api.Pod, container *api.Co
 	}
 }
 
+// execHandlerCommand builds the command actually exec'd for an exec handler.
+// Docker's exec API can set neither environment variables nor a working
+// directory, so the container's expanded environment and the handler's own
+// env are spliced in with env(1), and the working directory is entered by a
+// small shell wrapper.
+func (hr *handlerRunner) execHandlerCommand(pod *api.Pod, container *api.Container, action *api.ExecAction) ([]string, error) {
+	var env []string
+	if hr.containerEnv != nil {
+		containerEnv, err := hr.containerEnv(pod, container)
+		if err != nil {
+			return nil, fmt.Errorf("failed to expand environment of container %q: %v", container.Name, err)
+		}
+		env = append(env, containerEnv...)
+	}
+	for _, envVar := range action.Env {
+		env = append(env, fmt.Sprintf("%s=%s", envVar.Name, envVar.Value))
+	}
+	cmd := action.Command
+	if len(env) > 0 {
+		cmd = append(append([]string{"/usr/bin/env"}, env...), cmd...)
+	}
+	if action.WorkingDir != "" {
+		cmd = append([]string{"/bin/sh", "-c", `cd "$0" && exec "$@"`, action.WorkingDir}, cmd...)
+	}
+	return cmd, nil
+}
+
 // resolvePort attempts to turn a IntOrString port reference into a concrete port number.
 // If portReference has an int value, it is treated as a literal, and simply returns that value.
 // If portReference is a string, an attempt is first made to parse it as an integer.  If that fails,
@@ -39,6 +39,10 @@ type imageManager interface {
 	// enough space as per the garbage collection policy.
 	GarbageCollect() error
 
+	// UpdatePolicy replaces the fields of the garbage collection policy for
+	// which a new value is given. Safe to call while the manager is running.
+	UpdatePolicy(highThresholdPercent, lowThresholdPercent *int) error
+
 	// TODO(vmarmol): Have this subsume pulls as well.
 }
 
@@ -58,12 +62,18 @@ type realImageManager struct {
 	// Connection to the Docker daemon.
 	dockerClient dockertools.DockerInterface
 
+	// Invalidates cached image presence checks after garbage collection.
+	// May be nil.
+	containerManager *dockertools.DockerManager
+
 	// Records of images and their use.
 	imageRecords     map[string]*imageRecord
 	imageRecordsLock sync.Mutex
 
-	// The image garbage collection policy in use.
-	policy ImageGCPolicy
+	// The image garbage collection policy in use. Guarded by policyLock so
+	// it can be updated while the manager is running.
+	policy     ImageGCPolicy
+	policyLock sync.RWMutex
 
 	// cAdvisor instance.
 	cadvisor cadvisor.Interface
@@ -73,6 +83,10 @@ type realImageManager struct {
 
 	// Reference to this node.
 	nodeRef *api.ObjectReference
+
+	// The pod infra container image; never garbage collected since every
+	// pod depends on it.
+	podInfraContainerImage string
 }
 
 // Information about the images we track.
@@ -87,21 +101,29 @@ type imageRecord struct {
 	size int64
 }
 
-func newImageManager(dockerClient dockertools.DockerInterface, cadvisorInterface cadvisor.Interface, recorder record.EventRecorder, nodeRef *api.ObjectReference, policy ImageGCPolicy) (imageManager, error) {
-	// Validate policy.
+func validateImageGCPolicy(policy ImageGCPolicy) error {
 	if policy.HighThresholdPercent < 0 || policy.HighThresholdPercent > 100 {
-		return nil, fmt.Errorf("invalid HighThresholdPercent %d, must be in range [0-100]", policy.HighThresholdPercent)
+		return fmt.Errorf("invalid HighThresholdPercent %d, must be in range [0-100]", policy.HighThresholdPercent)
 	}
 	if policy.LowThresholdPercent < 0 || policy.LowThresholdPercent > 100 {
-		return nil, fmt.Errorf("invalid LowThresholdPercent %d, must be in range [0-100]", policy.LowThresholdPercent)
+		return fmt.Errorf("invalid LowThresholdPercent %d, must be in range [0-100]", policy.LowThresholdPercent)
+	}
+	return nil
+}
+
+func newImageManager(dockerClient dockertools.DockerInterface, containerManager *dockertools.DockerManager, cadvisorInterface cadvisor.Interface, recorder record.EventRecorder, nodeRef *api.ObjectReference, policy ImageGCPolicy, podInfraContainerImage string) (imageManager, error) {
+	if err := validateImageGCPolicy(policy); err != nil {
+		return nil, err
 	}
 	im := &realImageManager{
-		dockerClient: dockerClient,
-		policy:       policy,
-		imageRecords: make(map[string]*imageRecord),
-		cadvisor:     cadvisorInterface,
-		recorder:     recorder,
-		nodeRef:      nodeRef,
+		dockerClient:           dockerClient,
+		containerManager:       containerManager,
+		policy:                 policy,
+		imageRecords:           make(map[string]*imageRecord),
+		cadvisor:               cadvisorInterface,
+		recorder:               recorder,
+		nodeRef:                nodeRef,
+		podInfraContainerImage: podInfraContainerImage,
 	}
 
 	err := im.start()
@@ -142,8 +164,10 @@ func (im *realImageManager) detectImages(detected time.Time) error {
 		return err
 	}
 
-	// Make a set of images in use by containers.
+	// Make a set of images in use by containers. The pod infra container
+	// image is always considered in use.
 	imagesInUse := util.NewStringSet()
+	imagesInUse.Insert(im.podInfraContainerImage)
 	for _, container := range containers {
 		imagesInUse.Insert(container.Image)
 	}
@@ -198,10 +222,11 @@ func (im *realImageManager) GarbageCollect() error {
 	}
 
 	// If over the max threshold, free enough to place us at the lower threshold.
+	policy := im.gcPolicy()
 	usagePercent := int(usage * 100 / capacity)
-	if usagePercent >= im.policy.HighThresholdPercent {
-		amountToFree := usage - (int64(im.policy.LowThresholdPercent) * capacity / 100)
-		glog.Infof("[ImageManager]: Disk usage on %q (%s) is at %d%% which is over the high threshold (%d%%). Trying to free %d bytes", fsInfo.Device, fsInfo.Mountpoint, usagePercent, im.policy.HighThresholdPercent, amountToFree)
+	if usagePercent >= policy.HighThresholdPercent {
+		amountToFree := usage - (int64(policy.LowThresholdPercent) * capacity / 100)
+		glog.Infof("[ImageManager]: Disk usage on %q (%s) is at %d%% which is over the high threshold (%d%%). Trying to free %d bytes", fsInfo.Device, fsInfo.Mountpoint, usagePercent, policy.HighThresholdPercent, amountToFree)
 		freed, err := im.freeSpace(amountToFree)
 		if err != nil {
 			return err
@@ -217,6 +242,33 @@ func (im *realImageManager) GarbageCollect() error {
 	return nil
 }
 
+// gcPolicy returns a copy of the current garbage collection policy.
+func (im *realImageManager) gcPolicy() ImageGCPolicy {
+	im.policyLock.RLock()
+	defer im.policyLock.RUnlock()
+	return im.policy
+}
+
+func (im *realImageManager) UpdatePolicy(highThresholdPercent, lowThresholdPercent *int) error {
+	im.policyLock.Lock()
+	defer im.policyLock.Unlock()
+	policy := im.policy
+	if highThresholdPercent != nil {
+		policy.HighThresholdPercent = *highThresholdPercent
+	}
+	if lowThresholdPercent != nil {
+		policy.LowThresholdPercent = *lowThresholdPercent
+	}
+	if err := validateImageGCPolicy(policy); err != nil {
+		return err
+	}
+	if policy != im.policy {
+		glog.Infof("[ImageManager]: Garbage collection policy updated to high threshold %d%%, low threshold %d%%", policy.HighThresholdPercent, policy.LowThresholdPercent)
+	}
+	im.policy = policy
+	return nil
+}
+
 // Tries to free bytesToFree worth of images on the disk.
 //
 // Returns the number of bytes free and an error if any occured. The number of
@@ -267,6 +319,11 @@ func (im *realImageManager) freeSpace(bytesToFree int64) (int64, error) {
 		}
 	}
 
+	// Anything removed may still be in the puller's presence cache.
+	if spaceFreed > 0 && im.containerManager != nil {
+		im.containerManager.ForgetImages()
+	}
+
 	return spaceFreed, lastErr
 }
 
@@ -371,3 +371,21 @@ func TestGarbageCollectNotEnoughFreed(t *testing.T) {
 
 	assert.NotNil(t, manager.GarbageCollect())
 }
+
+func TestUpdatePolicy(t *testing.T) {
+	policy := ImageGCPolicy{
+		HighThresholdPercent: 90,
+		LowThresholdPercent:  80,
+	}
+	manager, _, _ := newRealImageManager(policy)
+
+	// Update a single threshold, the other is left alone.
+	high := 95
+	assert.NoError(t, manager.UpdatePolicy(&high, nil))
+	assert.Equal(t, ImageGCPolicy{HighThresholdPercent: 95, LowThresholdPercent: 80}, manager.gcPolicy())
+
+	// An invalid threshold is rejected and nothing changes.
+	low := 120
+	assert.NotNil(t, manager.UpdatePolicy(nil, &low))
+	assert.Equal(t, ImageGCPolicy{HighThresholdPercent: 95, LowThresholdPercent: 80}, manager.gcPolicy())
+}
@@ -17,6 +17,8 @@ limitations under the License.
 package kubelet
 
 import (
+	"bytes"
+	"encoding/json"
 	"errors"
 	"fmt"
 	"io"
@@ -25,13 +27,17 @@ import (
 	"net/http"
 	"os"
 	"path"
+	"reflect"
 	"sort"
+	"strconv"
 	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/GoogleCloudPlatform/kubernetes/pkg/api"
-	"github.com/GoogleCloudPlatform/kubernetes/pkg/api/validation"
+	apierrors "github.com/GoogleCloudPlatform/kubernetes/pkg/api/errors"
+	"github.com/GoogleCloudPlatform/kubernetes/pkg/api/resource"
 	"github.com/GoogleCloudPlatform/kubernetes/pkg/client"
 	"github.com/GoogleCloudPlatform/kubernetes/pkg/client/cache"
 	"github.com/GoogleCloudPlatform/kubernetes/pkg/client/record"
@@ -46,10 +52,10 @@ import (
 	"github.com/GoogleCloudPlatform/kubernetes/pkg/labels"
 	"github.com/GoogleCloudPlatform/kubernetes/pkg/probe"
 	"github.com/GoogleCloudPlatform/kubernetes/pkg/runtime"
-	"github.com/GoogleCloudPlatform/kubernetes/pkg/scheduler"
 	"github.com/GoogleCloudPlatform/kubernetes/pkg/types"
 	"github.com/GoogleCloudPlatform/kubernetes/pkg/util"
 	utilErrors "github.com/GoogleCloudPlatform/kubernetes/pkg/util/errors"
+	"github.com/GoogleCloudPlatform/kubernetes/pkg/util/expansion"
 	"github.com/GoogleCloudPlatform/kubernetes/pkg/version"
 	"github.com/GoogleCloudPlatform/kubernetes/pkg/volume"
 	"github.com/GoogleCloudPlatform/kubernetes/pkg/watch"
@@ -66,6 +72,14 @@ const (
 	// Max amount of time to wait for the Docker daemon to come up.
 	maxWaitForDocker = 5 * time.Minute
 
+	// Directory in which symlinks to container log files are kept for
+	// cluster level logging agents.
+	containerLogsDir = "/var/log/containers"
+
+	// The oldest docker API version the kubelet can talk to. Nodes with an
+	// older daemon are kept NotReady so that no pods are scheduled on them.
+	minimumDockerAPIVersion = "1.15"
+
 	// Initial node status update frequency and incremental frequency, for faster cluster startup.
 	// The update frequency will be increameted linearly, until it reaches status_update_frequency.
 	initialNodeStatusUpdateFrequency = 100 * time.Millisecond
@@ -73,6 +87,77 @@ const (
 
 	// nodeStatusUpdateRetry specifies how many times kubelet retries when posting node status failed.
 	nodeStatusUpdateRetry = 5
+
+	// Initial and maximum delay between node status update retries. The delay
+	// doubles on every failed attempt; conflicts retry immediately since a
+	// fresh copy of the node is fetched each time.
+	nodeStatusUpdateRetryBackoffInitial = 100 * time.Millisecond
+	nodeStatusUpdateRetryBackoffMax     = 5 * time.Second
+
+	// The periodic node status updates are jittered by up to this factor of
+	// the period so that the kubelets in a cluster do not post status in
+	// lockstep.
+	nodeStatusUpdateJitterFactor = 0.5
+
+	// How many consecutive identical full node status reports are posted
+	// before further unchanged reports are skipped. Any change resets the
+	// count and is posted immediately.
+	nodeStatusIdenticalReportLimit = 3
+
+	// Maximum number of container images reported in the node status.
+	nodeStatusMaxImages = 25
+
+	// The ndots value written into per-pod resolv.conf files for pods with
+	// cluster-first DNS. It must be large enough that a name of the form
+	// <service>.<namespace>.svc.<clusterDomain> resolves through the search
+	// path.
+	ndotsDNSOption = 5
+
+	// Limits imposed by the libc resolver on the search line; entries beyond
+	// these are silently ignored by the resolver.
+	maxDNSSearchPaths     = 6
+	maxDNSSearchListChars = 256
+
+	// Max age of the runtime pod cache before a per-pod lookup forces a
+	// refresh; lookups for pods whose containers the kubelet just created or
+	// killed refresh immediately.
+	runtimeCacheMaxAge = 2 * time.Second
+
+	// Thresholds at which the kubelet reports resource pressure conditions.
+	// TODO: make these configurable once there is an eviction policy.
+	memoryPressureThresholdPercent = uint64(90) // percent of memory capacity in use
+	diskPressureThresholdPercent   = uint64(90) // percent of a filesystem in use
+	pidPressureThresholdPercent    = int64(90)  // percent of the kernel pid limit in use
+
+	// How often the housekeeping pass looks for pod directories left behind
+	// by pods that are no longer known nor running.
+	orphanedPodCleanupPeriod = 2 * time.Minute
+
+	// How long a sync requested through /debug/sync may take before the
+	// request fails.
+	podResyncTimeout = 1 * time.Minute
+
+	// How often cached pod statuses are regenerated off the sync critical
+	// path.
+	podStatusReconcilePeriod = 10 * time.Second
+
+	// How often cadvisor is re-queried for machine info, so that hot-added
+	// CPUs and memory eventually show up in the node's capacity.
+	machineInfoRefreshPeriod = 5 * time.Minute
+
+	// How long after all pod sources have reported in that running pods
+	// unknown to every source are left alone, so that a source which is
+	// slow to resync after a kubelet restart can adopt them before they
+	// are fenced.
+	podAdoptionGracePeriod = 2 * time.Minute
+
+	// How many times each pod teardown step is attempted before the rest
+	// of the teardown is left for the next sync pass.
+	teardownStepAttempts = 3
+
+	// How long the first retry of a failed teardown step waits; doubled
+	// before each subsequent retry.
+	teardownStepBackoff = 100 * time.Millisecond
 )
 
 var (
@@ -143,6 +228,9 @@ func NewMainKubelet(
 	resyncInterval time.Duration,
 	pullQPS float32,
 	pullBurst int,
+	imagePullTimeout time.Duration,
+	registryRewrites map[string]string,
+	alwaysPullImages bool,
 	containerGCPolicy ContainerGCPolicy,
 	sourcesReady SourcesReadyFn,
 	clusterDomain string,
@@ -156,15 +244,36 @@ func NewMainKubelet(
 	cadvisorInterface cadvisor.Interface,
 	imageGCPolicy ImageGCPolicy,
 	cloud cloudprovider.Interface,
+	nodeIP net.IP,
+	registerNode bool,
+	nodeLabels map[string]string,
+	nodeTaints []api.Taint,
+	registerSchedulable bool,
 	nodeStatusUpdateFrequency time.Duration,
+	nodeStatusFullUpdateFrequency time.Duration,
 	resourceContainer string,
-	osInterface OSInterface) (*Kubelet, error) {
+	osInterface OSInterface,
+	maxConcurrentPodSyncs int,
+	sharedPIDNamespace bool,
+	dockerExecHandler dockertools.ExecHandler,
+	postStartFailurePolicy dockertools.HookFailurePolicy,
+	containerLogMaxSize int64,
+	containerLogMaxFiles int,
+	enableServiceLinks bool,
+	allowedUnsafeSysctls []string,
+	podInfraShmSize int64,
+	maxPods int,
+	migrateDataDirs bool,
+	nodeProblemConfig string) (*Kubelet, error) {
 	if rootDirectory == "" {
 		return nil, fmt.Errorf("invalid root directory %q", rootDirectory)
 	}
 	if resyncInterval <= 0 {
 		return nil, fmt.Errorf("invalid sync frequency %d", resyncInterval)
 	}
+	// Bound the duration of every docker request before instrumenting so a
+	// hung daemon shows up as errors rather than unbounded latencies.
+	dockerClient = dockertools.NewTimeoutDockerInterface(dockerClient)
 	dockerClient = dockertools.NewInstrumentedDockerInterface(dockerClient)
 
 	// Wait for the Docker daemon to be up (with a timeout).
@@ -259,13 +368,14 @@ func NewMainKubelet(
 	if err != nil {
 		return nil, err
 	}
-	imageManager, err := newImageManager(dockerClient, cadvisorInterface, recorder, nodeRef, imageGCPolicy)
-	if err != nil {
-		return nil, fmt.Errorf("failed to initialize image manager: %v", err)
-	}
 	statusManager := newStatusManager(kubeClient)
 	readinessManager := kubecontainer.NewReadinessManager()
 	containerRefManager := kubecontainer.NewRefManager()
+	if _, err := os.Stat(containerLogsDir); err != nil {
+		if err := os.MkdirAll(containerLogsDir, 0755); err != nil {
+			glog.Errorf("Failed to create directory %q: %v", containerLogsDir, err)
+		}
+	}
 	containerManager := dockertools.NewDockerManager(
 		dockerClient,
 		recorder,
@@ -273,7 +383,50 @@ func NewMainKubelet(
 		containerRefManager,
 		podInfraContainerImage,
 		pullQPS,
-		pullBurst)
+		pullBurst,
+		dockerRoot,
+		containerLogsDir,
+		podInfraShmSize)
+	if dockerExecHandler != nil {
+		containerManager.ExecHandler = dockerExecHandler
+	}
+	containerManager.RegistryRewrites = registryRewrites
+	if postStartFailurePolicy != "" {
+		containerManager.PostStartFailurePolicy = postStartFailurePolicy
+	}
+	imageManager, err := newImageManager(dockerClient, containerManager, cadvisorInterface, recorder, nodeRef, imageGCPolicy, podInfraContainerImage)
+	if err != nil {
+		return nil, fmt.Errorf("failed to initialize image manager: %v", err)
+	}
+
+	if containerLogMaxSize > 0 {
+		if _, err := newContainerLogManager(dockerClient, dockerRoot, containerLogMaxSize, containerLogMaxFiles); err != nil {
+			return nil, fmt.Errorf("failed to initialize container log manager: %v", err)
+		}
+	} else {
+		glog.Infof("Container log rotation is disabled")
+	}
+
+	// Validate the docker API version once at startup so that a daemon that
+	// is too old is reported immediately; the node ready condition rechecks
+	// on every status update.
+	if version, err := containerManager.Version(); err != nil {
+		glog.Errorf("Failed to read the docker version: %v", err)
+	} else if result, err := version.Compare(minimumDockerAPIVersion); err != nil || result < 0 {
+		glog.Errorf("Unsupported docker API version %s; the kubelet requires at least %s and will report the node as not ready", version, minimumDockerAPIVersion)
+	} else {
+		glog.Infof("Using docker API version %s", version)
+	}
+
+	// Make sure the pod infra container image is available before any pods
+	// are synced; air-gapped nodes must preload it.
+	if present, err := containerManager.IsImagePresent(podInfraContainerImage); err != nil {
+		glog.Errorf("Failed to inspect the pod infra container image %q: %v", podInfraContainerImage, err)
+	} else if !present {
+		if err := containerManager.Pull(podInfraContainerImage, nil); err != nil {
+			glog.Errorf("Failed to pull the pod infra container image %q: %v; no pods can be started until it is available", podInfraContainerImage, err)
+		}
+	}
 
 	volumeManager := newVolumeManager()
 
@@ -283,6 +436,10 @@ func NewMainKubelet(
 		kubeClient:                     kubeClient,
 		rootDirectory:                  rootDirectory,
 		resyncInterval:                 resyncInterval,
+		imagePullTimeout:               imagePullTimeout,
+		alwaysPullImages:               alwaysPullImages,
+		adoptionGracePeriod:            podAdoptionGracePeriod,
+		adoptionCandidates:             make(map[types.UID]empty),
 		containerRefManager:            containerRefManager,
 		readinessManager:               readinessManager,
 		runner:                         containerManager,
@@ -301,30 +458,53 @@ func NewMainKubelet(
 		statusManager:                  statusManager,
 		volumeManager:                  volumeManager,
 		cloud:                          cloud,
+		nodeIP:                         nodeIP,
+		registerNode:                   registerNode,
+		nodeLabels:                     nodeLabels,
+		nodeTaints:                     nodeTaints,
+		registerSchedulable:            registerSchedulable,
 		nodeRef:                        nodeRef,
 		containerManager:               containerManager,
 		nodeStatusUpdateFrequency:      nodeStatusUpdateFrequency,
+		nodeStatusFullUpdateFrequency:  nodeStatusFullUpdateFrequency,
 		resourceContainer:              resourceContainer,
 		os:                             osInterface,
+		sharedPIDNamespace:             sharedPIDNamespace,
 		dockerRoot:                     dockerRoot,
+		enableServiceLinks:             enableServiceLinks,
+		allowedUnsafeSysctls:           allowedUnsafeSysctls,
+		maxPods:                        maxPods,
 	}
 
 	klet.podManager = newBasicPodManager(klet.kubeClient)
+	klet.podStatusCache = newPodStatusCache()
 	klet.prober = newProber(klet.runner, klet.readinessManager, klet.containerRefManager, klet.recorder)
-	klet.handlerRunner = newHandlerRunner(klet.httpClient, klet.runner, klet.containerManager)
+	klet.handlerRunner = newHandlerRunner(klet.httpClient, klet.runner, klet.containerManager, klet.makeEnvironmentVariables)
 
-	runtimeCache, err := kubecontainer.NewRuntimeCache(containerManager)
+	runtimeCache, err := kubecontainer.NewRuntimeCache(containerManager, runtimeCacheMaxAge)
 	if err != nil {
 		return nil, err
 	}
 	klet.runtimeCache = runtimeCache
-	klet.podWorkers = newPodWorkers(runtimeCache, klet.syncPod, recorder)
+	klet.podWorkers = newPodWorkers(runtimeCache, klet.syncPod, recorder, maxConcurrentPodSyncs)
+	klet.oomWatcher = newOOMWatcher(cadvisorInterface, recorder, klet.podForCgroupPath)
+	klet.statsProvider = newStatsProvider(cadvisorInterface, statsCacheTTL)
+	if klet.problemDetector, err = newProblemDetector(nodeProblemConfig, recorder, nodeRef); err != nil {
+		return nil, err
+	}
+	statusManager.canBeDeleted = klet.podResourcesAreReclaimed
 
 	metrics.Register(runtimeCache)
 
 	if err = klet.setupDataDirs(); err != nil {
 		return nil, err
 	}
+	klet.containerHistory = newContainerHistory(klet.rootDirectory)
+	if migrateDataDirs {
+		if err = klet.migrateOldDataDirs(); err != nil {
+			return nil, err
+		}
+	}
 	if err = klet.volumePluginMgr.InitPlugins(volumePlugins, &volumeHost{klet}); err != nil {
 		return nil, err
 	}
@@ -368,6 +548,29 @@ type Kubelet struct {
 	resyncInterval time.Duration
 	sourcesReady   SourcesReadyFn
 
+	// If non-zero, image pulls that have not finished within this long fail.
+	imagePullTimeout time.Duration
+
+	// If true, every container start pulls its image first regardless of
+	// the pod's pull policy, so a cached image can never be run by a pod
+	// that would not have been allowed to pull it.
+	alwaysPullImages bool
+
+	// When all pod sources first reported in. Anchors the grace window
+	// during which running pods unknown to every source are left alone.
+	sourcesReadyTime time.Time
+
+	// How long after sourcesReadyTime an unknown running pod may wait to
+	// be adopted by a source before it is fenced.
+	adoptionGracePeriod time.Duration
+
+	// Running pods that no source has claimed, keyed by pod UID. Left
+	// alive until the adoption grace window expires.
+	adoptionCandidates map[types.UID]empty
+
+	// Tracks the time of the most recent sync loop iteration, for health checking.
+	syncLoopMonitor atomic.Value
+
 	podManager podManager
 
 	// Needed to report events for containers belonging to deleted/modified pods.
@@ -422,18 +625,50 @@ type Kubelet struct {
 	// Manager for images.
 	imageManager imageManager
 
-	// Cached MachineInfo returned by cadvisor.
-	machineInfo *cadvisorApi.MachineInfo
+	// Watches for containers killed by the kernel OOM killer.
+	oomWatcher OOMWatcher
+
+	// Scans logs for node problems; nil when detection is disabled.
+	problemDetector *problemDetector
+
+	// Cached MachineInfo returned by cadvisor, refreshed periodically.
+	machineInfoLock sync.Mutex
+	machineInfo     *cadvisorApi.MachineInfo
 
 	// Syncs pods statuses with apiserver; also used as a cache of statuses.
 	statusManager *statusManager
 
+	// Holds the last generated status of each pod for syncPod to read, so
+	// status generation stays off the sync critical path.
+	podStatusCache *podStatusCache
+
+	// Persists restart counts and last termination details across container
+	// garbage collection and kubelet restarts.
+	containerHistory *containerHistory
+
+	// Serves cadvisor stats out of a short-lived cache, coalescing
+	// concurrent identical requests.
+	statsProvider *statsProvider
+
 	// Manager for the volume maps for the pods.
 	volumeManager *volumeManager
 
 	//Cloud provider interface
 	cloud cloudprovider.Interface
 
+	// If non-nil, use this IP address for the node instead of resolving
+	// the hostname or asking the cloud provider.
+	nodeIP net.IP
+
+	// Set to true to have the node register itself with the apiserver.
+	registerNode bool
+	// Labels to add to the node object when registering.
+	nodeLabels map[string]string
+	// Taints to add to the node object when registering.
+	nodeTaints []api.Taint
+	// Set to false to have the node register itself as unschedulable.
+	registerSchedulable bool
+
 	// Reference to this node.
 	nodeRef *api.ObjectReference
 
@@ -453,12 +688,43 @@ type Kubelet struct {
 	//    as it takes time to gather all necessary node information.
 	nodeStatusUpdateFrequency time.Duration
 
+	// nodeStatusFullUpdateFrequency specifies how often kubelet posts the
+	// full node status to master; between full updates only the readiness
+	// heartbeat is refreshed.
+	nodeStatusFullUpdateFrequency time.Duration
+
+	// The node status as of the last full report, with heartbeat and
+	// transition times cleared, and how many identical reports have been
+	// posted in a row. After nodeStatusIdenticalReportLimit identical
+	// reports the write is skipped until the status changes; the readiness
+	// heartbeat keeps proving liveness in the meantime.
+	lastNodeStatus             api.NodeStatus
+	identicalNodeStatusReports int
+
 	// The name of the resource-only container to run the Kubelet in (empty for no container).
 	// Name must be absolute.
 	resourceContainer string
 
 	os         OSInterface
 	dockerRoot string
+
+	// Whether to run all containers of a pod in the pod infra container's
+	// PID namespace, so that the pause process reaps orphaned zombies.
+	sharedPIDNamespace bool
+
+	// Whether to inject env vars describing the services in a pod's
+	// namespace, for pods that do not specify spec.enableServiceLinks.
+	// The master service env vars are injected regardless.
+	enableServiceLinks bool
+
+	// Unsafe sysctls that pods on this node may request, as configured by
+	// the operator. An entry ending in "*" allows the whole prefix, e.g.
+	// "net.*". Safe sysctls are always allowed.
+	allowedUnsafeSysctls []string
+
+	// Maximum number of pods this kubelet will admit; published as the
+	// node's pod capacity. Zero means no limit.
+	maxPods int
 }
 
 // getRootDir returns the full path to the directory under which kubelet can
@@ -492,24 +758,10 @@ func (kl *Kubelet) getPluginDir(pluginName string) string {
 
 // getPodDir returns the full path to the per-pod data directory for the
 // specified pod.  This directory may not exist if the pod does not exist.
+// Directories written by pre-"pods/" kubelets are moved into this layout by
+// migrateOldDataDirs at startup.
 func (kl *Kubelet) getPodDir(podUID types.UID) string {
-	// Backwards compat.  The "old" stuff should be removed before 1.0
-	// release.  The thinking here is this:
-	//     !old && !new = use new
-	//     !old && new  = use new
-	//     old && !new  = use old
-	//     old && new   = use new (but warn)
-	oldPath := path.Join(kl.getRootDir(), string(podUID))
-	oldExists := dirExists(oldPath)
-	newPath := path.Join(kl.getPodsDir(), string(podUID))
-	newExists := dirExists(newPath)
-	if oldExists && !newExists {
-		return oldPath
-	}
-	if oldExists {
-		glog.Warningf("Data dir for pod %q exists in both old and new form, using new", podUID)
-	}
-	return newPath
+	return path.Join(kl.getPodsDir(), string(podUID))
 }
 
 // getPodVolumesDir returns the full path to the per-pod data directory under
@@ -544,23 +796,74 @@ func (kl *Kubelet) getPodPluginDir(podUID types.UID, pluginName string) string {
 // which container data is held for the specified pod.  This directory may not
 // exist if the pod or container does not exist.
 func (kl *Kubelet) getPodContainerDir(podUID types.UID, ctrName string) string {
-	// Backwards compat.  The "old" stuff should be removed before 1.0
-	// release.  The thinking here is this:
-	//     !old && !new = use new
-	//     !old && new  = use new
-	//     old && !new  = use old
-	//     old && new   = use new (but warn)
-	oldPath := path.Join(kl.getPodDir(podUID), ctrName)
-	oldExists := dirExists(oldPath)
-	newPath := path.Join(kl.getPodDir(podUID), "containers", ctrName)
-	newExists := dirExists(newPath)
-	if oldExists && !newExists {
-		return oldPath
+	return path.Join(kl.getPodDir(podUID), "containers", ctrName)
+}
+
+// migrateOldDataDirs moves data directories written by pre-"pods/" kubelets
+// into the current layout: pod directories live under "pods/" and container
+// directories under each pod's "containers/" subdirectory. It runs once at
+// startup so the path getters do not have to consult the disk on every call.
+// A directory that exists in both forms is left in the old place, matching
+// the former behavior of preferring the new form.
+func (kl *Kubelet) migrateOldDataDirs() error {
+	errlist := []error{}
+	entries, err := ioutil.ReadDir(kl.getRootDir())
+	if err != nil {
+		return err
+	}
+	for _, entry := range entries {
+		name := entry.Name()
+		// "pki" holds the node's bootstrap credentials, not pod data; moving
+		// it under "pods/" would get it reaped as an orphaned pod.
+		if !entry.IsDir() || name == "pods" || name == "plugins" || name == "pki" {
+			continue
+		}
+		oldPath := path.Join(kl.getRootDir(), name)
+		newPath := path.Join(kl.getPodsDir(), name)
+		if dirExists(newPath) {
+			glog.Warningf("Data dir for pod %q exists in both old and new form, leaving the old one in place", name)
+			continue
+		}
+		glog.Infof("Migrating old pod data dir %q to %q", oldPath, newPath)
+		if err := os.Rename(oldPath, newPath); err != nil {
+			errlist = append(errlist, err)
+		}
+	}
+
+	// Old-style container directories live directly in the pod directory.
+	podUIDs, err := kl.listPodsFromDisk()
+	if err != nil {
+		return utilErrors.NewAggregate(append(errlist, err))
 	}
-	if oldExists {
-		glog.Warningf("Data dir for pod %q, container %q exists in both old and new form, using new", podUID, ctrName)
+	for _, uid := range podUIDs {
+		podDir := kl.getPodDir(uid)
+		entries, err := ioutil.ReadDir(podDir)
+		if err != nil {
+			errlist = append(errlist, err)
+			continue
+		}
+		for _, entry := range entries {
+			name := entry.Name()
+			if !entry.IsDir() || name == "volumes" || name == "plugins" || name == "containers" {
+				continue
+			}
+			oldPath := path.Join(podDir, name)
+			newPath := kl.getPodContainerDir(uid, name)
+			if dirExists(newPath) {
+				glog.Warningf("Data dir for pod %q, container %q exists in both old and new form, leaving the old one in place", uid, name)
+				continue
+			}
+			glog.Infof("Migrating old container data dir %q to %q", oldPath, newPath)
+			if err := os.MkdirAll(path.Join(podDir, "containers"), 0750); err != nil {
+				errlist = append(errlist, err)
+				continue
+			}
+			if err := os.Rename(oldPath, newPath); err != nil {
+				errlist = append(errlist, err)
+			}
+		}
 	}
-	return newPath
+	return utilErrors.NewAggregate(errlist)
 }
 
 func dirExists(path string) bool {
@@ -629,6 +932,33 @@ func (kl *Kubelet) StartGarbageCollection() {
 	}, 5*time.Minute)
 }
 
+// UpdateImageGCPolicy updates the image garbage collection thresholds while
+// the kubelet is running; a nil threshold is left unchanged.
+func (kl *Kubelet) UpdateImageGCPolicy(highThresholdPercent, lowThresholdPercent *int) error {
+	return kl.imageManager.UpdatePolicy(highThresholdPercent, lowThresholdPercent)
+}
+
+// podForCgroupPath maps the cgroup path reported for an OOM killed
+// container back to the pod it belongs to. Docker names a container's
+// cgroup after its ID, so the last path segment is matched against the
+// containers the runtime knows about.
+func (kl *Kubelet) podForCgroupPath(cgroupPath string) (*api.Pod, bool) {
+	dockerID := path.Base(cgroupPath)
+	pods, err := kl.runtimeCache.GetPods()
+	if err != nil {
+		glog.Errorf("Error listing containers while resolving cgroup %q: %v", cgroupPath, err)
+		return nil, false
+	}
+	for _, pod := range pods {
+		for _, container := range pod.Containers {
+			if string(container.ID) == dockerID {
+				return kl.podManager.GetPodByName(pod.Namespace, pod.Name)
+			}
+		}
+	}
+	return nil, false
+}
+
 // Run starts the kubelet reacting to config updates
 func (kl *Kubelet) Run(updates <-chan PodUpdate) {
 	if kl.logServer == nil {
@@ -647,8 +977,19 @@ func (kl *Kubelet) Run(updates <-chan PodUpdate) {
 		glog.Infof("Running in container %q", kl.resourceContainer)
 	}
 
+	if err := kl.oomWatcher.Start(kl.nodeRef); err != nil {
+		glog.Errorf("Failed to start OOM watcher: %v", err)
+	}
+	if kl.problemDetector != nil {
+		kl.problemDetector.Start()
+	}
+
 	go kl.syncNodeStatus()
 	kl.statusManager.Start()
+	go util.Forever(kl.podWorkers.retryWork, podWorkerRetryPollInterval)
+	go util.Forever(kl.cleanupOrphanedPods, orphanedPodCleanupPeriod)
+	go util.Forever(kl.reconcilePodStatuses, podStatusReconcilePeriod)
+	go util.Forever(kl.refreshMachineInfo, machineInfoRefreshPeriod)
 	kl.syncLoop(updates, kl)
 }
 
@@ -657,6 +998,9 @@ func (kl *Kubelet) syncNodeStatus() {
 	if kl.kubeClient == nil {
 		return
 	}
+	if kl.registerNode {
+		kl.registerWithApiserver()
+	}
 	glog.Infof("Starting node status updates")
 	for feq := initialNodeStatusUpdateFrequency; feq < kl.nodeStatusUpdateFrequency; feq += nodeStatusUpdateFrequencyInc {
 		select {
@@ -666,11 +1010,20 @@ func (kl *Kubelet) syncNodeStatus() {
 			}
 		}
 	}
+	lastFullUpdate := time.Now()
 	for {
 		select {
-		case <-time.After(kl.nodeStatusUpdateFrequency):
-			if err := kl.updateNodeStatus(); err != nil {
-				glog.Errorf("Unable to update node status: %v", err)
+		case <-time.After(util.Jitter(kl.nodeStatusUpdateFrequency, nodeStatusUpdateJitterFactor)):
+			// Full status updates are heavy at scale; between them the
+			// kubelet only proves its liveness with a cheap heartbeat.
+			if time.Since(lastFullUpdate) >= kl.nodeStatusFullUpdateFrequency {
+				if err := kl.updateNodeStatus(); err != nil {
+					glog.Errorf("Unable to update node status: %v", err)
+				} else {
+					lastFullUpdate = time.Now()
+				}
+			} else if err := kl.updateNodeHeartbeat(); err != nil {
+				glog.Errorf("Unable to update node heartbeat: %v", err)
 			}
 		}
 	}
@@ -694,11 +1047,12 @@ func makeBinds(container *api.Container, podVolumes volumeMap) (binds []string)
 
 // GenerateRunContainerOptions generates the RunContainerOptions, which can be used by
 // the container runtime to set parameters for launching a container.
-func (kl *Kubelet) GenerateRunContainerOptions(pod *api.Pod, container *api.Container, netMode, ipcMode string) (*kubecontainer.RunContainerOptions, error) {
+func (kl *Kubelet) GenerateRunContainerOptions(pod *api.Pod, container *api.Container, podIP, netMode, ipcMode, pidMode string) (*kubecontainer.RunContainerOptions, error) {
 	var err error
 	opts := &kubecontainer.RunContainerOptions{
 		NetMode: netMode,
 		IpcMode: ipcMode,
+		PidMode: pidMode,
 	}
 
 	vol, ok := kl.volumeManager.GetVolumes(pod.UID)
@@ -706,7 +1060,7 @@ func (kl *Kubelet) GenerateRunContainerOptions(pod *api.Pod, container *api.Cont
 		return nil, fmt.Errorf("impossible: cannot find the mounted volumes for pod %q", kubecontainer.GetPodFullName(pod))
 	}
 	opts.Binds = makeBinds(container, vol)
-	opts.Envs, err = kl.makeEnvironmentVariables(pod.Namespace, container)
+	opts.Envs, err = kl.makeEnvironmentVariables(pod, container)
 	if err != nil {
 		return nil, err
 	}
@@ -719,19 +1073,57 @@ func (kl *Kubelet) GenerateRunContainerOptions(pod *api.Pod, container *api.Cont
 			opts.PodContainerDir = p
 		}
 	}
-	if pod.Spec.DNSPolicy == api.DNSClusterFirst {
-		opts.DNS, opts.DNSSearch, err = kl.getClusterDNS(pod)
+	// The pod IP is only known once the infra container is running; without
+	// it, e.g. for host network pods, the container keeps the hosts file of
+	// its image.
+	if len(podIP) > 0 {
+		opts.HostsFilePath, err = kl.makePodHosts(pod, podIP)
 		if err != nil {
 			return nil, err
 		}
 	}
+	if pod.Spec.DNSPolicy != api.DNSDefault {
+		var dnsOptions []string
+		if pod.Spec.DNSPolicy == api.DNSClusterFirst {
+			opts.DNS, opts.DNSSearch, err = kl.getClusterDNS(pod)
+			if err != nil {
+				return nil, err
+			}
+			if kl.clusterDNS != nil {
+				dnsOptions = append(dnsOptions, fmt.Sprintf("ndots:%d", ndotsDNSOption))
+			}
+		}
+		// Merge the pod's dnsConfig; with DNSNone it is the entire resolver
+		// configuration.
+		if dnsConfig := pod.Spec.DNSConfig; dnsConfig != nil {
+			opts.DNS = append(opts.DNS, dnsConfig.Nameservers...)
+			opts.DNSSearch = append(opts.DNSSearch, dnsConfig.Searches...)
+			for _, option := range dnsConfig.Options {
+				if option.Value != nil && len(*option.Value) > 0 {
+					dnsOptions = append(dnsOptions, fmt.Sprintf("%s:%s", option.Name, *option.Value))
+				} else {
+					dnsOptions = append(dnsOptions, option.Name)
+				}
+			}
+		}
+		opts.DNSSearch = kl.truncateDNSSearchPaths(pod, opts.DNSSearch)
+		// Only pin the resolver configuration when there is something the
+		// docker API cannot express; otherwise the container keeps docker's
+		// view of the host's resolv.conf.
+		if pod.Spec.DNSPolicy == api.DNSNone || kl.clusterDNS != nil || pod.Spec.DNSConfig != nil {
+			opts.ResolvConfPath, err = kl.makePodResolvConf(pod, opts.DNS, opts.DNSSearch, dnsOptions)
+			if err != nil {
+				return nil, err
+			}
+		}
+	}
 	return opts, nil
 }
 
 var masterServices = util.NewStringSet("kubernetes", "kubernetes-ro")
 
 // getServiceEnvVarMap makes a map[string]string of env vars for services a pod in namespace ns should see
-func (kl *Kubelet) getServiceEnvVarMap(ns string) (map[string]string, error) {
+func (kl *Kubelet) getServiceEnvVarMap(ns string, enableServiceLinks bool) (map[string]string, error) {
 	var (
 		serviceMap = make(map[string]api.Service)
 		m          = make(map[string]string)
@@ -756,19 +1148,15 @@ func (kl *Kubelet) getServiceEnvVarMap(ns string) (map[string]string, error) {
 		}
 		serviceName := service.Name
 
-		switch service.Namespace {
-		// for the case whether the master service namespace is the namespace the pod
-		// is in, the pod should receive all the services in the namespace.
-		//
-		// ordering of the case clauses below enforces this
-		case ns:
+		// The master service variables are always injected; the other
+		// services in the pod's namespace only when service links are
+		// enabled. Services in the pod's namespace take precedence over
+		// master services of the same name.
+		if service.Namespace == ns && enableServiceLinks {
 			serviceMap[serviceName] = service
-		case kl.masterServiceNamespace:
-			if masterServices.Has(serviceName) {
-				_, exists := serviceMap[serviceName]
-				if !exists {
-					serviceMap[serviceName] = service
-				}
+		} else if service.Namespace == kl.masterServiceNamespace && masterServices.Has(serviceName) {
+			if _, exists := serviceMap[serviceName]; !exists {
+				serviceMap[serviceName] = service
 			}
 		}
 	}
@@ -784,7 +1172,7 @@ func (kl *Kubelet) getServiceEnvVarMap(ns string) (map[string]string, error) {
 }
 
 // Make the service environment variables for a pod in the given namespace.
-func (kl *Kubelet) makeEnvironmentVariables(ns string, container *api.Container) ([]string, error) {
+func (kl *Kubelet) makeEnvironmentVariables(pod *api.Pod, container *api.Container) ([]string, error) {
 	var result []string
 	// Note:  These are added to the docker.Config, but are not included in the checksum computed
 	// by dockertools.BuildDockerName(...).  That way, we can still determine whether an
@@ -795,11 +1183,20 @@ func (kl *Kubelet) makeEnvironmentVariables(ns string, container *api.Container)
 	// To avoid this users can: (1) wait between starting a service and starting; or (2) detect
 	// missing service env var and exit and be restarted; or (3) use DNS instead of env vars
 	// and keep trying to resolve the DNS name of the service (recommended).
-	serviceEnv, err := kl.getServiceEnvVarMap(ns)
+	enableServiceLinks := kl.enableServiceLinks
+	if pod.Spec.EnableServiceLinks != nil {
+		enableServiceLinks = *pod.Spec.EnableServiceLinks
+	}
+	serviceEnv, err := kl.getServiceEnvVarMap(pod.Namespace, enableServiceLinks)
 	if err != nil {
 		return result, err
 	}
 
+	// Env vars declared earlier in the container can be referenced with the
+	// $(VAR) syntax by those declared later, as can the service env vars.
+	tmpEnv := make(map[string]string)
+	mappingFunc := expansion.MappingFuncFor(tmpEnv, serviceEnv)
+
 	for _, value := range container.Env {
 		// Accesses apiserver+Pods.
 		// So, the master may set service env vars, or kubelet may.  In case both are doing
@@ -807,7 +1204,24 @@ func (kl *Kubelet) makeEnvironmentVariables(ns string, container *api.Container)
 		// env vars.
 		// TODO: remove this net line once all platforms use apiserver+Pods.
 		delete(serviceEnv, value.Name)
-		result = append(result, fmt.Sprintf("%s=%s", value.Name, value.Value))
+
+		runtimeValue := value.Value
+		switch {
+		case value.ValueFrom != nil && value.ValueFrom.SecretKeyRef != nil:
+			runtimeValue, err = kl.secretKeyValue(pod, value.ValueFrom.SecretKeyRef)
+			if err != nil {
+				return result, err
+			}
+		case value.ValueFrom != nil && value.ValueFrom.ResourceFieldRef != nil:
+			runtimeValue, err = containerResourceRuntimeValue(value.ValueFrom.ResourceFieldRef, pod, container)
+			if err != nil {
+				return result, err
+			}
+		default:
+			runtimeValue = expansion.Expand(runtimeValue, mappingFunc)
+		}
+		tmpEnv[value.Name] = runtimeValue
+		result = append(result, fmt.Sprintf("%s=%s", value.Name, runtimeValue))
 	}
 
 	// Append remaining service env vars.
@@ -817,6 +1231,42 @@ func (kl *Kubelet) makeEnvironmentVariables(ns string, container *api.Container)
 	return result, nil
 }
 
+// Resolves the value of a resourceFieldRef env var source against the
+// resources of the named container, defaulting to the container declaring
+// the env var.
+func containerResourceRuntimeValue(fs *api.ResourceFieldSelector, pod *api.Pod, container *api.Container) (string, error) {
+	containerName := fs.ContainerName
+	if len(containerName) == 0 {
+		return api.ExtractContainerResourceValue(fs, container)
+	}
+	for i := range pod.Spec.Containers {
+		if pod.Spec.Containers[i].Name == containerName {
+			return api.ExtractContainerResourceValue(fs, &pod.Spec.Containers[i])
+		}
+	}
+	return "", fmt.Errorf("container %q not found in pod %q", containerName, kubecontainer.GetPodFullName(pod))
+}
+
+// Resolves the value of a secretKeyRef env var source by fetching the secret
+// from the apiserver. Failures are surfaced as pod events so that a bad
+// reference is visible without reading the kubelet's logs.
+func (kl *Kubelet) secretKeyValue(pod *api.Pod, selector *api.SecretKeySelector) (string, error) {
+	if kl.kubeClient == nil {
+		return "", fmt.Errorf("unable to fetch secret %q: no api server client", selector.Name)
+	}
+	secret, err := kl.kubeClient.Secrets(pod.Namespace).Get(selector.Name)
+	if err != nil {
+		kl.recorder.Eventf(pod, "missingSecret", "Cannot fetch secret %q for environment variables: %v", selector.Name, err)
+		return "", fmt.Errorf("unable to fetch secret %q: %v", selector.Name, err)
+	}
+	data, ok := secret.Data[selector.Key]
+	if !ok {
+		kl.recorder.Eventf(pod, "missingSecretKey", "Secret %q has no key %q for environment variables.", selector.Name, selector.Key)
+		return "", fmt.Errorf("secret %q has no key %q", selector.Name, selector.Key)
+	}
+	return string(data), nil
+}
+
 // getClusterDNS returns a list of the DNS servers and a list of the DNS search
 // domains of the cluster.
 func (kl *Kubelet) getClusterDNS(pod *api.Pod) ([]string, []string, error) {
@@ -832,18 +1282,129 @@ func (kl *Kubelet) getClusterDNS(pod *api.Pod) ([]string, []string, error) {
 		return nil, nil, err
 	}
 
-	var dns, dnsSearch []string
+	dns := hostDNS
+	dnsSearch := hostSearch
 
 	if kl.clusterDNS != nil {
 		dns = append([]string{kl.clusterDNS.String()}, hostDNS...)
 	}
 	if kl.clusterDomain != "" {
-		nsDomain := fmt.Sprintf("%s.%s", pod.Namespace, kl.clusterDomain)
-		dnsSearch = append([]string{nsDomain, kl.clusterDomain}, hostSearch...)
+		nsSvcDomain := fmt.Sprintf("%s.svc.%s", pod.Namespace, kl.clusterDomain)
+		svcDomain := fmt.Sprintf("svc.%s", kl.clusterDomain)
+		dnsSearch = append([]string{nsSvcDomain, svcDomain, kl.clusterDomain}, hostSearch...)
 	}
 	return dns, dnsSearch, nil
 }
 
+// truncateDNSSearchPaths enforces the libc resolver limits on the pod's DNS
+// search line. Domains are dropped from the end of the list, so the cluster
+// domains, which sort first, are kept. A truncation is reported as a pod
+// event rather than silently producing a resolv.conf the resolver ignores
+// part of.
+func (kl *Kubelet) truncateDNSSearchPaths(pod *api.Pod, dnsSearch []string) []string {
+	limitsExceeded := false
+	if len(dnsSearch) > maxDNSSearchPaths {
+		dnsSearch = dnsSearch[:maxDNSSearchPaths]
+		limitsExceeded = true
+	}
+	if searchLineLen := len(strings.Join(dnsSearch, " ")); searchLineLen > maxDNSSearchListChars {
+		cutDomainsNum := 0
+		cutDomainsLen := 0
+		for i := len(dnsSearch) - 1; i >= 0; i-- {
+			cutDomainsLen += len(dnsSearch[i]) + 1
+			cutDomainsNum++
+			if searchLineLen-cutDomainsLen <= maxDNSSearchListChars {
+				break
+			}
+		}
+		dnsSearch = dnsSearch[:len(dnsSearch)-cutDomainsNum]
+		limitsExceeded = true
+	}
+	if limitsExceeded {
+		metrics.DNSSearchPathTruncations.Inc()
+		message := fmt.Sprintf("Search Line limits were exceeded, some dns names have been omitted, the applied search line is: %s", strings.Join(dnsSearch, " "))
+		kl.recorder.Event(pod, "dnsSearchTruncated", message)
+		glog.Error(message)
+	}
+	return dnsSearch
+}
+
+// makePodHosts writes a hosts file for the pod into the pod's directory.
+// Mounting the file over /etc/hosts gives containers a consistent view of the
+// pod's own hostname and of the hostAliases from the pod spec, regardless of
+// the base image.
+func (kl *Kubelet) makePodHosts(pod *api.Pod, podIP string) (string, error) {
+	// Match the hostname given to docker, which caps it at 63 characters.
+	const hostnameMaxLen = 63
+	hostname := pod.Name
+	if len(pod.Spec.Hostname) > 0 {
+		hostname = pod.Spec.Hostname
+	}
+	if len(hostname) > hostnameMaxLen {
+		hostname = hostname[:hostnameMaxLen]
+	}
+
+	var buf bytes.Buffer
+	buf.WriteString("# Kubernetes-managed hosts file.\n")
+	buf.WriteString("127.0.0.1\tlocalhost\n")
+	buf.WriteString("::1\tlocalhost ip6-localhost ip6-loopback\n")
+	buf.WriteString("fe00::0\tip6-localnet\n")
+	buf.WriteString("fe00::0\tip6-mcastprefix\n")
+	buf.WriteString("fe00::1\tip6-allnodes\n")
+	buf.WriteString("fe00::2\tip6-allrouters\n")
+	if len(pod.Spec.Subdomain) > 0 && len(kl.clusterDomain) > 0 {
+		fqdn := fmt.Sprintf("%s.%s.%s.svc.%s", hostname, pod.Spec.Subdomain, pod.Namespace, kl.clusterDomain)
+		fmt.Fprintf(&buf, "%s\t%s %s\n", podIP, fqdn, hostname)
+	} else {
+		fmt.Fprintf(&buf, "%s\t%s\n", podIP, hostname)
+	}
+	if len(pod.Spec.HostAliases) > 0 {
+		buf.WriteString("\n# Entries added by HostAliases.\n")
+		for _, hostAlias := range pod.Spec.HostAliases {
+			for _, name := range hostAlias.Hostnames {
+				fmt.Fprintf(&buf, "%s\t%s\n", hostAlias.IP, name)
+			}
+		}
+	}
+
+	podDir := kl.getPodDir(pod.UID)
+	if err := os.MkdirAll(podDir, 0750); err != nil {
+		return "", err
+	}
+	hostsFilePath := path.Join(podDir, "etc-hosts")
+	if err := ioutil.WriteFile(hostsFilePath, buf.Bytes(), 0644); err != nil {
+		return "", err
+	}
+	return hostsFilePath, nil
+}
+
+// makePodResolvConf writes a resolv.conf for the pod into the pod's directory.
+// Mounting the file over /etc/resolv.conf lets the kubelet set resolver
+// options, such as ndots, that cannot be expressed through the docker API.
+// It returns the path of the written file.
+func (kl *Kubelet) makePodResolvConf(pod *api.Pod, dns, dnsSearch, dnsOptions []string) (string, error) {
+	var buf bytes.Buffer
+	for _, nameserver := range dns {
+		fmt.Fprintf(&buf, "nameserver %s\n", nameserver)
+	}
+	if len(dnsSearch) > 0 {
+		fmt.Fprintf(&buf, "search %s\n", strings.Join(dnsSearch, " "))
+	}
+	if len(dnsOptions) > 0 {
+		fmt.Fprintf(&buf, "options %s\n", strings.Join(dnsOptions, " "))
+	}
+
+	podDir := kl.getPodDir(pod.UID)
+	if err := os.MkdirAll(podDir, 0750); err != nil {
+		return "", err
+	}
+	resolvConfPath := path.Join(podDir, "resolv.conf")
+	if err := ioutil.WriteFile(resolvConfPath, buf.Bytes(), 0644); err != nil {
+		return "", err
+	}
+	return resolvConfPath, nil
+}
+
 // Returns the list of DNS servers and DNS search domains.
 func parseResolvConf(reader io.Reader) (nameservers []string, searches []string, err error) {
 	file, err := ioutil.ReadAll(reader)
@@ -907,7 +1468,7 @@ func (kl *Kubelet) createPodInfraContainer(pod *api.Pod) (dockertools.DockerID,
 	ok, err := kl.containerManager.IsImagePresent(container.Image)
 	if err != nil {
 		if ref != nil {
-			kl.recorder.Eventf(ref, "failed", "Failed to inspect image %q: %v", container.Image, err)
+			kl.recorder.Eventf(ref, "Failed", "Failed to inspect image %q: %v", container.Image, err)
 		}
 		return "", err
 	}
@@ -917,10 +1478,10 @@ func (kl *Kubelet) createPodInfraContainer(pod *api.Pod) (dockertools.DockerID,
 		}
 	}
 	if ref != nil {
-		kl.recorder.Eventf(ref, "pulled", "Successfully pulled image %q", container.Image)
+		kl.recorder.Eventf(ref, "Pulled", "Successfully pulled image %q", container.Image)
 	}
 
-	id, err := kl.containerManager.RunContainer(pod, container, kl, kl.handlerRunner, netNamespace, "")
+	id, err := kl.containerManager.RunContainer(pod, container, kl, kl.handlerRunner, "", netNamespace, "", "")
 	if err != nil {
 		return "", err
 	}
@@ -946,20 +1507,75 @@ func (kl *Kubelet) pullImage(img string, ref *api.ObjectReference) error {
 		metrics.ImagePullLatency.Observe(metrics.SinceInMicroseconds(start))
 	}()
 
-	if err := kl.containerManager.Pull(img); err != nil {
+	if ref != nil {
+		kl.recorder.Eventf(ref, "Pulling", "Pulling image %q", img)
+	}
+	var onProgress dockertools.PullProgressFunc
+	if ref != nil {
+		onProgress = func(progress string) {
+			kl.recorder.Eventf(ref, "Pulling", "Pulling image %q: %s", img, progress)
+		}
+	}
+	done := make(chan error, 1)
+	go func() {
+		defer util.HandleCrash()
+		done <- kl.containerManager.Pull(img, onProgress)
+	}()
+	var timeout <-chan time.Time
+	if kl.imagePullTimeout > 0 {
+		timeout = time.After(kl.imagePullTimeout)
+	}
+	var err error
+	select {
+	case err = <-done:
+	case <-timeout:
+		// The pull itself cannot be aborted; stop waiting for it and let
+		// the next sync retry.
+		err = fmt.Errorf("image pull did not finish within %v", kl.imagePullTimeout)
+	}
+	if err != nil {
 		if ref != nil {
-			kl.recorder.Eventf(ref, "failed", "Failed to pull image %q: %v", img, err)
+			kl.recorder.Eventf(ref, "Failed", "Failed to pull image %q: %v", img, err)
 		}
 		return err
 	}
 	if ref != nil {
-		kl.recorder.Eventf(ref, "pulled", "Successfully pulled image %q", img)
+		kl.recorder.Eventf(ref, "Pulled", "Successfully pulled image %q", img)
 	}
 	return nil
 }
 
-// Kill all running containers in a pod (includes the pod infra container).
-func (kl *Kubelet) killPod(pod kubecontainer.Pod) error {
+// pullImageWithCancel pulls an image, giving up early when cancel is closed.
+// Docker offers no way to abort an in-flight pull, so the pull itself runs to
+// completion in the background; we merely stop waiting for its result.
+func (kl *Kubelet) pullImageWithCancel(img string, ref *api.ObjectReference, cancel <-chan struct{}) error {
+	done := make(chan error, 1)
+	go func() {
+		done <- kl.pullImage(img, ref)
+	}()
+	select {
+	case err := <-done:
+		return err
+	case <-cancel:
+		return errSyncCanceled
+	}
+}
+
+// killDeadlineFor returns the time by which the pod's containers must be
+// stopped. For a pod being gracefully deleted that is its deletion
+// timestamp; the container manager's kill throttle stops pods with the
+// nearest deadline first when docker stops are queued. A zero deadline
+// means the kill has no urgency and queues behind all others.
+func killDeadlineFor(pod *api.Pod) time.Time {
+	if pod != nil && pod.DeletionTimestamp != nil {
+		return pod.DeletionTimestamp.Time
+	}
+	return time.Time{}
+}
+
+// Kill all running containers in a pod (includes the pod infra container),
+// stopping them by the given deadline if there is one.
+func (kl *Kubelet) killPod(pod kubecontainer.Pod, deadline time.Time) error {
 	// Send the kills in parallel since they may take a long time.
 	errs := make(chan error, len(pod.Containers))
 	wg := sync.WaitGroup{}
@@ -977,7 +1593,7 @@ func (kl *Kubelet) killPod(pod kubecontainer.Pod) error {
 					errs <- err
 				}
 			}
-			err := kl.containerManager.KillContainer(container.ID)
+			err := kl.containerManager.KillContainerWithDeadline(container.ID, deadline)
 			if err != nil {
 				glog.Errorf("Failed to delete container: %v; Skipping pod %q", err, pod.ID)
 				errs <- err
@@ -986,6 +1602,9 @@ func (kl *Kubelet) killPod(pod kubecontainer.Pod) error {
 		}(container)
 	}
 	wg.Wait()
+	// The pod's containers have changed; force the next cached lookup for it
+	// to refresh.
+	kl.runtimeCache.InvalidatePod(pod.ID)
 	close(errs)
 	if len(errs) > 0 {
 		errList := []error{}
@@ -997,6 +1616,139 @@ func (kl *Kubelet) killPod(pod kubecontainer.Pod) error {
 	return nil
 }
 
+// retryTeardownStep runs one step of the pod teardown pipeline, retrying
+// with doubling backoff so a transient failure in one step does not abort
+// the whole teardown.
+func retryTeardownStep(description string, step func() error) error {
+	backoff := teardownStepBackoff
+	var err error
+	for attempt := 1; attempt <= teardownStepAttempts; attempt++ {
+		if err = step(); err == nil {
+			return nil
+		}
+		glog.Warningf("Tearing down %s failed (attempt %d): %v", description, attempt, err)
+		if attempt < teardownStepAttempts {
+			time.Sleep(backoff)
+			backoff *= 2
+		}
+	}
+	return err
+}
+
+// killContainersInParallel kills the given containers in parallel, since
+// each kill may take a long time, and aggregates the failures.
+func (kl *Kubelet) killContainersInParallel(containers []*kubecontainer.Container) error {
+	errs := make(chan error, len(containers))
+	wg := sync.WaitGroup{}
+	for _, container := range containers {
+		wg.Add(1)
+		go func(container *kubecontainer.Container) {
+			defer util.HandleCrash()
+			defer wg.Done()
+			if err := kl.containerManager.KillContainer(container.ID); err != nil {
+				errs <- err
+			}
+		}(container)
+	}
+	wg.Wait()
+	close(errs)
+	errList := []error{}
+	for err := range errs {
+		errList = append(errList, err)
+	}
+	return utilErrors.NewAggregate(errList)
+}
+
+// teardownPodVolumes unmounts any of the pod's volumes that are still
+// mounted on disk. Volumes must only be torn down once every container
+// that could be using them is dead.
+func (kl *Kubelet) teardownPodVolumes(uid types.UID) error {
+	errlist := []error{}
+	for name, cleaner := range kl.getPodVolumesFromDisk() {
+		if !strings.HasPrefix(name, string(uid)+"/") {
+			continue
+		}
+		if err := cleaner.TearDown(); err != nil {
+			errlist = append(errlist, err)
+		}
+	}
+	if len(errlist) > 0 {
+		return utilErrors.NewAggregate(errlist)
+	}
+	kl.volumeManager.DeleteVolumes(uid)
+	return nil
+}
+
+// teardownPod deletes a pod in a strict order: regular containers first,
+// then the pod's network and infra container, then any volumes that are
+// still mounted, and finally the pod directory. Each step is retried
+// independently, and a step that keeps failing leaves the rest of the
+// teardown for the next sync pass rather than running later steps out of
+// order, which could leak veths or unmount volumes under live containers.
+func (kl *Kubelet) teardownPod(pod *kubecontainer.Pod) error {
+	// Kill the regular containers before the infra container, so the pod's
+	// network namespace stays intact while they shut down.
+	infraContainer := pod.FindContainerByName(dockertools.PodInfraContainerName)
+	var regularContainers []*kubecontainer.Container
+	for _, container := range pod.Containers {
+		if container != infraContainer {
+			regularContainers = append(regularContainers, container)
+		}
+	}
+	err := retryTeardownStep(fmt.Sprintf("containers of pod %q", pod.Name), func() error {
+		return kl.killContainersInParallel(regularContainers)
+	})
+	if err != nil {
+		return err
+	}
+	if infraContainer != nil {
+		err = retryTeardownStep(fmt.Sprintf("network of pod %q", pod.Name), func() error {
+			if err := kl.networkPlugin.TearDownPod(pod.Namespace, pod.Name, dockertools.DockerID(infraContainer.ID)); err != nil {
+				return err
+			}
+			return kl.containerManager.KillContainer(infraContainer.ID)
+		})
+		if err != nil {
+			return err
+		}
+	}
+	// The pod's containers have changed; force the next cached lookup for it
+	// to refresh.
+	kl.runtimeCache.InvalidatePod(pod.ID)
+	err = retryTeardownStep(fmt.Sprintf("volumes of pod %q", pod.Name), func() error {
+		return kl.teardownPodVolumes(pod.ID)
+	})
+	if err != nil {
+		return err
+	}
+	err = retryTeardownStep(fmt.Sprintf("directory of pod %q", pod.Name), func() error {
+		return os.RemoveAll(kl.getPodDir(pod.ID))
+	})
+	if err != nil {
+		return err
+	}
+	kl.containerHistory.forget(pod.ID)
+	glog.V(2).Infof("Pod %q (UID %q) cleaned up", pod.Name, pod.ID)
+	return nil
+}
+
+// teardownDeadPodInfraContainer calls the network plugin's teardown for a
+// pod whose infra container already exited. killPod cannot do this because
+// a dead infra container does not appear in the running pod, so without it
+// the pod's IP would be leaked when the sandbox is recreated.
+func (kl *Kubelet) teardownDeadPodInfraContainer(pod *api.Pod) error {
+	pods, err := kl.containerManager.GetPods(true)
+	if err != nil {
+		return err
+	}
+	deadPod := kubecontainer.Pods(pods).FindPodByID(pod.UID)
+	podInfraContainer := deadPod.FindContainerByName(dockertools.PodInfraContainerName)
+	if podInfraContainer == nil {
+		return nil
+	}
+	return kl.networkPlugin.TearDownPod(pod.Namespace, pod.Name, dockertools.DockerID(podInfraContainer.ID))
+}
+
 type empty struct{}
 
 // makePodDataDirs creates the dirs for the pod datas.
@@ -1014,7 +1766,7 @@ func (kl *Kubelet) makePodDataDirs(pod *api.Pod) error {
 	return nil
 }
 
-func shouldContainerBeRestarted(container *api.Container, pod *api.Pod, podStatus *api.PodStatus, readinessManager *kubecontainer.ReadinessManager) bool {
+func (kl *Kubelet) shouldContainerBeRestarted(container *api.Container, pod *api.Pod, podStatus *api.PodStatus) bool {
 	podFullName := kubecontainer.GetPodFullName(pod)
 
 	// Get all dead container status.
@@ -1028,16 +1780,24 @@ func shouldContainerBeRestarted(container *api.Container, pod *api.Pod, podStatu
 	// Set dead containers to unready state.
 	for _, c := range resultStatus {
 		// TODO(yifan): Unify the format of container ID. (i.e. including docker:// as prefix).
-		readinessManager.RemoveReadiness(strings.TrimPrefix(c.ContainerID, dockertools.DockerPrefix))
+		kl.readinessManager.RemoveReadiness(strings.TrimPrefix(c.ContainerID, dockertools.DockerPrefix))
 	}
 
 	// Check RestartPolicy for dead container.
 	if len(resultStatus) > 0 {
-		if pod.Spec.RestartPolicy == api.RestartPolicyNever {
+		switch {
+		case container.Sidecar:
+			// Sidecars are restarted regardless of the restart policy while
+			// any primary container still has work to do; once every primary
+			// is done they stay down.
+			if primaryContainersDone(pod, podStatus) {
+				glog.V(4).Infof("Primary containers of pod %q are done, not restarting sidecar %q", podFullName, container.Name)
+				return false
+			}
+		case pod.Spec.RestartPolicy == api.RestartPolicyNever:
 			glog.V(4).Infof("Already ran container %q of pod %q, do nothing", container.Name, podFullName)
 			return false
-		}
-		if pod.Spec.RestartPolicy == api.RestartPolicyOnFailure {
+		case pod.Spec.RestartPolicy == api.RestartPolicyOnFailure:
 			// Check the exit code of last run. Note: This assumes the result is sorted
 			// by the created time in reverse order.
 			if resultStatus[0].State.Termination.ExitCode == 0 {
@@ -1045,41 +1805,87 @@ func shouldContainerBeRestarted(container *api.Container, pod *api.Pod, podStatu
 				return false
 			}
 		}
+		if resultStatus[0].State.Termination.ExitCode != 0 {
+			// The container failed last time; leave a trail for users debugging
+			// crash-looping containers.
+			if ref, err := kubecontainer.GenerateContainerRef(pod, container); err == nil {
+				kl.recorder.Eventf(ref, "BackOff", "Restarting failed container %q of pod %q", container.Name, podFullName)
+			}
+		}
 	}
 	return true
 }
 
+// primaryContainersDone returns true when every non-sidecar container of the
+// pod has terminated and the restart policy does not call for another run.
+// Once that holds, sidecars have nothing left to serve and are torn down
+// rather than kept running or restarted.
+func primaryContainersDone(pod *api.Pod, podStatus *api.PodStatus) bool {
+	if pod.Spec.RestartPolicy == api.RestartPolicyAlways {
+		return false
+	}
+	primaries := 0
+	for _, container := range pod.Spec.Containers {
+		if container.Sidecar {
+			continue
+		}
+		primaries++
+		status := findContainerStatus(podStatus.ContainerStatuses, container.Name)
+		if status == nil || status.State.Termination == nil {
+			return false
+		}
+		if pod.Spec.RestartPolicy == api.RestartPolicyOnFailure && status.State.Termination.ExitCode != 0 {
+			return false
+		}
+	}
+	return primaries > 0
+}
+
 // Attempts to start a container pulling the image before that if necessary. It returns DockerID of a started container
 // if it was successful, and a non-nil error otherwise.
-func (kl *Kubelet) pullImageAndRunContainer(pod *api.Pod, container *api.Container, podInfraContainerID dockertools.DockerID) (dockertools.DockerID, error) {
+func (kl *Kubelet) pullImageAndRunContainer(pod *api.Pod, container *api.Container, podInfraContainerID dockertools.DockerID, podIP string, cancel <-chan struct{}) (dockertools.DockerID, kubecontainer.PodSyncResult) {
+	var result kubecontainer.PodSyncResult
 	podFullName := kubecontainer.GetPodFullName(pod)
 	ref, err := kubecontainer.GenerateContainerRef(pod, container)
 	if err != nil {
 		glog.Errorf("Couldn't make a ref to pod %v, container %v: '%v'", pod.Name, container.Name, err)
 	}
-	if container.ImagePullPolicy != api.PullNever {
+	if container.ImagePullPolicy != api.PullNever || kl.alwaysPullImages {
+		pullResult := kubecontainer.NewSyncResult(kubecontainer.PullImage, container.Image)
+		result.AddSyncResult(pullResult)
 		present, err := kl.containerManager.IsImagePresent(container.Image)
 		if err != nil {
 			if ref != nil {
-				kl.recorder.Eventf(ref, "failed", "Failed to inspect image %q: %v", container.Image, err)
+				kl.recorder.Eventf(ref, "Failed", "Failed to inspect image %q: %v", container.Image, err)
 			}
 			glog.Errorf("Failed to inspect image %q: %v; skipping pod %q container %q", container.Image, err, podFullName, container.Name)
-			return "", err
+			pullResult.Fail(err, "")
+			return "", result
 		}
-		if container.ImagePullPolicy == api.PullAlways ||
+		if kl.alwaysPullImages || container.ImagePullPolicy == api.PullAlways ||
 			(container.ImagePullPolicy == api.PullIfNotPresent && (!present)) {
-			if err := kl.pullImage(container.Image, ref); err != nil {
-				return "", err
+			if err := kl.pullImageWithCancel(container.Image, ref, cancel); err != nil {
+				pullResult.Fail(err, "")
+				return "", result
 			}
 		}
 	}
+	startResult := kubecontainer.NewSyncResult(kubecontainer.StartContainer, container.Name)
+	result.AddSyncResult(startResult)
 	// TODO(dawnchen): Check RestartPolicy.DelaySeconds before restart a container
 	namespaceMode := fmt.Sprintf("container:%v", podInfraContainerID)
-	containerID, err := kl.containerManager.RunContainer(pod, container, kl, kl.handlerRunner, namespaceMode, namespaceMode)
+	pidMode := ""
+	if kl.sharedPIDNamespace {
+		// Joining the infra container's PID namespace makes the pause
+		// process PID 1 for the pod, where it reaps orphaned zombies.
+		pidMode = namespaceMode
+	}
+	containerID, err := kl.containerManager.RunContainer(pod, container, kl, kl.handlerRunner, podIP, namespaceMode, namespaceMode, pidMode)
 	if err != nil {
 		// TODO(bburns) : Perhaps blacklist a container after N failures?
 		glog.Errorf("Error running pod %q container %q: %v", podFullName, container.Name, err)
-		return "", err
+		startResult.Fail(err, "")
+		return "", result
 	}
 	// Create a symbolic link to the Docker container log file using a name which captures the
 	// full pod name, the container name and the Docker container ID. Cluster level logging will
@@ -1096,7 +1902,7 @@ func (kl *Kubelet) pullImageAndRunContainer(pod *api.Pod, container *api.Contain
 	if err = kl.os.Symlink(containerLogFile, symlinkFile); err != nil {
 		glog.Errorf("Failed to create symbolic link to the log file of pod %q container %q: %v", podFullName, container.Name, err)
 	}
-	return containerID, nil
+	return containerID, result
 }
 
 // Structure keeping information on changes that need to happen for a pod. The semantics is as follows:
@@ -1149,12 +1955,20 @@ func (kl *Kubelet) computePodContainerChanges(pod *api.Pod, runningPod kubeconta
 		containersToKeep[podInfraContainerID] = -1
 	}
 
-	for index, container := range pod.Spec.Containers {
-		expectedHash := dockertools.HashContainer(&container)
+	// Keep running init containers; syncPod decides whether they still need
+	// to run to completion.
+	if !createPodInfraContainer {
+		for _, container := range pod.Spec.InitContainers {
+			if c := runningPod.FindContainerByName(container.Name); c != nil {
+				containersToKeep[dockertools.DockerID(c.ID)] = -1
+			}
+		}
+	}
 
+	for index, container := range pod.Spec.Containers {
 		c := runningPod.FindContainerByName(container.Name)
 		if c == nil {
-			if shouldContainerBeRestarted(&container, pod, &podStatus, kl.readinessManager) {
+			if kl.shouldContainerBeRestarted(&container, pod, &podStatus) {
 				// If we are here it means that the container is dead and should be restarted, or never existed and should
 				// be created. We may be inserting this ID again if the container has changed and it has
 				// RestartPolicy::Always, but it's not a big deal.
@@ -1168,12 +1982,19 @@ func (kl *Kubelet) computePodContainerChanges(pod *api.Pod, runningPod kubeconta
 		hash := c.Hash
 		glog.V(3).Infof("pod %q container %q exists as %v", podFullName, container.Name, containerID)
 
+		if container.Sidecar && primaryContainersDone(pod, &podStatus) {
+			// Every primary container is done; the sidecar has nothing left
+			// to serve and is killed by being left out of containersToKeep.
+			glog.V(2).Infof("pod %q sidecar container %q outlived the primary containers, it will be killed.", podFullName, container.Name)
+			continue
+		}
+
 		if createPodInfraContainer {
 			// createPodInfraContainer == true and Container exists
 			// If we're creating infra containere everything will be killed anyway
 			// If RestartPolicy is Always or OnFailure we restart containers that were running before we
 			// killed them when restarting Infra Container.
-			if pod.Spec.RestartPolicy != api.RestartPolicyNever {
+			if pod.Spec.RestartPolicy != api.RestartPolicyNever || container.Sidecar {
 				glog.V(1).Infof("Infra Container is being recreated. %q will be restarted.", container.Name)
 				containersToStart[index] = empty{}
 			}
@@ -1182,9 +2003,22 @@ func (kl *Kubelet) computePodContainerChanges(pod *api.Pod, runningPod kubeconta
 
 		// At this point, the container is running and pod infra container is good.
 		// We will look for changes and check healthiness for the container.
-		containerChanged := hash != 0 && hash != expectedHash
+		containerChanged := hash != 0 && dockertools.ContainerHashChanged(&container, hash)
 		if containerChanged {
-			glog.Infof("pod %q container %q hash changed (%d vs %d), it will be killed and re-created.", podFullName, container.Name, hash, expectedHash)
+			// When only the resource limits changed, apply them in place
+			// rather than killing and re-creating the container.
+			if kl.containerManager.TryUpdateContainerResources(containerID, &container) {
+				glog.V(2).Infof("pod %q container %q resource limits updated in place.", podFullName, container.Name)
+				containersToKeep[containerID] = index
+				continue
+			}
+			glog.Infof("pod %q container %q hash changed (%d vs %d), it will be killed and re-created.", podFullName, container.Name, hash, dockertools.HashContainer(&container))
+			containersToStart[index] = empty{}
+			continue
+		}
+
+		if kl.containerManager.RestartPolicyMutated(containerID) {
+			glog.Infof("pod %q container %q docker restart policy was mutated out-of-band, it will be killed and re-created.", podFullName, container.Name)
 			containersToStart[index] = empty{}
 			continue
 		}
@@ -1223,9 +2057,104 @@ func (kl *Kubelet) computePodContainerChanges(pod *api.Pod, runningPod kubeconta
 	}, nil
 }
 
-func (kl *Kubelet) syncPod(pod *api.Pod, mirrorPod *api.Pod, runningPod kubecontainer.Pod) error {
+// runInitContainers runs the pod's init containers one at a time, in order,
+// blocking until each has run to completion. Init containers that already
+// ran to completion are only run again when the pod infra container was
+// recreated, which gives the pod fresh namespaces.
+func (kl *Kubelet) runInitContainers(pod *api.Pod, podInfraContainerID dockertools.DockerID, podIP string, podStatus *api.PodStatus, infraRecreated bool, syncResult *kubecontainer.PodSyncResult, cancel <-chan struct{}) error {
+	for i := range pod.Spec.InitContainers {
+		if syncCanceled(cancel) {
+			return errSyncCanceled
+		}
+		container := &pod.Spec.InitContainers[i]
+		if !infraRecreated {
+			if status := findContainerStatus(podStatus.InitContainerStatuses, container.Name); status != nil && status.State.Termination != nil {
+				if status.State.Termination.ExitCode == 0 {
+					continue
+				}
+				if pod.Spec.RestartPolicy == api.RestartPolicyNever {
+					return fmt.Errorf("init container %q failed with exit code %d", container.Name, status.State.Termination.ExitCode)
+				}
+			}
+		}
+		glog.V(4).Infof("Running init container %q for pod %q", container.Name, kubecontainer.GetPodFullName(pod))
+		initResult := kubecontainer.NewSyncResult(kubecontainer.InitContainer, container.Name)
+		id, runResult := kl.pullImageAndRunContainer(pod, container, podInfraContainerID, podIP, cancel)
+		syncResult.AddSyncResult(runResult.SyncResults...)
+		syncResult.AddSyncResult(initResult)
+		if err := runResult.Error(); err != nil {
+			initResult.Fail(err, "")
+			return err
+		}
+		exitCode, err := kl.dockerClient.WaitContainer(string(id))
+		if err != nil {
+			initResult.Fail(err, "")
+			return err
+		}
+		if exitCode != 0 {
+			err := fmt.Errorf("init container %q exited with code %d", container.Name, exitCode)
+			initResult.Fail(err, "")
+			return err
+		}
+	}
+	return nil
+}
+
+// findContainerStatus returns the status with the given name, or nil.
+func findContainerStatus(statuses []api.ContainerStatus, name string) *api.ContainerStatus {
+	for i := range statuses {
+		if statuses[i].Name == name {
+			return &statuses[i]
+		}
+	}
+	return nil
+}
+
+// applySysctls writes the pod's sysctls through the pod infra container's
+// /proc, so that they take effect in the namespaces shared by the pod's
+// containers.
+func (kl *Kubelet) applySysctls(pod *api.Pod, pid int) error {
+	if pid == 0 {
+		return fmt.Errorf("cannot set sysctls for pod %q: infra container pid is unknown", kubecontainer.GetPodFullName(pod))
+	}
+	for _, sysctl := range pod.Spec.Sysctls {
+		fname := fmt.Sprintf("/proc/%d/root/proc/sys/%s", pid, strings.Replace(sysctl.Name, ".", "/", -1))
+		if err := ioutil.WriteFile(fname, []byte(sysctl.Value), 0644); err != nil {
+			return fmt.Errorf("cannot set sysctl %q to %q: %v", sysctl.Name, sysctl.Value, err)
+		}
+	}
+	return nil
+}
+
+// errSyncCanceled is returned from a pod sync that gave up because the pod's
+// work was canceled, e.g. because the pod was deleted mid-sync.
+var errSyncCanceled = errors.New("pod sync canceled")
+
+// syncCanceled reports whether the given cancel channel has been closed. A
+// nil channel is never canceled.
+func syncCanceled(cancel <-chan struct{}) bool {
+	select {
+	case <-cancel:
+		return true
+	default:
+		return false
+	}
+}
+
+func (kl *Kubelet) syncPod(pod *api.Pod, mirrorPod *api.Pod, runningPod kubecontainer.Pod, cancel <-chan struct{}) error {
 	podFullName := kubecontainer.GetPodFullName(pod)
 	uid := pod.UID
+	syncResult := kubecontainer.PodSyncResult{}
+
+	// Count every failed step so operators can tell which part of pod
+	// syncing is unhealthy.
+	defer func() {
+		for _, result := range syncResult.SyncResults {
+			if result.Error != nil {
+				metrics.SyncActionErrors.WithLabelValues(string(result.Action)).Inc()
+			}
+		}
+	}()
 
 	// Before returning, regenerate status and store it in the cache.
 	defer func() {
@@ -1238,36 +2167,41 @@ func (kl *Kubelet) syncPod(pod *api.Pod, mirrorPod *api.Pod, runningPod kubecont
 		if err != nil {
 			glog.Errorf("Unable to generate status for pod with name %q and uid %q info with error(%v)", podFullName, uid, err)
 		} else {
-			podToUpdate := pod
-			if mirrorPod != nil {
-				podToUpdate = mirrorPod
-			}
-			kl.statusManager.SetPodStatus(podToUpdate, status)
+			kl.podStatusCache.Set(uid, status)
+			// The status is keyed by the pod's own UID - for a static pod
+			// that is the static UID, not the mirror pod's. The apiserver
+			// write in the status manager goes by name, so the mirror pod
+			// still receives the update.
+			kl.statusManager.SetPodStatus(pod, status)
 		}
 	}()
 
 	// Kill pods we can't run.
 	err := canRunPod(pod)
 	if err != nil {
-		kl.killPod(runningPod)
-		return err
+		kl.killPod(runningPod, killDeadlineFor(pod))
+		syncResult.Fail(err)
+		return syncResult.Error()
 	}
 
 	if err := kl.makePodDataDirs(pod); err != nil {
 		glog.Errorf("Unable to make pod data directories for pod %q (uid %q): %v", podFullName, uid, err)
-		return err
+		syncResult.Fail(err)
+		return syncResult.Error()
 	}
 
-	podStatus, err := kl.generatePodStatus(pod)
+	podStatus, err := kl.cachedPodStatus(pod, runningPod)
 	if err != nil {
 		glog.Errorf("Unable to get status for pod %q (uid %q): %v", podFullName, uid, err)
-		return err
+		syncResult.Fail(err)
+		return syncResult.Error()
 	}
 
 	containerChanges, err := kl.computePodContainerChanges(pod, runningPod, podStatus)
 	glog.V(3).Infof("Got container changes for pod %q: %+v", podFullName, containerChanges)
 	if err != nil {
-		return err
+		syncResult.Fail(err)
+		return syncResult.Error()
 	}
 
 	if containerChanges.startInfraContainer || (len(containerChanges.containersToKeep) == 0 && len(containerChanges.containersToStart) == 0) {
@@ -1278,9 +2212,20 @@ func (kl *Kubelet) syncPod(pod *api.Pod, mirrorPod *api.Pod, runningPod kubecont
 		}
 
 		// Killing phase: if we want to start new infra container, or nothing is running kill everything (including infra container)
-		err = kl.killPod(runningPod)
+		killResult := kubecontainer.NewSyncResult(kubecontainer.KillPod, podFullName)
+		syncResult.AddSyncResult(killResult)
+		err = kl.killPod(runningPod, killDeadlineFor(pod))
 		if err != nil {
-			return err
+			killResult.Fail(err, "")
+			return syncResult.Error()
+		}
+		// If the infra container died on its own, killPod above never saw it
+		// and its networking is still set up; tear it down before a new
+		// sandbox is created so the pod does not keep its stale IP.
+		if containerChanges.startInfraContainer && len(runningPod.Containers) > 0 && runningPod.FindContainerByName(dockertools.PodInfraContainerName) == nil {
+			if err := kl.teardownDeadPodInfraContainer(pod); err != nil {
+				glog.Errorf("Failed tearing down network of dead infra container for pod %q: %v", podFullName, err)
+			}
 		}
 	} else {
 		// Otherwise kill any containers in this pod which are not specified as ones to keep.
@@ -1288,10 +2233,14 @@ func (kl *Kubelet) syncPod(pod *api.Pod, mirrorPod *api.Pod, runningPod kubecont
 			_, keep := containerChanges.containersToKeep[dockertools.DockerID(container.ID)]
 			if !keep {
 				glog.V(3).Infof("Killing unwanted container %+v", container)
-				err = kl.containerManager.KillContainer(container.ID)
+				killResult := kubecontainer.NewSyncResult(kubecontainer.KillContainer, container.Name)
+				syncResult.AddSyncResult(killResult)
+				err = kl.containerManager.KillContainerWithDeadline(container.ID, killDeadlineFor(pod))
 				if err != nil {
+					killResult.Fail(err, "")
 					glog.Errorf("Error killing container: %v", err)
 				}
+				kl.runtimeCache.InvalidatePod(pod.UID)
 			}
 		}
 	}
@@ -1302,37 +2251,113 @@ func (kl *Kubelet) syncPod(pod *api.Pod, mirrorPod *api.Pod, runningPod kubecont
 		glog.Errorf("Couldn't make a ref to pod %q: '%v'", podFullName, err)
 	}
 
+	if syncCanceled(cancel) {
+		syncResult.Fail(errSyncCanceled)
+		return syncResult.Error()
+	}
+
 	// Mount volumes.
+	mountResult := kubecontainer.NewSyncResult(kubecontainer.MountVolumes, podFullName)
+	syncResult.AddSyncResult(mountResult)
 	podVolumes, err := kl.mountExternalVolumes(pod)
 	if err != nil {
 		if ref != nil {
 			kl.recorder.Eventf(ref, "failedMount", "Unable to mount volumes for pod %q: %v", podFullName, err)
 		}
 		glog.Errorf("Unable to mount volumes for pod %q: %v; skipping pod", podFullName, err)
-		return err
+		mountResult.Fail(err, "")
+		return syncResult.Error()
 	}
 	kl.volumeManager.SetVolumes(pod.UID, podVolumes)
 
 	// If we should create infra container then we do it first.
+	podIP := podStatus.PodIP
 	podInfraContainerID := containerChanges.infraContainerId
 	if containerChanges.startInfraContainer && (len(containerChanges.containersToStart) > 0) {
 		glog.V(4).Infof("Creating pod infra container for %q", podFullName)
+		createResult := kubecontainer.NewSyncResult(kubecontainer.StartPodInfraContainer, podFullName)
+		syncResult.AddSyncResult(createResult)
 		podInfraContainerID, err = kl.createPodInfraContainer(pod)
+		if err != nil {
+			createResult.Fail(err, "")
+		}
 
 		// Call the networking plugin
 		if err == nil {
+			setupResult := kubecontainer.NewSyncResult(kubecontainer.SetupNetwork, podFullName)
+			syncResult.AddSyncResult(setupResult)
 			err = kl.networkPlugin.SetUpPod(pod.Namespace, pod.Name, podInfraContainerID)
+			if err != nil {
+				setupResult.Fail(err, "")
+				// Don't leave an infra container without networking behind;
+				// kill it so the next sync recreates the whole sandbox from
+				// scratch.
+				if nerr := kl.networkPlugin.TearDownPod(pod.Namespace, pod.Name, podInfraContainerID); nerr != nil {
+					glog.Errorf("Failed tearing down partially set up infra container for pod %q: %v", podFullName, nerr)
+				}
+				if kerr := kl.containerManager.KillContainer(types.UID(podInfraContainerID)); kerr != nil {
+					glog.Errorf("Failed to kill infra container without networking for pod %q: %v", podFullName, kerr)
+				}
+				kl.runtimeCache.InvalidatePod(pod.UID)
+			}
 		}
 		if err != nil {
 			glog.Errorf("Failed to create pod infra container: %v; Skipping pod %q", err, podFullName)
-			return err
+			return syncResult.Error()
+		}
+
+		// The new infra container determines the pod IP seen by the other
+		// containers, e.g. in their hosts file.
+		inspect, ierr := kl.dockerClient.InspectContainer(string(podInfraContainerID))
+		if ierr == nil && inspect.NetworkSettings != nil {
+			podIP = inspect.NetworkSettings.IPAddress
+		}
+
+		// Sysctls are applied through the infra container's namespaces, so
+		// they must be set before any other container starts.
+		if len(pod.Spec.Sysctls) > 0 {
+			if ierr == nil {
+				err = kl.applySysctls(pod, inspect.State.Pid)
+			} else {
+				err = ierr
+			}
+			if err != nil {
+				kl.recorder.Eventf(pod, "failedSysctl", "Failed to set sysctls: %v", err)
+				glog.Errorf("Failed to set sysctls for pod %q: %v; Skipping pod", podFullName, err)
+				sysctlResult := kubecontainer.NewSyncResult(kubecontainer.ApplySysctls, podFullName)
+				sysctlResult.Fail(err, "")
+				syncResult.AddSyncResult(sysctlResult)
+				return syncResult.Error()
+			}
+		}
+	}
+
+	// Run the init containers to completion, in order, before starting any
+	// of the regular containers.
+	if len(containerChanges.containersToStart) > 0 {
+		if err := kl.runInitContainers(pod, podInfraContainerID, podIP, &podStatus, containerChanges.startInfraContainer, &syncResult, cancel); err != nil {
+			if ref != nil {
+				kl.recorder.Eventf(ref, "failedInit", "Failed to run init containers: %v", err)
+			}
+			glog.Errorf("Failed to run init containers for pod %q: %v; Skipping pod", podFullName, err)
+			return syncResult.Error()
 		}
 	}
 
 	// Start everything
 	for container := range containerChanges.containersToStart {
+		if syncCanceled(cancel) {
+			syncResult.Fail(errSyncCanceled)
+			return syncResult.Error()
+		}
 		glog.V(4).Infof("Creating container %+v", pod.Spec.Containers[container])
-		kl.pullImageAndRunContainer(pod, &pod.Spec.Containers[container], podInfraContainerID)
+		_, runResult := kl.pullImageAndRunContainer(pod, &pod.Spec.Containers[container], podInfraContainerID, podIP, cancel)
+		syncResult.AddSyncResult(runResult.SyncResults...)
+	}
+	if containerChanges.startInfraContainer || len(containerChanges.containersToStart) > 0 {
+		// Newly created containers do not show up in the runtime cache until
+		// it is refreshed.
+		kl.runtimeCache.InvalidatePod(pod.UID)
 	}
 
 	if isStaticPod(pod) {
@@ -1352,10 +2377,10 @@ func (kl *Kubelet) syncPod(pod *api.Pod, mirrorPod *api.Pod, runningPod kubecont
 			// Pod status update is edge-triggered. If there is any update of the
 			// mirror pod, we need to delete the existing status associated with
 			// the static pod to trigger an update.
-			kl.statusManager.DeletePodStatus(podFullName)
+			kl.statusManager.DeletePodStatus(pod.UID)
 		}
 	}
-	return nil
+	return syncResult.Error()
 }
 
 // Stores all volumes defined by the set of pods into a map.
@@ -1392,6 +2417,65 @@ func (kl *Kubelet) cleanupOrphanedPodDirs(pods []*api.Pod) error {
 	return utilErrors.NewAggregate(errlist)
 }
 
+// cleanupOrphanedPods is the periodic housekeeping pass behind SyncPods: it
+// removes the directories of pods that are neither known to the pod manager
+// nor running, e.g. ones left behind by a kubelet crash. Volumes that are
+// still mounted under such a directory are torn down first, since removing
+// the directory over a live mount could destroy the data on the volume.
+func (kl *Kubelet) cleanupOrphanedPods() {
+	found, err := kl.listPodsFromDisk()
+	if err != nil {
+		glog.Errorf("Failed to list pod directories: %v", err)
+		metrics.OrphanedPodCleanupFailures.Inc()
+		return
+	}
+	known := util.NewStringSet()
+	for _, pod := range kl.podManager.GetPods() {
+		known.Insert(string(pod.UID))
+	}
+	runningPods, err := kl.runtimeCache.GetPods()
+	if err != nil {
+		glog.Errorf("Failed to list running pods: %v", err)
+		metrics.OrphanedPodCleanupFailures.Inc()
+		return
+	}
+	for _, pod := range runningPods {
+		known.Insert(string(pod.ID))
+	}
+
+	var mountedVolumes map[string]volume.Cleaner
+	for _, uid := range found {
+		if known.Has(string(uid)) {
+			continue
+		}
+		glog.Warningf("Orphaned pod %q found, cleaning up", uid)
+		if mountedVolumes == nil {
+			mountedVolumes = kl.getPodVolumesFromDisk()
+		}
+		failedTeardown := false
+		for name, cleaner := range mountedVolumes {
+			if !strings.HasPrefix(name, string(uid)+"/") {
+				continue
+			}
+			if err := cleaner.TearDown(); err != nil {
+				glog.Errorf("Could not tear down volume %q of orphaned pod %q: %v", name, uid, err)
+				metrics.OrphanedPodCleanupFailures.Inc()
+				failedTeardown = true
+			}
+		}
+		if failedTeardown {
+			// Leave the pod directory in place; the next pass retries.
+			continue
+		}
+		kl.volumeManager.DeleteVolumes(uid)
+		kl.containerHistory.forget(uid)
+		if err := os.RemoveAll(kl.getPodDir(uid)); err != nil {
+			glog.Errorf("Failed to remove directory of orphaned pod %q: %v", uid, err)
+			metrics.OrphanedPodCleanupFailures.Inc()
+		}
+	}
+}
+
 // Compares the map of current volumes to the map of desired volumes.
 // If an active volume does not have a respective desired volume, clean it up.
 func (kl *Kubelet) cleanupOrphanedVolumes(pods []*api.Pod, running []*docker.Container) error {
@@ -1402,7 +2486,7 @@ func (kl *Kubelet) cleanupOrphanedVolumes(pods []*api.Pod, running []*docker.Con
 		if len(running[ix].Name) == 0 {
 			glog.V(2).Infof("Found running container ix=%d with info: %+v", ix, running[ix])
 		}
-		containerName, _, err := dockertools.ParseDockerName(running[ix].Name)
+		containerName, _, err := dockertools.GetContainerNameInfo(running[ix])
 		if err != nil {
 			continue
 		}
@@ -1430,13 +2514,40 @@ func (kl *Kubelet) cleanupOrphanedVolumes(pods []*api.Pod, running []*docker.Con
 	return nil
 }
 
+// podResourcesAreReclaimed returns whether all of the pod's containers are
+// dead and its volumes have been unmounted, i.e. whether it is safe to send
+// the final delete for the pod to the apiserver.
+func (kl *Kubelet) podResourcesAreReclaimed(pod *api.Pod, status api.PodStatus) bool {
+	for _, containerStatus := range status.ContainerStatuses {
+		if containerStatus.State.Running != nil {
+			return false
+		}
+	}
+	runningPod, err := kl.runtimeCache.GetPodByUID(pod.UID)
+	if err != nil {
+		// Assume the worst if we cannot inspect the runtime.
+		return false
+	}
+	if len(runningPod.Containers) > 0 {
+		glog.V(3).Infof("Pod %q is terminated, but some of its containers are still running", kubecontainer.GetPodFullName(pod))
+		return false
+	}
+	for name := range kl.getPodVolumesFromDisk() {
+		if strings.HasPrefix(name, string(pod.UID)+"/") {
+			glog.V(3).Infof("Pod %q is terminated, but volume %q has not been cleaned up", kubecontainer.GetPodFullName(pod), name)
+			return false
+		}
+	}
+	return true
+}
+
 // Filter out pods in the terminated state ("Failed" or "Succeeded").
 func (kl *Kubelet) filterOutTerminatedPods(allPods []*api.Pod) []*api.Pod {
 	var pods []*api.Pod
 	for _, pod := range allPods {
 		var status api.PodStatus
 		// Check the cached pod status which was set after the last sync.
-		status, ok := kl.statusManager.GetPodStatus(kubecontainer.GetPodFullName(pod))
+		status, ok := kl.statusManager.GetPodStatus(pod.UID)
 		if !ok {
 			// If there is no cached status, use the status from the
 			// apiserver. This is useful if kubelet has recently been
@@ -1459,12 +2570,15 @@ func (kl *Kubelet) SyncPods(allPods []*api.Pod, podSyncTypes map[types.UID]metri
 		metrics.SyncPodsLatency.Observe(metrics.SinceInMicroseconds(start))
 	}()
 
+	// Inspect results cached during the previous pass are stale now.
+	kl.containerManager.FlushInspectCache()
+
 	// Remove obsolete entries in podStatus where the pod is no longer considered bound to this node.
-	podFullNames := make(map[string]bool)
+	podUIDs := make(map[types.UID]bool)
 	for _, pod := range allPods {
-		podFullNames[kubecontainer.GetPodFullName(pod)] = true
+		podUIDs[pod.UID] = true
 	}
-	kl.statusManager.RemoveOrphanedStatuses(podFullNames)
+	kl.statusManager.RemoveOrphanedStatuses(podUIDs)
 
 	// Reject pods that we cannot run.
 	kl.handleNotFittingPods(allPods)
@@ -1497,13 +2611,29 @@ func (kl *Kubelet) SyncPods(allPods []*api.Pod, podSyncTypes map[types.UID]metri
 		uid := pod.UID
 		desiredPods[uid] = empty{}
 
+		// If the pod was deleted and recreated under the same name, its old
+		// incarnation may still have running containers. Hold off starting
+		// the new incarnation until they are gone, so the two never overlap
+		// on host ports, volumes or the pod directory. The old containers
+		// are killed below as unwanted, and the new pod is picked up on the
+		// next sync.
+		if obsolete := obsoleteRunningPod(pod, runningPods); obsolete != nil {
+			glog.Infof("Delaying start of pod %q (UID %q): waiting for previous incarnation (UID %q) to be killed", podFullName, uid, obsolete.ID)
+			continue
+		}
+
 		// Run the sync in an async manifest worker.
-		kl.podWorkers.UpdatePod(pod, mirrorPods[podFullName], func() {
-			metrics.SyncPodLatency.WithLabelValues(podSyncTypes[pod.UID].String()).Observe(metrics.SinceInMicroseconds(start))
+		syncType, seen := podSyncTypes[pod.UID]
+		kl.podWorkers.UpdatePod(pod, mirrorPods[podFullName], syncType, func() {
+			metrics.SyncPodLatency.WithLabelValues(syncType.String()).Observe(metrics.SinceInMicroseconds(start))
+			if seen && syncType == metrics.SyncPodCreate {
+				// The first successful sync of a new pod starts its containers.
+				metrics.PodStartLatency.Observe(metrics.SinceInMicroseconds(start))
+			}
 		})
 
 		// Note the number of containers for new pods.
-		if val, ok := podSyncTypes[pod.UID]; ok && (val == metrics.SyncPodCreate) {
+		if seen && syncType == metrics.SyncPodCreate {
 			metrics.ContainersPerPodCount.Observe(float64(len(pod.Spec.Containers)))
 		}
 	}
@@ -1517,9 +2647,22 @@ func (kl *Kubelet) SyncPods(allPods []*api.Pod, podSyncTypes map[types.UID]metri
 		return nil
 	}
 
+	// All sources have reported in; anchor the grace window during which
+	// running pods that none of them claimed may still be adopted.
+	if kl.sourcesReadyTime.IsZero() {
+		kl.sourcesReadyTime = time.Now()
+	}
+
+	// Full names claimed by desired pods; an unwanted running pod bearing
+	// one of these names is the previous incarnation of a recreated pod.
+	claimedNames := make(map[string]empty)
+	for _, pod := range pods {
+		claimedNames[kubecontainer.GetPodFullName(pod)] = empty{}
+	}
+
 	// Kill containers associated with unwanted pods and get a list of
 	// unwanted containers that are still running.
-	running, err := kl.killUnwantedPods(desiredPods, runningPods)
+	running, err := kl.killUnwantedPods(desiredPods, claimedNames, runningPods)
 	if err != nil {
 		glog.Errorf("Failed killing unwanted containers: %v", err)
 		return err
@@ -1545,9 +2688,22 @@ func (kl *Kubelet) SyncPods(allPods []*api.Pod, podSyncTypes map[types.UID]metri
 	return err
 }
 
+// obsoleteRunningPod returns a running pod that has the same name and
+// namespace as the given pod but a different UID, i.e. the still-running
+// previous incarnation of a pod that was deleted and recreated under the
+// same name.
+func obsoleteRunningPod(pod *api.Pod, runningPods []*kubecontainer.Pod) *kubecontainer.Pod {
+	for _, running := range runningPods {
+		if running.ID != pod.UID && running.Name == pod.Name && running.Namespace == pod.Namespace && len(running.Containers) != 0 {
+			return running
+		}
+	}
+	return nil
+}
+
 // killUnwantedPods kills the unwanted, running pods in parallel, and returns
 // containers in those pods that it failed to terminate.
-func (kl *Kubelet) killUnwantedPods(desiredPods map[types.UID]empty,
+func (kl *Kubelet) killUnwantedPods(desiredPods map[types.UID]empty, claimedNames map[string]empty,
 	runningPods []*kubecontainer.Pod) ([]*docker.Container, error) {
 	type result struct {
 		containers []*docker.Container
@@ -1558,9 +2714,36 @@ func (kl *Kubelet) killUnwantedPods(desiredPods map[types.UID]empty,
 	numWorkers := 0
 	for _, pod := range runningPods {
 		if _, found := desiredPods[pod.ID]; found {
+			if _, deferred := kl.adoptionCandidates[pod.ID]; deferred {
+				// A source claimed the pod before the grace window
+				// expired; hand it over to its per-pod worker.
+				delete(kl.adoptionCandidates, pod.ID)
+				glog.Infof("Pod %q (UID %q) was adopted by a pod source", pod.Name, pod.ID)
+				kl.recorder.Eventf(kl.nodeRef, "podAdopted", "Pod %q (UID %q) was adopted by a pod source", pod.Name, pod.ID)
+			}
 			// Per-pod workers will handle the desired pods.
 			continue
 		}
+		// A desired pod claiming the same full name means this pod was
+		// deleted and recreated; the leftover can never be adopted and
+		// blocks its successor, so it is killed without waiting out the
+		// adoption grace window.
+		_, claimed := claimedNames[kubecontainer.BuildPodFullName(pod.Name, pod.Namespace)]
+		if !claimed && time.Since(kl.sourcesReadyTime) < kl.adoptionGracePeriod {
+			// The pod may belong to a source that has not finished
+			// resyncing after a kubelet restart; leave it running so
+			// node restarts don't flap workloads.
+			if _, deferred := kl.adoptionCandidates[pod.ID]; !deferred {
+				kl.adoptionCandidates[pod.ID] = empty{}
+				glog.Infof("Found running pod %q (UID %q) not requested by any source; waiting up to %v for a source to adopt it", pod.Name, pod.ID, kl.adoptionGracePeriod)
+				kl.recorder.Eventf(kl.nodeRef, "unknownPod", "Found running pod %q (UID %q) not requested by any source; waiting up to %v for a source to adopt it", pod.Name, pod.ID, kl.adoptionGracePeriod)
+			}
+			continue
+		}
+		if _, deferred := kl.adoptionCandidates[pod.ID]; deferred {
+			delete(kl.adoptionCandidates, pod.ID)
+			kl.recorder.Eventf(kl.nodeRef, "fencingPod", "Fencing pod %q (UID %q): no source adopted it within %v", pod.Name, pod.ID, kl.adoptionGracePeriod)
+		}
 		numWorkers++
 		go func(pod *kubecontainer.Pod, ch chan result) {
 			defer func() {
@@ -1569,17 +2752,8 @@ func (kl *Kubelet) killUnwantedPods(desiredPods map[types.UID]empty,
 				ch <- result{containers: containers, err: err}
 			}()
 			glog.V(1).Infof("Killing unwanted pod %q", pod.Name)
-			// Stop the containers.
-			err := kl.killPod(*pod)
-			if err != nil {
-				glog.Errorf("Failed killing the pod %q: %v", pod.Name, err)
-				return
-			}
-			// Remove the pod directory.
-			err = os.RemoveAll(kl.getPodDir(pod.ID))
-			if err != nil {
-				glog.Errorf("Failed removing pod directory for %q", pod.Name)
-				return
+			if err := kl.teardownPod(pod); err != nil {
+				glog.Errorf("Failed tearing down the pod %q: %v", pod.Name, err)
 			}
 		}(pod, ch)
 	}
@@ -1606,94 +2780,53 @@ func (kl *Kubelet) getRunningContainersByPod(pod *kubecontainer.Pod) ([]*docker.
 	return kl.containerManager.GetRunningContainers(containerIDs)
 }
 
-type podsByCreationTime []*api.Pod
-
-func (s podsByCreationTime) Len() int {
-	return len(s)
-}
-
-func (s podsByCreationTime) Swap(i, j int) {
-	s[i], s[j] = s[j], s[i]
-}
-
-func (s podsByCreationTime) Less(i, j int) bool {
-	return s[i].CreationTimestamp.Before(s[j].CreationTimestamp)
-}
-
-// checkHostPortConflicts detects pods with conflicted host ports.
-func checkHostPortConflicts(pods []*api.Pod) (fitting []*api.Pod, notFitting []*api.Pod) {
-	ports := map[int]bool{}
-	extract := func(p *api.ContainerPort) int { return p.HostPort }
-
-	// Respect the pod creation order when resolving conflicts.
-	sort.Sort(podsByCreationTime(pods))
-
-	for _, pod := range pods {
-		if errs := validation.AccumulateUniquePorts(pod.Spec.Containers, ports, extract); len(errs) != 0 {
-			glog.Errorf("Pod %q: HostPort is already allocated, ignoring: %v", kubecontainer.GetPodFullName(pod), errs)
-			notFitting = append(notFitting, pod)
-			continue
-		}
-		fitting = append(fitting, pod)
-	}
-	return
-}
-
-// checkCapacityExceeded detects pods that exceeds node's resources.
-func (kl *Kubelet) checkCapacityExceeded(pods []*api.Pod) (fitting []*api.Pod, notFitting []*api.Pod) {
-	info, err := kl.GetCachedMachineInfo()
-	if err != nil {
-		glog.Errorf("error getting machine info: %v", err)
-		return pods, nil
-	}
-
-	// Respect the pod creation order when resolving conflicts.
-	sort.Sort(podsByCreationTime(pods))
-
-	capacity := CapacityFromMachineInfo(info)
-	return scheduler.CheckPodsExceedingCapacity(pods, capacity)
-}
+// safeSysctls are namespaced sysctls that cannot affect other pods or the
+// node and may therefore be requested by any pod.
+var safeSysctls = util.NewStringSet(
+	"kernel.shm_rmid_forced",
+	"net.ipv4.ip_local_port_range",
+	"net.ipv4.tcp_syncookies",
+)
 
-// checkNodeSelectorMatching detects pods that do not match node's labels.
-func (kl *Kubelet) checkNodeSelectorMatching(pods []*api.Pod) (fitting []*api.Pod, notFitting []*api.Pod) {
-	node, err := kl.GetNode()
-	if err != nil {
-		glog.Errorf("error getting node: %v", err)
-		return pods, nil
+// sysctlAllowed returns whether the named sysctl is safe or has been
+// explicitly allowed by the operator.
+func (kl *Kubelet) sysctlAllowed(name string) bool {
+	if safeSysctls.Has(name) {
+		return true
 	}
-	for _, pod := range pods {
-		if !scheduler.PodMatchesNodeLabels(pod, node) {
-			notFitting = append(notFitting, pod)
-			continue
+	for _, pattern := range kl.allowedUnsafeSysctls {
+		if strings.HasSuffix(pattern, "*") {
+			if strings.HasPrefix(name, strings.TrimSuffix(pattern, "*")) {
+				return true
+			}
+		} else if name == pattern {
+			return true
 		}
-		fitting = append(fitting, pod)
 	}
-	return
+	return false
 }
 
-// handleNotfittingPods handles pods that do not fit on the node.
-// Currently conflicts on Port.HostPort values, matching node's labels and exceeding node's capacity are handled.
+// handleNotFittingPods runs the desired pods through the node's admission
+// handlers and rejects those that do not fit, recording an event and a
+// failed pod status for each. A rejected pod is not consulted by the
+// remaining handlers, so it does not claim host ports or capacity.
 func (kl *Kubelet) handleNotFittingPods(pods []*api.Pod) {
-	fitting, notFitting := checkHostPortConflicts(pods)
-	for _, pod := range notFitting {
-		kl.recorder.Eventf(pod, "hostPortConflict", "Cannot start the pod due to host port conflict.")
-		kl.statusManager.SetPodStatus(pod, api.PodStatus{
-			Phase:   api.PodFailed,
-			Message: "Pod cannot be started due to host port conflict"})
-	}
-	fitting, notFitting = kl.checkNodeSelectorMatching(fitting)
-	for _, pod := range notFitting {
-		kl.recorder.Eventf(pod, "nodeSelectorMismatching", "Cannot start the pod due to node selector mismatch.")
-		kl.statusManager.SetPodStatus(pod, api.PodStatus{
-			Phase:   api.PodFailed,
-			Message: "Pod cannot be started due to node selector mismatch"})
-	}
-	fitting, notFitting = kl.checkCapacityExceeded(fitting)
-	for _, pod := range notFitting {
-		kl.recorder.Eventf(pod, "capacityExceeded", "Cannot start the pod due to exceeded capacity.")
-		kl.statusManager.SetPodStatus(pod, api.PodStatus{
-			Phase:   api.PodFailed,
-			Message: "Pod cannot be started due to exceeded capacity"})
+	// Resolve conflicts over shared resources such as host ports and
+	// capacity in favor of critical pods, then by pod creation order.
+	sort.Sort(podsByAdmissionOrder(pods))
+	handlers := kl.admissionHandlers()
+	for _, pod := range pods {
+		for _, handler := range handlers {
+			ok, reason, message := handler.Admit(pod)
+			if ok {
+				continue
+			}
+			kl.recorder.Eventf(pod, reason, "%s.", message)
+			kl.statusManager.SetPodStatus(pod, api.PodStatus{
+				Phase:   api.PodFailed,
+				Message: message})
+			break
+		}
 	}
 }
 
@@ -1719,6 +2852,7 @@ func (kl *Kubelet) syncLoop(updates <-chan PodUpdate, handler SyncHandler) {
 			glog.V(4).Infof("Periodic sync")
 		}
 		start := time.Now()
+		kl.syncLoopMonitor.Store(start)
 		// If we already caught some update, try to wait for some short time
 		// to possibly batch it with other incoming updates.
 		for unsyncedPod {
@@ -1753,11 +2887,18 @@ func (kl *Kubelet) validatePodPhase(podStatus *api.PodStatus) error {
 	return fmt.Errorf("pod is not in 'Running', 'Succeeded' or 'Failed' state - State: %q", podStatus.Phase)
 }
 
-func (kl *Kubelet) validateContainerStatus(podStatus *api.PodStatus, containerName string) (dockerID string, err error) {
+func (kl *Kubelet) validateContainerStatus(podStatus *api.PodStatus, containerName string, previous bool) (dockerID string, err error) {
 	cStatus, found := api.GetContainerStatus(podStatus.ContainerStatuses, containerName)
 	if !found {
 		return "", fmt.Errorf("container %q not found in pod", containerName)
 	}
+	if previous {
+		if cStatus.LastTerminationState.Termination == nil {
+			return "", fmt.Errorf("previous terminated container %q not found in pod", containerName)
+		}
+		cID := cStatus.LastTerminationState.Termination.ContainerID
+		return strings.Replace(cID, dockertools.DockerPrefix, "", 1), nil
+	}
 	if cStatus.State.Waiting != nil {
 		return "", fmt.Errorf("container %q is in waiting state.", containerName)
 	}
@@ -1767,7 +2908,7 @@ func (kl *Kubelet) validateContainerStatus(podStatus *api.PodStatus, containerNa
 // GetKubeletContainerLogs returns logs from the container
 // TODO: this method is returning logs of random container attempts, when it should be returning the most recent attempt
 // or all of them.
-func (kl *Kubelet) GetKubeletContainerLogs(podFullName, containerName, tail string, follow bool, stdout, stderr io.Writer) error {
+func (kl *Kubelet) GetKubeletContainerLogs(podFullName, containerName string, logOptions *api.PodLogOptions, stdout, stderr io.Writer) error {
 	podStatus, err := kl.GetPodStatus(podFullName)
 	if err != nil {
 		return fmt.Errorf("failed to get status for pod %q - %v", podFullName, err)
@@ -1776,13 +2917,13 @@ func (kl *Kubelet) GetKubeletContainerLogs(podFullName, containerName, tail stri
 		// No log is available if pod is not in a "known" phase (e.g. Unknown).
 		return err
 	}
-	dockerContainerID, err := kl.validateContainerStatus(&podStatus, containerName)
+	dockerContainerID, err := kl.validateContainerStatus(&podStatus, containerName, logOptions.Previous)
 	if err != nil {
 		// No log is available if the container status is missing or is in the
 		// waiting state.
 		return err
 	}
-	return kl.containerManager.GetKubeletDockerContainerLogs(dockerContainerID, tail, follow, stdout, stderr)
+	return kl.containerManager.GetKubeletDockerContainerLogs(dockerContainerID, logOptions, stdout, stderr)
 }
 
 // GetHostname Returns the hostname as the kubelet sees it.
@@ -1819,6 +2960,36 @@ func (kl *Kubelet) GetPods() []*api.Pod {
 	return kl.podManager.GetPods()
 }
 
+// GetRunningPods returns the pods that are actually running on this node, as
+// observed by the container runtime cache, converted to api.Pods. Only the
+// identity and container names are filled in; the specs are not available
+// from the runtime.
+func (kl *Kubelet) GetRunningPods() ([]*api.Pod, error) {
+	pods, err := kl.runtimeCache.GetPods()
+	if err != nil {
+		return nil, err
+	}
+
+	apiPods := make([]*api.Pod, 0, len(pods))
+	for _, pod := range pods {
+		apiPod := &api.Pod{
+			ObjectMeta: api.ObjectMeta{
+				Name:      pod.Name,
+				Namespace: pod.Namespace,
+				UID:       pod.ID,
+			},
+		}
+		for _, container := range pod.Containers {
+			apiPod.Spec.Containers = append(apiPod.Spec.Containers, api.Container{
+				Name:  container.Name,
+				Image: container.Image,
+			})
+		}
+		apiPods = append(apiPods, apiPod)
+	}
+	return apiPods, nil
+}
+
 func (kl *Kubelet) GetPodByFullName(podFullName string) (*api.Pod, bool) {
 	return kl.podManager.GetPodByFullName(podFullName)
 }
@@ -1829,19 +3000,109 @@ func (kl *Kubelet) GetPodByName(namespace, name string) (*api.Pod, bool) {
 	return kl.podManager.GetPodByName(namespace, name)
 }
 
-// updateNodeStatus updates node status to master with retries.
+// initialNode constructs the Node object this kubelet registers as.
+func (kl *Kubelet) initialNode() *api.Node {
+	node := &api.Node{
+		ObjectMeta: api.ObjectMeta{
+			Name:   kl.hostname,
+			Labels: kl.nodeLabels,
+		},
+		Spec: api.NodeSpec{
+			ExternalID:    kl.hostname,
+			Unschedulable: !kl.registerSchedulable,
+		},
+	}
+	if len(kl.nodeTaints) > 0 {
+		taints, err := json.Marshal(kl.nodeTaints)
+		if err != nil {
+			// The taints were validated when the flag was parsed.
+			glog.Errorf("Unable to marshal node taints: %v", err)
+			return node
+		}
+		node.Annotations = map[string]string{api.TaintsAnnotationKey: string(taints)}
+	}
+	return node
+}
+
+// registerWithApiserver registers the node with the apiserver, retrying with
+// backoff until it succeeds or finds the node already registered.
+func (kl *Kubelet) registerWithApiserver() {
+	step := 100 * time.Millisecond
+	for {
+		time.Sleep(step)
+		step = step * 2
+		if step >= 7*time.Second {
+			step = 7 * time.Second
+		}
+
+		node := kl.initialNode()
+		glog.V(2).Infof("Attempting to register node %s", node.Name)
+		if _, err := kl.kubeClient.Nodes().Create(node); err != nil {
+			if apierrors.IsAlreadyExists(err) {
+				glog.Infof("Node %s was previously registered", node.Name)
+				return
+			}
+			glog.Errorf("Unable to register %s with the apiserver: %v", node.Name, err)
+			continue
+		}
+		glog.Infof("Successfully registered node %s", node.Name)
+		return
+	}
+}
+
+// updateNodeStatus updates the full node status to master with retries.
 func (kl *Kubelet) updateNodeStatus() error {
+	return kl.updateNodeWithRetries(kl.tryUpdateNodeStatus)
+}
+
+// updateNodeHeartbeat refreshes the node's readiness heartbeat with retries,
+// falling back to a full status update when readiness changed.
+func (kl *Kubelet) updateNodeHeartbeat() error {
+	return kl.updateNodeWithRetries(kl.tryUpdateNodeHeartbeat)
+}
+
+// updateNodeWithRetries runs one node update attempt with retries.
+func (kl *Kubelet) updateNodeWithRetries(try func() error) error {
+	backoff := nodeStatusUpdateRetryBackoffInitial
 	for i := 0; i < nodeStatusUpdateRetry; i++ {
-		err := kl.tryUpdateNodeStatus()
-		if err != nil {
-			glog.Errorf("error updating node status, will retry: %v", err)
-		} else {
+		err := try()
+		if err == nil {
 			return nil
 		}
+		if apierrors.IsConflict(err) {
+			// Another writer updated the node since we fetched it; retry
+			// right away, each attempt starts from a fresh copy.
+			glog.V(2).Infof("conflict updating node status, will retry: %v", err)
+			continue
+		}
+		glog.Errorf("error updating node status, will retry: %v", err)
+		time.Sleep(backoff)
+		backoff *= 2
+		if backoff > nodeStatusUpdateRetryBackoffMax {
+			backoff = nodeStatusUpdateRetryBackoffMax
+		}
 	}
 	return fmt.Errorf("Update node status exceeds retry count")
 }
 
+// validateContainerRuntime checks that the container runtime answers and
+// speaks a supported API version. A daemon that is hung or too old must not
+// be advertised as ready to run pods.
+func (kl *Kubelet) validateContainerRuntime() error {
+	version, err := kl.containerManager.Version()
+	if err != nil {
+		return fmt.Errorf("docker daemon is not responding: %v", err)
+	}
+	result, err := version.Compare(minimumDockerAPIVersion)
+	if err != nil {
+		return fmt.Errorf("failed to compare docker API version %s against %s: %v", version, minimumDockerAPIVersion, err)
+	}
+	if result < 0 {
+		return fmt.Errorf("docker API version %s is too old; at least %s is required", version, minimumDockerAPIVersion)
+	}
+	return nil
+}
+
 func (kl *Kubelet) recordNodeOnlineEvent() {
 	// TODO: This requires a transaction, either both node status is updated
 	// and event is recorded or neither should happen, see issue #6055.
@@ -1860,6 +3121,35 @@ func (kl *Kubelet) recordNodeUnschedulableEvent() {
 	kl.recorder.Eventf(kl.nodeRef, "unschedulable", "Node %s is now unschedulable", kl.hostname)
 }
 
+// byImageSize sorts images by size, largest first.
+type byImageSize []docker.APIImages
+
+func (a byImageSize) Len() int           { return len(a) }
+func (a byImageSize) Swap(i, j int)      { a[i], a[j] = a[j], a[i] }
+func (a byImageSize) Less(i, j int) bool { return a[i].Size > a[j].Size }
+
+// imagesForNodeStatus converts the images reported by docker into the list
+// published in the node status. Untagged images are of no use to the
+// scheduler and are dropped, and only the nodeStatusMaxImages largest
+// images are kept to bound the size of the node object.
+func imagesForNodeStatus(images []docker.APIImages) []api.ContainerImage {
+	sort.Sort(byImageSize(images))
+	var result []api.ContainerImage
+	for _, image := range images {
+		if len(image.RepoTags) == 0 {
+			continue
+		}
+		result = append(result, api.ContainerImage{
+			Names:     image.RepoTags,
+			SizeBytes: image.Size,
+		})
+		if len(result) == nodeStatusMaxImages {
+			break
+		}
+	}
+	return result
+}
+
 // Maintains Node.Spec.Unschedulable value from previous run of tryUpdateNodeStatus()
 var oldNodeUnschedulable bool
 
@@ -1873,6 +3163,10 @@ func (kl *Kubelet) tryUpdateNodeStatus() error {
 		return fmt.Errorf("no node instance returned for %q", kl.hostname)
 	}
 
+	if err := kl.setNodeAddresses(node); err != nil {
+		glog.Errorf("error setting node addresses: %v", err)
+	}
+
 	// TODO: Post NotReady if we cannot get MachineInfo from cAdvisor. This needs to start
 	// cAdvisor locally, e.g. for test-cmd.sh, and in integration test.
 	info, err := kl.GetCachedMachineInfo()
@@ -1882,6 +3176,7 @@ func (kl *Kubelet) tryUpdateNodeStatus() error {
 		node.Status.NodeInfo.MachineID = info.MachineID
 		node.Status.NodeInfo.SystemUUID = info.SystemUUID
 		node.Status.Capacity = CapacityFromMachineInfo(info)
+		node.Status.Capacity[api.ResourcePods] = *resource.NewQuantity(int64(kl.maxPods), resource.DecimalSI)
 		if node.Status.NodeInfo.BootID != "" &&
 			node.Status.NodeInfo.BootID != info.BootID {
 			// TODO: This requires a transaction, either both node status is updated
@@ -1905,6 +3200,12 @@ func (kl *Kubelet) tryUpdateNodeStatus() error {
 		node.Status.NodeInfo.KubeProxyVersion = version.Get().String()
 	}
 
+	if images, err := kl.dockerClient.ListImages(docker.ListImagesOptions{}); err != nil {
+		glog.Errorf("error listing images: %v", err)
+	} else {
+		node.Status.Images = imagesForNodeStatus(images)
+	}
+
 	currentTime := util.Now()
 	newCondition := api.NodeCondition{
 		Type:              api.NodeReady,
@@ -1912,12 +3213,19 @@ func (kl *Kubelet) tryUpdateNodeStatus() error {
 		Reason:            fmt.Sprintf("kubelet is posting ready status"),
 		LastHeartbeatTime: currentTime,
 	}
+	if err := kl.validateContainerRuntime(); err != nil {
+		newCondition.Status = api.ConditionFalse
+		newCondition.Reason = fmt.Sprintf("container runtime is not ready: %v", err)
+	}
 	updated := false
 	for i := range node.Status.Conditions {
 		if node.Status.Conditions[i].Type == api.NodeReady {
 			newCondition.LastTransitionTime = node.Status.Conditions[i].LastTransitionTime
-			if node.Status.Conditions[i].Status != api.ConditionTrue {
-				kl.recordNodeOnlineEvent()
+			if node.Status.Conditions[i].Status != newCondition.Status {
+				newCondition.LastTransitionTime = currentTime
+				if newCondition.Status == api.ConditionTrue {
+					kl.recordNodeOnlineEvent()
+				}
 			}
 			node.Status.Conditions[i] = newCondition
 			updated = true
@@ -1926,7 +3234,14 @@ func (kl *Kubelet) tryUpdateNodeStatus() error {
 	if !updated {
 		newCondition.LastTransitionTime = currentTime
 		node.Status.Conditions = append(node.Status.Conditions, newCondition)
-		kl.recordNodeOnlineEvent()
+		if newCondition.Status == api.ConditionTrue {
+			kl.recordNodeOnlineEvent()
+		}
+	}
+
+	kl.setNodePressureConditions(node)
+	if kl.problemDetector != nil {
+		kl.problemDetector.setConditions(node)
 	}
 
 	if oldNodeUnschedulable != node.Spec.Unschedulable {
@@ -1937,10 +3252,241 @@ func (kl *Kubelet) tryUpdateNodeStatus() error {
 		}
 		oldNodeUnschedulable = node.Spec.Unschedulable
 	}
+
+	// Skip the write when the status has been reported unchanged often
+	// enough; the readiness heartbeat keeps proving liveness in the
+	// meantime, and any change resets the count below.
+	status := comparableNodeStatus(node.Status)
+	if reflect.DeepEqual(status, kl.lastNodeStatus) {
+		kl.identicalNodeStatusReports++
+		if kl.identicalNodeStatusReports > nodeStatusIdenticalReportLimit {
+			glog.V(4).Infof("Node status unchanged after %d reports, skipping update", nodeStatusIdenticalReportLimit)
+			return nil
+		}
+	} else {
+		kl.identicalNodeStatusReports = 0
+	}
 	_, err = kl.kubeClient.Nodes().UpdateStatus(node)
+	if err == nil {
+		kl.lastNodeStatus = status
+	}
 	return err
 }
 
+// comparableNodeStatus returns a copy of the status with the per-condition
+// timestamps cleared, so that two statuses can be compared for real changes.
+// Transition times can be cleared along with the heartbeats because a
+// transition always comes with a condition status change.
+func comparableNodeStatus(status api.NodeStatus) api.NodeStatus {
+	status.Conditions = append([]api.NodeCondition{}, status.Conditions...)
+	for i := range status.Conditions {
+		status.Conditions[i].LastHeartbeatTime = util.Time{}
+		status.Conditions[i].LastTransitionTime = util.Time{}
+	}
+	return status
+}
+
+// tryUpdateNodeHeartbeat bumps the heartbeat time of the node's ready
+// condition without recomputing the rest of the status. The heartbeat only
+// needs to prove the kubelet is alive, so it checks nothing beyond the
+// container runtime; when readiness itself changed, or the node has no ready
+// condition yet, the cheap path is abandoned and a full status update is
+// sent instead so the transition is published immediately.
+func (kl *Kubelet) tryUpdateNodeHeartbeat() error {
+	node, err := kl.kubeClient.Nodes().Get(kl.hostname)
+	if err != nil {
+		return fmt.Errorf("error getting node %q: %v", kl.hostname, err)
+	}
+	if node == nil {
+		return fmt.Errorf("no node instance returned for %q", kl.hostname)
+	}
+	ready := api.ConditionTrue
+	if err := kl.validateContainerRuntime(); err != nil {
+		ready = api.ConditionFalse
+	}
+	for i := range node.Status.Conditions {
+		if node.Status.Conditions[i].Type != api.NodeReady {
+			continue
+		}
+		if node.Status.Conditions[i].Status == ready {
+			node.Status.Conditions[i].LastHeartbeatTime = util.Now()
+			_, err = kl.kubeClient.Nodes().UpdateStatus(node)
+			return err
+		}
+		break
+	}
+	return kl.tryUpdateNodeStatus()
+}
+
+// setNodeAddresses fills in the addresses of the node, preferring in order:
+// the --node-ip override, the addresses reported by the cloud provider, and
+// finally resolution of the hostname on the local host.
+func (kl *Kubelet) setNodeAddresses(node *api.Node) error {
+	if kl.nodeIP != nil {
+		node.Status.Addresses = []api.NodeAddress{
+			{Type: api.NodeInternalIP, Address: kl.nodeIP.String()},
+			{Type: api.NodeLegacyHostIP, Address: kl.nodeIP.String()},
+		}
+		return nil
+	}
+	if kl.cloud != nil {
+		instances, ok := kl.cloud.Instances()
+		if !ok {
+			return fmt.Errorf("failed to get instances from cloud provider")
+		}
+		addresses, err := instances.NodeAddresses(kl.hostname)
+		if err != nil {
+			return fmt.Errorf("failed to get node addresses from cloud provider: %v", err)
+		}
+		node.Status.Addresses = addresses
+		return nil
+	}
+	addr := net.ParseIP(kl.hostname)
+	if addr == nil {
+		if addrs, err := net.LookupIP(kl.hostname); err == nil {
+			for _, a := range addrs {
+				if a.To4() != nil {
+					addr = a
+					break
+				}
+			}
+		}
+	}
+	if addr == nil {
+		ip, err := util.ChooseHostInterface()
+		if err != nil {
+			return fmt.Errorf("failed to choose an IP for node %q: %v", kl.hostname, err)
+		}
+		addr = ip
+	}
+	node.Status.Addresses = []api.NodeAddress{
+		{Type: api.NodeInternalIP, Address: addr.String()},
+		{Type: api.NodeLegacyHostIP, Address: addr.String()},
+	}
+	return nil
+}
+
+// setNodeCondition updates the condition of the given type in the node status,
+// preserving the transition time if the status did not change, and appends the
+// condition if it is not present yet.
+func setNodeCondition(node *api.Node, condition api.NodeCondition) {
+	for i := range node.Status.Conditions {
+		if node.Status.Conditions[i].Type == condition.Type {
+			if node.Status.Conditions[i].Status == condition.Status {
+				condition.LastTransitionTime = node.Status.Conditions[i].LastTransitionTime
+			}
+			node.Status.Conditions[i] = condition
+			return
+		}
+	}
+	node.Status.Conditions = append(node.Status.Conditions, condition)
+}
+
+// setNodePressureConditions computes and publishes the MemoryPressure,
+// DiskPressure and PIDPressure conditions so the scheduler can steer pods
+// away from pressured nodes. Conditions whose inputs cannot be determined
+// are reported as Unknown rather than guessed.
+func (kl *Kubelet) setNodePressureConditions(node *api.Node) {
+	currentTime := util.Now()
+
+	memoryPressure := api.NodeCondition{
+		Type:               api.NodeMemoryPressure,
+		Status:             api.ConditionUnknown,
+		Reason:             "KubeletMemoryUnknown",
+		Message:            "kubelet could not determine available memory",
+		LastHeartbeatTime:  currentTime,
+		LastTransitionTime: currentTime,
+	}
+	diskPressure := api.NodeCondition{
+		Type:               api.NodeDiskPressure,
+		Status:             api.ConditionUnknown,
+		Reason:             "KubeletDiskUnknown",
+		Message:            "kubelet could not determine disk usage",
+		LastHeartbeatTime:  currentTime,
+		LastTransitionTime: currentTime,
+	}
+
+	info, err := kl.GetCachedMachineInfo()
+	if err != nil {
+		glog.Errorf("error getting machine info for pressure conditions: %v", err)
+		info = nil
+	}
+	infos, err := kl.GetRawContainerInfo("/", &cadvisorApi.ContainerInfoRequest{NumStats: 1}, false)
+	if err != nil {
+		glog.Errorf("error getting root container stats for pressure conditions: %v", err)
+	} else if rootInfo, found := infos["/"]; found && len(rootInfo.Stats) > 0 {
+		stats := rootInfo.Stats[len(rootInfo.Stats)-1]
+		if info != nil && info.MemoryCapacity > 0 {
+			if stats.Memory.WorkingSet*100 >= uint64(info.MemoryCapacity)*memoryPressureThresholdPercent {
+				memoryPressure.Status = api.ConditionTrue
+				memoryPressure.Reason = "KubeletHasInsufficientMemory"
+				memoryPressure.Message = fmt.Sprintf("memory usage is over %d%% of capacity", memoryPressureThresholdPercent)
+			} else {
+				memoryPressure.Status = api.ConditionFalse
+				memoryPressure.Reason = "KubeletHasSufficientMemory"
+				memoryPressure.Message = "kubelet has sufficient memory available"
+			}
+		}
+		diskPressure.Status = api.ConditionFalse
+		diskPressure.Reason = "KubeletHasNoDiskPressure"
+		diskPressure.Message = "kubelet has no disk pressure"
+		for _, fs := range stats.Filesystem {
+			if fs.Limit > 0 && fs.Usage*100 >= fs.Limit*diskPressureThresholdPercent {
+				diskPressure.Status = api.ConditionTrue
+				diskPressure.Reason = "KubeletHasDiskPressure"
+				diskPressure.Message = fmt.Sprintf("filesystem %s is over %d%% full", fs.Device, diskPressureThresholdPercent)
+				break
+			}
+		}
+	}
+	setNodeCondition(node, memoryPressure)
+	setNodeCondition(node, diskPressure)
+
+	pidPressure := api.NodeCondition{
+		Type:               api.NodePIDPressure,
+		Status:             api.ConditionUnknown,
+		Reason:             "KubeletPIDUnknown",
+		Message:            "kubelet could not determine process ID usage",
+		LastHeartbeatTime:  currentTime,
+		LastTransitionTime: currentTime,
+	}
+	if used, max, err := processIDUsage(); err != nil {
+		glog.Errorf("error getting process ID usage for pressure conditions: %v", err)
+	} else if used*100 >= max*pidPressureThresholdPercent {
+		pidPressure.Status = api.ConditionTrue
+		pidPressure.Reason = "KubeletHasInsufficientPID"
+		pidPressure.Message = fmt.Sprintf("%d of %d process IDs in use", used, max)
+	} else {
+		pidPressure.Status = api.ConditionFalse
+		pidPressure.Reason = "KubeletHasSufficientPID"
+		pidPressure.Message = "kubelet has sufficient PID available"
+	}
+	setNodeCondition(node, pidPressure)
+}
+
+// processIDUsage returns the number of process IDs in use on the host and the
+// kernel's limit on them.
+func processIDUsage() (used, max int64, err error) {
+	content, err := ioutil.ReadFile("/proc/sys/kernel/pid_max")
+	if err != nil {
+		return 0, 0, err
+	}
+	max, err = strconv.ParseInt(strings.TrimSpace(string(content)), 10, 64)
+	if err != nil {
+		return 0, 0, err
+	}
+	entries, err := ioutil.ReadDir("/proc")
+	if err != nil {
+		return 0, 0, err
+	}
+	for _, entry := range entries {
+		if _, err := strconv.Atoi(entry.Name()); err == nil {
+			used++
+		}
+	}
+	return used, max, nil
+}
+
 // getPhase returns the phase of a pod given its container info.
 func getPhase(spec *api.PodSpec, info []api.ContainerStatus) api.PodPhase {
 	running := 0
@@ -1949,7 +3495,19 @@ func getPhase(spec *api.PodSpec, info []api.ContainerStatus) api.PodPhase {
 	failed := 0
 	succeeded := 0
 	unknown := 0
+	primaries := 0
+	for _, container := range spec.Containers {
+		if !container.Sidecar {
+			primaries++
+		}
+	}
 	for _, container := range spec.Containers {
+		if container.Sidecar && primaries > 0 {
+			// Sidecars run for the benefit of the primary containers and are
+			// torn down once the primaries are done; they carry no weight in
+			// the pod phase.
+			continue
+		}
 		if containerStatus, ok := api.GetContainerStatus(info, container.Name); ok {
 			if containerStatus.State.Running != nil {
 				running++
@@ -2030,16 +3588,15 @@ func getPodReadyCondition(spec *api.PodSpec, statuses []api.ContainerStatus) []a
 
 // GetPodStatus returns information from Docker about the containers in a pod
 func (kl *Kubelet) GetPodStatus(podFullName string) (api.PodStatus, error) {
-	// Check to see if we have a cached version of the status.
-	cachedPodStatus, found := kl.statusManager.GetPodStatus(podFullName)
-	if found {
-		glog.V(3).Infof("Returning cached status for %q", podFullName)
-		return cachedPodStatus, nil
-	}
 	pod, found := kl.GetPodByFullName(podFullName)
 	if !found {
 		return api.PodStatus{}, fmt.Errorf("couldn't find pod %q", podFullName)
 	}
+	// Check to see if we have a cached version of the status.
+	if cachedPodStatus, found := kl.statusManager.GetPodStatus(pod.UID); found {
+		glog.V(3).Infof("Returning cached status for %q", podFullName)
+		return cachedPodStatus, nil
+	}
 	return kl.generatePodStatus(pod)
 }
 
@@ -2067,6 +3624,10 @@ func (kl *Kubelet) generatePodStatus(pod *api.Pod) (api.PodStatus, error) {
 		}
 	}
 
+	// Fold in persisted history so restart counts and last terminations
+	// survive container garbage collection and kubelet restarts.
+	kl.containerHistory.reconcile(pod.UID, podStatus.ContainerStatuses)
+
 	// Assume info is ready to process
 	podStatus.Phase = getPhase(spec, podStatus.ContainerStatuses)
 	for _, c := range spec.Containers {
@@ -2080,6 +3641,28 @@ func (kl *Kubelet) generatePodStatus(pod *api.Pod) (api.PodStatus, error) {
 	}
 	podStatus.Conditions = append(podStatus.Conditions, getPodReadyCondition(spec, podStatus.ContainerStatuses)...)
 
+	// The pod IP is only reported while the pod infra container is running;
+	// carry the last known value forward so the IP does not flap while
+	// containers are being restarted.
+	if podStatus.PodIP == "" {
+		if cachedStatus, found := kl.statusManager.GetPodStatus(pod.UID); found && cachedStatus.PodIP != "" {
+			podStatus.PodIP = cachedStatus.PodIP
+		} else {
+			podStatus.PodIP = pod.Status.PodIP
+		}
+	}
+
+	// Stamp the start time the first time the pod is observed past Pending,
+	// and preserve it on subsequent syncs.
+	if cachedStatus, found := kl.statusManager.GetPodStatus(pod.UID); found && cachedStatus.StartTime != nil {
+		podStatus.StartTime = cachedStatus.StartTime
+	} else if pod.Status.StartTime != nil {
+		podStatus.StartTime = pod.Status.StartTime
+	} else if podStatus.Phase == api.PodRunning || podStatus.Phase == api.PodSucceeded || podStatus.Phase == api.PodFailed {
+		now := util.Now()
+		podStatus.StartTime = &now
+	}
+
 	hostIP, err := kl.GetHostIP()
 	if err != nil {
 		glog.Errorf("Cannot get host IP: %v", err)
@@ -2090,6 +3673,94 @@ func (kl *Kubelet) generatePodStatus(pod *api.Pod) (api.PodStatus, error) {
 	return *podStatus, nil
 }
 
+// cachedPodStatus returns the pod's status from the asynchronously refreshed
+// status cache, falling back to inline generation when there is no cached
+// entry or the entry no longer agrees with the running containers.
+func (kl *Kubelet) cachedPodStatus(pod *api.Pod, runningPod kubecontainer.Pod) (api.PodStatus, error) {
+	if status, found := kl.podStatusCache.Get(pod.UID); found && statusMatchesRunningPod(&status, runningPod) {
+		return status, nil
+	}
+	status, err := kl.generatePodStatus(pod)
+	if err == nil {
+		kl.podStatusCache.Set(pod.UID, status)
+	}
+	return status, err
+}
+
+// statusMatchesRunningPod reports whether a cached status still agrees with
+// the currently running containers, i.e. no container has started or died
+// since the status was generated.
+func statusMatchesRunningPod(status *api.PodStatus, runningPod kubecontainer.Pod) bool {
+	running := make(map[string]bool, len(runningPod.Containers))
+	for _, c := range runningPod.Containers {
+		// The pod infra container never shows up in the status.
+		if c.Name == dockertools.PodInfraContainerName {
+			continue
+		}
+		running[string(c.ID)] = true
+	}
+	runningInStatus := 0
+	for _, st := range status.ContainerStatuses {
+		if st.State.Running == nil {
+			continue
+		}
+		if !running[strings.TrimPrefix(st.ContainerID, "docker://")] {
+			return false
+		}
+		runningInStatus++
+	}
+	return runningInStatus == len(running)
+}
+
+// reconcilePodStatuses periodically regenerates the cached status of every
+// known pod outside the sync loop, and drops entries for pods that no longer
+// exist.
+func (kl *Kubelet) reconcilePodStatuses() {
+	pods := kl.podManager.GetPods()
+	desiredPods := make(map[types.UID]empty, len(pods))
+	for _, pod := range pods {
+		desiredPods[pod.UID] = empty{}
+		status, err := kl.generatePodStatus(pod)
+		if err != nil {
+			glog.Errorf("Unable to generate status for pod %q: %v", kubecontainer.GetPodFullName(pod), err)
+			continue
+		}
+		kl.podStatusCache.Set(pod.UID, status)
+	}
+	kl.podStatusCache.Prune(desiredPods)
+}
+
+// ResyncInterval returns the interval used for periodic syncs.
+func (kl *Kubelet) ResyncInterval() time.Duration {
+	return kl.resyncInterval
+}
+
+// SyncPodNow queues an immediate sync of the given pod and waits for the
+// pod worker to complete it, for the /debug/sync endpoint.
+func (kl *Kubelet) SyncPodNow(pod *api.Pod) error {
+	_, mirrorPods := kl.podManager.GetPodsAndMirrorMap()
+	done := make(chan struct{})
+	kl.podWorkers.UpdatePod(pod, mirrorPods[kubecontainer.GetPodFullName(pod)], metrics.SyncPodSync, func() {
+		close(done)
+	})
+	select {
+	case <-done:
+		return nil
+	case <-time.After(podResyncTimeout):
+		return fmt.Errorf("timed out waiting for pod %q to sync", kubecontainer.GetPodFullName(pod))
+	}
+}
+
+// LatestLoopEntryTime returns the wall time of the most recent sync loop
+// iteration, or the zero time if the loop has not started yet.
+func (kl *Kubelet) LatestLoopEntryTime() time.Time {
+	val := kl.syncLoopMonitor.Load()
+	if val == nil {
+		return time.Time{}
+	}
+	return val.(time.Time)
+}
+
 // Returns logs of current machine.
 func (kl *Kubelet) ServeLogs(w http.ResponseWriter, req *http.Request) {
 	// TODO: whitelist logs we are willing to serve
@@ -2143,9 +3814,27 @@ func (kl *Kubelet) ExecInContainer(podFullName string, podUID types.UID, contain
 	return kl.runner.ExecInContainer(string(container.ID), cmd, stdin, stdout, stderr, tty)
 }
 
+// AttachContainer attaches to a container, connecting the supplied
+// stdin/stdout/stderr to the container's stdio streams.
+func (kl *Kubelet) AttachContainer(podFullName string, podUID types.UID, containerName string, stdin io.Reader, stdout, stderr io.WriteCloser, tty bool) error {
+	podUID = kl.podManager.TranslatePodUID(podUID)
+
+	if kl.runner == nil {
+		return fmt.Errorf("no runner specified.")
+	}
+	container, err := kl.findContainer(podFullName, podUID, containerName)
+	if err != nil {
+		return err
+	}
+	if container == nil {
+		return fmt.Errorf("container not found (%q)", containerName)
+	}
+	return kl.runner.AttachContainer(string(container.ID), stdin, stdout, stderr, tty)
+}
+
 // PortForward connects to the pod's port and copies data between the port
 // and the stream.
-func (kl *Kubelet) PortForward(podFullName string, podUID types.UID, port uint16, stream io.ReadWriteCloser) error {
+func (kl *Kubelet) PortForward(podFullName string, podUID types.UID, port uint16, protocol string, stream io.ReadWriteCloser) error {
 	podUID = kl.podManager.TranslatePodUID(podUID)
 
 	if kl.runner == nil {
@@ -2157,7 +3846,7 @@ func (kl *Kubelet) PortForward(podFullName string, podUID types.UID, port uint16
 		return err
 	}
 	pod := kubecontainer.Pods(pods).FindPod(podFullName, podUID)
-	return kl.runner.PortForward(&pod, port, stream)
+	return kl.runner.PortForward(&pod, port, protocol, stream)
 }
 
 // BirthCry sends an event that the kubelet has started up.
@@ -2187,19 +3876,38 @@ func (kl *Kubelet) GetContainerInfo(podFullName string, uid types.UID, container
 		return nil, ErrContainerNotFound
 	}
 
-	ci, err := kl.cadvisor.DockerContainer(dockerContainer.ID, req)
+	ci, err := kl.statsProvider.DockerContainer(dockerContainer.ID, req)
 	if err != nil {
 		return nil, err
 	}
+	// All containers of a pod share the infra container's network namespace,
+	// so per-app-container network counters are meaningless; report the
+	// infra container's interface stats for every container of the pod.
+	if containerName != dockertools.PodInfraContainerName {
+		if infraContainer, found, _ := dockerContainers.FindPodContainer(podFullName, uid, dockertools.PodInfraContainerName); found {
+			if infraInfo, err := kl.statsProvider.DockerContainer(infraContainer.ID, req); err == nil {
+				overlayNetworkStats(&ci, &infraInfo)
+			}
+		}
+	}
 	return &ci, nil
 }
 
+// overlayNetworkStats replaces the network counters in info's samples with
+// those of the pod infra container, aligning the samples from the newest
+// backwards.
+func overlayNetworkStats(info, infra *cadvisorApi.ContainerInfo) {
+	for i, j := len(info.Stats)-1, len(infra.Stats)-1; i >= 0 && j >= 0; i, j = i-1, j-1 {
+		info.Stats[i].Network = infra.Stats[j].Network
+	}
+}
+
 // Returns stats (from Cadvisor) for a non-Kubernetes container.
 func (kl *Kubelet) GetRawContainerInfo(containerName string, req *cadvisorApi.ContainerInfoRequest, subcontainers bool) (map[string]*cadvisorApi.ContainerInfo, error) {
 	if subcontainers {
 		return kl.cadvisor.SubcontainerInfo(containerName, req)
 	} else {
-		containerInfo, err := kl.cadvisor.ContainerInfo(containerName, req)
+		containerInfo, err := kl.statsProvider.ContainerInfo(containerName, req)
 		if err != nil {
 			return nil, err
 		}
@@ -2209,8 +3917,12 @@ func (kl *Kubelet) GetRawContainerInfo(containerName string, req *cadvisorApi.Co
 	}
 }
 
-// GetCachedMachineInfo assumes that the machine info can't change without a reboot
+// GetCachedMachineInfo returns the last known machine info. It is refreshed
+// periodically, so capacity changes such as hot-added memory are eventually
+// reflected.
 func (kl *Kubelet) GetCachedMachineInfo() (*cadvisorApi.MachineInfo, error) {
+	kl.machineInfoLock.Lock()
+	defer kl.machineInfoLock.Unlock()
 	if kl.machineInfo == nil {
 		info, err := kl.cadvisor.MachineInfo()
 		if err != nil {
@@ -2221,8 +3933,37 @@ func (kl *Kubelet) GetCachedMachineInfo() (*cadvisorApi.MachineInfo, error) {
 	return kl.machineInfo, nil
 }
 
-func (kl *Kubelet) ListenAndServe(address net.IP, port uint, tlsOptions *TLSOptions, enableDebuggingHandlers bool) {
-	ListenAndServeKubeletServer(kl, address, port, tlsOptions, enableDebuggingHandlers)
+// GetVersionInfo returns host and cadvisor version information from cadvisor.
+func (kl *Kubelet) GetVersionInfo() (*cadvisorApi.VersionInfo, error) {
+	return kl.cadvisor.VersionInfo()
+}
+
+// refreshMachineInfo re-queries cadvisor for machine info and records an
+// event when the machine's capacity has changed. The next node status update
+// publishes the new capacity.
+func (kl *Kubelet) refreshMachineInfo() {
+	info, err := kl.cadvisor.MachineInfo()
+	if err == cadvisor.ErrDisabled {
+		return
+	}
+	if err != nil {
+		glog.Errorf("error refreshing machine info: %v", err)
+		return
+	}
+	kl.machineInfoLock.Lock()
+	old := kl.machineInfo
+	kl.machineInfo = info
+	kl.machineInfoLock.Unlock()
+	if old != nil && (old.NumCores != info.NumCores || old.MemoryCapacity != info.MemoryCapacity) {
+		glog.Infof("Machine capacity changed: %d cores, %d bytes of memory (was %d cores, %d bytes)",
+			info.NumCores, info.MemoryCapacity, old.NumCores, old.MemoryCapacity)
+		kl.recorder.Eventf(kl.nodeRef, "nodeCapacityChanged",
+			"Node capacity is now %d cores and %d bytes of memory", info.NumCores, info.MemoryCapacity)
+	}
+}
+
+func (kl *Kubelet) ListenAndServe(address net.IP, port uint, tlsOptions *TLSOptions, auth AuthInterface, enableDebuggingHandlers bool) {
+	ListenAndServeKubeletServer(kl, address, port, tlsOptions, auth, enableDebuggingHandlers)
 }
 
 func (kl *Kubelet) ListenAndServeReadOnly(address net.IP, port uint) {
@@ -22,6 +22,7 @@ import (
 	"fmt"
 	"io"
 	"io/ioutil"
+	"net"
 	"net/http"
 	"os"
 	"path"
@@ -45,6 +46,7 @@ import (
 	"github.com/GoogleCloudPlatform/kubernetes/pkg/kubelet/dockertools"
 	"github.com/GoogleCloudPlatform/kubernetes/pkg/kubelet/metrics"
 	"github.com/GoogleCloudPlatform/kubernetes/pkg/kubelet/network"
+	"github.com/GoogleCloudPlatform/kubernetes/pkg/runtime"
 	"github.com/GoogleCloudPlatform/kubernetes/pkg/types"
 	"github.com/GoogleCloudPlatform/kubernetes/pkg/util"
 	"github.com/GoogleCloudPlatform/kubernetes/pkg/version"
@@ -52,6 +54,7 @@ import (
 	_ "github.com/GoogleCloudPlatform/kubernetes/pkg/volume/host_path"
 	"github.com/fsouza/go-dockerclient"
 	cadvisorApi "github.com/google/cadvisor/info/v1"
+	cadvisorApiV2 "github.com/google/cadvisor/info/v2"
 )
 
 func init() {
@@ -69,7 +72,11 @@ type TestKubelet struct {
 }
 
 func newTestKubelet(t *testing.T) *TestKubelet {
-	fakeDocker := &dockertools.FakeDockerClient{Errors: make(map[string]error), RemovedImages: util.StringSet{}}
+	fakeDocker := &dockertools.FakeDockerClient{
+		Errors:        make(map[string]error),
+		RemovedImages: util.StringSet{},
+		VersionInfo:   docker.Env{"Version=1.5.0", "ApiVersion=1.18"},
+	}
 	fakeRecorder := &record.FakeRecorder{}
 	fakeKubeClient := &testclient.Fake{}
 	kubelet := &Kubelet{}
@@ -89,33 +96,39 @@ func newTestKubelet(t *testing.T) *TestKubelet {
 	}
 	waitGroup := new(sync.WaitGroup)
 	kubelet.sourcesReady = func() bool { return true }
+	kubelet.adoptionCandidates = make(map[types.UID]empty)
 	kubelet.masterServiceNamespace = api.NamespaceDefault
 	kubelet.serviceLister = testServiceLister{}
+	kubelet.enableServiceLinks = true
 	kubelet.nodeLister = testNodeLister{}
 	kubelet.readinessManager = kubecontainer.NewReadinessManager()
 	kubelet.recorder = fakeRecorder
 	kubelet.statusManager = newStatusManager(fakeKubeClient)
+	kubelet.podStatusCache = newPodStatusCache()
 	if err := kubelet.setupDataDirs(); err != nil {
 		t.Fatalf("can't initialize kubelet data dirs: %v", err)
 	}
+	kubelet.containerHistory = newContainerHistory(kubelet.rootDirectory)
 	mockCadvisor := &cadvisor.Mock{}
 	kubelet.cadvisor = mockCadvisor
+	kubelet.statsProvider = newStatsProvider(mockCadvisor, 0)
 	podManager, fakeMirrorClient := newFakePodManager()
 	kubelet.podManager = podManager
 	kubelet.containerRefManager = kubecontainer.NewRefManager()
-	kubelet.containerManager = dockertools.NewDockerManager(fakeDocker, fakeRecorder, kubelet.readinessManager, kubelet.containerRefManager, dockertools.PodInfraContainerImage, 0, 0)
+	kubelet.containerManager = dockertools.NewDockerManager(fakeDocker, fakeRecorder, kubelet.readinessManager, kubelet.containerRefManager, dockertools.PodInfraContainerImage, 0, 0, "/var/lib/docker", "/var/log/containers", 0)
 	kubelet.runtimeCache = kubecontainer.NewFakeRuntimeCache(kubelet.containerManager)
 	kubelet.podWorkers = newPodWorkers(
 		kubelet.runtimeCache,
-		func(pod *api.Pod, mirrorPod *api.Pod, runningPod container.Pod) error {
-			err := kubelet.syncPod(pod, mirrorPod, runningPod)
+		func(pod *api.Pod, mirrorPod *api.Pod, runningPod container.Pod, cancel <-chan struct{}) error {
+			err := kubelet.syncPod(pod, mirrorPod, runningPod, cancel)
 			waitGroup.Done()
 			return err
 		},
-		fakeRecorder)
+		fakeRecorder,
+		0)
 	kubelet.containerManager.Puller = &dockertools.FakeDockerPuller{}
 	kubelet.prober = newProber(nil, kubelet.readinessManager, kubelet.containerRefManager, kubelet.recorder)
-	kubelet.handlerRunner = newHandlerRunner(&fakeHTTP{}, &fakeContainerCommandRunner{}, kubelet.containerManager)
+	kubelet.handlerRunner = newHandlerRunner(&fakeHTTP{}, &fakeContainerCommandRunner{}, kubelet.containerManager, nil)
 	kubelet.volumeManager = newVolumeManager()
 	return &TestKubelet{kubelet, fakeDocker, mockCadvisor, fakeKubeClient, waitGroup, fakeMirrorClient}
 }
@@ -242,96 +255,67 @@ func TestKubeletDirs(t *testing.T) {
 	}
 }
 
-func TestKubeletDirsCompat(t *testing.T) {
+func TestMigrateOldDataDirs(t *testing.T) {
 	testKubelet := newTestKubelet(t)
 	kubelet := testKubelet.kubelet
 	root := kubelet.rootDirectory
-	if err := os.MkdirAll(root, 0750); err != nil {
-		t.Fatalf("can't mkdir(%q): %s", root, err)
-	}
-
-	var exp, got string
 
-	// Old-style pod dir.
-	if err := os.MkdirAll(fmt.Sprintf("%s/oldpod", root), 0750); err != nil {
-		t.Fatalf("can't mkdir(%q): %s", root, err)
-	}
-	// New-style pod dir.
-	if err := os.MkdirAll(fmt.Sprintf("%s/pods/newpod", root), 0750); err != nil {
+	// Old-style pod dir with an old-style container dir inside.
+	if err := os.MkdirAll(fmt.Sprintf("%s/oldpod/oldctr", root), 0750); err != nil {
 		t.Fatalf("can't mkdir(%q): %s", root, err)
 	}
-	// Both-style pod dir.
+	// Pod dir that exists in both forms.
 	if err := os.MkdirAll(fmt.Sprintf("%s/bothpod", root), 0750); err != nil {
 		t.Fatalf("can't mkdir(%q): %s", root, err)
 	}
 	if err := os.MkdirAll(fmt.Sprintf("%s/pods/bothpod", root), 0750); err != nil {
 		t.Fatalf("can't mkdir(%q): %s", root, err)
 	}
-
-	got = kubelet.getPodDir("oldpod")
-	exp = path.Join(root, "oldpod")
-	if got != exp {
-		t.Errorf("expected %q', got %q", exp, got)
-	}
-
-	got = kubelet.getPodDir("newpod")
-	exp = path.Join(root, "pods/newpod")
-	if got != exp {
-		t.Errorf("expected %q', got %q", exp, got)
-	}
-
-	got = kubelet.getPodDir("bothpod")
-	exp = path.Join(root, "pods/bothpod")
-	if got != exp {
-		t.Errorf("expected %q', got %q", exp, got)
-	}
-
-	got = kubelet.getPodDir("neitherpod")
-	exp = path.Join(root, "pods/neitherpod")
-	if got != exp {
-		t.Errorf("expected %q', got %q", exp, got)
-	}
-
-	root = kubelet.getPodDir("newpod")
-
-	// Old-style container dir.
-	if err := os.MkdirAll(fmt.Sprintf("%s/oldctr", root), 0750); err != nil {
-		t.Fatalf("can't mkdir(%q): %s", root, err)
-	}
-	// New-style container dir.
-	if err := os.MkdirAll(fmt.Sprintf("%s/containers/newctr", root), 0750); err != nil {
+	// New-style pod dir with a container dir in both forms.
+	if err := os.MkdirAll(fmt.Sprintf("%s/pods/newpod/bothctr", root), 0750); err != nil {
 		t.Fatalf("can't mkdir(%q): %s", root, err)
 	}
-	// Both-style container dir.
-	if err := os.MkdirAll(fmt.Sprintf("%s/bothctr", root), 0750); err != nil {
+	if err := os.MkdirAll(fmt.Sprintf("%s/pods/newpod/containers/bothctr", root), 0750); err != nil {
 		t.Fatalf("can't mkdir(%q): %s", root, err)
 	}
-	if err := os.MkdirAll(fmt.Sprintf("%s/containers/bothctr", root), 0750); err != nil {
+	// Credentials dir, which is not pod data and must stay put.
+	if err := os.MkdirAll(fmt.Sprintf("%s/pki", root), 0750); err != nil {
 		t.Fatalf("can't mkdir(%q): %s", root, err)
 	}
 
-	got = kubelet.getPodContainerDir("newpod", "oldctr")
-	exp = path.Join(root, "oldctr")
-	if got != exp {
-		t.Errorf("expected %q', got %q", exp, got)
+	if err := kubelet.migrateOldDataDirs(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
 	}
 
-	got = kubelet.getPodContainerDir("newpod", "newctr")
-	exp = path.Join(root, "containers/newctr")
-	if got != exp {
-		t.Errorf("expected %q', got %q", exp, got)
+	// The old-style dirs moved into the new layout...
+	for _, dir := range []string{
+		kubelet.getPodDir("oldpod"),
+		kubelet.getPodContainerDir("oldpod", "oldctr"),
+	} {
+		if !dirExists(dir) {
+			t.Errorf("expected %q to exist after the migration", dir)
+		}
 	}
-
-	got = kubelet.getPodContainerDir("newpod", "bothctr")
-	exp = path.Join(root, "containers/bothctr")
-	if got != exp {
-		t.Errorf("expected %q', got %q", exp, got)
+	if dirExists(path.Join(root, "oldpod")) {
+		t.Errorf("expected old-style pod dir to be gone after the migration")
+	}
+	// ...while dirs that exist in both forms are left alone.
+	for _, dir := range []string{
+		path.Join(root, "bothpod"),
+		kubelet.getPodDir("bothpod"),
+		path.Join(kubelet.getPodDir("newpod"), "bothctr"),
+		kubelet.getPodContainerDir("newpod", "bothctr"),
+	} {
+		if !dirExists(dir) {
+			t.Errorf("expected %q to survive the migration", dir)
+		}
 	}
-
-	got = kubelet.getPodContainerDir("newpod", "neitherctr")
-	exp = path.Join(root, "containers/neitherctr")
-	if got != exp {
-		t.Errorf("expected %q', got %q", exp, got)
+	// ...and the credentials dir is not mistaken for a pod dir.
+	if !dirExists(path.Join(root, "pki")) {
+		t.Errorf("expected the pki dir to survive the migration")
+	}
+	if dirExists(kubelet.getPodDir("pki")) {
+		t.Errorf("expected the pki dir not to be migrated into the pods dir")
 	}
 }
 
@@ -469,6 +453,7 @@ func generatePodInfraContainerHash(pod *api.Pod) uint64 {
 func TestSyncPodsDoesNothing(t *testing.T) {
 	testKubelet := newTestKubelet(t)
 	testKubelet.fakeCadvisor.On("MachineInfo").Return(&cadvisorApi.MachineInfo{}, nil)
+	testKubelet.fakeCadvisor.On("DockerImagesFsInfo").Return(cadvisorApiV2.FsInfo{}, nil)
 	kubelet := testKubelet.kubelet
 	fakeDocker := testKubelet.fakeDocker
 	waitGroup := testKubelet.waitGroup
@@ -525,15 +510,15 @@ func TestSyncPodsDoesNothing(t *testing.T) {
 		"list", "list",
 		// Get pod status.
 		"list", "inspect_container", "inspect_container",
-		// Check the pod infra contianer.
-		"inspect_container",
-		// Get pod status.
-		"list", "inspect_container", "inspect_container"})
+		// Check the pod infra container and get pod status again; all
+		// inspects are served from the inspect cache.
+		"list"})
 }
 
 func TestSyncPodsWithTerminationLog(t *testing.T) {
 	testKubelet := newTestKubelet(t)
 	testKubelet.fakeCadvisor.On("MachineInfo").Return(&cadvisorApi.MachineInfo{}, nil)
+	testKubelet.fakeCadvisor.On("DockerImagesFsInfo").Return(cadvisorApiV2.FsInfo{}, nil)
 	kubelet := testKubelet.kubelet
 	fakeDocker := testKubelet.fakeDocker
 	waitGroup := testKubelet.waitGroup
@@ -567,8 +552,8 @@ func TestSyncPodsWithTerminationLog(t *testing.T) {
 		"list", "list",
 		// Get pod status.
 		"list", "inspect_image",
-		// Create pod infra container.
-		"create", "start", "inspect_container",
+		// Create pod infra container; the second inspect fetches the pod IP.
+		"create", "start", "inspect_container", "inspect_container",
 		// Create container.
 		"create", "start",
 		// Get pod status.
@@ -596,6 +581,7 @@ func matchString(t *testing.T, pattern, str string) bool {
 func TestSyncPodsCreatesNetAndContainer(t *testing.T) {
 	testKubelet := newTestKubelet(t)
 	testKubelet.fakeCadvisor.On("MachineInfo").Return(&cadvisorApi.MachineInfo{}, nil)
+	testKubelet.fakeCadvisor.On("DockerImagesFsInfo").Return(cadvisorApiV2.FsInfo{}, nil)
 	kubelet := testKubelet.kubelet
 	fakeDocker := testKubelet.fakeDocker
 	waitGroup := testKubelet.waitGroup
@@ -627,8 +613,8 @@ func TestSyncPodsCreatesNetAndContainer(t *testing.T) {
 		"list", "list",
 		// Get pod status.
 		"list", "inspect_image",
-		// Create pod infra container.
-		"create", "start", "inspect_container",
+		// Create pod infra container; the second inspect fetches the pod IP.
+		"create", "start", "inspect_container", "inspect_container",
 		// Create container.
 		"create", "start",
 		// Get pod status.
@@ -657,6 +643,7 @@ func TestSyncPodsCreatesNetAndContainer(t *testing.T) {
 func TestSyncPodsCreatesNetAndContainerPullsImage(t *testing.T) {
 	testKubelet := newTestKubelet(t)
 	testKubelet.fakeCadvisor.On("MachineInfo").Return(&cadvisorApi.MachineInfo{}, nil)
+	testKubelet.fakeCadvisor.On("DockerImagesFsInfo").Return(cadvisorApiV2.FsInfo{}, nil)
 	kubelet := testKubelet.kubelet
 	fakeDocker := testKubelet.fakeDocker
 	waitGroup := testKubelet.waitGroup
@@ -690,8 +677,8 @@ func TestSyncPodsCreatesNetAndContainerPullsImage(t *testing.T) {
 		"list", "list",
 		// Get pod status.
 		"list", "inspect_image",
-		// Create pod infra container.
-		"create", "start", "inspect_container",
+		// Create pod infra container; the second inspect fetches the pod IP.
+		"create", "start", "inspect_container", "inspect_container",
 		// Create container.
 		"create", "start",
 		// Get pod status.
@@ -714,6 +701,7 @@ func TestSyncPodsCreatesNetAndContainerPullsImage(t *testing.T) {
 func TestSyncPodsWithPodInfraCreatesContainer(t *testing.T) {
 	testKubelet := newTestKubelet(t)
 	testKubelet.fakeCadvisor.On("MachineInfo").Return(&cadvisorApi.MachineInfo{}, nil)
+	testKubelet.fakeCadvisor.On("DockerImagesFsInfo").Return(cadvisorApiV2.FsInfo{}, nil)
 	kubelet := testKubelet.kubelet
 	fakeDocker := testKubelet.fakeDocker
 	waitGroup := testKubelet.waitGroup
@@ -757,12 +745,11 @@ func TestSyncPodsWithPodInfraCreatesContainer(t *testing.T) {
 		"list", "list",
 		// Get pod status.
 		"list", "inspect_container", "inspect_image",
-		// Check the pod infra container.
-		"inspect_container",
+		// Check the pod infra container: served from the inspect cache.
 		// Create container.
 		"create", "start",
-		// Get pod status.
-		"list", "inspect_container", "inspect_container"})
+		// Get pod status; only the new container needs an inspect.
+		"list", "inspect_container"})
 
 	fakeDocker.Lock()
 	if len(fakeDocker.Created) != 1 ||
@@ -775,12 +762,13 @@ func TestSyncPodsWithPodInfraCreatesContainer(t *testing.T) {
 func TestSyncPodsWithPodInfraCreatesContainerCallsHandler(t *testing.T) {
 	testKubelet := newTestKubelet(t)
 	testKubelet.fakeCadvisor.On("MachineInfo").Return(&cadvisorApi.MachineInfo{}, nil)
+	testKubelet.fakeCadvisor.On("DockerImagesFsInfo").Return(cadvisorApiV2.FsInfo{}, nil)
 	kubelet := testKubelet.kubelet
 	fakeDocker := testKubelet.fakeDocker
 	waitGroup := testKubelet.waitGroup
 	fakeHttp := fakeHTTP{}
 	kubelet.httpClient = &fakeHttp
-	kubelet.handlerRunner = newHandlerRunner(kubelet.httpClient, &fakeContainerCommandRunner{}, kubelet.containerManager)
+	kubelet.handlerRunner = newHandlerRunner(kubelet.httpClient, &fakeContainerCommandRunner{}, kubelet.containerManager, nil)
 	pods := []*api.Pod{
 		{
 			ObjectMeta: api.ObjectMeta{
@@ -832,12 +820,11 @@ func TestSyncPodsWithPodInfraCreatesContainerCallsHandler(t *testing.T) {
 		"list", "list",
 		// Get pod status.
 		"list", "inspect_container", "inspect_image",
-		// Check the pod infra container.
-		"inspect_container",
+		// Check the pod infra container: served from the inspect cache.
 		// Create container.
 		"create", "start",
-		// Get pod status.
-		"list", "inspect_container", "inspect_container"})
+		// Get pod status; only the new container needs an inspect.
+		"list", "inspect_container"})
 
 	fakeDocker.Lock()
 	if len(fakeDocker.Created) != 1 ||
@@ -853,6 +840,7 @@ func TestSyncPodsWithPodInfraCreatesContainerCallsHandler(t *testing.T) {
 func TestSyncPodsDeletesWithNoPodInfraContainer(t *testing.T) {
 	testKubelet := newTestKubelet(t)
 	testKubelet.fakeCadvisor.On("MachineInfo").Return(&cadvisorApi.MachineInfo{}, nil)
+	testKubelet.fakeCadvisor.On("DockerImagesFsInfo").Return(cadvisorApiV2.FsInfo{}, nil)
 	kubelet := testKubelet.kubelet
 	fakeDocker := testKubelet.fakeDocker
 	waitGroup := testKubelet.waitGroup
@@ -934,8 +922,10 @@ func TestSyncPodsDeletesWithNoPodInfraContainer(t *testing.T) {
 		"list", "inspect_container",
 		// Kill the container since pod infra container is not running.
 		"stop",
-		// Create pod infra container.
-		"create", "start", "inspect_container",
+		// Look for a dead pod infra container to tear its network down.
+		"list",
+		// Create pod infra container; the second inspect fetches the pod IP.
+		"create", "start", "inspect_container", "inspect_container",
 		// Create container.
 		"create", "start",
 		// Get pod status.
@@ -945,10 +935,11 @@ func TestSyncPodsDeletesWithNoPodInfraContainer(t *testing.T) {
 		"list",
 		// Check the pod infra container.
 		"inspect_container",
-		// Get pod status.
-		"list", "inspect_container", "inspect_container",
-		// Get pod status.
-		"list", "inspect_container", "inspect_container"})
+		// Get pod status; the infra container is served from the
+		// inspect cache.
+		"list", "inspect_container",
+		// Get pod status again; all inspects are cached.
+		"list"})
 
 	// A map iteration is used to delete containers, so must not depend on
 	// order here.
@@ -962,10 +953,160 @@ func TestSyncPodsDeletesWithNoPodInfraContainer(t *testing.T) {
 	fakeDocker.Unlock()
 }
 
+// recordingNetworkPlugin records the set up and tear down calls made by the
+// kubelet, and can be made to fail pod setup.
+type recordingNetworkPlugin struct {
+	sync.Mutex
+	setUpErr error
+	setUp    []string
+	tornDown []string
+}
+
+func (p *recordingNetworkPlugin) Init(host network.Host) error { return nil }
+
+func (p *recordingNetworkPlugin) Name() string { return "recording" }
+
+func (p *recordingNetworkPlugin) SetUpPod(namespace string, name string, id dockertools.DockerID) error {
+	p.Lock()
+	defer p.Unlock()
+	p.setUp = append(p.setUp, fmt.Sprintf("%s/%s:%s", namespace, name, id))
+	return p.setUpErr
+}
+
+func (p *recordingNetworkPlugin) TearDownPod(namespace string, name string, id dockertools.DockerID) error {
+	p.Lock()
+	defer p.Unlock()
+	p.tornDown = append(p.tornDown, fmt.Sprintf("%s/%s:%s", namespace, name, id))
+	return nil
+}
+
+func TestSyncPodsTearsDownNetworkOfDeadInfraContainer(t *testing.T) {
+	testKubelet := newTestKubelet(t)
+	testKubelet.fakeCadvisor.On("MachineInfo").Return(&cadvisorApi.MachineInfo{}, nil)
+	testKubelet.fakeCadvisor.On("DockerImagesFsInfo").Return(cadvisorApiV2.FsInfo{}, nil)
+	kubelet := testKubelet.kubelet
+	fakeDocker := testKubelet.fakeDocker
+	waitGroup := testKubelet.waitGroup
+	plugin := &recordingNetworkPlugin{}
+	kubelet.networkPlugin = plugin
+
+	pods := []*api.Pod{
+		{
+			ObjectMeta: api.ObjectMeta{
+				UID:       "12345678",
+				Name:      "foo",
+				Namespace: "new",
+			},
+			Spec: api.PodSpec{
+				Containers: []api.Container{
+					{Name: "bar"},
+				},
+			},
+		},
+	}
+	fakeDocker.ContainerList = []docker.APIContainers{
+		{
+			// format is // k8s_<container-id>_<pod-fullname>_<pod-uid>
+			Names: []string{"/k8s_bar_foo_new_12345678_0"},
+			ID:    "1234",
+		},
+	}
+	fakeDocker.ExitedContainerList = []docker.APIContainers{
+		{
+			// The infra container died; it only shows up in the full list.
+			Names: []string{"/k8s_POD." + strconv.FormatUint(generatePodInfraContainerHash(pods[0]), 16) + "_foo_new_12345678_0"},
+			ID:    "9876",
+		},
+	}
+	fakeDocker.ContainerMap = map[string]*docker.Container{
+		"1234": {
+			ID:         "1234",
+			Config:     &docker.Config{},
+			HostConfig: &docker.HostConfig{},
+		},
+	}
+
+	waitGroup.Add(1)
+	kubelet.podManager.SetPods(pods)
+	err := kubelet.SyncPods(pods, emptyPodUIDs, map[string]*api.Pod{}, time.Now())
+	if err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+	waitGroup.Wait()
+
+	// The dead infra container's networking must be torn down before a new
+	// sandbox is set up.
+	if len(plugin.tornDown) != 1 || plugin.tornDown[0] != "new/foo:9876" {
+		t.Errorf("unexpected teardown calls: %v", plugin.tornDown)
+	}
+	if len(plugin.setUp) != 1 || plugin.setUp[0] == "new/foo:9876" {
+		t.Errorf("unexpected setup calls: %v", plugin.setUp)
+	}
+	// The old app container must have been killed with the sandbox.
+	fakeDocker.Lock()
+	if len(fakeDocker.Stopped) != 1 || fakeDocker.Stopped[0] != "1234" {
+		t.Errorf("Wrong containers were stopped: %v", fakeDocker.Stopped)
+	}
+	fakeDocker.Unlock()
+}
+
+func TestSyncPodsKillsInfraContainerWhenNetworkSetupFails(t *testing.T) {
+	testKubelet := newTestKubelet(t)
+	testKubelet.fakeCadvisor.On("MachineInfo").Return(&cadvisorApi.MachineInfo{}, nil)
+	testKubelet.fakeCadvisor.On("DockerImagesFsInfo").Return(cadvisorApiV2.FsInfo{}, nil)
+	kubelet := testKubelet.kubelet
+	fakeDocker := testKubelet.fakeDocker
+	waitGroup := testKubelet.waitGroup
+	plugin := &recordingNetworkPlugin{setUpErr: fmt.Errorf("no network for you")}
+	kubelet.networkPlugin = plugin
+
+	pods := []*api.Pod{
+		{
+			ObjectMeta: api.ObjectMeta{
+				UID:       "12345678",
+				Name:      "foo",
+				Namespace: "new",
+			},
+			Spec: api.PodSpec{
+				Containers: []api.Container{
+					{Name: "bar"},
+				},
+			},
+		},
+	}
+
+	waitGroup.Add(1)
+	kubelet.podManager.SetPods(pods)
+	err := kubelet.SyncPods(pods, emptyPodUIDs, map[string]*api.Pod{}, time.Now())
+	if err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+	waitGroup.Wait()
+
+	// The infra container was created but could not be set up, so it must
+	// not be left behind without networking.
+	if len(plugin.setUp) != 1 {
+		t.Fatalf("unexpected setup calls: %v", plugin.setUp)
+	}
+	if len(plugin.tornDown) != 1 || plugin.tornDown[0] != plugin.setUp[0] {
+		t.Errorf("unexpected teardown calls: %v", plugin.tornDown)
+	}
+	fakeDocker.Lock()
+	if len(fakeDocker.Stopped) != 1 {
+		t.Errorf("Wrong containers were stopped: %v", fakeDocker.Stopped)
+	}
+	// No app container may have been started in the broken sandbox.
+	if len(fakeDocker.Created) != 1 {
+		t.Errorf("unexpected containers created: %v", fakeDocker.Created)
+	}
+	fakeDocker.Unlock()
+}
+
 func TestSyncPodsDeletesWhenSourcesAreReady(t *testing.T) {
 	ready := false
 	testKubelet := newTestKubelet(t)
 	testKubelet.fakeCadvisor.On("MachineInfo").Return(&cadvisorApi.MachineInfo{}, nil)
+	testKubelet.fakeCadvisor.On("DockerImagesFsInfo").Return(cadvisorApiV2.FsInfo{}, nil)
 	kubelet := testKubelet.kubelet
 	fakeDocker := testKubelet.fakeDocker
 	kubelet.sourcesReady = func() bool { return ready }
@@ -1011,6 +1152,7 @@ func TestSyncPodsDeletesWhenSourcesAreReady(t *testing.T) {
 func TestSyncPodsDeletes(t *testing.T) {
 	testKubelet := newTestKubelet(t)
 	testKubelet.fakeCadvisor.On("MachineInfo").Return(&cadvisorApi.MachineInfo{}, nil)
+	testKubelet.fakeCadvisor.On("DockerImagesFsInfo").Return(cadvisorApiV2.FsInfo{}, nil)
 	kubelet := testKubelet.kubelet
 	fakeDocker := testKubelet.fakeDocker
 	fakeDocker.ContainerList = []docker.APIContainers{
@@ -1049,9 +1191,72 @@ func TestSyncPodsDeletes(t *testing.T) {
 	}
 }
 
+// Tests that when a pod is deleted and recreated under the same name, the new
+// incarnation is not started until the old one's containers have been killed.
+func TestSyncPodsKillsObsoleteIncarnationBeforeStart(t *testing.T) {
+	testKubelet := newTestKubelet(t)
+	testKubelet.fakeCadvisor.On("MachineInfo").Return(&cadvisorApi.MachineInfo{}, nil)
+	testKubelet.fakeCadvisor.On("DockerImagesFsInfo").Return(cadvisorApiV2.FsInfo{}, nil)
+	kubelet := testKubelet.kubelet
+	fakeDocker := testKubelet.fakeDocker
+	// An active adoption grace window must not keep the old incarnation
+	// alive; its name is claimed by the new pod, so it can never be adopted.
+	kubelet.adoptionGracePeriod = podAdoptionGracePeriod
+	kubelet.sourcesReadyTime = time.Now()
+
+	pods := []*api.Pod{
+		{
+			ObjectMeta: api.ObjectMeta{
+				UID:       "12345678",
+				Name:      "bar",
+				Namespace: "new",
+			},
+			Spec: api.PodSpec{
+				Containers: []api.Container{
+					{Name: "foo"},
+				},
+			},
+		},
+	}
+	// Running containers of the previous incarnation of the pod: same name,
+	// different UID.
+	fakeDocker.ContainerList = []docker.APIContainers{
+		{
+			Names: []string{"/k8s_foo_bar_new_87654321_42"},
+			ID:    "1234",
+		},
+		{
+			// pod infra container
+			Names: []string{"/k8s_POD_bar_new_87654321_42"},
+			ID:    "9876",
+		},
+	}
+	kubelet.podManager.SetPods(pods)
+	err := kubelet.SyncPods(pods, emptyPodUIDs, map[string]*api.Pod{}, time.Now())
+	if err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+
+	// The old containers are killed, and nothing is created for the new
+	// incarnation until a later sync finds the node clean.
+	expectedToStop := map[string]bool{
+		"1234": true,
+		"9876": true,
+	}
+	if len(fakeDocker.Stopped) != 2 ||
+		!expectedToStop[fakeDocker.Stopped[0]] ||
+		!expectedToStop[fakeDocker.Stopped[1]] {
+		t.Errorf("Wrong containers were stopped: %v", fakeDocker.Stopped)
+	}
+	if len(fakeDocker.Created) != 0 {
+		t.Errorf("unexpected containers created: %v", fakeDocker.Created)
+	}
+}
+
 func TestSyncPodsDeletesDuplicate(t *testing.T) {
 	testKubelet := newTestKubelet(t)
 	testKubelet.fakeCadvisor.On("MachineInfo").Return(&cadvisorApi.MachineInfo{}, nil)
+	testKubelet.fakeCadvisor.On("DockerImagesFsInfo").Return(cadvisorApiV2.FsInfo{}, nil)
 	kubelet := testKubelet.kubelet
 	fakeDocker := testKubelet.fakeDocker
 	waitGroup := testKubelet.waitGroup
@@ -1118,12 +1323,11 @@ func TestSyncPodsDeletesDuplicate(t *testing.T) {
 		"list", "list",
 		// Get pod status.
 		"list", "inspect_container", "inspect_container", "inspect_container",
-		// Check the pod infra container.
-		"inspect_container",
+		// Check the pod infra container: served from the inspect cache.
 		// Kill the duplicated container.
 		"stop",
-		// Get pod status.
-		"list", "inspect_container", "inspect_container", "inspect_container"})
+		// Get pod status; only the killed container needs an inspect.
+		"list", "inspect_container"})
 	// Expect one of the duplicates to be killed.
 	if len(fakeDocker.Stopped) != 1 || (fakeDocker.Stopped[0] != "1234" && fakeDocker.Stopped[0] != "4567") {
 		t.Errorf("Wrong containers were stopped: %v", fakeDocker.Stopped)
@@ -1133,6 +1337,7 @@ func TestSyncPodsDeletesDuplicate(t *testing.T) {
 func TestSyncPodsBadHash(t *testing.T) {
 	testKubelet := newTestKubelet(t)
 	testKubelet.fakeCadvisor.On("MachineInfo").Return(&cadvisorApi.MachineInfo{}, nil)
+	testKubelet.fakeCadvisor.On("DockerImagesFsInfo").Return(cadvisorApiV2.FsInfo{}, nil)
 	kubelet := testKubelet.kubelet
 	fakeDocker := testKubelet.fakeDocker
 	waitGroup := testKubelet.waitGroup
@@ -1189,12 +1394,12 @@ func TestSyncPodsBadHash(t *testing.T) {
 		"list", "list",
 		// Get pod status.
 		"list", "inspect_container", "inspect_container",
-		// Check the pod infra container.
-		"inspect_container",
+		// Check the pod infra container: served from the inspect cache.
 		// Kill and restart the bad hash container.
 		"stop", "create", "start",
-		// Get pod status.
-		"list", "inspect_container", "inspect_container", "inspect_container"})
+		// Get pod status; the infra container is still cached, but the
+		// killed and recreated containers must be re-inspected.
+		"list", "inspect_container", "inspect_container"})
 
 	if err := fakeDocker.AssertStopped([]string{"1234"}); err != nil {
 		t.Errorf("%v", err)
@@ -1204,6 +1409,7 @@ func TestSyncPodsBadHash(t *testing.T) {
 func TestSyncPodsUnhealthy(t *testing.T) {
 	testKubelet := newTestKubelet(t)
 	testKubelet.fakeCadvisor.On("MachineInfo").Return(&cadvisorApi.MachineInfo{}, nil)
+	testKubelet.fakeCadvisor.On("DockerImagesFsInfo").Return(cadvisorApiV2.FsInfo{}, nil)
 	kubelet := testKubelet.kubelet
 	fakeDocker := testKubelet.fakeDocker
 	waitGroup := testKubelet.waitGroup
@@ -1263,14 +1469,14 @@ func TestSyncPodsUnhealthy(t *testing.T) {
 		"list", "list",
 		// Get pod status.
 		"list", "inspect_container", "inspect_container",
-		// Check the pod infra container.
-		"inspect_container",
+		// Check the pod infra container: served from the inspect cache.
 		// Kill the unhealthy container.
 		"stop",
 		// Restart the unhealthy container.
 		"create", "start",
-		// Get pod status.
-		"list", "inspect_container", "inspect_container", "inspect_container"})
+		// Get pod status; the infra container is still cached, but the
+		// killed and recreated containers must be re-inspected.
+		"list", "inspect_container", "inspect_container"})
 
 	if err := fakeDocker.AssertStopped([]string{"1234"}); err != nil {
 		t.Errorf("%v", err)
@@ -1352,6 +1558,55 @@ func TestGetPodVolumesFromDisk(t *testing.T) {
 	}
 }
 
+func TestCleanupOrphanedPods(t *testing.T) {
+	testKubelet := newTestKubelet(t)
+	kubelet := testKubelet.kubelet
+	plug := &volume.FakeVolumePlugin{"fake", nil}
+	kubelet.volumePluginMgr.InitPlugins([]volume.VolumePlugin{plug}, &volumeHost{kubelet})
+
+	pods := []*api.Pod{
+		{
+			ObjectMeta: api.ObjectMeta{
+				UID:       "12345678",
+				Name:      "foo",
+				Namespace: "new",
+			},
+		},
+	}
+	kubelet.podManager.SetPods(pods)
+	testKubelet.fakeDocker.ContainerList = []docker.APIContainers{
+		{
+			// A running pod that the pod manager does not know about.
+			Names: []string{"/k8s_POD_bar_new_87654321_0"},
+			ID:    "1234",
+		},
+	}
+
+	// Pod directories for the known pod, the running pod, and an orphan.
+	for _, uid := range []types.UID{"12345678", "87654321", "orphan"} {
+		if err := os.MkdirAll(kubelet.getPodDir(uid), 0750); err != nil {
+			t.Fatalf("can't mkdir(%q): %v", kubelet.getPodDir(uid), err)
+		}
+	}
+	// The orphan still has a volume mounted.
+	orphanVolume := volume.FakeVolume{"orphan", "vol1", plug}
+	orphanVolume.SetUp()
+
+	kubelet.cleanupOrphanedPods()
+
+	if _, err := os.Stat(kubelet.getPodDir("orphan")); !os.IsNotExist(err) {
+		t.Errorf("expected orphaned pod directory to be removed, got %v", err)
+	}
+	if _, err := os.Stat(orphanVolume.GetPath()); !os.IsNotExist(err) {
+		t.Errorf("expected orphaned pod volume to be torn down, got %v", err)
+	}
+	for _, uid := range []types.UID{"12345678", "87654321"} {
+		if _, err := os.Stat(kubelet.getPodDir(uid)); err != nil {
+			t.Errorf("expected pod directory %q to be kept: %v", kubelet.getPodDir(uid), err)
+		}
+	}
+}
+
 type stubVolume struct {
 	path string
 }
@@ -1452,12 +1707,58 @@ func TestGetContainerInfo(t *testing.T) {
 	mockCadvisor.AssertExpectations(t)
 }
 
-func TestGetRawContainerInfoRoot(t *testing.T) {
-	containerPath := "/"
-	containerInfo := &cadvisorApi.ContainerInfo{
-		ContainerReference: cadvisorApi.ContainerReference{
-			Name: containerPath,
-		},
+func TestGetContainerInfoPodNetworkFromInfraContainer(t *testing.T) {
+	containerID := "ab2cdf"
+	infraContainerID := "9b125"
+	containerInfo := cadvisorApi.ContainerInfo{
+		ContainerReference: cadvisorApi.ContainerReference{Name: "/docker/" + containerID},
+		Stats:              []*cadvisorApi.ContainerStats{{Network: cadvisorApi.NetworkStats{RxBytes: 1}}},
+	}
+	infraContainerInfo := cadvisorApi.ContainerInfo{
+		ContainerReference: cadvisorApi.ContainerReference{Name: "/docker/" + infraContainerID},
+		Stats:              []*cadvisorApi.ContainerStats{{Network: cadvisorApi.NetworkStats{RxBytes: 42, TxBytes: 17}}},
+	}
+
+	testKubelet := newTestKubelet(t)
+	kubelet := testKubelet.kubelet
+	fakeDocker := testKubelet.fakeDocker
+	mockCadvisor := testKubelet.fakeCadvisor
+	cadvisorReq := &cadvisorApi.ContainerInfoRequest{}
+	mockCadvisor.On("DockerContainer", containerID, cadvisorReq).Return(containerInfo, nil)
+	mockCadvisor.On("DockerContainer", infraContainerID, cadvisorReq).Return(infraContainerInfo, nil)
+
+	fakeDocker.ContainerList = []docker.APIContainers{
+		{
+			ID:    containerID,
+			Names: []string{"/k8s_foo_qux_ns_1234_42"},
+		},
+		{
+			ID:    infraContainerID,
+			Names: []string{"/k8s_POD_qux_ns_1234_42"},
+		},
+	}
+
+	stats, err := kubelet.GetContainerInfo("qux_ns", "", "foo", cadvisorReq)
+	if err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+	if stats == nil {
+		t.Fatalf("stats should not be nil")
+	}
+	// The network counters must come from the infra container, which owns the
+	// pod's network namespace.
+	if stats.Stats[0].Network.RxBytes != 42 || stats.Stats[0].Network.TxBytes != 17 {
+		t.Errorf("expected the infra container's network stats, got %+v", stats.Stats[0].Network)
+	}
+	mockCadvisor.AssertExpectations(t)
+}
+
+func TestGetRawContainerInfoRoot(t *testing.T) {
+	containerPath := "/"
+	containerInfo := &cadvisorApi.ContainerInfo{
+		ContainerReference: cadvisorApi.ContainerReference{
+			Name: containerPath,
+		},
 	}
 	fakeDocker := dockertools.FakeDockerClient{}
 
@@ -1466,8 +1767,9 @@ func TestGetRawContainerInfoRoot(t *testing.T) {
 	mockCadvisor.On("ContainerInfo", containerPath, cadvisorReq).Return(containerInfo, nil)
 
 	kubelet := Kubelet{
-		dockerClient: &fakeDocker,
-		cadvisor:     mockCadvisor,
+		dockerClient:  &fakeDocker,
+		cadvisor:      mockCadvisor,
+		statsProvider: newStatsProvider(mockCadvisor, 0),
 	}
 
 	_, err := kubelet.GetRawContainerInfo(containerPath, cadvisorReq, false)
@@ -1498,8 +1800,9 @@ func TestGetRawContainerInfoSubcontainers(t *testing.T) {
 	mockCadvisor.On("SubcontainerInfo", containerPath, cadvisorReq).Return(containerInfo, nil)
 
 	kubelet := Kubelet{
-		dockerClient: &fakeDocker,
-		cadvisor:     mockCadvisor,
+		dockerClient:  &fakeDocker,
+		cadvisor:      mockCadvisor,
+		statsProvider: newStatsProvider(mockCadvisor, 0),
 	}
 
 	result, err := kubelet.GetRawContainerInfo(containerPath, cadvisorReq, true)
@@ -1653,7 +1956,16 @@ func (f *fakeContainerCommandRunner) ExecInContainer(id string, cmd []string, in
 	return f.E
 }
 
-func (f *fakeContainerCommandRunner) PortForward(pod *kubecontainer.Pod, port uint16, stream io.ReadWriteCloser) error {
+func (f *fakeContainerCommandRunner) AttachContainer(id string, in io.Reader, out, err io.WriteCloser, tty bool) error {
+	f.ID = id
+	f.Stdin = in
+	f.Stdout = out
+	f.Stderr = err
+	f.TTY = tty
+	return f.E
+}
+
+func (f *fakeContainerCommandRunner) PortForward(pod *kubecontainer.Pod, port uint16, protocol string, stream io.ReadWriteCloser) error {
 	podInfraContainer := pod.FindContainerByName(dockertools.PodInfraContainerName)
 	if podInfraContainer == nil {
 		return fmt.Errorf("cannot find pod infra container in pod %q", kubecontainer.BuildPodFullName(pod.Name, pod.Namespace))
@@ -1736,7 +2048,7 @@ func TestRunHandlerExec(t *testing.T) {
 	kubelet := testKubelet.kubelet
 	fakeDocker := testKubelet.fakeDocker
 	kubelet.runner = &fakeCommandRunner
-	kubelet.handlerRunner = newHandlerRunner(&fakeHTTP{}, kubelet.runner, kubelet.containerManager)
+	kubelet.handlerRunner = newHandlerRunner(&fakeHTTP{}, kubelet.runner, kubelet.containerManager, nil)
 
 	containerID := "abc1234"
 	podName := "podFoo"
@@ -1775,6 +2087,49 @@ func TestRunHandlerExec(t *testing.T) {
 	}
 }
 
+func TestRunHandlerExecWithEnv(t *testing.T) {
+	fakeCommandRunner := fakeContainerCommandRunner{}
+	testKubelet := newTestKubelet(t)
+	kubelet := testKubelet.kubelet
+	kubelet.runner = &fakeCommandRunner
+	containerEnv := func(pod *api.Pod, container *api.Container) ([]string, error) {
+		return []string{"FOO=bar"}, nil
+	}
+	kubelet.handlerRunner = newHandlerRunner(&fakeHTTP{}, kubelet.runner, kubelet.containerManager, containerEnv)
+
+	containerID := "abc1234"
+	container := api.Container{
+		Name: "containerFoo",
+		Lifecycle: &api.Lifecycle{
+			PostStart: &api.Handler{
+				Exec: &api.ExecAction{
+					Command:    []string{"ls", "-a"},
+					Env:        []api.EnvVar{{Name: "BAZ", Value: "qux"}},
+					WorkingDir: "/data",
+				},
+			},
+		},
+	}
+
+	pod := api.Pod{}
+	pod.ObjectMeta.Name = "podFoo"
+	pod.ObjectMeta.Namespace = "nsFoo"
+	pod.Spec.Containers = []api.Container{container}
+	err := kubelet.handlerRunner.Run(containerID, &pod, &container, container.Lifecycle.PostStart)
+	if err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+	expectedCmd := []string{
+		"/bin/sh", "-c", `cd "$0" && exec "$@"`, "/data",
+		"/usr/bin/env", "FOO=bar", "BAZ=qux",
+		"ls", "-a",
+	}
+	if fakeCommandRunner.ID != containerID ||
+		!reflect.DeepEqual(expectedCmd, fakeCommandRunner.Cmd) {
+		t.Errorf("unexpected commands: %v", fakeCommandRunner)
+	}
+}
+
 type fakeHTTP struct {
 	url string
 	err error
@@ -1791,7 +2146,7 @@ func TestRunHandlerHttp(t *testing.T) {
 	testKubelet := newTestKubelet(t)
 	kubelet := testKubelet.kubelet
 	kubelet.httpClient = &fakeHttp
-	kubelet.handlerRunner = newHandlerRunner(kubelet.httpClient, &fakeContainerCommandRunner{}, kubelet.containerManager)
+	kubelet.handlerRunner = newHandlerRunner(kubelet.httpClient, &fakeContainerCommandRunner{}, kubelet.containerManager, nil)
 
 	containerID := "abc1234"
 	podName := "podFoo"
@@ -1852,6 +2207,7 @@ func TestRunHandlerNil(t *testing.T) {
 func TestSyncPodEventHandlerFails(t *testing.T) {
 	testKubelet := newTestKubelet(t)
 	testKubelet.fakeCadvisor.On("MachineInfo").Return(&cadvisorApi.MachineInfo{}, nil)
+	testKubelet.fakeCadvisor.On("DockerImagesFsInfo").Return(cadvisorApiV2.FsInfo{}, nil)
 	kubelet := testKubelet.kubelet
 	fakeDocker := testKubelet.fakeDocker
 	waitGroup := testKubelet.waitGroup
@@ -1859,7 +2215,7 @@ func TestSyncPodEventHandlerFails(t *testing.T) {
 	kubelet.httpClient = &fakeHTTP{
 		err: fmt.Errorf("test error"),
 	}
-	kubelet.handlerRunner = newHandlerRunner(kubelet.httpClient, &fakeContainerCommandRunner{}, kubelet.containerManager)
+	kubelet.handlerRunner = newHandlerRunner(kubelet.httpClient, &fakeContainerCommandRunner{}, kubelet.containerManager, nil)
 
 	pods := []*api.Pod{
 		{
@@ -1912,14 +2268,13 @@ func TestSyncPodEventHandlerFails(t *testing.T) {
 		"list", "list",
 		// Get pod status.
 		"list", "inspect_container", "inspect_image",
-		// Check the pod infra container.
-		"inspect_container",
+		// Check the pod infra container: served from the inspect cache.
 		// Create the container.
 		"create", "start",
 		// Kill the container since event handler fails.
 		"stop",
-		// Get pod status.
-		"list", "inspect_container", "inspect_container"})
+		// Get pod status; only the killed container needs an inspect.
+		"list", "inspect_container"})
 
 	// TODO(yifan): Check the stopped container's name.
 	if len(fakeDocker.Stopped) != 1 {
@@ -1934,9 +2289,86 @@ func TestSyncPodEventHandlerFails(t *testing.T) {
 	}
 }
 
+func TestSyncPodEventHandlerWarnOnly(t *testing.T) {
+	testKubelet := newTestKubelet(t)
+	testKubelet.fakeCadvisor.On("MachineInfo").Return(&cadvisorApi.MachineInfo{}, nil)
+	testKubelet.fakeCadvisor.On("DockerImagesFsInfo").Return(cadvisorApiV2.FsInfo{}, nil)
+	kubelet := testKubelet.kubelet
+	fakeDocker := testKubelet.fakeDocker
+	waitGroup := testKubelet.waitGroup
+
+	kubelet.httpClient = &fakeHTTP{
+		err: fmt.Errorf("test error"),
+	}
+	kubelet.handlerRunner = newHandlerRunner(kubelet.httpClient, &fakeContainerCommandRunner{}, kubelet.containerManager, nil)
+	kubelet.containerManager.PostStartFailurePolicy = dockertools.HookFailureWarnOnly
+
+	pods := []*api.Pod{
+		{
+			ObjectMeta: api.ObjectMeta{
+				UID:       "12345678",
+				Name:      "foo",
+				Namespace: "new",
+			},
+			Spec: api.PodSpec{
+				Containers: []api.Container{
+					{Name: "bar",
+						Lifecycle: &api.Lifecycle{
+							PostStart: &api.Handler{
+								HTTPGet: &api.HTTPGetAction{
+									Host: "does.no.exist",
+									Port: util.IntOrString{IntVal: 8080, Kind: util.IntstrInt},
+									Path: "bar",
+								},
+							},
+						},
+					},
+				},
+			},
+		},
+	}
+
+	fakeDocker.ContainerList = []docker.APIContainers{
+		{
+			// pod infra container
+			Names: []string{"/k8s_POD." + strconv.FormatUint(generatePodInfraContainerHash(pods[0]), 16) + "_foo_new_12345678_42"},
+			ID:    "9876",
+		},
+	}
+	fakeDocker.ContainerMap = map[string]*docker.Container{
+		"9876": {
+			ID:         "9876",
+			Config:     &docker.Config{},
+			HostConfig: &docker.HostConfig{},
+		},
+	}
+	kubelet.podManager.SetPods(pods)
+	waitGroup.Add(1)
+	err := kubelet.SyncPods(pods, emptyPodUIDs, map[string]*api.Pod{}, time.Now())
+	if err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+	waitGroup.Wait()
+
+	verifyCalls(t, fakeDocker, []string{
+		"list", "list",
+		// Get pod status.
+		"list", "inspect_container", "inspect_image",
+		// Check the pod infra container: served from the inspect cache.
+		// Create the container; it survives the failed event handler.
+		"create", "start",
+		// Get pod status.
+		"list", "inspect_container"})
+
+	if len(fakeDocker.Stopped) != 0 {
+		t.Errorf("Wrong containers were stopped: %v", fakeDocker.Stopped)
+	}
+}
+
 func TestSyncPodsWithPullPolicy(t *testing.T) {
 	testKubelet := newTestKubelet(t)
 	testKubelet.fakeCadvisor.On("MachineInfo").Return(&cadvisorApi.MachineInfo{}, nil)
+	testKubelet.fakeCadvisor.On("DockerImagesFsInfo").Return(cadvisorApiV2.FsInfo{}, nil)
 	kubelet := testKubelet.kubelet
 	fakeDocker := testKubelet.fakeDocker
 	waitGroup := testKubelet.waitGroup
@@ -1992,6 +2424,57 @@ func TestSyncPodsWithPullPolicy(t *testing.T) {
 	fakeDocker.Unlock()
 }
 
+func TestSyncPodsWithAlwaysPullImages(t *testing.T) {
+	testKubelet := newTestKubelet(t)
+	testKubelet.fakeCadvisor.On("MachineInfo").Return(&cadvisorApi.MachineInfo{}, nil)
+	testKubelet.fakeCadvisor.On("DockerImagesFsInfo").Return(cadvisorApiV2.FsInfo{}, nil)
+	kubelet := testKubelet.kubelet
+	kubelet.alwaysPullImages = true
+	fakeDocker := testKubelet.fakeDocker
+	waitGroup := testKubelet.waitGroup
+	puller := kubelet.containerManager.Puller.(*dockertools.FakeDockerPuller)
+	puller.HasImages = []string{"existing_one"}
+	kubelet.containerManager.PodInfraContainerImage = "custom_image_name"
+	fakeDocker.ContainerList = []docker.APIContainers{}
+
+	pods := []*api.Pod{
+		{
+			ObjectMeta: api.ObjectMeta{
+				UID:       "12345678",
+				Name:      "foo",
+				Namespace: "new",
+			},
+			Spec: api.PodSpec{
+				Containers: []api.Container{
+					{Name: "bar", Image: "pull_never_image", ImagePullPolicy: api.PullNever},
+					{Name: "bar1", Image: "existing_one", ImagePullPolicy: api.PullIfNotPresent},
+				},
+			},
+		},
+	}
+	kubelet.podManager.SetPods(pods)
+	waitGroup.Add(1)
+	err := kubelet.SyncPods(pods, emptyPodUIDs, map[string]*api.Pod{}, time.Now())
+	if err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+	waitGroup.Wait()
+
+	// Every image is pulled, even present ones and those with a PullNever
+	// policy.
+	pulledImageSet := make(map[string]empty)
+	for v := range puller.ImagesPulled {
+		pulledImageSet[puller.ImagesPulled[v]] = empty{}
+	}
+	if !reflect.DeepEqual(pulledImageSet, map[string]empty{
+		"custom_image_name": {},
+		"pull_never_image":  {},
+		"existing_one":      {},
+	}) {
+		t.Errorf("Unexpected pulled containers: %v", puller.ImagesPulled)
+	}
+}
+
 func TestParseResolvConf(t *testing.T) {
 	testCases := []struct {
 		data        string
@@ -2036,6 +2519,163 @@ func TestParseResolvConf(t *testing.T) {
 	}
 }
 
+func TestTruncateDNSSearchPaths(t *testing.T) {
+	testCases := []struct {
+		dnsSearch []string
+		expected  []string
+	}{
+		{[]string{}, []string{}},
+		{[]string{"a.com", "b.com"}, []string{"a.com", "b.com"}},
+		{
+			[]string{"1.com", "2.com", "3.com", "4.com", "5.com", "6.com", "7.com"},
+			[]string{"1.com", "2.com", "3.com", "4.com", "5.com", "6.com"},
+		},
+		{
+			// Six 50-character domains exceed the 256-character line limit;
+			// the last one is dropped.
+			[]string{
+				strings.Repeat("a", 46) + ".com",
+				strings.Repeat("b", 46) + ".com",
+				strings.Repeat("c", 46) + ".com",
+				strings.Repeat("d", 46) + ".com",
+				strings.Repeat("e", 46) + ".com",
+				strings.Repeat("f", 46) + ".com",
+			},
+			[]string{
+				strings.Repeat("a", 46) + ".com",
+				strings.Repeat("b", 46) + ".com",
+				strings.Repeat("c", 46) + ".com",
+				strings.Repeat("d", 46) + ".com",
+				strings.Repeat("e", 46) + ".com",
+			},
+		},
+	}
+	for i, tc := range testCases {
+		testKubelet := newTestKubelet(t)
+		kl := testKubelet.kubelet
+		pod := &api.Pod{ObjectMeta: api.ObjectMeta{UID: "12345678", Name: "foo", Namespace: "test"}}
+		actual := kl.truncateDNSSearchPaths(pod, tc.dnsSearch)
+		if !reflect.DeepEqual(actual, tc.expected) {
+			t.Errorf("[%d] expected %v, got %v", i, tc.expected, actual)
+		}
+	}
+}
+
+func TestSysctlAllowed(t *testing.T) {
+	testKubelet := newTestKubelet(t)
+	kl := testKubelet.kubelet
+	kl.allowedUnsafeSysctls = []string{"kernel.msgmax", "net.ipv4.route.*"}
+
+	allowed := []string{
+		"kernel.shm_rmid_forced",
+		"net.ipv4.ip_local_port_range",
+		"net.ipv4.tcp_syncookies",
+		"kernel.msgmax",
+		"net.ipv4.route.min_pmtu",
+	}
+	for _, name := range allowed {
+		if !kl.sysctlAllowed(name) {
+			t.Errorf("expected sysctl %q to be allowed", name)
+		}
+	}
+
+	forbidden := []string{
+		"net.core.somaxconn",
+		"kernel.msgmnb",
+		"net.ipv4.ip_forward",
+	}
+	for _, name := range forbidden {
+		if kl.sysctlAllowed(name) {
+			t.Errorf("expected sysctl %q to be forbidden", name)
+		}
+	}
+}
+
+func TestMakePodResolvConf(t *testing.T) {
+	testKubelet := newTestKubelet(t)
+	kl := testKubelet.kubelet
+	kl.clusterDomain = "cluster.local"
+	pod := &api.Pod{ObjectMeta: api.ObjectMeta{UID: "12345678", Name: "foo", Namespace: "test"}}
+
+	resolvConfPath, err := kl.makePodResolvConf(pod,
+		[]string{"10.0.0.10", "4.3.2.1"},
+		[]string{"test.svc.cluster.local", "svc.cluster.local", "cluster.local"},
+		[]string{"ndots:5"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	data, err := ioutil.ReadFile(resolvConfPath)
+	if err != nil {
+		t.Fatalf("unexpected error reading %q: %v", resolvConfPath, err)
+	}
+	expected := "nameserver 10.0.0.10\nnameserver 4.3.2.1\nsearch test.svc.cluster.local svc.cluster.local cluster.local\noptions ndots:5\n"
+	if string(data) != expected {
+		t.Errorf("expected %q, got %q", expected, string(data))
+	}
+}
+
+func TestMakePodHosts(t *testing.T) {
+	testKubelet := newTestKubelet(t)
+	kl := testKubelet.kubelet
+	pod := &api.Pod{
+		ObjectMeta: api.ObjectMeta{UID: "12345678", Name: "foo", Namespace: "test"},
+		Spec: api.PodSpec{
+			HostAliases: []api.HostAlias{
+				{IP: "10.1.2.3", Hostnames: []string{"db", "db.test"}},
+			},
+		},
+	}
+
+	hostsFilePath, err := kl.makePodHosts(pod, "10.0.0.5")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	data, err := ioutil.ReadFile(hostsFilePath)
+	if err != nil {
+		t.Fatalf("unexpected error reading %q: %v", hostsFilePath, err)
+	}
+	expected := "# Kubernetes-managed hosts file.\n" +
+		"127.0.0.1\tlocalhost\n" +
+		"::1\tlocalhost ip6-localhost ip6-loopback\n" +
+		"fe00::0\tip6-localnet\n" +
+		"fe00::0\tip6-mcastprefix\n" +
+		"fe00::1\tip6-allnodes\n" +
+		"fe00::2\tip6-allrouters\n" +
+		"10.0.0.5\tfoo\n" +
+		"\n# Entries added by HostAliases.\n" +
+		"10.1.2.3\tdb\n" +
+		"10.1.2.3\tdb.test\n"
+	if string(data) != expected {
+		t.Errorf("expected %q, got %q", expected, string(data))
+	}
+}
+
+func TestMakePodHostsWithHostnameAndSubdomain(t *testing.T) {
+	testKubelet := newTestKubelet(t)
+	kl := testKubelet.kubelet
+	kl.clusterDomain = "cluster.local"
+	pod := &api.Pod{
+		ObjectMeta: api.ObjectMeta{UID: "12345678", Name: "foo-0", Namespace: "test"},
+		Spec: api.PodSpec{
+			Hostname:  "custom",
+			Subdomain: "web",
+		},
+	}
+
+	hostsFilePath, err := kl.makePodHosts(pod, "10.0.0.5")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	data, err := ioutil.ReadFile(hostsFilePath)
+	if err != nil {
+		t.Fatalf("unexpected error reading %q: %v", hostsFilePath, err)
+	}
+	expectedEntry := "10.0.0.5\tcustom.web.test.svc.cluster.local custom\n"
+	if !strings.Contains(string(data), expectedEntry) {
+		t.Errorf("expected hosts file to contain %q, got %q", expectedEntry, string(data))
+	}
+}
+
 type testServiceLister struct {
 	services []api.Service
 }
@@ -2390,7 +3030,7 @@ func TestMakeEnvironmentVariables(t *testing.T) {
 			kl.serviceLister = testServiceLister{services}
 		}
 
-		result, err := kl.makeEnvironmentVariables(tc.ns, tc.container)
+		result, err := kl.makeEnvironmentVariables(&api.Pod{ObjectMeta: api.ObjectMeta{Namespace: tc.ns}}, tc.container)
 		if err != nil {
 			t.Errorf("[%v] Unexpected error: %v", tc.name, err)
 		}
@@ -2406,13 +3046,109 @@ func TestMakeEnvironmentVariables(t *testing.T) {
 	}
 }
 
-func runningState(cName string) api.ContainerStatus {
-	return api.ContainerStatus{
-		Name: cName,
-		State: api.ContainerState{
-			Running: &api.ContainerStateRunning{},
-		},
-	}
+func TestMakeEnvironmentVariablesServiceLinksDisabled(t *testing.T) {
+	services := []api.Service{
+		{
+			ObjectMeta: api.ObjectMeta{Name: "kubernetes", Namespace: api.NamespaceDefault},
+			Spec: api.ServiceSpec{
+				Ports: []api.ServicePort{{
+					Protocol: "TCP",
+					Port:     8081,
+				}},
+				PortalIP: "1.2.3.1",
+			},
+		},
+		{
+			ObjectMeta: api.ObjectMeta{Name: "test", Namespace: "test1"},
+			Spec: api.ServiceSpec{
+				Ports: []api.ServicePort{{
+					Protocol: "TCP",
+					Port:     8083,
+				}},
+				PortalIP: "1.2.3.3",
+			},
+		},
+	}
+
+	testKubelet := newTestKubelet(t)
+	kl := testKubelet.kubelet
+	kl.serviceLister = testServiceLister{services}
+
+	falseVal := false
+	pod := &api.Pod{
+		ObjectMeta: api.ObjectMeta{Namespace: "test1", Name: "foo"},
+		Spec:       api.PodSpec{EnableServiceLinks: &falseVal},
+	}
+	result, err := kl.makeEnvironmentVariables(pod, &api.Container{})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	resultSet := util.NewStringSet(result...)
+	expected := util.NewStringSet(
+		"KUBERNETES_SERVICE_HOST=1.2.3.1",
+		"KUBERNETES_SERVICE_PORT=8081",
+		"KUBERNETES_PORT=tcp://1.2.3.1:8081",
+		"KUBERNETES_PORT_8081_TCP=tcp://1.2.3.1:8081",
+		"KUBERNETES_PORT_8081_TCP_PROTO=tcp",
+		"KUBERNETES_PORT_8081_TCP_PORT=8081",
+		"KUBERNETES_PORT_8081_TCP_ADDR=1.2.3.1")
+	if !resultSet.IsSuperset(expected) {
+		t.Errorf("Unexpected env entries; expected {%v}, got {%v}", expected, resultSet)
+	}
+	if a := len(resultSet); a != len(expected) {
+		t.Errorf("Unexpected number of env vars; expected %v, got %v", len(expected), a)
+	}
+}
+
+func TestMakeEnvironmentVariablesSecretKeyRef(t *testing.T) {
+	testKubelet := newTestKubelet(t)
+	kl := testKubelet.kubelet
+	kl.serviceLister = testServiceLister{}
+	testKubelet.fakeKubeClient.ReactFn = func(action testclient.FakeAction) (runtime.Object, error) {
+		if action.Action != "get-secret" {
+			t.Errorf("unexpected action: %v", action.Action)
+		}
+		return &api.Secret{
+			ObjectMeta: api.ObjectMeta{Namespace: "test1", Name: "api-token"},
+			Data:       map[string][]byte{"token": []byte("s3cr3t")},
+		}, nil
+	}
+
+	pod := &api.Pod{ObjectMeta: api.ObjectMeta{Namespace: "test1", Name: "foo"}}
+	container := &api.Container{
+		Env: []api.EnvVar{
+			{
+				Name: "TOKEN",
+				ValueFrom: &api.EnvVarSource{
+					SecretKeyRef: &api.SecretKeySelector{Name: "api-token", Key: "token"},
+				},
+			},
+		},
+	}
+
+	result, err := kl.makeEnvironmentVariables(pod, container)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !util.NewStringSet(result...).Has("TOKEN=s3cr3t") {
+		t.Errorf("expected TOKEN=s3cr3t in env vars, got %v", result)
+	}
+
+	// A reference to a key the secret does not have must fail the sync.
+	container.Env[0].ValueFrom.SecretKeyRef.Key = "missing"
+	if _, err := kl.makeEnvironmentVariables(pod, container); err == nil {
+		t.Errorf("expected an error for a missing secret key")
+	}
+}
+
+func runningState(cName string) api.ContainerStatus {
+	return api.ContainerStatus{
+		Name: cName,
+		State: api.ContainerState{
+			Running: &api.ContainerStateRunning{},
+		},
+	}
 }
 func stoppedState(cName string) api.ContainerStatus {
 	return api.ContainerStatus{
@@ -2443,6 +3179,65 @@ func failedState(cName string) api.ContainerStatus {
 	}
 }
 
+func TestGeneratePodStatusStartTime(t *testing.T) {
+	for _, policy := range []api.RestartPolicy{api.RestartPolicyAlways, api.RestartPolicyOnFailure, api.RestartPolicyNever} {
+		testKubelet := newTestKubelet(t)
+		testKubelet.fakeCadvisor.On("MachineInfo").Return(&cadvisorApi.MachineInfo{}, nil)
+		testKubelet.fakeCadvisor.On("DockerImagesFsInfo").Return(cadvisorApiV2.FsInfo{}, nil)
+		kubelet := testKubelet.kubelet
+		fakeDocker := testKubelet.fakeDocker
+
+		containers := []api.Container{{Name: "bar"}}
+		pod := api.Pod{
+			ObjectMeta: api.ObjectMeta{
+				UID:       "12345678",
+				Name:      "foo",
+				Namespace: "new",
+			},
+			Spec: api.PodSpec{
+				Host:          "machine",
+				Containers:    containers,
+				RestartPolicy: policy,
+			},
+		}
+		names := []string{"/k8s_bar." + strconv.FormatUint(dockertools.HashContainer(&containers[0]), 16) + "_foo_new_12345678_0"}
+		fakeDocker.ContainerList = []docker.APIContainers{{Names: names, ID: "1234"}}
+		fakeDocker.ContainerMap = map[string]*docker.Container{
+			"1234": {
+				ID:     "1234",
+				Name:   "bar",
+				Config: &docker.Config{},
+				State: docker.State{
+					Running:   true,
+					StartedAt: time.Now(),
+				},
+			},
+		}
+
+		status, err := kubelet.generatePodStatus(&pod)
+		if err != nil {
+			t.Fatalf("policy %v: unexpected error %v", policy, err)
+		}
+		if status.Phase != api.PodRunning {
+			t.Fatalf("policy %v: expected phase %v, got %v", policy, api.PodRunning, status.Phase)
+		}
+		if status.StartTime == nil || status.StartTime.IsZero() {
+			t.Errorf("policy %v: expected start time to be set", policy)
+			continue
+		}
+
+		// The start time is stamped only once and preserved afterwards.
+		pod.Status = status
+		laterStatus, err := kubelet.generatePodStatus(&pod)
+		if err != nil {
+			t.Fatalf("policy %v: unexpected error %v", policy, err)
+		}
+		if laterStatus.StartTime == nil || !laterStatus.StartTime.Time.Equal(status.StartTime.Time) {
+			t.Errorf("policy %v: expected start time %v to be preserved, got %v", policy, status.StartTime, laterStatus.StartTime)
+		}
+	}
+}
+
 func TestPodPhaseWithRestartAlways(t *testing.T) {
 	desiredState := api.PodSpec{
 		Host: "machine",
@@ -2694,6 +3489,111 @@ func TestPodPhaseWithRestartOnFailure(t *testing.T) {
 	}
 }
 
+func TestPodPhaseWithSidecar(t *testing.T) {
+	desiredState := api.PodSpec{
+		Host: "machine",
+		Containers: []api.Container{
+			{Name: "containerA"},
+			{Name: "sidecar", Sidecar: true},
+		},
+		RestartPolicy: api.RestartPolicyNever,
+	}
+
+	tests := []struct {
+		pod    *api.Pod
+		status api.PodPhase
+		test   string
+	}{
+		{
+			&api.Pod{
+				Spec: desiredState,
+				Status: api.PodStatus{
+					ContainerStatuses: []api.ContainerStatus{
+						runningState("containerA"),
+						failedState("sidecar"),
+					},
+				},
+			},
+			api.PodRunning,
+			"failed sidecar does not fail the pod",
+		},
+		{
+			&api.Pod{
+				Spec: desiredState,
+				Status: api.PodStatus{
+					ContainerStatuses: []api.ContainerStatus{
+						succeededState("containerA"),
+						runningState("sidecar"),
+					},
+				},
+			},
+			api.PodSucceeded,
+			"pod succeeds while the sidecar is still being torn down",
+		},
+		{
+			&api.Pod{
+				Spec: desiredState,
+				Status: api.PodStatus{
+					ContainerStatuses: []api.ContainerStatus{
+						succeededState("containerA"),
+						failedState("sidecar"),
+					},
+				},
+			},
+			api.PodSucceeded,
+			"killed sidecar does not fail the pod",
+		},
+	}
+	for _, test := range tests {
+		if status := getPhase(&test.pod.Spec, test.pod.Status.ContainerStatuses); status != test.status {
+			t.Errorf("In test %s, expected %v, got %v", test.test, test.status, status)
+		}
+	}
+}
+
+func TestShouldSidecarBeRestarted(t *testing.T) {
+	testKubelet := newTestKubelet(t)
+	kubelet := testKubelet.kubelet
+	pod := &api.Pod{
+		ObjectMeta: api.ObjectMeta{
+			UID:       "12345678",
+			Name:      "foo",
+			Namespace: "new",
+		},
+		Spec: api.PodSpec{
+			Containers: []api.Container{
+				{Name: "main"},
+				{Name: "sidecar", Sidecar: true},
+			},
+			RestartPolicy: api.RestartPolicyNever,
+		},
+	}
+	sidecar := &pod.Spec.Containers[1]
+
+	// The sidecar failed while the primary container is still running; the
+	// restart policy does not apply to it.
+	podStatus := &api.PodStatus{
+		ContainerStatuses: []api.ContainerStatus{
+			runningState("main"),
+			failedState("sidecar"),
+		},
+	}
+	if !kubelet.shouldContainerBeRestarted(sidecar, pod, podStatus) {
+		t.Errorf("expected the sidecar to be restarted while the primary container is running")
+	}
+
+	// Once the primary container is done the sidecar stays down.
+	podStatus = &api.PodStatus{
+		ContainerStatuses: []api.ContainerStatus{
+			succeededState("main"),
+			failedState("sidecar"),
+		},
+	}
+	if kubelet.shouldContainerBeRestarted(sidecar, pod, podStatus) {
+		t.Errorf("expected the sidecar to stay down once the primary container is done")
+	}
+}
+
 func getReadyStatus(cName string) api.ContainerStatus {
 	return api.ContainerStatus{
 		Name:  cName,
@@ -2958,6 +3858,7 @@ func TestPortForwardNoSuchPod(t *testing.T) {
 		kubecontainer.GetPodFullName(&api.Pod{ObjectMeta: api.ObjectMeta{Name: podName, Namespace: podNamespace}}),
 		"",
 		port,
+		api.PortForwardProtocolTCP,
 		nil,
 	)
 	if err == nil {
@@ -2994,6 +3895,7 @@ func TestPortForwardNoSuchContainer(t *testing.T) {
 		}}),
 		"",
 		port,
+		api.PortForwardProtocolTCP,
 		nil,
 	)
 	if err == nil {
@@ -3040,6 +3942,7 @@ func TestPortForward(t *testing.T) {
 		}}),
 		"",
 		port,
+		api.PortForwardProtocolTCP,
 		stream,
 	)
 	if err != nil {
@@ -3065,18 +3968,19 @@ func TestGetHostPortConflicts(t *testing.T) {
 		{Spec: api.PodSpec{Containers: []api.Container{{Ports: []api.ContainerPort{{HostPort: 83}}}}}},
 	}
 	// Pods should not cause any conflict.
-	_, conflicts := checkHostPortConflicts(pods)
-	if len(conflicts) != 0 {
-		t.Errorf("expected no conflicts, Got %#v", conflicts)
+	handler := newHostPortAdmitHandler()
+	for _, pod := range pods {
+		if ok, _, _ := handler.Admit(pod); !ok {
+			t.Errorf("expected no conflict, Got one for %#v", pod)
+		}
 	}
 
 	// The new pod should cause conflict and be reported.
-	expected := &api.Pod{
+	conflicting := &api.Pod{
 		Spec: api.PodSpec{Containers: []api.Container{{Ports: []api.ContainerPort{{HostPort: 81}}}}},
 	}
-	pods = append(pods, expected)
-	if _, actual := checkHostPortConflicts(pods); !reflect.DeepEqual(actual, []*api.Pod{expected}) {
-		t.Errorf("expected %#v, Got %#v", expected, actual)
+	if ok, reason, _ := handler.Admit(conflicting); ok || reason != "hostPortConflict" {
+		t.Errorf("expected a host port conflict, Got ok=%t reason=%q", ok, reason)
 	}
 }
 
@@ -3085,6 +3989,7 @@ func TestHandlePortConflicts(t *testing.T) {
 	testKubelet := newTestKubelet(t)
 	kl := testKubelet.kubelet
 	testKubelet.fakeCadvisor.On("MachineInfo").Return(&cadvisorApi.MachineInfo{}, nil)
+	testKubelet.fakeCadvisor.On("DockerImagesFsInfo").Return(cadvisorApiV2.FsInfo{}, nil)
 
 	spec := api.PodSpec{Containers: []api.Container{{Ports: []api.ContainerPort{{HostPort: 80}}}}}
 	pods := []*api.Pod{
@@ -3110,12 +4015,13 @@ func TestHandlePortConflicts(t *testing.T) {
 	pods[0].CreationTimestamp = util.NewTime(time.Now().Add(1 * time.Second))
 	// The newer pod should be rejected.
 	conflictedPodName := kubecontainer.GetPodFullName(pods[0])
+	conflictedPodUID := pods[0].UID
 
 	kl.handleNotFittingPods(pods)
 	// Check pod status stored in the status map.
-	status, err := kl.GetPodStatus(conflictedPodName)
-	if err != nil {
-		t.Fatalf("status of pod %q is not found in the status map: %#v", conflictedPodName, err)
+	status, ok := kl.statusManager.GetPodStatus(conflictedPodUID)
+	if !ok {
+		t.Fatalf("status of pod %q is not found in the status map", conflictedPodName)
 	}
 	if status.Phase != api.PodFailed {
 		t.Fatalf("expected pod status %q. Got %q.", api.PodFailed, status.Phase)
@@ -3123,7 +4029,7 @@ func TestHandlePortConflicts(t *testing.T) {
 
 	// Check if we can retrieve the pod status from GetPodStatus().
 	kl.podManager.SetPods(pods)
-	status, err = kl.GetPodStatus(conflictedPodName)
+	status, err := kl.GetPodStatus(conflictedPodName)
 	if err != nil {
 		t.Fatalf("unable to retrieve pod status for pod %q: %#v.", conflictedPodName, err)
 	}
@@ -3132,6 +4038,141 @@ func TestHandlePortConflicts(t *testing.T) {
 	}
 }
 
+func TestHandleInvalidPodSpec(t *testing.T) {
+	testKubelet := newTestKubelet(t)
+	kl := testKubelet.kubelet
+	testKubelet.fakeCadvisor.On("MachineInfo").Return(&cadvisorApi.MachineInfo{}, nil)
+	testKubelet.fakeCadvisor.On("DockerImagesFsInfo").Return(cadvisorApiV2.FsInfo{}, nil)
+
+	pods := []*api.Pod{
+		{
+			ObjectMeta: api.ObjectMeta{
+				UID:       "123456789",
+				Name:      "badpod",
+				Namespace: "foo",
+				Annotations: map[string]string{
+					ConfigSourceAnnotationKey: FileSource,
+				},
+			},
+			Spec: api.PodSpec{
+				RestartPolicy: api.RestartPolicyAlways,
+				DNSPolicy:     api.DNSClusterFirst,
+				Containers: []api.Container{{
+					Name:            "ctr",
+					Image:           "image",
+					ImagePullPolicy: api.PullIfNotPresent,
+					// The volume mount refers to a volume the pod does not define.
+					VolumeMounts: []api.VolumeMount{{Name: "missing", MountPath: "/mnt"}},
+				}},
+			},
+		},
+	}
+	badPodName := kubecontainer.GetPodFullName(pods[0])
+	badPodUID := pods[0].UID
+
+	kl.handleNotFittingPods(pods)
+	status, ok := kl.statusManager.GetPodStatus(badPodUID)
+	if !ok {
+		t.Fatalf("status of pod %q is not found in the status map", badPodName)
+	}
+	if status.Phase != api.PodFailed {
+		t.Fatalf("expected pod status %q. Got %q.", api.PodFailed, status.Phase)
+	}
+	if status.Message == "" {
+		t.Fatalf("expected a message explaining the rejection")
+	}
+}
+
+// Tests that a critical pod wins a host port conflict against an older
+// non-critical pod.
+func TestHandlePortConflictsCriticalPodWins(t *testing.T) {
+	testKubelet := newTestKubelet(t)
+	kl := testKubelet.kubelet
+	testKubelet.fakeCadvisor.On("MachineInfo").Return(&cadvisorApi.MachineInfo{}, nil)
+	testKubelet.fakeCadvisor.On("DockerImagesFsInfo").Return(cadvisorApiV2.FsInfo{}, nil)
+
+	spec := api.PodSpec{Containers: []api.Container{{Ports: []api.ContainerPort{{HostPort: 80}}}}}
+	pods := []*api.Pod{
+		{
+			ObjectMeta: api.ObjectMeta{
+				UID:         "123456789",
+				Name:        "criticalpod",
+				Namespace:   "foo",
+				Annotations: map[string]string{api.CriticalPodAnnotationKey: ""},
+			},
+			Spec: spec,
+		},
+		{
+			ObjectMeta: api.ObjectMeta{
+				UID:       "987654321",
+				Name:      "oldpod",
+				Namespace: "foo",
+			},
+			Spec: spec,
+		},
+	}
+	// The critical pod is newer, but should still be admitted first.
+	pods[1].CreationTimestamp = util.NewTime(time.Now())
+	pods[0].CreationTimestamp = util.NewTime(time.Now().Add(1 * time.Second))
+	evictedPodName := kubecontainer.GetPodFullName(pods[1])
+	evictedPodUID := pods[1].UID
+	criticalPodName := kubecontainer.GetPodFullName(pods[0])
+	criticalPodUID := pods[0].UID
+
+	kl.handleNotFittingPods(pods)
+	status, ok := kl.statusManager.GetPodStatus(evictedPodUID)
+	if !ok {
+		t.Fatalf("status of pod %q is not found in the status map", evictedPodName)
+	}
+	if status.Phase != api.PodFailed {
+		t.Fatalf("expected pod status %q. Got %q.", api.PodFailed, status.Phase)
+	}
+	if _, ok := kl.statusManager.GetPodStatus(criticalPodUID); ok {
+		t.Fatalf("expected no status for critical pod %q, but one was set", criticalPodName)
+	}
+}
+
+// Tests that pods beyond the node's pod limit are rejected.
+func TestHandleMaxPods(t *testing.T) {
+	testKubelet := newTestKubelet(t)
+	kl := testKubelet.kubelet
+	kl.maxPods = 1
+	testKubelet.fakeCadvisor.On("MachineInfo").Return(&cadvisorApi.MachineInfo{}, nil)
+	testKubelet.fakeCadvisor.On("DockerImagesFsInfo").Return(cadvisorApiV2.FsInfo{}, nil)
+
+	pods := []*api.Pod{
+		{
+			ObjectMeta: api.ObjectMeta{
+				UID:       "123456789",
+				Name:      "newpod",
+				Namespace: "foo",
+			},
+		},
+		{
+			ObjectMeta: api.ObjectMeta{
+				UID:       "987654321",
+				Name:      "oldpod",
+				Namespace: "foo",
+			},
+		},
+	}
+	// Make sure the Pods are in the reverse order of creation time.
+	pods[1].CreationTimestamp = util.NewTime(time.Now())
+	pods[0].CreationTimestamp = util.NewTime(time.Now().Add(1 * time.Second))
+	// The newer pod should be rejected.
+	rejectedPodName := kubecontainer.GetPodFullName(pods[0])
+	rejectedPodUID := pods[0].UID
+
+	kl.handleNotFittingPods(pods)
+	status, ok := kl.statusManager.GetPodStatus(rejectedPodUID)
+	if !ok {
+		t.Fatalf("status of pod %q is not found in the status map", rejectedPodName)
+	}
+	if status.Phase != api.PodFailed {
+		t.Fatalf("expected pod status %q. Got %q.", api.PodFailed, status.Phase)
+	}
+}
+
 // Tests that we handle not matching labels selector correctly by setting the failed status in status map.
 func TestHandleNodeSelector(t *testing.T) {
 	testKubelet := newTestKubelet(t)
@@ -3140,6 +4181,7 @@ func TestHandleNodeSelector(t *testing.T) {
 		{ObjectMeta: api.ObjectMeta{Name: "testnode", Labels: map[string]string{"key": "B"}}},
 	}}
 	testKubelet.fakeCadvisor.On("MachineInfo").Return(&cadvisorApi.MachineInfo{}, nil)
+	testKubelet.fakeCadvisor.On("DockerImagesFsInfo").Return(cadvisorApiV2.FsInfo{}, nil)
 	pods := []*api.Pod{
 		{
 			ObjectMeta: api.ObjectMeta{
@@ -3160,12 +4202,13 @@ func TestHandleNodeSelector(t *testing.T) {
 	}
 	// The first pod should be rejected.
 	notfittingPodName := kubecontainer.GetPodFullName(pods[0])
+	notfittingPodUID := pods[0].UID
 
 	kl.handleNotFittingPods(pods)
 	// Check pod status stored in the status map.
-	status, err := kl.GetPodStatus(notfittingPodName)
-	if err != nil {
-		t.Fatalf("status of pod %q is not found in the status map: %#v", notfittingPodName, err)
+	status, ok := kl.statusManager.GetPodStatus(notfittingPodUID)
+	if !ok {
+		t.Fatalf("status of pod %q is not found in the status map", notfittingPodName)
 	}
 	if status.Phase != api.PodFailed {
 		t.Fatalf("expected pod status %q. Got %q.", api.PodFailed, status.Phase)
@@ -3173,7 +4216,7 @@ func TestHandleNodeSelector(t *testing.T) {
 
 	// Check if we can retrieve the pod status from GetPodStatus().
 	kl.podManager.SetPods(pods)
-	status, err = kl.GetPodStatus(notfittingPodName)
+	status, err := kl.GetPodStatus(notfittingPodName)
 	if err != nil {
 		t.Fatalf("unable to retrieve pod status for pod %q: %#v.", notfittingPodName, err)
 	}
@@ -3182,11 +4225,59 @@ func TestHandleNodeSelector(t *testing.T) {
 	}
 }
 
+func TestHandleNodeTaints(t *testing.T) {
+	testKubelet := newTestKubelet(t)
+	kl := testKubelet.kubelet
+	kl.nodeTaints = []api.Taint{{Key: "dedicated", Value: "gpu", Effect: api.TaintEffectNoExecute}}
+	kl.nodeLister = testNodeLister{nodes: []api.Node{
+		{ObjectMeta: api.ObjectMeta{Name: "testnode"}},
+	}}
+	testKubelet.fakeCadvisor.On("MachineInfo").Return(&cadvisorApi.MachineInfo{}, nil)
+	testKubelet.fakeCadvisor.On("DockerImagesFsInfo").Return(cadvisorApiV2.FsInfo{}, nil)
+	pods := []*api.Pod{
+		{
+			ObjectMeta: api.ObjectMeta{
+				UID:       "123456789",
+				Name:      "podA",
+				Namespace: "foo",
+			},
+		},
+		{
+			ObjectMeta: api.ObjectMeta{
+				UID:       "987654321",
+				Name:      "podB",
+				Namespace: "foo",
+				Annotations: map[string]string{
+					api.TolerationsAnnotationKey: `[{"key": "dedicated", "operator": "Equal", "value": "gpu", "effect": "NoExecute"}]`,
+				},
+			},
+		},
+	}
+	// The first pod does not tolerate the taint and should be rejected.
+	notfittingPodName := kubecontainer.GetPodFullName(pods[0])
+	notfittingPodUID := pods[0].UID
+	fittingPodName := kubecontainer.GetPodFullName(pods[1])
+
+	kl.podManager.SetPods(pods)
+	kl.handleNotFittingPods(pods)
+	status, ok := kl.statusManager.GetPodStatus(notfittingPodUID)
+	if !ok {
+		t.Fatalf("status of pod %q is not found in the status map", notfittingPodName)
+	}
+	if status.Phase != api.PodFailed {
+		t.Fatalf("expected pod status %q. Got %q.", api.PodFailed, status.Phase)
+	}
+	if status, err := kl.GetPodStatus(fittingPodName); err == nil && status.Phase == api.PodFailed {
+		t.Fatalf("expected the tolerating pod not to be rejected")
+	}
+}
+
 // Tests that we handle exceeded resources correctly by setting the failed status in status map.
 func TestHandleMemExceeded(t *testing.T) {
 	testKubelet := newTestKubelet(t)
 	kl := testKubelet.kubelet
 	testKubelet.fakeCadvisor.On("MachineInfo").Return(&cadvisorApi.MachineInfo{MemoryCapacity: 100}, nil)
+	testKubelet.fakeCadvisor.On("DockerImagesFsInfo").Return(cadvisorApiV2.FsInfo{}, nil)
 
 	spec := api.PodSpec{Containers: []api.Container{{Resources: api.ResourceRequirements{
 		Limits: api.ResourceList{
@@ -3216,12 +4307,13 @@ func TestHandleMemExceeded(t *testing.T) {
 	pods[0].CreationTimestamp = util.NewTime(time.Now().Add(1 * time.Second))
 	// The newer pod should be rejected.
 	notfittingPodName := kubecontainer.GetPodFullName(pods[0])
+	notfittingPodUID := pods[0].UID
 
 	kl.handleNotFittingPods(pods)
 	// Check pod status stored in the status map.
-	status, err := kl.GetPodStatus(notfittingPodName)
-	if err != nil {
-		t.Fatalf("status of pod %q is not found in the status map: %#v", notfittingPodName, err)
+	status, ok := kl.statusManager.GetPodStatus(notfittingPodUID)
+	if !ok {
+		t.Fatalf("status of pod %q is not found in the status map", notfittingPodName)
 	}
 	if status.Phase != api.PodFailed {
 		t.Fatalf("expected pod status %q. Got %q.", api.PodFailed, status.Phase)
@@ -3229,7 +4321,7 @@ func TestHandleMemExceeded(t *testing.T) {
 
 	// Check if we can retrieve the pod status from GetPodStatus().
 	kl.podManager.SetPods(pods)
-	status, err = kl.GetPodStatus(notfittingPodName)
+	status, err := kl.GetPodStatus(notfittingPodName)
 	if err != nil {
 		t.Fatalf("unable to retrieve pod status for pod %q: %#v.", notfittingPodName, err)
 	}
@@ -3242,21 +4334,23 @@ func TestHandleMemExceeded(t *testing.T) {
 func TestPurgingObsoleteStatusMapEntries(t *testing.T) {
 	testKubelet := newTestKubelet(t)
 	testKubelet.fakeCadvisor.On("MachineInfo").Return(&cadvisorApi.MachineInfo{}, nil)
+	testKubelet.fakeCadvisor.On("DockerImagesFsInfo").Return(cadvisorApiV2.FsInfo{}, nil)
 
 	kl := testKubelet.kubelet
 	pods := []*api.Pod{
-		{ObjectMeta: api.ObjectMeta{Name: "pod1"}, Spec: api.PodSpec{Containers: []api.Container{{Ports: []api.ContainerPort{{HostPort: 80}}}}}},
-		{ObjectMeta: api.ObjectMeta{Name: "pod2"}, Spec: api.PodSpec{Containers: []api.Container{{Ports: []api.ContainerPort{{HostPort: 80}}}}}},
+		{ObjectMeta: api.ObjectMeta{UID: "1234", Name: "pod1"}, Spec: api.PodSpec{Containers: []api.Container{{Ports: []api.ContainerPort{{HostPort: 80}}}}}},
+		{ObjectMeta: api.ObjectMeta{UID: "5678", Name: "pod2"}, Spec: api.PodSpec{Containers: []api.Container{{Ports: []api.ContainerPort{{HostPort: 80}}}}}},
 	}
+	rejectedPodUID := pods[1].UID
 	// Run once to populate the status map.
 	kl.handleNotFittingPods(pods)
-	if _, err := kl.GetPodStatus(kubecontainer.BuildPodFullName("pod2", "")); err != nil {
-		t.Fatalf("expected to have status cached for %q: %v", "pod2", err)
+	if _, ok := kl.statusManager.GetPodStatus(rejectedPodUID); !ok {
+		t.Fatalf("expected to have status cached for %q", "pod2")
 	}
 	// Sync with empty pods so that the entry in status map will be removed.
 	kl.SyncPods([]*api.Pod{}, emptyPodUIDs, map[string]*api.Pod{}, time.Now())
-	if _, err := kl.GetPodStatus(kubecontainer.BuildPodFullName("pod2", "")); err == nil {
-		t.Fatalf("expected to not have status cached for %q: %v", "pod2", err)
+	if _, ok := kl.statusManager.GetPodStatus(rejectedPodUID); ok {
+		t.Fatalf("expected to not have status cached for %q", "pod2")
 	}
 }
 
@@ -3332,7 +4426,7 @@ func TestValidateContainerStatus(t *testing.T) {
 	for i, tc := range testCases {
 		_, err := kubelet.validateContainerStatus(&api.PodStatus{
 			ContainerStatuses: tc.statuses,
-		}, containerName)
+		}, containerName, false)
 		if tc.success {
 			if err != nil {
 				t.Errorf("[case %d]: unexpected failure - %v", i, err)
@@ -3343,7 +4437,7 @@ func TestValidateContainerStatus(t *testing.T) {
 	}
 	if _, err := kubelet.validateContainerStatus(&api.PodStatus{
 		ContainerStatuses: testCases[0].statuses,
-	}, "blah"); err == nil {
+	}, "blah", false); err == nil {
 		t.Errorf("expected error with invalid container name")
 	}
 }
@@ -3351,6 +4445,8 @@ func TestValidateContainerStatus(t *testing.T) {
 func TestUpdateNewNodeStatus(t *testing.T) {
 	testKubelet := newTestKubelet(t)
 	kubelet := testKubelet.kubelet
+	kubelet.nodeIP = net.ParseIP("127.0.0.1")
+	kubelet.maxPods = 40
 	kubeClient := testKubelet.fakeKubeClient
 	kubeClient.ReactFn = testclient.NewSimpleFake(&api.NodeList{Items: []api.Node{
 		{ObjectMeta: api.ObjectMeta{Name: "testnode"}},
@@ -3364,6 +4460,11 @@ func TestUpdateNewNodeStatus(t *testing.T) {
 	}
 	mockCadvisor := testKubelet.fakeCadvisor
 	mockCadvisor.On("MachineInfo").Return(machineInfo, nil)
+	rootInfo := &cadvisorApi.ContainerInfo{
+		ContainerReference: cadvisorApi.ContainerReference{Name: "/"},
+		Stats:              []*cadvisorApi.ContainerStats{{}},
+	}
+	mockCadvisor.On("ContainerInfo", "/", &cadvisorApi.ContainerInfoRequest{NumStats: 1}).Return(rootInfo, nil)
 	versionInfo := &cadvisorApi.VersionInfo{
 		KernelVersion:      "3.16.0-0.bpo.4-amd64",
 		ContainerOsVersion: "Debian GNU/Linux 7 (wheezy)",
@@ -3376,9 +4477,33 @@ func TestUpdateNewNodeStatus(t *testing.T) {
 		Status: api.NodeStatus{
 			Conditions: []api.NodeCondition{
 				{
-					Type:               api.NodeReady,
-					Status:             api.ConditionTrue,
-					Reason:             fmt.Sprintf("kubelet is posting ready status"),
+					Type:               api.NodeReady,
+					Status:             api.ConditionTrue,
+					Reason:             fmt.Sprintf("kubelet is posting ready status"),
+					LastHeartbeatTime:  util.Time{},
+					LastTransitionTime: util.Time{},
+				},
+				{
+					Type:               api.NodeMemoryPressure,
+					Status:             api.ConditionFalse,
+					Reason:             "KubeletHasSufficientMemory",
+					Message:            "kubelet has sufficient memory available",
+					LastHeartbeatTime:  util.Time{},
+					LastTransitionTime: util.Time{},
+				},
+				{
+					Type:               api.NodeDiskPressure,
+					Status:             api.ConditionFalse,
+					Reason:             "KubeletHasNoDiskPressure",
+					Message:            "kubelet has no disk pressure",
+					LastHeartbeatTime:  util.Time{},
+					LastTransitionTime: util.Time{},
+				},
+				{
+					Type:               api.NodePIDPressure,
+					Status:             api.ConditionFalse,
+					Reason:             "KubeletHasSufficientPID",
+					Message:            "kubelet has sufficient PID available",
 					LastHeartbeatTime:  util.Time{},
 					LastTransitionTime: util.Time{},
 				},
@@ -3396,6 +4521,11 @@ func TestUpdateNewNodeStatus(t *testing.T) {
 			Capacity: api.ResourceList{
 				api.ResourceCPU:    *resource.NewMilliQuantity(2000, resource.DecimalSI),
 				api.ResourceMemory: *resource.NewQuantity(1024, resource.BinarySI),
+				api.ResourcePods:   *resource.NewQuantity(40, resource.DecimalSI),
+			},
+			Addresses: []api.NodeAddress{
+				{Type: api.NodeInternalIP, Address: "127.0.0.1"},
+				{Type: api.NodeLegacyHostIP, Address: "127.0.0.1"},
 			},
 		},
 	}
@@ -3416,8 +4546,10 @@ func TestUpdateNewNodeStatus(t *testing.T) {
 	if updatedNode.Status.Conditions[0].LastTransitionTime.IsZero() {
 		t.Errorf("unexpected zero last transition timestamp")
 	}
-	updatedNode.Status.Conditions[0].LastHeartbeatTime = util.Time{}
-	updatedNode.Status.Conditions[0].LastTransitionTime = util.Time{}
+	for i := range updatedNode.Status.Conditions {
+		updatedNode.Status.Conditions[i].LastHeartbeatTime = util.Time{}
+		updatedNode.Status.Conditions[i].LastTransitionTime = util.Time{}
+	}
 	if !reflect.DeepEqual(expectedNode, updatedNode) {
 		t.Errorf("unexpected objects: %s", util.ObjectDiff(expectedNode, updatedNode))
 	}
@@ -3426,6 +4558,8 @@ func TestUpdateNewNodeStatus(t *testing.T) {
 func TestUpdateExistingNodeStatus(t *testing.T) {
 	testKubelet := newTestKubelet(t)
 	kubelet := testKubelet.kubelet
+	kubelet.nodeIP = net.ParseIP("127.0.0.1")
+	kubelet.maxPods = 40
 	kubeClient := testKubelet.fakeKubeClient
 	kubeClient.ReactFn = testclient.NewSimpleFake(&api.NodeList{Items: []api.Node{
 		{
@@ -3457,6 +4591,11 @@ func TestUpdateExistingNodeStatus(t *testing.T) {
 		MemoryCapacity: 1024,
 	}
 	mockCadvisor.On("MachineInfo").Return(machineInfo, nil)
+	rootInfo := &cadvisorApi.ContainerInfo{
+		ContainerReference: cadvisorApi.ContainerReference{Name: "/"},
+		Stats:              []*cadvisorApi.ContainerStats{{}},
+	}
+	mockCadvisor.On("ContainerInfo", "/", &cadvisorApi.ContainerInfoRequest{NumStats: 1}).Return(rootInfo, nil)
 	versionInfo := &cadvisorApi.VersionInfo{
 		KernelVersion:      "3.16.0-0.bpo.4-amd64",
 		ContainerOsVersion: "Debian GNU/Linux 7 (wheezy)",
@@ -3475,6 +4614,30 @@ func TestUpdateExistingNodeStatus(t *testing.T) {
 					LastHeartbeatTime:  util.Time{}, // placeholder
 					LastTransitionTime: util.Time{}, // placeholder
 				},
+				{
+					Type:               api.NodeMemoryPressure,
+					Status:             api.ConditionFalse,
+					Reason:             "KubeletHasSufficientMemory",
+					Message:            "kubelet has sufficient memory available",
+					LastHeartbeatTime:  util.Time{},
+					LastTransitionTime: util.Time{},
+				},
+				{
+					Type:               api.NodeDiskPressure,
+					Status:             api.ConditionFalse,
+					Reason:             "KubeletHasNoDiskPressure",
+					Message:            "kubelet has no disk pressure",
+					LastHeartbeatTime:  util.Time{},
+					LastTransitionTime: util.Time{},
+				},
+				{
+					Type:               api.NodePIDPressure,
+					Status:             api.ConditionFalse,
+					Reason:             "KubeletHasSufficientPID",
+					Message:            "kubelet has sufficient PID available",
+					LastHeartbeatTime:  util.Time{},
+					LastTransitionTime: util.Time{},
+				},
 			},
 			NodeInfo: api.NodeSystemInfo{
 				MachineID:               "123",
@@ -3489,6 +4652,11 @@ func TestUpdateExistingNodeStatus(t *testing.T) {
 			Capacity: api.ResourceList{
 				api.ResourceCPU:    *resource.NewMilliQuantity(2000, resource.DecimalSI),
 				api.ResourceMemory: *resource.NewQuantity(1024, resource.BinarySI),
+				api.ResourcePods:   *resource.NewQuantity(40, resource.DecimalSI),
+			},
+			Addresses: []api.NodeAddress{
+				{Type: api.NodeInternalIP, Address: "127.0.0.1"},
+				{Type: api.NodeLegacyHostIP, Address: "127.0.0.1"},
 			},
 		},
 	}
@@ -3511,13 +4679,36 @@ func TestUpdateExistingNodeStatus(t *testing.T) {
 		t.Errorf("expected \n%#v\n, got \n%#v", updatedNode.Status.Conditions[0].LastTransitionTime.Rfc3339Copy(),
 			util.Date(2012, 1, 1, 0, 0, 0, 0, time.UTC))
 	}
-	updatedNode.Status.Conditions[0].LastHeartbeatTime = util.Time{}
-	updatedNode.Status.Conditions[0].LastTransitionTime = util.Time{}
+	for i := range updatedNode.Status.Conditions {
+		updatedNode.Status.Conditions[i].LastHeartbeatTime = util.Time{}
+		updatedNode.Status.Conditions[i].LastTransitionTime = util.Time{}
+	}
 	if !reflect.DeepEqual(expectedNode, updatedNode) {
 		t.Errorf("expected \n%v\n, got \n%v", expectedNode, updatedNode)
 	}
 }
 
+func TestInitialNode(t *testing.T) {
+	testKubelet := newTestKubelet(t)
+	kubelet := testKubelet.kubelet
+	kubelet.nodeLabels = map[string]string{"zone": "us-central1-a"}
+	kubelet.registerSchedulable = false
+
+	node := kubelet.initialNode()
+	if node.Name != "testnode" {
+		t.Errorf("unexpected node name: %s", node.Name)
+	}
+	if node.Spec.ExternalID != "testnode" {
+		t.Errorf("unexpected external ID: %s", node.Spec.ExternalID)
+	}
+	if !node.Spec.Unschedulable {
+		t.Errorf("expected node to register as unschedulable")
+	}
+	if !reflect.DeepEqual(node.Labels, kubelet.nodeLabels) {
+		t.Errorf("unexpected labels: %v", node.Labels)
+	}
+}
+
 func TestUpdateNodeStatusError(t *testing.T) {
 	testKubelet := newTestKubelet(t)
 	kubelet := testKubelet.kubelet
@@ -3548,7 +4739,7 @@ func TestCreateMirrorPod(t *testing.T) {
 	}
 	pods := []*api.Pod{pod}
 	kl.podManager.SetPods(pods)
-	err := kl.syncPod(pod, nil, container.Pod{})
+	err := kl.syncPod(pod, nil, container.Pod{}, nil)
 	if err != nil {
 		t.Errorf("unexpected error: %v", err)
 	}
@@ -3564,6 +4755,7 @@ func TestCreateMirrorPod(t *testing.T) {
 func TestDeleteOutdatedMirrorPod(t *testing.T) {
 	testKubelet := newTestKubelet(t)
 	testKubelet.fakeCadvisor.On("MachineInfo").Return(&cadvisorApi.MachineInfo{}, nil)
+	testKubelet.fakeCadvisor.On("DockerImagesFsInfo").Return(cadvisorApiV2.FsInfo{}, nil)
 	kl := testKubelet.kubelet
 	manager := testKubelet.fakeMirrorClient
 	pod := &api.Pod{
@@ -3601,7 +4793,7 @@ func TestDeleteOutdatedMirrorPod(t *testing.T) {
 
 	pods := []*api.Pod{pod, mirrorPod}
 	kl.podManager.SetPods(pods)
-	err := kl.syncPod(pod, mirrorPod, container.Pod{})
+	err := kl.syncPod(pod, mirrorPod, container.Pod{}, nil)
 	if err != nil {
 		t.Errorf("unexpected error: %v", err)
 	}
@@ -3615,6 +4807,7 @@ func TestDeleteOutdatedMirrorPod(t *testing.T) {
 func TestDeleteOrphanedMirrorPods(t *testing.T) {
 	testKubelet := newTestKubelet(t)
 	testKubelet.fakeCadvisor.On("MachineInfo").Return(&cadvisorApi.MachineInfo{}, nil)
+	testKubelet.fakeCadvisor.On("DockerImagesFsInfo").Return(cadvisorApiV2.FsInfo{}, nil)
 	kl := testKubelet.kubelet
 	manager := testKubelet.fakeMirrorClient
 	orphanPods := []*api.Pod{
@@ -3735,6 +4928,7 @@ func TestGetContainerInfoForMirrorPods(t *testing.T) {
 func TestDoNotCacheStatusForStaticPods(t *testing.T) {
 	testKubelet := newTestKubelet(t)
 	testKubelet.fakeCadvisor.On("MachineInfo").Return(&cadvisorApi.MachineInfo{}, nil)
+	testKubelet.fakeCadvisor.On("DockerImagesFsInfo").Return(cadvisorApiV2.FsInfo{}, nil)
 	kubelet := testKubelet.kubelet
 	waitGroup := testKubelet.waitGroup
 
@@ -3749,8 +4943,10 @@ func TestDoNotCacheStatusForStaticPods(t *testing.T) {
 				},
 			},
 			Spec: api.PodSpec{
+				RestartPolicy: api.RestartPolicyAlways,
+				DNSPolicy:     api.DNSClusterFirst,
 				Containers: []api.Container{
-					{Name: "bar"},
+					{Name: "bar", Image: "foo", ImagePullPolicy: api.PullIfNotPresent},
 				},
 			},
 		},
@@ -3763,7 +4959,7 @@ func TestDoNotCacheStatusForStaticPods(t *testing.T) {
 	}
 	waitGroup.Wait()
 	podFullName := kubecontainer.GetPodFullName(pods[0])
-	status, ok := kubelet.statusManager.GetPodStatus(podFullName)
+	status, ok := kubelet.statusManager.GetPodStatus(pods[0].UID)
 	if ok {
 		t.Errorf("unexpected status %#v found for static pod %q", status, podFullName)
 	}
@@ -3793,7 +4989,7 @@ func TestHostNetworkAllowed(t *testing.T) {
 		},
 	}
 	kubelet.podManager.SetPods([]*api.Pod{pod})
-	err := kubelet.syncPod(pod, nil, container.Pod{})
+	err := kubelet.syncPod(pod, nil, container.Pod{}, nil)
 	if err != nil {
 		t.Errorf("expected pod infra creation to succeed: %v", err)
 	}
@@ -3822,7 +5018,7 @@ func TestHostNetworkDisallowed(t *testing.T) {
 			HostNetwork: true,
 		},
 	}
-	err := kubelet.syncPod(pod, nil, container.Pod{})
+	err := kubelet.syncPod(pod, nil, container.Pod{}, nil)
 	if err == nil {
 		t.Errorf("expected pod infra creation to fail")
 	}
@@ -3831,6 +5027,7 @@ func TestHostNetworkDisallowed(t *testing.T) {
 func TestSyncPodsWithRestartPolicy(t *testing.T) {
 	testKubelet := newTestKubelet(t)
 	testKubelet.fakeCadvisor.On("MachineInfo").Return(&cadvisorApi.MachineInfo{}, nil)
+	testKubelet.fakeCadvisor.On("DockerImagesFsInfo").Return(cadvisorApiV2.FsInfo{}, nil)
 	kubelet := testKubelet.kubelet
 	fakeDocker := testKubelet.fakeDocker
 	waitGroup := testKubelet.waitGroup
@@ -3915,12 +5112,11 @@ func TestSyncPodsWithRestartPolicy(t *testing.T) {
 			[]string{"list", "list",
 				// Get pod status.
 				"list", "inspect_container", "inspect_container", "inspect_container",
-				// Check the pod infra container.
-				"inspect_container",
+				// Check the pod infra container: served from the inspect cache.
 				// Restart both containers.
 				"create", "start", "create", "start",
-				// Get pod status.
-				"list", "inspect_container", "inspect_container", "inspect_container", "inspect_container", "inspect_container"},
+				// Get pod status; only the restarted containers need an inspect.
+				"list", "inspect_container", "inspect_container"},
 			[]string{"succeeded", "failed"},
 			[]string{},
 		},
@@ -3929,12 +5125,11 @@ func TestSyncPodsWithRestartPolicy(t *testing.T) {
 			[]string{"list", "list",
 				// Get pod status.
 				"list", "inspect_container", "inspect_container", "inspect_container",
-				// Check the pod infra container.
-				"inspect_container",
+				// Check the pod infra container: served from the inspect cache.
 				// Restart the failed container.
 				"create", "start",
-				// Get pod status.
-				"list", "inspect_container", "inspect_container", "inspect_container", "inspect_container"},
+				// Get pod status; only the restarted container needs an inspect.
+				"list", "inspect_container"},
 			[]string{"failed"},
 			[]string{},
 		},
@@ -3943,12 +5138,12 @@ func TestSyncPodsWithRestartPolicy(t *testing.T) {
 			[]string{"list", "list",
 				// Get pod status.
 				"list", "inspect_container", "inspect_container", "inspect_container",
-				// Check the pod infra container.
-				"inspect_container",
+				// Check the pod infra container: served from the inspect cache.
 				// Stop the last pod infra container.
 				"stop",
-				// Get pod status.
-				"list", "inspect_container", "inspect_container", "inspect_container"},
+				// Get pod status; only the stopped infra container needs an
+				// inspect.
+				"list", "inspect_container"},
 			[]string{},
 			[]string{"9876"},
 		},
@@ -3984,6 +5179,7 @@ func TestSyncPodsWithRestartPolicy(t *testing.T) {
 func TestGetPodStatusWithLastTermination(t *testing.T) {
 	testKubelet := newTestKubelet(t)
 	testKubelet.fakeCadvisor.On("MachineInfo").Return(&cadvisorApi.MachineInfo{}, nil)
+	testKubelet.fakeCadvisor.On("DockerImagesFsInfo").Return(cadvisorApiV2.FsInfo{}, nil)
 	kubelet := testKubelet.kubelet
 	fakeDocker := testKubelet.fakeDocker
 	waitGroup := testKubelet.waitGroup
@@ -4072,6 +5268,9 @@ func TestGetPodStatusWithLastTermination(t *testing.T) {
 		fakeDocker.ExitedContainerList = exitedAPIContainers
 		fakeDocker.ContainerMap = containerMap
 		fakeDocker.ClearCalls()
+		// Every iteration simulates a brand new pod that reuses the UID, so
+		// drop the history persisted by the previous one.
+		kubelet.containerHistory.forget("12345678")
 		pods := []*api.Pod{
 			{
 				ObjectMeta: api.ObjectMeta{
@@ -4152,7 +5351,7 @@ func TestGetPodCreationFailureReason(t *testing.T) {
 	pods := []*api.Pod{pod}
 	kubelet.podManager.SetPods(pods)
 	kubelet.volumeManager.SetVolumes(pod.UID, volumeMap{})
-	_, err := kubelet.containerManager.RunContainer(pod, &pod.Spec.Containers[0], kubelet, kubelet.handlerRunner, "", "")
+	_, err := kubelet.containerManager.RunContainer(pod, &pod.Spec.Containers[0], kubelet, kubelet.handlerRunner, "", "", "", "")
 	if err == nil {
 		t.Errorf("expected error, found nil")
 	}
@@ -4175,6 +5374,7 @@ func TestGetPodCreationFailureReason(t *testing.T) {
 func TestGetRestartCount(t *testing.T) {
 	testKubelet := newTestKubelet(t)
 	testKubelet.fakeCadvisor.On("MachineInfo").Return(&cadvisorApi.MachineInfo{}, nil)
+	testKubelet.fakeCadvisor.On("DockerImagesFsInfo").Return(cadvisorApiV2.FsInfo{}, nil)
 	kubelet := testKubelet.kubelet
 	fakeDocker := testKubelet.fakeDocker
 
@@ -4259,6 +5459,77 @@ func TestGetRestartCount(t *testing.T) {
 	verifyRestartCount(&pod, 2)
 }
 
+func TestGetRestartCountFromLabels(t *testing.T) {
+	testKubelet := newTestKubelet(t)
+	testKubelet.fakeCadvisor.On("MachineInfo").Return(&cadvisorApi.MachineInfo{}, nil)
+	testKubelet.fakeCadvisor.On("DockerImagesFsInfo").Return(cadvisorApiV2.FsInfo{}, nil)
+	kubelet := testKubelet.kubelet
+	fakeDocker := testKubelet.fakeDocker
+
+	containers := []api.Container{
+		{Name: "bar"},
+	}
+	pod := api.Pod{
+		ObjectMeta: api.ObjectMeta{
+			UID:       "12345678",
+			Name:      "foo",
+			Namespace: "new",
+		},
+		Spec: api.PodSpec{
+			Containers: containers,
+		},
+	}
+
+	names := []string{"/k8s_bar." + strconv.FormatUint(dockertools.HashContainer(&containers[0]), 16) + "_foo_new_12345678_0"}
+	currTime := time.Now()
+	containerMap := map[string]*docker.Container{
+		"1234": {
+			ID:     "1234",
+			Name:   "bar",
+			Config: &docker.Config{},
+			State: docker.State{
+				ExitCode:   42,
+				StartedAt:  currTime.Add(-60 * time.Second),
+				FinishedAt: currTime.Add(-60 * time.Second),
+			},
+		},
+		"5678": {
+			ID:   "5678",
+			Name: "bar",
+			Config: &docker.Config{
+				Labels: map[string]string{"io.kubernetes.container.restartCount": "5"},
+			},
+			State: docker.State{
+				ExitCode:   42,
+				StartedAt:  currTime.Add(-30 * time.Second),
+				FinishedAt: currTime.Add(-30 * time.Second),
+			},
+		},
+	}
+	fakeDocker.ContainerMap = containerMap
+
+	verifyRestartCount := func(pod *api.Pod, expectedCount int) api.PodStatus {
+		status, err := kubelet.generatePodStatus(pod)
+		if err != nil {
+			t.Errorf("unexpected error %v", err)
+		}
+		restartCount := status.ContainerStatuses[0].RestartCount
+		if restartCount != expectedCount {
+			t.Errorf("expected %d restart count, got %d", expectedCount, restartCount)
+		}
+		return status
+	}
+
+	// The newest container carries its restart count as a label; older dead
+	// containers must not be counted on top of it.
+	fakeDocker.ExitedContainerList = []docker.APIContainers{{Names: names, ID: "5678"}, {Names: names, ID: "1234"}}
+	pod.Status = verifyRestartCount(&pod, 5)
+
+	// The unlabeled dead container has been GC'd; the count is unaffected.
+	fakeDocker.ExitedContainerList = []docker.APIContainers{{Names: names, ID: "5678"}}
+	verifyRestartCount(&pod, 5)
+}
+
 func TestFilterOutTerminatedPods(t *testing.T) {
 	testKubelet := newTestKubelet(t)
 	kubelet := testKubelet.kubelet
@@ -4275,3 +5546,331 @@ func TestFilterOutTerminatedPods(t *testing.T) {
 		t.Errorf("expected %#v, got %#v", expected, actual)
 	}
 }
+
+func TestRefreshMachineInfo(t *testing.T) {
+	testKubelet := newTestKubelet(t)
+	kubelet := testKubelet.kubelet
+	recorder := &recordingRecorder{}
+	kubelet.recorder = recorder
+	kubelet.machineInfo = &cadvisorApi.MachineInfo{NumCores: 2, MemoryCapacity: 1024}
+
+	// A refresh that finds the same capacity must stay quiet.
+	testKubelet.fakeCadvisor.On("MachineInfo").Return(&cadvisorApi.MachineInfo{NumCores: 2, MemoryCapacity: 1024}, nil).Once()
+	kubelet.refreshMachineInfo()
+	if len(recorder.recorded()) != 0 {
+		t.Errorf("unexpected events: %v", recorder.recorded())
+	}
+
+	// Hot-added memory must update the cached info and be announced.
+	testKubelet.fakeCadvisor.On("MachineInfo").Return(&cadvisorApi.MachineInfo{NumCores: 2, MemoryCapacity: 2048}, nil)
+	kubelet.refreshMachineInfo()
+	info, err := kubelet.GetCachedMachineInfo()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if info.MemoryCapacity != 2048 {
+		t.Errorf("expected the cached memory capacity to be refreshed, got %d", info.MemoryCapacity)
+	}
+	events := recorder.recorded()
+	if len(events) != 1 || events[0].reason != "nodeCapacityChanged" {
+		t.Errorf("expected a nodeCapacityChanged event, got %v", events)
+	}
+}
+
+func TestKillUnwantedPodsAdoptionGracePeriod(t *testing.T) {
+	testKubelet := newTestKubelet(t)
+	kubelet := testKubelet.kubelet
+	fakeDocker := testKubelet.fakeDocker
+	recorder := &recordingRecorder{}
+	kubelet.recorder = recorder
+	kubelet.adoptionGracePeriod = podAdoptionGracePeriod
+	kubelet.sourcesReadyTime = time.Now()
+
+	runningPods := []*kubecontainer.Pod{
+		{
+			ID:         "12345678",
+			Name:       "foo",
+			Namespace:  "new",
+			Containers: []*kubecontainer.Container{{ID: "1234", Name: "bar"}},
+		},
+	}
+
+	// Within the grace window an unknown pod is left running, and its
+	// discovery is announced exactly once.
+	for i := 0; i < 2; i++ {
+		if _, err := kubelet.killUnwantedPods(map[types.UID]empty{}, map[string]empty{}, runningPods); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+	if len(fakeDocker.Stopped) != 0 {
+		t.Errorf("unexpected containers stopped: %v", fakeDocker.Stopped)
+	}
+	events := recorder.recorded()
+	if len(events) != 1 || events[0].reason != "unknownPod" {
+		t.Errorf("expected a single unknownPod event, got %v", events)
+	}
+
+	// A source claiming the pod adopts it.
+	desiredPods := map[types.UID]empty{"12345678": {}}
+	if _, err := kubelet.killUnwantedPods(desiredPods, map[string]empty{}, runningPods); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	events = recorder.recorded()
+	if len(events) != 2 || events[1].reason != "podAdopted" {
+		t.Errorf("expected a podAdopted event, got %v", events)
+	}
+
+	// A pod that no source claims before the window expires is fenced.
+	kubelet.adoptionCandidates["12345678"] = empty{}
+	kubelet.sourcesReadyTime = time.Now().Add(-2 * podAdoptionGracePeriod)
+	if _, err := kubelet.killUnwantedPods(map[types.UID]empty{}, map[string]empty{}, runningPods); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(fakeDocker.Stopped) != 1 || fakeDocker.Stopped[0] != "1234" {
+		t.Errorf("expected container %q to be stopped, got %v", "1234", fakeDocker.Stopped)
+	}
+	events = recorder.recorded()
+	if len(events) != 3 || events[2].reason != "fencingPod" {
+		t.Errorf("expected a fencingPod event, got %v", events)
+	}
+	if len(kubelet.adoptionCandidates) != 0 {
+		t.Errorf("expected no remaining adoption candidates, got %v", kubelet.adoptionCandidates)
+	}
+}
+
+func TestRetryTeardownStep(t *testing.T) {
+	attempts := 0
+	err := retryTeardownStep("flaky step", func() error {
+		attempts++
+		if attempts < 3 {
+			return fmt.Errorf("transient error")
+		}
+		return nil
+	})
+	if err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+	if attempts != 3 {
+		t.Errorf("expected 3 attempts, got %d", attempts)
+	}
+
+	attempts = 0
+	err = retryTeardownStep("broken step", func() error {
+		attempts++
+		return fmt.Errorf("persistent error")
+	})
+	if err == nil {
+		t.Errorf("expected an error")
+	}
+	if attempts != teardownStepAttempts {
+		t.Errorf("expected %d attempts, got %d", teardownStepAttempts, attempts)
+	}
+}
+
+func TestTeardownPodOrdering(t *testing.T) {
+	testKubelet := newTestKubelet(t)
+	kubelet := testKubelet.kubelet
+	fakeDocker := testKubelet.fakeDocker
+	fakeDocker.ContainerList = []docker.APIContainers{
+		{
+			Names: []string{"/k8s_bar_foo_new_12345678_42"},
+			ID:    "5678",
+		},
+		{
+			// pod infra container
+			Names: []string{"/k8s_POD_foo_new_12345678_42"},
+			ID:    "9876",
+		},
+	}
+	pod := &kubecontainer.Pod{
+		ID:        "12345678",
+		Name:      "foo",
+		Namespace: "new",
+		Containers: []*kubecontainer.Container{
+			{ID: "9876", Name: dockertools.PodInfraContainerName},
+			{ID: "5678", Name: "bar"},
+		},
+	}
+
+	if err := kubelet.teardownPod(pod); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	// The regular container must be stopped before the pod infra container,
+	// so the pod's network namespace outlives its users.
+	if !reflect.DeepEqual(fakeDocker.Stopped, []string{"5678", "9876"}) {
+		t.Errorf("wrong stop order: %v", fakeDocker.Stopped)
+	}
+}
+
+func TestUpdateNodeHeartbeat(t *testing.T) {
+	testKubelet := newTestKubelet(t)
+	kubelet := testKubelet.kubelet
+	kubeClient := testKubelet.fakeKubeClient
+	existingCapacity := api.ResourceList{
+		api.ResourceCPU:    *resource.NewMilliQuantity(2000, resource.DecimalSI),
+		api.ResourceMemory: *resource.NewQuantity(1024, resource.BinarySI),
+	}
+	kubeClient.ReactFn = testclient.NewSimpleFake(&api.NodeList{Items: []api.Node{
+		{
+			ObjectMeta: api.ObjectMeta{Name: "testnode"},
+			Status: api.NodeStatus{
+				Conditions: []api.NodeCondition{
+					{
+						Type:               api.NodeReady,
+						Status:             api.ConditionTrue,
+						Reason:             fmt.Sprintf("kubelet is posting ready status"),
+						LastHeartbeatTime:  util.Date(2012, 1, 1, 0, 0, 0, 0, time.UTC),
+						LastTransitionTime: util.Date(2012, 1, 1, 0, 0, 0, 0, time.UTC),
+					},
+				},
+				Capacity: existingCapacity,
+			},
+		},
+	}}).ReactFn
+
+	if err := kubelet.updateNodeHeartbeat(); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+	if len(kubeClient.Actions) != 2 {
+		t.Fatalf("unexpected actions: %v", kubeClient.Actions)
+	}
+	updatedNode, ok := kubeClient.Actions[1].Value.(*api.Node)
+	if !ok {
+		t.Fatalf("unexpected object type")
+	}
+	// Only the heartbeat time may change; the rest of the status is left
+	// exactly as the apiserver had it.
+	if reflect.DeepEqual(updatedNode.Status.Conditions[0].LastHeartbeatTime.Rfc3339Copy().UTC(), util.Date(2012, 1, 1, 0, 0, 0, 0, time.UTC).Time) {
+		t.Errorf("expected the heartbeat time to be refreshed")
+	}
+	if !reflect.DeepEqual(updatedNode.Status.Conditions[0].LastTransitionTime.Rfc3339Copy().UTC(), util.Date(2012, 1, 1, 0, 0, 0, 0, time.UTC).Time) {
+		t.Errorf("unexpected transition time: %v", updatedNode.Status.Conditions[0].LastTransitionTime)
+	}
+	if len(updatedNode.Status.Conditions) != 1 {
+		t.Errorf("unexpected conditions: %v", updatedNode.Status.Conditions)
+	}
+	if c := updatedNode.Status.Capacity[api.ResourceCPU]; c.MilliValue() != 2000 {
+		t.Errorf("unexpected capacity: %v", updatedNode.Status.Capacity)
+	}
+	if m := updatedNode.Status.Capacity[api.ResourceMemory]; m.Value() != 1024 {
+		t.Errorf("unexpected capacity: %v", updatedNode.Status.Capacity)
+	}
+	if updatedNode.Status.NodeInfo.KubeletVersion != "" {
+		t.Errorf("expected the heartbeat to leave node info alone, got %v", updatedNode.Status.NodeInfo)
+	}
+}
+
+func TestUpdateNodeHeartbeatPublishesTransitions(t *testing.T) {
+	testKubelet := newTestKubelet(t)
+	kubelet := testKubelet.kubelet
+	kubeClient := testKubelet.fakeKubeClient
+	kubeClient.ReactFn = testclient.NewSimpleFake(&api.NodeList{Items: []api.Node{
+		{
+			ObjectMeta: api.ObjectMeta{Name: "testnode"},
+			Status: api.NodeStatus{
+				Conditions: []api.NodeCondition{
+					{
+						Type:               api.NodeReady,
+						Status:             api.ConditionFalse,
+						Reason:             "container runtime is not ready",
+						LastHeartbeatTime:  util.Date(2012, 1, 1, 0, 0, 0, 0, time.UTC),
+						LastTransitionTime: util.Date(2012, 1, 1, 0, 0, 0, 0, time.UTC),
+					},
+				},
+			},
+		},
+	}}).ReactFn
+	mockCadvisor := testKubelet.fakeCadvisor
+	mockCadvisor.On("MachineInfo").Return(&cadvisorApi.MachineInfo{}, nil)
+	rootInfo := &cadvisorApi.ContainerInfo{
+		ContainerReference: cadvisorApi.ContainerReference{Name: "/"},
+		Stats:              []*cadvisorApi.ContainerStats{{}},
+	}
+	mockCadvisor.On("ContainerInfo", "/", &cadvisorApi.ContainerInfoRequest{NumStats: 1}).Return(rootInfo, nil)
+	mockCadvisor.On("VersionInfo").Return(&cadvisorApi.VersionInfo{}, nil)
+
+	// The runtime recovered since the posted status, so the cheap heartbeat
+	// must be abandoned in favor of a full status update.
+	if err := kubelet.updateNodeHeartbeat(); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+	if len(kubeClient.Actions) != 3 {
+		t.Fatalf("unexpected actions: %v", kubeClient.Actions)
+	}
+	updatedNode, ok := kubeClient.Actions[2].Value.(*api.Node)
+	if !ok {
+		t.Fatalf("unexpected object type")
+	}
+	if updatedNode.Status.Conditions[0].Status != api.ConditionTrue {
+		t.Errorf("expected the ready transition to be published, got %v", updatedNode.Status.Conditions[0])
+	}
+	if updatedNode.Status.NodeInfo.KubeletVersion != version.Get().String() {
+		t.Errorf("expected a full status update, got node info %v", updatedNode.Status.NodeInfo)
+	}
+}
+
+func TestUpdateNodeStatusSkipsUnchangedReports(t *testing.T) {
+	testKubelet := newTestKubelet(t)
+	kubelet := testKubelet.kubelet
+	kubelet.nodeIP = net.ParseIP("127.0.0.1")
+	kubeClient := testKubelet.fakeKubeClient
+	kubeClient.ReactFn = testclient.NewSimpleFake(&api.NodeList{Items: []api.Node{
+		{ObjectMeta: api.ObjectMeta{Name: "testnode"}},
+	}}).ReactFn
+	mockCadvisor := testKubelet.fakeCadvisor
+	mockCadvisor.On("MachineInfo").Return(&cadvisorApi.MachineInfo{}, nil)
+	rootInfo := &cadvisorApi.ContainerInfo{
+		ContainerReference: cadvisorApi.ContainerReference{Name: "/"},
+		Stats:              []*cadvisorApi.ContainerStats{{}},
+	}
+	mockCadvisor.On("ContainerInfo", "/", &cadvisorApi.ContainerInfoRequest{NumStats: 1}).Return(rootInfo, nil)
+	mockCadvisor.On("VersionInfo").Return(&cadvisorApi.VersionInfo{}, nil)
+
+	// The first report and the identical ones up to the limit are posted;
+	// after that, unchanged reports only fetch the node.
+	reports := nodeStatusIdenticalReportLimit + 3
+	for i := 0; i < reports; i++ {
+		if err := kubelet.updateNodeStatus(); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+	expected := reports + 1 + nodeStatusIdenticalReportLimit
+	if len(kubeClient.Actions) != expected {
+		t.Errorf("expected %d actions, got %d", expected, len(kubeClient.Actions))
+	}
+
+	// A capacity change must be posted immediately.
+	kubelet.machineInfo = &cadvisorApi.MachineInfo{NumCores: 4, MemoryCapacity: 2048}
+	if err := kubelet.updateNodeStatus(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(kubeClient.Actions) != expected+2 {
+		t.Errorf("expected a changed status to be posted, got %d actions", len(kubeClient.Actions))
+	}
+}
+
+func TestImagesForNodeStatus(t *testing.T) {
+	images := []docker.APIImages{
+		{ID: "1", RepoTags: []string{"example.com/app:v1"}, Size: 100},
+		{ID: "2", RepoTags: []string{}, Size: 400},
+		{ID: "3", RepoTags: []string{"example.com/app:v2", "example.com/app:latest"}, Size: 300},
+		{ID: "4", RepoTags: []string{"example.com/base:v1"}, Size: 200},
+	}
+	expected := []api.ContainerImage{
+		{Names: []string{"example.com/app:v2", "example.com/app:latest"}, SizeBytes: 300},
+		{Names: []string{"example.com/base:v1"}, SizeBytes: 200},
+		{Names: []string{"example.com/app:v1"}, SizeBytes: 100},
+	}
+	result := imagesForNodeStatus(images)
+	if !reflect.DeepEqual(result, expected) {
+		t.Errorf("expected images %#v, got %#v", expected, result)
+	}
+
+	many := make([]docker.APIImages, nodeStatusMaxImages+5)
+	for i := range many {
+		many[i] = docker.APIImages{RepoTags: []string{fmt.Sprintf("example.com/app:v%d", i)}, Size: int64(i)}
+	}
+	if result := imagesForNodeStatus(many); len(result) != nodeStatusMaxImages {
+		t.Errorf("expected %d images, got %d", nodeStatusMaxImages, len(result))
+	}
+}
@@ -65,6 +65,88 @@ var (
 		},
 		[]string{"operation_type"},
 	)
+	DockerErrors = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Subsystem: kubeletSubsystem,
+			Name:      "docker_errors",
+			Help:      "Cumulative number of Docker errors. Broken down by operation type and error class.",
+		},
+		[]string{"operation_type", "error_class"},
+	)
+	PodWorkerLatency = prometheus.NewSummaryVec(
+		prometheus.SummaryOpts{
+			Subsystem: kubeletSubsystem,
+			Name:      "pod_worker_latency_microseconds",
+			Help:      "Latency in microseconds to sync a single pod in the pod worker. Broken down by operation type: create, update, or sync",
+		},
+		[]string{"operation_type"},
+	)
+	PodStartLatency = prometheus.NewSummary(
+		prometheus.SummaryOpts{
+			Subsystem: kubeletSubsystem,
+			Name:      "pod_start_latency_microseconds",
+			Help:      "Latency in microseconds from seeing a new pod to its first successful sync, i.e. until its containers have been started.",
+		},
+	)
+	ContainerRelistLatency = prometheus.NewSummary(
+		prometheus.SummaryOpts{
+			Subsystem: kubeletSubsystem,
+			Name:      "container_relist_latency_microseconds",
+			Help:      "Latency in microseconds to relist the running pods from the container runtime.",
+		},
+	)
+	DNSSearchPathTruncations = prometheus.NewCounter(
+		prometheus.CounterOpts{
+			Subsystem: kubeletSubsystem,
+			Name:      "dns_search_path_truncations",
+			Help:      "Cumulative number of pods whose DNS search path exceeded resolver limits and was truncated.",
+		},
+	)
+	ShadowedPodCount = prometheus.NewGauge(
+		prometheus.GaugeOpts{
+			Subsystem: kubeletSubsystem,
+			Name:      "shadowed_pod_count",
+			Help:      "Number of pods currently shadowed by the same pod from a higher precedence config source.",
+		},
+	)
+	OrphanedPodCleanupFailures = prometheus.NewCounter(
+		prometheus.CounterOpts{
+			Subsystem: kubeletSubsystem,
+			Name:      "orphaned_pod_cleanup_failures",
+			Help:      "Cumulative number of failures while cleaning up directories and volumes of orphaned pods.",
+		},
+	)
+	CoalescedPodUpdates = prometheus.NewCounter(
+		prometheus.CounterOpts{
+			Subsystem: kubeletSubsystem,
+			Name:      "coalesced_pod_updates",
+			Help:      "Cumulative number of pod updates dropped because a newer update for the same pod superseded them before delivery.",
+		},
+	)
+	SyncActionErrors = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Subsystem: kubeletSubsystem,
+			Name:      "pod_sync_action_errors",
+			Help:      "Cumulative number of failed pod sync actions, partitioned by action.",
+		},
+		[]string{"action"},
+	)
+	ProbeResults = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Subsystem: kubeletSubsystem,
+			Name:      "probe_results",
+			Help:      "Cumulative number of probe executions, partitioned by pod, container, probe type and result.",
+		},
+		[]string{"pod", "namespace", "container", "probe_type", "result"},
+	)
+	ProbeLatency = prometheus.NewSummaryVec(
+		prometheus.SummaryOpts{
+			Subsystem: kubeletSubsystem,
+			Name:      "probe_latency_microseconds",
+			Help:      "Latency in microseconds of a single probe execution, partitioned by pod, container and probe type.",
+		},
+		[]string{"pod", "namespace", "container", "probe_type"},
+	)
 )
 
 var registerMetrics sync.Once
@@ -76,8 +158,19 @@ func Register(containerCache kubecontainer.RuntimeCache) {
 		prometheus.MustRegister(ImagePullLatency)
 		prometheus.MustRegister(SyncPodLatency)
 		prometheus.MustRegister(DockerOperationsLatency)
+		prometheus.MustRegister(DockerErrors)
 		prometheus.MustRegister(SyncPodsLatency)
+		prometheus.MustRegister(PodWorkerLatency)
+		prometheus.MustRegister(PodStartLatency)
+		prometheus.MustRegister(ContainerRelistLatency)
 		prometheus.MustRegister(ContainersPerPodCount)
+		prometheus.MustRegister(DNSSearchPathTruncations)
+		prometheus.MustRegister(ShadowedPodCount)
+		prometheus.MustRegister(OrphanedPodCleanupFailures)
+		prometheus.MustRegister(CoalescedPodUpdates)
+		prometheus.MustRegister(SyncActionErrors)
+		prometheus.MustRegister(ProbeResults)
+		prometheus.MustRegister(ProbeLatency)
 		prometheus.MustRegister(newPodAndContainerCollector(containerCache))
 	})
 }
@@ -0,0 +1,87 @@
+/*
+Copyright 2015 Google Inc. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package kubelet
+
+import (
+	"github.com/GoogleCloudPlatform/kubernetes/pkg/api"
+	"github.com/GoogleCloudPlatform/kubernetes/pkg/client/record"
+	"github.com/GoogleCloudPlatform/kubernetes/pkg/kubelet/cadvisor"
+
+	"github.com/golang/glog"
+	"github.com/google/cadvisor/events"
+	cadvisorApi "github.com/google/cadvisor/info/v1"
+)
+
+// OOMWatcher watches for containers killed by the kernel OOM killer and
+// surfaces the kills as pod events. Implementations are pluggable so the
+// notification source (cadvisor's kernel log parsing, cgroup memory
+// notifiers, ...) can vary by platform.
+type OOMWatcher interface {
+	// Start begins watching and returns immediately. Kills that cannot be
+	// attributed to a pod are recorded against the given reference,
+	// typically the node.
+	Start(ref *api.ObjectReference) error
+}
+
+// podResolver maps the cgroup path of an OOM killed container back to the
+// pod it belongs to.
+type podResolver func(cgroupPath string) (*api.Pod, bool)
+
+// realOOMWatcher consumes OOM kill events streamed by cadvisor, which
+// watches the kernel log for them.
+type realOOMWatcher struct {
+	cadvisor   cadvisor.Interface
+	recorder   record.EventRecorder
+	resolvePod podResolver
+}
+
+func newOOMWatcher(cadvisor cadvisor.Interface, recorder record.EventRecorder, resolvePod podResolver) OOMWatcher {
+	return &realOOMWatcher{
+		cadvisor:   cadvisor,
+		recorder:   recorder,
+		resolvePod: resolvePod,
+	}
+}
+
+func (ow *realOOMWatcher) Start(ref *api.ObjectReference) error {
+	request := events.NewRequest()
+	request.EventType[cadvisorApi.EventOom] = true
+	eventChannel, err := ow.cadvisor.WatchForEvents(request)
+	if err != nil {
+		return err
+	}
+
+	go func() {
+		for event := range eventChannel.GetChannel() {
+			glog.V(2).Infof("Got OOM event from cadvisor: %v", event)
+			ow.recordEvent(ref, event)
+		}
+		glog.Errorf("Unexpectedly stopped receiving OOM notifications from cadvisor")
+	}()
+	return nil
+}
+
+// recordEvent attributes an OOM kill to the pod owning the killed
+// container's cgroup, falling back to the given reference when the cgroup
+// does not belong to any pod the kubelet knows about.
+func (ow *realOOMWatcher) recordEvent(ref *api.ObjectReference, event *cadvisorApi.Event) {
+	if pod, found := ow.resolvePod(event.ContainerName); found {
+		ow.recorder.Eventf(pod, "oomKilling", "Memory cgroup out of memory: container in cgroup %s was OOM killed", event.ContainerName)
+		return
+	}
+	ow.recorder.Event(ref, "systemOOM", "System OOM encountered")
+}
@@ -0,0 +1,98 @@
+/*
+Copyright 2015 Google Inc. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package kubelet
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/GoogleCloudPlatform/kubernetes/pkg/api"
+	"github.com/GoogleCloudPlatform/kubernetes/pkg/kubelet/cadvisor"
+	"github.com/GoogleCloudPlatform/kubernetes/pkg/runtime"
+
+	"github.com/google/cadvisor/events"
+	cadvisorApi "github.com/google/cadvisor/info/v1"
+)
+
+// recordingRecorder remembers the object and reason of every event.
+type recordingRecorder struct {
+	sync.Mutex
+	events []recordedEvent
+}
+
+type recordedEvent struct {
+	object runtime.Object
+	reason string
+}
+
+func (r *recordingRecorder) Event(object runtime.Object, reason, message string) {
+	r.Lock()
+	defer r.Unlock()
+	r.events = append(r.events, recordedEvent{object, reason})
+}
+
+func (r *recordingRecorder) Eventf(object runtime.Object, reason, messageFmt string, args ...interface{}) {
+	r.Event(object, reason, messageFmt)
+}
+
+func (r *recordingRecorder) recorded() []recordedEvent {
+	r.Lock()
+	defer r.Unlock()
+	return append([]recordedEvent{}, r.events...)
+}
+
+func TestOOMWatcherRecordsEvents(t *testing.T) {
+	pod := &api.Pod{ObjectMeta: api.ObjectMeta{UID: "12345678", Name: "foo", Namespace: "new"}}
+	resolver := func(cgroupPath string) (*api.Pod, bool) {
+		if cgroupPath == "/docker/podcontainer" {
+			return pod, true
+		}
+		return nil, false
+	}
+
+	mockCadvisor := &cadvisor.Mock{}
+	eventChannel := events.NewEventChannel(0)
+	mockCadvisor.On("WatchForEvents").Return(eventChannel, nil)
+	recorder := &recordingRecorder{}
+	watcher := newOOMWatcher(mockCadvisor, recorder, resolver)
+
+	nodeRef := &api.ObjectReference{Kind: "Node", Name: "testnode"}
+	if err := watcher.Start(nodeRef); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	eventChannel.GetChannel() <- &cadvisorApi.Event{ContainerName: "/docker/podcontainer", EventType: cadvisorApi.EventOom}
+	eventChannel.GetChannel() <- &cadvisorApi.Event{ContainerName: "/docker/unknown", EventType: cadvisorApi.EventOom}
+
+	var got []recordedEvent
+	for i := 0; i < 50; i++ {
+		if got = recorder.recorded(); len(got) == 2 {
+			break
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	if len(got) != 2 {
+		t.Fatalf("expected 2 events, got %v", got)
+	}
+	if got[0].object != runtime.Object(pod) || got[0].reason != "oomKilling" {
+		t.Errorf("expected an oomKilling event on the pod, got %v", got[0])
+	}
+	if got[1].object != runtime.Object(nodeRef) || got[1].reason != "systemOOM" {
+		t.Errorf("expected a systemOOM event on the node, got %v", got[1])
+	}
+}
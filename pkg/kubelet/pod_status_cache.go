@@ -0,0 +1,62 @@
+/*
+Copyright 2015 Google Inc. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package kubelet
+
+import (
+	"sync"
+
+	"github.com/GoogleCloudPlatform/kubernetes/pkg/api"
+	"github.com/GoogleCloudPlatform/kubernetes/pkg/types"
+)
+
+// podStatusCache holds the last generated status of each pod so that syncPod
+// does not have to query docker inline. It is refreshed asynchronously by the
+// kubelet's status reconciliation loop and after every completed sync.
+type podStatusCache struct {
+	lock     sync.RWMutex
+	statuses map[types.UID]api.PodStatus
+}
+
+func newPodStatusCache() *podStatusCache {
+	return &podStatusCache{statuses: make(map[types.UID]api.PodStatus)}
+}
+
+// Get returns the cached status of the given pod, if any.
+func (c *podStatusCache) Get(uid types.UID) (api.PodStatus, bool) {
+	c.lock.RLock()
+	defer c.lock.RUnlock()
+	status, found := c.statuses[uid]
+	return status, found
+}
+
+// Set stores the status of the given pod.
+func (c *podStatusCache) Set(uid types.UID, status api.PodStatus) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	c.statuses[uid] = status
+}
+
+// Prune drops cached statuses of pods that are not in desiredPods.
+func (c *podStatusCache) Prune(desiredPods map[types.UID]empty) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	for uid := range c.statuses {
+		if _, found := desiredPods[uid]; !found {
+			delete(c.statuses, uid)
+		}
+	}
+}
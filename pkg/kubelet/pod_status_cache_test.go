@@ -0,0 +1,77 @@
+/*
+Copyright 2015 Google Inc. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package kubelet
+
+import (
+	"testing"
+
+	"github.com/GoogleCloudPlatform/kubernetes/pkg/api"
+	kubecontainer "github.com/GoogleCloudPlatform/kubernetes/pkg/kubelet/container"
+	"github.com/GoogleCloudPlatform/kubernetes/pkg/kubelet/dockertools"
+	"github.com/GoogleCloudPlatform/kubernetes/pkg/types"
+)
+
+func TestPodStatusCache(t *testing.T) {
+	cache := newPodStatusCache()
+	uid := types.UID("12345678")
+	if _, found := cache.Get(uid); found {
+		t.Errorf("expected a miss on an empty cache")
+	}
+	cache.Set(uid, api.PodStatus{Phase: api.PodRunning})
+	status, found := cache.Get(uid)
+	if !found || status.Phase != api.PodRunning {
+		t.Errorf("unexpected cached status: %v, %v", status, found)
+	}
+
+	cache.Prune(map[types.UID]empty{uid: {}})
+	if _, found := cache.Get(uid); !found {
+		t.Errorf("expected the desired pod's status to survive pruning")
+	}
+	cache.Prune(map[types.UID]empty{})
+	if _, found := cache.Get(uid); found {
+		t.Errorf("expected the status of a removed pod to be pruned")
+	}
+}
+
+func TestStatusMatchesRunningPod(t *testing.T) {
+	status := api.PodStatus{
+		ContainerStatuses: []api.ContainerStatus{
+			{Name: "bar", ContainerID: "docker://1234", State: api.ContainerState{Running: &api.ContainerStateRunning{}}},
+			{Name: "old", ContainerID: "docker://4567", State: api.ContainerState{Termination: &api.ContainerStateTerminated{}}},
+		},
+	}
+	runningPod := kubecontainer.Pod{
+		Containers: []*kubecontainer.Container{
+			{ID: "1234", Name: "bar"},
+			{ID: "9876", Name: dockertools.PodInfraContainerName},
+		},
+	}
+	if !statusMatchesRunningPod(&status, runningPod) {
+		t.Errorf("expected the status to match the running pod")
+	}
+
+	// A container died since the status was generated.
+	if statusMatchesRunningPod(&status, kubecontainer.Pod{}) {
+		t.Errorf("expected a mismatch after a running container disappeared")
+	}
+
+	// A container started since the status was generated.
+	runningPod.Containers = append(runningPod.Containers, &kubecontainer.Container{ID: "5678", Name: "new"})
+	if statusMatchesRunningPod(&status, runningPod) {
+		t.Errorf("expected a mismatch after a new container appeared")
+	}
+}
@@ -23,12 +23,22 @@ import (
 	"github.com/GoogleCloudPlatform/kubernetes/pkg/api"
 	"github.com/GoogleCloudPlatform/kubernetes/pkg/client/record"
 	kubecontainer "github.com/GoogleCloudPlatform/kubernetes/pkg/kubelet/container"
+	"github.com/GoogleCloudPlatform/kubernetes/pkg/kubelet/metrics"
 	"github.com/GoogleCloudPlatform/kubernetes/pkg/types"
 	"github.com/GoogleCloudPlatform/kubernetes/pkg/util"
 	"github.com/golang/glog"
 )
 
-type syncPodFnType func(*api.Pod, *api.Pod, kubecontainer.Pod) error
+type syncPodFnType func(*api.Pod, *api.Pod, kubecontainer.Pod, <-chan struct{}) error
+
+const (
+	// Backoff between retries of a pod sync after an error; doubled on every
+	// consecutive failure up to the max, and reset on success.
+	podSyncRetryBackoffInitial = 1 * time.Second
+	podSyncRetryBackoffMax     = 1 * time.Minute
+	// How often the retry queue is polled for pods that have become eligible.
+	podWorkerRetryPollInterval = 1 * time.Second
+)
 
 type podWorkers struct {
 	// Protects all per worker fields.
@@ -52,6 +62,20 @@ type podWorkers struct {
 	// different pods at the same time.
 	syncPodFn syncPodFnType
 
+	// Cancel channels of in-flight syncs; closed when the pod's work is
+	// abandoned so the sync can give up early.
+	syncCancels map[types.UID]chan struct{}
+
+	// Pods waiting to be re-synced after a failed sync, and the work to
+	// deliver when they become eligible.
+	retryQueue      *workQueue
+	retryWorkUpdate map[types.UID]workUpdate
+	// Current retry backoff per pod; reset on a successful sync.
+	syncBackoff map[types.UID]time.Duration
+
+	// Bounds the number of pod syncs running at once; nil means unbounded.
+	syncSemaphore chan struct{}
+
 	// The EventRecorder to use
 	recorder record.EventRecorder
 }
@@ -63,18 +87,30 @@ type workUpdate struct {
 	// The mirror pod of pod; nil if it does not exist.
 	mirrorPod *api.Pod
 
+	// The type of the sync that triggered this update.
+	syncType metrics.SyncPodType
+
 	// Function to call when the update is complete.
 	updateCompleteFn func()
 }
 
 func newPodWorkers(runtimeCache kubecontainer.RuntimeCache, syncPodFn syncPodFnType,
-	recorder record.EventRecorder) *podWorkers {
+	recorder record.EventRecorder, maxConcurrentSyncs int) *podWorkers {
+	var syncSemaphore chan struct{}
+	if maxConcurrentSyncs > 0 {
+		syncSemaphore = make(chan struct{}, maxConcurrentSyncs)
+	}
 	return &podWorkers{
 		podUpdates:                map[types.UID]chan workUpdate{},
 		isWorking:                 map[types.UID]bool{},
 		lastUndeliveredWorkUpdate: map[types.UID]workUpdate{},
+		syncCancels:               map[types.UID]chan struct{}{},
 		runtimeCache:              runtimeCache,
 		syncPodFn:                 syncPodFn,
+		retryQueue:                newWorkQueue(),
+		retryWorkUpdate:           map[types.UID]workUpdate{},
+		syncBackoff:               map[types.UID]time.Duration{},
+		syncSemaphore:             syncSemaphore,
 		recorder:                  recorder,
 	}
 }
@@ -84,34 +120,109 @@ func (p *podWorkers) managePodLoop(podUpdates <-chan workUpdate) {
 	for newWork := range podUpdates {
 		func() {
 			defer p.checkForUpdates(newWork.pod.UID, newWork.updateCompleteFn)
+			if p.syncSemaphore != nil {
+				p.syncSemaphore <- struct{}{}
+				defer func() { <-p.syncSemaphore }()
+			}
 			// We would like to have the state of Docker from at least the moment
 			// when we finished the previous processing of that pod.
 			if err := p.runtimeCache.ForceUpdateIfOlder(minRuntimeCacheTime); err != nil {
 				glog.Errorf("Error updating docker cache: %v", err)
+				p.requeueWork(newWork)
 				return
 			}
-			pods, err := p.runtimeCache.GetPods()
+			runningPod, err := p.runtimeCache.GetPodByUID(newWork.pod.UID)
 			if err != nil {
 				glog.Errorf("Error getting pods while syncing pod: %v", err)
+				p.requeueWork(newWork)
 				return
 			}
 
-			err = p.syncPodFn(newWork.pod, newWork.mirrorPod,
-				kubecontainer.Pods(pods).FindPodByID(newWork.pod.UID))
+			start := time.Now()
+			cancel := p.startSync(newWork.pod.UID)
+			err = p.syncPodFn(newWork.pod, newWork.mirrorPod, runningPod, cancel)
+			p.finishSync(newWork.pod.UID)
+			metrics.PodWorkerLatency.WithLabelValues(newWork.syncType.String()).Observe(metrics.SinceInMicroseconds(start))
 			if err != nil {
-				glog.Errorf("Error syncing pod %s, skipping: %v", newWork.pod.UID, err)
-				p.recorder.Eventf(newWork.pod, "failedSync", "Error syncing pod, skipping: %v", err)
+				glog.Errorf("Error syncing pod %s, requeuing: %v", newWork.pod.UID, err)
+				p.recorder.Eventf(newWork.pod, "failedSync", "Error syncing pod, will retry: %v", err)
+				p.requeueWork(newWork)
 				return
 			}
 			minRuntimeCacheTime = time.Now()
+			p.forgetRetry(newWork.pod.UID)
 
 			newWork.updateCompleteFn()
 		}()
 	}
 }
 
+// startSync registers the cancel channel for a sync about to start.
+func (p *podWorkers) startSync(uid types.UID) <-chan struct{} {
+	p.podLock.Lock()
+	defer p.podLock.Unlock()
+	cancel := make(chan struct{})
+	p.syncCancels[uid] = cancel
+	return cancel
+}
+
+// finishSync drops the cancel channel of a finished sync.
+func (p *podWorkers) finishSync(uid types.UID) {
+	p.podLock.Lock()
+	defer p.podLock.Unlock()
+	delete(p.syncCancels, uid)
+}
+
+// requeueWork schedules a retry of the given work after an error-driven
+// exponential backoff.
+func (p *podWorkers) requeueWork(work workUpdate) {
+	uid := work.pod.UID
+	p.podLock.Lock()
+	backoff := p.syncBackoff[uid]
+	if backoff == 0 {
+		backoff = podSyncRetryBackoffInitial
+	} else {
+		backoff *= 2
+		if backoff > podSyncRetryBackoffMax {
+			backoff = podSyncRetryBackoffMax
+		}
+	}
+	p.syncBackoff[uid] = backoff
+	p.retryWorkUpdate[uid] = work
+	p.podLock.Unlock()
+	glog.V(3).Infof("Requeuing pod %q for sync in %v", kubecontainer.GetPodFullName(work.pod), backoff)
+	p.retryQueue.Enqueue(uid, backoff)
+}
+
+// forgetRetry clears any pending retry state for the pod after a successful
+// sync.
+func (p *podWorkers) forgetRetry(uid types.UID) {
+	p.podLock.Lock()
+	delete(p.retryWorkUpdate, uid)
+	delete(p.syncBackoff, uid)
+	p.podLock.Unlock()
+	p.retryQueue.Forget(uid)
+}
+
+// retryWork re-delivers the last failed work update for pods whose backoff
+// has expired. It is run periodically from the kubelet's sync loop ticker.
+func (p *podWorkers) retryWork() {
+	for _, uid := range p.retryQueue.GetWork() {
+		p.podLock.Lock()
+		work, found := p.retryWorkUpdate[uid]
+		if found {
+			delete(p.retryWorkUpdate, uid)
+		}
+		p.podLock.Unlock()
+		if !found {
+			continue
+		}
+		p.UpdatePod(work.pod, work.mirrorPod, metrics.SyncPodSync, work.updateCompleteFn)
+	}
+}
+
 // Apply the new setting to the specified pod. updateComplete is called when the update is completed.
-func (p *podWorkers) UpdatePod(pod *api.Pod, mirrorPod *api.Pod, updateComplete func()) {
+func (p *podWorkers) UpdatePod(pod *api.Pod, mirrorPod *api.Pod, syncType metrics.SyncPodType, updateComplete func()) {
 	uid := pod.UID
 	var podUpdates chan workUpdate
 	var exists bool
@@ -135,12 +246,19 @@ func (p *podWorkers) UpdatePod(pod *api.Pod, mirrorPod *api.Pod, updateComplete
 		podUpdates <- workUpdate{
 			pod:              pod,
 			mirrorPod:        mirrorPod,
+			syncType:         syncType,
 			updateCompleteFn: updateComplete,
 		}
 	} else {
+		// A newer update supersedes any still-undelivered one; syncing
+		// against the latest spec covers both.
+		if _, pending := p.lastUndeliveredWorkUpdate[pod.UID]; pending {
+			metrics.CoalescedPodUpdates.Inc()
+		}
 		p.lastUndeliveredWorkUpdate[pod.UID] = workUpdate{
 			pod:              pod,
 			mirrorPod:        mirrorPod,
+			syncType:         syncType,
 			updateCompleteFn: updateComplete,
 		}
 	}
@@ -153,12 +271,20 @@ func (p *podWorkers) ForgetNonExistingPodWorkers(desiredPods map[types.UID]empty
 		if _, exists := desiredPods[key]; !exists {
 			close(channel)
 			delete(p.podUpdates, key)
+			// Give up any sync still in flight; its pod no longer exists.
+			if cancel, inFlight := p.syncCancels[key]; inFlight {
+				close(cancel)
+				delete(p.syncCancels, key)
+			}
 			// If there is an undelivered work update for this pod we need to remove it
 			// since per-pod goroutine won't be able to put it to the already closed
 			// channel when it finish processing the current work update.
 			if _, cached := p.lastUndeliveredWorkUpdate[key]; cached {
 				delete(p.lastUndeliveredWorkUpdate, key)
 			}
+			delete(p.retryWorkUpdate, key)
+			delete(p.syncBackoff, key)
+			p.retryQueue.Forget(key)
 		}
 	}
 }
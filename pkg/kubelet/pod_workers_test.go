@@ -17,6 +17,7 @@ limitations under the License.
 package kubelet
 
 import (
+	"fmt"
 	"sync"
 	"testing"
 	"time"
@@ -25,6 +26,7 @@ import (
 	"github.com/GoogleCloudPlatform/kubernetes/pkg/client/record"
 	kubecontainer "github.com/GoogleCloudPlatform/kubernetes/pkg/kubelet/container"
 	"github.com/GoogleCloudPlatform/kubernetes/pkg/kubelet/dockertools"
+	"github.com/GoogleCloudPlatform/kubernetes/pkg/kubelet/metrics"
 	"github.com/GoogleCloudPlatform/kubernetes/pkg/types"
 )
 
@@ -40,7 +42,7 @@ func newPod(uid, name string) *api.Pod {
 func createPodWorkers() (*podWorkers, map[types.UID][]string) {
 	fakeDocker := &dockertools.FakeDockerClient{}
 	fakeRecorder := &record.FakeRecorder{}
-	dockerManager := dockertools.NewDockerManager(fakeDocker, fakeRecorder, nil, nil, dockertools.PodInfraContainerImage, 0, 0)
+	dockerManager := dockertools.NewDockerManager(fakeDocker, fakeRecorder, nil, nil, dockertools.PodInfraContainerImage, 0, 0, "/var/lib/docker", "/var/log/containers", 0)
 	fakeRuntimeCache := kubecontainer.NewFakeRuntimeCache(dockerManager)
 
 	lock := sync.Mutex{}
@@ -48,7 +50,7 @@ func createPodWorkers() (*podWorkers, map[types.UID][]string) {
 
 	podWorkers := newPodWorkers(
 		fakeRuntimeCache,
-		func(pod *api.Pod, mirrorPod *api.Pod, runningPod kubecontainer.Pod) error {
+		func(pod *api.Pod, mirrorPod *api.Pod, runningPod kubecontainer.Pod, cancel <-chan struct{}) error {
 			func() {
 				lock.Lock()
 				defer lock.Unlock()
@@ -57,6 +59,7 @@ func createPodWorkers() (*podWorkers, map[types.UID][]string) {
 			return nil
 		},
 		fakeRecorder,
+		0,
 	)
 	return podWorkers, processed
 }
@@ -85,7 +88,7 @@ func TestUpdatePod(t *testing.T) {
 	numPods := 20
 	for i := 0; i < numPods; i++ {
 		for j := i; j < numPods; j++ {
-			podWorkers.UpdatePod(newPod(string(j), string(i)), nil, func() {})
+			podWorkers.UpdatePod(newPod(string(j), string(i)), nil, metrics.SyncPodUpdate, func() {})
 		}
 	}
 	drainWorkers(podWorkers, numPods)
@@ -118,7 +121,7 @@ func TestForgetNonExistingPodWorkers(t *testing.T) {
 
 	numPods := 20
 	for i := 0; i < numPods; i++ {
-		podWorkers.UpdatePod(newPod(string(i), "name"), nil, func() {})
+		podWorkers.UpdatePod(newPod(string(i), "name"), nil, metrics.SyncPodUpdate, func() {})
 	}
 	drainWorkers(podWorkers, numPods)
 
@@ -145,3 +148,123 @@ func TestForgetNonExistingPodWorkers(t *testing.T) {
 		t.Errorf("Incorrect number of open channels %v", len(podWorkers.podUpdates))
 	}
 }
+
+func TestForgetNonExistingPodWorkersCancelsSync(t *testing.T) {
+	fakeDocker := &dockertools.FakeDockerClient{}
+	fakeRecorder := &record.FakeRecorder{}
+	dockerManager := dockertools.NewDockerManager(fakeDocker, fakeRecorder, nil, nil, dockertools.PodInfraContainerImage, 0, 0, "/var/lib/docker", "/var/log/containers", 0)
+	fakeRuntimeCache := kubecontainer.NewFakeRuntimeCache(dockerManager)
+
+	started := make(chan struct{})
+	canceled := make(chan struct{})
+	podWorkers := newPodWorkers(
+		fakeRuntimeCache,
+		func(pod *api.Pod, mirrorPod *api.Pod, runningPod kubecontainer.Pod, cancel <-chan struct{}) error {
+			close(started)
+			<-cancel
+			close(canceled)
+			return nil
+		},
+		fakeRecorder,
+		0,
+	)
+
+	pod := newPod("1", "foo")
+	podWorkers.UpdatePod(pod, nil, metrics.SyncPodUpdate, func() {})
+	<-started
+
+	// Forgetting the pod's worker must cancel its in-flight sync.
+	podWorkers.ForgetNonExistingPodWorkers(map[types.UID]empty{})
+	select {
+	case <-canceled:
+	case <-time.After(10 * time.Second):
+		t.Fatalf("in-flight sync was not canceled")
+	}
+}
+
+func TestFailedSyncIsRetriedWithBackoff(t *testing.T) {
+	fakeDocker := &dockertools.FakeDockerClient{}
+	fakeRecorder := &record.FakeRecorder{}
+	dockerManager := dockertools.NewDockerManager(fakeDocker, fakeRecorder, nil, nil, dockertools.PodInfraContainerImage, 0, 0, "/var/lib/docker", "/var/log/containers", 0)
+	fakeRuntimeCache := kubecontainer.NewFakeRuntimeCache(dockerManager)
+
+	lock := sync.Mutex{}
+	attempts := 0
+	podWorkers := newPodWorkers(
+		fakeRuntimeCache,
+		func(pod *api.Pod, mirrorPod *api.Pod, runningPod kubecontainer.Pod, cancel <-chan struct{}) error {
+			lock.Lock()
+			defer lock.Unlock()
+			attempts++
+			if attempts == 1 {
+				return fmt.Errorf("intentional test error")
+			}
+			return nil
+		},
+		fakeRecorder,
+		0,
+	)
+
+	drainWorker := func(uid types.UID) {
+		for {
+			podWorkers.podLock.Lock()
+			working := podWorkers.isWorking[uid]
+			podWorkers.podLock.Unlock()
+			if !working {
+				return
+			}
+			time.Sleep(50 * time.Millisecond)
+		}
+	}
+
+	pod := newPod("1", "foo")
+	podWorkers.UpdatePod(pod, nil, metrics.SyncPodUpdate, func() {})
+	drainWorker(pod.UID)
+
+	// The failed sync must be queued for a retry with a backoff.
+	podWorkers.podLock.Lock()
+	if _, found := podWorkers.retryWorkUpdate[pod.UID]; !found {
+		t.Fatalf("expected failed work to be queued for retry")
+	}
+	if podWorkers.syncBackoff[pod.UID] != podSyncRetryBackoffInitial {
+		t.Errorf("expected backoff %v, got %v", podSyncRetryBackoffInitial, podWorkers.syncBackoff[pod.UID])
+	}
+	podWorkers.podLock.Unlock()
+
+	// Make the retry eligible immediately and deliver it.
+	podWorkers.retryQueue.Enqueue(pod.UID, 0)
+	podWorkers.retryWork()
+	drainWorker(pod.UID)
+
+	lock.Lock()
+	if attempts != 2 {
+		t.Errorf("expected 2 sync attempts, got %d", attempts)
+	}
+	lock.Unlock()
+
+	// The successful retry must clear the backoff state.
+	podWorkers.podLock.Lock()
+	if _, found := podWorkers.syncBackoff[pod.UID]; found {
+		t.Errorf("expected backoff to be reset after a successful sync")
+	}
+	podWorkers.podLock.Unlock()
+}
+
+func TestWorkQueue(t *testing.T) {
+	queue := newWorkQueue()
+	now := time.Now()
+	queue.now = func() time.Time { return now }
+
+	queue.Enqueue(types.UID("1"), 0)
+	queue.Enqueue(types.UID("2"), time.Minute)
+	if items := queue.GetWork(); len(items) != 1 || items[0] != types.UID("1") {
+		t.Errorf("expected only the eligible item, got %v", items)
+	}
+	now = now.Add(time.Minute)
+	if items := queue.GetWork(); len(items) != 1 || items[0] != types.UID("2") {
+		t.Errorf("expected the delayed item after its backoff, got %v", items)
+	}
+	if items := queue.GetWork(); len(items) != 0 {
+		t.Errorf("expected an empty queue, got %v", items)
+	}
+}
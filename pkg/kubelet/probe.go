@@ -25,6 +25,7 @@ import (
 	"github.com/GoogleCloudPlatform/kubernetes/pkg/client/record"
 	kubecontainer "github.com/GoogleCloudPlatform/kubernetes/pkg/kubelet/container"
 	"github.com/GoogleCloudPlatform/kubernetes/pkg/kubelet/dockertools"
+	"github.com/GoogleCloudPlatform/kubernetes/pkg/kubelet/metrics"
 	"github.com/GoogleCloudPlatform/kubernetes/pkg/probe"
 	execprobe "github.com/GoogleCloudPlatform/kubernetes/pkg/probe/exec"
 	httprobe "github.com/GoogleCloudPlatform/kubernetes/pkg/probe/http"
@@ -120,7 +121,10 @@ func (pb *prober) probeLiveness(pod *api.Pod, status api.PodStatus, container ap
 	if time.Now().Unix()-createdAt < p.InitialDelaySeconds {
 		return probe.Success, nil
 	}
-	return pb.runProbeWithRetries(p, pod, status, container, containerID, maxProbeRetries)
+	start := time.Now()
+	result, err := pb.runProbeWithRetries(p, pod, status, container, containerID, maxProbeRetries)
+	recordProbeResult("liveness", pod, container, result, start)
+	return result, err
 }
 
 // probeReadiness probes the readiness of a container.
@@ -133,7 +137,17 @@ func (pb *prober) probeReadiness(pod *api.Pod, status api.PodStatus, container a
 	if time.Now().Unix()-createdAt < p.InitialDelaySeconds {
 		return probe.Failure, nil
 	}
-	return pb.runProbeWithRetries(p, pod, status, container, containerID, maxProbeRetries)
+	start := time.Now()
+	result, err := pb.runProbeWithRetries(p, pod, status, container, containerID, maxProbeRetries)
+	recordProbeResult("readiness", pod, container, result, start)
+	return result, err
+}
+
+// recordProbeResult exports the outcome and duration of a probe execution on
+// /metrics/probes.
+func recordProbeResult(probeType string, pod *api.Pod, container api.Container, result probe.Result, start time.Time) {
+	metrics.ProbeResults.WithLabelValues(pod.Name, pod.Namespace, container.Name, probeType, result.String()).Inc()
+	metrics.ProbeLatency.WithLabelValues(pod.Name, pod.Namespace, container.Name, probeType).Observe(metrics.SinceInMicroseconds(start))
 }
 
 // runProbeWithRetries tries to probe the container in a finite loop, it returns the last result
@@ -0,0 +1,242 @@
+/*
+Copyright 2015 Google Inc. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package kubelet
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"regexp"
+	"sync"
+	"time"
+
+	"github.com/GoogleCloudPlatform/kubernetes/pkg/api"
+	"github.com/GoogleCloudPlatform/kubernetes/pkg/client/record"
+	"github.com/GoogleCloudPlatform/kubernetes/pkg/util"
+
+	"github.com/golang/glog"
+)
+
+const (
+	// How often the problem detector scans the log for new lines.
+	nodeProblemScanPeriod = 10 * time.Second
+	// The log scanned for problem patterns when the configuration file
+	// does not name one.
+	defaultProblemLogPath = "/var/log/kern.log"
+)
+
+// problemRule maps a log pattern to a node condition. A line matching
+// Pattern marks the condition true until the kubelet is restarted; log
+// based problems such as kernel deadlocks do not clear themselves.
+type problemRule struct {
+	// Condition is the NodeCondition type reported when the pattern
+	// matches, e.g. "KernelDeadlock".
+	Condition api.NodeConditionType `json:"condition"`
+	// Reason is the CamelCase reason put on the condition and the event.
+	Reason string `json:"reason"`
+	// Pattern is a regular expression matched against every new log line.
+	Pattern string `json:"pattern"`
+
+	pattern *regexp.Regexp
+}
+
+// problemDetectorConfig is the file format accepted by
+// --node_problem_config. Rules left empty fall back to the defaults.
+type problemDetectorConfig struct {
+	// LogPath is the log to scan, typically the kernel log.
+	LogPath string        `json:"logPath,omitempty"`
+	Rules   []problemRule `json:"rules,omitempty"`
+}
+
+// defaultProblemRules covers the failure modes that most often leave a node
+// unusable while it still reports Ready: kernel deadlocks, a hung docker
+// daemon and filesystems remounted read-only.
+func defaultProblemRules() []problemRule {
+	return []problemRule{
+		{Condition: "DockerHung", Reason: "DockerHung", Pattern: `task docker\S* blocked for more than \d+ seconds`},
+		{Condition: "KernelDeadlock", Reason: "TaskHung", Pattern: `task \S+ blocked for more than \d+ seconds`},
+		{Condition: "KernelDeadlock", Reason: "UnregisterNetDeviceStuck", Pattern: `unregister_netdevice: waiting for \S+ to become free`},
+		{Condition: "ReadonlyFilesystem", Reason: "FilesystemIsReadOnly", Pattern: `Remounting filesystem read-only`},
+	}
+}
+
+// problemDetector scans a log for the configured problem patterns and
+// surfaces matches as node conditions and events.
+type problemDetector struct {
+	recorder record.EventRecorder
+	nodeRef  *api.ObjectReference
+	logPath  string
+	rules    []problemRule
+
+	mutex sync.Mutex
+	// offset is how far into the log the detector has scanned; -1 until
+	// the first scan, which skips everything written before the kubelet
+	// started.
+	offset     int64
+	conditions map[api.NodeConditionType]api.NodeCondition
+}
+
+// newProblemDetector builds a detector from the configuration file at the
+// given path. An empty path disables node problem detection.
+func newProblemDetector(configPath string, recorder record.EventRecorder, nodeRef *api.ObjectReference) (*problemDetector, error) {
+	if configPath == "" {
+		return nil, nil
+	}
+	data, err := ioutil.ReadFile(configPath)
+	if err != nil {
+		return nil, err
+	}
+	config := problemDetectorConfig{}
+	if err := json.Unmarshal(data, &config); err != nil {
+		return nil, fmt.Errorf("invalid node problem configuration %q: %v", configPath, err)
+	}
+	if config.LogPath == "" {
+		config.LogPath = defaultProblemLogPath
+	}
+	if len(config.Rules) == 0 {
+		config.Rules = defaultProblemRules()
+	}
+	rules, err := compileProblemRules(config.Rules)
+	if err != nil {
+		return nil, fmt.Errorf("invalid node problem configuration %q: %v", configPath, err)
+	}
+	return &problemDetector{
+		recorder:   recorder,
+		nodeRef:    nodeRef,
+		logPath:    config.LogPath,
+		rules:      rules,
+		offset:     -1,
+		conditions: map[api.NodeConditionType]api.NodeCondition{},
+	}, nil
+}
+
+// compileProblemRules validates the rules and compiles their patterns.
+func compileProblemRules(rules []problemRule) ([]problemRule, error) {
+	for i := range rules {
+		if rules[i].Condition == "" || rules[i].Reason == "" {
+			return nil, fmt.Errorf("rule %d must set both condition and reason", i)
+		}
+		pattern, err := regexp.Compile(rules[i].Pattern)
+		if err != nil {
+			return nil, fmt.Errorf("rule %d has an invalid pattern: %v", i, err)
+		}
+		rules[i].pattern = pattern
+	}
+	return rules, nil
+}
+
+// Start begins scanning the log in the background.
+func (pd *problemDetector) Start() {
+	glog.Infof("Watching %q for node problems", pd.logPath)
+	go util.Forever(pd.scan, nodeProblemScanPeriod)
+}
+
+// scan reads the log lines written since the last scan and matches them
+// against the problem rules. The first scan only records the end of the
+// log, so problems from before the kubelet started are not replayed.
+func (pd *problemDetector) scan() {
+	file, err := os.Open(pd.logPath)
+	if err != nil {
+		glog.Errorf("Cannot open %q for node problem detection: %v", pd.logPath, err)
+		return
+	}
+	defer file.Close()
+
+	size := int64(0)
+	if info, err := file.Stat(); err == nil {
+		size = info.Size()
+	}
+	if pd.offset < 0 || pd.offset > size {
+		// First scan, or the log was rotated or truncated under us.
+		pd.offset = size
+		return
+	}
+	if _, err := file.Seek(pd.offset, 0); err != nil {
+		glog.Errorf("Cannot seek in %q for node problem detection: %v", pd.logPath, err)
+		return
+	}
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		line := scanner.Text()
+		pd.offset += int64(len(line)) + 1
+		pd.processLine(line)
+	}
+	if err := scanner.Err(); err != nil {
+		glog.Errorf("Error reading %q for node problem detection: %v", pd.logPath, err)
+	}
+}
+
+// processLine marks the condition of every rule the line matches and emits
+// an event for each newly observed problem.
+func (pd *problemDetector) processLine(line string) {
+	for i := range pd.rules {
+		rule := &pd.rules[i]
+		if !rule.pattern.MatchString(line) {
+			continue
+		}
+		pd.mutex.Lock()
+		existing, found := pd.conditions[rule.Condition]
+		if !found || existing.Status != api.ConditionTrue || existing.Reason != rule.Reason {
+			pd.recorder.Eventf(pd.nodeRef, rule.Reason, "Node condition %s is now true: %s", rule.Condition, line)
+		}
+		currentTime := util.Now()
+		condition := api.NodeCondition{
+			Type:               rule.Condition,
+			Status:             api.ConditionTrue,
+			Reason:             rule.Reason,
+			Message:            line,
+			LastHeartbeatTime:  currentTime,
+			LastTransitionTime: currentTime,
+		}
+		if found && existing.Status == api.ConditionTrue {
+			condition.LastTransitionTime = existing.LastTransitionTime
+		}
+		pd.conditions[rule.Condition] = condition
+		pd.mutex.Unlock()
+	}
+}
+
+// setConditions publishes one condition per configured condition type on
+// the node: true with the offending log line for observed problems, false
+// for types with no match yet.
+func (pd *problemDetector) setConditions(node *api.Node) {
+	pd.mutex.Lock()
+	defer pd.mutex.Unlock()
+	currentTime := util.Now()
+	seen := util.NewStringSet()
+	for i := range pd.rules {
+		conditionType := pd.rules[i].Condition
+		if seen.Has(string(conditionType)) {
+			continue
+		}
+		seen.Insert(string(conditionType))
+		condition, found := pd.conditions[conditionType]
+		if !found {
+			condition = api.NodeCondition{
+				Type:               conditionType,
+				Status:             api.ConditionFalse,
+				Reason:             "NoProblemsDetected",
+				Message:            fmt.Sprintf("no %s problems observed in %s", conditionType, pd.logPath),
+				LastTransitionTime: currentTime,
+			}
+		}
+		condition.LastHeartbeatTime = currentTime
+		setNodeCondition(node, condition)
+	}
+}
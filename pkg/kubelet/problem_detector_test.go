@@ -0,0 +1,151 @@
+/*
+Copyright 2015 Google Inc. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package kubelet
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/GoogleCloudPlatform/kubernetes/pkg/api"
+)
+
+func newTestProblemDetector(t *testing.T) (*problemDetector, *recordingRecorder, string) {
+	dir, err := ioutil.TempDir("", "problemdetector")
+	if err != nil {
+		t.Fatalf("can't make a temp dir: %v", err)
+	}
+	logPath := filepath.Join(dir, "kern.log")
+	if err := ioutil.WriteFile(logPath, []byte("old line from before the kubelet started\n"), 0644); err != nil {
+		t.Fatalf("can't write log: %v", err)
+	}
+	rules, err := compileProblemRules(defaultProblemRules())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	recorder := &recordingRecorder{}
+	pd := &problemDetector{
+		recorder:   recorder,
+		nodeRef:    &api.ObjectReference{Kind: "Node", Name: "testnode"},
+		logPath:    logPath,
+		rules:      rules,
+		offset:     -1,
+		conditions: map[api.NodeConditionType]api.NodeCondition{},
+	}
+	return pd, recorder, logPath
+}
+
+func appendToLog(t *testing.T, logPath, line string) {
+	file, err := os.OpenFile(logPath, os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		t.Fatalf("can't open log: %v", err)
+	}
+	defer file.Close()
+	if _, err := file.WriteString(line + "\n"); err != nil {
+		t.Fatalf("can't append to log: %v", err)
+	}
+}
+
+func getCondition(node *api.Node, conditionType api.NodeConditionType) *api.NodeCondition {
+	for i := range node.Status.Conditions {
+		if node.Status.Conditions[i].Type == conditionType {
+			return &node.Status.Conditions[i]
+		}
+	}
+	return nil
+}
+
+func TestProblemDetectorReportsMatches(t *testing.T) {
+	pd, recorder, logPath := newTestProblemDetector(t)
+	defer os.RemoveAll(filepath.Dir(logPath))
+
+	// The first scan only positions the detector at the end of the log;
+	// problems from before the kubelet started are not replayed.
+	pd.scan()
+	if events := recorder.recorded(); len(events) != 0 {
+		t.Errorf("unexpected events from the first scan: %v", events)
+	}
+
+	deadlockLine := "INFO: task nfsd:1234 blocked for more than 120 seconds."
+	appendToLog(t, logPath, deadlockLine)
+	appendToLog(t, logPath, "a perfectly healthy line")
+	pd.scan()
+
+	events := recorder.recorded()
+	if len(events) != 1 || events[0].reason != "TaskHung" {
+		t.Errorf("expected one TaskHung event, got %v", events)
+	}
+
+	node := &api.Node{}
+	pd.setConditions(node)
+	condition := getCondition(node, "KernelDeadlock")
+	if condition == nil || condition.Status != api.ConditionTrue {
+		t.Fatalf("expected a true KernelDeadlock condition, got %v", condition)
+	}
+	if condition.Reason != "TaskHung" || condition.Message != deadlockLine {
+		t.Errorf("unexpected condition: %v", condition)
+	}
+	if condition := getCondition(node, "ReadonlyFilesystem"); condition == nil || condition.Status != api.ConditionFalse {
+		t.Errorf("expected a false ReadonlyFilesystem condition, got %v", condition)
+	}
+
+	// A repeated match must not produce another event.
+	appendToLog(t, logPath, deadlockLine)
+	pd.scan()
+	if events := recorder.recorded(); len(events) != 1 {
+		t.Errorf("expected no further events, got %v", events)
+	}
+}
+
+func TestNewProblemDetector(t *testing.T) {
+	if pd, err := newProblemDetector("", nil, nil); pd != nil || err != nil {
+		t.Errorf("expected detection to be disabled, got %v, %v", pd, err)
+	}
+
+	dir, err := ioutil.TempDir("", "problemdetector")
+	if err != nil {
+		t.Fatalf("can't make a temp dir: %v", err)
+	}
+	defer os.RemoveAll(dir)
+	configPath := filepath.Join(dir, "config.json")
+
+	if err := ioutil.WriteFile(configPath, []byte(`{"logPath": "/var/log/messages"}`), 0644); err != nil {
+		t.Fatalf("can't write config: %v", err)
+	}
+	pd, err := newProblemDetector(configPath, nil, nil)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if pd.logPath != "/var/log/messages" || len(pd.rules) != len(defaultProblemRules()) {
+		t.Errorf("expected the default rules against /var/log/messages, got %+v", pd)
+	}
+
+	if err := ioutil.WriteFile(configPath, []byte(`{"rules": [{"condition": "Broken", "reason": "Bad", "pattern": "["}]}`), 0644); err != nil {
+		t.Fatalf("can't write config: %v", err)
+	}
+	if _, err := newProblemDetector(configPath, nil, nil); err == nil {
+		t.Errorf("expected an error for an invalid pattern")
+	}
+
+	if err := ioutil.WriteFile(configPath, []byte(`{"rules": [{"pattern": "x"}]}`), 0644); err != nil {
+		t.Fatalf("can't write config: %v", err)
+	}
+	if _, err := newProblemDetector(configPath, nil, nil); err == nil {
+		t.Errorf("expected an error for a rule without condition and reason")
+	}
+}
@@ -87,23 +87,23 @@ func (kl *Kubelet) runPod(pod *api.Pod, retryDelay time.Duration) error {
 	delay := retryDelay
 	retry := 0
 	for {
-		pods, err := kl.containerManager.GetPods(false)
+		pods, err := kl.containerManager.GetPods(true)
 		if err != nil {
 			return fmt.Errorf("failed to get kubelet pods: %v", err)
 		}
 		p := container.Pods(pods).FindPodByID(pod.UID)
-		running, err := kl.isPodRunning(pod, p)
+		done, err := kl.isPodComplete(pod, p)
 		if err != nil {
 			return fmt.Errorf("failed to check pod status: %v", err)
 		}
-		if running {
-			glog.Infof("pod %q containers running", pod.Name)
+		if done {
+			glog.Infof("pod %q containers running or completed", pod.Name)
 			return nil
 		}
 		glog.Infof("pod %q containers not running: syncing", pod.Name)
 		// We don't create mirror pods in this mode; pass a dummy boolean value
 		// to sycnPod.
-		if err = kl.syncPod(pod, nil, p); err != nil {
+		if err = kl.syncPod(pod, nil, p, nil); err != nil {
 			return fmt.Errorf("error syncing pod: %v", err)
 		}
 		if retry >= RunOnceMaxRetries {
@@ -117,8 +117,10 @@ func (kl *Kubelet) runPod(pod *api.Pod, retryDelay time.Duration) error {
 	}
 }
 
-// isPodRunning returns true if all containers of a manifest are running.
-func (kl *Kubelet) isPodRunning(pod *api.Pod, runningPod container.Pod) (bool, error) {
+// isPodComplete returns true once every container of a manifest is either
+// ready and running or has terminated successfully. Containers that
+// terminated with a non-zero exit code fail the pod immediately.
+func (kl *Kubelet) isPodComplete(pod *api.Pod, runningPod container.Pod) (bool, error) {
 	for _, container := range pod.Spec.Containers {
 		c := runningPod.FindContainerByName(container.Name)
 		if c == nil {
@@ -131,7 +133,18 @@ func (kl *Kubelet) isPodRunning(pod *api.Pod, runningPod container.Pod) (bool, e
 			return false, err
 		}
 		if !inspectResult.State.Running {
-			glog.Infof("container %q not running: %#v", container.Name, inspectResult.State)
+			if inspectResult.State.FinishedAt.IsZero() {
+				glog.Infof("container %q not yet started: %#v", container.Name, inspectResult.State)
+				return false, nil
+			}
+			if inspectResult.State.ExitCode != 0 {
+				return false, fmt.Errorf("container %q terminated with exit code %d", container.Name, inspectResult.State.ExitCode)
+			}
+			// Terminated successfully.
+			continue
+		}
+		if container.ReadinessProbe != nil && !kl.readinessManager.GetReadiness(string(c.ID)) {
+			glog.Infof("container %q running but not yet ready", container.Name)
 			return false, nil
 		}
 	}
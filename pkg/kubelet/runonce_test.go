@@ -30,6 +30,7 @@ import (
 	"github.com/GoogleCloudPlatform/kubernetes/pkg/kubelet/network"
 	docker "github.com/fsouza/go-dockerclient"
 	cadvisorApi "github.com/google/cadvisor/info/v1"
+	cadvisorApiV2 "github.com/google/cadvisor/info/v2"
 )
 
 type listContainersResult struct {
@@ -74,6 +75,7 @@ func (d *testDocker) InspectContainer(id string) (*docker.Container, error) {
 func TestRunOnce(t *testing.T) {
 	cadvisor := &cadvisor.Mock{}
 	cadvisor.On("MachineInfo").Return(&cadvisorApi.MachineInfo{}, nil)
+	cadvisor.On("DockerImagesFsInfo").Return(cadvisorApiV2.FsInfo{}, nil)
 
 	podManager, _ := newFakePodManager()
 
@@ -83,6 +85,7 @@ func TestRunOnce(t *testing.T) {
 		cadvisor:            cadvisor,
 		nodeLister:          testNodeLister{},
 		statusManager:       newStatusManager(nil),
+		podStatusCache:      newPodStatusCache(),
 		containerRefManager: kubecontainer.NewRefManager(),
 		readinessManager:    kubecontainer.NewReadinessManager(),
 		podManager:          podManager,
@@ -94,6 +97,7 @@ func TestRunOnce(t *testing.T) {
 	if err := kb.setupDataDirs(); err != nil {
 		t.Errorf("Failed to init data dirs: %v", err)
 	}
+	kb.containerHistory = newContainerHistory(kb.rootDirectory)
 	podContainers := []docker.APIContainers{
 		{
 			Names:  []string{"/k8s_bar." + strconv.FormatUint(dockertools.HashContainer(&api.Container{Name: "bar"}), 16) + "_foo_new_12345678_42"},
@@ -154,8 +158,12 @@ func TestRunOnce(t *testing.T) {
 		kb.containerRefManager,
 		dockertools.PodInfraContainerImage,
 		0,
+		0,
+		"/var/lib/docker",
+		"/var/log/containers",
 		0)
 	kb.containerManager.Puller = &dockertools.FakeDockerPuller{}
+	kb.runtimeCache = kubecontainer.NewFakeRuntimeCache(kb.containerManager)
 
 	pods := []*api.Pod{
 		{
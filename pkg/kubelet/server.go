@@ -20,8 +20,10 @@ import (
 	"crypto/tls"
 	"encoding/json"
 	"errors"
+	"flag"
 	"fmt"
 	"io"
+	"io/ioutil"
 	"net"
 	"net/http"
 	"net/url"
@@ -33,35 +35,107 @@ import (
 
 	"github.com/GoogleCloudPlatform/kubernetes/pkg/api"
 	"github.com/GoogleCloudPlatform/kubernetes/pkg/api/latest"
+	"github.com/GoogleCloudPlatform/kubernetes/pkg/auth/authenticator"
+	"github.com/GoogleCloudPlatform/kubernetes/pkg/auth/authorizer"
+	"github.com/GoogleCloudPlatform/kubernetes/pkg/auth/user"
+	"github.com/GoogleCloudPlatform/kubernetes/pkg/configz"
 	"github.com/GoogleCloudPlatform/kubernetes/pkg/healthz"
 	"github.com/GoogleCloudPlatform/kubernetes/pkg/httplog"
+	"github.com/GoogleCloudPlatform/kubernetes/pkg/kubelet/cadvisor"
 	kubecontainer "github.com/GoogleCloudPlatform/kubernetes/pkg/kubelet/container"
 	"github.com/GoogleCloudPlatform/kubernetes/pkg/runtime"
 	"github.com/GoogleCloudPlatform/kubernetes/pkg/types"
 	"github.com/GoogleCloudPlatform/kubernetes/pkg/util/flushwriter"
 	"github.com/GoogleCloudPlatform/kubernetes/pkg/util/httpstream"
 	"github.com/GoogleCloudPlatform/kubernetes/pkg/util/httpstream/spdy"
+	"github.com/GoogleCloudPlatform/kubernetes/pkg/util/wsstream"
+	"github.com/GoogleCloudPlatform/kubernetes/pkg/version"
 	"github.com/golang/glog"
 	cadvisorApi "github.com/google/cadvisor/info/v1"
+	cadvisorMetrics "github.com/google/cadvisor/metrics"
 	"github.com/prometheus/client_golang/prometheus"
 )
 
 // Server is a http.Handler which exposes kubelet functionality over HTTP.
 type Server struct {
 	host HostInterface
+	auth AuthInterface
 	mux  *http.ServeMux
 }
 
+// AuthInterface contains all methods required by the auth filters.
+type AuthInterface interface {
+	authenticator.Request
+	authorizer.Authorizer
+}
+
+// kubeletAuth implements AuthInterface.
+type kubeletAuth struct {
+	authenticator.Request
+	authorizer.Authorizer
+}
+
+// NewKubeletAuth returns a kubelet AuthInterface composed of the given
+// authenticator and authorizer.
+func NewKubeletAuth(authenticator authenticator.Request, authorizer authorizer.Authorizer) AuthInterface {
+	return &kubeletAuth{authenticator, authorizer}
+}
+
 type TLSOptions struct {
 	Config   *tls.Config
 	CertFile string
 	KeyFile  string
 }
 
+// ReloadableCertificate serves a TLS certificate that can be replaced while
+// the listener is running, so established functionality such as exec and
+// logs does not break when the serving certificate is renewed.
+type ReloadableCertificate struct {
+	certFile string
+	keyFile  string
+
+	lock sync.RWMutex
+	cert *tls.Certificate
+}
+
+// NewReloadableCertificate loads the certificate and key from disk, failing
+// if they cannot be used to serve TLS.
+func NewReloadableCertificate(certFile, keyFile string) (*ReloadableCertificate, error) {
+	r := &ReloadableCertificate{
+		certFile: certFile,
+		keyFile:  keyFile,
+	}
+	if err := r.Reload(); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+// Reload re-reads the certificate and key from disk. The previous
+// certificate stays in use if the files cannot be loaded.
+func (r *ReloadableCertificate) Reload() error {
+	cert, err := tls.LoadX509KeyPair(r.certFile, r.keyFile)
+	if err != nil {
+		return err
+	}
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	r.cert = &cert
+	return nil
+}
+
+// GetCertificate hands the current certificate to the TLS listener; intended
+// for use as tls.Config.GetCertificate.
+func (r *ReloadableCertificate) GetCertificate(clientHello *tls.ClientHelloInfo) (*tls.Certificate, error) {
+	r.lock.RLock()
+	defer r.lock.RUnlock()
+	return r.cert, nil
+}
+
 // ListenAndServeKubeletServer initializes a server to respond to HTTP network requests on the Kubelet.
-func ListenAndServeKubeletServer(host HostInterface, address net.IP, port uint, tlsOptions *TLSOptions, enableDebuggingHandlers bool) {
+func ListenAndServeKubeletServer(host HostInterface, address net.IP, port uint, tlsOptions *TLSOptions, auth AuthInterface, enableDebuggingHandlers bool) {
 	glog.Infof("Starting to listen on %s:%d", address, port)
-	handler := NewServer(host, enableDebuggingHandlers)
+	handler := NewServer(host, auth, enableDebuggingHandlers)
 	s := &http.Server{
 		Addr:           net.JoinHostPort(address.String(), strconv.FormatUint(uint64(port), 10)),
 		Handler:        &handler,
@@ -71,6 +145,11 @@ func ListenAndServeKubeletServer(host HostInterface, address net.IP, port uint,
 	}
 	if tlsOptions != nil {
 		s.TLSConfig = tlsOptions.Config
+		if tlsOptions.Config != nil && tlsOptions.Config.GetCertificate != nil {
+			// The config provides certificates dynamically (e.g. for
+			// rotation); the listener must not pin the files itself.
+			glog.Fatal(s.ListenAndServeTLS("", ""))
+		}
 		glog.Fatal(s.ListenAndServeTLS(tlsOptions.CertFile, tlsOptions.KeyFile))
 	} else {
 		glog.Fatal(s.ListenAndServe())
@@ -80,14 +159,11 @@ func ListenAndServeKubeletServer(host HostInterface, address net.IP, port uint,
 // ListenAndServeKubeletReadOnlyServer initializes a server to respond to HTTP network requests on the Kubelet.
 func ListenAndServeKubeletReadOnlyServer(host HostInterface, address net.IP, port uint) {
 	glog.V(1).Infof("Starting to listen read-only on %s:%d", address, port)
-	s := &Server{host, http.NewServeMux()}
-	healthz.InstallHandler(s.mux)
-	s.mux.HandleFunc("/stats/", s.handleStats)
-	s.mux.Handle("/metrics", prometheus.Handler())
+	s := NewReadOnlyServer(host)
 
 	server := &http.Server{
 		Addr:           net.JoinHostPort(address.String(), strconv.FormatUint(uint64(port), 10)),
-		Handler:        s,
+		Handler:        &s,
 		ReadTimeout:    5 * time.Minute,
 		WriteTimeout:   5 * time.Minute,
 		MaxHeaderBytes: 1 << 20,
@@ -102,22 +178,29 @@ type HostInterface interface {
 	GetContainerRuntimeVersion() (kubecontainer.Version, error)
 	GetRawContainerInfo(containerName string, req *cadvisorApi.ContainerInfoRequest, subcontainers bool) (map[string]*cadvisorApi.ContainerInfo, error)
 	GetCachedMachineInfo() (*cadvisorApi.MachineInfo, error)
+	GetVersionInfo() (*cadvisorApi.VersionInfo, error)
 	GetPods() []*api.Pod
+	GetRunningPods() ([]*api.Pod, error)
 	GetPodByName(namespace, name string) (*api.Pod, bool)
 	GetPodStatus(name string) (api.PodStatus, error)
 	RunInContainer(name string, uid types.UID, container string, cmd []string) ([]byte, error)
 	ExecInContainer(name string, uid types.UID, container string, cmd []string, in io.Reader, out, err io.WriteCloser, tty bool) error
-	GetKubeletContainerLogs(podFullName, containerName, tail string, follow bool, stdout, stderr io.Writer) error
+	AttachContainer(name string, uid types.UID, container string, in io.Reader, out, err io.WriteCloser, tty bool) error
+	GetKubeletContainerLogs(podFullName, containerName string, logOptions *api.PodLogOptions, stdout, stderr io.Writer) error
 	ServeLogs(w http.ResponseWriter, req *http.Request)
-	PortForward(name string, uid types.UID, port uint16, stream io.ReadWriteCloser) error
+	PortForward(name string, uid types.UID, port uint16, protocol string, stream io.ReadWriteCloser) error
 	StreamingConnectionIdleTimeout() time.Duration
 	GetHostname() string
+	LatestLoopEntryTime() time.Time
+	ResyncInterval() time.Duration
+	SyncPodNow(pod *api.Pod) error
 }
 
 // NewServer initializes and configures a kubelet.Server object to handle HTTP requests.
-func NewServer(host HostInterface, enableDebuggingHandlers bool) Server {
+func NewServer(host HostInterface, auth AuthInterface, enableDebuggingHandlers bool) Server {
 	server := Server{
 		host: host,
+		auth: auth,
 		mux:  http.NewServeMux(),
 	}
 	server.InstallDefaultHandlers()
@@ -127,29 +210,134 @@ func NewServer(host HostInterface, enableDebuggingHandlers bool) Server {
 	return server
 }
 
+// NewReadOnlyServer initializes and configures a kubelet.Server object to
+// handle HTTP requests on the read-only port. Only stats, metrics and health
+// checking are exposed; none of the handlers that run commands in or stream
+// data from containers are installed.
+func NewReadOnlyServer(host HostInterface) Server {
+	server := Server{
+		host: host,
+		mux:  http.NewServeMux(),
+	}
+	server.InstallReadOnlyHandlers()
+	return server
+}
+
+// InstallReadOnlyHandlers registers the subset of HTTP request patterns that
+// are safe to expose on the unauthenticated read-only port.
+func (s *Server) InstallReadOnlyHandlers() {
+	healthz.InstallHandler(s.mux)
+	s.mux.HandleFunc("/stats/", s.handleStats)
+	s.mux.HandleFunc("/version", s.handleVersion)
+	s.installMetricsHandlers()
+}
+
 // InstallDefaultHandlers registers the default set of supported HTTP request patterns with the mux.
 func (s *Server) InstallDefaultHandlers() {
 	healthz.InstallHandler(s.mux,
 		healthz.PingHealthz,
 		healthz.NamedCheck("docker", s.dockerHealthCheck),
 		healthz.NamedCheck("hostname", s.hostnameHealthCheck),
+		healthz.NamedCheck("cadvisor", s.cadvisorHealthCheck),
+		healthz.NamedCheck("syncloop", s.syncLoopHealthCheck),
 	)
+	configz.InstallHandler(s.mux)
 	s.mux.HandleFunc("/podInfo", s.handlePodInfoOld)
 	s.mux.HandleFunc("/api/v1beta1/podInfo", s.handlePodInfoVersioned)
 	s.mux.HandleFunc("/pods", s.handlePods)
 	s.mux.HandleFunc("/stats/", s.handleStats)
 	s.mux.HandleFunc("/spec/", s.handleSpec)
+	s.mux.HandleFunc("/version", s.handleVersion)
 }
 
 // InstallDeguggingHandlers registers the HTTP request patterns that serve logs or run commands/containers
 func (s *Server) InstallDebuggingHandlers() {
 	s.mux.HandleFunc("/run/", s.handleRun)
 	s.mux.HandleFunc("/exec/", s.handleExec)
+	s.mux.HandleFunc("/attach/", s.handleAttach)
 	s.mux.HandleFunc("/portForward/", s.handlePortForward)
 
 	s.mux.HandleFunc("/logs/", s.handleLogs)
 	s.mux.HandleFunc("/containerLogs/", s.handleContainerLogs)
+	s.mux.HandleFunc("/runningpods", s.handleRunningPods)
+	s.mux.HandleFunc("/debug/sync/", s.handleDebugSync)
+	s.mux.HandleFunc("/debug/flags/v", s.handleDebugFlagV)
+	s.installMetricsHandlers()
+}
+
+// cadvisorCollectorRegistration guards against registering the cAdvisor
+// collectors more than once; the Prometheus registry is process-global and
+// both the read-write and read-only servers install the metrics handlers.
+var cadvisorCollectorRegistration sync.Once
+
+// installMetricsHandlers registers the Prometheus metrics endpoints. The
+// kubelet's own metrics and the cAdvisor machine and container metrics share
+// one registry, so /metrics/cadvisor and /metrics/probes are aliases kept
+// for scrape configs that address those metric families explicitly.
+func (s *Server) installMetricsHandlers() {
+	cadvisorCollectorRegistration.Do(func() {
+		prometheus.MustRegister(cadvisorMetrics.NewPrometheusCollector(&rawInfoProvider{host: s.host}))
+		prometheus.MustRegister(&machineInfoCollector{host: s.host})
+	})
 	s.mux.Handle("/metrics", prometheus.Handler())
+	s.mux.Handle("/metrics/cadvisor", prometheus.Handler())
+	s.mux.Handle("/metrics/probes", prometheus.Handler())
+}
+
+// rawInfoProvider adapts HostInterface to the subcontainer info provider
+// expected by cAdvisor's Prometheus collector.
+type rawInfoProvider struct {
+	host HostInterface
+}
+
+func (p *rawInfoProvider) SubcontainersInfo(containerName string, query *cadvisorApi.ContainerInfoRequest) ([]*cadvisorApi.ContainerInfo, error) {
+	infos, err := p.host.GetRawContainerInfo(containerName, query, true)
+	if err != nil {
+		return nil, err
+	}
+	result := make([]*cadvisorApi.ContainerInfo, 0, len(infos))
+	for _, info := range infos {
+		result = append(result, info)
+	}
+	return result, nil
+}
+
+var (
+	machineCPUCoresDesc = prometheus.NewDesc(
+		"machine_cpu_cores",
+		"Number of CPU cores on the machine.",
+		nil, nil)
+	machineMemoryDesc = prometheus.NewDesc(
+		"machine_memory_bytes",
+		"Amount of memory installed on the machine.",
+		nil, nil)
+)
+
+// machineInfoCollector exports machine-level metrics from cAdvisor's cached
+// machine info.
+type machineInfoCollector struct {
+	host HostInterface
+}
+
+func (c *machineInfoCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- machineCPUCoresDesc
+	ch <- machineMemoryDesc
+}
+
+func (c *machineInfoCollector) Collect(ch chan<- prometheus.Metric) {
+	info, err := c.host.GetCachedMachineInfo()
+	if err != nil {
+		glog.Warningf("Failed to get machine info while collecting metrics: %v", err)
+		return
+	}
+	ch <- prometheus.MustNewConstMetric(
+		machineCPUCoresDesc,
+		prometheus.GaugeValue,
+		float64(info.NumCores))
+	ch <- prometheus.MustNewConstMetric(
+		machineMemoryDesc,
+		prometheus.GaugeValue,
+		float64(info.MemoryCapacity))
 }
 
 // error serializes an error object into an HTTP response.
@@ -194,6 +382,32 @@ func (s *Server) hostnameHealthCheck(req *http.Request) error {
 	return nil
 }
 
+// cadvisorHealthCheck verifies that cadvisor answers a stats query.
+func (s *Server) cadvisorHealthCheck(req *http.Request) error {
+	if _, err := s.host.GetRawContainerInfo("/", &cadvisorApi.ContainerInfoRequest{NumStats: 1}, false); err != nil {
+		return fmt.Errorf("failed to get root container stats from cadvisor: %v", err)
+	}
+	return nil
+}
+
+// syncLoopHealthCheck verifies that the main sync loop has iterated recently.
+// The loop is expected to enter a new iteration at least once per resync
+// interval, so treat the kubelet as unhealthy when that has not happened
+// within two intervals (with a floor, so a slow sync of many pods does not
+// flap the check).
+func (s *Server) syncLoopHealthCheck(req *http.Request) error {
+	duration := s.host.ResyncInterval() * 2
+	minDuration := time.Minute
+	if duration < minDuration {
+		duration = minDuration
+	}
+	enterLoopTime := s.host.LatestLoopEntryTime()
+	if !enterLoopTime.IsZero() && time.Now().After(enterLoopTime.Add(duration)) {
+		return fmt.Errorf("sync loop has not completed in %v", time.Now().Sub(enterLoopTime))
+	}
+	return nil
+}
+
 // handleContainerLogs handles containerLogs request against the Kubelet
 func (s *Server) handleContainerLogs(w http.ResponseWriter, req *http.Request) {
 	defer req.Body.Close()
@@ -229,8 +443,22 @@ func (s *Server) handleContainerLogs(w http.ResponseWriter, req *http.Request) {
 	}
 
 	uriValues := u.Query()
-	follow, _ := strconv.ParseBool(uriValues.Get("follow"))
-	tail := uriValues.Get("tail")
+	logOptions := &api.PodLogOptions{}
+	logOptions.Follow, _ = strconv.ParseBool(uriValues.Get("follow"))
+	logOptions.Previous, _ = strconv.ParseBool(uriValues.Get("previous"))
+	logOptions.Timestamps, _ = strconv.ParseBool(uriValues.Get("timestamps"))
+	if sinceSeconds, err := strconv.ParseInt(uriValues.Get("sinceSeconds"), 10, 64); err == nil {
+		logOptions.SinceSeconds = &sinceSeconds
+	}
+	// "tail" is the deprecated form of "tailLines"; it also accepts the
+	// literal "all", which maps to an unset TailLines.
+	tail := uriValues.Get("tailLines")
+	if len(tail) == 0 {
+		tail = uriValues.Get("tail")
+	}
+	if tailLines, err := strconv.ParseInt(tail, 10, 64); err == nil {
+		logOptions.TailLines = &tailLines
+	}
 
 	pod, ok := s.host.GetPodByName(podNamespace, podID)
 	if !ok {
@@ -249,6 +477,21 @@ func (s *Server) handleContainerLogs(w http.ResponseWriter, req *http.Request) {
 		return
 	}
 
+	if wsstream.IsWebSocketRequest(req) {
+		wsConn := wsstream.NewConn(wsstream.WriteChannel)
+		wsConn.SetIdleTimeout(s.host.StreamingConnectionIdleTimeout())
+		streams, err := wsConn.Open(httplog.Unlogged(w), req)
+		if err != nil {
+			glog.Errorf("Unable to upgrade websocket connection: %v", err)
+			return
+		}
+		defer wsConn.Close()
+		if err := s.host.GetKubeletContainerLogs(kubecontainer.GetPodFullName(pod), containerName, logOptions, streams[0], streams[0]); err != nil {
+			glog.Errorf("Error getting container logs: %v", err)
+		}
+		return
+	}
+
 	if _, ok := w.(http.Flusher); !ok {
 		s.error(w, fmt.Errorf("unable to convert %v into http.Flusher", w))
 		return
@@ -256,7 +499,7 @@ func (s *Server) handleContainerLogs(w http.ResponseWriter, req *http.Request) {
 	fw := flushwriter.Wrap(w)
 	w.Header().Set("Transfer-Encoding", "chunked")
 	w.WriteHeader(http.StatusOK)
-	err = s.host.GetKubeletContainerLogs(kubecontainer.GetPodFullName(pod), containerName, tail, follow, fw, fw)
+	err = s.host.GetKubeletContainerLogs(kubecontainer.GetPodFullName(pod), containerName, logOptions, fw, fw)
 	if err != nil {
 		s.error(w, err)
 		return
@@ -279,6 +522,58 @@ func (s *Server) handlePods(w http.ResponseWriter, req *http.Request) {
 	w.Write(data)
 }
 
+// handleRunningPods returns the pods actually running on the node according
+// to the container runtime, so operators can diff them against the desired
+// pods reported by /pods.
+func (s *Server) handleRunningPods(w http.ResponseWriter, req *http.Request) {
+	pods, err := s.host.GetRunningPods()
+	if err != nil {
+		s.error(w, err)
+		return
+	}
+	podList := new(api.PodList)
+	for _, pod := range pods {
+		podList.Items = append(podList.Items, *pod)
+	}
+	data, err := latest.Codec.Encode(podList)
+	if err != nil {
+		s.error(w, err)
+		return
+	}
+	w.Header().Add("Content-type", "application/json")
+	w.Write(data)
+}
+
+// versionResponse is the payload served by /version: the kubelet's own build
+// information plus the container runtime and cadvisor versions, for fleet
+// auditing tools that cannot parse NodeStatus.
+type versionResponse struct {
+	version.Info
+	RuntimeVersion  string `json:"runtimeVersion"`
+	CadvisorVersion string `json:"cadvisorVersion"`
+}
+
+// handleVersion reports the kubelet, container runtime, and cadvisor
+// versions. The runtime and cadvisor versions are best-effort: an
+// unreachable docker daemon or a disabled cadvisor leaves them empty rather
+// than failing the whole request.
+func (s *Server) handleVersion(w http.ResponseWriter, req *http.Request) {
+	response := versionResponse{Info: version.Get()}
+	if runtimeVersion, err := s.host.GetContainerRuntimeVersion(); err == nil {
+		response.RuntimeVersion = runtimeVersion.String()
+	}
+	if verinfo, err := s.host.GetVersionInfo(); err == nil {
+		response.CadvisorVersion = verinfo.CadvisorVersion
+	}
+	data, err := json.Marshal(response)
+	if err != nil {
+		s.error(w, err)
+		return
+	}
+	w.Header().Add("Content-type", "application/json")
+	w.Write(data)
+}
+
 func (s *Server) handlePodInfoOld(w http.ResponseWriter, req *http.Request) {
 	s.handlePodStatus(w, req, false)
 }
@@ -330,12 +625,38 @@ func (s *Server) handleStats(w http.ResponseWriter, req *http.Request) {
 
 // handleLogs handles logs requests against the Kubelet.
 func (s *Server) handleLogs(w http.ResponseWriter, req *http.Request) {
+	// The log server serves files straight out of /var/log; make sure a
+	// crafted path cannot escape that directory.
+	if containsDotDot(req.URL.Path) {
+		http.Error(w, "invalid path", http.StatusBadRequest)
+		return
+	}
 	s.host.ServeLogs(w, req)
 }
 
+// containsDotDot returns true if the path contains a relative path element
+// that could climb out of the directory being served.
+func containsDotDot(p string) bool {
+	if !strings.Contains(p, "..") {
+		return false
+	}
+	for _, part := range strings.FieldsFunc(p, isSlashRune) {
+		if part == ".." {
+			return true
+		}
+	}
+	return false
+}
+
+func isSlashRune(r rune) bool { return r == '/' || r == '\\' }
+
 // handleSpec handles spec requests against the Kubelet.
 func (s *Server) handleSpec(w http.ResponseWriter, req *http.Request) {
 	info, err := s.host.GetCachedMachineInfo()
+	if err == cadvisor.ErrDisabled {
+		http.Error(w, err.Error(), http.StatusServiceUnavailable)
+		return
+	}
 	if err != nil {
 		s.error(w, err)
 		return
@@ -349,6 +670,70 @@ func (s *Server) handleSpec(w http.ResponseWriter, req *http.Request) {
 	w.Write(data)
 }
 
+// handleDebugSync queues an immediate sync of the pod named by the path
+// /debug/sync/<podNamespace>/<podID> and waits for it to complete.
+func (s *Server) handleDebugSync(w http.ResponseWriter, req *http.Request) {
+	if req.Method != "POST" {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	u, err := url.ParseRequestURI(req.RequestURI)
+	if err != nil {
+		s.error(w, err)
+		return
+	}
+	parts := strings.Split(u.Path, "/")
+	if len(parts) != 5 {
+		http.Error(w, "Unexpected path for pod sync, expected /debug/sync/<podNamespace>/<podID>", http.StatusBadRequest)
+		return
+	}
+	pod, ok := s.host.GetPodByName(parts[3], parts[4])
+	if !ok {
+		http.Error(w, "Pod does not exist", http.StatusNotFound)
+		return
+	}
+	if err := s.host.SyncPodNow(pod); err != nil {
+		s.error(w, err)
+		return
+	}
+	w.Header().Add("Content-type", "text/plain")
+	fmt.Fprintf(w, "pod %q synced\n", kubecontainer.GetPodFullName(pod))
+}
+
+// handleDebugFlagV adjusts glog's verbosity at runtime. A GET returns the
+// current level; a PUT with the new level in the request body changes it.
+func (s *Server) handleDebugFlagV(w http.ResponseWriter, req *http.Request) {
+	vflag := flag.Lookup("v")
+	if vflag == nil {
+		http.Error(w, "The v flag is not registered", http.StatusInternalServerError)
+		return
+	}
+	switch req.Method {
+	case "GET", "HEAD":
+	case "PUT":
+		body, err := ioutil.ReadAll(io.LimitReader(req.Body, 128))
+		if err != nil {
+			s.error(w, err)
+			return
+		}
+		level := strings.TrimSpace(string(body))
+		if _, err := strconv.Atoi(level); err != nil {
+			http.Error(w, fmt.Sprintf("Invalid verbosity level %q", level), http.StatusBadRequest)
+			return
+		}
+		if err := vflag.Value.Set(level); err != nil {
+			s.error(w, err)
+			return
+		}
+		glog.Infof("Set glog verbosity to %s", level)
+	default:
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	w.Header().Add("Content-type", "text/plain")
+	fmt.Fprintf(w, "%s\n", vflag.Value.String())
+}
+
 func parseContainerCoordinates(path string) (namespace, pod string, uid types.UID, container string, err error) {
 	parts := strings.Split(path, "/")
 
@@ -400,24 +785,59 @@ func (s *Server) handleRun(w http.ResponseWriter, req *http.Request) {
 
 const streamCreationTimeout = 30 * time.Second
 
-// handleExec handles requests to run a command inside a container.
-func (s *Server) handleExec(w http.ResponseWriter, req *http.Request) {
-	u, err := url.ParseRequestURI(req.RequestURI)
-	if err != nil {
-		s.error(w, err)
-		return
+// streamCloser adapts the websocket connection to the interface the SPDY
+// based handlers expect for tearing a streaming session down.
+type streamCloser interface {
+	Close() error
+}
+
+// createWebSocketStreams negotiates a websocket upgrade for clients that
+// cannot speak SPDY. Channel layout is fixed: 0=stdin, 1=stdout, 2=stderr,
+// 3=error. Streams the client did not ask for are returned as nil.
+func (s *Server) createWebSocketStreams(w http.ResponseWriter, req *http.Request) (io.Reader, io.WriteCloser, io.WriteCloser, io.WriteCloser, streamCloser, bool, bool) {
+	req.ParseForm()
+	stdin := req.FormValue(api.ExecStdinParam) == "1"
+	stdout := req.FormValue(api.ExecStdoutParam) == "1"
+	tty := req.FormValue(api.ExecTTYParam) == "1"
+	stderr := !tty && req.FormValue(api.ExecStderrParam) == "1"
+
+	if !stdin && !stdout && !stderr {
+		http.Error(w, "You must specify at least 1 of stdin, stdout, stderr", http.StatusBadRequest)
+		return nil, nil, nil, nil, nil, false, false
 	}
-	podNamespace, podID, uid, container, err := parseContainerCoordinates(u.Path)
+
+	conn := wsstream.NewConn(
+		wsstream.ReadChannel,  // stdin
+		wsstream.WriteChannel, // stdout
+		wsstream.WriteChannel, // stderr
+		wsstream.WriteChannel, // error
+	)
+	conn.SetIdleTimeout(s.host.StreamingConnectionIdleTimeout())
+	streams, err := conn.Open(httplog.Unlogged(w), req)
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusBadRequest)
-		return
+		glog.Errorf("Unable to upgrade websocket connection: %v", err)
+		return nil, nil, nil, nil, nil, false, false
 	}
-	pod, ok := s.host.GetPodByName(podNamespace, podID)
-	if !ok {
-		http.Error(w, "Pod does not exist", http.StatusNotFound)
-		return
+
+	var stdinStream io.Reader
+	var stdoutStream, stderrStream io.WriteCloser
+	if stdin {
+		stdinStream = streams[0]
 	}
+	if stdout {
+		stdoutStream = streams[1]
+	}
+	if stderr {
+		stderrStream = streams[2]
+	}
+	return stdinStream, stdoutStream, stderrStream, streams[3], conn, tty, true
+}
 
+// createStreams upgrades the request to a streaming connection and waits for
+// the client to create the streams it announced in the request parameters. It
+// returns a nil connection if the negotiation fails; in that case the client
+// has already been notified of the error.
+func (s *Server) createStreams(w http.ResponseWriter, req *http.Request) (io.Reader, io.WriteCloser, io.WriteCloser, httpstream.Stream, httpstream.Connection, bool, bool) {
 	req.ParseForm()
 	// start at 1 for error stream
 	expectedStreams := 1
@@ -434,7 +854,7 @@ func (s *Server) handleExec(w http.ResponseWriter, req *http.Request) {
 
 	if expectedStreams == 1 {
 		http.Error(w, "You must specify at least 1 of stdin, stdout, stderr", http.StatusBadRequest)
-		return
+		return nil, nil, nil, nil, nil, false, false
 	}
 
 	streamCh := make(chan httpstream.Stream)
@@ -449,9 +869,8 @@ func (s *Server) handleExec(w http.ResponseWriter, req *http.Request) {
 		// The upgrader is responsible for notifying the client of any errors that
 		// occurred during upgrading. All we can do is return here at this point
 		// if we weren't successful in upgrading.
-		return
+		return nil, nil, nil, nil, nil, false, false
 	}
-	defer conn.Close()
 
 	conn.SetIdleTimeout(s.host.StreamingConnectionIdleTimeout())
 
@@ -468,7 +887,6 @@ WaitForStreams:
 			switch streamType {
 			case api.StreamTypeError:
 				errorStream = stream
-				defer errorStream.Reset()
 				receivedStreams++
 			case api.StreamTypeStdin:
 				stdinStream = stream
@@ -489,7 +907,8 @@ WaitForStreams:
 			// TODO find a way to return the error to the user. Maybe use a separate
 			// stream to report errors?
 			glog.Error("Timed out waiting for client to create streams")
-			return
+			conn.Close()
+			return nil, nil, nil, nil, nil, false, false
 		}
 	}
 
@@ -498,6 +917,52 @@ WaitForStreams:
 		stdinStream.Close()
 	}
 
+	return stdinStream, stdoutStream, stderrStream, errorStream, conn, tty, true
+}
+
+// handleExec handles requests to run a command inside a container.
+func (s *Server) handleExec(w http.ResponseWriter, req *http.Request) {
+	u, err := url.ParseRequestURI(req.RequestURI)
+	if err != nil {
+		s.error(w, err)
+		return
+	}
+	podNamespace, podID, uid, container, err := parseContainerCoordinates(u.Path)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	pod, ok := s.host.GetPodByName(podNamespace, podID)
+	if !ok {
+		http.Error(w, "Pod does not exist", http.StatusNotFound)
+		return
+	}
+
+	if wsstream.IsWebSocketRequest(req) {
+		stdinStream, stdoutStream, stderrStream, errorStream, conn, tty, ok := s.createWebSocketStreams(w, req)
+		if !ok {
+			return
+		}
+		defer conn.Close()
+
+		err = s.host.ExecInContainer(kubecontainer.GetPodFullName(pod), uid, container, u.Query()[api.ExecCommandParamm], stdinStream, stdoutStream, stderrStream, tty)
+		if err != nil {
+			msg := fmt.Sprintf("Error executing command in container: %v", err)
+			glog.Error(msg)
+			errorStream.Write([]byte(msg))
+		}
+		return
+	}
+
+	stdinStream, stdoutStream, stderrStream, errorStream, conn, tty, ok := s.createStreams(w, req)
+	if !ok {
+		return
+	}
+	defer conn.Close()
+	if errorStream != nil {
+		defer errorStream.Reset()
+	}
+
 	err = s.host.ExecInContainer(kubecontainer.GetPodFullName(pod), uid, container, u.Query()[api.ExecCommandParamm], stdinStream, stdoutStream, stderrStream, tty)
 	if err != nil {
 		msg := fmt.Sprintf("Error executing command in container: %v", err)
@@ -506,6 +971,57 @@ WaitForStreams:
 	}
 }
 
+// handleAttach handles requests to attach to a container.
+func (s *Server) handleAttach(w http.ResponseWriter, req *http.Request) {
+	u, err := url.ParseRequestURI(req.RequestURI)
+	if err != nil {
+		s.error(w, err)
+		return
+	}
+	podNamespace, podID, uid, container, err := parseContainerCoordinates(u.Path)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	pod, ok := s.host.GetPodByName(podNamespace, podID)
+	if !ok {
+		http.Error(w, "Pod does not exist", http.StatusNotFound)
+		return
+	}
+
+	if wsstream.IsWebSocketRequest(req) {
+		stdinStream, stdoutStream, stderrStream, errorStream, conn, tty, ok := s.createWebSocketStreams(w, req)
+		if !ok {
+			return
+		}
+		defer conn.Close()
+
+		err = s.host.AttachContainer(kubecontainer.GetPodFullName(pod), uid, container, stdinStream, stdoutStream, stderrStream, tty)
+		if err != nil {
+			msg := fmt.Sprintf("Error attaching to container: %v", err)
+			glog.Error(msg)
+			errorStream.Write([]byte(msg))
+		}
+		return
+	}
+
+	stdinStream, stdoutStream, stderrStream, errorStream, conn, tty, ok := s.createStreams(w, req)
+	if !ok {
+		return
+	}
+	defer conn.Close()
+	if errorStream != nil {
+		defer errorStream.Reset()
+	}
+
+	err = s.host.AttachContainer(kubecontainer.GetPodFullName(pod), uid, container, stdinStream, stdoutStream, stderrStream, tty)
+	if err != nil {
+		msg := fmt.Sprintf("Error attaching to container: %v", err)
+		glog.Error(msg)
+		errorStream.Write([]byte(msg))
+	}
+}
+
 func parsePodCoordinates(path string) (namespace, pod string, uid types.UID, err error) {
 	parts := strings.Split(path, "/")
 
@@ -543,6 +1059,11 @@ func (s *Server) handlePortForward(w http.ResponseWriter, req *http.Request) {
 		return
 	}
 
+	if wsstream.IsWebSocketRequest(req) {
+		s.handleWebSocketPortForward(w, req, kubecontainer.GetPodFullName(pod), uid)
+		return
+	}
+
 	streamChan := make(chan httpstream.Stream, 1)
 	upgrader := spdy.NewResponseUpgrader()
 	conn := upgrader.UpgradeResponse(w, req, func(stream httpstream.Stream) error {
@@ -554,6 +1075,11 @@ func (s *Server) handlePortForward(w http.ResponseWriter, req *http.Request) {
 		if port < 1 {
 			return fmt.Errorf("Port '%d' must be greater than 0", port)
 		}
+		switch protocol := stream.Headers().Get(api.ProtocolHeader); protocol {
+		case "", api.PortForwardProtocolTCP, api.PortForwardProtocolUDP:
+		default:
+			return fmt.Errorf("Unsupported port forwarding protocol '%s'", protocol)
+		}
 		streamChan <- stream
 		return nil
 	})
@@ -597,6 +1123,63 @@ Loop:
 	}
 }
 
+// handleWebSocketPortForward forwards the ports given as repeated 'port'
+// query parameters over a websocket connection. Each port is assigned a
+// pair of channels in request order: data (even) and error (odd).
+func (s *Server) handleWebSocketPortForward(w http.ResponseWriter, req *http.Request, pod string, uid types.UID) {
+	req.ParseForm()
+	portStrings := req.Form[api.PortHeader]
+	if len(portStrings) == 0 {
+		http.Error(w, "You must specify at least 1 port", http.StatusBadRequest)
+		return
+	}
+	ports := make([]uint16, len(portStrings))
+	for i, portString := range portStrings {
+		port, err := strconv.ParseUint(portString, 10, 16)
+		if err != nil || port < 1 {
+			http.Error(w, fmt.Sprintf("Unable to parse '%s' as a port", portString), http.StatusBadRequest)
+			return
+		}
+		ports[i] = uint16(port)
+	}
+	protocol := req.FormValue(api.ProtocolHeader)
+	switch protocol {
+	case "":
+		protocol = api.PortForwardProtocolTCP
+	case api.PortForwardProtocolTCP, api.PortForwardProtocolUDP:
+	default:
+		http.Error(w, fmt.Sprintf("Unsupported port forwarding protocol '%s'", protocol), http.StatusBadRequest)
+		return
+	}
+
+	channels := make([]wsstream.ChannelType, 0, len(ports)*2)
+	for range ports {
+		channels = append(channels, wsstream.ReadWriteChannel, wsstream.WriteChannel)
+	}
+	conn := wsstream.NewConn(channels...)
+	conn.SetIdleTimeout(s.host.StreamingConnectionIdleTimeout())
+	streams, err := conn.Open(httplog.Unlogged(w), req)
+	if err != nil {
+		glog.Errorf("Unable to upgrade websocket connection: %v", err)
+		return
+	}
+	defer conn.Close()
+
+	var wg sync.WaitGroup
+	for i, port := range ports {
+		wg.Add(1)
+		go func(dataStream, errorStream io.ReadWriteCloser, port uint16) {
+			defer wg.Done()
+			if err := s.host.PortForward(pod, uid, port, protocol, dataStream); err != nil {
+				msg := fmt.Errorf("Error forwarding %s port %d to pod %s, uid %v: %v", protocol, port, pod, uid, err)
+				glog.Error(msg)
+				errorStream.Write([]byte(msg.Error()))
+			}
+		}(streams[2*i], streams[2*i+1], port)
+	}
+	wg.Wait()
+}
+
 func waitForPortForwardDataStreamAndRun(pod string, uid types.UID, errorStream httpstream.Stream, dataStreamChan chan httpstream.Stream, host HostInterface) {
 	defer errorStream.Reset()
 
@@ -612,14 +1195,33 @@ func waitForPortForwardDataStreamAndRun(pod string, uid types.UID, errorStream h
 
 	portString := dataStream.Headers().Get(api.PortHeader)
 	port, _ := strconv.ParseUint(portString, 10, 16)
-	err := host.PortForward(pod, uid, uint16(port), dataStream)
+	protocol := dataStream.Headers().Get(api.ProtocolHeader)
+	if protocol == "" {
+		protocol = api.PortForwardProtocolTCP
+	}
+	err := host.PortForward(pod, uid, uint16(port), protocol, dataStream)
 	if err != nil {
-		msg := fmt.Errorf("Error forwarding port %d to pod %s, uid %v: %v", port, pod, uid, err)
+		msg := fmt.Errorf("Error forwarding %s port %d to pod %s, uid %v: %v", protocol, port, pod, uid, err)
 		glog.Error(msg)
 		errorStream.Write([]byte(msg.Error()))
 	}
 }
 
+// getRequestAttributes populates authorizer attributes for the request to the kubelet.
+func (s *Server) getRequestAttributes(u user.Info, req *http.Request) authorizer.Attributes {
+	readOnly := req.Method == "GET" || req.Method == "HEAD"
+	// The resource is the first path segment, e.g. "exec" for "/exec/<podID>/<containerName>".
+	resource := strings.TrimPrefix(req.URL.Path, "/")
+	if i := strings.Index(resource, "/"); i != -1 {
+		resource = resource[:i]
+	}
+	return authorizer.AttributesRecord{
+		User:     u,
+		ReadOnly: readOnly,
+		Resource: resource,
+	}
+}
+
 // ServeHTTP responds to HTTP requests on the Kubelet.
 func (s *Server) ServeHTTP(w http.ResponseWriter, req *http.Request) {
 	defer httplog.NewLogged(req, &w).StacktraceWhen(
@@ -631,6 +1233,21 @@ func (s *Server) ServeHTTP(w http.ResponseWriter, req *http.Request) {
 			http.StatusSwitchingProtocols,
 		),
 	).Log()
+	if s.auth != nil {
+		u, ok, err := s.auth.AuthenticateRequest(req)
+		if err != nil || !ok {
+			if err != nil {
+				glog.Errorf("Unable to authenticate the request due to an error: %v", err)
+			}
+			http.Error(w, "Unauthorized", http.StatusUnauthorized)
+			return
+		}
+		if err := s.auth.Authorize(s.getRequestAttributes(u, req)); err != nil {
+			glog.V(2).Infof("Forbidden: %q, reason: %v", req.URL.Path, err)
+			http.Error(w, "Forbidden", http.StatusForbidden)
+			return
+		}
+	}
 	s.mux.ServeHTTP(w, req)
 }
 
@@ -662,6 +1279,8 @@ func (s *Server) serveStats(w http.ResponseWriter, req *http.Request) {
 	// Stats requests are in the following forms:
 	//
 	// /stats/                                              : Root container stats
+	// /stats/summary                                       : Aggregated node, pod and container stats
+	// /stats/usage                                         : Recent usage samples for all pods and containers
 	// /stats/container/                                    : Non-Kubernetes container stats (returns a map)
 	// /stats/<pod name>/<container name>                   : Stats for Kubernetes pod/container
 	// /stats/<namespace>/<pod name>/<uid>/<container name> : Stats for Kubernetes namespace/pod/uid/container
@@ -689,6 +1308,16 @@ func (s *Server) serveStats(w http.ResponseWriter, req *http.Request) {
 		statsMap, err = s.host.GetRawContainerInfo("/", &cadvisorRequest, false)
 		stats = statsMap["/"]
 	case 2:
+		// Aggregated node/pod/container stats.
+		if components[1] == "summary" {
+			stats, err = s.buildStatsSummary()
+			break
+		}
+		// Recent usage samples for all pods and containers.
+		if components[1] == "usage" {
+			stats, err = s.buildUsageReport()
+			break
+		}
 		// Non-Kubernetes container stats.
 		if components[1] != "container" {
 			http.Error(w, fmt.Sprintf("unknown stats request type %q", components[1]), http.StatusNotFound)
@@ -721,6 +1350,9 @@ func (s *Server) serveStats(w http.ResponseWriter, req *http.Request) {
 	case ErrNoKubeletContainers, ErrContainerNotFound:
 		http.Error(w, err.Error(), http.StatusNotFound)
 		return
+	case cadvisor.ErrDisabled:
+		http.Error(w, err.Error(), http.StatusServiceUnavailable)
+		return
 	default:
 		s.error(w, err)
 		return
@@ -33,12 +33,16 @@ import (
 	"time"
 
 	"github.com/GoogleCloudPlatform/kubernetes/pkg/api"
+	"github.com/GoogleCloudPlatform/kubernetes/pkg/kubelet/cadvisor"
 	kubecontainer "github.com/GoogleCloudPlatform/kubernetes/pkg/kubelet/container"
 	"github.com/GoogleCloudPlatform/kubernetes/pkg/kubelet/dockertools"
+	"github.com/GoogleCloudPlatform/kubernetes/pkg/kubelet/metrics"
 	"github.com/GoogleCloudPlatform/kubernetes/pkg/types"
 	"github.com/GoogleCloudPlatform/kubernetes/pkg/util/httpstream"
 	"github.com/GoogleCloudPlatform/kubernetes/pkg/util/httpstream/spdy"
+	"github.com/GoogleCloudPlatform/kubernetes/pkg/version"
 	cadvisorApi "github.com/google/cadvisor/info/v1"
+	"github.com/prometheus/client_golang/prometheus"
 )
 
 type fakeKubelet struct {
@@ -48,14 +52,20 @@ type fakeKubelet struct {
 	rawInfoFunc                        func(query *cadvisorApi.ContainerInfoRequest) (map[string]*cadvisorApi.ContainerInfo, error)
 	machineInfoFunc                    func() (*cadvisorApi.MachineInfo, error)
 	podsFunc                           func() []*api.Pod
+	runningPodsFunc                    func() ([]*api.Pod, error)
 	logFunc                            func(w http.ResponseWriter, req *http.Request)
 	runFunc                            func(podFullName string, uid types.UID, containerName string, cmd []string) ([]byte, error)
 	containerVersionFunc               func() (kubecontainer.Version, error)
+	versionInfoFunc                    func() (*cadvisorApi.VersionInfo, error)
 	execFunc                           func(pod string, uid types.UID, container string, cmd []string, in io.Reader, out, err io.WriteCloser, tty bool) error
-	portForwardFunc                    func(name string, uid types.UID, port uint16, stream io.ReadWriteCloser) error
-	containerLogsFunc                  func(podFullName, containerName, tail string, follow bool, stdout, stderr io.Writer) error
+	attachFunc                         func(pod string, uid types.UID, container string, in io.Reader, out, err io.WriteCloser, tty bool) error
+	portForwardFunc                    func(name string, uid types.UID, port uint16, protocol string, stream io.ReadWriteCloser) error
+	containerLogsFunc                  func(podFullName, containerName string, logOptions *api.PodLogOptions, stdout, stderr io.Writer) error
 	streamingConnectionIdleTimeoutFunc func() time.Duration
 	hostnameFunc                       func() string
+	latestLoopEntryTimeFunc            func() time.Time
+	resyncIntervalFunc                 func() time.Duration
+	syncPodNowFunc                     func(pod *api.Pod) error
 }
 
 func (fk *fakeKubelet) GetPodByName(namespace, name string) (*api.Pod, bool) {
@@ -82,16 +92,24 @@ func (fk *fakeKubelet) GetCachedMachineInfo() (*cadvisorApi.MachineInfo, error)
 	return fk.machineInfoFunc()
 }
 
+func (fk *fakeKubelet) GetVersionInfo() (*cadvisorApi.VersionInfo, error) {
+	return fk.versionInfoFunc()
+}
+
 func (fk *fakeKubelet) GetPods() []*api.Pod {
 	return fk.podsFunc()
 }
 
+func (fk *fakeKubelet) GetRunningPods() ([]*api.Pod, error) {
+	return fk.runningPodsFunc()
+}
+
 func (fk *fakeKubelet) ServeLogs(w http.ResponseWriter, req *http.Request) {
 	fk.logFunc(w, req)
 }
 
-func (fk *fakeKubelet) GetKubeletContainerLogs(podFullName, containerName, tail string, follow bool, stdout, stderr io.Writer) error {
-	return fk.containerLogsFunc(podFullName, containerName, tail, follow, stdout, stderr)
+func (fk *fakeKubelet) GetKubeletContainerLogs(podFullName, containerName string, logOptions *api.PodLogOptions, stdout, stderr io.Writer) error {
+	return fk.containerLogsFunc(podFullName, containerName, logOptions, stdout, stderr)
 }
 
 func (fk *fakeKubelet) GetHostname() string {
@@ -106,14 +124,30 @@ func (fk *fakeKubelet) ExecInContainer(name string, uid types.UID, container str
 	return fk.execFunc(name, uid, container, cmd, in, out, err, tty)
 }
 
-func (fk *fakeKubelet) PortForward(name string, uid types.UID, port uint16, stream io.ReadWriteCloser) error {
-	return fk.portForwardFunc(name, uid, port, stream)
+func (fk *fakeKubelet) AttachContainer(name string, uid types.UID, container string, in io.Reader, out, err io.WriteCloser, tty bool) error {
+	return fk.attachFunc(name, uid, container, in, out, err, tty)
+}
+
+func (fk *fakeKubelet) PortForward(name string, uid types.UID, port uint16, protocol string, stream io.ReadWriteCloser) error {
+	return fk.portForwardFunc(name, uid, port, protocol, stream)
 }
 
 func (fk *fakeKubelet) StreamingConnectionIdleTimeout() time.Duration {
 	return fk.streamingConnectionIdleTimeoutFunc()
 }
 
+func (fk *fakeKubelet) LatestLoopEntryTime() time.Time {
+	return fk.latestLoopEntryTimeFunc()
+}
+
+func (fk *fakeKubelet) ResyncInterval() time.Duration {
+	return fk.resyncIntervalFunc()
+}
+
+func (fk *fakeKubelet) SyncPodNow(pod *api.Pod) error {
+	return fk.syncPodNowFunc(pod)
+}
+
 type serverTestFramework struct {
 	serverUnderTest *Server
 	fakeKubelet     *fakeKubelet
@@ -131,13 +165,124 @@ func newServerTest() *serverTestFramework {
 				},
 			}, true
 		},
+		rawInfoFunc: func(req *cadvisorApi.ContainerInfoRequest) (map[string]*cadvisorApi.ContainerInfo, error) {
+			return map[string]*cadvisorApi.ContainerInfo{}, nil
+		},
+		latestLoopEntryTimeFunc: func() time.Time {
+			return time.Now()
+		},
+		resyncIntervalFunc: func() time.Duration {
+			return time.Minute
+		},
+		machineInfoFunc: func() (*cadvisorApi.MachineInfo, error) {
+			return &cadvisorApi.MachineInfo{}, nil
+		},
 	}
-	server := NewServer(fw.fakeKubelet, true)
+	server := NewServer(fw.fakeKubelet, nil, true)
 	fw.serverUnderTest = &server
 	fw.testHTTPServer = httptest.NewServer(fw.serverUnderTest)
 	return fw
 }
 
+func TestSyncLoopHealthCheck(t *testing.T) {
+	fw := newServerTest()
+	fw.fakeKubelet.resyncIntervalFunc = func() time.Duration {
+		return 10 * time.Second
+	}
+
+	loopTime := time.Time{}
+	fw.fakeKubelet.latestLoopEntryTimeFunc = func() time.Time {
+		return loopTime
+	}
+
+	// The loop has not started yet; do not report failure.
+	if err := fw.serverUnderTest.syncLoopHealthCheck(nil); err != nil {
+		t.Errorf("unexpected failure before the loop started: %v", err)
+	}
+
+	loopTime = time.Now()
+	if err := fw.serverUnderTest.syncLoopHealthCheck(nil); err != nil {
+		t.Errorf("unexpected failure for a recent iteration: %v", err)
+	}
+
+	loopTime = time.Now().Add(-10 * time.Minute)
+	if err := fw.serverUnderTest.syncLoopHealthCheck(nil); err == nil {
+		t.Errorf("expected failure for a stale iteration")
+	}
+}
+
+func TestCadvisorMetricsEndpoint(t *testing.T) {
+	fw := newServerTest()
+	resp, err := http.Get(fw.testHTTPServer.URL + "/metrics/cadvisor")
+	if err != nil {
+		t.Fatalf("Got error GETing: %v", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Errorf("expected status code %d, got %d", http.StatusOK, resp.StatusCode)
+	}
+	body, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		t.Fatalf("Error reading body: %v", err)
+	}
+	if !strings.Contains(string(body), "machine_cpu_cores") {
+		t.Errorf("expected machine metrics in response, got: %s", string(body))
+	}
+}
+
+func TestProbeMetricsEndpoint(t *testing.T) {
+	fw := newServerTest()
+	// The probe metrics are normally registered by metrics.Register; tests
+	// do not start a kubelet, so register and populate them directly.
+	prometheus.Register(metrics.ProbeResults)
+	metrics.ProbeResults.WithLabelValues("podFoo", "nsFoo", "containerFoo", "readiness", "success").Inc()
+
+	resp, err := http.Get(fw.testHTTPServer.URL + "/metrics/probes")
+	if err != nil {
+		t.Fatalf("Got error GETing: %v", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Errorf("expected status code %d, got %d", http.StatusOK, resp.StatusCode)
+	}
+	body, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		t.Fatalf("Error reading body: %v", err)
+	}
+	if !strings.Contains(string(body), "kubelet_probe_results") {
+		t.Errorf("expected probe metrics in response, got: %s", string(body))
+	}
+}
+
+func TestReadOnlyServerExcludesPrivilegedHandlers(t *testing.T) {
+	fw := &serverTestFramework{}
+	fw.fakeKubelet = &fakeKubelet{}
+	server := NewReadOnlyServer(fw.fakeKubelet)
+	fw.serverUnderTest = &server
+	fw.testHTTPServer = httptest.NewServer(fw.serverUnderTest)
+	defer fw.testHTTPServer.Close()
+
+	for _, path := range []string{"/run/podFoo/containerFoo", "/exec/podFoo/containerFoo", "/attach/podFoo/containerFoo", "/portForward/podFoo", "/logs/", "/containerLogs/podFoo/containerFoo"} {
+		resp, err := http.Get(fw.testHTTPServer.URL + path)
+		if err != nil {
+			t.Fatalf("Got error GETing %s: %v", path, err)
+		}
+		resp.Body.Close()
+		if resp.StatusCode != http.StatusNotFound {
+			t.Errorf("expected %q to be absent from the read-only server, got status %d", path, resp.StatusCode)
+		}
+	}
+
+	resp, err := http.Get(fw.testHTTPServer.URL + "/healthz")
+	if err != nil {
+		t.Fatalf("Got error GETing /healthz: %v", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Errorf("expected /healthz to be served on the read-only server, got status %d", resp.StatusCode)
+	}
+}
+
 // encodeJSON returns obj marshalled as a JSON string, panicing on any errors
 func encodeJSON(obj interface{}) string {
 	data, err := json.Marshal(obj)
@@ -191,6 +336,60 @@ func TestPodStatus(t *testing.T) {
 	}
 }
 
+func TestServeVersion(t *testing.T) {
+	fw := newServerTest()
+	fw.fakeKubelet.containerVersionFunc = func() (kubecontainer.Version, error) {
+		return dockertools.NewVersion("1.15")
+	}
+	fw.fakeKubelet.versionInfoFunc = func() (*cadvisorApi.VersionInfo, error) {
+		return &cadvisorApi.VersionInfo{CadvisorVersion: "0.11.0"}, nil
+	}
+	resp, err := http.Get(fw.testHTTPServer.URL + "/version")
+	if err != nil {
+		t.Fatalf("Got error GETing: %v", err)
+	}
+	got, err := readResp(resp)
+	if err != nil {
+		t.Errorf("Error reading body: %v", err)
+	}
+	expected := versionResponse{
+		Info:            version.Get(),
+		RuntimeVersion:  "1.15",
+		CadvisorVersion: "0.11.0",
+	}
+	expectedBytes, err := json.Marshal(expected)
+	if err != nil {
+		t.Fatalf("Unexpected marshal error %v", err)
+	}
+	if got != string(expectedBytes) {
+		t.Errorf("Expected: '%v', got: '%v'", expected, got)
+	}
+}
+
+func TestServeVersionWithCadvisorDisabled(t *testing.T) {
+	fw := newServerTest()
+	fw.fakeKubelet.containerVersionFunc = func() (kubecontainer.Version, error) {
+		return dockertools.NewVersion("1.15")
+	}
+	fw.fakeKubelet.versionInfoFunc = func() (*cadvisorApi.VersionInfo, error) {
+		return nil, cadvisor.ErrDisabled
+	}
+	resp, err := http.Get(fw.testHTTPServer.URL + "/version")
+	if err != nil {
+		t.Fatalf("Got error GETing: %v", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		t.Errorf("expected status code %d, got %d", http.StatusOK, resp.StatusCode)
+	}
+	got, err := readResp(resp)
+	if err != nil {
+		t.Errorf("Error reading body: %v", err)
+	}
+	if !strings.Contains(got, `"cadvisorVersion":""`) {
+		t.Errorf("expected an empty cadvisor version, got %q", got)
+	}
+}
+
 func TestContainerInfo(t *testing.T) {
 	fw := newServerTest()
 	expectedInfo := &cadvisorApi.ContainerInfo{}
@@ -268,6 +467,21 @@ func TestContainerNotFound(t *testing.T) {
 	defer resp.Body.Close()
 }
 
+func TestStatsWithCadvisorDisabled(t *testing.T) {
+	fw := newServerTest()
+	fw.fakeKubelet.containerInfoFunc = func(podID string, uid types.UID, containerName string, req *cadvisorApi.ContainerInfoRequest) (*cadvisorApi.ContainerInfo, error) {
+		return nil, cadvisor.ErrDisabled
+	}
+	resp, err := http.Get(fw.testHTTPServer.URL + "/stats/custom/somepod/9b01b80f-8fb4-11e4-95ab-4200af06647/goodcontainer")
+	if err != nil {
+		t.Fatalf("Got error GETing: %v", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusServiceUnavailable {
+		t.Fatalf("Received status %d expecting %d", resp.StatusCode, http.StatusServiceUnavailable)
+	}
+}
+
 func TestRootInfo(t *testing.T) {
 	fw := newServerTest()
 	expectedInfo := &cadvisorApi.ContainerInfo{
@@ -296,6 +510,169 @@ func TestRootInfo(t *testing.T) {
 	}
 }
 
+func TestStatsSummary(t *testing.T) {
+	fw := newServerTest()
+	sampleTime := time.Unix(1429900000, 0)
+	makeStats := func(cpuTotal, memUsage uint64) []*cadvisorApi.ContainerStats {
+		return []*cadvisorApi.ContainerStats{
+			{
+				Timestamp: sampleTime.Add(-10 * time.Second),
+			},
+			{
+				Timestamp: sampleTime,
+				Cpu:       cadvisorApi.CpuStats{Usage: cadvisorApi.CpuUsage{Total: cpuTotal}},
+				Memory:    cadvisorApi.MemoryStats{Usage: memUsage, WorkingSet: memUsage / 2},
+				Network:   cadvisorApi.NetworkStats{RxBytes: 100, TxBytes: 200},
+			},
+		}
+	}
+	fw.fakeKubelet.hostnameFunc = func() string {
+		return "somehostname"
+	}
+	fw.fakeKubelet.rawInfoFunc = func(req *cadvisorApi.ContainerInfoRequest) (map[string]*cadvisorApi.ContainerInfo, error) {
+		return map[string]*cadvisorApi.ContainerInfo{
+			"/": {
+				ContainerReference: cadvisorApi.ContainerReference{Name: "/"},
+				Stats:              makeStats(20000000000, 1024),
+			},
+		}, nil
+	}
+	fw.fakeKubelet.podsFunc = func() []*api.Pod {
+		return []*api.Pod{
+			{
+				ObjectMeta: api.ObjectMeta{Namespace: "ns", Name: "somepod", UID: "1234"},
+				Spec: api.PodSpec{
+					Containers: []api.Container{{Name: "goodcontainer"}},
+				},
+			},
+		}
+	}
+	fw.fakeKubelet.containerInfoFunc = func(podID string, uid types.UID, containerName string, req *cadvisorApi.ContainerInfoRequest) (*cadvisorApi.ContainerInfo, error) {
+		if podID != "somepod_ns" || uid != "1234" {
+			return nil, fmt.Errorf("bad podID or uid: podID=%v; uid=%v", podID, uid)
+		}
+		switch containerName {
+		case "goodcontainer", dockertools.PodInfraContainerName:
+			return &cadvisorApi.ContainerInfo{Stats: makeStats(10000000000, 512)}, nil
+		default:
+			return nil, ErrContainerNotFound
+		}
+	}
+
+	resp, err := http.Get(fw.testHTTPServer.URL + "/stats/summary")
+	if err != nil {
+		t.Fatalf("Got error GETing: %v", err)
+	}
+	defer resp.Body.Close()
+	var summary StatsSummary
+	if err := json.NewDecoder(resp.Body).Decode(&summary); err != nil {
+		t.Fatalf("received invalid json data: %v", err)
+	}
+	if summary.Node.NodeName != "somehostname" {
+		t.Errorf("unexpected node name: %q", summary.Node.NodeName)
+	}
+	if summary.Node.CPU.UsageCoreNanoSeconds != 20000000000 {
+		t.Errorf("unexpected node cpu usage: %d", summary.Node.CPU.UsageCoreNanoSeconds)
+	}
+	if summary.Node.CPU.UsageNanoCores != 2000000000 {
+		t.Errorf("unexpected node cpu rate: %d", summary.Node.CPU.UsageNanoCores)
+	}
+	if summary.Node.Memory.UsageBytes != 1024 || summary.Node.Memory.WorkingSetBytes != 512 {
+		t.Errorf("unexpected node memory stats: %#v", summary.Node.Memory)
+	}
+	if len(summary.Pods) != 1 {
+		t.Fatalf("unexpected pod count: %d", len(summary.Pods))
+	}
+	pod := summary.Pods[0]
+	if pod.PodRef.Name != "somepod" || pod.PodRef.Namespace != "ns" || pod.PodRef.UID != "1234" {
+		t.Errorf("unexpected pod reference: %#v", pod.PodRef)
+	}
+	if len(pod.Containers) != 1 || pod.Containers[0].Name != "goodcontainer" {
+		t.Fatalf("unexpected containers: %#v", pod.Containers)
+	}
+	if pod.Containers[0].Memory.UsageBytes != 512 {
+		t.Errorf("unexpected container memory stats: %#v", pod.Containers[0].Memory)
+	}
+	if pod.Network.RxBytes != 100 || pod.Network.TxBytes != 200 {
+		t.Errorf("unexpected pod network stats: %#v", pod.Network)
+	}
+}
+
+func TestStatsUsage(t *testing.T) {
+	fw := newServerTest()
+	sampleTime := time.Unix(1429900000, 0)
+	fw.fakeKubelet.podsFunc = func() []*api.Pod {
+		return []*api.Pod{
+			{
+				ObjectMeta: api.ObjectMeta{Namespace: "ns", Name: "somepod", UID: "1234"},
+				Spec: api.PodSpec{
+					Containers: []api.Container{{Name: "goodcontainer"}},
+				},
+			},
+		}
+	}
+	fw.fakeKubelet.containerInfoFunc = func(podID string, uid types.UID, containerName string, req *cadvisorApi.ContainerInfoRequest) (*cadvisorApi.ContainerInfo, error) {
+		if podID != "somepod_ns" || uid != "1234" || containerName != "goodcontainer" {
+			return nil, ErrContainerNotFound
+		}
+		if req.NumStats != usageReportSamples {
+			t.Errorf("unexpected sample count requested: %d", req.NumStats)
+		}
+		return &cadvisorApi.ContainerInfo{
+			Stats: []*cadvisorApi.ContainerStats{
+				{
+					Timestamp: sampleTime.Add(-10 * time.Second),
+					Cpu:       cadvisorApi.CpuStats{Usage: cadvisorApi.CpuUsage{Total: 10000000000}},
+					Memory:    cadvisorApi.MemoryStats{Usage: 1024, WorkingSet: 512},
+				},
+				{
+					Timestamp: sampleTime,
+					Cpu:       cadvisorApi.CpuStats{Usage: cadvisorApi.CpuUsage{Total: 30000000000}},
+					Memory:    cadvisorApi.MemoryStats{Usage: 2048, WorkingSet: 1024},
+				},
+			},
+		}, nil
+	}
+
+	resp, err := http.Get(fw.testHTTPServer.URL + "/stats/usage")
+	if err != nil {
+		t.Fatalf("Got error GETing: %v", err)
+	}
+	defer resp.Body.Close()
+	var report UsageReport
+	if err := json.NewDecoder(resp.Body).Decode(&report); err != nil {
+		t.Fatalf("received invalid json data: %v", err)
+	}
+	if len(report.Pods) != 1 {
+		t.Fatalf("unexpected pod count: %d", len(report.Pods))
+	}
+	pod := report.Pods[0]
+	if pod.PodRef.Name != "somepod" || pod.PodRef.Namespace != "ns" || pod.PodRef.UID != "1234" {
+		t.Errorf("unexpected pod reference: %#v", pod.PodRef)
+	}
+	if len(pod.Containers) != 1 || pod.Containers[0].Name != "goodcontainer" {
+		t.Fatalf("unexpected containers: %#v", pod.Containers)
+	}
+	samples := pod.Containers[0].Samples
+	if len(samples) != 2 {
+		t.Fatalf("unexpected sample count: %d", len(samples))
+	}
+	// The oldest sample has no predecessor to derive a rate from.
+	if samples[0].CPUUsageNanoCores != 0 {
+		t.Errorf("unexpected cpu rate for the oldest sample: %d", samples[0].CPUUsageNanoCores)
+	}
+	// 20s of CPU over 10s elapsed = 2 cores.
+	if samples[1].CPUUsageNanoCores != 2000000000 {
+		t.Errorf("unexpected cpu rate: %d", samples[1].CPUUsageNanoCores)
+	}
+	if samples[1].CPUUsageCoreNanoSeconds != 30000000000 {
+		t.Errorf("unexpected cumulative cpu usage: %d", samples[1].CPUUsageCoreNanoSeconds)
+	}
+	if samples[1].MemoryUsageBytes != 2048 || samples[1].MemoryWorkingSetBytes != 1024 {
+		t.Errorf("unexpected memory usage: %#v", samples[1])
+	}
+}
+
 func TestSubcontainerContainerInfo(t *testing.T) {
 	fw := newServerTest()
 	const kubeletContainer = "/kubelet"
@@ -394,6 +771,126 @@ func TestServeLogs(t *testing.T) {
 	}
 }
 
+func TestServeLogsRejectsDotDot(t *testing.T) {
+	fw := newServerTest()
+
+	fw.fakeKubelet.logFunc = func(w http.ResponseWriter, req *http.Request) {
+		t.Errorf("expected the request to be rejected before reaching the log server")
+	}
+
+	// Build the request by hand and invoke the handler directly so nothing
+	// cleans the path for us along the way.
+	req, err := http.NewRequest("GET", "/logs/", nil)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	req.URL.Path = "/logs/../../etc/passwd"
+	recorder := httptest.NewRecorder()
+	fw.serverUnderTest.handleLogs(recorder, req)
+	if recorder.Code != http.StatusBadRequest {
+		t.Errorf("expected status %d, got %d", http.StatusBadRequest, recorder.Code)
+	}
+}
+
+func TestDebugSync(t *testing.T) {
+	fw := newServerTest()
+	synced := []string{}
+	fw.fakeKubelet.syncPodNowFunc = func(pod *api.Pod) error {
+		synced = append(synced, getPodName(pod.Name, pod.Namespace))
+		return nil
+	}
+
+	resp, err := http.Post(fw.testHTTPServer.URL+"/debug/sync/other/foo", "text/plain", nil)
+	if err != nil {
+		t.Fatalf("Got error POSTing: %v", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Errorf("expected %v, got %v", http.StatusOK, resp.StatusCode)
+	}
+	if len(synced) != 1 || synced[0] != getPodName("foo", "other") {
+		t.Errorf("expected one sync of %q, got %v", getPodName("foo", "other"), synced)
+	}
+
+	// A GET must not trigger a sync.
+	resp, err = http.Get(fw.testHTTPServer.URL + "/debug/sync/other/foo")
+	if err != nil {
+		t.Fatalf("Got error GETing: %v", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusMethodNotAllowed {
+		t.Errorf("expected %v, got %v", http.StatusMethodNotAllowed, resp.StatusCode)
+	}
+	if len(synced) != 1 {
+		t.Errorf("expected no further syncs, got %v", synced)
+	}
+
+	// An unknown pod is a 404.
+	fw.fakeKubelet.podByNameFunc = func(namespace, name string) (*api.Pod, bool) {
+		return nil, false
+	}
+	resp, err = http.Post(fw.testHTTPServer.URL+"/debug/sync/other/gone", "text/plain", nil)
+	if err != nil {
+		t.Fatalf("Got error POSTing: %v", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusNotFound {
+		t.Errorf("expected %v, got %v", http.StatusNotFound, resp.StatusCode)
+	}
+}
+
+func TestDebugFlagV(t *testing.T) {
+	fw := newServerTest()
+
+	read := func() string {
+		resp, err := http.Get(fw.testHTTPServer.URL + "/debug/flags/v")
+		if err != nil {
+			t.Fatalf("Got error GETing: %v", err)
+		}
+		defer resp.Body.Close()
+		if resp.StatusCode != http.StatusOK {
+			t.Errorf("expected %v, got %v", http.StatusOK, resp.StatusCode)
+		}
+		body, err := ioutil.ReadAll(resp.Body)
+		if err != nil {
+			t.Fatalf("Cannot copy resp: %v", err)
+		}
+		return strings.TrimSpace(string(body))
+	}
+	put := func(level string) *http.Response {
+		req, err := http.NewRequest("PUT", fw.testHTTPServer.URL+"/debug/flags/v", strings.NewReader(level))
+		if err != nil {
+			t.Fatalf("Got error building request: %v", err)
+		}
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatalf("Got error PUTting: %v", err)
+		}
+		return resp
+	}
+
+	original := read()
+	defer func() {
+		resp := put(original)
+		resp.Body.Close()
+	}()
+
+	resp := put("4")
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Errorf("expected %v, got %v", http.StatusOK, resp.StatusCode)
+	}
+	if level := read(); level != "4" {
+		t.Errorf("expected verbosity 4, got %q", level)
+	}
+
+	resp = put("not-a-number")
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusBadRequest {
+		t.Errorf("expected %v, got %v", http.StatusBadRequest, resp.StatusCode)
+	}
+}
+
 func TestServeRunInContainer(t *testing.T) {
 	fw := newServerTest()
 	output := "foo bar"
@@ -553,19 +1050,16 @@ func setPodByNameFunc(fw *serverTestFramework, namespace, pod, container string)
 	}
 }
 
-func setGetContainerLogsFunc(fw *serverTestFramework, t *testing.T, expectedPodName, expectedContainerName, expectedTail string, expectedFollow bool, output string) {
-	fw.fakeKubelet.containerLogsFunc = func(podFullName, containerName, tail string, follow bool, stdout, stderr io.Writer) error {
+func setGetContainerLogsFunc(fw *serverTestFramework, t *testing.T, expectedPodName, expectedContainerName string, expectedLogOptions *api.PodLogOptions, output string) {
+	fw.fakeKubelet.containerLogsFunc = func(podFullName, containerName string, logOptions *api.PodLogOptions, stdout, stderr io.Writer) error {
 		if podFullName != expectedPodName {
 			t.Errorf("expected %s, got %s", expectedPodName, podFullName)
 		}
 		if containerName != expectedContainerName {
 			t.Errorf("expected %s, got %s", expectedContainerName, containerName)
 		}
-		if tail != expectedTail {
-			t.Errorf("expected %s, got %s", expectedTail, tail)
-		}
-		if follow != expectedFollow {
-			t.Errorf("expected %t, got %t", expectedFollow, follow)
+		if !reflect.DeepEqual(logOptions, expectedLogOptions) {
+			t.Errorf("expected %#v, got %#v", expectedLogOptions, logOptions)
 		}
 		io.WriteString(stdout, output)
 		return nil
@@ -579,10 +1073,8 @@ func TestContainerLogs(t *testing.T) {
 	podName := "foo"
 	expectedPodName := getPodName(podName, podNamespace)
 	expectedContainerName := "baz"
-	expectedTail := ""
-	expectedFollow := false
 	setPodByNameFunc(fw, podNamespace, podName, expectedContainerName)
-	setGetContainerLogsFunc(fw, t, expectedPodName, expectedContainerName, expectedTail, expectedFollow, output)
+	setGetContainerLogsFunc(fw, t, expectedPodName, expectedContainerName, &api.PodLogOptions{}, output)
 	resp, err := http.Get(fw.testHTTPServer.URL + "/containerLogs/" + podNamespace + "/" + podName + "/" + expectedContainerName)
 	if err != nil {
 		t.Errorf("Got error GETing: %v", err)
@@ -606,10 +1098,9 @@ func TestContainerLogsWithTail(t *testing.T) {
 	podName := "foo"
 	expectedPodName := getPodName(podName, podNamespace)
 	expectedContainerName := "baz"
-	expectedTail := "5"
-	expectedFollow := false
+	expectedTailLines := int64(5)
 	setPodByNameFunc(fw, podNamespace, podName, expectedContainerName)
-	setGetContainerLogsFunc(fw, t, expectedPodName, expectedContainerName, expectedTail, expectedFollow, output)
+	setGetContainerLogsFunc(fw, t, expectedPodName, expectedContainerName, &api.PodLogOptions{TailLines: &expectedTailLines}, output)
 	resp, err := http.Get(fw.testHTTPServer.URL + "/containerLogs/" + podNamespace + "/" + podName + "/" + expectedContainerName + "?tail=5")
 	if err != nil {
 		t.Errorf("Got error GETing: %v", err)
@@ -626,6 +1117,38 @@ func TestContainerLogsWithTail(t *testing.T) {
 	}
 }
 
+func TestContainerLogsWithSinceTimestampsAndPrevious(t *testing.T) {
+	fw := newServerTest()
+	output := "foo bar"
+	podNamespace := "other"
+	podName := "foo"
+	expectedPodName := getPodName(podName, podNamespace)
+	expectedContainerName := "baz"
+	expectedSinceSeconds := int64(100)
+	expectedTailLines := int64(5)
+	setPodByNameFunc(fw, podNamespace, podName, expectedContainerName)
+	setGetContainerLogsFunc(fw, t, expectedPodName, expectedContainerName, &api.PodLogOptions{
+		Previous:     true,
+		Timestamps:   true,
+		SinceSeconds: &expectedSinceSeconds,
+		TailLines:    &expectedTailLines,
+	}, output)
+	resp, err := http.Get(fw.testHTTPServer.URL + "/containerLogs/" + podNamespace + "/" + podName + "/" + expectedContainerName + "?previous=1&timestamps=1&sinceSeconds=100&tailLines=5")
+	if err != nil {
+		t.Errorf("Got error GETing: %v", err)
+	}
+	defer resp.Body.Close()
+
+	body, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		t.Errorf("Error reading container logs: %v", err)
+	}
+	result := string(body)
+	if result != output {
+		t.Errorf("Expected: '%v', got: '%v'", output, result)
+	}
+}
+
 func TestContainerLogsWithFollow(t *testing.T) {
 	fw := newServerTest()
 	output := "foo bar"
@@ -633,10 +1156,8 @@ func TestContainerLogsWithFollow(t *testing.T) {
 	podName := "foo"
 	expectedPodName := getPodName(podName, podNamespace)
 	expectedContainerName := "baz"
-	expectedTail := ""
-	expectedFollow := true
 	setPodByNameFunc(fw, podNamespace, podName, expectedContainerName)
-	setGetContainerLogsFunc(fw, t, expectedPodName, expectedContainerName, expectedTail, expectedFollow, output)
+	setGetContainerLogsFunc(fw, t, expectedPodName, expectedContainerName, &api.PodLogOptions{Follow: true}, output)
 	resp, err := http.Get(fw.testHTTPServer.URL + "/containerLogs/" + podNamespace + "/" + podName + "/" + expectedContainerName + "?follow=1")
 	if err != nil {
 		t.Errorf("Got error GETing: %v", err)
@@ -924,6 +1445,169 @@ func TestServeExecInContainer(t *testing.T) {
 	}
 }
 
+func TestServeAttachContainer(t *testing.T) {
+	tests := []struct {
+		stdin              bool
+		stdout             bool
+		stderr             bool
+		tty                bool
+		responseStatusCode int
+	}{
+		{responseStatusCode: http.StatusBadRequest},
+		{stdout: true, responseStatusCode: http.StatusSwitchingProtocols},
+		{stdout: true, stderr: true, responseStatusCode: http.StatusSwitchingProtocols},
+		{stdin: true, stdout: true, tty: true, responseStatusCode: http.StatusSwitchingProtocols},
+	}
+
+	for i, test := range tests {
+		fw := newServerTest()
+
+		fw.fakeKubelet.streamingConnectionIdleTimeoutFunc = func() time.Duration {
+			return 0
+		}
+
+		podNamespace := "other"
+		podName := "foo"
+		expectedPodName := getPodName(podName, podNamespace)
+		expectedContainerName := "baz"
+		expectedStdout := "stdout"
+		attachFuncDone := make(chan struct{})
+		clientStdoutReadDone := make(chan struct{})
+
+		fw.fakeKubelet.attachFunc = func(podFullName string, uid types.UID, containerName string, in io.Reader, out, stderr io.WriteCloser, tty bool) error {
+			defer close(attachFuncDone)
+			if podFullName != expectedPodName {
+				t.Fatalf("%d: podFullName: expected %s, got %s", i, expectedPodName, podFullName)
+			}
+			if containerName != expectedContainerName {
+				t.Fatalf("%d: containerName: expected %s, got %s", i, expectedContainerName, containerName)
+			}
+			if tty != test.tty {
+				t.Fatalf("%d: tty: expected %t, got %t", i, test.tty, tty)
+			}
+			if test.stdin && in == nil {
+				t.Fatalf("%d: stdin: expected non-nil", i)
+			}
+			if test.stdout {
+				if out == nil {
+					t.Fatalf("%d: stdout: expected non-nil", i)
+				}
+				_, err := out.Write([]byte(expectedStdout))
+				if err != nil {
+					t.Fatalf("%d: error writing to stdout: %v", i, err)
+				}
+				out.Close()
+				<-clientStdoutReadDone
+			}
+			if test.tty && stderr != nil {
+				t.Fatalf("%d: tty set but received non-nil stderr: %v", i, stderr)
+			}
+			return nil
+		}
+
+		url := fw.testHTTPServer.URL + "/attach/" + podNamespace + "/" + podName + "/" + expectedContainerName + "?"
+		if test.stdin {
+			url += "&" + api.ExecStdinParam + "=1"
+		}
+		if test.stdout {
+			url += "&" + api.ExecStdoutParam + "=1"
+		}
+		if test.stderr && !test.tty {
+			url += "&" + api.ExecStderrParam + "=1"
+		}
+		if test.tty {
+			url += "&" + api.ExecTTYParam + "=1"
+		}
+
+		var (
+			resp                *http.Response
+			err                 error
+			upgradeRoundTripper httpstream.UpgradeRoundTripper
+			c                   *http.Client
+		)
+
+		if test.responseStatusCode != http.StatusSwitchingProtocols {
+			c = &http.Client{}
+		} else {
+			upgradeRoundTripper = spdy.NewRoundTripper(nil)
+			c = &http.Client{Transport: upgradeRoundTripper}
+		}
+
+		resp, err = c.Get(url)
+		if err != nil {
+			t.Fatalf("%d: Got error GETing: %v", i, err)
+		}
+		defer resp.Body.Close()
+
+		if e, a := test.responseStatusCode, resp.StatusCode; e != a {
+			t.Fatalf("%d: response status: expected %v, got %v", i, e, a)
+		}
+
+		if test.responseStatusCode != http.StatusSwitchingProtocols {
+			continue
+		}
+
+		conn, err := upgradeRoundTripper.NewConnection(resp)
+		if err != nil {
+			t.Fatalf("Unexpected error creating streaming connection: %s", err)
+		}
+		if conn == nil {
+			t.Fatalf("%d: unexpected nil conn", i)
+		}
+		defer conn.Close()
+
+		h := http.Header{}
+		h.Set(api.StreamType, api.StreamTypeError)
+		errorStream, err := conn.CreateStream(h)
+		if err != nil {
+			t.Fatalf("%d: error creating error stream: %v", i, err)
+		}
+		defer errorStream.Reset()
+
+		if test.stdin {
+			h.Set(api.StreamType, api.StreamTypeStdin)
+			stream, err := conn.CreateStream(h)
+			if err != nil {
+				t.Fatalf("%d: error creating stdin stream: %v", i, err)
+			}
+			defer stream.Reset()
+		}
+
+		var stdoutStream httpstream.Stream
+		if test.stdout {
+			h.Set(api.StreamType, api.StreamTypeStdout)
+			stdoutStream, err = conn.CreateStream(h)
+			if err != nil {
+				t.Fatalf("%d: error creating stdout stream: %v", i, err)
+			}
+			defer stdoutStream.Reset()
+		}
+
+		if test.stderr && !test.tty {
+			h.Set(api.StreamType, api.StreamTypeStderr)
+			stderrStream, err := conn.CreateStream(h)
+			if err != nil {
+				t.Fatalf("%d: error creating stderr stream: %v", i, err)
+			}
+			defer stderrStream.Reset()
+		}
+
+		if test.stdout {
+			output := make([]byte, 10)
+			n, err := stdoutStream.Read(output)
+			close(clientStdoutReadDone)
+			if err != nil {
+				t.Fatalf("%d: error reading from stdout stream: %v", i, err)
+			}
+			if e, a := expectedStdout, string(output[0:n]); e != a {
+				t.Fatalf("%d: stdout: expected '%v', got '%v'", i, e, a)
+			}
+		}
+
+		<-attachFuncDone
+	}
+}
+
 func TestServePortForwardIdleTimeout(t *testing.T) {
 	fw := newServerTest()
 
@@ -991,7 +1675,7 @@ func TestServePortForward(t *testing.T) {
 
 		portForwardFuncDone := make(chan struct{})
 
-		fw.fakeKubelet.portForwardFunc = func(name string, uid types.UID, port uint16, stream io.ReadWriteCloser) error {
+		fw.fakeKubelet.portForwardFunc = func(name string, uid types.UID, port uint16, protocol string, stream io.ReadWriteCloser) error {
 			defer close(portForwardFuncDone)
 
 			if e, a := expectedPodName, name; e != a {
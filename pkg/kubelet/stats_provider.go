@@ -0,0 +1,113 @@
+/*
+Copyright 2015 Google Inc. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package kubelet
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/GoogleCloudPlatform/kubernetes/pkg/kubelet/cadvisor"
+	cadvisorApi "github.com/google/cadvisor/info/v1"
+)
+
+// statsCacheTTL is how long a cadvisor response is served from the cache
+// before a fresh query is issued.
+const statsCacheTTL = 2 * time.Second
+
+// statsCacheEntry is one cached cadvisor response. done is closed once the
+// fetch completes, so concurrent identical requests can wait for the result
+// of the request already in flight instead of querying cadvisor again.
+type statsCacheEntry struct {
+	info    cadvisorApi.ContainerInfo
+	err     error
+	expires time.Time
+	done    chan struct{}
+}
+
+// statsProvider serves cadvisor container stats out of a short-lived cache
+// and coalesces concurrent identical requests, so that heavy scraping of the
+// stats endpoints cannot overload cadvisor.
+type statsProvider struct {
+	cadvisor cadvisor.Interface
+	ttl      time.Duration
+
+	lock    sync.Mutex
+	entries map[string]*statsCacheEntry
+}
+
+func newStatsProvider(cadvisorInterface cadvisor.Interface, ttl time.Duration) *statsProvider {
+	return &statsProvider{
+		cadvisor: cadvisorInterface,
+		ttl:      ttl,
+		entries:  map[string]*statsCacheEntry{},
+	}
+}
+
+// DockerContainer is a caching wrapper around cadvisor.DockerContainer.
+func (p *statsProvider) DockerContainer(name string, req *cadvisorApi.ContainerInfoRequest) (cadvisorApi.ContainerInfo, error) {
+	return p.get("docker/"+name, req, func() (cadvisorApi.ContainerInfo, error) {
+		return p.cadvisor.DockerContainer(name, req)
+	})
+}
+
+// ContainerInfo is a caching wrapper around cadvisor.ContainerInfo.
+func (p *statsProvider) ContainerInfo(name string, req *cadvisorApi.ContainerInfoRequest) (*cadvisorApi.ContainerInfo, error) {
+	info, err := p.get("raw/"+name, req, func() (cadvisorApi.ContainerInfo, error) {
+		info, err := p.cadvisor.ContainerInfo(name, req)
+		if err != nil {
+			return cadvisorApi.ContainerInfo{}, err
+		}
+		return *info, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &info, nil
+}
+
+// get returns the cached response for the given request, waiting for an
+// identical request already in flight if there is one, and queries cadvisor
+// through fetch otherwise.
+func (p *statsProvider) get(name string, req *cadvisorApi.ContainerInfoRequest, fetch func() (cadvisorApi.ContainerInfo, error)) (cadvisorApi.ContainerInfo, error) {
+	key := fmt.Sprintf("%s|%+v", name, req)
+	p.lock.Lock()
+	entry, found := p.entries[key]
+	if found {
+		select {
+		case <-entry.done:
+			// The fetch completed; serve it while it is fresh.
+			if time.Now().Before(entry.expires) {
+				p.lock.Unlock()
+				return entry.info, entry.err
+			}
+		default:
+			// A fetch for the same request is in flight; wait for it.
+			p.lock.Unlock()
+			<-entry.done
+			return entry.info, entry.err
+		}
+	}
+	entry = &statsCacheEntry{done: make(chan struct{})}
+	p.entries[key] = entry
+	p.lock.Unlock()
+
+	entry.info, entry.err = fetch()
+	entry.expires = time.Now().Add(p.ttl)
+	close(entry.done)
+	return entry.info, entry.err
+}
@@ -0,0 +1,92 @@
+/*
+Copyright 2015 Google Inc. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package kubelet
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/GoogleCloudPlatform/kubernetes/pkg/kubelet/cadvisor"
+	cadvisorApi "github.com/google/cadvisor/info/v1"
+)
+
+func TestStatsProviderCachesFreshResponses(t *testing.T) {
+	mockCadvisor := &cadvisor.Mock{}
+	containerInfo := cadvisorApi.ContainerInfo{
+		ContainerReference: cadvisorApi.ContainerReference{Name: "/docker/ab2cdf"},
+	}
+	req := &cadvisorApi.ContainerInfoRequest{NumStats: 2}
+	mockCadvisor.On("DockerContainer", "ab2cdf", req).Return(containerInfo, nil)
+
+	provider := newStatsProvider(mockCadvisor, time.Minute)
+	for i := 0; i < 3; i++ {
+		info, err := provider.DockerContainer("ab2cdf", req)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if info.Name != containerInfo.Name {
+			t.Errorf("unexpected container info: %+v", info)
+		}
+	}
+	// All but the first request must be served from the cache.
+	mockCadvisor.AssertNumberOfCalls(t, "DockerContainer", 1)
+
+	// A different request must not be served from the same entry.
+	otherReq := &cadvisorApi.ContainerInfoRequest{NumStats: 5}
+	mockCadvisor.On("DockerContainer", "ab2cdf", otherReq).Return(containerInfo, nil)
+	if _, err := provider.DockerContainer("ab2cdf", otherReq); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	mockCadvisor.AssertNumberOfCalls(t, "DockerContainer", 2)
+}
+
+func TestStatsProviderCoalescesConcurrentRequests(t *testing.T) {
+	provider := newStatsProvider(nil, time.Minute)
+
+	fetches := 0
+	fetching := make(chan struct{})
+	proceed := make(chan struct{})
+	fetch := func() (cadvisorApi.ContainerInfo, error) {
+		fetches++
+		close(fetching)
+		<-proceed
+		return cadvisorApi.ContainerInfo{}, nil
+	}
+
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		provider.get("docker/ab2cdf", &cadvisorApi.ContainerInfoRequest{}, fetch)
+	}()
+	<-fetching
+
+	// A second identical request must wait for the one in flight instead of
+	// fetching again.
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		provider.get("docker/ab2cdf", &cadvisorApi.ContainerInfoRequest{}, fetch)
+	}()
+	close(proceed)
+	wg.Wait()
+
+	if fetches != 1 {
+		t.Errorf("expected 1 fetch, got %d", fetches)
+	}
+}
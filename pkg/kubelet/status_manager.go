@@ -20,86 +20,150 @@ import (
 	"fmt"
 	"reflect"
 	"sync"
+	"time"
 
 	"github.com/GoogleCloudPlatform/kubernetes/pkg/api"
+	apierrors "github.com/GoogleCloudPlatform/kubernetes/pkg/api/errors"
 	"github.com/GoogleCloudPlatform/kubernetes/pkg/client"
 	kubecontainer "github.com/GoogleCloudPlatform/kubernetes/pkg/kubelet/container"
+	"github.com/GoogleCloudPlatform/kubernetes/pkg/types"
 	"github.com/GoogleCloudPlatform/kubernetes/pkg/util"
 	"github.com/golang/glog"
 )
 
+const (
+	// Backoff used by the sync worker after a failed status update.
+	statusUpdateRetryBackoffInitial = 500 * time.Millisecond
+	statusUpdateRetryBackoffMax     = 10 * time.Second
+)
+
+// versionedPodStatus is a pod status together with a monotonically increasing
+// per-pod version number, used to drop stale and duplicate updates.
+type versionedPodStatus struct {
+	status  api.PodStatus
+	version uint64
+}
+
 type podStatusSyncRequest struct {
 	pod    *api.Pod
-	status api.PodStatus
+	status versionedPodStatus
 }
 
 // Updates pod statuses in apiserver. Writes only when new status has changed.
 // All methods are thread-safe.
 type statusManager struct {
 	kubeClient client.Interface
-	// Map from pod full name to sync status of the corresponding pod.
+	// Map from pod UID to sync status of the corresponding pod. Statuses are
+	// keyed strictly by UID so that a pod deleted and recreated under the
+	// same name never inherits its predecessor's status.
 	podStatusesLock  sync.RWMutex
-	podStatuses      map[string]api.PodStatus
+	podStatuses      map[types.UID]versionedPodStatus
 	podStatusChannel chan podStatusSyncRequest
+	// Map from pod UID to the version of the status most recently
+	// written to the apiserver.
+	apiStatusVersions map[types.UID]uint64
+	// canBeDeleted, when set, reports whether a terminating pod's containers
+	// are dead and its volumes have been unmounted, so the final delete can
+	// be sent to the apiserver.
+	canBeDeleted func(pod *api.Pod, status api.PodStatus) bool
 }
 
 func newStatusManager(kubeClient client.Interface) *statusManager {
 	return &statusManager{
-		kubeClient:       kubeClient,
-		podStatuses:      make(map[string]api.PodStatus),
-		podStatusChannel: make(chan podStatusSyncRequest, 1000), // Buffer up to 1000 statuses
+		kubeClient:        kubeClient,
+		podStatuses:       make(map[types.UID]versionedPodStatus),
+		podStatusChannel:  make(chan podStatusSyncRequest, 1000), // Buffer up to 1000 statuses
+		apiStatusVersions: make(map[types.UID]uint64),
 	}
 }
 
 func (s *statusManager) Start() {
+	// Don't start the status syncing loop if we don't have a client.
+	if s.kubeClient == nil {
+		glog.Infof("Kubernetes client is nil, not starting status manager.")
+		return
+	}
 	// syncBatch blocks when no updates are available, we can run it in a tight loop.
 	glog.Info("Starting to sync pod status with apiserver")
+	backoff := statusUpdateRetryBackoffInitial
 	go util.Forever(func() {
 		err := s.syncBatch()
 		if err != nil {
 			glog.Warningf("Failed to updated pod status: %v", err)
+			// Back off before retrying so a broken apiserver is not hammered.
+			time.Sleep(backoff)
+			backoff *= 2
+			if backoff > statusUpdateRetryBackoffMax {
+				backoff = statusUpdateRetryBackoffMax
+			}
+		} else {
+			backoff = statusUpdateRetryBackoffInitial
 		}
 	}, 0)
 }
 
-func (s *statusManager) GetPodStatus(podFullName string) (api.PodStatus, bool) {
+func (s *statusManager) GetPodStatus(uid types.UID) (api.PodStatus, bool) {
 	s.podStatusesLock.RLock()
 	defer s.podStatusesLock.RUnlock()
-	status, ok := s.podStatuses[podFullName]
-	return status, ok
+	status, ok := s.podStatuses[uid]
+	return status.status, ok
 }
 
 func (s *statusManager) SetPodStatus(pod *api.Pod, status api.PodStatus) {
-	podFullName := kubecontainer.GetPodFullName(pod)
 	s.podStatusesLock.Lock()
 	defer s.podStatusesLock.Unlock()
-	oldStatus, found := s.podStatuses[podFullName]
-	if !found || !reflect.DeepEqual(oldStatus, status) {
-		s.podStatuses[podFullName] = status
-		s.podStatusChannel <- podStatusSyncRequest{pod, status}
-	} else {
-		glog.V(3).Infof("Ignoring same pod status for %s - old: %s new: %s", podFullName, oldStatus, status)
+	oldStatus, found := s.podStatuses[pod.UID]
+	if found && reflect.DeepEqual(oldStatus.status, status) {
+		glog.V(3).Infof("Ignoring same pod status for %s - old: %+v new: %+v", kubecontainer.GetPodFullName(pod), oldStatus.status, status)
+		return
+	}
+	newStatus := versionedPodStatus{status: status, version: oldStatus.version + 1}
+	s.podStatuses[pod.UID] = newStatus
+	if s.kubeClient == nil {
+		// Without a master the status is only kept in the local cache.
+		return
 	}
+	s.podStatusChannel <- podStatusSyncRequest{pod, newStatus}
 }
 
-func (s *statusManager) DeletePodStatus(podFullName string) {
+func (s *statusManager) DeletePodStatus(uid types.UID) {
 	s.podStatusesLock.Lock()
 	defer s.podStatusesLock.Unlock()
-	delete(s.podStatuses, podFullName)
+	delete(s.podStatuses, uid)
+	delete(s.apiStatusVersions, uid)
 }
 
 // TODO(filipg): It'd be cleaner if we can do this without signal from user.
-func (s *statusManager) RemoveOrphanedStatuses(podFullNames map[string]bool) {
+func (s *statusManager) RemoveOrphanedStatuses(podUIDs map[types.UID]bool) {
 	s.podStatusesLock.Lock()
 	defer s.podStatusesLock.Unlock()
 	for key := range s.podStatuses {
-		if _, ok := podFullNames[key]; !ok {
+		if _, ok := podUIDs[key]; !ok {
 			glog.V(5).Infof("Removing %q from status map.", key)
 			delete(s.podStatuses, key)
+			delete(s.apiStatusVersions, key)
 		}
 	}
 }
 
+// needsUpdate returns whether the given status version still needs to be sent
+// to the apiserver, i.e. it has not been superseded by a newer local version
+// and has not already been written successfully.
+func (s *statusManager) needsUpdate(uid types.UID, version uint64) bool {
+	s.podStatusesLock.RLock()
+	defer s.podStatusesLock.RUnlock()
+	if latest, found := s.podStatuses[uid]; found && latest.version > version {
+		return false
+	}
+	return s.apiStatusVersions[uid] < version
+}
+
+func (s *statusManager) markUpdated(uid types.UID, version uint64) {
+	s.podStatusesLock.Lock()
+	defer s.podStatusesLock.Unlock()
+	s.apiStatusVersions[uid] = version
+}
+
 // syncBatch syncs pods statuses with the apiserver.
 func (s *statusManager) syncBatch() error {
 	syncRequest := <-s.podStatusChannel
@@ -107,25 +171,60 @@ func (s *statusManager) syncBatch() error {
 	podFullName := kubecontainer.GetPodFullName(pod)
 	status := syncRequest.status
 
+	// Coalesce rapid successive updates: if a newer version is already queued
+	// behind this one, or this version has already been written, skip it.
+	if !s.needsUpdate(pod.UID, status.version) {
+		glog.V(4).Infof("Skipping stale status update for pod %q (version %d)", podFullName, status.version)
+		return nil
+	}
+
 	var err error
 	statusPod := &api.Pod{
 		ObjectMeta: pod.ObjectMeta,
 	}
 	// TODO: make me easier to express from client code
 	statusPod, err = s.kubeClient.Pods(statusPod.Namespace).Get(statusPod.Name)
+	if apierrors.IsNotFound(err) {
+		// The pod is gone from the apiserver, so retrying can never
+		// succeed; drop the cached status instead of requeueing. If the
+		// pod reappears, the next SetPodStatus queues a fresh update.
+		glog.V(3).Infof("Pod %q was deleted from the apiserver; dropping its cached status", podFullName)
+		s.DeletePodStatus(pod.UID)
+		return nil
+	}
 	if err == nil {
-		statusPod.Status = status
+		statusPod.Status = status.status
 		_, err = s.kubeClient.Pods(pod.Namespace).UpdateStatus(statusPod)
 		// TODO: handle conflict as a retry, make that easier too.
 		if err == nil {
 			glog.V(3).Infof("Status for pod %q updated successfully", pod.Name)
-			return nil
+			s.markUpdated(pod.UID, status.version)
+			if pod.DeletionTimestamp == nil || s.canBeDeleted == nil || !s.canBeDeleted(pod, status.status) {
+				return nil
+			}
+			// The pod is terminating and all of its resources have been
+			// reclaimed; send the final delete so it does not linger in
+			// the Terminating state.
+			// TODO: use a grace period of 0 once the client supports
+			// DeleteOptions.
+			if err = s.kubeClient.Pods(pod.Namespace).Delete(pod.Name); err == nil || apierrors.IsNotFound(err) {
+				// NotFound means someone else already deleted the pod,
+				// which is just as final.
+				glog.V(3).Infof("Pod %q fully terminated and removed from etcd", pod.Name)
+				s.DeletePodStatus(pod.UID)
+				return nil
+			}
 		}
 	}
 
-	// We failed to update status. In order to make sure we retry next time
-	// we delete cached value. This may result in an additional update, but
-	// this is ok.
-	s.DeletePodStatus(podFullName)
+	// Requeue the failed update so the worker retries it after backing off.
+	select {
+	case s.podStatusChannel <- syncRequest:
+	default:
+		// The channel is full. Drop the cached value instead so the status
+		// is re-sent on the next SetPodStatus. This may result in an
+		// additional update, but this is ok.
+		s.DeletePodStatus(pod.UID)
+	}
 	return fmt.Errorf("error updating status for pod %q: %v", pod.Name, err)
 }
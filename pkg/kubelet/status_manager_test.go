@@ -17,13 +17,17 @@ limitations under the License.
 package kubelet
 
 import (
+	"fmt"
 	"math/rand"
+	"reflect"
 	"strconv"
 	"testing"
 
 	"github.com/GoogleCloudPlatform/kubernetes/pkg/api"
+	apierrors "github.com/GoogleCloudPlatform/kubernetes/pkg/api/errors"
 	"github.com/GoogleCloudPlatform/kubernetes/pkg/client"
 	"github.com/GoogleCloudPlatform/kubernetes/pkg/client/testclient"
+	"github.com/GoogleCloudPlatform/kubernetes/pkg/util"
 )
 
 var testPod *api.Pod = &api.Pod{
@@ -104,6 +108,74 @@ func TestUnchangedStatus(t *testing.T) {
 	verifyUpdates(t, syncer, 1)
 }
 
+func TestStatusNotInheritedAcrossRecreation(t *testing.T) {
+	// A pod deleted and recreated under the same name has a new UID and must
+	// not see the old incarnation's status.
+	syncer := newTestStatusManager()
+	syncer.SetPodStatus(testPod, getRandomPodStatus())
+	recreated := *testPod
+	recreated.UID = "87654321"
+	if _, found := syncer.GetPodStatus(recreated.UID); found {
+		t.Errorf("expected no status for the recreated pod %q", recreated.Name)
+	}
+	if _, found := syncer.GetPodStatus(testPod.UID); !found {
+		t.Errorf("expected the old incarnation's status to remain")
+	}
+}
+
+func TestCoalescedStatus(t *testing.T) {
+	syncer := newTestStatusManager()
+	syncer.SetPodStatus(testPod, getRandomPodStatus())
+	syncer.SetPodStatus(testPod, getRandomPodStatus())
+	// Both updates are queued, but only the latest version should hit the
+	// apiserver; the stale one is skipped.
+	for i := 0; i < 2; i++ {
+		if err := syncer.syncBatch(); err != nil {
+			t.Errorf("unexpected syncing error: %v", err)
+		}
+	}
+	verifyActions(t, syncer.kubeClient, []string{"get-pod", "update-status-pod"})
+}
+
+func TestUnchangedStatusAfterSync(t *testing.T) {
+	syncer := newTestStatusManager()
+	podStatus := getRandomPodStatus()
+	syncer.SetPodStatus(testPod, podStatus)
+	if err := syncer.syncBatch(); err != nil {
+		t.Errorf("unexpected syncing error: %v", err)
+	}
+	// Re-setting the same status after a successful sync must not queue
+	// another update.
+	syncer.SetPodStatus(testPod, podStatus)
+	verifyUpdates(t, syncer, 0)
+}
+
+func TestFailedStatusUpdateRequeued(t *testing.T) {
+	syncer := newTestStatusManager()
+	syncer.kubeClient = &testclient.Fake{Err: fmt.Errorf("intentional test error")}
+	syncer.SetPodStatus(testPod, getRandomPodStatus())
+	if err := syncer.syncBatch(); err == nil {
+		t.Errorf("expected syncing error")
+	}
+	// The failed update should be requeued for a retry.
+	verifyUpdates(t, syncer, 1)
+}
+
+func TestStatusOfDeletedPodNotRequeued(t *testing.T) {
+	syncer := newTestStatusManager()
+	syncer.kubeClient = &testclient.Fake{Err: apierrors.NewNotFound("pod", testPod.Name)}
+	syncer.SetPodStatus(testPod, getRandomPodStatus())
+	if err := syncer.syncBatch(); err != nil {
+		t.Errorf("unexpected syncing error: %v", err)
+	}
+	// The pod is gone from the apiserver; retrying can never succeed, so
+	// the update must be dropped rather than requeued.
+	verifyUpdates(t, syncer, 0)
+	if _, found := syncer.GetPodStatus(testPod.UID); found {
+		t.Errorf("expected the status of the deleted pod to be dropped")
+	}
+}
+
 func TestSyncBatch(t *testing.T) {
 	syncer := newTestStatusManager()
 	syncer.SetPodStatus(testPod, getRandomPodStatus())
@@ -113,3 +185,46 @@ func TestSyncBatch(t *testing.T) {
 	}
 	verifyActions(t, syncer.kubeClient, []string{"get-pod", "update-status-pod"})
 }
+
+func TestTerminatedPodIsDeleted(t *testing.T) {
+	syncer := newTestStatusManager()
+	syncer.canBeDeleted = func(pod *api.Pod, status api.PodStatus) bool { return true }
+	now := util.Now()
+	pod := *testPod
+	pod.DeletionTimestamp = &now
+	syncer.SetPodStatus(&pod, getRandomPodStatus())
+	if err := syncer.syncBatch(); err != nil {
+		t.Errorf("unexpected syncing error: %v", err)
+	}
+	verifyActions(t, syncer.kubeClient, []string{"get-pod", "update-status-pod", "delete-pod"})
+}
+
+func TestTerminatedPodNotDeletedWhileResourcesRemain(t *testing.T) {
+	syncer := newTestStatusManager()
+	syncer.canBeDeleted = func(pod *api.Pod, status api.PodStatus) bool { return false }
+	now := util.Now()
+	pod := *testPod
+	pod.DeletionTimestamp = &now
+	syncer.SetPodStatus(&pod, getRandomPodStatus())
+	if err := syncer.syncBatch(); err != nil {
+		t.Errorf("unexpected syncing error: %v", err)
+	}
+	verifyActions(t, syncer.kubeClient, []string{"get-pod", "update-status-pod"})
+}
+
+func TestSetPodStatusWithoutClient(t *testing.T) {
+	// Without an apiserver client the status is cached locally but never
+	// queued for syncing.
+	syncer := newStatusManager(nil)
+	syncer.Start()
+	status := getRandomPodStatus()
+	syncer.SetPodStatus(testPod, status)
+	verifyUpdates(t, syncer, 0)
+	cached, found := syncer.GetPodStatus(testPod.UID)
+	if !found {
+		t.Errorf("expected status for pod %q to be cached", testPod.Name)
+	}
+	if !reflect.DeepEqual(cached, status) {
+		t.Errorf("expected status %#v, got %#v", status, cached)
+	}
+}
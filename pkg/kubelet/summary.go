@@ -0,0 +1,190 @@
+/*
+Copyright 2015 Google Inc. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package kubelet
+
+import (
+	"time"
+
+	kubecontainer "github.com/GoogleCloudPlatform/kubernetes/pkg/kubelet/container"
+	"github.com/GoogleCloudPlatform/kubernetes/pkg/kubelet/dockertools"
+	cadvisorApi "github.com/google/cadvisor/info/v1"
+)
+
+// StatsSummary is served on /stats/summary and aggregates node, pod and
+// container usage into a single stable schema, so monitoring agents do not
+// need to issue a cadvisor query per container.
+type StatsSummary struct {
+	// Stats for the node itself (the root cgroup).
+	Node NodeStats `json:"node"`
+	// Stats for each pod known to this Kubelet.
+	Pods []PodStats `json:"pods"`
+}
+
+// NodeStats holds node-level resource usage.
+type NodeStats struct {
+	NodeName string       `json:"nodeName"`
+	CPU      CPUStats     `json:"cpu"`
+	Memory   MemoryStats  `json:"memory"`
+	Network  NetworkStats `json:"network"`
+	Fs       FsStats      `json:"fs"`
+}
+
+// PodReference identifies the pod a set of stats belongs to.
+type PodReference struct {
+	Name      string `json:"name"`
+	Namespace string `json:"namespace"`
+	UID       string `json:"uid"`
+}
+
+// PodStats holds pod-level resource usage.
+type PodStats struct {
+	PodRef PodReference `json:"podRef"`
+	// Stats for each running container in the pod.
+	Containers []ContainerStats `json:"containers"`
+	// Network stats for the pod, taken from the container owning the pod's
+	// network namespace.
+	Network NetworkStats `json:"network"`
+}
+
+// ContainerStats holds container-level resource usage.
+type ContainerStats struct {
+	Name   string      `json:"name"`
+	CPU    CPUStats    `json:"cpu"`
+	Memory MemoryStats `json:"memory"`
+	Fs     FsStats     `json:"fs"`
+}
+
+// CPUStats holds CPU usage at the latest sample point.
+type CPUStats struct {
+	Time time.Time `json:"time"`
+	// Usage rate in nanocores (nanoseconds of CPU time per second), derived
+	// from the two most recent samples. Zero if fewer than two samples exist.
+	UsageNanoCores uint64 `json:"usageNanoCores"`
+	// Cumulative CPU usage in nanoseconds.
+	UsageCoreNanoSeconds uint64 `json:"usageCoreNanoSeconds"`
+}
+
+// MemoryStats holds memory usage at the latest sample point.
+type MemoryStats struct {
+	Time       time.Time `json:"time"`
+	UsageBytes uint64    `json:"usageBytes"`
+	// The amount of working set memory; this is <= UsageBytes.
+	WorkingSetBytes uint64 `json:"workingSetBytes"`
+}
+
+// NetworkStats holds cumulative network counters at the latest sample point.
+type NetworkStats struct {
+	Time     time.Time `json:"time"`
+	RxBytes  uint64    `json:"rxBytes"`
+	RxErrors uint64    `json:"rxErrors"`
+	TxBytes  uint64    `json:"txBytes"`
+	TxErrors uint64    `json:"txErrors"`
+}
+
+// FsStats holds filesystem usage, summed over the devices visible to the
+// container, at the latest sample point.
+type FsStats struct {
+	CapacityBytes uint64 `json:"capacityBytes"`
+	UsedBytes     uint64 `json:"usedBytes"`
+}
+
+// buildStatsSummary assembles a StatsSummary from cadvisor data.
+func (s *Server) buildStatsSummary() (*StatsSummary, error) {
+	query := &cadvisorApi.ContainerInfoRequest{NumStats: 2}
+	summary := &StatsSummary{
+		Node: NodeStats{NodeName: s.host.GetHostname()},
+	}
+
+	rootInfo, err := s.host.GetRawContainerInfo("/", query, false)
+	if err != nil {
+		return nil, err
+	}
+	if info, ok := rootInfo["/"]; ok {
+		summary.Node.CPU, summary.Node.Memory, summary.Node.Network, summary.Node.Fs = latestUsage(info)
+	}
+
+	for _, pod := range s.host.GetPods() {
+		podFullName := kubecontainer.GetPodFullName(pod)
+		podStats := PodStats{
+			PodRef: PodReference{
+				Name:      pod.Name,
+				Namespace: pod.Namespace,
+				UID:       string(pod.UID),
+			},
+		}
+		for _, container := range pod.Spec.Containers {
+			info, err := s.host.GetContainerInfo(podFullName, pod.UID, container.Name, query)
+			if err != nil {
+				// The container may not be running yet, or may have been
+				// removed; report what we have for the rest of the pod.
+				continue
+			}
+			cpu, memory, _, fs := latestUsage(info)
+			podStats.Containers = append(podStats.Containers, ContainerStats{
+				Name:   container.Name,
+				CPU:    cpu,
+				Memory: memory,
+				Fs:     fs,
+			})
+		}
+		// The pod's network namespace is owned by the infra container.
+		if info, err := s.host.GetContainerInfo(podFullName, pod.UID, dockertools.PodInfraContainerName, query); err == nil {
+			_, _, network, _ := latestUsage(info)
+			podStats.Network = network
+		}
+		summary.Pods = append(summary.Pods, podStats)
+	}
+	return summary, nil
+}
+
+// latestUsage derives the summary stats from the most recent cadvisor samples
+// of the given container.
+func latestUsage(info *cadvisorApi.ContainerInfo) (CPUStats, MemoryStats, NetworkStats, FsStats) {
+	var cpu CPUStats
+	var memory MemoryStats
+	var network NetworkStats
+	var fs FsStats
+	if len(info.Stats) == 0 {
+		return cpu, memory, network, fs
+	}
+	last := info.Stats[len(info.Stats)-1]
+
+	cpu.Time = last.Timestamp
+	cpu.UsageCoreNanoSeconds = last.Cpu.Usage.Total
+	if len(info.Stats) > 1 {
+		prev := info.Stats[len(info.Stats)-2]
+		if interval := last.Timestamp.Sub(prev.Timestamp); interval > 0 && last.Cpu.Usage.Total >= prev.Cpu.Usage.Total {
+			cpu.UsageNanoCores = uint64(float64(last.Cpu.Usage.Total-prev.Cpu.Usage.Total) / interval.Seconds())
+		}
+	}
+
+	memory.Time = last.Timestamp
+	memory.UsageBytes = last.Memory.Usage
+	memory.WorkingSetBytes = last.Memory.WorkingSet
+
+	network.Time = last.Timestamp
+	network.RxBytes = last.Network.RxBytes
+	network.RxErrors = last.Network.RxErrors
+	network.TxBytes = last.Network.TxBytes
+	network.TxErrors = last.Network.TxErrors
+
+	for _, fsStats := range last.Filesystem {
+		fs.CapacityBytes += fsStats.Limit
+		fs.UsedBytes += fsStats.Usage
+	}
+	return cpu, memory, network, fs
+}
@@ -0,0 +1,120 @@
+/*
+Copyright 2015 Google Inc. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package kubelet
+
+import (
+	"time"
+
+	kubecontainer "github.com/GoogleCloudPlatform/kubernetes/pkg/kubelet/container"
+	cadvisorApi "github.com/google/cadvisor/info/v1"
+)
+
+// The number of recent cadvisor samples reported per container on
+// /stats/usage. At cadvisor's default sampling interval of one second this
+// covers roughly the last half minute.
+const usageReportSamples = 30
+
+// UsageReport is served on /stats/usage and reports a short window of recent
+// CPU and memory usage samples for every container on the node. Unlike
+// /stats/summary, which reduces usage to the latest sample point, the window
+// lets a consumer such as a vertical pod autoscaler see how usage varies
+// rather than a single instant.
+type UsageReport struct {
+	// Time the report was assembled.
+	Timestamp time.Time `json:"timestamp"`
+	// Usage for each pod known to this Kubelet.
+	Pods []PodUsage `json:"pods"`
+}
+
+// PodUsage holds the usage samples of a single pod's containers.
+type PodUsage struct {
+	PodRef PodReference `json:"podRef"`
+	// Usage for each running container in the pod.
+	Containers []ContainerUsage `json:"containers"`
+}
+
+// ContainerUsage holds the usage samples of a single container, oldest first.
+type ContainerUsage struct {
+	Name    string        `json:"name"`
+	Samples []UsageSample `json:"samples"`
+}
+
+// UsageSample is one observation of a container's CPU and memory usage.
+type UsageSample struct {
+	Time time.Time `json:"time"`
+	// Usage rate in nanocores (nanoseconds of CPU time per second), derived
+	// from the previous sample. Zero for the oldest sample in the window.
+	CPUUsageNanoCores uint64 `json:"cpuUsageNanoCores"`
+	// Cumulative CPU usage in nanoseconds.
+	CPUUsageCoreNanoSeconds uint64 `json:"cpuUsageCoreNanoSeconds"`
+	MemoryUsageBytes        uint64 `json:"memoryUsageBytes"`
+	// The amount of working set memory; this is <= MemoryUsageBytes.
+	MemoryWorkingSetBytes uint64 `json:"memoryWorkingSetBytes"`
+}
+
+// buildUsageReport assembles a UsageReport from cadvisor data.
+func (s *Server) buildUsageReport() (*UsageReport, error) {
+	query := &cadvisorApi.ContainerInfoRequest{NumStats: usageReportSamples}
+	report := &UsageReport{Timestamp: time.Now()}
+
+	for _, pod := range s.host.GetPods() {
+		podFullName := kubecontainer.GetPodFullName(pod)
+		podUsage := PodUsage{
+			PodRef: PodReference{
+				Name:      pod.Name,
+				Namespace: pod.Namespace,
+				UID:       string(pod.UID),
+			},
+		}
+		for _, container := range pod.Spec.Containers {
+			info, err := s.host.GetContainerInfo(podFullName, pod.UID, container.Name, query)
+			if err != nil {
+				// The container may not be running yet, or may have been
+				// removed; report what we have for the rest of the pod.
+				continue
+			}
+			podUsage.Containers = append(podUsage.Containers, ContainerUsage{
+				Name:    container.Name,
+				Samples: usageSamples(info),
+			})
+		}
+		report.Pods = append(report.Pods, podUsage)
+	}
+	return report, nil
+}
+
+// usageSamples converts cadvisor samples into usage samples, deriving the CPU
+// usage rate from each pair of adjacent samples.
+func usageSamples(info *cadvisorApi.ContainerInfo) []UsageSample {
+	samples := make([]UsageSample, 0, len(info.Stats))
+	for i, stats := range info.Stats {
+		sample := UsageSample{
+			Time:                    stats.Timestamp,
+			CPUUsageCoreNanoSeconds: stats.Cpu.Usage.Total,
+			MemoryUsageBytes:        stats.Memory.Usage,
+			MemoryWorkingSetBytes:   stats.Memory.WorkingSet,
+		}
+		if i > 0 {
+			prev := info.Stats[i-1]
+			if interval := stats.Timestamp.Sub(prev.Timestamp); interval > 0 && stats.Cpu.Usage.Total >= prev.Cpu.Usage.Total {
+				sample.CPUUsageNanoCores = uint64(float64(stats.Cpu.Usage.Total-prev.Cpu.Usage.Total) / interval.Seconds())
+			}
+		}
+		samples = append(samples, sample)
+	}
+	return samples
+}
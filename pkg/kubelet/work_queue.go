@@ -0,0 +1,70 @@
+/*
+Copyright 2015 Google Inc. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package kubelet
+
+import (
+	"sync"
+	"time"
+
+	"github.com/GoogleCloudPlatform/kubernetes/pkg/types"
+)
+
+// workQueue tracks pods that are waiting to be re-synced, each with the time
+// at which it becomes eligible.
+type workQueue struct {
+	lock sync.Mutex
+	// now is overridden in tests.
+	now   func() time.Time
+	queue map[types.UID]time.Time
+}
+
+func newWorkQueue() *workQueue {
+	return &workQueue{
+		now:   time.Now,
+		queue: make(map[types.UID]time.Time),
+	}
+}
+
+// Enqueue inserts a new item or delays an existing one; the item becomes
+// eligible after the given delay.
+func (q *workQueue) Enqueue(uid types.UID, delay time.Duration) {
+	q.lock.Lock()
+	defer q.lock.Unlock()
+	q.queue[uid] = q.now().Add(delay)
+}
+
+// Forget removes an item from the queue if present.
+func (q *workQueue) Forget(uid types.UID) {
+	q.lock.Lock()
+	defer q.lock.Unlock()
+	delete(q.queue, uid)
+}
+
+// GetWork removes and returns all items that have become eligible.
+func (q *workQueue) GetWork() []types.UID {
+	q.lock.Lock()
+	defer q.lock.Unlock()
+	now := q.now()
+	var items []types.UID
+	for uid, eligible := range q.queue {
+		if !eligible.After(now) {
+			items = append(items, uid)
+			delete(q.queue, uid)
+		}
+	}
+	return items
+}
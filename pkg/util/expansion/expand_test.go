@@ -0,0 +1,140 @@
+/*
+Copyright 2015 Google Inc. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package expansion
+
+import (
+	"testing"
+)
+
+func TestMapReference(t *testing.T) {
+	envs := map[string]string{
+		"FOO":  "bar",
+		"ZOO":  "zonk",
+		"BLU":  "blue",
+		"DATA": "",
+	}
+
+	mapping := MappingFuncFor(envs)
+
+	cases := []struct {
+		name     string
+		input    string
+		expected string
+	}{
+		{
+			name:     "whole string",
+			input:    "$(FOO)",
+			expected: "bar",
+		},
+		{
+			name:     "repeat",
+			input:    "$(FOO)-$(FOO)",
+			expected: "bar-bar",
+		},
+		{
+			name:     "multiple repeats",
+			input:    "$(FOO)-$(ZOO)-$(BLU)-$(ZOO)",
+			expected: "bar-zonk-blue-zonk",
+		},
+		{
+			name:     "mapped to empty string",
+			input:    "a$(DATA)b",
+			expected: "ab",
+		},
+		{
+			name:     "beginning",
+			input:    "$(FOO)-1",
+			expected: "bar-1",
+		},
+		{
+			name:     "middle",
+			input:    "1-$(FOO)-2",
+			expected: "1-bar-2",
+		},
+		{
+			name:     "end",
+			input:    "1-$(FOO)",
+			expected: "1-bar",
+		},
+		{
+			name:     "unmapped reference is untouched",
+			input:    "$(WHALES)",
+			expected: "$(WHALES)",
+		},
+		{
+			name:     "escaped operator",
+			input:    "$$(VAR_NAME)",
+			expected: "$(VAR_NAME)",
+		},
+		{
+			name:     "double escape",
+			input:    "$$$$(BIG_MONEY)",
+			expected: "$$(BIG_MONEY)",
+		},
+		{
+			name:     "escaped followed by reference",
+			input:    "$$(FOO)$(FOO)",
+			expected: "$(FOO)bar",
+		},
+		{
+			name:     "operator not starting a reference",
+			input:    "foo-$bar",
+			expected: "foo-$bar",
+		},
+		{
+			name:     "trailing operator",
+			input:    "foo$",
+			expected: "foo$",
+		},
+		{
+			name:     "incomplete reference",
+			input:    "foo-$(FOO",
+			expected: "foo-$(FOO",
+		},
+		{
+			name:     "empty reference",
+			input:    "foo-$()-bar",
+			expected: "foo-$()-bar",
+		},
+		{
+			name:     "no references",
+			input:    "plain text",
+			expected: "plain text",
+		},
+	}
+
+	for _, tc := range cases {
+		expanded := Expand(tc.input, mapping)
+		if e, a := tc.expected, expanded; e != a {
+			t.Errorf("%v: expected %q, got %q", tc.name, e, a)
+		}
+	}
+}
+
+func TestMappingPrecedence(t *testing.T) {
+	mapping := MappingFuncFor(
+		map[string]string{"DUPE": "first"},
+		map[string]string{"DUPE": "second", "ONLY": "second"},
+	)
+
+	if e, a := "first", Expand("$(DUPE)", mapping); e != a {
+		t.Errorf("expected the first context to win: expected %q, got %q", e, a)
+	}
+	if e, a := "second", Expand("$(ONLY)", mapping); e != a {
+		t.Errorf("expected fallthrough to the second context: expected %q, got %q", e, a)
+	}
+}
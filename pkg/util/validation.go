@@ -86,6 +86,17 @@ func IsCIdentifier(value string) bool {
 	return cIdentifierRegexp.MatchString(value)
 }
 
+const SysctlFmt string = "[a-z0-9]([-_a-z0-9]*[a-z0-9])?(\\.[a-z0-9]([-_a-z0-9]*[a-z0-9])?)*"
+const SysctlMaxLength int = 253
+
+var sysctlRegexp = regexp.MustCompile("^" + SysctlFmt + "$")
+
+// IsValidSysctlName tests for a string that conforms to the dot-separated
+// format of sysctl names, e.g. net.ipv4.tcp_syncookies.
+func IsValidSysctlName(value string) bool {
+	return len(value) <= SysctlMaxLength && sysctlRegexp.MatchString(value)
+}
+
 // IsValidPortNum tests that the argument is a valid, non-zero port number.
 func IsValidPortNum(port int) bool {
 	return 0 < port && port < 65536
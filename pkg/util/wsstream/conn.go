@@ -0,0 +1,306 @@
+/*
+Copyright 2015 Google Inc. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package wsstream
+
+import (
+	"encoding/base64"
+	"fmt"
+	"io"
+	"net/http"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/golang/glog"
+	"golang.org/x/net/websocket"
+)
+
+// The subprotocols accepted by a Conn. In the base64 variant every frame's
+// payload (after the channel prefix byte, which is then an ASCII digit) is
+// base64 encoded, which allows text-only websocket clients to participate.
+const (
+	ChannelWebSocketProtocol       = "channel.k8s.io"
+	Base64ChannelWebSocketProtocol = "base64.channel.k8s.io"
+)
+
+var connectionUpgradeRegex = regexp.MustCompile("(^|.*,\\s*)upgrade($|\\s*,)")
+
+// IsWebSocketRequest returns true if the incoming request contains connection
+// upgrade headers for a websocket.
+func IsWebSocketRequest(req *http.Request) bool {
+	return connectionUpgradeRegex.MatchString(strings.ToLower(req.Header.Get("Connection"))) &&
+		strings.ToLower(req.Header.Get("Upgrade")) == "websocket"
+}
+
+// ChannelType describes the direction of a channel as seen by the server.
+type ChannelType int
+
+const (
+	// IgnoreChannel frames are discarded and writes are dropped.
+	IgnoreChannel ChannelType = iota
+	// ReadChannel carries data from the client to the server.
+	ReadChannel
+	// WriteChannel carries data from the server to the client.
+	WriteChannel
+	// ReadWriteChannel carries data in both directions.
+	ReadWriteChannel
+)
+
+// Conn supplies a number of channels multiplexed over a single websocket
+// connection. Create a Conn with NewConn, then call Open with the incoming
+// request to perform the upgrade and obtain the channel ends.
+type Conn struct {
+	channels []*websocketChannel
+	codec    codecType
+	ready    chan struct{}
+	// Closes ready exactly once, whether the connection was opened by
+	// handle or refused during the handshake.
+	readyOnce sync.Once
+	// Set by handle before ready is closed; false afterwards means the
+	// upgrade never completed.
+	opened  bool
+	ws      *websocket.Conn
+	timeout time.Duration
+}
+
+type codecType int
+
+const (
+	rawCodec codecType = iota
+	base64Codec
+)
+
+// NewConn creates a WebSocket connection that supports a set of channels.
+// Channels begin each frame with a single byte identifying the channel
+// number (0-N from the provided channel types). The channel types must be
+// declared in channel number order.
+func NewConn(channelTypes ...ChannelType) *Conn {
+	channels := make([]*websocketChannel, len(channelTypes))
+	for i := range channels {
+		switch channelTypes[i] {
+		case ReadChannel:
+			channels[i] = newWebsocketChannel(true, false)
+		case WriteChannel:
+			channels[i] = newWebsocketChannel(false, true)
+		case ReadWriteChannel:
+			channels[i] = newWebsocketChannel(true, true)
+		default:
+			channels[i] = newWebsocketChannel(false, false)
+		}
+	}
+	return &Conn{
+		channels: channels,
+		ready:    make(chan struct{}),
+	}
+}
+
+// SetIdleTimeout sets the interval for both reads and writes before timeout.
+// If not specified, there is no timeout on the connection.
+func (conn *Conn) SetIdleTimeout(duration time.Duration) {
+	conn.timeout = duration
+}
+
+// Open the connection and create channels for reading and writing. It returns
+// an array of streams corresponding to the channel types passed to NewConn.
+// It blocks until the websocket handshake has completed and then serves
+// frames until the client disconnects. An error is returned if the handshake
+// fails, e.g. because the client offered no supported subprotocol.
+func (conn *Conn) Open(w http.ResponseWriter, req *http.Request) ([]io.ReadWriteCloser, error) {
+	go func() {
+		// If the handshake refused the connection or ServeHTTP panicked,
+		// handle never ran and nobody else will unblock Open.
+		defer conn.signalReady()
+		defer func() {
+			if p := recover(); p != nil {
+				glog.Errorf("websocket server fatal error: %v", p)
+			}
+		}()
+		websocket.Server{Handshake: conn.handshake, Handler: conn.handle}.ServeHTTP(w, req)
+	}()
+	<-conn.ready
+	if !conn.opened {
+		return nil, fmt.Errorf("unable to upgrade websocket connection")
+	}
+	rwc := make([]io.ReadWriteCloser, len(conn.channels))
+	for i := range conn.channels {
+		rwc[i] = conn.channels[i]
+	}
+	return rwc, nil
+}
+
+// signalReady unblocks Open. The first caller wins, so a successful handle
+// marks the connection opened before the ServeHTTP goroutine returns.
+func (conn *Conn) signalReady() {
+	conn.readyOnce.Do(func() {
+		close(conn.ready)
+	})
+}
+
+func (conn *Conn) handshake(config *websocket.Config, req *http.Request) error {
+	conn.codec = rawCodec
+	for _, protocol := range config.Protocol {
+		switch protocol {
+		case ChannelWebSocketProtocol:
+			config.Protocol = []string{protocol}
+			return nil
+		case Base64ChannelWebSocketProtocol:
+			conn.codec = base64Codec
+			config.Protocol = []string{protocol}
+			return nil
+		}
+	}
+	if len(config.Protocol) == 0 {
+		return nil
+	}
+	return fmt.Errorf("none of the websocket subprotocols %v are supported", config.Protocol)
+}
+
+func (conn *Conn) resetTimeout() {
+	if conn.timeout > 0 {
+		conn.ws.SetDeadline(time.Now().Add(conn.timeout))
+	}
+}
+
+// handle implements a websocket handler.
+func (conn *Conn) handle(ws *websocket.Conn) {
+	conn.ws = ws
+	for i := range conn.channels {
+		conn.channels[i].open(conn)
+	}
+	conn.opened = true
+	conn.signalReady()
+	defer conn.Close()
+
+	for {
+		conn.resetTimeout()
+		var data []byte
+		if err := websocket.Message.Receive(ws, &data); err != nil {
+			if err != io.EOF {
+				glog.Errorf("Error on websocket receive: %v", err)
+			}
+			break
+		}
+		if len(data) == 0 {
+			continue
+		}
+		channel := int(data[0])
+		if conn.codec == base64Codec {
+			channel = channel - '0'
+		}
+		data = data[1:]
+		if channel < 0 || channel >= len(conn.channels) {
+			glog.V(6).Infof("Frame is targeted for a missing or ignored channel: %d", channel)
+			continue
+		}
+		if _, err := conn.channels[channel].DataFromSocket(data); err != nil {
+			glog.Errorf("Unable to write frame to %d: %v %v", channel, err, string(data))
+			continue
+		}
+	}
+}
+
+// Close is only valid after Open has been called.
+func (conn *Conn) Close() error {
+	<-conn.ready
+	for i := range conn.channels {
+		conn.channels[i].Close()
+	}
+	conn.ws.Close()
+	return nil
+}
+
+// write sends a frame for the given channel to the websocket connection.
+func (conn *Conn) write(channel byte, data []byte) (int, error) {
+	conn.resetTimeout()
+	switch conn.codec {
+	case rawCodec:
+		frame := make([]byte, len(data)+1)
+		frame[0] = channel
+		copy(frame[1:], data)
+		if err := websocket.Message.Send(conn.ws, frame); err != nil {
+			return 0, err
+		}
+	case base64Codec:
+		frame := string('0'+channel) + base64.StdEncoding.EncodeToString(data)
+		if err := websocket.Message.Send(conn.ws, frame); err != nil {
+			return 0, err
+		}
+	}
+	return len(data), nil
+}
+
+// websocketChannel represents a channel in a connection.
+type websocketChannel struct {
+	conn *Conn
+	num  byte
+	r    io.Reader
+	w    io.WriteCloser
+
+	read, write bool
+}
+
+// newWebsocketChannel creates a pipe for writing to a websocket. Do not write
+// to this pipe before the websocket connection is opened.
+func newWebsocketChannel(read, write bool) *websocketChannel {
+	r, w := io.Pipe()
+	return &websocketChannel{r: r, w: w, read: read, write: write}
+}
+
+func (p *websocketChannel) open(conn *Conn) {
+	p.conn = conn
+	for i := range conn.channels {
+		if conn.channels[i] == p {
+			p.num = byte(i)
+		}
+	}
+}
+
+func (p *websocketChannel) Write(data []byte) (int, error) {
+	if !p.write {
+		return len(data), nil
+	}
+	return p.conn.write(p.num, data)
+}
+
+// DataFromSocket is invoked by the connection receiver to move data from the
+// connection into a specific channel.
+func (p *websocketChannel) DataFromSocket(data []byte) (int, error) {
+	if !p.read {
+		return len(data), nil
+	}
+	if p.conn.codec == base64Codec {
+		decoded := make([]byte, base64.StdEncoding.DecodedLen(len(data)))
+		n, err := base64.StdEncoding.Decode(decoded, data)
+		if err != nil {
+			return 0, err
+		}
+		data = decoded[:n]
+	}
+	return p.w.Write(data)
+}
+
+func (p *websocketChannel) Read(data []byte) (int, error) {
+	if !p.read {
+		return 0, io.EOF
+	}
+	return p.r.Read(data)
+}
+
+func (p *websocketChannel) Close() error {
+	return p.w.Close()
+}
@@ -0,0 +1,149 @@
+/*
+Copyright 2015 Google Inc. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package wsstream
+
+import (
+	"encoding/base64"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"golang.org/x/net/websocket"
+)
+
+func TestIsWebSocketRequest(t *testing.T) {
+	req, _ := http.NewRequest("GET", "/", nil)
+	if IsWebSocketRequest(req) {
+		t.Errorf("expected not a websocket request")
+	}
+	req.Header.Set("Connection", "Upgrade")
+	req.Header.Set("Upgrade", "websocket")
+	if !IsWebSocketRequest(req) {
+		t.Errorf("expected a websocket request")
+	}
+	req.Header.Set("Connection", "keep-alive, Upgrade")
+	if !IsWebSocketRequest(req) {
+		t.Errorf("expected a websocket request")
+	}
+}
+
+func TestRawConn(t *testing.T) {
+	channels := make(chan []io.ReadWriteCloser, 1)
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		conn := NewConn(ReadChannel, WriteChannel)
+		streams, err := conn.Open(w, req)
+		if err != nil {
+			t.Errorf("error opening connection: %v", err)
+			return
+		}
+		channels <- streams
+		// echo channel 0 to channel 1
+		io.Copy(streams[1], streams[0])
+	}))
+	defer s.Close()
+
+	url := "ws" + strings.TrimPrefix(s.URL, "http")
+	client, err := websocket.Dial(url, ChannelWebSocketProtocol, "http://localhost")
+	if err != nil {
+		t.Fatalf("error dialing websocket: %v", err)
+	}
+	defer client.Close()
+
+	if err := websocket.Message.Send(client, append([]byte{0}, []byte("hello")...)); err != nil {
+		t.Fatalf("error sending frame: %v", err)
+	}
+
+	var data []byte
+	if err := websocket.Message.Receive(client, &data); err != nil {
+		t.Fatalf("error receiving frame: %v", err)
+	}
+	if len(data) == 0 || data[0] != 1 {
+		t.Fatalf("expected frame for channel 1, got %v", data)
+	}
+	if e, a := "hello", string(data[1:]); e != a {
+		t.Errorf("expected %q, got %q", e, a)
+	}
+	<-channels
+}
+
+func TestRejectedHandshakeUnblocksOpen(t *testing.T) {
+	errs := make(chan error, 1)
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		conn := NewConn(ReadChannel, WriteChannel)
+		_, err := conn.Open(w, req)
+		errs <- err
+	}))
+	defer s.Close()
+
+	url := "ws" + strings.TrimPrefix(s.URL, "http")
+	if _, err := websocket.Dial(url, "unsupported.protocol", "http://localhost"); err == nil {
+		t.Errorf("expected the dial to be rejected")
+	}
+	// Open must return an error rather than block forever when the
+	// handshake refuses the connection.
+	select {
+	case err := <-errs:
+		if err == nil {
+			t.Errorf("expected Open to return an error for a rejected handshake")
+		}
+	case <-time.After(10 * time.Second):
+		t.Fatalf("timed out waiting for Open to return")
+	}
+}
+
+func TestBase64Conn(t *testing.T) {
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		conn := NewConn(ReadChannel, WriteChannel)
+		streams, err := conn.Open(w, req)
+		if err != nil {
+			t.Errorf("error opening connection: %v", err)
+			return
+		}
+		io.Copy(streams[1], streams[0])
+	}))
+	defer s.Close()
+
+	url := "ws" + strings.TrimPrefix(s.URL, "http")
+	client, err := websocket.Dial(url, Base64ChannelWebSocketProtocol, "http://localhost")
+	if err != nil {
+		t.Fatalf("error dialing websocket: %v", err)
+	}
+	defer client.Close()
+
+	encoded := base64.StdEncoding.EncodeToString([]byte("hello"))
+	if err := websocket.Message.Send(client, "0"+encoded); err != nil {
+		t.Fatalf("error sending frame: %v", err)
+	}
+
+	var data string
+	if err := websocket.Message.Receive(client, &data); err != nil {
+		t.Fatalf("error receiving frame: %v", err)
+	}
+	if len(data) == 0 || data[0] != '1' {
+		t.Fatalf("expected frame for channel 1, got %q", data)
+	}
+	decoded, err := base64.StdEncoding.DecodeString(data[1:])
+	if err != nil {
+		t.Fatalf("error decoding frame: %v", err)
+	}
+	if e, a := "hello", string(decoded); e != a {
+		t.Errorf("expected %q, got %q", e, a)
+	}
+}
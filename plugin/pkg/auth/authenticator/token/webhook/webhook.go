@@ -0,0 +1,99 @@
+/*
+Copyright 2015 Google Inc. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package webhook implements a token authenticator which delegates the
+// decision to a remote HTTP endpoint, typically served by the API server.
+// The token under review is posted as JSON and the endpoint replies with
+// the authentication decision and the resolved user.
+package webhook
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	"github.com/GoogleCloudPlatform/kubernetes/pkg/auth/user"
+)
+
+// TokenReview is the wire format exchanged with the remote endpoint. The
+// authenticator fills in Spec and expects Status in the response.
+type TokenReview struct {
+	Spec   TokenReviewSpec   `json:"spec"`
+	Status TokenReviewStatus `json:"status,omitempty"`
+}
+
+// TokenReviewSpec holds the token to be reviewed.
+type TokenReviewSpec struct {
+	Token string `json:"token"`
+}
+
+// TokenReviewStatus is the decision made by the remote endpoint.
+type TokenReviewStatus struct {
+	Authenticated bool     `json:"authenticated"`
+	User          UserInfo `json:"user,omitempty"`
+	Error         string   `json:"error,omitempty"`
+}
+
+// UserInfo describes the user a token was resolved to.
+type UserInfo struct {
+	Username string   `json:"username,omitempty"`
+	UID      string   `json:"uid,omitempty"`
+	Groups   []string `json:"groups,omitempty"`
+}
+
+// TokenAuthenticator reviews tokens against a remote endpoint.
+type TokenAuthenticator struct {
+	reviewURL string
+	client    *http.Client
+}
+
+// New returns a TokenAuthenticator which posts token reviews to reviewURL.
+func New(reviewURL string) *TokenAuthenticator {
+	return &TokenAuthenticator{
+		reviewURL: reviewURL,
+		client:    http.DefaultClient,
+	}
+}
+
+// AuthenticateToken implements authenticator.Token by asking the remote
+// endpoint to review the token.
+func (a *TokenAuthenticator) AuthenticateToken(token string) (user.Info, bool, error) {
+	review := TokenReview{Spec: TokenReviewSpec{Token: token}}
+	body, err := json.Marshal(review)
+	if err != nil {
+		return nil, false, err
+	}
+	resp, err := a.client.Post(a.reviewURL, "application/json", bytes.NewReader(body))
+	if err != nil {
+		return nil, false, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, false, fmt.Errorf("token review endpoint returned status %d", resp.StatusCode)
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&review); err != nil {
+		return nil, false, err
+	}
+	if !review.Status.Authenticated {
+		return nil, false, nil
+	}
+	return &user.DefaultInfo{
+		Name:   review.Status.User.Username,
+		UID:    review.Status.User.UID,
+		Groups: review.Status.User.Groups,
+	}, true, nil
+}
@@ -0,0 +1,79 @@
+/*
+Copyright 2015 Google Inc. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package webhook
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestWebhookTokenAuthenticator(t *testing.T) {
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		var review TokenReview
+		if err := json.NewDecoder(req.Body).Decode(&review); err != nil {
+			t.Errorf("failed to decode review: %v", err)
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		if review.Spec.Token == "known" {
+			review.Status = TokenReviewStatus{
+				Authenticated: true,
+				User:          UserInfo{Username: "jane", UID: "1", Groups: []string{"group1"}},
+			}
+		}
+		json.NewEncoder(w).Encode(&review)
+	}))
+	defer s.Close()
+
+	auth := New(s.URL)
+
+	user, ok, err := auth.AuthenticateToken("known")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !ok {
+		t.Fatalf("expected token to authenticate")
+	}
+	if user.GetName() != "jane" || user.GetUID() != "1" {
+		t.Errorf("unexpected user: %#v", user)
+	}
+	if groups := user.GetGroups(); len(groups) != 1 || groups[0] != "group1" {
+		t.Errorf("unexpected groups: %v", groups)
+	}
+
+	user, ok, err = auth.AuthenticateToken("unknown")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if ok {
+		t.Errorf("expected token to be rejected, got user %#v", user)
+	}
+}
+
+func TestWebhookTokenAuthenticatorError(t *testing.T) {
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		http.Error(w, "failure", http.StatusInternalServerError)
+	}))
+	defer s.Close()
+
+	auth := New(s.URL)
+	if _, _, err := auth.AuthenticateToken("token"); err == nil {
+		t.Errorf("expected error from failing endpoint")
+	}
+}
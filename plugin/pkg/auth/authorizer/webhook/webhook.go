@@ -0,0 +1,103 @@
+/*
+Copyright 2015 Google Inc. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package webhook implements an authorizer which delegates the decision to a
+// remote HTTP endpoint, typically served by the API server. The attributes of
+// the request under review are posted as a subject access review and the
+// endpoint replies with the authorization decision.
+package webhook
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	"github.com/GoogleCloudPlatform/kubernetes/pkg/auth/authorizer"
+)
+
+// SubjectAccessReview is the wire format exchanged with the remote endpoint.
+// The authorizer fills in Spec and expects Status in the response.
+type SubjectAccessReview struct {
+	Spec   SubjectAccessReviewSpec   `json:"spec"`
+	Status SubjectAccessReviewStatus `json:"status,omitempty"`
+}
+
+// SubjectAccessReviewSpec describes the request being authorized.
+type SubjectAccessReviewSpec struct {
+	User      string   `json:"user,omitempty"`
+	Groups    []string `json:"groups,omitempty"`
+	Namespace string   `json:"namespace,omitempty"`
+	Resource  string   `json:"resource,omitempty"`
+	ReadOnly  bool     `json:"readOnly,omitempty"`
+}
+
+// SubjectAccessReviewStatus is the decision made by the remote endpoint.
+type SubjectAccessReviewStatus struct {
+	Allowed bool   `json:"allowed"`
+	Reason  string `json:"reason,omitempty"`
+}
+
+// WebhookAuthorizer delegates authorization decisions to a remote endpoint.
+type WebhookAuthorizer struct {
+	reviewURL string
+	client    *http.Client
+}
+
+// New returns a WebhookAuthorizer which posts subject access reviews to
+// reviewURL.
+func New(reviewURL string) *WebhookAuthorizer {
+	return &WebhookAuthorizer{
+		reviewURL: reviewURL,
+		client:    http.DefaultClient,
+	}
+}
+
+// Authorize implements authorizer.Authorizer by asking the remote endpoint to
+// review the request attributes.
+func (w *WebhookAuthorizer) Authorize(a authorizer.Attributes) error {
+	review := SubjectAccessReview{
+		Spec: SubjectAccessReviewSpec{
+			User:      a.GetUserName(),
+			Groups:    a.GetGroups(),
+			Namespace: a.GetNamespace(),
+			Resource:  a.GetResource(),
+			ReadOnly:  a.IsReadOnly(),
+		},
+	}
+	body, err := json.Marshal(review)
+	if err != nil {
+		return err
+	}
+	resp, err := w.client.Post(w.reviewURL, "application/json", bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("subject access review endpoint returned status %d", resp.StatusCode)
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&review); err != nil {
+		return err
+	}
+	if !review.Status.Allowed {
+		if review.Status.Reason != "" {
+			return fmt.Errorf("request denied: %s", review.Status.Reason)
+		}
+		return fmt.Errorf("request denied")
+	}
+	return nil
+}
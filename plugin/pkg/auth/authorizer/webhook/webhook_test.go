@@ -0,0 +1,76 @@
+/*
+Copyright 2015 Google Inc. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package webhook
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/GoogleCloudPlatform/kubernetes/pkg/auth/authorizer"
+	"github.com/GoogleCloudPlatform/kubernetes/pkg/auth/user"
+)
+
+func TestWebhookAuthorizer(t *testing.T) {
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		var review SubjectAccessReview
+		if err := json.NewDecoder(req.Body).Decode(&review); err != nil {
+			t.Errorf("failed to decode review: %v", err)
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		if review.Spec.User == "jane" && review.Spec.Resource == "exec" {
+			review.Status.Allowed = true
+		} else {
+			review.Status.Reason = "no policy matched"
+		}
+		json.NewEncoder(w).Encode(&review)
+	}))
+	defer s.Close()
+
+	authz := New(s.URL)
+
+	allowed := authorizer.AttributesRecord{
+		User:     &user.DefaultInfo{Name: "jane"},
+		Resource: "exec",
+	}
+	if err := authz.Authorize(allowed); err != nil {
+		t.Errorf("expected request to be allowed, got %v", err)
+	}
+
+	denied := authorizer.AttributesRecord{
+		User:     &user.DefaultInfo{Name: "john"},
+		Resource: "exec",
+	}
+	if err := authz.Authorize(denied); err == nil {
+		t.Errorf("expected request to be denied")
+	}
+}
+
+func TestWebhookAuthorizerError(t *testing.T) {
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		http.Error(w, "failure", http.StatusInternalServerError)
+	}))
+	defer s.Close()
+
+	authz := New(s.URL)
+	attrs := authorizer.AttributesRecord{User: &user.DefaultInfo{Name: "jane"}}
+	if err := authz.Authorize(attrs); err == nil {
+		t.Errorf("expected error from failing endpoint")
+	}
+}